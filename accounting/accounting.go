@@ -218,6 +218,9 @@ const (
 	AUDIT_REQUESTS_FILTERED
 	AUDIT_ACTIONS
 	AUDIT_ACTIONS_FAILED
+
+	RESULT_CACHE_HITS
+	RESULT_CACHE_MISSES
 )
 
 // Define names for all the metrics we are interested in:
@@ -258,6 +261,9 @@ const (
 	_AUDIT_ACTIONS           = "audit_actions"
 	_AUDIT_ACTIONS_FAILED    = "audit_actions_failed"
 
+	_RESULT_CACHE_HITS   = "result_cache_hits"
+	_RESULT_CACHE_MISSES = "result_cache_misses"
+
 	REQUEST_RATE  = "request_rate"
 	REQUEST_TIMER = "request_timer"
 	PREPARED      = "prepared"
@@ -300,6 +306,9 @@ var metricNames = []string{
 	_AUDIT_REQUESTS_FILTERED,
 	_AUDIT_ACTIONS,
 	_AUDIT_ACTIONS_FAILED,
+
+	_RESULT_CACHE_HITS,
+	_RESULT_CACHE_MISSES,
 }
 
 const (
@@ -340,6 +349,16 @@ func RegisterMetrics(acctStore AccountingStore) {
 
 	// We have to use a meter due to the way it's used in accounting_gm.go
 	preparedMeter = ms.Meter(PREPARED)
+
+	registerDimensions()
+}
+
+// The vital signs of the local node
+func Vitals() (interface{}, errors.Error) {
+	if acctstore == nil {
+		return nil, nil
+	}
+	return acctstore.Vitals()
 }
 
 // Record request metrics
@@ -0,0 +1,174 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package accounting
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dimensions along which request latencies and errors are broken down
+type DimensionId int
+
+const (
+	DIM_STATEMENT DimensionId = iota
+	DIM_KEYSPACE
+	DIM_USER
+	DIM_CLIENT
+)
+
+// please keep in sync with the mnemonics
+var dimensionNames = []string{
+	"statement",
+	"keyspace",
+	"user",
+	"client",
+}
+
+const (
+	// maximum number of distinct values tracked for each dimension
+	// any further value is accounted for under _DIM_OTHER
+	_DIM_CAP = 64
+
+	// values are truncated to keep metric names manageable
+	_DIM_VALUE_LEN = 64
+
+	_DIM_OTHER   = "_other"
+	_DIM_UNKNOWN = "unknown"
+)
+
+// the metrics kept for each value of a dimension
+type dimensionMetrics struct {
+	timer  Timer
+	errors Counter
+}
+
+type dimension struct {
+	sync.RWMutex
+	name   string
+	values map[string]*dimensionMetrics
+}
+
+var dimensions []*dimension
+
+func registerDimensions() {
+	dimensions = make([]*dimension, len(dimensionNames))
+	for id, name := range dimensionNames {
+		dimensions[id] = &dimension{
+			name:   name,
+			values: make(map[string]*dimensionMetrics, _DIM_CAP),
+		}
+	}
+}
+
+// Returns the metrics for a value, creating them if the dimension
+// hasn't yet reached its cap.
+// Metrics are registered as <dimension>.<value>.request_timer and
+// <dimension>.<value>.errors
+func (this *dimension) metrics(val string) *dimensionMetrics {
+	if len(val) > _DIM_VALUE_LEN {
+		val = val[:_DIM_VALUE_LEN]
+	}
+
+	this.RLock()
+	m, ok := this.values[val]
+	this.RUnlock()
+	if ok {
+		return m
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	m, ok = this.values[val]
+	if ok {
+		return m
+	}
+	if len(this.values) >= _DIM_CAP {
+		val = _DIM_OTHER
+		m, ok = this.values[val]
+		if ok {
+			return m
+		}
+	}
+
+	ms := acctstore.MetricRegistry()
+	prefix := this.name + "." + val + "."
+	m = &dimensionMetrics{
+		timer:  ms.Timer(prefix + REQUEST_TIMER),
+		errors: ms.Counter(prefix + _ERRORS),
+	}
+	this.values[val] = m
+	return m
+}
+
+func (this *dimension) record(val string, request_time time.Duration, error_count int) {
+	m := this.metrics(val)
+	m.timer.Update(request_time)
+	if error_count > 0 {
+		m.errors.Inc(int64(error_count))
+	}
+}
+
+// Record request latency and errors by statement type, keyspaces
+// referenced, users and client
+func RecordDimensionMetrics(request_time time.Duration, error_count int, stmt string,
+	keyspaces []string, users []string, client string) {
+
+	if acctstore == nil {
+		return
+	}
+
+	stmt = strings.ToLower(stmt)
+	if stmt == "" {
+		stmt = _DIM_UNKNOWN
+	}
+	dimensions[DIM_STATEMENT].record(stmt, request_time, error_count)
+
+	for _, keyspace := range keyspaces {
+		dimensions[DIM_KEYSPACE].record(keyspace, request_time, error_count)
+	}
+	for _, user := range users {
+		if user != "" {
+			dimensions[DIM_USER].record(user, request_time, error_count)
+		}
+	}
+	if client == "" {
+		client = _DIM_UNKNOWN
+	}
+	dimensions[DIM_CLIENT].record(client, request_time, error_count)
+}
+
+// Summary of the dimensional metrics, for vitals
+func DimensionVitals() map[string]interface{} {
+	if acctstore == nil {
+		return nil
+	}
+
+	rv := make(map[string]interface{}, len(dimensions))
+	for _, d := range dimensions {
+		d.RLock()
+		values := make(map[string]interface{}, len(d.values))
+		for val, m := range d.values {
+			ps := m.timer.Percentiles([]float64{0.5, 0.95, 0.99})
+			values[val] = map[string]interface{}{
+				"count":                     m.timer.Count(),
+				"errors":                    m.errors.Count(),
+				"request_time.mean":         time.Duration(m.timer.Mean()).String(),
+				"request_time.median":       time.Duration(ps[0]).String(),
+				"request_time.95percentile": time.Duration(ps[1]).String(),
+				"request_time.99percentile": time.Duration(ps[2]).String(),
+			}
+		}
+		d.RUnlock()
+		rv[d.name] = values
+	}
+	return rv
+}
@@ -134,6 +134,8 @@ func (g *gometricsAccountingStore) Vitals() (interface{}, errors.Error) {
 		Req95:          time.Duration(request_timer.Percentile(.95)).String(),
 		Req99:          time.Duration(request_timer.Percentile(.99)).String(),
 		Prepared:       prepPercent,
+		Dimensions:     accounting.DimensionVitals(),
+		Workloads:      server.WorkloadVitals(),
 	}, nil
 
 }
@@ -164,6 +166,12 @@ type VitalsRecord struct {
 	Req99          string  `json:"request_time.99percentile"`
 	Prepared       float64 `json:"request.prepared.percent"`
 
+	// request latencies and errors by statement type, keyspace, user and client
+	Dimensions map[string]interface{} `json:"dimensions,omitempty"`
+
+	// admission control, by workload class
+	Workloads map[string]interface{} `json:"workload_classes,omitempty"`
+
 	// FIXME Active vs Queued threads, local time, version, direct vs prepared, network
 }
 
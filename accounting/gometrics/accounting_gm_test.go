@@ -10,7 +10,11 @@
 package accounting_gm
 
 import (
+	"fmt"
 	"testing"
+	"time"
+
+	"github.com/couchbase/query/accounting"
 )
 
 func TestGoMetrics(t *testing.T) {
@@ -51,3 +55,36 @@ func TestGoMetrics(t *testing.T) {
 	acctstore.MetricRegistry().Histogram("response_count")
 	acctstore.MetricRegistry().Timer("request_time")
 }
+
+func TestDimensionMetrics(t *testing.T) {
+	acctstore := NewAccountingStore()
+
+	// clear metrics registered by other tests with different types
+	for _, name := range []string{"request_count", "request_rate", "request_time"} {
+		acctstore.MetricRegistry().Unregister(name)
+	}
+	accounting.RegisterMetrics(acctstore)
+
+	accounting.RecordDimensionMetrics(10*time.Millisecond, 1, "SELECT",
+		[]string{"default:customer"}, []string{"local:alice"}, "cbq")
+	for i := 0; i < 100; i++ {
+		accounting.RecordDimensionMetrics(time.Millisecond, 0, "SELECT",
+			nil, nil, fmt.Sprintf("client%d", i))
+	}
+
+	if acctstore.MetricRegistry().Counter("keyspace.default:customer.errors").Count() != 1 {
+		t.Fatalf("Expected keyspace error count to be 1")
+	}
+	if acctstore.MetricRegistry().Timer("statement.select.request_timer").Count() != 101 {
+		t.Fatalf("Expected statement request count to be 101")
+	}
+
+	vitals := accounting.DimensionVitals()
+	clients := vitals["client"].(map[string]interface{})
+	if len(clients) > 65 {
+		t.Fatalf("Expected client dimension to be capped, got %v values", len(clients))
+	}
+	if _, ok := clients["_other"]; !ok {
+		t.Fatalf("Expected client dimension overflow to be tracked")
+	}
+}
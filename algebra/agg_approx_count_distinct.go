@@ -0,0 +1,141 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function APPROX_COUNT_DISTINCT(expr). It
+returns an estimate of the number of distinct values of expr in the
+group, excluding NULL and MISSING, using a HyperLogLog sketch of fixed
+size. Small counts are exact. Type ApproxCountDistinct is a struct
+that inherits from AggregateBase.
+*/
+type ApproxCountDistinct struct {
+	AggregateBase
+}
+
+/*
+The function NewApproxCountDistinct calls NewAggregateBase to
+create an aggregate function named APPROX_COUNT_DISTINCT with
+one expression as input.
+*/
+func NewApproxCountDistinct(operand expression.Expression) Aggregate {
+	rv := &ApproxCountDistinct{
+		*NewAggregateBase("approx_count_distinct", operand),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *ApproxCountDistinct) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *ApproxCountDistinct) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *ApproxCountDistinct) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+The constructor returns a NewApproxCountDistinct with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *ApproxCountDistinct) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewApproxCountDistinct(operands[0])
+	}
+}
+
+/*
+If no input to the APPROX_COUNT_DISTINCT function, then the default
+value returned is a zero value.
+*/
+func (this *ApproxCountDistinct) Default() value.Value { return value.ZERO_VALUE }
+
+/*
+Aggregates input data by evaluating operands. NULL and MISSING values
+are skipped. Other values are added to the sketch.
+*/
+func (this *ApproxCountDistinct) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() <= value.NULL {
+		return cumulative, nil
+	}
+
+	av, ok := cumulative.(value.AnnotatedValue)
+	if !ok {
+		av = value.NewAnnotatedValue(cumulative)
+		av.SetAttachment("hll", newHyperLogLog())
+	}
+
+	av.GetAttachment("hll").(*hyperLogLog).add(item)
+	return av, nil
+}
+
+/*
+Aggregates intermediate results by merging their sketches.
+*/
+func (this *ApproxCountDistinct) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	if part == value.ZERO_VALUE {
+		return cumulative, nil
+	} else if cumulative == value.ZERO_VALUE {
+		return part, nil
+	}
+
+	psketch, e := getSketch(part, "hll")
+	if e != nil {
+		return nil, e
+	}
+
+	csketch, e := getSketch(cumulative, "hll")
+	if e != nil {
+		return nil, e
+	}
+
+	csketch.(*hyperLogLog).merge(psketch.(*hyperLogLog))
+	return cumulative, nil
+}
+
+/*
+Compute the Final result, the estimated count.
+*/
+func (this *ApproxCountDistinct) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
+	if cumulative == value.ZERO_VALUE {
+		return cumulative, nil
+	}
+
+	sketch, e := getSketch(cumulative, "hll")
+	if e != nil {
+		return nil, e
+	}
+
+	return value.NewValue(sketch.(*hyperLogLog).count()), nil
+}
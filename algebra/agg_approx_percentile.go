@@ -0,0 +1,267 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function APPROX_PERCENTILE(expr,
+fraction). It returns an estimate of the value at fraction of the
+ordered number values in the group, interpolating between values,
+using a t-digest sketch. If fraction is an array of fractions, it
+returns an array of estimates. Type ApproxPercentile is a struct that
+inherits from AggregateBase.
+*/
+type ApproxPercentile struct {
+	AggregateBase
+}
+
+/*
+The function NewApproxPercentile calls NewParameterizedAggregateBase
+to create an aggregate function named APPROX_PERCENTILE with one
+expression as input and the fraction as parameter.
+*/
+func NewApproxPercentile(operand, fraction expression.Expression) Aggregate {
+	rv := &ApproxPercentile{
+		*NewParameterizedAggregateBase("approx_percentile", operand, fraction),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *ApproxPercentile) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type JSON, a number or an array of numbers.
+*/
+func (this *ApproxPercentile) Type() value.Type { return value.JSON }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *ApproxPercentile) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+func (this *ApproxPercentile) MinArgs() int { return 2 }
+
+func (this *ApproxPercentile) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewApproxPercentile with the input operand
+and fraction cast to a Function as the FunctionConstructor.
+*/
+func (this *ApproxPercentile) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewApproxPercentile(operands[0], operands[1])
+	}
+}
+
+/*
+If no input to the APPROX_PERCENTILE function, then the default value
+returned is a null.
+*/
+func (this *ApproxPercentile) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. Values other than
+numbers are skipped. Numbers are added to the sketch.
+*/
+func (this *ApproxPercentile) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateDigest(this, item, cumulative, context)
+}
+
+/*
+Aggregates intermediate results by merging their sketches.
+*/
+func (this *ApproxPercentile) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return mergeDigests(part, cumulative)
+}
+
+/*
+Compute the Final result, the estimates at the fractions.
+*/
+func (this *ApproxPercentile) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	fractions, array, e := percentileFractions(this, this.Operands()[1], context)
+	if e != nil {
+		return nil, e
+	}
+
+	sketch, e := getSketch(cumulative, "digest")
+	if e != nil {
+		return nil, e
+	}
+
+	digest := sketch.(*tDigest)
+	if !array {
+		return value.NewValue(digest.quantile(fractions[0])), nil
+	}
+
+	rv := make([]interface{}, len(fractions))
+	for i, f := range fractions {
+		rv[i] = digest.quantile(f)
+	}
+
+	return value.NewValue(rv), nil
+}
+
+/*
+This represents the Aggregate function APPROX_MEDIAN(expr). It
+returns an estimate of the median of the number values in the group,
+using a t-digest sketch. Type ApproxMedian is a struct that inherits
+from AggregateBase.
+*/
+type ApproxMedian struct {
+	AggregateBase
+}
+
+/*
+The function NewApproxMedian calls NewAggregateBase to create an
+aggregate function named APPROX_MEDIAN with one expression as input.
+*/
+func NewApproxMedian(operand expression.Expression) Aggregate {
+	rv := &ApproxMedian{
+		*NewAggregateBase("approx_median", operand),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *ApproxMedian) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type NUMBER.
+*/
+func (this *ApproxMedian) Type() value.Type { return value.NUMBER }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *ApproxMedian) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+/*
+The constructor returns a NewApproxMedian with the input operand
+cast to a Function as the FunctionConstructor.
+*/
+func (this *ApproxMedian) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewApproxMedian(operands[0])
+	}
+}
+
+/*
+If no input to the APPROX_MEDIAN function, then the default value
+returned is a null.
+*/
+func (this *ApproxMedian) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. Values other than
+numbers are skipped. Numbers are added to the sketch.
+*/
+func (this *ApproxMedian) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateDigest(this, item, cumulative, context)
+}
+
+/*
+Aggregates intermediate results by merging their sketches.
+*/
+func (this *ApproxMedian) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return mergeDigests(part, cumulative)
+}
+
+/*
+Compute the Final result, the estimated median.
+*/
+func (this *ApproxMedian) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	sketch, e := getSketch(cumulative, "digest")
+	if e != nil {
+		return nil, e
+	}
+
+	return value.NewValue(sketch.(*tDigest).quantile(0.5)), nil
+}
+
+/*
+Add the number value of the aggregate operand to the t-digest of the
+cumulative value.
+*/
+func cumulateDigest(agg Aggregate, item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := agg.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	av, ok := cumulative.(value.AnnotatedValue)
+	if !ok {
+		av = value.NewAnnotatedValue(cumulative)
+		av.SetAttachment("digest", newTDigest())
+	}
+
+	av.GetAttachment("digest").(*tDigest).add(value.AsNumberValue(item).Float64())
+	return av, nil
+}
+
+/*
+Merge the t-digests of intermediate results.
+*/
+func mergeDigests(part, cumulative value.Value) (value.Value, error) {
+	if part == value.NULL_VALUE {
+		return cumulative, nil
+	} else if cumulative == value.NULL_VALUE {
+		return part, nil
+	}
+
+	psketch, e := getSketch(part, "digest")
+	if e != nil {
+		return nil, e
+	}
+
+	csketch, e := getSketch(cumulative, "digest")
+	if e != nil {
+		return nil, e
+	}
+
+	csketch.(*tDigest).merge(psketch.(*tDigest))
+	return cumulative, nil
+}
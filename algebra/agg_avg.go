@@ -122,6 +122,9 @@ func (this *Avg) ComputeFinal(cumulative value.Value, context Context) (value.Va
 	}
 
 	if count.Actual().(float64) > 0.0 {
+		if value.IsDecimal(sum) {
+			return value.DecimalDiv(sum, count), nil
+		}
 		return value.NewValue(sum.Actual().(float64) / count.Actual().(float64)), nil
 	} else {
 		return value.NULL_VALUE, nil
@@ -147,7 +150,7 @@ func (this *Avg) cumulatePart(part, cumulative value.Value, context Context) (va
 
 	if psum.Type() != value.NUMBER || pcount.Type() != value.NUMBER ||
 		csum.Type() != value.NUMBER || ccount.Type() != value.NUMBER {
-		return nil, fmt.Errorf("Missing or invalid partial sum or count in AVG: %v, %v, %v, v.",
+		return nil, fmt.Errorf("Missing or invalid partial sum or count in AVG: %v, %v, %v, %v.",
 			psum.Actual(), pcount.Actual(), csum.Actual(), ccount.Actual())
 	}
 
@@ -0,0 +1,133 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the Aggregate function MODE(expr), also written as
+the ordered-set aggregate MODE() WITHIN GROUP (ORDER BY expr [ASC |
+DESC]). It returns the most frequent value of expr in the group,
+excluding NULL and MISSING. Among equally frequent values, it returns
+the first in the order, the smallest by default. Type Mode is a
+struct that inherits from AggregateBase.
+*/
+type Mode struct {
+	AggregateBase
+}
+
+/*
+The function NewMode calls NewParameterizedAggregateBase to create an
+aggregate function named MODE with one expression as input and the
+optional descending flag as parameter.
+*/
+func NewMode(operands ...expression.Expression) Aggregate {
+	rv := &Mode{
+		*NewParameterizedAggregateBase("mode", operands[0], operands[1:]...),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *Mode) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type JSON.
+*/
+func (this *Mode) Type() value.Type { return value.JSON }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *Mode) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+func (this *Mode) MinArgs() int { return 1 }
+
+func (this *Mode) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewMode with the input operands cast to a
+Function as the FunctionConstructor.
+*/
+func (this *Mode) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewMode(operands...)
+	}
+}
+
+/*
+If no input to the MODE function, then the default value returned is
+a null.
+*/
+func (this *Mode) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. NULL and MISSING values
+are skipped. Other values are collected in a list.
+*/
+func (this *Mode) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() <= value.NULL {
+		return cumulative, nil
+	}
+
+	return listAdd(item, cumulative), nil
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *Mode) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateOrderedSets(part, cumulative)
+}
+
+/*
+Compute the Final result. Count the runs of equal values in the
+ordered list, and return the value of the longest run.
+*/
+func (this *Mode) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	values, e := orderedSetValues(this, cumulative, context)
+	if e != nil {
+		return nil, e
+	}
+
+	mode, modeCount := 0, 0
+	for i, j := 0, 0; i < len(values); i = j {
+		for j = i + 1; j < len(values) && values[j].Collate(values[i]) == 0; j++ {
+		}
+
+		if j-i > modeCount {
+			mode, modeCount = i, j-i
+		}
+	}
+
+	return values[mode], nil
+}
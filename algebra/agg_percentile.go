@@ -0,0 +1,318 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"math"
+	"sort"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+This represents the ordered-set Aggregate function
+PERCENTILE_CONT(fraction) WITHIN GROUP (ORDER BY expr [ASC | DESC]),
+also written PERCENTILE_CONT(expr, fraction [, descending ]). It
+returns the value at fraction of the ordered number values in the
+group, interpolating between the two nearest values. If fraction is
+an array of fractions, it returns an array of values. Type
+PercentileCont is a struct that inherits from AggregateBase.
+*/
+type PercentileCont struct {
+	AggregateBase
+}
+
+/*
+The function NewPercentileCont calls NewParameterizedAggregateBase to
+create an aggregate function named PERCENTILE_CONT with one expression
+as input, and the fraction and optional descending flag as parameters.
+*/
+func NewPercentileCont(operands ...expression.Expression) Aggregate {
+	rv := &PercentileCont{
+		*NewParameterizedAggregateBase("percentile_cont", operands[0], operands[1:]...),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *PercentileCont) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type JSON, a number or an array of numbers.
+*/
+func (this *PercentileCont) Type() value.Type { return value.JSON }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *PercentileCont) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+func (this *PercentileCont) MinArgs() int { return 2 }
+
+func (this *PercentileCont) MaxArgs() int { return 3 }
+
+/*
+The constructor returns a NewPercentileCont with the input operands
+cast to a Function as the FunctionConstructor.
+*/
+func (this *PercentileCont) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewPercentileCont(operands...)
+	}
+}
+
+/*
+If no input to the PERCENTILE_CONT function, then the default value
+returned is a null.
+*/
+func (this *PercentileCont) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. Values other than
+numbers are skipped. Numbers are collected in a list.
+*/
+func (this *PercentileCont) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() != value.NUMBER {
+		return cumulative, nil
+	}
+
+	return listAdd(item, cumulative), nil
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *PercentileCont) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateOrderedSets(part, cumulative)
+}
+
+/*
+Compute the Final result, the interpolated values at the fractions.
+*/
+func (this *PercentileCont) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	fractions, array, e := percentileFractions(this, this.Operands()[1], context)
+	if e != nil {
+		return nil, e
+	}
+
+	values, e := orderedSetValues(this, cumulative, context)
+	if e != nil {
+		return nil, e
+	}
+
+	rv := make([]interface{}, len(fractions))
+	for i, f := range fractions {
+		pos := f * float64(len(values)-1)
+		lo := int(math.Floor(pos))
+		hi := int(math.Ceil(pos))
+		lf := value.AsNumberValue(values[lo]).Float64()
+		hf := value.AsNumberValue(values[hi]).Float64()
+		rv[i] = lf + (hf-lf)*(pos-float64(lo))
+	}
+
+	if !array {
+		return value.NewValue(rv[0]), nil
+	}
+
+	return value.NewValue(rv), nil
+}
+
+/*
+This represents the ordered-set Aggregate function
+PERCENTILE_DISC(fraction) WITHIN GROUP (ORDER BY expr [ASC | DESC]),
+also written PERCENTILE_DISC(expr, fraction [, descending ]). It
+returns the first value in the order of the group, excluding NULL and
+MISSING, whose cumulative distribution is at least fraction. If
+fraction is an array of fractions, it returns an array of values. Type
+PercentileDisc is a struct that inherits from AggregateBase.
+*/
+type PercentileDisc struct {
+	AggregateBase
+}
+
+/*
+The function NewPercentileDisc calls NewParameterizedAggregateBase to
+create an aggregate function named PERCENTILE_DISC with one expression
+as input, and the fraction and optional descending flag as parameters.
+*/
+func NewPercentileDisc(operands ...expression.Expression) Aggregate {
+	rv := &PercentileDisc{
+		*NewParameterizedAggregateBase("percentile_disc", operands[0], operands[1:]...),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *PercentileDisc) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type JSON.
+*/
+func (this *PercentileDisc) Type() value.Type { return value.JSON }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *PercentileDisc) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+func (this *PercentileDisc) MinArgs() int { return 2 }
+
+func (this *PercentileDisc) MaxArgs() int { return 3 }
+
+/*
+The constructor returns a NewPercentileDisc with the input operands
+cast to a Function as the FunctionConstructor.
+*/
+func (this *PercentileDisc) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewPercentileDisc(operands...)
+	}
+}
+
+/*
+If no input to the PERCENTILE_DISC function, then the default value
+returned is a null.
+*/
+func (this *PercentileDisc) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. NULL and MISSING values
+are skipped. Other values are collected in a list.
+*/
+func (this *PercentileDisc) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() <= value.NULL {
+		return cumulative, nil
+	}
+
+	return listAdd(item, cumulative), nil
+}
+
+/*
+Aggregates intermediate results and return them.
+*/
+func (this *PercentileDisc) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	return cumulateOrderedSets(part, cumulative)
+}
+
+/*
+Compute the Final result, the values at the fractions.
+*/
+func (this *PercentileDisc) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	fractions, array, e := percentileFractions(this, this.Operands()[1], context)
+	if e != nil {
+		return nil, e
+	}
+
+	values, e := orderedSetValues(this, cumulative, context)
+	if e != nil {
+		return nil, e
+	}
+
+	rv := make([]interface{}, len(fractions))
+	for i, f := range fractions {
+		pos := int(math.Ceil(f*float64(len(values)))) - 1
+		if pos < 0 {
+			pos = 0
+		}
+		rv[i] = values[pos]
+	}
+
+	if !array {
+		return value.NewValue(rv[0]), nil
+	}
+
+	return value.NewValue(rv), nil
+}
+
+/*
+Aggregate intermediate lists of ordered-set aggregates, whose
+cumulative value is NULL until the first value is added.
+*/
+func cumulateOrderedSets(part, cumulative value.Value) (value.Value, error) {
+	if part == value.NULL_VALUE {
+		return cumulative, nil
+	} else if cumulative == value.NULL_VALUE {
+		return part, nil
+	}
+
+	return cumulateLists(part, cumulative)
+}
+
+/*
+Return the collected values of an ordered-set aggregate, in the order
+given by its optional descending flag following the MinArgs()
+operands.
+*/
+func orderedSetValues(agg Aggregate, cumulative value.Value, context Context) ([]value.Value, error) {
+	descending := false
+	if operands := agg.Operands(); len(operands) > agg.MinArgs() {
+		dv, e := aggregateParameter(agg, operands[agg.MinArgs()], context)
+		if e != nil {
+			return nil, e
+		}
+
+		descending = dv.Truth()
+	}
+
+	list, e := getList(cumulative)
+	if e != nil {
+		return nil, e
+	}
+
+	values := make([]value.Value, list.Len())
+	copy(values, list.Values())
+	sort.Slice(values, func(i, j int) bool {
+		if descending {
+			return values[i].Collate(values[j]) > 0
+		}
+		return values[i].Collate(values[j]) < 0
+	})
+
+	return values, nil
+}
@@ -31,6 +31,17 @@ func GetAggregate(name string, distinct bool) (Aggregate, bool) {
 	}
 }
 
+/*
+This method is used to retrieve an ordered-set aggregate function,
+such as PERCENTILE_CONT, by the parser for the WITHIN GROUP (ORDER BY
+expr) syntax. The ordered expression becomes the first operand of the
+aggregate, followed by its arguments.
+*/
+func GetOrderedSetAggregate(name string) (Aggregate, bool) {
+	rv, ok := _ORDERED_SET_AGGREGATES[strings.ToLower(name)]
+	return rv, ok
+}
+
 /*
 Aggregate functions with a DISTINCT specified. The variable
 represents a map from string to Aggregate Function. The
@@ -59,24 +70,43 @@ var _DISTINCT_AGGREGATES = map[string]Aggregate{
 /*
 Non Distinct Aggregate functions. The variable represents a
 map from string to Aggregate Function. Contains aggregate
-functions ARRAY_AGG, AVG, COUNT, MAX, MIN and SUM.
+functions ARRAY_AGG, AVG, COUNT, MAX, MIN and SUM, the ordered-set
+aggregates, and the approximate aggregates.
 */
 var _OTHER_AGGREGATES = map[string]Aggregate{
-	"array_agg":     &ArrayAgg{},
-	"avg":           &Avg{},
-	"count":         &Count{},
-	"countn":        &Countn{},
-	"max":           &Max{},
-	"mean":          &Avg{},
-	"median":        &Median{},
-	"min":           &Min{},
-	"stddev":        &Stddev{},
-	"stddev_pop":    &StddevPop{},
-	"stddev_samp":   &StddevSamp{},
-	"sum":           &Sum{},
-	"variance":      &Variance{},
-	"var_pop":       &VarPop{},
-	"variance_pop":  &VarPop{},
-	"var_samp":      &VarSamp{},
-	"variance_samp": &VarSamp{},
+	"approx_count_distinct": &ApproxCountDistinct{},
+	"approx_median":         &ApproxMedian{},
+	"approx_percentile":     &ApproxPercentile{},
+	"top_k":                 &TopK{},
+	"array_agg":             &ArrayAgg{},
+	"avg":                   &Avg{},
+	"count":                 &Count{},
+	"countn":                &Countn{},
+	"max":                   &Max{},
+	"mean":                  &Avg{},
+	"median":                &Median{},
+	"min":                   &Min{},
+	"mode":                  &Mode{},
+	"percentile_cont":       &PercentileCont{},
+	"percentile_disc":       &PercentileDisc{},
+	"stddev":                &Stddev{},
+	"stddev_pop":            &StddevPop{},
+	"stddev_samp":           &StddevSamp{},
+	"sum":                   &Sum{},
+	"variance":              &Variance{},
+	"var_pop":               &VarPop{},
+	"variance_pop":          &VarPop{},
+	"var_samp":              &VarSamp{},
+	"variance_samp":         &VarSamp{},
+}
+
+/*
+Ordered-set aggregate functions, which can be written with WITHIN
+GROUP (ORDER BY expr). The variable represents a map from string to
+Aggregate Function.
+*/
+var _ORDERED_SET_AGGREGATES = map[string]Aggregate{
+	"mode":            &Mode{},
+	"percentile_cont": &PercentileCont{},
+	"percentile_disc": &PercentileDisc{},
 }
@@ -0,0 +1,457 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The sketches of the approximate aggregates. Each summarizes its input
+in bounded memory, and merges with other sketches of the same kind,
+so that the aggregates can be computed in parallel.
+*/
+
+/*
+Returns the sketch attached to a cumulative value.
+*/
+func getSketch(item value.Value, name string) (interface{}, error) {
+	av, ok := item.(value.AnnotatedValue)
+	if !ok {
+		return nil, fmt.Errorf("Invalid %v of type %T.", item, item)
+	}
+
+	sketch := av.GetAttachment(name)
+	if sketch == nil {
+		return nil, fmt.Errorf("Invalid %s %v.", name, av)
+	}
+
+	return sketch, nil
+}
+
+///////////////////////////////////////////////////
+//
+// HyperLogLog
+//
+///////////////////////////////////////////////////
+
+/*
+Registers are addressed by the top _HLL_PRECISION bits of a hash,
+giving a standard error of 1.04 / sqrt(2^_HLL_PRECISION), about 0.8%.
+*/
+const _HLL_PRECISION = 14
+
+const _HLL_REGISTERS = 1 << _HLL_PRECISION
+
+/*
+Up to this many distinct hashes are kept, and counted exactly, before
+switching to registers.
+*/
+const _HLL_SPARSE_LIMIT = 1024
+
+/*
+A HyperLogLog estimate of the number of distinct values.
+*/
+type hyperLogLog struct {
+	sparse    map[uint64]bool
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{
+		sparse: make(map[uint64]bool),
+	}
+}
+
+func hashValue(item value.Value) uint64 {
+	bytes, _ := item.MarshalJSON()
+	return util.SeaHashSum64(bytes)
+}
+
+func (this *hyperLogLog) add(item value.Value) {
+	this.addHash(hashValue(item))
+}
+
+func (this *hyperLogLog) addHash(hash uint64) {
+	if this.registers == nil {
+		this.sparse[hash] = true
+		if len(this.sparse) > _HLL_SPARSE_LIMIT {
+			this.densify()
+		}
+		return
+	}
+
+	index := hash >> (64 - _HLL_PRECISION)
+	rank := uint8(1)
+	for w := hash << _HLL_PRECISION; w&(1<<63) == 0 && rank <= 64-_HLL_PRECISION; w <<= 1 {
+		rank++
+	}
+
+	if rank > this.registers[index] {
+		this.registers[index] = rank
+	}
+}
+
+func (this *hyperLogLog) densify() {
+	this.registers = make([]uint8, _HLL_REGISTERS)
+	for hash, _ := range this.sparse {
+		this.addHash(hash)
+	}
+	this.sparse = nil
+}
+
+func (this *hyperLogLog) merge(other *hyperLogLog) {
+	if other.registers == nil {
+		for hash, _ := range other.sparse {
+			this.addHash(hash)
+		}
+		return
+	}
+
+	if this.registers == nil {
+		this.densify()
+	}
+
+	for i, r := range other.registers {
+		if r > this.registers[i] {
+			this.registers[i] = r
+		}
+	}
+}
+
+func (this *hyperLogLog) count() int64 {
+	if this.registers == nil {
+		return int64(len(this.sparse))
+	}
+
+	m := float64(_HLL_REGISTERS)
+	sum := 0.0
+	zeros := 0
+	for _, r := range this.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Linear counting for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return int64(estimate + 0.5)
+}
+
+///////////////////////////////////////////////////
+//
+// t-digest
+//
+///////////////////////////////////////////////////
+
+/*
+The compression of t-digests. A digest keeps at most about this many
+centroids, and is most accurate near the extreme quantiles.
+*/
+const _DIGEST_COMPRESSION = 100
+
+type centroid struct {
+	mean, weight float64
+}
+
+/*
+A merging t-digest, estimating the quantiles of numbers.
+*/
+type tDigest struct {
+	centroids []centroid
+	buffer    []centroid
+	min, max  float64
+}
+
+func newTDigest() *tDigest {
+	return &tDigest{
+		min: math.Inf(1),
+		max: math.Inf(-1),
+	}
+}
+
+func (this *tDigest) add(f float64) {
+	this.buffer = append(this.buffer, centroid{f, 1})
+	this.min = math.Min(this.min, f)
+	this.max = math.Max(this.max, f)
+	if len(this.buffer) >= 5*_DIGEST_COMPRESSION {
+		this.compress()
+	}
+}
+
+func (this *tDigest) merge(other *tDigest) {
+	this.buffer = append(this.buffer, other.centroids...)
+	this.buffer = append(this.buffer, other.buffer...)
+	this.min = math.Min(this.min, other.min)
+	this.max = math.Max(this.max, other.max)
+	this.compress()
+}
+
+func digestScale(q float64) float64 {
+	return _DIGEST_COMPRESSION / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func digestScaleInverse(k float64) float64 {
+	if k >= _DIGEST_COMPRESSION/4 {
+		return 1
+	}
+
+	return (math.Sin(k*2*math.Pi/_DIGEST_COMPRESSION) + 1) / 2
+}
+
+/*
+Merges the buffer into the centroids, combining neighbouring centroids
+while their weight stays within the bound of the scale function.
+*/
+func (this *tDigest) compress() {
+	if len(this.buffer) == 0 {
+		return
+	}
+
+	all := append(this.centroids, this.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	total := 0.0
+	for _, c := range all {
+		total += c.weight
+	}
+
+	merged := make([]centroid, 0, 2*_DIGEST_COMPRESSION)
+	cur := all[0]
+	sofar := 0.0
+	limit := total * digestScaleInverse(digestScale(0)+1)
+	for _, c := range all[1:] {
+		if sofar+cur.weight+c.weight <= limit {
+			w := cur.weight + c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / w
+			cur.weight = w
+			continue
+		}
+
+		sofar += cur.weight
+		merged = append(merged, cur)
+		limit = total * digestScaleInverse(digestScale(sofar/total)+1)
+		cur = c
+	}
+
+	this.centroids = append(merged, cur)
+	this.buffer = nil
+}
+
+/*
+Returns the estimated value at fraction q of the ordered input,
+interpolating between centroids. Input held in centroids of weight
+one yields the exact continuous percentile.
+*/
+func (this *tDigest) quantile(q float64) float64 {
+	this.compress()
+
+	cs := this.centroids
+	if len(cs) == 1 {
+		return cs[0].mean
+	}
+
+	total := 0.0
+	for _, c := range cs {
+		total += c.weight
+	}
+
+	index := q*(total-1) + 0.5
+	left := cs[0].weight / 2
+	if index < left {
+		return this.min + (cs[0].mean-this.min)*index/left
+	}
+
+	for i := 0; i < len(cs)-1; i++ {
+		right := left + (cs[i].weight+cs[i+1].weight)/2
+		if index <= right {
+			return cs[i].mean + (cs[i+1].mean-cs[i].mean)*(index-left)/(right-left)
+		}
+		left = right
+	}
+
+	last := cs[len(cs)-1]
+	return last.mean + (this.max-last.mean)*(index-left)/(last.weight/2)
+}
+
+///////////////////////////////////////////////////
+//
+// Space-Saving
+//
+///////////////////////////////////////////////////
+
+/*
+A counter of the Space-Saving algorithm. The count overestimates the
+frequency of the value by at most error.
+*/
+type ssCounter struct {
+	item  value.Value
+	key   string
+	count int64
+	error int64
+	index int
+}
+
+/*
+The counters of the Space-Saving algorithm, a min-heap by count.
+*/
+type ssCounters []*ssCounter
+
+func (this ssCounters) Len() int           { return len(this) }
+func (this ssCounters) Less(i, j int) bool { return this[i].count < this[j].count }
+
+func (this ssCounters) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+	this[i].index = i
+	this[j].index = j
+}
+
+func (this *ssCounters) Push(x interface{}) {
+	c := x.(*ssCounter)
+	c.index = len(*this)
+	*this = append(*this, c)
+}
+
+func (this *ssCounters) Pop() interface{} {
+	old := *this
+	c := old[len(old)-1]
+	*this = old[0 : len(old)-1]
+	return c
+}
+
+/*
+A Space-Saving summary of the most frequent values. With capacity
+counters, every value more frequent than 1/capacity of the input is
+counted.
+*/
+type spaceSaving struct {
+	capacity int
+	counters ssCounters
+	keys     map[string]*ssCounter
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{
+		capacity: capacity,
+		counters: make(ssCounters, 0, capacity),
+		keys:     make(map[string]*ssCounter, capacity),
+	}
+}
+
+func (this *spaceSaving) add(item value.Value) {
+	bytes, _ := item.MarshalJSON()
+	key := string(bytes)
+
+	if c, ok := this.keys[key]; ok {
+		c.count++
+		heap.Fix(&this.counters, c.index)
+		return
+	}
+
+	if len(this.counters) < this.capacity {
+		c := &ssCounter{item: item, key: key, count: 1}
+		this.keys[key] = c
+		heap.Push(&this.counters, c)
+		return
+	}
+
+	// Replace the least frequent value
+	c := this.counters[0]
+	delete(this.keys, c.key)
+	c.item, c.key, c.error = item, key, c.count
+	c.count++
+	this.keys[key] = c
+	heap.Fix(&this.counters, 0)
+}
+
+func (this *spaceSaving) min() int64 {
+	if len(this.counters) < this.capacity {
+		return 0
+	}
+
+	return this.counters[0].count
+}
+
+/*
+Merges two summaries, as described in "Mergeable Summaries" by
+Agarwal et al. A value missing from a full summary may have occurred
+there up to its minimum count.
+*/
+func (this *spaceSaving) merge(other *spaceSaving) {
+	thisMin := this.min()
+	otherMin := other.min()
+
+	all := make(ssCounters, 0, len(this.counters)+len(other.counters))
+	for _, c := range this.counters {
+		if o, ok := other.keys[c.key]; ok {
+			c.count += o.count
+			c.error += o.error
+		} else {
+			c.count += otherMin
+			c.error += otherMin
+		}
+		all = append(all, c)
+	}
+
+	for _, o := range other.counters {
+		if _, ok := this.keys[o.key]; !ok {
+			all = append(all, &ssCounter{
+				item:  o.item,
+				key:   o.key,
+				count: o.count + thisMin,
+				error: o.error + thisMin,
+			})
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].count > all[j].count })
+	if len(all) > this.capacity {
+		all = all[0:this.capacity]
+	}
+
+	this.keys = make(map[string]*ssCounter, len(all))
+	for i, c := range all {
+		c.index = i
+		this.keys[c.key] = c
+	}
+	this.counters = all
+	heap.Init(&this.counters)
+}
+
+/*
+Returns the k most frequent values, most frequent first.
+*/
+func (this *spaceSaving) top(k int) []*ssCounter {
+	top := make([]*ssCounter, len(this.counters))
+	copy(top, this.counters)
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].count != top[j].count {
+			return top[i].count > top[j].count
+		}
+		return top[i].item.Collate(top[j].item) < 0
+	})
+
+	if len(top) > k {
+		top = top[0:k]
+	}
+
+	return top
+}
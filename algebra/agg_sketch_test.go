@@ -0,0 +1,73 @@
+package algebra
+
+import (
+	"math"
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	a := newHyperLogLog()
+	b := newHyperLogLog()
+	for i := 0; i < 100000; i++ {
+		a.add(value.NewValue(i))
+		b.add(value.NewValue(i + 50000))
+	}
+
+	a.merge(b)
+	count := a.count()
+	if math.Abs(float64(count)-150000)/150000 > 0.03 {
+		t.Errorf("expected about 150000 distinct values, received %d", count)
+	}
+
+	small := newHyperLogLog()
+	for i := 0; i < 500; i++ {
+		small.add(value.NewValue(i % 100))
+	}
+	if small.count() != 100 {
+		t.Errorf("expected exactly 100 distinct values, received %d", small.count())
+	}
+}
+
+func TestTDigest(t *testing.T) {
+	a := newTDigest()
+	b := newTDigest()
+	for i := 0; i < 100000; i++ {
+		if i%2 == 0 {
+			a.add(float64(i))
+		} else {
+			b.add(float64(i))
+		}
+	}
+
+	a.merge(b)
+	for _, q := range []float64{0, 0.01, 0.5, 0.95, 0.99, 1} {
+		expected := q * 99999
+		if math.Abs(a.quantile(q)-expected) > 500 {
+			t.Errorf("expected quantile %v to be about %v, received %v", q, expected, a.quantile(q))
+		}
+	}
+}
+
+func TestSpaceSaving(t *testing.T) {
+	a := newSpaceSaving(20)
+	b := newSpaceSaving(20)
+	for i := 0; i < 10000; i++ {
+		item := value.NewValue(i % 1000)
+		if i%10 == 0 {
+			item = value.NewValue("hot")
+		}
+		if i < 5000 {
+			a.add(item)
+		} else {
+			b.add(item)
+		}
+	}
+
+	a.merge(b)
+	top := a.top(1)
+	if len(top) != 1 || top[0].item.Actual() != "hot" || top[0].count < 1000 {
+		t.Errorf("expected hot to be most frequent, received %v", top)
+	}
+}
@@ -0,0 +1,204 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"fmt"
+
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The default number of values returned by TOP_K.
+*/
+const _TOP_K_DEFAULT = 10
+
+/*
+The largest number of values TOP_K can return.
+*/
+const _TOP_K_MAX = 1000
+
+/*
+The number of Space-Saving counters per value returned. More counters
+make the counts of the returned values more accurate.
+*/
+const _TOP_K_COUNTERS = 10
+
+/*
+This represents the Aggregate function TOP_K(expr [, k ]). It returns
+an estimate of the k most frequent values of expr in the group, 10 by
+default, excluding NULL and MISSING, as an array of objects with the
+value and its count, most frequent first. Counts may overestimate,
+using a Space-Saving sketch. Type TopK is a struct that inherits from
+AggregateBase.
+*/
+type TopK struct {
+	AggregateBase
+}
+
+/*
+The function NewTopK calls NewParameterizedAggregateBase to create an
+aggregate function named TOP_K with one expression as input and the
+optional k as parameter.
+*/
+func NewTopK(operands ...expression.Expression) Aggregate {
+	rv := &TopK{
+		*NewParameterizedAggregateBase("top_k", operands[0], operands[1:]...),
+	}
+
+	rv.SetExpr(rv)
+	return rv
+}
+
+/*
+It calls the VisitFunction method by passing in the receiver to
+and returns the interface. It is a visitor pattern.
+*/
+func (this *TopK) Accept(visitor expression.Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+/*
+It returns a value of type ARRAY.
+*/
+func (this *TopK) Type() value.Type { return value.ARRAY }
+
+/*
+Calls the evaluate method for aggregate functions and passes in the
+receiver, current item and current context.
+*/
+func (this *TopK) Evaluate(item value.Value, context expression.Context) (result value.Value, e error) {
+	return this.evaluate(this, item, context)
+}
+
+func (this *TopK) MinArgs() int { return 1 }
+
+func (this *TopK) MaxArgs() int { return 2 }
+
+/*
+The constructor returns a NewTopK with the input operands cast to a
+Function as the FunctionConstructor.
+*/
+func (this *TopK) Constructor() expression.FunctionConstructor {
+	return func(operands ...expression.Expression) expression.Function {
+		return NewTopK(operands...)
+	}
+}
+
+/*
+If no input to the TOP_K function, then the default value returned
+is a null.
+*/
+func (this *TopK) Default() value.Value { return value.NULL_VALUE }
+
+/*
+Aggregates input data by evaluating operands. NULL and MISSING values
+are skipped. Other values are counted in the sketch.
+*/
+func (this *TopK) CumulateInitial(item, cumulative value.Value, context Context) (value.Value, error) {
+	item, e := this.Operand().Evaluate(item, context)
+	if e != nil {
+		return nil, e
+	}
+
+	if item.Type() <= value.NULL {
+		return cumulative, nil
+	}
+
+	av, ok := cumulative.(value.AnnotatedValue)
+	if !ok {
+		k, e := this.k(context)
+		if e != nil {
+			return nil, e
+		}
+
+		av = value.NewAnnotatedValue(cumulative)
+		av.SetAttachment("counters", newSpaceSaving(k*_TOP_K_COUNTERS))
+	}
+
+	av.GetAttachment("counters").(*spaceSaving).add(item)
+	return av, nil
+}
+
+/*
+Aggregates intermediate results by merging their sketches.
+*/
+func (this *TopK) CumulateIntermediate(part, cumulative value.Value, context Context) (value.Value, error) {
+	if part == value.NULL_VALUE {
+		return cumulative, nil
+	} else if cumulative == value.NULL_VALUE {
+		return part, nil
+	}
+
+	psketch, e := getSketch(part, "counters")
+	if e != nil {
+		return nil, e
+	}
+
+	csketch, e := getSketch(cumulative, "counters")
+	if e != nil {
+		return nil, e
+	}
+
+	csketch.(*spaceSaving).merge(psketch.(*spaceSaving))
+	return cumulative, nil
+}
+
+/*
+Compute the Final result, the k most frequent values and their
+counts.
+*/
+func (this *TopK) ComputeFinal(cumulative value.Value, context Context) (c value.Value, e error) {
+	if cumulative == value.NULL_VALUE {
+		return cumulative, nil
+	}
+
+	k, e := this.k(context)
+	if e != nil {
+		return nil, e
+	}
+
+	sketch, e := getSketch(cumulative, "counters")
+	if e != nil {
+		return nil, e
+	}
+
+	top := sketch.(*spaceSaving).top(k)
+	rv := make([]interface{}, len(top))
+	for i, c := range top {
+		rv[i] = map[string]interface{}{
+			"value": c.item,
+			"count": c.count,
+		}
+	}
+
+	return value.NewValue(rv), nil
+}
+
+func (this *TopK) k(context Context) (int, error) {
+	if len(this.Operands()) < 2 {
+		return _TOP_K_DEFAULT, nil
+	}
+
+	kv, e := aggregateParameter(this, this.Operands()[1], context)
+	if e != nil {
+		return 0, e
+	}
+
+	if kv.Type() == value.NUMBER {
+		k := value.AsNumberValue(kv).Float64()
+		if value.IsInt(k) && k > 0 && k <= _TOP_K_MAX {
+			return int(k), nil
+		}
+	}
+
+	return 0, fmt.Errorf("TOP_K() k must be an integer between 1 and %d, not %v.", _TOP_K_MAX, kv)
+}
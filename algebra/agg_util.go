@@ -12,7 +12,9 @@ package algebra
 import (
 	"fmt"
 	"sort"
+	"strings"
 
+	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/value"
 )
 
@@ -388,3 +390,57 @@ func computeVariance(cumulative value.Value, distinct, samp bool, delta float64)
 
 	return value.NewValue(variance / (count - delta)), nil
 }
+
+/*
+Evaluate a parameter of an aggregate, such as the fraction of a
+percentile, which must be constant.
+*/
+func aggregateParameter(agg Aggregate, param expression.Expression, context Context) (value.Value, error) {
+	if param.Static() == nil {
+		return nil, fmt.Errorf("%s() parameter %v must be a constant.",
+			strings.ToUpper(agg.Name()), param)
+	}
+
+	return param.Evaluate(nil, context)
+}
+
+/*
+Evaluate the fraction parameter of a percentile aggregate, either a
+number or an array of numbers between 0 and 1. Return the fractions,
+and whether they were given as an array.
+*/
+func percentileFractions(agg Aggregate, param expression.Expression, context Context) ([]float64, bool, error) {
+	fv, e := aggregateParameter(agg, param, context)
+	if e != nil {
+		return nil, false, e
+	}
+
+	var items []interface{}
+	array := fv.Type() == value.ARRAY
+	if array {
+		items = fv.Actual().([]interface{})
+	} else {
+		items = []interface{}{fv}
+	}
+
+	fractions := make([]float64, len(items))
+	for i, item := range items {
+		f := value.NewValue(item)
+		if f.Type() != value.NUMBER {
+			return nil, false, fmt.Errorf("%s() fraction %v must be a number between 0 and 1.",
+				strings.ToUpper(agg.Name()), f)
+		}
+
+		fractions[i] = value.AsNumberValue(f).Float64()
+		if fractions[i] < 0 || fractions[i] > 1 {
+			return nil, false, fmt.Errorf("%s() fraction %v must be a number between 0 and 1.",
+				strings.ToUpper(agg.Name()), f)
+		}
+	}
+
+	if len(fractions) == 0 {
+		return nil, false, fmt.Errorf("%s() requires at least one fraction.", strings.ToUpper(agg.Name()))
+	}
+
+	return fractions, array, nil
+}
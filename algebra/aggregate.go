@@ -87,6 +87,18 @@ func NewAggregateBase(name string, operand expression.Expression) *AggregateBase
 	}
 }
 
+/*
+This method creates an aggregate whose operand is followed by
+constant parameters, such as the fraction of a percentile.
+*/
+func NewParameterizedAggregateBase(name string, operand expression.Expression,
+	parameters ...expression.Expression) *AggregateBase {
+	return &AggregateBase{
+		*expression.NewParameterizedUnaryFunctionBase(name, operand, parameters...),
+		"",
+	}
+}
+
 /*
 This method evaluates the input aggregate, by retrieving the
 aggregates map from the attachments and performing a lookup
@@ -27,12 +27,13 @@ and returning represents the returning clause.
 type Delete struct {
 	statementBase
 
-	keyspace  *KeyspaceRef          `json:"keyspace"`
-	keys      expression.Expression `json:"keys"`
-	indexes   IndexRefs             `json:"indexes"`
-	where     expression.Expression `json:"where"`
-	limit     expression.Expression `json:"limit"`
-	returning *Projection           `json:"returning"`
+	keyspace   *KeyspaceRef          `json:"keyspace"`
+	keys       expression.Expression `json:"keys"`
+	indexes    IndexRefs             `json:"indexes"`
+	where      expression.Expression `json:"where"`
+	limit      expression.Expression `json:"limit"`
+	returning  *Projection           `json:"returning"`
+	optimHints *OptimHints           `json:"optimizer_hints"`
 }
 
 /*
@@ -255,3 +256,14 @@ delete statement.
 func (this *Delete) Returning() *Projection {
 	return this.returning
 }
+
+/*
+Returns the optimizer hints following DELETE, if any.
+*/
+func (this *Delete) OptimHints() *OptimHints {
+	return this.optimHints
+}
+
+func (this *Delete) SetOptimHints(optimHints *OptimHints) {
+	this.optimHints = optimHints
+}
@@ -34,6 +34,18 @@ func NewExpressionTerm(fromExpr expression.Expression, as string,
 	return &ExpressionTerm{fromExpr, as, keyspaceTerm, isKeyspace, false, joinHint, 0}
 }
 
+/*
+Returns a copy of the term, and of its keyspace term, which the planner
+can change without changing the statement. Expressions are shared.
+*/
+func (this *ExpressionTerm) Copy() *ExpressionTerm {
+	rv := *this
+	if this.keyspaceTerm != nil {
+		rv.keyspaceTerm = this.keyspaceTerm.Copy()
+	}
+	return &rv
+}
+
 /*
 Visitor pattern.
 */
@@ -270,6 +282,16 @@ func (this *ExpressionTerm) SetAnsiJoin() {
 	this.property |= TERM_ANSI_JOIN
 }
 
+/*
+Unset ANSI JOIN property, when the term becomes the primary term
+*/
+func (this *ExpressionTerm) UnsetAnsiJoin() {
+	this.property &^= TERM_ANSI_JOIN
+	if this.keyspaceTerm != nil {
+		this.keyspaceTerm.UnsetAnsiJoin()
+	}
+}
+
 /*
 Set ANSI NEST property
 */
@@ -150,6 +150,13 @@ func (this *Join) Right() *KeyspaceTerm {
 	return this.right
 }
 
+/*
+Set the left source object, as when the planner expands views
+*/
+func (this *Join) SetLeft(left FromTerm) {
+	this.left = left
+}
+
 /*
 Returns boolean value based on if it is
 an outer or inner JOIN.
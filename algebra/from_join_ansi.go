@@ -22,19 +22,60 @@ Represents the ANSI JOIN clause. AnsiJoins create new input objects by
 combining two or more source objects.  They can be chained.
 */
 type AnsiJoin struct {
-	left     FromTerm
-	right    SimpleFromTerm
-	outer    bool
-	onclause expression.Expression
+	left       FromTerm
+	right      SimpleFromTerm
+	outer      bool
+	rightOuter bool
+	semi       bool
+	onclause   expression.Expression
 }
 
 func NewAnsiJoin(left FromTerm, outer bool, right SimpleFromTerm, onclause expression.Expression) *AnsiJoin {
-	return &AnsiJoin{left, right, outer, onclause}
+	return &AnsiJoin{left, right, outer, false, false, onclause}
+}
+
+/*
+Returns a copy of the join, which the planner can change without
+changing the statement. The terms it joins are shared.
+*/
+func (this *AnsiJoin) Copy() *AnsiJoin {
+	rv := *this
+	return &rv
 }
 
 func NewAnsiRightJoin(left SimpleFromTerm, right SimpleFromTerm, onclause expression.Expression) *AnsiJoin {
 	TransferJoinHint(left, right)
-	return &AnsiJoin{right, left, true, onclause}
+	return &AnsiJoin{right, left, true, false, false, onclause}
+}
+
+/*
+RIGHT OUTER JOIN whose left-hand side is itself a join, and thus
+cannot be swapped with the right-hand side.
+*/
+func NewAnsiRightOuterJoin(left FromTerm, right SimpleFromTerm, onclause expression.Expression) *AnsiJoin {
+	return &AnsiJoin{left, right, false, true, false, onclause}
+}
+
+func NewAnsiFullJoin(left FromTerm, right SimpleFromTerm, onclause expression.Expression) *AnsiJoin {
+	return &AnsiJoin{left, right, true, true, false, onclause}
+}
+
+/*
+CROSS JOIN is an inner join with a constant TRUE ON-clause.
+*/
+func NewAnsiCrossJoin(left FromTerm, right SimpleFromTerm) *AnsiJoin {
+	return &AnsiJoin{left, right, false, false, false, expression.TRUE_EXPR}
+}
+
+/*
+Semi-join, or anti-join if anti is true, of the left source with the
+right source: the rows of the left source for which some row, or no
+row, of the right source satisfies the ON-clause, without the right
+source. Built by the planner from EXISTS, NOT EXISTS and IN subqueries
+of the WHERE clause; an anti-join is an outer join.
+*/
+func NewAnsiSemiJoin(left FromTerm, right SimpleFromTerm, onclause expression.Expression, anti bool) *AnsiJoin {
+	return &AnsiJoin{left, right, anti, false, true, onclause}
 }
 
 func TransferJoinHint(left SimpleFromTerm, right SimpleFromTerm) {
@@ -110,7 +151,15 @@ func (this *AnsiJoin) Privileges() (*auth.Privileges, errors.Error) {
 func (this *AnsiJoin) String() string {
 	s := this.left.String()
 
-	if this.outer {
+	if this.semi && this.outer {
+		s += " anti join "
+	} else if this.semi {
+		s += " semi join "
+	} else if this.outer && this.rightOuter {
+		s += " full outer join "
+	} else if this.rightOuter {
+		s += " right outer join "
+	} else if this.outer {
 		s += " left outer join "
 	} else {
 		s += " join "
@@ -182,6 +231,30 @@ func (this *AnsiJoin) Outer() bool {
 	return this.outer
 }
 
+/*
+Returns whether the unmatched rows of the right source
+are preserved, as in RIGHT and FULL OUTER JOIN.
+*/
+func (this *AnsiJoin) RightOuter() bool {
+	return this.rightOuter
+}
+
+/*
+Returns whether only the rows of the left source are returned, as in
+a semi-join or anti-join.
+*/
+func (this *AnsiJoin) Semi() bool {
+	return this.semi
+}
+
+/*
+Returns whether this is an anti-join, returning the rows of the left
+source not matched by any row of the right source.
+*/
+func (this *AnsiJoin) Anti() bool {
+	return this.semi && this.outer
+}
+
 /*
 Returns ON-clause of ANSI JOIN
 */
@@ -189,6 +262,20 @@ func (this *AnsiJoin) Onclause() expression.Expression {
 	return this.onclause
 }
 
+/*
+Set the left source object
+*/
+func (this *AnsiJoin) SetLeft(left FromTerm) {
+	this.left = left
+}
+
+/*
+Set the right source object, as when the planner expands views
+*/
+func (this *AnsiJoin) SetRight(right SimpleFromTerm) {
+	this.right = right
+}
+
 /*
 Set outer
 */
@@ -196,6 +283,13 @@ func (this *AnsiJoin) SetOuter(outer bool) {
 	this.outer = outer
 }
 
+/*
+Set right outer
+*/
+func (this *AnsiJoin) SetRightOuter(rightOuter bool) {
+	this.rightOuter = rightOuter
+}
+
 /*
 Set ON-clause
 */
@@ -211,6 +305,12 @@ func (this *AnsiJoin) MarshalJSON() ([]byte, error) {
 	r["left"] = this.left
 	r["right"] = this.right
 	r["outer"] = this.outer
+	if this.rightOuter {
+		r["right_outer"] = this.rightOuter
+	}
+	if this.semi {
+		r["semi"] = this.semi
+	}
 	r["onclause"] = this.onclause
 	return json.Marshal(r)
 }
@@ -163,6 +163,13 @@ func (this *IndexJoin) Right() *KeyspaceTerm {
 	return this.right
 }
 
+/*
+Set the left source object, as when the planner expands views
+*/
+func (this *IndexJoin) SetLeft(left FromTerm) {
+	this.left = left
+}
+
 func (this *IndexJoin) Outer() bool {
 	return this.outer
 }
@@ -57,6 +57,15 @@ func NewKeyspaceTerm(namespace, keyspace string, as string,
 	return &KeyspaceTerm{namespace, keyspace, as, keys, indexes, nil, JOIN_HINT_NONE, 0}
 }
 
+/*
+Returns a copy of the term, which the planner can change without
+changing the statement. Expressions are shared.
+*/
+func (this *KeyspaceTerm) Copy() *KeyspaceTerm {
+	rv := *this
+	return &rv
+}
+
 func (this *KeyspaceTerm) Accept(visitor NodeVisitor) (interface{}, error) {
 	return visitor.VisitKeyspaceTerm(this)
 }
@@ -116,7 +125,7 @@ func privilegesFromKeyspace(namespace, keyspace string) (*auth.Privileges, error
 	fullKeyspace := namespace + ":" + keyspace
 	if namespace == "#system" {
 		switch keyspace {
-		case "user_info", "applicable_roles":
+		case "user_info", "applicable_roles", "audit":
 			privs.Add(fullKeyspace, auth.PRIV_SECURITY_READ)
 		case "keyspaces", "indexes", "my_user_info":
 			// Do nothing. These tables handle security internally, by
@@ -295,6 +304,13 @@ func (this *KeyspaceTerm) Indexes() IndexRefs {
 	return this.indexes
 }
 
+/*
+Set the indexes, as from an INDEX optimizer hint.
+*/
+func (this *KeyspaceTerm) SetIndexes(indexes IndexRefs) {
+	this.indexes = indexes
+}
+
 /*
 Returns the join keys expression defined by the ON KEYS
 or ON KEY ... FOR ... clause.
@@ -412,6 +428,13 @@ func (this *KeyspaceTerm) SetAnsiJoin() {
 	this.property |= TERM_ANSI_JOIN
 }
 
+/*
+Unset ANSI JOIN property, when the term becomes the primary term
+*/
+func (this *KeyspaceTerm) UnsetAnsiJoin() {
+	this.property &^= TERM_ANSI_JOIN
+}
+
 /*
 Set ANSI NEST property
 */
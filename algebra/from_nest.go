@@ -154,6 +154,13 @@ func (this *Nest) Right() *KeyspaceTerm {
 	return this.right
 }
 
+/*
+Set the left source object, as when the planner expands views
+*/
+func (this *Nest) SetLeft(left FromTerm) {
+	this.left = left
+}
+
 /*
 Returns a boolean value depending on if it is
 an outer or inner NEST.
@@ -157,6 +157,20 @@ func (this *AnsiNest) Right() SimpleFromTerm {
 	return this.right
 }
 
+/*
+Set the left source object, as when the planner expands views
+*/
+func (this *AnsiNest) SetLeft(left FromTerm) {
+	this.left = left
+}
+
+/*
+Set the right source object, as when the planner expands views
+*/
+func (this *AnsiNest) SetRight(right SimpleFromTerm) {
+	this.right = right
+}
+
 /*
 Returns boolean value based on if it is
 an outer or inner NEST.
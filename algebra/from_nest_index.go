@@ -166,6 +166,13 @@ func (this *IndexNest) Right() *KeyspaceTerm {
 	return this.right
 }
 
+/*
+Set the left source object, as when the planner expands views
+*/
+func (this *IndexNest) SetLeft(left FromTerm) {
+	this.left = left
+}
+
 func (this *IndexNest) Outer() bool {
 	return this.outer
 }
@@ -152,6 +152,13 @@ func (this *Unnest) Left() FromTerm {
 	return this.left
 }
 
+/*
+Set the left source object, as when the planner expands views
+*/
+func (this *Unnest) SetLeft(left FromTerm) {
+	this.left = left
+}
+
 /*
 Returns the source array object path expression for
 the UNNEST clause.
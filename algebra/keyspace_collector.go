@@ -0,0 +1,331 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"sort"
+
+	"github.com/couchbase/query/expression"
+)
+
+/*
+Returns the fully qualified names of all the keyspaces referenced by
+a statement, including the ones referenced by subqueries, in sorted
+order. Keyspaces with no explicit namespace are qualified with the
+namespace provided.
+*/
+func ReferencedKeyspaces(stmt Statement, namespace string) ([]string, error) {
+	collector := newKeyspaceCollector(namespace)
+	_, err := stmt.Accept(collector)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]string, 0, len(collector.keyspaces))
+	for keyspace, _ := range collector.keyspaces {
+		rv = append(rv, keyspace)
+	}
+	sort.Strings(rv)
+	return rv, nil
+}
+
+/*
+keyspaceCollector is a Visitor and NodeVisitor that gathers keyspace
+references from FROM clauses, DML targets and DDL targets.
+*/
+type keyspaceCollector struct {
+	namespace string
+	keyspaces map[string]bool
+}
+
+func newKeyspaceCollector(namespace string) *keyspaceCollector {
+	return &keyspaceCollector{
+		namespace: namespace,
+		keyspaces: make(map[string]bool, 4),
+	}
+}
+
+func (this *keyspaceCollector) add(namespace, keyspace string) {
+	if keyspace == "" {
+		return
+	}
+	if namespace == "" {
+		namespace = this.namespace
+	}
+	this.keyspaces[namespace+":"+keyspace] = true
+}
+
+func (this *keyspaceCollector) addRef(ref *KeyspaceRef) {
+	if ref != nil {
+		this.add(ref.Namespace(), ref.Keyspace())
+	}
+}
+
+/*
+Collect the keyspaces referenced by subqueries in the expressions.
+Nested subqueries are listed as well, so only their FROM clauses
+need to be visited.
+*/
+func (this *keyspaceCollector) visitExpressions(exprs expression.Expressions) error {
+	subqueries, err := expression.ListSubqueries(exprs, true)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range subqueries {
+		sub, ok := s.(*Subquery)
+		if !ok {
+			continue
+		}
+		_, err = sub.Select().Subresult().Accept(this)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (this *keyspaceCollector) visitSelect(stmt *Select) error {
+	if stmt == nil {
+		return nil
+	}
+
+	_, err := stmt.Subresult().Accept(this)
+	if err != nil {
+		return err
+	}
+	return this.visitExpressions(stmt.Expressions())
+}
+
+// Statements
+
+func (this *keyspaceCollector) VisitSelect(stmt *Select) (interface{}, error) {
+	return nil, this.visitSelect(stmt)
+}
+
+func (this *keyspaceCollector) VisitInsert(stmt *Insert) (interface{}, error) {
+	this.addRef(stmt.KeyspaceRef())
+	err := this.visitSelect(stmt.Select())
+	if err != nil {
+		return nil, err
+	}
+	return nil, this.visitExpressions(stmt.Expressions())
+}
+
+func (this *keyspaceCollector) VisitUpsert(stmt *Upsert) (interface{}, error) {
+	this.addRef(stmt.KeyspaceRef())
+	err := this.visitSelect(stmt.Select())
+	if err != nil {
+		return nil, err
+	}
+	return nil, this.visitExpressions(stmt.Expressions())
+}
+
+func (this *keyspaceCollector) VisitDelete(stmt *Delete) (interface{}, error) {
+	this.addRef(stmt.KeyspaceRef())
+	return nil, this.visitExpressions(stmt.Expressions())
+}
+
+func (this *keyspaceCollector) VisitUpdate(stmt *Update) (interface{}, error) {
+	this.addRef(stmt.KeyspaceRef())
+	return nil, this.visitExpressions(stmt.Expressions())
+}
+
+func (this *keyspaceCollector) VisitMerge(stmt *Merge) (interface{}, error) {
+	this.addRef(stmt.KeyspaceRef())
+
+	source := stmt.Source()
+	var err error
+	if source.SubqueryTerm() != nil {
+		_, err = source.SubqueryTerm().Accept(this)
+	} else if source.ExpressionTerm() != nil {
+		_, err = source.ExpressionTerm().Accept(this)
+	} else if source.From() != nil {
+		_, err = source.From().Accept(this)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, this.visitExpressions(stmt.Expressions())
+}
+
+func (this *keyspaceCollector) VisitCreatePrimaryIndex(stmt *CreatePrimaryIndex) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitCreateIndex(stmt *CreateIndex) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitDropIndex(stmt *DropIndex) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitAlterIndex(stmt *AlterIndex) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitBuildIndexes(stmt *BuildIndexes) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitCreateMaterializedView(stmt *CreateMaterializedView) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, this.visitSelect(stmt.Query())
+}
+
+func (this *keyspaceCollector) VisitDropMaterializedView(stmt *DropMaterializedView) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitRefreshMaterializedView(stmt *RefreshMaterializedView) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitCreateView(stmt *CreateView) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, this.visitSelect(stmt.Query())
+}
+
+func (this *keyspaceCollector) VisitDropView(stmt *DropView) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitGrantRole(stmt *GrantRole) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitRevokeRole(stmt *RevokeRole) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitExplain(stmt *Explain) (interface{}, error) {
+	return stmt.Statement().Accept(this)
+}
+
+func (this *keyspaceCollector) VisitPrepare(stmt *Prepare) (interface{}, error) {
+	return stmt.Statement().Accept(this)
+}
+
+func (this *keyspaceCollector) VisitExecute(stmt *Execute) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitInferKeyspace(stmt *InferKeyspace) (interface{}, error) {
+	this.addRef(stmt.Keyspace())
+	return nil, nil
+}
+
+// Nodes
+
+func (this *keyspaceCollector) VisitSelectTerm(node *SelectTerm) (interface{}, error) {
+	return nil, this.visitSelect(node.Select())
+}
+
+func (this *keyspaceCollector) VisitSubselect(node *Subselect) (interface{}, error) {
+	if node.From() != nil {
+		return node.From().Accept(this)
+	}
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitKeyspaceTerm(node *KeyspaceTerm) (interface{}, error) {
+	this.add(node.Namespace(), node.Keyspace())
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitExpressionTerm(node *ExpressionTerm) (interface{}, error) {
+	if node.IsKeyspace() {
+		return node.KeyspaceTerm().Accept(this)
+	}
+	return nil, nil
+}
+
+func (this *keyspaceCollector) VisitSubqueryTerm(node *SubqueryTerm) (interface{}, error) {
+	return nil, this.visitSelect(node.Subquery())
+}
+
+func (this *keyspaceCollector) visitJoin(left, right FromTerm) (interface{}, error) {
+	_, err := left.Accept(this)
+	if err != nil {
+		return nil, err
+	}
+	return right.Accept(this)
+}
+
+func (this *keyspaceCollector) VisitJoin(node *Join) (interface{}, error) {
+	return this.visitJoin(node.Left(), node.Right())
+}
+
+func (this *keyspaceCollector) VisitIndexJoin(node *IndexJoin) (interface{}, error) {
+	return this.visitJoin(node.Left(), node.Right())
+}
+
+func (this *keyspaceCollector) VisitAnsiJoin(node *AnsiJoin) (interface{}, error) {
+	return this.visitJoin(node.Left(), node.Right())
+}
+
+func (this *keyspaceCollector) VisitNest(node *Nest) (interface{}, error) {
+	return this.visitJoin(node.Left(), node.Right())
+}
+
+func (this *keyspaceCollector) VisitIndexNest(node *IndexNest) (interface{}, error) {
+	return this.visitJoin(node.Left(), node.Right())
+}
+
+func (this *keyspaceCollector) VisitAnsiNest(node *AnsiNest) (interface{}, error) {
+	return this.visitJoin(node.Left(), node.Right())
+}
+
+func (this *keyspaceCollector) VisitUnnest(node *Unnest) (interface{}, error) {
+	return node.Left().Accept(this)
+}
+
+func (this *keyspaceCollector) visitSetOp(first, second Subresult) (interface{}, error) {
+	_, err := first.Accept(this)
+	if err != nil {
+		return nil, err
+	}
+	return second.Accept(this)
+}
+
+func (this *keyspaceCollector) VisitUnion(node *Union) (interface{}, error) {
+	return this.visitSetOp(node.First(), node.Second())
+}
+
+func (this *keyspaceCollector) VisitUnionAll(node *UnionAll) (interface{}, error) {
+	return this.visitSetOp(node.First(), node.Second())
+}
+
+func (this *keyspaceCollector) VisitIntersect(node *Intersect) (interface{}, error) {
+	return this.visitSetOp(node.First(), node.Second())
+}
+
+func (this *keyspaceCollector) VisitIntersectAll(node *IntersectAll) (interface{}, error) {
+	return this.visitSetOp(node.First(), node.Second())
+}
+
+func (this *keyspaceCollector) VisitExcept(node *Except) (interface{}, error) {
+	return this.visitSetOp(node.First(), node.Second())
+}
+
+func (this *keyspaceCollector) VisitExceptAll(node *ExceptAll) (interface{}, error) {
+	return this.visitSetOp(node.First(), node.Second())
+}
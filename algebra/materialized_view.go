@@ -0,0 +1,232 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the CREATE MATERIALIZED VIEW statement. The results of the
+query are stored in the keyspace named by the view, which must exist.
+The text of the query is kept, as the view's definition.
+*/
+type CreateMaterializedView struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	query    *Select      `json:"query"`
+	text     string       `json:"text"`
+}
+
+func NewCreateMaterializedView(keyspace *KeyspaceRef, query *Select, text string) *CreateMaterializedView {
+	rv := &CreateMaterializedView{
+		keyspace: keyspace,
+		query:    query,
+		text:     text,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+func (this *CreateMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateMaterializedView(this)
+}
+
+func (this *CreateMaterializedView) Signature() value.Value {
+	return nil
+}
+
+func (this *CreateMaterializedView) Formalize() error {
+	return this.query.Formalize()
+}
+
+func (this *CreateMaterializedView) MapExpressions(mapper expression.Mapper) error {
+	return this.query.MapExpressions(mapper)
+}
+
+func (this *CreateMaterializedView) Expressions() expression.Expressions {
+	return this.query.Expressions()
+}
+
+/*
+Returns all required privileges: those of the query, and the ability
+to write, and list, the documents of the view's keyspace.
+*/
+func (this *CreateMaterializedView) Privileges() (*auth.Privileges, errors.Error) {
+	privs, err := this.query.Privileges()
+	if err != nil {
+		return nil, err
+	}
+	privs.AddAll(materializedViewPrivileges(this.keyspace))
+	return privs, nil
+}
+
+func (this *CreateMaterializedView) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+func (this *CreateMaterializedView) Query() *Select {
+	return this.query
+}
+
+/*
+Returns the definition of the view, as written.
+*/
+func (this *CreateMaterializedView) Text() string {
+	return this.text
+}
+
+func (this *CreateMaterializedView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createMaterializedView"}
+	r["keyspaceRef"] = this.keyspace
+	r["definition"] = this.text
+	return json.Marshal(r)
+}
+
+func (this *CreateMaterializedView) Type() string {
+	return "CREATE_MATERIALIZED_VIEW"
+}
+
+/*
+Represents the DROP MATERIALIZED VIEW statement. The view's keyspace,
+and the results in it, are left as they are.
+*/
+type DropMaterializedView struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+}
+
+func NewDropMaterializedView(keyspace *KeyspaceRef) *DropMaterializedView {
+	rv := &DropMaterializedView{
+		keyspace: keyspace,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+func (this *DropMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropMaterializedView(this)
+}
+
+func (this *DropMaterializedView) Signature() value.Value {
+	return nil
+}
+
+func (this *DropMaterializedView) Formalize() error {
+	return nil
+}
+
+func (this *DropMaterializedView) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+func (this *DropMaterializedView) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges.
+*/
+func (this *DropMaterializedView) Privileges() (*auth.Privileges, errors.Error) {
+	return materializedViewPrivileges(this.keyspace), nil
+}
+
+func (this *DropMaterializedView) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+func (this *DropMaterializedView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "dropMaterializedView"}
+	r["keyspaceRef"] = this.keyspace
+	return json.Marshal(r)
+}
+
+func (this *DropMaterializedView) Type() string {
+	return "DROP_MATERIALIZED_VIEW"
+}
+
+/*
+Represents the REFRESH MATERIALIZED VIEW statement, which recomputes
+the results of the view from scratch.
+*/
+type RefreshMaterializedView struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+}
+
+func NewRefreshMaterializedView(keyspace *KeyspaceRef) *RefreshMaterializedView {
+	rv := &RefreshMaterializedView{
+		keyspace: keyspace,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+func (this *RefreshMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitRefreshMaterializedView(this)
+}
+
+func (this *RefreshMaterializedView) Signature() value.Value {
+	return nil
+}
+
+func (this *RefreshMaterializedView) Formalize() error {
+	return nil
+}
+
+func (this *RefreshMaterializedView) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+func (this *RefreshMaterializedView) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges. The definition is run as it was
+created: refreshing a view only requires access to the view's keyspace.
+*/
+func (this *RefreshMaterializedView) Privileges() (*auth.Privileges, errors.Error) {
+	return materializedViewPrivileges(this.keyspace), nil
+}
+
+func (this *RefreshMaterializedView) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+func (this *RefreshMaterializedView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "refreshMaterializedView"}
+	r["keyspaceRef"] = this.keyspace
+	return json.Marshal(r)
+}
+
+func (this *RefreshMaterializedView) Type() string {
+	return "REFRESH_MATERIALIZED_VIEW"
+}
+
+func materializedViewPrivileges(keyspace *KeyspaceRef) *auth.Privileges {
+	privs := auth.NewPrivileges()
+	fullName := keyspace.FullName()
+	privs.Add(fullName, auth.PRIV_QUERY_SELECT)
+	privs.Add(fullName, auth.PRIV_QUERY_INSERT)
+	privs.Add(fullName, auth.PRIV_QUERY_DELETE)
+	return privs
+}
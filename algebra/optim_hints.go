@@ -0,0 +1,339 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Optimizer hints, given in a comment following SELECT, UPDATE or
+DELETE, such as
+
+	SELECT /*+ INDEX(t idx1) NO_INDEX(t idx2) USE_HASH(u/BUILD) ORDERED
+	           MAX_PARALLELISM(8) NO_COVER * / ...
+
+RESULT_CACHE, or RESULT_CACHE(30s) with a time to live, asks for the
+results of the statement to be cached.
+
+The hints are applied by the planner, which records for each hint
+whether it was followed, or why it was ignored; unknown hints are
+ignored, like any other comment.
+*/
+type OptimHints struct {
+	text  string
+	hints []*OptimHint
+}
+
+/*
+A hint is a name, optionally followed by parenthesized arguments.
+*/
+var _OPTIM_HINT = regexp.MustCompile(`[A-Za-z_][A-Za-z_0-9]*(\s*\([^)]*\))?`)
+
+var _OPTIM_HINT_ARGS = regexp.MustCompile("[\\s,]+")
+
+/*
+Parse the text of a hint comment, including the enclosing /*+ and * /.
+*/
+func NewOptimHints(text string) *OptimHints {
+	body := strings.TrimSuffix(strings.TrimPrefix(text, "/*+"), "*/")
+	rv := &OptimHints{
+		text: strings.TrimSpace(body),
+	}
+
+	for _, hint := range _OPTIM_HINT.FindAllString(body, -1) {
+		rv.hints = append(rv.hints, newOptimHint(hint))
+	}
+
+	return rv
+}
+
+/*
+Join the FROM terms in the order they are written.
+*/
+func (this *OptimHints) Ordered() bool {
+	if this == nil {
+		return false
+	}
+
+	for _, hint := range this.hints {
+		if hint.name == HINT_ORDERED && hint.state != HINT_STATE_IGNORED {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Returns the hints, in the order written.
+*/
+func (this *OptimHints) Hints() []*OptimHint {
+	if this == nil {
+		return nil
+	}
+
+	return this.hints
+}
+
+func (this *OptimHints) String() string {
+	return "/*+ " + this.text + " */"
+}
+
+func (this *OptimHints) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.hints)
+}
+
+const (
+	HINT_INDEX           = "INDEX"
+	HINT_NO_INDEX        = "NO_INDEX"
+	HINT_USE_HASH        = "USE_HASH"
+	HINT_USE_NL          = "USE_NL"
+	HINT_ORDERED         = "ORDERED"
+	HINT_MAX_PARALLELISM = "MAX_PARALLELISM"
+	HINT_NO_COVER        = "NO_COVER"
+	HINT_RESULT_CACHE    = "RESULT_CACHE"
+)
+
+type HintState int
+
+const (
+	HINT_STATE_UNKNOWN HintState = iota
+	HINT_STATE_FOLLOWED
+	HINT_STATE_IGNORED
+)
+
+/*
+A single optimizer hint, such as INDEX(t idx1 idx2). The keyspace of
+USE_HASH may be followed by /BUILD or /PROBE.
+*/
+type OptimHint struct {
+	text   string
+	name   string
+	args   []string
+	state  HintState
+	reason string
+}
+
+func newOptimHint(text string) *OptimHint {
+	rv := &OptimHint{
+		text: text,
+	}
+
+	name := text
+	if paren := strings.IndexByte(text, '('); paren >= 0 {
+		name = text[:paren]
+		args := strings.TrimSuffix(strings.TrimSpace(text[paren+1:]), ")")
+		for _, arg := range _OPTIM_HINT_ARGS.Split(args, -1) {
+			arg = strings.Trim(arg, "`")
+			if arg != "" {
+				rv.args = append(rv.args, arg)
+			}
+		}
+	}
+	rv.name = strings.ToUpper(strings.TrimSpace(name))
+
+	nargs := len(rv.args)
+	switch rv.name {
+	case HINT_INDEX, HINT_NO_INDEX:
+		if nargs < 2 {
+			rv.Ignore("expected a keyspace and one or more indexes")
+		}
+	case HINT_USE_HASH:
+		if nargs != 1 {
+			rv.Ignore("expected a keyspace")
+		} else if option := rv.Option(); option != "" && option != "BUILD" && option != "PROBE" {
+			rv.Ignore("expected BUILD or PROBE after " + rv.Keyspace())
+		}
+	case HINT_USE_NL:
+		if nargs != 1 || rv.Option() != "" {
+			rv.Ignore("expected a keyspace")
+		}
+	case HINT_ORDERED, HINT_NO_COVER:
+		if nargs != 0 {
+			rv.Ignore("unexpected arguments")
+		}
+	case HINT_MAX_PARALLELISM:
+		if nargs != 1 || rv.Value() < 1 {
+			rv.Ignore("expected a positive integer")
+		}
+	case HINT_RESULT_CACHE:
+		if nargs > 1 || (nargs == 1 && rv.Duration() <= 0) {
+			rv.Ignore("expected a positive duration")
+		}
+	default:
+		rv.Ignore("unknown hint")
+	}
+
+	return rv
+}
+
+/*
+Returns the hint name, in upper case.
+*/
+func (this *OptimHint) Name() string {
+	return this.name
+}
+
+/*
+Returns the keyspace alias the hint applies to.
+*/
+func (this *OptimHint) Keyspace() string {
+	if len(this.args) == 0 {
+		return ""
+	}
+
+	if slash := strings.IndexByte(this.args[0], '/'); slash >= 0 {
+		return this.args[0][:slash]
+	}
+	return this.args[0]
+}
+
+/*
+Returns the option following the keyspace, as in USE_HASH(t/BUILD).
+*/
+func (this *OptimHint) Option() string {
+	if len(this.args) == 0 {
+		return ""
+	}
+
+	if slash := strings.IndexByte(this.args[0], '/'); slash >= 0 {
+		return strings.ToUpper(this.args[0][slash+1:])
+	}
+	return ""
+}
+
+/*
+Returns the index names of INDEX and NO_INDEX.
+*/
+func (this *OptimHint) Indexes() []string {
+	if len(this.args) < 2 {
+		return nil
+	}
+
+	return this.args[1:]
+}
+
+/*
+Returns the value of MAX_PARALLELISM, or 0 if invalid.
+*/
+func (this *OptimHint) Value() int {
+	if len(this.args) != 1 {
+		return 0
+	}
+
+	n, err := strconv.Atoi(this.args[0])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+/*
+Returns the time to live of RESULT_CACHE, such as 30s, or 0 if not
+given or invalid.
+*/
+func (this *OptimHint) Duration() time.Duration {
+	if len(this.args) != 1 {
+		return 0
+	}
+
+	d, err := time.ParseDuration(this.args[0])
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+/*
+A copy of the hint, whose state can be recorded without changing the
+statement.
+*/
+func (this *OptimHint) Copy() *OptimHint {
+	rv := *this
+	return &rv
+}
+
+func (this *OptimHint) State() HintState {
+	return this.state
+}
+
+func (this *OptimHint) Reason() string {
+	return this.reason
+}
+
+/*
+Record that the planner followed the hint.
+*/
+func (this *OptimHint) Follow() {
+	if this.state == HINT_STATE_UNKNOWN {
+		this.state = HINT_STATE_FOLLOWED
+	}
+}
+
+/*
+Record that the hint was ignored, and why. The first reason given is
+kept.
+*/
+func (this *OptimHint) Ignore(reason string) {
+	if this.state != HINT_STATE_IGNORED {
+		this.state = HINT_STATE_IGNORED
+		this.reason = reason
+	}
+}
+
+func (this *OptimHint) String() string {
+	return this.text
+}
+
+func (this *OptimHint) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"hint": this.text}
+	switch this.state {
+	case HINT_STATE_FOLLOWED:
+		r["state"] = "followed"
+	case HINT_STATE_IGNORED:
+		r["state"] = "ignored"
+		r["reason"] = this.reason
+	default:
+		r["state"] = "not used"
+	}
+	return json.Marshal(r)
+}
+
+func (this *OptimHint) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		Hint   string `json:"hint"`
+		State  string `json:"state"`
+		Reason string `json:"reason"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	*this = *newOptimHint(_unmarshalled.Hint)
+	switch _unmarshalled.State {
+	case "followed":
+		this.state = HINT_STATE_FOLLOWED
+		this.reason = ""
+	case "ignored":
+		this.state = HINT_STATE_IGNORED
+		this.reason = _unmarshalled.Reason
+	default:
+		this.state = HINT_STATE_UNKNOWN
+		this.reason = ""
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOptimHints(t *testing.T) {
+	hints := NewOptimHints("/*+ INDEX(t idx1, `idx2`) USE_HASH(u/probe) ORDERED MAX_PARALLELISM(8) NO_COVER" +
+		" NO_INDEX(t) USE_HASH(u/sideways) MAX_PARALLELISM(0) FOO */").Hints()
+	if len(hints) != 9 {
+		t.Fatalf("expected 9 hints, received %d", len(hints))
+	}
+
+	index := hints[0]
+	if index.Name() != HINT_INDEX || index.Keyspace() != "t" || len(index.Indexes()) != 2 ||
+		index.Indexes()[1] != "idx2" || index.State() != HINT_STATE_UNKNOWN {
+		t.Errorf("unexpected INDEX hint %v: %v %v", index, index.Keyspace(), index.Indexes())
+	}
+
+	if hints[1].Keyspace() != "u" || hints[1].Option() != "PROBE" || hints[1].State() != HINT_STATE_UNKNOWN {
+		t.Errorf("unexpected USE_HASH hint %v", hints[1])
+	}
+
+	if hints[3].Value() != 8 {
+		t.Errorf("expected MAX_PARALLELISM of 8, received %d", hints[3].Value())
+	}
+
+	for _, hint := range hints[5:] {
+		if hint.State() != HINT_STATE_IGNORED || hint.Reason() == "" {
+			t.Errorf("expected %v to be ignored", hint)
+		}
+	}
+
+	hints = NewOptimHints("/*+ RESULT_CACHE(30s) RESULT_CACHE(soon) */").Hints()
+	if hints[0].Duration() != 30*time.Second || hints[0].State() != HINT_STATE_UNKNOWN {
+		t.Errorf("unexpected RESULT_CACHE hint %v: %v", hints[0], hints[0].Duration())
+	}
+	if hints[1].State() != HINT_STATE_IGNORED {
+		t.Errorf("expected %v to be ignored", hints[1])
+	}
+
+	index.Follow()
+	index.Ignore("not usable")
+	index.Follow()
+	if index.State() != HINT_STATE_IGNORED || index.Reason() != "not usable" {
+		t.Errorf("expected INDEX hint to be ignored, received state %d", index.State())
+	}
+}
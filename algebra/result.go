@@ -91,6 +91,23 @@ func (this *Projection) Signature() value.Value {
 	return rv
 }
 
+/*
+Returns a copy of the projection, with copies of its terms and their
+expressions, which the planner can map without changing the statement.
+*/
+func (this *Projection) Copy() *Projection {
+	rv := *this
+	rv.terms = make(ResultTerms, len(this.terms))
+	for i, term := range this.terms {
+		t := *term
+		if t.expr != nil {
+			t.expr = t.expr.Copy()
+		}
+		rv.terms[i] = &t
+	}
+	return &rv
+}
+
 /*
 This method maps the result expressions.
 */
@@ -31,6 +31,7 @@ type Subselect struct {
 	group      *Group                `json:"group"`
 	projection *Projection           `json:"projection"`
 	correlated bool                  `json:"correlated"`
+	optimHints *OptimHints           `json:"optimizer_hints"`
 }
 
 /*
@@ -38,7 +39,16 @@ Constructor.
 */
 func NewSubselect(with expression.Bindings, from FromTerm, let expression.Bindings,
 	where expression.Expression, group *Group, projection *Projection) *Subselect {
-	return &Subselect{with, from, let, where, group, projection, false}
+	return &Subselect{with, from, let, where, group, projection, false, nil}
+}
+
+/*
+Returns a copy of the subselect, whose clauses the planner can replace
+without changing the statement. The clauses are shared.
+*/
+func (this *Subselect) Copy() *Subselect {
+	rv := *this
+	return &rv
 }
 
 /*
@@ -241,7 +251,13 @@ func (this *Subselect) Privileges() (*auth.Privileges, errors.Error) {
    Representation as a N1QL string.
 */
 func (this *Subselect) String() string {
-	s := "select " + this.projection.String()
+	s := "select "
+
+	if this.optimHints != nil {
+		s += this.optimHints.String() + " "
+	}
+
+	s += this.projection.String()
 
 	if this.from != nil {
 		s += " from " + this.from.String()
@@ -286,6 +302,24 @@ func (this *Subselect) From() FromTerm {
 	return this.from
 }
 
+/*
+Replaces the FROM clause, as when the planner reorders joins.
+*/
+func (this *Subselect) SetFrom(from FromTerm) {
+	this.from = from
+}
+
+/*
+Returns the optimizer hints following SELECT, if any.
+*/
+func (this *Subselect) OptimHints() *OptimHints {
+	return this.optimHints
+}
+
+func (this *Subselect) SetOptimHints(optimHints *OptimHints) {
+	this.optimHints = optimHints
+}
+
 /*
 Returns the let field that represents the Let
 clause in the subselect statement.
@@ -302,6 +336,14 @@ func (this *Subselect) Where() expression.Expression {
 	return this.where
 }
 
+/*
+Replaces the WHERE clause, as when the planner rewrites subqueries
+as joins.
+*/
+func (this *Subselect) SetWhere(where expression.Expression) {
+	this.where = where
+}
+
 /*
 Returns the group field that represents the group by
 clause in the subselect statement.
@@ -318,6 +360,14 @@ func (this *Subselect) Projection() *Projection {
 	return this.projection
 }
 
+/*
+Replaces the projection, as when the planner rewrites subqueries
+as joins.
+*/
+func (this *Subselect) SetProjection(projection *Projection) {
+	this.projection = projection
+}
+
 /*
    Representation as a N1QL string.
 */
@@ -22,14 +22,15 @@ Represents the UPDATE statement.
 type Update struct {
 	statementBase
 
-	keyspace  *KeyspaceRef          `json:"keyspace"`
-	keys      expression.Expression `json:"keys"`
-	indexes   IndexRefs             `json:"indexes"`
-	set       *Set                  `json:"set"`
-	unset     *Unset                `json:"unset"`
-	where     expression.Expression `json:"where"`
-	limit     expression.Expression `json:"limit"`
-	returning *Projection           `json:"returning"`
+	keyspace   *KeyspaceRef          `json:"keyspace"`
+	keys       expression.Expression `json:"keys"`
+	indexes    IndexRefs             `json:"indexes"`
+	set        *Set                  `json:"set"`
+	unset      *Unset                `json:"unset"`
+	where      expression.Expression `json:"where"`
+	limit      expression.Expression `json:"limit"`
+	returning  *Projection           `json:"returning"`
+	optimHints *OptimHints           `json:"optimizer_hints"`
 }
 
 func NewUpdate(keyspace *KeyspaceRef, keys expression.Expression, indexes IndexRefs,
@@ -294,3 +295,14 @@ UPDATE statement.
 func (this *Update) Returning() *Projection {
 	return this.returning
 }
+
+/*
+Returns the optimizer hints following UPDATE, if any.
+*/
+func (this *Update) OptimHints() *OptimHints {
+	return this.optimHints
+}
+
+func (this *Update) SetOptimHints(optimHints *OptimHints) {
+	this.optimHints = optimHints
+}
@@ -0,0 +1,176 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package algebra
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents the CREATE VIEW statement. Only the text of the query is
+kept, as the view's definition: the view can then be used in FROM
+clauses, in place of a keyspace of the same name, which must not
+exist.
+*/
+type CreateView struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	query    *Select      `json:"query"`
+	text     string       `json:"text"`
+}
+
+func NewCreateView(keyspace *KeyspaceRef, query *Select, text string) *CreateView {
+	rv := &CreateView{
+		keyspace: keyspace,
+		query:    query,
+		text:     text,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+func (this *CreateView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateView(this)
+}
+
+func (this *CreateView) Signature() value.Value {
+	return nil
+}
+
+func (this *CreateView) Formalize() error {
+	return this.query.Formalize()
+}
+
+func (this *CreateView) MapExpressions(mapper expression.Mapper) error {
+	return this.query.MapExpressions(mapper)
+}
+
+func (this *CreateView) Expressions() expression.Expressions {
+	return this.query.Expressions()
+}
+
+/*
+Returns all required privileges: those of the query, so that views
+can only be defined over documents their creator can read.
+*/
+func (this *CreateView) Privileges() (*auth.Privileges, errors.Error) {
+	return this.query.Privileges()
+}
+
+/*
+Returns the name of the view.
+*/
+func (this *CreateView) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+func (this *CreateView) Query() *Select {
+	return this.query
+}
+
+/*
+Returns the definition of the view, as written.
+*/
+func (this *CreateView) Text() string {
+	return this.text
+}
+
+func (this *CreateView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "createView"}
+	r["keyspaceRef"] = this.keyspace
+	r["definition"] = this.text
+	return json.Marshal(r)
+}
+
+func (this *CreateView) Type() string {
+	return "CREATE_VIEW"
+}
+
+/*
+Represents the DROP VIEW statement.
+*/
+type DropView struct {
+	statementBase
+
+	keyspace *KeyspaceRef `json:"keyspace"`
+	query    *Select      `json:"query"`
+}
+
+func NewDropView(keyspace *KeyspaceRef) *DropView {
+	rv := &DropView{
+		keyspace: keyspace,
+	}
+
+	rv.stmt = rv
+	return rv
+}
+
+func (this *DropView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropView(this)
+}
+
+func (this *DropView) Signature() value.Value {
+	return nil
+}
+
+func (this *DropView) Formalize() error {
+	return nil
+}
+
+func (this *DropView) MapExpressions(mapper expression.Mapper) error {
+	return nil
+}
+
+func (this *DropView) Expressions() expression.Expressions {
+	return nil
+}
+
+/*
+Returns all required privileges: those of the view's definition, once
+the planner has set it, so that a view can only be dropped by those
+who could have created it.
+*/
+func (this *DropView) Privileges() (*auth.Privileges, errors.Error) {
+	if this.query == nil {
+		return auth.NewPrivileges(), nil
+	}
+	return this.query.Privileges()
+}
+
+/*
+Returns the name of the view.
+*/
+func (this *DropView) Keyspace() *KeyspaceRef {
+	return this.keyspace
+}
+
+/*
+Set the definition of the view dropped.
+*/
+func (this *DropView) SetQuery(query *Select) {
+	this.query = query
+}
+
+func (this *DropView) MarshalJSON() ([]byte, error) {
+	r := map[string]interface{}{"type": "dropView"}
+	r["keyspaceRef"] = this.keyspace
+	return json.Marshal(r)
+}
+
+func (this *DropView) Type() string {
+	return "DROP_VIEW"
+}
@@ -37,6 +37,19 @@ type Visitor interface {
 	VisitAlterIndex(stmt *AlterIndex) (interface{}, error)
 	VisitBuildIndexes(stmt *BuildIndexes) (interface{}, error)
 
+	/*
+	   Visitor for materialized view statements.
+	*/
+	VisitCreateMaterializedView(stmt *CreateMaterializedView) (interface{}, error)
+	VisitDropMaterializedView(stmt *DropMaterializedView) (interface{}, error)
+	VisitRefreshMaterializedView(stmt *RefreshMaterializedView) (interface{}, error)
+
+	/*
+	   Visitor for view statements.
+	*/
+	VisitCreateView(stmt *CreateView) (interface{}, error)
+	VisitDropView(stmt *DropView) (interface{}, error)
+
 	/*
 	   Visitor for ROLES statements.
 	*/
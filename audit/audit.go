@@ -103,8 +103,9 @@ type ApiAuditFields struct {
 }
 
 // An auditor is a component that can accept an audit record for processing.
-// We create a formal interface, so we can have two Auditors: the regular one that
-// talks to the audit daemon, and a mock that just stores audit records for testing.
+// We create a formal interface, so we can have several Auditors: the regular one that
+// talks to the audit daemon, a local one that writes audit records to a file, and a
+// mock that just stores audit records for testing.
 // The mock is over in the test file.
 type Auditor interface {
 	auditInfo() *datastore.AuditInfo
@@ -162,10 +163,10 @@ var _AUDITOR Auditor
 // accessing the audit functionality. It is NOT the number of worker threads
 // the audit system itself has.
 func StartAuditService(server string, numServicers int) {
-	// No support for auditing?
-	// Set auditor to NIL for no auditing work at all.
+	// No support for the audit daemon?
+	// Audit to a local file instead, once one is configured.
 	if !VERSION_SUPPORTS_AUDIT {
-		_AUDITOR = nil
+		_AUDITOR = newFileAuditor(numServicers)
 		return
 	}
 
@@ -440,6 +441,8 @@ const (
 	API_ADMIN_SETTINGS                   = 28700
 	API_ADMIN_CLUSTERS                   = 28701
 	API_ADMIN_COMPLETED_REQUESTS         = 28702
+	API_ADMIN_VALIDATIONS                = 28704
+	API_ADMIN_INDEXES_VALIDATIONS        = 28705
 )
 
 func SubmitApiRequest(event *ApiAuditFields) {
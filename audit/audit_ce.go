@@ -11,5 +11,5 @@
 
 package audit
 
-// Do not audit in CE version.
+// No audit daemon in CE version: audit records are written to a local file.
 var VERSION_SUPPORTS_AUDIT = false
@@ -0,0 +1,458 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	adt "github.com/couchbase/goutils/go-cbaudit"
+	"github.com/couchbase/query/accounting"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/util"
+)
+
+// The local audit file is rotated once it reaches _AUDIT_FILE_SIZE bytes,
+// and the last _AUDIT_FILE_BACKUPS rotated files are kept.
+const (
+	_AUDIT_FILE_SIZE    = 100 * 1024 * 1024
+	_AUDIT_FILE_BACKUPS = 10
+)
+
+const _CONFIGURATION_CHANGE = 28703
+
+// Unrecognized statements are audited under this event id.
+const _OTHER_STATEMENT = 28687
+
+// The local auditor writes audit records, one JSON object per line, to a
+// rotating file instead of sending them to the audit daemon.
+// Each record is the same as the daemon's, with the event id in the "id" field.
+// Auditing is enabled when a file is set, and the events to be audited are
+// filtered by event id or statement type, and by user, as configured through
+// the server settings.
+type fileAuditor struct {
+	auditRecordQueue chan auditQueueEntry
+
+	auditInfoLock sync.RWMutex // get read or write lock before modifying reference to
+	info          *datastore.AuditInfo
+	disabled      []interface{} // event ids and statement types, as set
+	whitelist     []string      // user names, as set
+
+	fileLock sync.RWMutex
+	file     *util.RotatingFile
+}
+
+func newFileAuditor(numServicers int) *fileAuditor {
+	auditor := &fileAuditor{
+		auditRecordQueue: make(chan auditQueueEntry, numServicers*25),
+		info: &datastore.AuditInfo{
+			EventDisabled:   map[uint32]bool{},
+			UserWhitelisted: map[datastore.UserInfo]bool{},
+		},
+		disabled:  []interface{}{},
+		whitelist: []string{},
+	}
+
+	// records are written by a single worker, so that they appear in the file
+	// in the order they are submitted
+	go fileAuditWorker(auditor, 1)
+	return auditor
+}
+
+func (fa *fileAuditor) auditInfo() *datastore.AuditInfo {
+	fa.auditInfoLock.RLock()
+	ret := fa.info
+	fa.auditInfoLock.RUnlock()
+	return ret
+}
+
+func (fa *fileAuditor) setAuditInfo(info *datastore.AuditInfo) {
+	fa.auditInfoLock.Lock()
+	fa.info = info
+	fa.auditInfoLock.Unlock()
+}
+
+func (fa *fileAuditor) submit(entry auditQueueEntry) {
+	// Put the audit entry on the queue for processing.
+	// If the queue is full, block until it clears.
+	fa.auditRecordQueue <- entry
+}
+
+func (fa *fileAuditor) fileName() string {
+	fa.fileLock.RLock()
+	defer fa.fileLock.RUnlock()
+	if fa.file == nil {
+		return ""
+	}
+	return fa.file.Name()
+}
+
+func (fa *fileAuditor) setFile(name string) errors.Error {
+	var file *util.RotatingFile
+
+	if name != "" {
+		var err error
+
+		file, err = util.NewRotatingFile(name, _AUDIT_FILE_SIZE, 0, _AUDIT_FILE_BACKUPS)
+		if err != nil {
+			return errors.NewAdminAuditError(err, "unable to open audit file "+name)
+		}
+	}
+
+	fa.fileLock.Lock()
+	oldFile := fa.file
+	fa.file = file
+	fa.fileLock.Unlock()
+
+	if oldFile != nil {
+		oldFile.Close()
+	}
+	fa.updateAuditInfo(nil, nil)
+	return nil
+}
+
+// Rebuilds the audit specification from the current file and filters.
+// nil filters are left unchanged.
+func (fa *fileAuditor) updateAuditInfo(disabled []interface{}, whitelist []string) errors.Error {
+	fa.auditInfoLock.Lock()
+	if disabled == nil {
+		disabled = fa.disabled
+	}
+	if whitelist == nil {
+		whitelist = fa.whitelist
+	}
+
+	info := &datastore.AuditInfo{
+		AuditEnabled:    fa.fileName() != "",
+		EventDisabled:   make(map[uint32]bool, len(disabled)),
+		UserWhitelisted: make(map[datastore.UserInfo]bool, len(whitelist)),
+		Uid:             strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+	for _, event := range disabled {
+		eventId, ok := eventIdFromFilter(event)
+		if !ok {
+			fa.auditInfoLock.Unlock()
+			return errors.NewAdminAuditError(nil, fmt.Sprintf("unknown audit event %v", event))
+		}
+		info.EventDisabled[eventId] = true
+	}
+	for _, user := range whitelist {
+		info.UserWhitelisted[userInfoFromUsername(user)] = true
+	}
+	fa.info = info
+	fa.disabled = disabled
+	fa.whitelist = whitelist
+	fa.auditInfoLock.Unlock()
+
+	if info.AuditEnabled {
+		change := n1qlConfigurationChangeEvent{
+			Timestamp:  time.Now().Format("2006-01-02T15:04:05.000Z07:00"),
+			RealUserid: adt.RealUserId{Domain: "internal", Username: "couchbase"},
+			Uuid:       info.Uid,
+		}
+		fa.write(_CONFIGURATION_CHANGE, &change)
+	}
+	return nil
+}
+
+// Events are filtered by id, or by statement type, e.g. "SELECT".
+func eventIdFromFilter(event interface{}) (uint32, bool) {
+	switch event := event.(type) {
+	case string:
+		eventType := strings.ToUpper(event)
+		if eventType == "OTHER" {
+			return _OTHER_STATEMENT, true
+		}
+		eventId, ok := _EVENT_TYPE_MAP[eventType]
+		return eventId, ok
+	case float64:
+		return uint32(event), event > 0 && event == float64(uint32(event))
+	case int64:
+		return uint32(event), event > 0 && event == int64(uint32(event))
+	case int:
+		return uint32(event), event > 0 && event == int(uint32(event))
+	}
+	return 0, false
+}
+
+// Records are written as the daemon would log them, with the event id
+// as the first field.
+func (fa *fileAuditor) write(eventId uint32, record interface{}) bool {
+	bytes, err := json.Marshal(record)
+	if err != nil || len(bytes) < 2 || bytes[0] != '{' {
+		logging.Errorf("Unable to marshal audit record %v: %v", eventId, err)
+		return false
+	}
+	line := make([]byte, 0, len(bytes)+16)
+	line = append(line, fmt.Sprintf("{\"id\":%d", eventId)...)
+	if len(bytes) > 2 {
+		line = append(line, ',')
+	}
+	line = append(line, bytes[1:]...)
+	line = append(line, '\n')
+
+	fa.fileLock.RLock()
+	defer fa.fileLock.RUnlock()
+	if fa.file == nil {
+		return false
+	}
+	_, err = fa.file.Write(line)
+	if err != nil {
+		logging.Errorf("Unable to write audit record %v: %v", eventId, err)
+		return false
+	}
+	return true
+}
+
+func fileAuditWorker(auditor *fileAuditor, num int) {
+	// If this audit worker panics, start up a replacement.
+	defer func() {
+		r := recover()
+		if r != nil {
+			logging.Errorf("Audit file worker %d: Panic: %v. Starting a replacement.", num, r)
+			go fileAuditWorker(auditor, num+1)
+		}
+	}()
+	logging.Infof("Starting audit file worker %d", num)
+
+	for {
+		entry := <-auditor.auditRecordQueue
+
+		accounting.UpdateCounter(accounting.AUDIT_ACTIONS)
+		var ok bool
+		if entry.isQueryType {
+			ok = auditor.write(entry.eventId, entry.queryAuditRecord)
+		} else {
+			ok = auditor.write(entry.eventId, entry.apiAuditRecord)
+		}
+		if !ok {
+			accounting.UpdateCounter(accounting.AUDIT_ACTIONS_FAILED)
+		}
+	}
+}
+
+func localAuditor() (*fileAuditor, errors.Error) {
+	auditor, ok := _AUDITOR.(*fileAuditor)
+	if !ok {
+		return nil, errors.NewAdminAuditError(nil, "audit records are handled by the audit service")
+	}
+	return auditor, nil
+}
+
+// Whether audit records are handled locally, rather than by the audit service.
+func CheckLocalAudit() errors.Error {
+	_, err := localAuditor()
+	return err
+}
+
+// The local audit file. An empty name disables auditing.
+func SetAuditFile(name string) errors.Error {
+	auditor, err := localAuditor()
+	if err != nil {
+		return err
+	}
+	return auditor.setFile(name)
+}
+
+func AuditFile() string {
+	auditor, err := localAuditor()
+	if err != nil {
+		return ""
+	}
+	return auditor.fileName()
+}
+
+// Events not to be audited, as event ids or statement types.
+func SetDisabledEvents(events []interface{}) errors.Error {
+	auditor, err := localAuditor()
+	if err != nil {
+		return err
+	}
+	if events == nil {
+		events = []interface{}{}
+	}
+	return auditor.updateAuditInfo(events, nil)
+}
+
+func DisabledEvents() []interface{} {
+	auditor, err := localAuditor()
+	if err != nil {
+		return nil
+	}
+	auditor.auditInfoLock.RLock()
+	defer auditor.auditInfoLock.RUnlock()
+	return auditor.disabled
+}
+
+// Users not to be audited, e.g. "admin" or "external:jdoe".
+func SetUserWhitelist(users []string) errors.Error {
+	auditor, err := localAuditor()
+	if err != nil {
+		return err
+	}
+	if users == nil {
+		users = []string{}
+	}
+	return auditor.updateAuditInfo(nil, users)
+}
+
+func UserWhitelist() []string {
+	auditor, err := localAuditor()
+	if err != nil {
+		return nil
+	}
+	auditor.auditInfoLock.RLock()
+	defer auditor.auditInfoLock.RUnlock()
+	return auditor.whitelist
+}
+
+func CheckAuditEvent(event interface{}) bool {
+	_, ok := eventIdFromFilter(event)
+	return ok
+}
+
+// Reading the audit file.
+// Records are keyed by the name of the file holding them and their offset
+// within the file, eg audit.json:1024, or audit.json.20181019-120000.000000:0
+// for a rotated file. The key of a record changes when the file is rotated.
+
+func auditFiles() ([]string, errors.Error) {
+	auditor, err := localAuditor()
+	if err != nil {
+		return nil, err
+	}
+
+	auditor.fileLock.RLock()
+	defer auditor.fileLock.RUnlock()
+	if auditor.file == nil {
+		return nil, nil
+	}
+	return append(auditor.file.Backups(), auditor.file.Name()), nil
+}
+
+/*
+Calls f with the key of each record in the audit files, oldest first,
+until it returns false.
+*/
+func ScanAuditRecords(f func(key string) bool) errors.Error {
+	files, err := auditFiles()
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		more, e := scanAuditFile(name, f)
+		if e != nil {
+			return errors.NewAdminAuditError(e, "unable to read audit file "+name)
+		}
+		if !more {
+			break
+		}
+	}
+	return nil
+}
+
+func scanAuditFile(name string, f func(key string) bool) (bool, error) {
+	file, err := os.Open(name)
+	if err != nil {
+
+		// rotated away since
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	base := filepath.Base(name)
+	reader := bufio.NewReader(file)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+
+		// a partial line is a record being written
+		if err == io.EOF {
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
+		if !f(base + ":" + strconv.FormatInt(offset, 10)) {
+			return false, nil
+		}
+		offset += int64(len(line))
+	}
+}
+
+/*
+Returns the record with the given key, or nil if there is none.
+*/
+func AuditRecord(key string) ([]byte, errors.Error) {
+	files, err := auditFiles()
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	// only the audit files can be read
+	colon := strings.LastIndex(key, ":")
+	if colon < 0 {
+		return nil, nil
+	}
+	offset, e := strconv.ParseInt(key[colon+1:], 10, 64)
+	if e != nil || offset < 0 {
+		return nil, nil
+	}
+	name := ""
+	for _, file := range files {
+		if filepath.Base(file) == key[:colon] {
+			name = file
+			break
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	file, e := os.Open(name)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, nil
+		}
+		return nil, errors.NewAdminAuditError(e, "unable to read audit file "+name)
+	}
+	defer file.Close()
+
+	_, e = file.Seek(offset, io.SeekStart)
+	if e != nil {
+		return nil, errors.NewAdminAuditError(e, "unable to read audit file "+name)
+	}
+	line, e := bufio.NewReader(file).ReadBytes('\n')
+	if e != nil {
+		return nil, nil
+	}
+
+	// the offset must be that of the start of a record
+	if offset > 0 {
+		prev := make([]byte, 1)
+		_, e = file.ReadAt(prev, offset-1)
+		if e != nil || prev[0] != '\n' {
+			return nil, nil
+		}
+	}
+	return line[:len(line)-1], nil
+}
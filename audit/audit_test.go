@@ -1,6 +1,10 @@
 package audit
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -327,3 +331,87 @@ func TestWhitelistedUsers(t *testing.T) {
 		}
 	}
 }
+
+// The local auditor writes the records that pass the filters to its file,
+// and reads them back by key.
+func TestFileAuditor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("Unable to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	auditor := newFileAuditor(1)
+	_AUDITOR = auditor
+
+	// not enabled until a file is set
+	Submit(&simpleAuditable{eventType: "SELECT"})
+	if AuditFile() != "" || auditor.auditInfo().AuditEnabled {
+		t.Fatalf("Expected auditing to be disabled")
+	}
+
+	if SetDisabledEvents([]interface{}{"delete", float64(28676)}) != nil {
+		t.Fatalf("Unable to set disabled events")
+	}
+	if SetDisabledEvents([]interface{}{"GARBAGE"}) == nil {
+		t.Fatalf("Expected unknown event to be rejected")
+	}
+	if SetUserWhitelist([]string{"bob"}) != nil {
+		t.Fatalf("Unable to set user whitelist")
+	}
+	if SetAuditFile(filepath.Join(dir, "audit.json")) != nil {
+		t.Fatalf("Unable to set audit file")
+	}
+
+	Submit(&simpleAuditable{eventType: "SELECT", statement: "SELECT 1", eventUsers: []string{"bill", "bob"}})
+	Submit(&simpleAuditable{eventType: "INSERT"})
+	Submit(&simpleAuditable{eventType: "DELETE"})
+	Submit(&simpleAuditable{eventType: "UPDATE", eventUsers: []string{"bob"}})
+	SubmitApiRequest(&ApiAuditFields{EventTypeId: API_ADMIN_PING, HttpMethod: "GET"})
+
+	// the configuration change, the SELECT by bill, and the ping
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = keys[:0]
+		ScanAuditRecords(func(key string) bool {
+			keys = append(keys, key)
+			return true
+		})
+		if len(keys) >= 3 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 audit records, found %v", keys)
+	}
+
+	expected := []uint32{_CONFIGURATION_CHANGE, 28672, API_ADMIN_PING}
+	for i, key := range keys {
+		bytes, err := AuditRecord(key)
+		if err != nil || bytes == nil {
+			t.Fatalf("Unable to read audit record %v: %v", key, err)
+		}
+		var record map[string]interface{}
+		e := json.Unmarshal(bytes, &record)
+		if e != nil {
+			t.Fatalf("Invalid audit record %s: %v", bytes, e)
+		}
+		if record["id"] != float64(expected[i]) {
+			t.Errorf("Expected event id %v, found %v", expected[i], record["id"])
+		}
+		if i == 1 && record["statement"] != "SELECT 1" {
+			t.Errorf("Unexpected audit record %v", record)
+		}
+	}
+
+	for _, key := range []string{"audit.json:1", "../audit.json:0", "other.json:0", "audit.json"} {
+		bytes, _ := AuditRecord(key)
+		if bytes != nil {
+			t.Errorf("Expected no record for key %v, found %s", key, bytes)
+		}
+	}
+
+	SetAuditFile("")
+	_AUDITOR = nil
+}
@@ -30,6 +30,11 @@ func opIsUnimplemented(namespace, bucket string, requested auth.Privilege) bool
 			}
 			return false
 		}
+		// Validation rules can be written, with the privileges checked
+		// in privilegeString().
+		if bucket == "validations" {
+			return false
+		}
 		// For other system buckets, INSERT/UPDATE/DELETE are not supported.
 		if requested == auth.PRIV_QUERY_UPDATE || requested == auth.PRIV_QUERY_INSERT || requested == auth.PRIV_QUERY_DELETE {
 			return true
@@ -41,6 +46,13 @@ func opIsUnimplemented(namespace, bucket string, requested auth.Privilege) bool
 
 func privilegeString(namespace, bucket string, requested auth.Privilege) (string, error) {
 	var permission string
+	if namespace == "#system" && bucket == "validations" {
+		switch requested {
+		case auth.PRIV_QUERY_UPDATE, auth.PRIV_QUERY_INSERT, auth.PRIV_QUERY_DELETE:
+			// Validation rules constrain every writer of a keyspace.
+			return "cluster.settings!write", nil
+		}
+	}
 	switch requested {
 	case auth.PRIV_WRITE:
 		permission = fmt.Sprintf("cluster.bucket[%s].data.docs!write", bucket)
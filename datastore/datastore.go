@@ -137,6 +137,35 @@ func GetSystemstore() Datastore {
 	return _SYSTEMSTORE
 }
 
+// Notified of the keys, in a keyspace given as namespace:keyspace, that
+// have been mutated, by datastores that can tell
+// keys is nil when the datastore does not know which keys changed
+type MutationListener func(keyspace string, keys []string)
+
+var _MUTATION_LISTENERS []MutationListener
+
+// listeners are added at startup, before any mutations are notified
+func AddMutationListener(listener MutationListener) {
+	_MUTATION_LISTENERS = append(_MUTATION_LISTENERS, listener)
+}
+
+func NotifyMutation(namespace, keyspace string, keys []string) {
+	for _, listener := range _MUTATION_LISTENERS {
+		listener(namespace+":"+keyspace, keys)
+	}
+}
+
+// Keyspaces that notify the mutation listeners of all their mutations,
+// so that listeners need no other means of tracking changes
+type MutationNotifier interface {
+	NotifiesMutations() bool
+}
+
+func NotifiesMutations(keyspace Keyspace) bool {
+	notifier, ok := keyspace.(MutationNotifier)
+	return ok && notifier.NotifiesMutations()
+}
+
 func GetKeyspace(namespace, keyspace string) (Keyspace, errors.Error) {
 	var datastore Datastore
 
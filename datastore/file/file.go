@@ -446,6 +446,13 @@ func (b *keyspace) performOp(op int, kvPairs []value.Pair) ([]value.Pair, errors
 		}
 	}
 
+	if len(insertedKeys) > 0 {
+		keys := make([]string, len(insertedKeys))
+		for i, kv := range insertedKeys {
+			keys[i] = kv.Name
+		}
+		datastore.NotifyMutation(b.namespace.Name(), b.name, keys)
+	}
 	return insertedKeys, returnErr
 
 }
@@ -477,6 +484,10 @@ func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]s
 		}
 	}
 
+	if len(deleted) > 0 {
+		datastore.NotifyMutation(b.namespace.Name(), b.name, deleted)
+	}
+
 	if len(fileError) > 0 {
 		errLine := fmt.Sprintf("Delete failed on some keys %v", fileError)
 		return deleted, errors.NewFileDatastoreError(nil, errLine)
@@ -488,6 +499,12 @@ func (b *keyspace) Delete(deletes []string, context datastore.QueryContext) ([]s
 func (b *keyspace) Release() {
 }
 
+// all mutations go through performOp and Delete
+// files changed outside the datastore are not noticed
+func (b *keyspace) NotifiesMutations() bool {
+	return true
+}
+
 func (b *keyspace) path() string {
 	return filepath.Join(b.namespace.path(), b.name)
 }
@@ -677,6 +694,8 @@ func (pi *primaryIndex) Scan(requestId string, span *datastore.Span, distinct bo
 		switch a := a.(type) {
 		case string:
 			low = a
+		case nil:
+			// covering scans start after null, which is before all keys
 		default:
 			conn.Error(errors.NewFileDatastoreError(nil, fmt.Sprintf("Invalid lower bound %v of type %T.", a, a)))
 			return
@@ -265,6 +265,9 @@ const (
 	AGG_VARIANCE   AggregateType = "VARIANCE"
 	AGG_VARSAMP    AggregateType = "VAR_SAMP"
 	AGG_VARPOP     AggregateType = "VAR_POP"
+	AGG_MODE       AggregateType = "MODE"
+	AGG_PCTCONT    AggregateType = "PERCENTILE_CONT"
+	AGG_PCTDISC    AggregateType = "PERCENTILE_DISC"
 )
 
 type IndexGroupKeys []*IndexGroupKey
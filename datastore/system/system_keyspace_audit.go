@@ -0,0 +1,216 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"github.com/couchbase/query/audit"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+// the records of the local audit file, keyed by file and offset
+type auditKeyspace struct {
+	keyspaceBase
+	name    string
+	indexer datastore.Indexer
+}
+
+func (b *auditKeyspace) Release() {
+}
+
+func (b *auditKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *auditKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *auditKeyspace) Name() string {
+	return b.name
+}
+
+func (b *auditKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	var count int64
+
+	err := audit.ScanAuditRecords(func(key string) bool {
+		count++
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (b *auditKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *auditKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *auditKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+
+	for _, key := range keys {
+		record, err := audit.AuditRecord(key)
+		if err != nil {
+			errs = appendError(errs, err)
+			continue
+		}
+		if record == nil {
+			continue
+		}
+		item := value.NewAnnotatedValue(value.NewValue(record))
+		item.SetAttachment("meta", map[string]interface{}{
+			"id": key,
+		})
+		item.SetId(key)
+		keysMap[key] = item
+	}
+	return
+}
+
+func (b *auditKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *auditKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *auditKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *auditKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func newAuditKeyspace(p *namespace) (*auditKeyspace, errors.Error) {
+	b := new(auditKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_AUDIT
+
+	primary := &auditIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.indexer)
+
+	return b, nil
+}
+
+type auditIndex struct {
+	indexBase
+	name     string
+	keyspace *auditKeyspace
+}
+
+func (pi *auditIndex) KeyspaceId() string {
+	return pi.name
+}
+
+func (pi *auditIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *auditIndex) Name() string {
+	return pi.name
+}
+
+func (pi *auditIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *auditIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *auditIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *auditIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *auditIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *auditIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *auditIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *auditIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, pi.Name())
+}
+
+func (pi *auditIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	if span == nil {
+		pi.ScanEntries(requestId, limit, cons, vector, conn)
+		return
+	}
+
+	var numProduced int64 = 0
+
+	defer close(conn.EntryChannel())
+	spanEvaluator, err := compileSpan(span)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+	err = audit.ScanAuditRecords(func(key string) bool {
+		if !spanEvaluator.evaluate(key) {
+			return true
+		}
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		if !sendSystemKey(conn, &entry) {
+			return false
+		}
+		numProduced++
+		return limit <= 0 || numProduced < limit
+	})
+	if err != nil {
+		conn.Error(err)
+	}
+}
+
+func (pi *auditIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	var numProduced int64 = 0
+
+	defer close(conn.EntryChannel())
+	err := audit.ScanAuditRecords(func(key string) bool {
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		if !sendSystemKey(conn, &entry) {
+			return false
+		}
+		numProduced++
+		return limit <= 0 || numProduced < limit
+	})
+	if err != nil {
+		conn.Error(err)
+	}
+}
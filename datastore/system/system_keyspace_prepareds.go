@@ -112,6 +112,12 @@ func (b *preparedsKeyspace) Fetch(keys []string, keysMap map[string]value.Annota
 				if node != "" {
 					itemMap["node"] = node
 				}
+				if baseline := prepareds.BaselineStatus(entry.Prepared); baseline != nil {
+					itemMap["baseline"] = baseline
+				}
+				if planChanges := entry.PlanChanges(); planChanges != nil {
+					itemMap["planChanges"] = planChanges
+				}
 
 				// only give times for entries that have completed at least one execution
 				if entry.Uses > 0 && entry.RequestTime > 0 {
@@ -0,0 +1,214 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/validation"
+	"github.com/couchbase/query/value"
+)
+
+// the document validation rules, keyed by keyspace, e.g.
+// INSERT INTO system:validations VALUES("default:orders", {"schema": {...}})
+type validationsKeyspace struct {
+	keyspaceBase
+	name    string
+	indexer datastore.Indexer
+}
+
+func (b *validationsKeyspace) Release() {
+}
+
+func (b *validationsKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *validationsKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *validationsKeyspace) Name() string {
+	return b.name
+}
+
+func (b *validationsKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	return int64(validation.CountRules()), nil
+}
+
+func (b *validationsKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *validationsKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *validationsKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+
+	for _, key := range keys {
+		record := validation.RuleRecord(key)
+		if record == nil {
+			continue
+		}
+		item := value.NewAnnotatedValue(record)
+		item.SetAttachment("meta", map[string]interface{}{
+			"id": key,
+		})
+		item.SetId(key)
+		keysMap[key] = item
+	}
+	return
+}
+
+func (b *validationsKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	return b.setRules(inserts, validation.RULE_INSERT)
+}
+
+func (b *validationsKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	return b.setRules(updates, validation.RULE_UPDATE)
+}
+
+func (b *validationsKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	return b.setRules(upserts, validation.RULE_UPSERT)
+}
+
+func (b *validationsKeyspace) setRules(pairs []value.Pair, mode int) ([]value.Pair, errors.Error) {
+	for i, pair := range pairs {
+		err := validation.SetRule(pair.Name, pair.Value, mode)
+		if err != nil {
+			return pairs[0:i], err
+		}
+	}
+	return pairs, nil
+}
+
+func (b *validationsKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	deleted := make([]string, 0, len(deletes))
+	for _, key := range deletes {
+		ok, err := validation.DeleteRule(key)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted = append(deleted, key)
+		}
+	}
+	return deleted, nil
+}
+
+func newValidationsKeyspace(p *namespace) (*validationsKeyspace, errors.Error) {
+	b := new(validationsKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_VALIDATIONS
+
+	primary := &validationsIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.indexer)
+
+	return b, nil
+}
+
+type validationsIndex struct {
+	indexBase
+	name     string
+	keyspace *validationsKeyspace
+}
+
+func (pi *validationsIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *validationsIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *validationsIndex) Name() string {
+	return pi.name
+}
+
+func (pi *validationsIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *validationsIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *validationsIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *validationsIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *validationsIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *validationsIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *validationsIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *validationsIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, pi.Name())
+}
+
+func (pi *validationsIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	if span == nil {
+		pi.ScanEntries(requestId, limit, cons, vector, conn)
+		return
+	}
+
+	var numProduced int64 = 0
+
+	defer close(conn.EntryChannel())
+	spanEvaluator, err := compileSpan(span)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+	validation.ScanRules(func(key string) bool {
+		if !spanEvaluator.evaluate(key) {
+			return true
+		}
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		if !sendSystemKey(conn, &entry) {
+			return false
+		}
+		numProduced++
+		return limit <= 0 || numProduced < limit
+	})
+}
+
+func (pi *validationsIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	var numProduced int64 = 0
+
+	defer close(conn.EntryChannel())
+	validation.ScanRules(func(key string) bool {
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		if !sendSystemKey(conn, &entry) {
+			return false
+		}
+		numProduced++
+		return limit <= 0 || numProduced < limit
+	})
+}
@@ -0,0 +1,209 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+	"github.com/couchbase/query/views"
+)
+
+// the views, keyed by namespace/name
+// views are created and dropped with CREATE VIEW and DROP VIEW
+type viewsKeyspace struct {
+	keyspaceBase
+	name    string
+	indexer datastore.Indexer
+}
+
+func (b *viewsKeyspace) Release() {
+}
+
+func (b *viewsKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *viewsKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *viewsKeyspace) Name() string {
+	return b.name
+}
+
+func (b *viewsKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	return int64(len(views.Views())), nil
+}
+
+func (b *viewsKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *viewsKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *viewsKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+
+	for _, key := range keys {
+		err, ns, name := splitId(key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		view := views.GetView(ns, name)
+		if view == nil {
+			continue
+		}
+		item := value.NewAnnotatedValue(map[string]interface{}{
+			"name":         view.Name,
+			"namespace_id": view.Namespace,
+			"definition":   view.Definition,
+			"created":      view.Created.String(),
+		})
+		item.SetAttachment("meta", map[string]interface{}{
+			"id": key,
+		})
+		item.SetId(key)
+		keysMap[key] = item
+	}
+	return
+}
+
+func (b *viewsKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *viewsKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *viewsKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *viewsKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func newViewsKeyspace(p *namespace) (*viewsKeyspace, errors.Error) {
+	b := new(viewsKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_VIEWS
+
+	primary := &viewsIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.indexer)
+
+	return b, nil
+}
+
+type viewsIndex struct {
+	indexBase
+	name     string
+	keyspace *viewsKeyspace
+}
+
+func (pi *viewsIndex) KeyspaceId() string {
+	return pi.keyspace.Id()
+}
+
+func (pi *viewsIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *viewsIndex) Name() string {
+	return pi.name
+}
+
+func (pi *viewsIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *viewsIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *viewsIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *viewsIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *viewsIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *viewsIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *viewsIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *viewsIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, pi.Name())
+}
+
+func (pi *viewsIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	if span == nil {
+		pi.ScanEntries(requestId, limit, cons, vector, conn)
+		return
+	}
+
+	var numProduced int64 = 0
+
+	defer close(conn.EntryChannel())
+	spanEvaluator, err := compileSpan(span)
+	if err != nil {
+		conn.Error(err)
+		return
+	}
+	for _, view := range views.Views() {
+		key := view.Namespace + "/" + view.Name
+		if !spanEvaluator.evaluate(key) {
+			continue
+		}
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		if !sendSystemKey(conn, &entry) {
+			return
+		}
+		numProduced++
+		if limit > 0 && numProduced >= limit {
+			return
+		}
+	}
+}
+
+func (pi *viewsIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	var numProduced int64 = 0
+
+	defer close(conn.EntryChannel())
+	for _, view := range views.Views() {
+		entry := datastore.IndexEntry{PrimaryKey: view.Namespace + "/" + view.Name}
+		if !sendSystemKey(conn, &entry) {
+			return
+		}
+		numProduced++
+		if limit > 0 && numProduced >= limit {
+			return
+		}
+	}
+}
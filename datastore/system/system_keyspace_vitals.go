@@ -0,0 +1,250 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package system
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/accounting"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/distributed"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/value"
+)
+
+// key of the local entry for a standalone query node
+const _VITALS_LOCAL = "local"
+
+type vitalsKeyspace struct {
+	keyspaceBase
+	name    string
+	indexer datastore.Indexer
+}
+
+func (b *vitalsKeyspace) Release() {
+}
+
+func (b *vitalsKeyspace) NamespaceId() string {
+	return b.namespace.Id()
+}
+
+func (b *vitalsKeyspace) Id() string {
+	return b.Name()
+}
+
+func (b *vitalsKeyspace) Name() string {
+	return b.name
+}
+
+func (b *vitalsKeyspace) Count(context datastore.QueryContext) (int64, errors.Error) {
+	return int64(len(vitalsNodes())), nil
+}
+
+func (b *vitalsKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	return b.indexer, nil
+}
+
+func (b *vitalsKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	return []datastore.Indexer{b.indexer}, nil
+}
+
+func (b *vitalsKeyspace) Fetch(keys []string, keysMap map[string]value.AnnotatedValue,
+	context datastore.QueryContext, subPaths []string) (errs []errors.Error) {
+
+	creds, authToken := credsFromContext(context)
+
+	// now that the node name can change in flight, use a consistent one across fetches
+	whoAmI := distributed.RemoteAccess().WhoAmI()
+	for _, key := range keys {
+
+		// remote entry
+		if key != _VITALS_LOCAL && key != whoAmI {
+			distributed.RemoteAccess().GetRemoteDoc(key, key,
+				"vitals", "GET",
+				func(doc map[string]interface{}) {
+					remoteValue := value.NewAnnotatedValue(doc)
+					remoteValue.SetField("node", key)
+					remoteValue.SetAttachment("meta", map[string]interface{}{
+						"id": key,
+					})
+					remoteValue.SetId(key)
+					keysMap[key] = remoteValue
+				},
+				func(warn errors.Error) {
+					context.Warning(warn)
+				}, creds, authToken)
+			continue
+		}
+
+		// local entry
+		vitals, err := accounting.Vitals()
+		if err != nil {
+			errs = appendError(errs, err)
+			continue
+		}
+		if vitals == nil {
+			continue
+		}
+		bytes, jErr := json.Marshal(vitals)
+		if jErr != nil {
+			errs = appendError(errs, errors.NewSystemDatastoreError(jErr, "Error marshalling vitals"))
+			continue
+		}
+		item := value.NewAnnotatedValue(value.NewValue(bytes))
+		if whoAmI != "" {
+			item.SetField("node", whoAmI)
+		}
+		item.SetAttachment("meta", map[string]interface{}{
+			"id": key,
+		})
+		item.SetId(key)
+		keysMap[key] = item
+	}
+	return
+}
+
+func (b *vitalsKeyspace) Insert(inserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *vitalsKeyspace) Update(updates []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *vitalsKeyspace) Upsert(upserts []value.Pair) ([]value.Pair, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+func (b *vitalsKeyspace) Delete(deletes []string, context datastore.QueryContext) ([]string, errors.Error) {
+	// FIXME
+	return nil, errors.NewSystemNotImplementedError(nil, "")
+}
+
+// the keys of the keyspace: all the query nodes in a cluster, or the local node
+func vitalsNodes() []string {
+	nodes := distributed.RemoteAccess().GetNodeNames()
+	if len(nodes) == 0 {
+		return []string{_VITALS_LOCAL}
+	}
+	return nodes
+}
+
+func newVitalsKeyspace(p *namespace) (*vitalsKeyspace, errors.Error) {
+	b := new(vitalsKeyspace)
+	setKeyspaceBase(&b.keyspaceBase, p)
+	b.name = KEYSPACE_NAME_VITALS
+
+	primary := &vitalsIndex{name: "#primary", keyspace: b}
+	b.indexer = newSystemIndexer(b, primary)
+	setIndexBase(&primary.indexBase, b.indexer)
+
+	return b, nil
+}
+
+type vitalsIndex struct {
+	indexBase
+	name     string
+	keyspace *vitalsKeyspace
+}
+
+func (pi *vitalsIndex) KeyspaceId() string {
+	return pi.name
+}
+
+func (pi *vitalsIndex) Id() string {
+	return pi.Name()
+}
+
+func (pi *vitalsIndex) Name() string {
+	return pi.name
+}
+
+func (pi *vitalsIndex) Type() datastore.IndexType {
+	return datastore.SYSTEM
+}
+
+func (pi *vitalsIndex) SeekKey() expression.Expressions {
+	return nil
+}
+
+func (pi *vitalsIndex) RangeKey() expression.Expressions {
+	return nil
+}
+
+func (pi *vitalsIndex) Condition() expression.Expression {
+	return nil
+}
+
+func (pi *vitalsIndex) IsPrimary() bool {
+	return true
+}
+
+func (pi *vitalsIndex) State() (state datastore.IndexState, msg string, err errors.Error) {
+	return datastore.ONLINE, "", nil
+}
+
+func (pi *vitalsIndex) Statistics(requestId string, span *datastore.Span) (
+	datastore.Statistics, errors.Error) {
+	return nil, nil
+}
+
+func (pi *vitalsIndex) Drop(requestId string) errors.Error {
+	return errors.NewSystemIdxNoDropError(nil, pi.Name())
+}
+
+func (pi *vitalsIndex) Scan(requestId string, span *datastore.Span, distinct bool, limit int64,
+	cons datastore.ScanConsistency, vector timestamp.Vector, conn *datastore.IndexConnection) {
+	if span == nil {
+		pi.ScanEntries(requestId, limit, cons, vector, conn)
+	} else {
+		var numProduced int64 = 0
+
+		defer close(conn.EntryChannel())
+		spanEvaluator, err := compileSpan(span)
+		if err != nil {
+			conn.Error(err)
+			return
+		}
+		for _, key := range vitalsNodes() {
+			if spanEvaluator.evaluate(key) {
+				entry := datastore.IndexEntry{PrimaryKey: key}
+				if !sendSystemKey(conn, &entry) {
+					return
+				}
+				numProduced++
+				if limit > 0 && numProduced >= limit {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (pi *vitalsIndex) ScanEntries(requestId string, limit int64, cons datastore.ScanConsistency,
+	vector timestamp.Vector, conn *datastore.IndexConnection) {
+	var numProduced int64 = 0
+
+	defer close(conn.EntryChannel())
+	for _, key := range vitalsNodes() {
+		entry := datastore.IndexEntry{PrimaryKey: key}
+		if !sendSystemKey(conn, &entry) {
+			return
+		}
+		numProduced++
+		if limit > 0 && numProduced >= limit {
+			break
+		}
+	}
+}
@@ -154,5 +154,29 @@ func (p *namespace) loadKeyspaces() (e errors.Error) {
 	}
 	p.keyspaces[applicableRoles.Name()] = applicableRoles
 
+	vitals, e := newVitalsKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[vitals.Name()] = vitals
+
+	audit, e := newAuditKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[audit.Name()] = audit
+
+	validations, e := newValidationsKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[validations.Name()] = validations
+
+	views, e := newViewsKeyspace(p)
+	if e != nil {
+		return e
+	}
+	p.keyspaces[views.Name()] = views
+
 	return nil
 }
@@ -162,3 +162,8 @@ func NewAdminBodyError(e error) Error {
 	return &err{level: EXCEPTION, ICode: 2220, IKey: "admin.accounting.bad_body", ICause: e,
 		InternalMsg: "Error getting request body", InternalCaller: CallerN(1)}
 }
+
+func NewAdminAuditError(e error, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 2230, IKey: "admin.audit.error", ICause: e,
+		InternalMsg: "Audit error: " + msg, InternalCaller: CallerN(1)}
+}
@@ -87,3 +87,14 @@ func NewSystemMalformedKeyError(key string, keyspace string) Error {
 		InternalMsg:    fmt.Sprintf("System datastore : key %q is not of the correct format for keyspace %s", key, keyspace),
 		InternalCaller: CallerN(1)}
 }
+
+func NewSystemValidationRuleError(e error, keyspace string, msg string) Error {
+	return &err{level: EXCEPTION, ICode: 11013, IKey: "datastore.system.validation_rule", ICause: e,
+		InternalMsg:    fmt.Sprintf("System datastore : invalid validation rule for keyspace %s - %s", keyspace, msg),
+		InternalCaller: CallerN(1)}
+}
+
+func NewSystemValidationStoreError(e error) Error {
+	return &err{level: EXCEPTION, ICode: 11014, IKey: "datastore.system.validation_store", ICause: e,
+		InternalMsg: "System datastore : unable to store validation rules", InternalCaller: CallerN(1)}
+}
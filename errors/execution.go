@@ -221,3 +221,15 @@ func NewMergeMultiInsertError(key string) Error {
 		InternalMsg:    fmt.Sprintf("Multiple INSERT of the same document (document key '%s') in a MERGE statement", key),
 		InternalCaller: CallerN(1)}
 }
+
+func NewDocumentValidationError(keyspace, key string, cause error) Error {
+	return &err{level: EXCEPTION, ICode: 5340, IKey: "execution.document_validation", ICause: cause,
+		InternalMsg:    fmt.Sprintf("Document %s does not conform to the validation rule of keyspace %s", key, keyspace),
+		InternalCaller: CallerN(1)}
+}
+
+func NewMaterializedViewRefreshError(name string, cause error) Error {
+	return &err{level: EXCEPTION, ICode: 5350, IKey: "execution.materialized_view_refresh", ICause: cause,
+		InternalMsg:    fmt.Sprintf("Unable to refresh materialized view %s", name),
+		InternalCaller: CallerN(1)}
+}
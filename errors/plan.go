@@ -71,6 +71,72 @@ func NewEncodingNameMismatchError(name string) Error {
 		InternalMsg: fmt.Sprintf("Prepared name in encoded plan parameter is not %s", name), InternalCaller: CallerN(1)}
 }
 
+const NO_SUCH_BASELINE = 4091
+
+func NewNoSuchBaselineError(name string) Error {
+	return &err{level: EXCEPTION, ICode: NO_SUCH_BASELINE, IKey: "plan.baseline.no_such_name",
+		InternalMsg: fmt.Sprintf("No such plan baseline: %s", name), InternalCaller: CallerN(1)}
+}
+
+func NewBaselineStoreError(e error) Error {
+	return &err{level: EXCEPTION, ICode: 4092, IKey: "plan.baseline.store",
+		ICause: e, InternalMsg: "Unable to store plan baselines", InternalCaller: CallerN(1)}
+}
+
+func NewPreparedSnapshotError(e error) Error {
+	return &err{level: EXCEPTION, ICode: 4093, IKey: "plan.prepared_snapshot",
+		ICause: e, InternalMsg: "Unable to read or write prepared statements snapshot", InternalCaller: CallerN(1)}
+}
+
+const NO_SUCH_MATERIALIZED_VIEW = 4094
+
+func NewNoSuchMaterializedViewError(name string) Error {
+	return &err{level: EXCEPTION, ICode: NO_SUCH_MATERIALIZED_VIEW, IKey: "plan.materialized_view.no_such_name",
+		InternalMsg: fmt.Sprintf("No such materialized view: %s", name), InternalCaller: CallerN(1)}
+}
+
+func NewMaterializedViewExistsError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4095, IKey: "plan.materialized_view.already_exists",
+		InternalMsg: fmt.Sprintf("The materialized view %s already exists.", name), InternalCaller: CallerN(1)}
+}
+
+func NewMaterializedViewDefinitionError(name, reason string) Error {
+	return &err{level: EXCEPTION, ICode: 4096, IKey: "plan.materialized_view.definition",
+		InternalMsg: fmt.Sprintf("Invalid definition for materialized view %s: %s", name, reason), InternalCaller: CallerN(1)}
+}
+
+func NewMaterializedViewStoreError(e error) Error {
+	return &err{level: EXCEPTION, ICode: 4097, IKey: "plan.materialized_view.store",
+		ICause: e, InternalMsg: "Unable to store materialized views", InternalCaller: CallerN(1)}
+}
+
+const NO_SUCH_VIEW = 4101
+
+func NewNoSuchViewError(name string) Error {
+	return &err{level: EXCEPTION, ICode: NO_SUCH_VIEW, IKey: "plan.view.no_such_name",
+		InternalMsg: fmt.Sprintf("No such view: %s", name), InternalCaller: CallerN(1)}
+}
+
+func NewViewExistsError(name string) Error {
+	return &err{level: EXCEPTION, ICode: 4102, IKey: "plan.view.already_exists",
+		InternalMsg: fmt.Sprintf("The view %s already exists.", name), InternalCaller: CallerN(1)}
+}
+
+func NewViewDefinitionError(name, reason string) Error {
+	return &err{level: EXCEPTION, ICode: 4103, IKey: "plan.view.definition",
+		InternalMsg: fmt.Sprintf("Invalid definition for view %s: %s", name, reason), InternalCaller: CallerN(1)}
+}
+
+func NewViewStoreError(e error) Error {
+	return &err{level: EXCEPTION, ICode: 4104, IKey: "plan.view.store",
+		ICause: e, InternalMsg: "Unable to store views", InternalCaller: CallerN(1)}
+}
+
+func NewViewUseError(name, reason string) Error {
+	return &err{level: EXCEPTION, ICode: 4105, IKey: "plan.view.use",
+		InternalMsg: fmt.Sprintf("The view %s cannot be used here: %s", name, reason), InternalCaller: CallerN(1)}
+}
+
 const NO_INDEX_JOIN = 4100
 
 func NewNoIndexJoinError(alias, op string) Error {
@@ -131,6 +197,13 @@ func NewNoAnsiJoinError(alias, op string) Error {
 		InternalMsg: fmt.Sprintf("No index available for ANSI %s term %s", op, alias), InternalCaller: CallerN(1)}
 }
 
+const RIGHT_OUTER_JOIN_CORRELATED = 4331
+
+func NewRightOuterJoinCorrelatedError(alias string) Error {
+	return &err{level: EXCEPTION, ICode: RIGHT_OUTER_JOIN_CORRELATED, IKey: "plan.ansi_join.right_outer_correlated",
+		InternalMsg: fmt.Sprintf("Right-hand side of ANSI RIGHT or FULL OUTER JOIN term %s cannot depend on other terms", alias), InternalCaller: CallerN(1)}
+}
+
 const PARTITION_INDEX_NOT_SUPPORTED = 4340
 
 func NewPartitionIndexNotSupportedError() Error {
@@ -110,3 +110,8 @@ func NewServiceErrorHttpMethod(method string) Error {
 	return &err{level: EXCEPTION, ICode: 1170, IKey: "service.io.request.method",
 		InternalMsg: fmt.Sprintf("Unsupported method %s", method), InternalCaller: CallerN(1)}
 }
+
+func NewServiceErrorWorkloadRejected(class string, reason string) Error {
+	return &err{level: EXCEPTION, ICode: 1180, IKey: "service.workload.rejected",
+		InternalMsg: fmt.Sprintf("Request rejected by workload class %s: %s", class, reason), InternalCaller: CallerN(1)}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -70,6 +71,7 @@ type base struct {
 	inDocs         int64
 	outDocs        int64
 	phaseSwitches  int64
+	concurrent     int64
 	stopped        bool
 	isRoot         bool
 	bit            uint8
@@ -79,6 +81,7 @@ type base struct {
 	activeLock     sync.Mutex
 	primed         bool
 	completed      bool
+	traceSpan      *tracing.Span
 }
 
 const _ITEM_CAP = 512
@@ -172,6 +175,7 @@ func (this *base) close(context *Context) {
 			serializedClose(this.output, base, context)
 		}
 	}
+	this.endSpan()
 	this.inactive()
 }
 
@@ -323,7 +327,7 @@ func (this *base) SendStop() {
 
 // stop for the terminal operator case
 func (this *base) baseSendStop() {
-	if this.stopped || this.completed {
+	if this.stopped || this.isComplete() {
 		return
 	}
 	this.switchPhase(_CHANTIME)
@@ -332,7 +336,7 @@ func (this *base) baseSendStop() {
 }
 
 func (this *base) chanSendStop() {
-	if this.completed {
+	if this.isComplete() {
 		return
 	}
 	this.switchPhase(_CHANTIME)
@@ -508,7 +512,6 @@ func (this *base) runConsumer(cons consumer, context *Context, parent value.Valu
 			this.setExecPhase(this.execPhase, context)
 		}
 		this.switchPhase(_EXECTIME)
-		defer func() { this.switchPhase(_NOTIME) }() // accrue current phase's time
 		if this.serialized == true {
 			ok := true
 			if !active || (context.Readonly() && !cons.readonly()) {
@@ -517,18 +520,24 @@ func (this *base) runConsumer(cons consumer, context *Context, parent value.Valu
 				ok = cons.beforeItems(context, parent)
 			}
 
+			// from now on, the input's go routine switches our phases
 			if ok {
+				this.switchPhase(_NOTIME)
 				go this.input.RunOnce(context, parent)
 			}
 
 			if !ok {
 				this.notify()
+				this.switchPhase(_NOTIME)
 				this.close(context)
 			}
 			return
 		}
+
+		// once closed, the operator may be reopened on another go routine
 		defer this.close(context)
-		defer this.notify() // Notify that I have stopped
+		defer func() { this.switchPhase(_NOTIME) }() // accrue current phase's time
+		defer this.notify()                          // Notify that I have stopped
 		defer func() { this.batch = nil }()
 
 		if !active || (context.Readonly() && !cons.readonly()) {
@@ -598,6 +607,19 @@ func serializedClose(op Operator, opBase *base, context *Context) {
 	opBase.inactive()
 }
 
+// run the input while beforeItems runs an independent subplan, rather
+// than once it is done, if a worker is free: the caller releases it
+// once the subplan is done
+// serialized operators are fed by their input, so they cannot do this
+func (this *base) runInputConcurrently(context *Context, parent value.Value) bool {
+	if this.input == nil || this.serialized || !context.acquireWorker() {
+		return false
+	}
+	this.addConcurrent(1)
+	go this.input.RunOnce(context, parent)
+	return true
+}
+
 // Override if needed
 func (this *base) beforeItems(context *Context, parent value.Value) bool {
 	return true
@@ -849,6 +871,13 @@ func (this *base) inactive() {
 	this.activeCond.Signal()
 }
 
+// the operator may be terminating on another go routine
+func (this *base) isComplete() bool {
+	this.activeCond.L.Lock()
+	defer this.activeCond.L.Unlock()
+	return this.completed
+}
+
 func (this *base) wait() {
 	this.activeCond.L.Lock()
 
@@ -922,6 +951,56 @@ func (this *base) switchPhase(p timePhases) {
 func (this *base) setExecPhase(phase Phases, context *Context) {
 	context.AddPhaseOperator(phase)
 	this.addExecPhase(phase, context)
+	this.traceSpan = context.StartSpan(phase.String(), tracing.SPAN_KIND_INTERNAL)
+}
+
+// tracing
+// operator spans report the same statistics as the profile
+func (this *base) endSpan() {
+	if this.traceSpan == nil {
+		return
+	}
+	this.traceSpan.SetAttribute("itemsIn", this.inDocs)
+	this.traceSpan.SetAttribute("itemsOut", this.outDocs)
+	this.traceSpan.SetAttribute("execTime", time.Duration(this.execTime))
+	this.traceSpan.SetAttribute("kernTime", time.Duration(this.chanTime))
+	this.traceSpan.SetAttribute("servTime", time.Duration(this.servTime))
+	this.traceSpan.End()
+}
+
+// datastore calls are children of the operator span
+func (this *base) startCallSpan(call string, keyspace string, context *Context) *tracing.Span {
+	var span *tracing.Span
+
+	name := "datastore." + call
+	if this.traceSpan != nil {
+		span = this.traceSpan.StartSpan(name, tracing.SPAN_KIND_CLIENT)
+	} else {
+		span = context.StartSpan(name, tracing.SPAN_KIND_CLIENT)
+	}
+	span.SetAttribute("keyspace", keyspace)
+	return span
+}
+
+func (this *base) startScanSpan(index datastore.Index, keyspace string, context *Context) *tracing.Span {
+	span := this.startCallSpan("indexScan", keyspace, context)
+	span.SetAttribute("index", index.Name())
+	span.SetAttribute("using", string(index.Type()))
+	return span
+}
+
+func endCallSpan(span *tracing.Span, keys int, errs ...errors.Error) {
+	if span == nil {
+		return
+	}
+	span.SetAttribute("keys", keys)
+	for _, err := range errs {
+		if err != nil {
+			span.SetError(err.Error())
+			break
+		}
+	}
+	span.End()
 }
 
 // accrues phase times (useful where we don't want to count operators)
@@ -950,6 +1029,11 @@ func (this *base) addOutDocs(d int64) {
 	go_atomic.AddInt64((*int64)(&this.outDocs), d)
 }
 
+// subplans run concurrently by the operator
+func (this *base) addConcurrent(d int64) {
+	go_atomic.AddInt64((*int64)(&this.concurrent), d)
+}
+
 // profile marshaller
 func (this *base) marshalTimes(r map[string]interface{}) {
 	var d time.Duration
@@ -964,6 +1048,9 @@ func (this *base) marshalTimes(r map[string]interface{}) {
 	if this.phaseSwitches != 0 {
 		stats["#phaseSwitches"] = this.phaseSwitches
 	}
+	if this.concurrent != 0 {
+		stats["#concurrent"] = this.concurrent
+	}
 
 	execTime := this.execTime
 	chanTime := this.chanTime
@@ -1013,6 +1100,7 @@ func (this *base) accrueTime(copy *base) {
 	this.inDocs += copy.inDocs
 	this.outDocs += copy.outDocs
 	this.phaseSwitches += copy.phaseSwitches
+	this.concurrent += copy.concurrent
 	this.execTime += copy.execTime
 	this.chanTime += copy.chanTime
 	this.servTime += copy.servTime
@@ -16,6 +16,7 @@ import (
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/timestamp"
 	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/views"
 )
 
 // Build a query execution pipeline from a query plan.
@@ -288,6 +289,26 @@ func (this *builder) VisitHashNest(plan *plan.HashNest) (interface{}, error) {
 	return NewHashNest(plan, this.context, c.(Operator)), nil
 }
 
+func (this *builder) VisitNLSemiJoin(plan *plan.NLSemiJoin) (interface{}, error) {
+	child := plan.Child()
+	c, e := child.Accept(this)
+	if e != nil {
+		return nil, e
+	}
+
+	return NewNLSemiJoin(plan, this.context, c.(Operator)), nil
+}
+
+func (this *builder) VisitHashSemiJoin(plan *plan.HashSemiJoin) (interface{}, error) {
+	child := plan.Child()
+	c, e := child.Accept(this)
+	if e != nil {
+		return nil, e
+	}
+
+	return NewHashSemiJoin(plan, this.context, c.(Operator)), nil
+}
+
 func (this *builder) VisitUnnest(plan *plan.Unnest) (interface{}, error) {
 	return NewUnnest(plan, this.context), nil
 }
@@ -570,6 +591,36 @@ func (this *builder) VisitBuildIndexes(plan *plan.BuildIndexes) (interface{}, er
 	return NewBuildIndexes(plan, this.context), nil
 }
 
+// Materialized views
+func (this *builder) VisitCreateMaterializedView(plan *plan.CreateMaterializedView) (interface{}, error) {
+	return NewCreateMaterializedView(plan, this.context), nil
+}
+
+func (this *builder) VisitDropMaterializedView(plan *plan.DropMaterializedView) (interface{}, error) {
+	return NewDropMaterializedView(plan, this.context), nil
+}
+
+func (this *builder) VisitRefreshMaterializedView(plan *plan.RefreshMaterializedView) (interface{}, error) {
+	return NewRefreshMaterializedView(plan, this.context), nil
+}
+
+// Read the view if it is fresh, otherwise run the query
+func (this *builder) VisitMaterializedView(plan *plan.MaterializedView) (interface{}, error) {
+	if views.Fresh(plan.Namespace(), plan.Name(), plan.Definition()) {
+		return plan.View().Accept(this)
+	}
+	return plan.Query().Accept(this)
+}
+
+// Views
+func (this *builder) VisitCreateView(plan *plan.CreateView) (interface{}, error) {
+	return NewCreateView(plan, this.context), nil
+}
+
+func (this *builder) VisitDropView(plan *plan.DropView) (interface{}, error) {
+	return NewDropView(plan, this.context), nil
+}
+
 // Prepare
 func (this *builder) VisitPrepare(plan *plan.Prepare) (interface{}, error) {
 	return NewPrepare(plan, this.context, plan.Prepared()), nil
@@ -0,0 +1,238 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/value"
+)
+
+// workers are released once the subplans using them are done
+func expectWorkersReleased(t *testing.T, text string, context *Context) {
+	for i := 0; i < 100 && len(context.workers) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(context.workers) > 0 {
+		t.Errorf("Expected the workers to be released for %s, %v in use", text, len(context.workers))
+	}
+}
+
+func TestConcurrentHashJoin(t *testing.T) {
+	cases := []struct {
+		text     string
+		operator string
+		expected []string
+	}{
+		{"SELECT a.i AS x, b.i AS y FROM b0 AS a JOIN b1 AS b USE HASH(BUILD) ON a.i = b.i", "HashJoin",
+			[]string{`{"x":0,"y":0}`, `{"x":1,"y":1}`, `{"x":2,"y":2}`}},
+		{"SELECT a.i AS x, b.i AS y FROM b0 AS a JOIN b1 AS b USE HASH(PROBE) ON a.i = b.i", "HashJoin",
+			[]string{`{"x":0,"y":0}`, `{"x":1,"y":1}`, `{"x":2,"y":2}`}},
+		{"SELECT a.i AS x, b.i AS y FROM b0 AS a LEFT JOIN b1 AS b USE HASH(BUILD) ON a.i = b.i AND b.i > 0", "HashJoin",
+			[]string{`{"x":0}`, `{"x":1,"y":1}`, `{"x":2,"y":2}`}},
+		{"SELECT a.i AS x, b.i AS y FROM b0 AS a JOIN b1 AS b USE HASH(BUILD) ON a.i = b.i ORDER BY a.i LIMIT 1", "HashJoin",
+			[]string{`{"x":0,"y":0}`}},
+		{"SELECT a.i AS x, ARRAY n.i FOR n IN c END AS y FROM b0 AS a NEST b1 AS c USE HASH(BUILD) ON a.i = c.i", "HashNest",
+			[]string{`{"x":0,"y":[0]}`, `{"x":1,"y":[1]}`, `{"x":2,"y":[2]}`}},
+	}
+
+	for _, c := range cases {
+
+		// the same results, however the build and probe sides interleave
+		for i := 0; i < 10; i++ {
+			prepared, results, context := runStatementWorkers(t, c.text, 0, 4)
+			expectResults(t, c.text, results, c.expected...)
+			expectOperator(t, c.text, prepared, c.operator, false)
+			expectWorkersReleased(t, c.text, context)
+		}
+	}
+}
+
+func TestPrefetchSubqueries(t *testing.T) {
+	projection := "SELECT a.i, (SELECT RAW COUNT(*) FROM b1)[0] AS n, (SELECT RAW MAX(c.i) FROM b1 AS c)[0] AS m FROM b0 AS a"
+
+	for i := 0; i < 10; i++ {
+		_, results, context := runStatementWorkers(t, projection, 0, 4)
+		expectResults(t, projection, results, `{"i":0,"m":2,"n":3}`, `{"i":1,"m":2,"n":3}`, `{"i":2,"m":2,"n":3}`)
+		expectWorkersReleased(t, projection, context)
+		if prefetched := context.contextPrefetched(); prefetched == nil || len(prefetched.entries) != 2 {
+			t.Errorf("Expected the subqueries of %s to be prefetched", projection)
+		}
+
+		// not prefetched without rows
+		text := projection + " WHERE a.i > 5"
+		_, results, context = runStatementWorkers(t, text, 0, 4)
+		expectResults(t, text, results)
+		if prefetched := context.contextPrefetched(); prefetched != nil && len(prefetched.entries) > 0 {
+			t.Errorf("Expected no subqueries to be prefetched for %s", text)
+		}
+
+		// stopped once the rows are done
+		text = projection + " LIMIT 1"
+		_, results, context = runStatementWorkers(t, text, 0, 4)
+		if len(results) != 1 {
+			t.Errorf("Expected one result for %s, got %v", text, results)
+		}
+		expectWorkersReleased(t, text, context)
+	}
+}
+
+func TestPrefetchFallback(t *testing.T) {
+	ds, err := mock.NewDatastore("mock:keyspaces=2,items=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+
+	parse := func(text string) *algebra.Select {
+		stmt, er := n1ql.ParseStatement(text)
+		if er != nil {
+			t.Fatalf("Unable to parse %s: %v", text, er)
+		}
+		return stmt.(*algebra.Select)
+	}
+
+	for i := 0; i < 10; i++ {
+		context := NewContext("test", ds, nil, "p0", true, 1, 0, 0, 0, nil, nil, nil,
+			datastore.UNBOUNDED, &noScanVectors{}, newTestOutput(), nil, nil, datastore.INDEX_API_MAX, 0)
+		context.workers = make(chan bool, 2)
+
+		// a stopped subquery is evaluated by whoever needs it
+		text := "SELECT RAW COUNT(*) FROM b1"
+		query := parse(text)
+		result := context.prefetchSubquery(query, nil)
+		if result == nil {
+			t.Fatalf("Expected %s to be prefetched", text)
+		}
+		result.stop()
+		v, er := context.EvaluateSubquery(query, nil)
+		if er != nil || !v.Equals(value.NewValue([]interface{}{3})).Truth() {
+			t.Errorf("Expected [3] for %s, got %v %v", text, v, er)
+		}
+
+		// as are subqueries already evaluated
+		if context.prefetchSubquery(query, nil) != nil {
+			t.Errorf("Expected %s not to be prefetched again", text)
+		}
+
+		// errors are those of the subquery
+		text = "SELECT RAW COUNT(*) FROM nosuch"
+		query = parse(text)
+		if context.prefetchSubquery(query, nil) == nil {
+			t.Fatalf("Expected %s to be prefetched", text)
+		}
+		if _, er = context.EvaluateSubquery(query, nil); er == nil {
+			t.Errorf("Expected an error for %s", text)
+		}
+		expectWorkersReleased(t, text, context)
+	}
+}
+
+// counts the UNION ALL branches running at once
+type branchProbe struct {
+	sync.Mutex
+	running int
+	max     int
+}
+
+func (this *branchProbe) start() {
+	this.Lock()
+	this.running++
+	if this.running > this.max {
+		this.max = this.running
+	}
+	this.Unlock()
+}
+
+func (this *branchProbe) finish() {
+	this.Lock()
+	this.running--
+	this.Unlock()
+}
+
+// a branch, which is running from when it starts until it notifies
+// the UNION ALL that it is done
+type probedBranch struct {
+	Operator
+	probe *branchProbe
+}
+
+func (this *probedBranch) SetParent(parent Operator) {
+	this.Operator.SetParent(&probedParent{parent, this.probe})
+}
+
+func (this *probedBranch) RunOnce(context *Context, parent value.Value) {
+	this.probe.start()
+	this.Operator.RunOnce(context, parent)
+}
+
+type probedParent struct {
+	Operator
+	probe *branchProbe
+}
+
+func (this *probedParent) keepAlive(op Operator) bool {
+	this.probe.finish()
+	return this.Operator.keepAlive(this.Operator)
+}
+
+// the branches of a UNION ALL of several SELECTs are nested UNION ALLs
+func probeUnionAll(op Operator, probe *branchProbe) bool {
+	switch op := op.(type) {
+	case *UnionAll:
+		for c, child := range op.children {
+			if !probeUnionAll(child, probe) {
+				op.children[c] = &probedBranch{child, probe}
+			}
+		}
+		return true
+	case *Sequence:
+		for _, child := range op.children {
+			if probeUnionAll(child, probe) {
+				return true
+			}
+		}
+	case *Authorize:
+		return probeUnionAll(op.child, probe)
+	}
+	return false
+}
+
+func TestUnionAllBranches(t *testing.T) {
+	text := "SELECT a.i FROM b0 AS a UNION ALL SELECT b.i FROM b1 AS b " +
+		"UNION ALL SELECT c.i FROM b0 AS c UNION ALL SELECT d.i FROM b1 AS d"
+	expected := make([]string, 0, 12)
+	for i := 0; i < 4; i++ {
+		expected = append(expected, `{"i":0}`, `{"i":1}`, `{"i":2}`)
+	}
+
+	// max_parallelism 1, 2 and 4
+	for _, workers := range []int{0, 1, 3} {
+		for i := 0; i < 10; i++ {
+			probe := &branchProbe{}
+			_, results, context := runStatementOperator(t, text, 0, workers, func(op Operator) {
+				if !probeUnionAll(op, probe) {
+					t.Fatalf("Expected a UNION ALL operator for %s", text)
+				}
+			})
+			expectResults(t, text, results, expected...)
+			expectWorkersReleased(t, text, context)
+
+			if probe.max < 1 || probe.max > workers+1 {
+				t.Errorf("Expected at most %d branches of %s to run at once, got %d", workers+1, text, probe.max)
+			}
+		}
+	}
+}
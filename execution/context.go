@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/planner"
 	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/value"
 )
 
@@ -122,6 +124,7 @@ type Output interface {
 
 type Context struct {
 	requestId          string
+	clientContextId    string
 	datastore          datastore.Datastore
 	systemstore        datastore.Datastore
 	namespace          string
@@ -143,12 +146,16 @@ type Context struct {
 	prepared           *plan.Prepared
 	subplans           *subqueryMap
 	subresults         *subqueryMap
+	prefetched         *subqueryMap
+	workers            chan bool
 	httpRequest        *http.Request
 	authenticatedUsers auth.AuthenticatedUsers
 	mutex              sync.RWMutex
 	whitelist          map[string]interface{}
 	inlistHashMap      map[*expression.In]*expression.InlistHash
 	inlistHashLock     sync.RWMutex
+	trace              *tracing.Trace
+	traceParent        *tracing.Span
 }
 
 func NewContext(requestId string, datastore, systemstore datastore.Datastore,
@@ -187,6 +194,7 @@ func NewContext(requestId string, datastore, systemstore datastore.Datastore,
 	if rv.maxParallelism <= 0 || rv.maxParallelism > runtime.NumCPU() {
 		rv.maxParallelism = runtime.NumCPU()
 	}
+	rv.workers = make(chan bool, rv.maxParallelism-1)
 
 	return rv
 }
@@ -199,6 +207,14 @@ func (this *Context) RequestId() string {
 	return this.requestId
 }
 
+func (this *Context) SetClientContextId(clientContextId string) {
+	this.clientContextId = clientContextId
+}
+
+func (this *Context) ClientContextId() string {
+	return this.clientContextId
+}
+
 func (this *Context) Type() string {
 	if this.prepared != nil {
 		return this.prepared.Type()
@@ -218,6 +234,20 @@ func (this *Context) GetWhitelist() map[string]interface{} {
 	return this.whitelist
 }
 
+func (this *Context) SetTrace(trace *tracing.Trace, parent *tracing.Span) {
+	this.trace = trace
+	this.traceParent = parent
+}
+
+func (this *Context) Trace() *tracing.Trace {
+	return this.trace
+}
+
+// starts a span for an operator phase or a datastore call, if the request is being traced
+func (this *Context) StartSpan(name string, kind tracing.SpanKind) *tracing.Span {
+	return this.trace.StartSpan(name, kind, this.traceParent)
+}
+
 func (this *Context) DatastoreVersion() string {
 	return this.datastore.Info().Version()
 }
@@ -395,6 +425,25 @@ func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value)
 		return subresult.(value.Value), nil
 	}
 
+	// wait for the subquery, if it is being evaluated concurrently
+	// if it was stopped, or did not complete, evaluate it here
+	prefetched, ok := this.getPrefetched().get(query)
+	if ok {
+		result := prefetched.(*subqueryResult)
+		<-result.done
+		if result.result != nil || result.err != nil {
+			return result.result, result.err
+		}
+	}
+
+	return this.evaluateSubquery(query, parent, nil)
+}
+
+// a prefetched subquery can be stopped while it runs, in which case
+// its results are discarded
+func (this *Context) evaluateSubquery(query *algebra.Select, parent value.Value,
+	prefetch *subqueryResult) (value.Value, error) {
+	subresults := this.getSubresults()
 	subplans := this.getSubplans()
 	subplan, planFound := subplans.get(query)
 
@@ -419,22 +468,121 @@ func (this *Context) EvaluateSubquery(query *algebra.Select, parent value.Value)
 	// FIXME: this should handled by the planner
 	collect := NewCollect(plan.NewCollect(), this)
 	sequence := NewSequence(plan.NewSequence(), this, pipeline, collect)
+	if prefetch != nil && !prefetch.start(sequence) {
+		sequence.Done()
+		return nil, nil
+	}
 	sequence.RunOnce(this, parent)
 
 	// Await completion
 	collect.waitComplete()
 
 	results := collect.ValuesOnce()
+	if prefetch != nil && !prefetch.finish() {
+		sequence.Done()
+		return nil, nil
+	}
 	sequence.Done()
 
 	// Cache results
-	if !planFound && !query.IsCorrelated() {
+	// the plan may have been built by a prefetch that was stopped
+	if !query.IsCorrelated() {
 		subresults.set(query, results)
 	}
 
 	return results, nil
 }
 
+type subqueryResult struct {
+	sync.Mutex
+	done     chan bool
+	result   value.Value
+	err      error
+	sequence *Sequence // while running
+	stopped  bool
+}
+
+// note the running subquery, unless it has already been stopped
+func (this *subqueryResult) start(sequence *Sequence) bool {
+	this.Lock()
+	defer this.Unlock()
+	if this.stopped {
+		return false
+	}
+	this.sequence = sequence
+	return true
+}
+
+// whether the subquery completed without being stopped
+func (this *subqueryResult) finish() bool {
+	this.Lock()
+	defer this.Unlock()
+	this.sequence = nil
+	return !this.stopped
+}
+
+/*
+Stop a prefetched subquery whose result is no longer needed, such as
+when the request is stopped, or has produced all the rows it needs.
+Anybody still waiting for its result evaluates the subquery instead.
+*/
+func (this *subqueryResult) stop() {
+	this.Lock()
+	defer this.Unlock()
+	this.stopped = true
+	if this.sequence != nil {
+		this.sequence.SendStop()
+	}
+}
+
+/*
+Start evaluating an uncorrelated subquery on a worker, if one is free,
+so that its result is ready by the time the rows using it need it.
+The caller stops the subquery once the rows are done.
+*/
+func (this *Context) prefetchSubquery(query *algebra.Select, parent value.Value) *subqueryResult {
+	if query.IsCorrelated() {
+		return nil
+	}
+	_, ok := this.getSubresults().get(query)
+	if ok || !this.acquireWorker() {
+		return nil
+	}
+
+	result := &subqueryResult{done: make(chan bool)}
+	if !this.getPrefetched().add(query, result) {
+		this.releaseWorker()
+		return nil
+	}
+
+	go func() {
+		defer this.releaseWorker()
+		defer close(result.done)
+		defer this.Recover() // Recover from any panic
+		result.result, result.err = this.evaluateSubquery(query, parent, result)
+	}()
+	return result
+}
+
+/*
+Independent subplans run concurrently with the rest of the request
+on workers, so that no more than max_parallelism of them, counting
+the request's own, run at once. Workers are never waited for: with
+none free, subplans simply run serially.
+*/
+func (this *Context) acquireWorker() bool {
+	select {
+	case this.workers <- true:
+		return true
+	default:
+		return false
+	}
+}
+
+func (this *Context) releaseWorker() {
+	<-this.workers
+}
+
 func (this *Context) getSubplans() *subqueryMap {
 	if this.contextSubplans() == nil {
 		this.initSubplans()
@@ -477,6 +625,27 @@ func (this *Context) initSubresults() {
 	}
 }
 
+func (this *Context) getPrefetched() *subqueryMap {
+	if this.contextPrefetched() == nil {
+		this.initPrefetched()
+	}
+	return this.contextPrefetched()
+}
+
+func (this *Context) contextPrefetched() *subqueryMap {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.prefetched
+}
+
+func (this *Context) initPrefetched() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.prefetched == nil {
+		this.prefetched = newSubqueryMap()
+	}
+}
+
 // Synchronized map
 type subqueryMap struct {
 	mutex   sync.RWMutex
@@ -502,11 +671,74 @@ func (this *subqueryMap) set(key *algebra.Select, value interface{}) {
 	this.mutex.Unlock()
 }
 
+// set, unless already set
+func (this *subqueryMap) add(key *algebra.Select, value interface{}) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	_, ok := this.entries[key]
+	if !ok {
+		this.entries[key] = value
+	}
+	return !ok
+}
+
+/*
+Log entries made on behalf of a request carry the request id,
+client context id and users, so that they can be correlated with it.
+*/
+func (this *Context) Logp(level logging.Level, msg string, kv ...logging.Pair) {
+	if !logging.Enabled(level) {
+		return
+	}
+	logging.Logp(level, msg, append(kv, this.logPairs()...)...)
+}
+
+func (this *Context) Logf(level logging.Level, format string, args ...interface{}) {
+	if !logging.Enabled(level) {
+		return
+	}
+	logging.Logp(level, fmt.Sprintf(format, args...), this.logPairs()...)
+}
+
+// the users are only known for certain once the request has been authorized
+func (this *Context) logPairs() []logging.Pair {
+	users := this.authenticatedUsers
+	if len(users) == 0 {
+		users = CredentialUsers(this.credentials)
+	}
+	return RequestLogPairs(this.requestId, this.clientContextId, users)
+}
+
+func CredentialUsers(credentials auth.Credentials) []string {
+	rv := make([]string, 0, len(credentials))
+	for user := range credentials {
+		if user != "" {
+			rv = append(rv, user)
+		}
+	}
+	return rv
+}
+
+/*
+The pairs identifying a request in the log entries made on its behalf.
+*/
+func RequestLogPairs(requestId string, clientContextId string, users []string) []logging.Pair {
+	rv := make([]logging.Pair, 1, 3)
+	rv[0] = logging.Pair{"request_id", requestId}
+	if clientContextId != "" {
+		rv = append(rv, logging.Pair{"client_context_id", clientContextId})
+	}
+	if len(users) > 0 {
+		rv = append(rv, logging.Pair{"user", "<ud>" + strings.Join(users, ",") + "</ud>"})
+	}
+	return rv
+}
+
 func (this *Context) assert(test bool, what string) bool {
 	if test {
 		return true
 	}
-	logging.Severef("assert failure: %v\n\nrequest text:\n<ud>%v</ud>\n",
+	this.Logf(logging.SEVERE, "assert failure: %v\n\nrequest text:\n<ud>%v</ud>\n",
 		what, this.prepared.Text())
 	this.Abort(errors.NewExecutionInternalError(what))
 	return false
@@ -518,7 +750,7 @@ func (this *Context) Recover() {
 		buf := make([]byte, 1<<16)
 		n := runtime.Stack(buf, false)
 		s := string(buf[0:n])
-		logging.Severef("panic: %v\n\nrequest text:\n<ud>%v</ud>\n\nstack:\n%v",
+		this.Logf(logging.SEVERE, "panic: %v\n\nrequest text:\n<ud>%v</ud>\n\nstack:\n%v",
 			err, this.prepared.Text(), s)
 
 		// TODO - this may very well be a duplicate, if the orchestrator is redirecting
@@ -0,0 +1,37 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/logging"
+)
+
+func TestRequestLogPairs(t *testing.T) {
+	pairs := RequestLogPairs("1234", "", nil)
+	expected := []logging.Pair{logging.Pair{Name: "request_id", Value: "1234"}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+
+	users := CredentialUsers(auth.Credentials{"admin": "password", "": "ignored"})
+	pairs = RequestLogPairs("1234", "abc", users)
+	expected = []logging.Pair{
+		logging.Pair{Name: "request_id", Value: "1234"},
+		logging.Pair{Name: "client_context_id", Value: "abc"},
+		logging.Pair{Name: "user", Value: "<ud>admin</ud>"},
+	}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("Expected %v, got %v", expected, pairs)
+	}
+}
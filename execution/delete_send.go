@@ -119,7 +119,9 @@ func (this *SendDelete) flushBatch(context *Context) bool {
 
 	this.switchPhase(_SERVTIME)
 
+	span := this.startCallSpan("delete", this.plan.Keyspace().Name(), context)
 	deleted_keys, e := this.plan.Keyspace().Delete(keys, context)
+	endCallSpan(span, len(deleted_keys), e)
 
 	this.switchPhase(_EXECTIME)
 
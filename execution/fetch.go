@@ -122,7 +122,9 @@ func (this *Fetch) flushBatch(context *Context) bool {
 	this.switchPhase(_SERVTIME)
 
 	// Fetch
+	span := this.startCallSpan("fetch", this.plan.Keyspace().Name(), context)
 	errs := this.plan.Keyspace().Fetch(fetchKeys, fetchMap, context, this.plan.SubPaths())
+	endCallSpan(span, len(fetchKeys), errs...)
 
 	this.switchPhase(_EXECTIME)
 
@@ -103,6 +103,7 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 
 	keyExpr := this.plan.Key()
 	valExpr := this.plan.Value()
+	rule := validationRule(this.plan.Keyspace())
 	var key, val value.Value
 	var err error
 	var ok bool
@@ -152,6 +153,10 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 			continue
 		}
 
+		if !validateDocument(rule, this.plan.Keyspace(), dpair.Name, val, context) {
+			continue
+		}
+
 		dpair.Value = val
 		i++
 	}
@@ -162,7 +167,9 @@ func (this *SendInsert) flushBatch(context *Context) bool {
 
 	// Perform the actual INSERT
 	var er errors.Error
+	span := this.startCallSpan("insert", this.plan.Keyspace().Name(), context)
 	dpairs, er = this.plan.Keyspace().Insert(dpairs)
+	endCallSpan(span, len(dpairs), er)
 
 	this.switchPhase(_EXECTIME)
 
@@ -77,7 +77,9 @@ func (this *joinBase) joinFetch(keyspace datastore.Keyspace, keyCount map[string
 	}
 
 	this.switchPhase(_SERVTIME)
+	span := this.startCallSpan("fetch", keyspace.Name(), context)
 	errs := keyspace.Fetch(fetchKeys, pairMap, context, nil)
+	endCallSpan(span, len(fetchKeys), errs...)
 	this.switchPhase(_EXECTIME)
 
 	fetchOk := true
@@ -27,6 +27,7 @@ type HashJoin struct {
 	hashTab   *util.HashTable
 	buildVals value.Values
 	probeVals value.Values
+	matched   map[value.AnnotatedValue]bool // build side rows joined, for RIGHT or FULL OUTER JOIN
 }
 
 func NewHashJoin(plan *plan.HashJoin, context *Context, child Operator) *HashJoin {
@@ -84,6 +85,9 @@ func (this *HashJoin) beforeItems(context *Context, parent value.Value) bool {
 
 	this.buildVals = make(value.Values, len(this.plan.BuildExprs()))
 	this.probeVals = make(value.Values, len(this.plan.ProbeExprs()))
+	if this.plan.RightOuter() {
+		this.matched = make(map[value.AnnotatedValue]bool)
+	}
 
 	this.child.SetOutput(this.child)
 	this.child.SetInput(nil)
@@ -92,8 +96,14 @@ func (this *HashJoin) beforeItems(context *Context, parent value.Value) bool {
 
 	go this.child.RunOnce(context, parent)
 
-	return buildHashTab(&(this.base), this.child, this.hashTab,
+	// the left-hand side does not need the hash table until it is built
+	concurrent := this.plan.Concurrent() && this.runInputConcurrently(context, parent)
+	ok := buildHashTab(&(this.base), this.child, this.hashTab,
 		this.plan.BuildExprs(), this.buildVals, context)
+	if concurrent {
+		context.releaseWorker()
+	}
+	return ok
 }
 
 func buildHashTab(base *base, buildOp Operator, hashTab *util.HashTable,
@@ -192,6 +202,9 @@ func (this *HashJoin) processItem(item value.AnnotatedValue, context *Context) b
 				this.plan.BuildAliases(), this.ansiFlags, context, "join")
 			if match && ok {
 				matched = true
+				if this.matched != nil {
+					this.matched[right_item] = true
+				}
 				ok = this.sendItem(joined)
 			}
 		} else {
@@ -214,8 +227,21 @@ func (this *HashJoin) processItem(item value.AnnotatedValue, context *Context) b
 }
 
 func (this *HashJoin) afterItems(context *Context) {
-	this.dropHashTable()
+	defer this.dropHashTable()
+	defer func() { this.matched = nil }()
+
 	this.plan.Onclause().ResetMemory(context)
+
+	// build side rows not joined with any probe side row
+	if this.plan.RightOuter() && this.hashTab != nil && !this.stopped {
+		for outVal := this.hashTab.Iterate(); outVal != nil; outVal = this.hashTab.Iterate() {
+			if right_item, ok := outVal.(value.AnnotatedValue); ok && !this.matched[right_item] {
+				if !this.sendItem(right_item) {
+					return
+				}
+			}
+		}
+	}
 }
 
 func (this *HashJoin) dropHashTable() {
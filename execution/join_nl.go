@@ -27,9 +27,11 @@ const (
 
 type NLJoin struct {
 	base
-	plan      *plan.NLJoin
-	child     Operator
-	ansiFlags uint32
+	plan       *plan.NLJoin
+	child      Operator
+	ansiFlags  uint32
+	rightItems value.AnnotatedValues // right-hand side of RIGHT or FULL OUTER JOIN
+	matched    []bool
 }
 
 func NewNLJoin(plan *plan.NLJoin, context *Context, child Operator) *NLJoin {
@@ -82,12 +84,62 @@ func (this *NLJoin) beforeItems(context *Context, parent value.Value) bool {
 		this.plan.Onclause().EnableInlistHash(context)
 	}
 
+	// the right-hand side of RIGHT or FULL OUTER JOIN does not depend on
+	// the left-hand side, and is read only once
+	if this.plan.RightOuter() {
+		this.rightItems = nil
+		this.matched = nil
+
+		this.child.SetOutput(this.child)
+		this.child.SetInput(nil)
+		this.child.SetParent(this)
+		this.child.SetStop(nil)
+
+		go this.child.RunOnce(context, parent)
+
+		return this.readRightItems(context)
+	}
+
 	return true
 }
 
+func (this *NLJoin) readRightItems(context *Context) bool {
+	stopped := false
+	n := 1
+
+loop:
+	for {
+		right_item, child, cont := this.getItemChildrenOp(this.child)
+		if cont {
+			if right_item != nil {
+				this.rightItems = append(this.rightItems, right_item)
+			} else if child >= 0 {
+				n--
+			} else {
+				break loop
+			}
+		} else {
+			stopped = true
+			break loop
+		}
+	}
+
+	if n > 0 {
+		notifyChildren(this.child)
+		this.childrenWaitNoStop(n)
+	}
+
+	this.matched = make([]bool, len(this.rightItems))
+	return !stopped
+}
+
 func (this *NLJoin) processItem(item value.AnnotatedValue, context *Context) bool {
 	defer this.switchPhase(_EXECTIME)
 
+	if this.plan.RightOuter() {
+		return this.processRightOuter(item, context)
+	}
+
 	if (this.ansiFlags & ANSI_REOPEN_CHILD) != 0 {
 		if this.child != nil {
 			this.child.SendStop()
@@ -150,8 +202,48 @@ loop:
 	return true
 }
 
+func (this *NLJoin) processRightOuter(item value.AnnotatedValue, context *Context) bool {
+	matched := false
+	aliases := []string{this.plan.Alias()}
+
+	for i, right_item := range this.rightItems {
+		match, ok, joined := processAnsiExec(item, right_item, this.plan.Onclause(),
+			aliases, this.ansiFlags, context, "join")
+		if !ok {
+			return false
+		}
+		if match {
+			matched = true
+			this.matched[i] = true
+			if !this.sendItem(joined) {
+				return false
+			}
+		}
+	}
+
+	if this.plan.Outer() && !matched {
+		return this.sendItem(item)
+	}
+
+	return true
+}
+
 func (this *NLJoin) afterItems(context *Context) {
+	defer func() {
+		this.rightItems = nil
+		this.matched = nil
+	}()
+
 	this.plan.Onclause().ResetMemory(context)
+
+	// right-hand side rows not joined with any left-hand side row
+	if this.plan.RightOuter() && !this.stopped {
+		for i, right_item := range this.rightItems {
+			if !this.matched[i] && !this.sendItem(right_item) {
+				return
+			}
+		}
+	}
 }
 
 func processAnsiExec(item value.AnnotatedValue, right_item value.AnnotatedValue,
@@ -0,0 +1,186 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+type HashSemiJoin struct {
+	base
+	plan      *plan.HashSemiJoin
+	child     Operator
+	ansiFlags uint32
+	hashTab   *util.HashTable
+	buildVals value.Values
+	probeVals value.Values
+}
+
+func NewHashSemiJoin(plan *plan.HashSemiJoin, context *Context, child Operator) *HashSemiJoin {
+	rv := &HashSemiJoin{
+		plan:  plan,
+		child: child,
+	}
+
+	newBase(&rv.base, context)
+	rv.trackChildren(1)
+	rv.execPhase = HASH_JOIN
+	rv.output = rv
+	return rv
+}
+
+func (this *HashSemiJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitHashSemiJoin(this)
+}
+
+func (this *HashSemiJoin) Copy() Operator {
+	rv := &HashSemiJoin{
+		plan:  this.plan,
+		child: this.child.Copy(),
+	}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *HashSemiJoin) RunOnce(context *Context, parent value.Value) {
+	this.runConsumer(this, context, parent)
+}
+
+func (this *HashSemiJoin) beforeItems(context *Context, parent value.Value) bool {
+	if !context.assert(this.child != nil, "Hash Semi Join has no child") {
+		return false
+	}
+	if !context.assert(this.plan.Onclause() != nil, "Hash Semi Join does not have onclause") {
+		return false
+	}
+
+	// check for constant TRUE or FALSE onclause
+	cpred := this.plan.Onclause().Value()
+	if cpred != nil {
+		if cpred.Truth() {
+			this.ansiFlags |= ANSI_ONCLAUSE_TRUE
+		} else {
+			this.ansiFlags |= ANSI_ONCLAUSE_FALSE
+		}
+	} else {
+		this.plan.Onclause().EnableInlistHash(context)
+	}
+
+	// build hash table from the right-hand side
+	this.hashTab = util.NewHashTable(util.HASH_TABLE_FOR_HASH_JOIN)
+
+	this.buildVals = make(value.Values, len(this.plan.BuildExprs()))
+	this.probeVals = make(value.Values, len(this.plan.ProbeExprs()))
+
+	this.child.SetOutput(this.child)
+	this.child.SetInput(nil)
+	this.child.SetParent(this)
+	this.child.SetStop(nil)
+
+	go this.child.RunOnce(context, parent)
+
+	// the left-hand side does not need the hash table until it is built
+	concurrent := this.plan.Concurrent() && this.runInputConcurrently(context, parent)
+	ok := buildHashTab(&(this.base), this.child, this.hashTab,
+		this.plan.BuildExprs(), this.buildVals, context)
+	if concurrent {
+		context.releaseWorker()
+	}
+	return ok
+}
+
+func (this *HashSemiJoin) processItem(item value.AnnotatedValue, context *Context) bool {
+	defer this.switchPhase(_EXECTIME)
+
+	probeVal := getProbeVal(item, this.plan.ProbeExprs(), this.probeVals, context)
+	if probeVal == nil {
+		return false
+	}
+
+	outVal, err := this.hashTab.Get(probeVal, value.MarshalValue, value.EqualValue)
+	if err != nil {
+		context.Error(errors.NewHashTableGetError(err))
+		return false
+	}
+
+	// the matching right-hand side rows are read to the end, leaving
+	// no position in the hash table for the next probe
+	aliases := []string{this.plan.Alias()}
+	matched := false
+	for outVal != nil {
+		if !matched {
+			right_item, ok := outVal.(value.AnnotatedValue)
+			if !ok {
+				context.Error(errors.NewExecutionInternalError("Hash Table Get produced non-Annotated value"))
+				return false
+			}
+
+			matched, ok, _ = processAnsiExec(item, right_item, this.plan.Onclause(),
+				aliases, this.ansiFlags, context, "join")
+			if !ok {
+				return false
+			}
+		}
+
+		outVal, err = this.hashTab.GetNext()
+		if err != nil {
+			context.Error(errors.NewHashTableGetError(err))
+			return false
+		}
+	}
+
+	if matched != this.plan.Anti() {
+		return this.sendItem(item)
+	}
+
+	return true
+}
+
+func (this *HashSemiJoin) afterItems(context *Context) {
+	defer this.dropHashTable()
+
+	this.plan.Onclause().ResetMemory(context)
+}
+
+func (this *HashSemiJoin) dropHashTable() {
+	if this.hashTab != nil {
+		this.hashTab.Drop()
+		this.hashTab = nil
+	}
+}
+
+func (this *HashSemiJoin) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+		r["~child"] = this.child
+	})
+	return json.Marshal(r)
+}
+
+func (this *HashSemiJoin) SendStop() {
+	this.baseSendStop()
+	child := this.child
+	if child != nil {
+		child.SendStop()
+	}
+}
+
+func (this *HashSemiJoin) Done() {
+	this.baseDone()
+	if this.child != nil {
+		this.child.Done()
+	}
+	this.child = nil
+}
@@ -0,0 +1,173 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+type NLSemiJoin struct {
+	base
+	plan      *plan.NLSemiJoin
+	child     Operator
+	ansiFlags uint32
+}
+
+func NewNLSemiJoin(plan *plan.NLSemiJoin, context *Context, child Operator) *NLSemiJoin {
+	rv := &NLSemiJoin{
+		plan:  plan,
+		child: child,
+	}
+
+	newBase(&rv.base, context)
+	rv.trackChildren(1)
+	rv.execPhase = NL_JOIN
+	rv.output = rv
+	return rv
+}
+
+func (this *NLSemiJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitNLSemiJoin(this)
+}
+
+func (this *NLSemiJoin) Copy() Operator {
+	rv := &NLSemiJoin{
+		plan:  this.plan,
+		child: this.child.Copy(),
+	}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *NLSemiJoin) RunOnce(context *Context, parent value.Value) {
+	this.runConsumer(this, context, parent)
+}
+
+func (this *NLSemiJoin) beforeItems(context *Context, parent value.Value) bool {
+	if !context.assert(this.child != nil, "Nested Loop Semi Join has no child") {
+		return false
+	}
+	if !context.assert(this.plan.Onclause() != nil, "Nested Loop Semi Join does not have onclause") {
+		return false
+	}
+
+	// check for constant TRUE or FALSE onclause
+	cpred := this.plan.Onclause().Value()
+	if cpred != nil {
+		if cpred.Truth() {
+			this.ansiFlags |= ANSI_ONCLAUSE_TRUE
+		} else {
+			this.ansiFlags |= ANSI_ONCLAUSE_FALSE
+		}
+	} else {
+		this.plan.Onclause().EnableInlistHash(context)
+	}
+
+	return true
+}
+
+func (this *NLSemiJoin) processItem(item value.AnnotatedValue, context *Context) bool {
+	defer this.switchPhase(_EXECTIME)
+
+	if (this.ansiFlags & ANSI_REOPEN_CHILD) != 0 {
+		if this.child != nil {
+			this.child.SendStop()
+			this.child.reopen(context)
+		}
+	} else {
+		this.ansiFlags |= ANSI_REOPEN_CHILD
+	}
+
+	this.child.SetOutput(this.child)
+	this.child.SetInput(nil)
+	this.child.SetParent(this)
+	this.child.SetStop(nil)
+
+	go this.child.RunOnce(context, item)
+
+	ok := true
+	matched := false
+	stopped := false
+	n := 1
+	aliases := []string{this.plan.Alias()}
+
+	// stop at the first matching right-hand side row
+loop:
+	for ok && !matched {
+		right_item, child, cont := this.getItemChildrenOp(this.child)
+		if cont {
+			if right_item != nil {
+				matched, ok, _ = processAnsiExec(item, right_item, this.plan.Onclause(),
+					aliases, this.ansiFlags, context, "join")
+			} else if child >= 0 {
+				n--
+			} else {
+				break loop
+			}
+		} else {
+			stopped = true
+			break loop
+		}
+	}
+
+	if n > 0 {
+		notifyChildren(this.child)
+		this.childrenWaitNoStop(n)
+	}
+
+	if stopped || !ok {
+		return false
+	}
+
+	if matched != this.plan.Anti() {
+		return this.sendItem(item)
+	}
+
+	return true
+}
+
+func (this *NLSemiJoin) afterItems(context *Context) {
+	this.plan.Onclause().ResetMemory(context)
+}
+
+func (this *NLSemiJoin) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+		r["~child"] = this.child
+	})
+	return json.Marshal(r)
+}
+
+func (this *NLSemiJoin) SendStop() {
+	this.baseSendStop()
+	child := this.child
+	if child != nil {
+		child.SendStop()
+	}
+}
+
+func (this *NLSemiJoin) reopen(context *Context) {
+	this.baseReopen(context)
+	this.ansiFlags &^= ANSI_REOPEN_CHILD
+	if this.child != nil {
+		this.child.reopen(context)
+	}
+}
+
+func (this *NLSemiJoin) Done() {
+	this.baseDone()
+	if this.child != nil {
+		this.child.Done()
+	}
+	this.child = nil
+}
@@ -0,0 +1,77 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"testing"
+)
+
+func TestSemiJoins(t *testing.T) {
+	cases := []struct {
+		text     string
+		operator string
+		expected []string
+	}{
+		{"SELECT RAW a.i FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c WHERE c.i = a.i + 1)",
+			"HashSemiJoin", []string{"0", "1"}},
+		{"SELECT RAW a.i FROM b0 AS a WHERE NOT EXISTS (SELECT 1 FROM b1 AS c WHERE c.i = a.i + 1)",
+			"HashAntiJoin", []string{"2"}},
+		{"SELECT RAW a.i FROM b0 AS a WHERE a.i + 1 IN (SELECT RAW c.i FROM b1 AS c WHERE c.id = a.id OR c.i > 0)",
+			"HashSemiJoin", []string{"0", "1"}},
+		{"SELECT RAW a.i FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c USE KEYS a.id WHERE c.i > 0)",
+			"NestedLoopSemiJoin", []string{"1", "2"}},
+		{"SELECT RAW a.i FROM b0 AS a WHERE NOT EXISTS (SELECT 1 FROM b1 AS c USE KEYS a.id WHERE c.i > 0)",
+			"NestedLoopAntiJoin", []string{"0"}},
+
+		// a NULL on the right-hand side never matches
+		{"SELECT RAW a.i FROM b0 AS a WHERE a.i IN " +
+			"(SELECT RAW CASE WHEN c.i = 1 THEN NULL ELSE c.i END FROM b1 AS c WHERE c.i = a.i)",
+			"HashSemiJoin", []string{"0", "2"}},
+	}
+
+	for _, c := range cases {
+		prepared, results := runStatement(t, c.text, 0)
+		expectResults(t, c.text, results, c.expected...)
+		expectOperator(t, c.text, prepared, c.operator, false)
+	}
+}
+
+func TestNotInSubquery(t *testing.T) {
+	// NOT IN is not an anti-join: it is NULL, and rejects the row, when the
+	// value is not found but the subquery returns a NULL
+	text := "SELECT RAW a.i FROM b0 AS a WHERE a.i NOT IN (SELECT RAW CASE WHEN c.i = 1 THEN NULL ELSE c.i END " +
+		"FROM b1 AS c USE KEYS [\"0\", \"1\", \"2\"] WHERE c.i != a.i)"
+	_, results := runStatement(t, text, 0)
+	expectResults(t, text, results, "1")
+}
+
+func TestScalarAggregateSubqueries(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected []string
+	}{
+		{"SELECT a.i, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.i = a.i AND c.i > 0)[0] AS n FROM b0 AS a",
+			[]string{`{"i":0,"n":0}`, `{"i":1,"n":1}`, `{"i":2,"n":1}`}},
+		{"SELECT RAW a.i FROM b0 AS a WHERE (SELECT RAW MAX(c.i) FROM b1 AS c WHERE c.i = a.i AND c.i < 2)[0] IS NULL",
+			[]string{"2"}},
+
+		// the outer join is kept for the default of COUNT
+		{"SELECT RAW a.i FROM b0 AS a WHERE 0 = (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.i = a.i + 1)[0]",
+			[]string{"2"}},
+		{"SELECT RAW a.i FROM b0 AS a WHERE (SELECT RAW SUM(c.i) FROM b1 AS c WHERE c.i = a.i + 1)[0] > 1",
+			[]string{"1"}},
+	}
+
+	for _, c := range cases {
+		prepared, results := runStatement(t, c.text, 0)
+		expectResults(t, c.text, results, c.expected...)
+		expectOperator(t, c.text, prepared, "HashJoin", false)
+	}
+}
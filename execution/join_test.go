@@ -0,0 +1,239 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/planner"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+type testOutput struct {
+	results []value.Value
+	errs    []errors.Error
+	done    chan bool
+}
+
+func newTestOutput() *testOutput {
+	return &testOutput{done: make(chan bool)}
+}
+
+func (this *testOutput) SetUp() {}
+
+func (this *testOutput) Result(item value.AnnotatedValue) bool {
+	this.results = append(this.results, item.Copy())
+	return true
+}
+
+func (this *testOutput) CloseResults()                              { close(this.done) }
+func (this *testOutput) Abort(err errors.Error)                     { this.errs = append(this.errs, err) }
+func (this *testOutput) Fatal(err errors.Error)                     { this.errs = append(this.errs, err) }
+func (this *testOutput) Error(err errors.Error)                     { this.errs = append(this.errs, err) }
+func (this *testOutput) Warning(wrn errors.Error)                   {}
+func (this *testOutput) AddMutationCount(uint64)                    {}
+func (this *testOutput) MutationCount() uint64                      { return 0 }
+func (this *testOutput) SortCount() uint64                          { return 0 }
+func (this *testOutput) SetSortCount(i uint64)                      {}
+func (this *testOutput) AddPhaseOperator(p Phases)                  {}
+func (this *testOutput) AddPhaseCount(p Phases, c uint64)           {}
+func (this *testOutput) FmtPhaseCounts() map[string]interface{}     { return nil }
+func (this *testOutput) FmtPhaseOperators() map[string]interface{}  { return nil }
+func (this *testOutput) AddPhaseTime(phase Phases, d time.Duration) {}
+func (this *testOutput) FmtPhaseTimes() map[string]interface{}      { return nil }
+
+/*
+Plans and runs a statement against a mock datastore of two keyspaces,
+b0 and b1, of three documents {"id": "n", "i": n} each.
+*/
+func runStatement(t *testing.T, text string, featureControls uint64) (plan.Operator, []string) {
+	prepared, results, _ := runStatementWorkers(t, text, featureControls, 0)
+	return prepared, results
+}
+
+/*
+As runStatement, with workers for subplans to run concurrently on,
+whatever the number of CPUs.
+*/
+func runStatementWorkers(t *testing.T, text string, featureControls uint64, workers int) (
+	plan.Operator, []string, *Context) {
+	return runStatementOperator(t, text, featureControls, workers, nil)
+}
+
+/*
+As runStatementWorkers, with a function to inspect or wrap the
+operators before they run.
+*/
+func runStatementOperator(t *testing.T, text string, featureControls uint64, workers int,
+	inspect func(Operator)) (plan.Operator, []string, *Context) {
+	ds, err := mock.NewDatastore("mock:keyspaces=2,items=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+
+	stmt, er := n1ql.ParseStatement(text)
+	if er != nil {
+		t.Fatalf("Unable to parse %s: %v", text, er)
+	}
+
+	prepared, er := planner.Build(stmt, ds, nil, "p0", false, nil, nil, datastore.INDEX_API_MAX, featureControls)
+	if er != nil {
+		t.Fatalf("Unable to plan %s: %v", text, er)
+	}
+
+	output := newTestOutput()
+	context := NewContext("test", ds, nil, "p0", true, 1, 0, 0, 0, nil, nil, nil,
+		datastore.UNBOUNDED, &noScanVectors{}, output, nil, nil, datastore.INDEX_API_MAX, featureControls)
+	if workers > 0 {
+		context.workers = make(chan bool, workers)
+	}
+	operator, er := Build(prepared, context)
+	if er != nil {
+		t.Fatalf("Unable to build %s: %v", text, er)
+	}
+	if inspect != nil {
+		inspect(operator)
+	}
+
+	go operator.RunOnce(context, nil)
+	<-output.done
+
+	if len(output.errs) > 0 {
+		t.Fatalf("Unable to run %s: %v", text, output.errs)
+	}
+
+	results := make([]string, len(output.results))
+	for i, result := range output.results {
+		bytes, _ := json.Marshal(result)
+		results[i] = string(bytes)
+	}
+	sort.Strings(results)
+	return prepared, results, context
+}
+
+func expectResults(t *testing.T, text string, results []string, expected ...string) {
+	sort.Strings(expected)
+	if len(results) != len(expected) {
+		t.Errorf("Expected %v for %s, got %v", expected, text, results)
+		return
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("Expected %v for %s, got %v", expected, text, results)
+			return
+		}
+	}
+}
+
+/*
+Checks that the plan has the join operator, and whether it is a
+RIGHT or FULL OUTER JOIN.
+*/
+func expectOperator(t *testing.T, text string, prepared plan.Operator, operator string, rightOuter bool) {
+	var find func(op interface{}) bool
+	find = func(op interface{}) bool {
+		switch op := op.(type) {
+		case map[string]interface{}:
+			if op["#operator"] == operator && (op["right_outer"] == true) == rightOuter {
+				return true
+			}
+			for _, child := range op {
+				if find(child) {
+					return true
+				}
+			}
+		case []interface{}:
+			for _, child := range op {
+				if find(child) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	bytes, _ := json.Marshal(prepared)
+	var unmarshalled interface{}
+	json.Unmarshal(bytes, &unmarshalled)
+	if !find(unmarshalled) {
+		t.Errorf("Expected %s with right_outer %v for %s, got %s", operator, rightOuter, text, bytes)
+	}
+}
+
+func TestRightOuterJoin(t *testing.T) {
+	left := `[{"x":0},{"x":1},{"x":5}]`
+	full := []string{
+		`{"i":0,"x":0}`,
+		`{"i":1,"x":1}`,
+		`{"i":2}`,
+		`{"x":5}`,
+	}
+	right := []string{
+		`{"i":0,"x":0}`,
+		`{"i":1,"x":1}`,
+		`{"i":2}`,
+	}
+
+	operators := map[uint64]string{0: "HashJoin", util.N1QL_HASH_JOIN: "NestedLoopJoin"}
+	for controls, operator := range operators {
+		text := "SELECT a.x, b.i FROM " + left + " AS a FULL JOIN b0 AS b ON a.x = b.i"
+		prepared, results := runStatement(t, text, controls)
+		expectResults(t, text, results, full...)
+		expectOperator(t, text, prepared, operator, true)
+
+		// RIGHT OUTER JOIN is only kept as such when its left-hand side is a join
+		text = "SELECT a.x, b.i FROM " + left + " AS a JOIN [0, 1, 5] AS c ON a.x = c " +
+			"RIGHT JOIN b0 AS b ON a.x = b.i"
+		prepared, results = runStatement(t, text, controls)
+		expectResults(t, text, results, right...)
+		expectOperator(t, text, prepared, operator, true)
+	}
+}
+
+func TestFullOuterJoinWhere(t *testing.T) {
+	left := `[{"x":0},{"x":1},{"x":5}]`
+
+	for _, controls := range []uint64{0, util.N1QL_HASH_JOIN} {
+		// does not reject nulls on either side: the join stays a FULL OUTER JOIN
+		text := "SELECT a.x, b.i FROM " + left + " AS a FULL JOIN b0 AS b ON a.x = b.i " +
+			"WHERE a.x IS MISSING OR b.i IS MISSING"
+		_, results := runStatement(t, text, controls)
+		expectResults(t, text, results, `{"i":2}`, `{"x":5}`)
+
+		text = "SELECT a.x, b.i FROM " + left + " AS a FULL JOIN b0 AS b ON a.x = b.i WHERE a.x IS MISSING"
+		_, results = runStatement(t, text, controls)
+		expectResults(t, text, results, `{"i":2}`)
+
+	}
+
+	// rejects nulls on the left-hand side: LEFT OUTER JOIN, which needs an index
+	// on the right-hand side for a nested-loop join
+	text := "SELECT a.x, b.i FROM " + left + " AS a FULL JOIN b0 AS b USE HASH(BUILD) ON a.x = b.i WHERE a.x > 0"
+	prepared, results := runStatement(t, text, 0)
+	expectResults(t, text, results, `{"i":1,"x":1}`, `{"x":5}`)
+	expectOperator(t, text, prepared, "HashJoin", false)
+}
+
+func TestCrossJoin(t *testing.T) {
+	text := "SELECT a.i AS x, b.i AS y FROM b0 AS a CROSS JOIN b1 AS b WHERE a.i < 2 AND b.i > 0"
+	prepared, results := runStatement(t, text, 0)
+	expectResults(t, text, results,
+		`{"x":0,"y":1}`, `{"x":0,"y":2}`, `{"x":1,"y":1}`, `{"x":1,"y":2}`)
+	expectOperator(t, text, prepared, "NestedLoopJoin", false)
+}
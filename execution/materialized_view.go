@@ -0,0 +1,355 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+	"github.com/couchbase/query/views"
+)
+
+type CreateMaterializedView struct {
+	base
+	plan *plan.CreateMaterializedView
+}
+
+func NewCreateMaterializedView(plan *plan.CreateMaterializedView, context *Context) *CreateMaterializedView {
+	rv := &CreateMaterializedView{
+		plan: plan,
+	}
+
+	newRedirectBase(&rv.base)
+	rv.output = rv
+	return rv
+}
+
+func (this *CreateMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateMaterializedView(this)
+}
+
+func (this *CreateMaterializedView) Copy() Operator {
+	rv := &CreateMaterializedView{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *CreateMaterializedView) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover() // Recover from any panic
+		this.active()
+		defer this.close(context)
+		this.switchPhase(_EXECTIME)
+		defer this.switchPhase(_NOTIME)
+		defer this.notify() // Notify that I have stopped
+
+		if context.Readonly() {
+			return
+		}
+
+		// Create and populate the view
+		this.switchPhase(_SERVTIME)
+		keyspace := this.plan.Keyspace()
+		_, mutations, err := views.CreateMaterializedView(keyspace.NamespaceId(), keyspace.Name(),
+			this.plan.Definition(), newViewEvaluator(context, keyspace.NamespaceId()))
+		context.AddMutationCount(mutations)
+		if err != nil {
+			context.Error(err)
+		}
+	})
+}
+
+func (this *CreateMaterializedView) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+type DropMaterializedView struct {
+	base
+	plan *plan.DropMaterializedView
+}
+
+func NewDropMaterializedView(plan *plan.DropMaterializedView, context *Context) *DropMaterializedView {
+	rv := &DropMaterializedView{
+		plan: plan,
+	}
+
+	newRedirectBase(&rv.base)
+	rv.output = rv
+	return rv
+}
+
+func (this *DropMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropMaterializedView(this)
+}
+
+func (this *DropMaterializedView) Copy() Operator {
+	rv := &DropMaterializedView{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *DropMaterializedView) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover() // Recover from any panic
+		this.active()
+		defer this.close(context)
+		this.switchPhase(_EXECTIME)
+		defer this.switchPhase(_NOTIME)
+		defer this.notify() // Notify that I have stopped
+
+		if context.Readonly() {
+			return
+		}
+
+		// Actually drop the view
+		this.switchPhase(_SERVTIME)
+		keyspace := this.plan.Keyspace()
+		err := views.DropMaterializedView(keyspace.NamespaceId(), keyspace.Name())
+		if err != nil {
+			context.Error(err)
+		}
+	})
+}
+
+func (this *DropMaterializedView) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+type RefreshMaterializedView struct {
+	base
+	plan *plan.RefreshMaterializedView
+}
+
+func NewRefreshMaterializedView(plan *plan.RefreshMaterializedView, context *Context) *RefreshMaterializedView {
+	rv := &RefreshMaterializedView{
+		plan: plan,
+	}
+
+	newRedirectBase(&rv.base)
+	rv.output = rv
+	return rv
+}
+
+func (this *RefreshMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitRefreshMaterializedView(this)
+}
+
+func (this *RefreshMaterializedView) Copy() Operator {
+	rv := &RefreshMaterializedView{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *RefreshMaterializedView) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover() // Recover from any panic
+		this.active()
+		defer this.close(context)
+		this.switchPhase(_EXECTIME)
+		defer this.switchPhase(_NOTIME)
+		defer this.notify() // Notify that I have stopped
+
+		if context.Readonly() {
+			return
+		}
+
+		this.switchPhase(_SERVTIME)
+		keyspace := this.plan.Keyspace()
+		view := views.GetMaterializedView(keyspace.NamespaceId(), keyspace.Name())
+		if view == nil {
+			context.Error(errors.NewNoSuchMaterializedViewError(keyspace.Name()))
+			return
+		}
+
+		mutations, err := view.Refresh(newViewEvaluator(context, view.Namespace))
+		context.AddMutationCount(mutations)
+		if err != nil {
+			context.Error(errors.NewMaterializedViewRefreshError(view.Name, err))
+		}
+	})
+}
+
+func (this *RefreshMaterializedView) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+// Runs the queries refreshing a materialized view, in the view's namespace
+type viewEvaluator struct {
+	*Context
+	output *viewOutput
+}
+
+// refreshes requested by a statement run as the statement
+func newViewEvaluator(context *Context, namespace string) *viewEvaluator {
+	output := &viewOutput{parent: context}
+	viewContext := NewContext(context.requestId, context.datastore, context.systemstore, namespace,
+		false, context.maxParallelism, context.scanCap, context.pipelineCap, context.pipelineBatch,
+		nil, nil, context.credentials, context.consistency, context.scanVectorSource, output,
+		context.httpRequest, nil, context.indexApiVersion, context.featureControls)
+	viewContext.reqDeadline = context.reqDeadline
+	viewContext.authenticatedUsers = context.authenticatedUsers
+	return &viewEvaluator{Context: viewContext, output: output}
+}
+
+// Returns the evaluator of background refreshes, which see all the
+// mutations made before they start
+func NewMaterializedViewEvaluator(store, systemstore datastore.Datastore) views.NewEvaluator {
+	return func(namespace string) views.Evaluator {
+		output := &viewOutput{}
+		context := NewContext("", store, systemstore, namespace, false, 0, 0, 0, 0,
+			nil, nil, nil, datastore.SCAN_PLUS, &noScanVectors{}, output, nil, nil,
+			util.GetMaxIndexAPI(), util.GetN1qlFeatureControl())
+		return &viewEvaluator{Context: context, output: output}
+	}
+}
+
+func (this *viewEvaluator) Evaluate(query *algebra.Select) (value.Value, errors.Error) {
+	rv, e := this.Context.EvaluateSubquery(query, nil)
+	if e != nil {
+		err, ok := e.(errors.Error)
+		if !ok {
+			err = errors.NewError(e, "")
+		}
+		return nil, err
+	}
+
+	err := this.output.takeError()
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// Background refreshes have no scan vectors
+type noScanVectors struct {
+}
+
+func (this *noScanVectors) ScanVector(namespace_id string, keyspace_name string) timestamp.Vector {
+	return nil
+}
+
+func (this *noScanVectors) Type() int32 {
+	return timestamp.NO_VECTORS
+}
+
+// Collects the errors of refresh queries
+type viewOutput struct {
+	sync.Mutex
+	parent        *Context // if any, gets the warnings
+	err           errors.Error
+	mutationCount uint64
+	sortCount     uint64
+}
+
+func (this *viewOutput) takeError() errors.Error {
+	this.Lock()
+	defer this.Unlock()
+	err := this.err
+	this.err = nil
+	return err
+}
+
+func (this *viewOutput) setError(err errors.Error) {
+	this.Lock()
+	defer this.Unlock()
+	if this.err == nil {
+		this.err = err
+	}
+}
+
+func (this *viewOutput) SetUp() {
+}
+
+func (this *viewOutput) Result(item value.AnnotatedValue) bool {
+	return true
+}
+
+func (this *viewOutput) CloseResults() {
+}
+
+func (this *viewOutput) Abort(err errors.Error) {
+	this.setError(err)
+}
+
+func (this *viewOutput) Fatal(err errors.Error) {
+	this.setError(err)
+}
+
+func (this *viewOutput) Error(err errors.Error) {
+	this.setError(err)
+}
+
+func (this *viewOutput) Warning(wrn errors.Error) {
+	if this.parent != nil {
+		this.parent.Warning(wrn)
+	}
+}
+
+func (this *viewOutput) AddMutationCount(i uint64) {
+	this.Lock()
+	this.mutationCount += i
+	this.Unlock()
+}
+
+func (this *viewOutput) MutationCount() uint64 {
+	this.Lock()
+	defer this.Unlock()
+	return this.mutationCount
+}
+
+func (this *viewOutput) SortCount() uint64 {
+	this.Lock()
+	defer this.Unlock()
+	return this.sortCount
+}
+
+func (this *viewOutput) SetSortCount(i uint64) {
+	this.Lock()
+	this.sortCount = i
+	this.Unlock()
+}
+
+func (this *viewOutput) AddPhaseOperator(p Phases) {
+}
+
+func (this *viewOutput) AddPhaseCount(p Phases, c uint64) {
+}
+
+func (this *viewOutput) FmtPhaseCounts() map[string]interface{} {
+	return nil
+}
+
+func (this *viewOutput) FmtPhaseOperators() map[string]interface{} {
+	return nil
+}
+
+func (this *viewOutput) AddPhaseTime(phase Phases, duration time.Duration) {
+}
+
+func (this *viewOutput) FmtPhaseTimes() map[string]interface{} {
+	return nil
+}
@@ -91,8 +91,14 @@ func (this *HashNest) beforeItems(context *Context, parent value.Value) bool {
 
 	go this.child.RunOnce(context, parent)
 
-	return buildHashTab(&(this.base), this.child, this.hashTab,
+	// the left-hand side does not need the hash table until it is built
+	concurrent := this.plan.Concurrent() && this.runInputConcurrently(context, parent)
+	ok := buildHashTab(&(this.base), this.child, this.hashTab,
 		this.plan.BuildExprs(), this.buildVals, context)
+	if concurrent {
+		context.releaseWorker()
+	}
+	return ok
 }
 
 func (this *HashNest) processItem(item value.AnnotatedValue, context *Context) bool {
@@ -12,6 +12,7 @@ package execution
 import (
 	"encoding/json"
 
+	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
@@ -20,7 +21,10 @@ import (
 
 type InitialProject struct {
 	base
-	plan *plan.InitialProject
+	plan       *plan.InitialProject
+	outer      value.Value       // the parent value of the subqueries
+	prefetch   bool              // start the uncorrelated subqueries with the first row
+	prefetched []*subqueryResult // the subqueries started
 }
 
 func NewInitialProject(plan *plan.InitialProject, context *Context) *InitialProject {
@@ -47,9 +51,46 @@ func (this *InitialProject) RunOnce(context *Context, parent value.Value) {
 	this.runConsumer(this, context, parent)
 }
 
+func (this *InitialProject) beforeItems(context *Context, parent value.Value) bool {
+	this.outer = parent
+	this.prefetch = this.plan.Concurrent()
+	return true
+}
+
+// start the uncorrelated subqueries once there is a row to project,
+// so that they run concurrently with each other and with the rows
+// that follow
+func (this *InitialProject) prefetchSubqueries(context *Context) {
+	subqueries, err := expression.ListSubqueries(this.plan.Projection().Expressions(), false)
+	if err != nil {
+		return
+	}
+	for _, subquery := range subqueries {
+		if subquery, ok := subquery.(*algebra.Subquery); ok {
+			if result := context.prefetchSubquery(subquery.Select(), this.outer); result != nil {
+				this.prefetched = append(this.prefetched, result)
+				this.addConcurrent(1)
+			}
+		}
+	}
+}
+
+// the subqueries still running are no longer needed
+func (this *InitialProject) afterItems(context *Context) {
+	for _, result := range this.prefetched {
+		result.stop()
+	}
+	this.prefetched = nil
+}
+
 var _EMPTY_ANNOTATED_VALUE = value.NewAnnotatedValue(map[string]interface{}{})
 
 func (this *InitialProject) processItem(item value.AnnotatedValue, context *Context) bool {
+	if this.prefetch {
+		this.prefetch = false
+		this.prefetchSubqueries(context)
+	}
+
 	terms := this.plan.Terms()
 	n := len(terms)
 
@@ -107,7 +148,17 @@ func (this *InitialProject) processTerms(item value.AnnotatedValue, context *Con
 
 	for _, term := range this.plan.Terms() {
 		if term.Result().Alias() != "" {
-			v, err := term.Result().Expression().Evaluate(item, context)
+			// COLLATE projects the original value, but ORDER BY
+			// on the alias follows the collation
+			expr := term.Result().Expression()
+			collate, ok := expr.(*expression.Collate)
+			if ok && collate.Collation() != nil {
+				expr = collate.First()
+			} else {
+				collate = nil
+			}
+
+			v, err := expr.Evaluate(item, context)
 			if err != nil {
 				context.Error(errors.NewEvaluationError(err, "projection"))
 				return false
@@ -117,7 +168,11 @@ func (this *InitialProject) processTerms(item value.AnnotatedValue, context *Con
 
 			// Explicit aliases override data
 			if term.Result().As() != "" {
-				pv.SetField(term.Result().As(), v)
+				if collate != nil {
+					pv.SetField(term.Result().As(), collate.Key(v))
+				} else {
+					pv.SetField(term.Result().As(), v)
+				}
 			}
 		} else {
 			// Star
@@ -248,6 +248,8 @@ func (this *spanScan) scan(context *Context, conn *datastore.IndexConnection, pa
 
 	keyspaceTerm := this.plan.Term()
 	scanVector := context.ScanVectorSource().ScanVector(keyspaceTerm.Namespace(), keyspaceTerm.Keyspace())
+	span := this.startScanSpan(this.plan.Index(), keyspaceTerm.Keyspace(), context)
+	defer span.End()
 	this.plan.Index().Scan(context.RequestId(), dspan, this.plan.Distinct(), limit,
 		context.ScanConsistency(), scanVector, conn)
 }
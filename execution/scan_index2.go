@@ -167,6 +167,8 @@ func (this *IndexScan2) scan(context *Context, conn *datastore.IndexConnection,
 		indexProjection = &datastore.IndexProjection{EntryKeys: proj.EntryKeys, PrimaryKey: proj.PrimaryKey}
 	}
 
+	span := this.startScanSpan(plan.Index(), plan.Term().Keyspace(), context)
+	defer span.End()
 	plan.Index().Scan2(context.RequestId(), dspans, plan.Reverse(), plan.Distinct(), plan.Ordered(),
 		indexProjection, offset, limit,
 		context.ScanConsistency(), scanVector, conn)
@@ -168,6 +168,8 @@ func (this *IndexScan3) scan(context *Context, conn *datastore.IndexConnection,
 	indexProjection, indexOrder, indexGroupAggs := planToScanMapping(plan.Index(), plan.Projection(),
 		plan.OrderTerms(), plan.GroupAggs(), plan.Covers())
 
+	span := this.startScanSpan(plan.Index(), plan.Term().Keyspace(), context)
+	defer span.End()
 	plan.Index().Scan3(context.RequestId(), dspans, plan.Reverse(), plan.Distinct(),
 		indexProjection, offset, limit, indexGroupAggs, indexOrder,
 		context.ScanConsistency(), scanVector, conn)
@@ -118,7 +118,7 @@ func (this *PrimaryScan) scanPrimary(context *Context, parent value.Value) {
 			return
 		}
 
-		logging.Errorp(emsg, logging.Pair{"chunkSize", nitems},
+		context.Logp(logging.ERROR, emsg, logging.Pair{"chunkSize", nitems},
 			logging.Pair{"startingEntry", stringifyIndexEntry(lastEntry)})
 
 		// do chunked scans; lastEntry the starting point
@@ -177,6 +177,8 @@ func (this *PrimaryScan) scanEntries(context *Context, conn *datastore.IndexConn
 	scanVector := context.ScanVectorSource().ScanVector(keyspace.NamespaceId(), keyspace.Name())
 
 	index := this.plan.Index()
+	span := this.startScanSpan(index, keyspace.Name(), context)
+	defer span.End()
 	index.ScanEntries(context.RequestId(), limit, context.ScanConsistency(), scanVector, conn)
 }
 
@@ -190,6 +192,8 @@ func (this *PrimaryScan) scanChunk(context *Context, conn *datastore.IndexConnec
 	}
 	keyspace := this.plan.Keyspace()
 	scanVector := context.ScanVectorSource().ScanVector(keyspace.NamespaceId(), keyspace.Name())
+	span := this.startScanSpan(this.plan.Index(), keyspace.Name(), context)
+	defer span.End()
 	this.plan.Index().Scan(context.RequestId(), ds, true, limit,
 		context.ScanConsistency(), scanVector, conn)
 }
@@ -114,7 +114,7 @@ func (this *PrimaryScan3) scanPrimary(context *Context, parent value.Value) {
 			return
 		}
 
-		logging.Errorp(emsg, logging.Pair{"chunkSize", nitems},
+		context.Logp(logging.ERROR, emsg, logging.Pair{"chunkSize", nitems},
 			logging.Pair{"startingEntry", stringifyIndexEntry(lastEntry)})
 
 		// do chunked scans; lastEntry the starting point
@@ -174,6 +174,8 @@ func (this *PrimaryScan3) scanEntries(context *Context, conn *datastore.IndexCon
 	indexProjection, indexOrder, indexGroupAggs := planToScanMapping(index, this.plan.Projection(),
 		this.plan.OrderTerms(), this.plan.GroupAggs(), nil)
 
+	span := this.startScanSpan(index, keyspace.Name(), context)
+	defer span.End()
 	index.ScanEntries3(context.RequestId(), indexProjection, offset, limit, indexGroupAggs, indexOrder,
 		context.ScanConsistency(), scanVector, conn)
 }
@@ -188,6 +190,8 @@ func (this *PrimaryScan3) scanChunk(context *Context, conn *datastore.IndexConne
 	}
 	keyspace := this.plan.Keyspace()
 	scanVector := context.ScanVectorSource().ScanVector(keyspace.NamespaceId(), keyspace.Name())
+	span := this.startScanSpan(this.plan.Index(), keyspace.Name(), context)
+	defer span.End()
 	this.plan.Index().Scan(context.RequestId(), ds, true, limit,
 		context.ScanConsistency(), scanVector, conn)
 }
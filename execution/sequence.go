@@ -57,11 +57,11 @@ func (this *Sequence) RunOnce(context *Context, parent value.Value) {
 		defer context.Recover() // Recover from any panic
 		active := this.active()
 		this.switchPhase(_EXECTIME)
-		defer this.switchPhase(_NOTIME)
 		this.SetKeepAlive(1, context)
 
 		n := len(this.children)
 		if !active || !context.assert(n > 0, "Sequence has no children") {
+			this.switchPhase(_NOTIME)
 			this.close(context)
 			return
 		}
@@ -92,6 +92,8 @@ func (this *Sequence) RunOnce(context *Context, parent value.Value) {
 		last_child.SetParent(this)
 
 		// Run last child
+		// once it is running, it notifies us on its own go routine
+		this.switchPhase(_NOTIME)
 		go last_child.RunOnce(context, parent)
 	})
 }
@@ -68,17 +68,53 @@ func (this *UnionAll) RunOnce(context *Context, parent value.Value) {
 			return
 		}
 
-		// Run children in parallel
-		for _, child := range this.children {
+		// Run children in parallel, within max_parallelism
+		// the first child, and those for which a worker is free, start
+		// at once, and the others as the running children complete
+		// a worker is held until a child completes, as children such as
+		// sequences return from RunOnce before they are done
+		pending := make([]Operator, 0, n)
+		workers := 0
+		for c, child := range this.children {
 			child.SetOutput(this.output)
 			child.SetStop(nil)
 			child.SetParent(this)
-			go child.RunOnce(context, parent)
+			if c == 0 {
+				go child.RunOnce(context, parent)
+			} else if context.acquireWorker() {
+				workers++
+				this.addConcurrent(1)
+				go child.RunOnce(context, parent)
+			} else {
+				pending = append(pending, child)
+			}
 		}
 
-		if !this.childrenWait(n) {
+		stopped := false
+		for c := 0; c < n && !stopped; c++ {
+			if !this.childrenWait(1) {
+				stopped = true
+			} else if len(pending) > 0 {
+				go pending[0].RunOnce(context, parent)
+				pending = pending[1:]
+			} else if workers > 0 {
+				workers--
+				context.releaseWorker()
+			}
+		}
+
+		for ; workers > 0; workers-- {
+			context.releaseWorker()
+		}
+
+		if stopped {
 			this.notifyStop()
 			notifyChildren(this.children...)
+
+			// the children not started only need to stop
+			for _, child := range pending {
+				go child.RunOnce(context, parent)
+			}
 		}
 
 		context.SetSortCount(0)
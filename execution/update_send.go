@@ -108,7 +108,10 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 		pairs = make([]value.Pair, 0, len(this.batch))
 	}
 
-	for i, item := range this.batch {
+	rule := validationRule(this.plan.Keyspace())
+	i := 0
+
+	for _, item := range this.batch {
 		uv, ok := item.Field(this.plan.Alias())
 		if !ok {
 			context.Error(errors.NewUpdateAliasMissingError(this.plan.Alias()))
@@ -126,9 +129,6 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 			return false
 		}
 
-		pairs = pairs[0 : i+1]
-		pairs[i].Name = key
-
 		clone := item.GetAttachment("clone")
 		switch clone := clone.(type) {
 		case value.AnnotatedValue:
@@ -138,8 +138,15 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 				return false
 			}
 
+			// documents that fail validation are neither written nor returned
+			if !validateDocument(rule, this.plan.Keyspace(), key, cv, context) {
+				continue
+			}
+
 			cav := value.NewAnnotatedValue(cv)
 			cav.SetAnnotations(av)
+			pairs = pairs[0 : i+1]
+			pairs[i].Name = key
 			pairs[i].Value = cav
 			item.SetField(this.plan.Alias(), cav)
 		default:
@@ -147,11 +154,18 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 				"Invalid UPDATE value of type %T.", clone)))
 			return false
 		}
+
+		this.batch[i] = item
+		i++
 	}
 
+	pairs = pairs[0:i]
+
 	this.switchPhase(_SERVTIME)
 
+	span := this.startCallSpan("update", this.plan.Keyspace().Name(), context)
 	pairs, e := this.plan.Keyspace().Update(pairs)
+	endCallSpan(span, len(pairs), e)
 
 	this.switchPhase(_EXECTIME)
 
@@ -162,7 +176,7 @@ func (this *SendUpdate) flushBatch(context *Context) bool {
 		context.Error(e)
 	}
 
-	for _, item := range this.batch {
+	for _, item := range this.batch[0:i] {
 		if !this.sendItem(item) {
 			return false
 		}
@@ -73,6 +73,7 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 
 	keyExpr := this.plan.Key()
 	valExpr := this.plan.Value()
+	rule := validationRule(this.plan.Keyspace())
 	var key, val value.Value
 	var err error
 	var ok bool
@@ -122,6 +123,10 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 			continue
 		}
 
+		if !validateDocument(rule, this.plan.Keyspace(), dpair.Name, val, context) {
+			continue
+		}
+
 		dpair.Value = val
 		i++
 	}
@@ -132,7 +137,9 @@ func (this *SendUpsert) flushBatch(context *Context) bool {
 
 	// Perform the actual UPSERT
 	var er errors.Error
+	span := this.startCallSpan("upsert", this.plan.Keyspace().Name(), context)
 	dpairs, er = this.plan.Keyspace().Upsert(dpairs)
+	endCallSpan(span, len(dpairs), er)
 
 	this.switchPhase(_EXECTIME)
 
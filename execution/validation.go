@@ -0,0 +1,59 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/validation"
+	"github.com/couchbase/query/value"
+)
+
+// at most this many violations are reported per document
+const _MAX_VALIDATION_ERRORS = 10
+
+/*
+Returns the validation rule of the keyspace being written, if any.
+*/
+func validationRule(keyspace datastore.Keyspace) *validation.Schema {
+	return validation.Rule(keyspace.NamespaceId(), keyspace.Name())
+}
+
+/*
+Checks a document against the validation rule of its keyspace, and
+reports a document validation error if it does not conform.
+*/
+func validateDocument(rule *validation.Schema, keyspace datastore.Keyspace, key string,
+	doc value.Value, context *Context) bool {
+	if rule == nil {
+		return true
+	}
+
+	errs := rule.Validate(doc)
+	if len(errs) == 0 {
+		return true
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for i, e := range errs {
+		if i == _MAX_VALIDATION_ERRORS {
+			msgs = append(msgs, fmt.Sprintf("and %d more", len(errs)-i))
+			break
+		}
+		msgs = append(msgs, e.Error())
+	}
+
+	context.Error(errors.NewDocumentValidationError(keyspace.NamespaceId()+":"+keyspace.Name(), key,
+		fmt.Errorf("%s", strings.Join(msgs, "; "))))
+	return false
+}
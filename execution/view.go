@@ -0,0 +1,126 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package execution
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+	"github.com/couchbase/query/views"
+)
+
+type CreateView struct {
+	base
+	plan *plan.CreateView
+}
+
+func NewCreateView(plan *plan.CreateView, context *Context) *CreateView {
+	rv := &CreateView{
+		plan: plan,
+	}
+
+	newRedirectBase(&rv.base)
+	rv.output = rv
+	return rv
+}
+
+func (this *CreateView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateView(this)
+}
+
+func (this *CreateView) Copy() Operator {
+	rv := &CreateView{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *CreateView) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover() // Recover from any panic
+		this.active()
+		defer this.close(context)
+		this.switchPhase(_EXECTIME)
+		defer this.switchPhase(_NOTIME)
+		defer this.notify() // Notify that I have stopped
+
+		if context.Readonly() {
+			return
+		}
+
+		// Actually create the view
+		this.switchPhase(_SERVTIME)
+		_, err := views.CreateView(this.plan.Namespace(), this.plan.Name(), this.plan.Definition())
+		if err != nil {
+			context.Error(err)
+		}
+	})
+}
+
+func (this *CreateView) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
+
+type DropView struct {
+	base
+	plan *plan.DropView
+}
+
+func NewDropView(plan *plan.DropView, context *Context) *DropView {
+	rv := &DropView{
+		plan: plan,
+	}
+
+	newRedirectBase(&rv.base)
+	rv.output = rv
+	return rv
+}
+
+func (this *DropView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropView(this)
+}
+
+func (this *DropView) Copy() Operator {
+	rv := &DropView{plan: this.plan}
+	this.base.copy(&rv.base)
+	return rv
+}
+
+func (this *DropView) RunOnce(context *Context, parent value.Value) {
+	this.once.Do(func() {
+		defer context.Recover() // Recover from any panic
+		this.active()
+		defer this.close(context)
+		this.switchPhase(_EXECTIME)
+		defer this.switchPhase(_NOTIME)
+		defer this.notify() // Notify that I have stopped
+
+		if context.Readonly() {
+			return
+		}
+
+		// Actually drop the view
+		this.switchPhase(_SERVTIME)
+		err := views.DropView(this.plan.Namespace(), this.plan.Name())
+		if err != nil {
+			context.Error(err)
+		}
+	})
+}
+
+func (this *DropView) MarshalJSON() ([]byte, error) {
+	r := this.plan.MarshalBase(func(r map[string]interface{}) {
+		this.marshalTimes(r)
+	})
+	return json.Marshal(r)
+}
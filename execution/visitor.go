@@ -44,6 +44,8 @@ type Visitor interface {
 	VisitNLNest(op *NLNest) (interface{}, error)
 	VisitHashJoin(op *HashJoin) (interface{}, error)
 	VisitHashNest(op *HashNest) (interface{}, error)
+	VisitNLSemiJoin(op *NLSemiJoin) (interface{}, error)
+	VisitHashSemiJoin(op *HashSemiJoin) (interface{}, error)
 
 	// Let + Letting, With
 	VisitLet(op *Let) (interface{}, error)
@@ -112,6 +114,15 @@ type Visitor interface {
 	VisitAlterIndex(op *AlterIndex) (interface{}, error)
 	VisitBuildIndexes(op *BuildIndexes) (interface{}, error)
 
+	// Materialized views
+	VisitCreateMaterializedView(op *CreateMaterializedView) (interface{}, error)
+	VisitDropMaterializedView(op *DropMaterializedView) (interface{}, error)
+	VisitRefreshMaterializedView(op *RefreshMaterializedView) (interface{}, error)
+
+	// Views
+	VisitCreateView(op *CreateView) (interface{}, error)
+	VisitDropView(op *DropView) (interface{}, error)
+
 	// Roles
 	VisitGrantRole(op *GrantRole) (interface{}, error)
 	VisitRevokeRole(op *RevokeRole) (interface{}, error)
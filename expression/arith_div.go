@@ -49,6 +49,10 @@ func (this *Div) Apply(context Context, first, second value.Value) (value.Value,
 	}
 
 	if second.Type() == value.NUMBER {
+		if first.Type() == value.NUMBER && (value.IsDecimal(first) || value.IsDecimal(second)) {
+			return value.DecimalDiv(first, second), nil
+		}
+
 		s := second.Actual().(float64)
 		if s == 0.0 {
 			return value.NULL_VALUE, nil
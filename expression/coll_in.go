@@ -26,6 +26,7 @@ type In struct {
 }
 
 func NewIn(first, second Expression) Function {
+	first, second = collated(first, second)
 	rv := &In{
 		*NewBinaryFunctionBase("in", first, second),
 	}
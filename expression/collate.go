@@ -0,0 +1,104 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"fmt"
+
+	"github.com/couchbase/query/value"
+)
+
+/*
+Represents expr COLLATE collation. It evaluates to the collation key
+of the value of expr, so that comparisons, ORDER BY terms and index
+keys over it follow the collation. A projection of it returns the
+value of expr itself; see Key(). The second operand is the name of
+the collation, as a string constant.
+*/
+type Collate struct {
+	BinaryFunctionBase
+	collation *value.Collation
+}
+
+func NewCollate(operand, collation Expression) Function {
+	rv := &Collate{
+		*NewBinaryFunctionBase("collate", operand, collation),
+		nil,
+	}
+
+	if name := collation.Value(); name != nil && name.Type() == value.STRING {
+		rv.collation = value.NewCollation(name.Actual().(string))
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Collate) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Collate) Type() value.Type { return this.First().Type() }
+
+func (this *Collate) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *Collate) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if this.collation == nil {
+		return nil, fmt.Errorf("Invalid collation %v.", second)
+	}
+
+	return this.collation.Key(first), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *Collate) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewCollate(operands[0], operands[1])
+	}
+}
+
+/*
+Returns the collation, or nil if it is not valid.
+*/
+func (this *Collate) Collation() *value.Collation {
+	return this.collation
+}
+
+/*
+Returns the collation key of a value of the first operand. Projections
+evaluate the first operand, so as to return the original value, and
+use this for the keys that later terms are compared on.
+*/
+func (this *Collate) Key(val value.Value) value.Value {
+	return this.collation.Key(val)
+}
+
+/*
+Applies the collation of one operand of a comparison to the other,
+so that, for instance, name COLLATE ci = "Fred" compares the keys of
+both operands.
+*/
+func collated(first, second Expression) (Expression, Expression) {
+	c1, ok1 := first.(*Collate)
+	c2, ok2 := second.(*Collate)
+	if ok1 && !ok2 {
+		second = NewCollate(second, c1.Second())
+	} else if ok2 && !ok1 {
+		first = NewCollate(first, c2.Second())
+	}
+	return first, second
+}
@@ -0,0 +1,96 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestILike(t *testing.T) {
+	tests := []struct {
+		expr     Expression
+		expected value.Value
+	}{
+		{NewILike(NewConstant("Fred"), NewConstant("fR%")), value.TRUE_VALUE},
+		{NewILike(NewConstant("Fred"), NewConstant("_RED")), value.TRUE_VALUE},
+		{NewILike(NewConstant("Fred"), NewConstant("fr")), value.FALSE_VALUE},
+		{NewNotILike(NewConstant("Fred"), NewConstant("FRED")), value.FALSE_VALUE},
+		{NewILike(NewConstant(1), NewConstant("1")), value.NULL_VALUE},
+		{NewILike(NewConstant(value.MISSING_VALUE), NewConstant("a")), value.MISSING_VALUE},
+	}
+
+	for _, test := range tests {
+		rv, err := test.expr.Evaluate(nil, nil)
+		if err != nil {
+			t.Errorf("Unexpected error %v for %v", err, test.expr)
+		} else if rv.Type() != test.expected.Type() || !rv.Equals(test.expected).Truth() &&
+			rv.Type() > value.NULL {
+			t.Errorf("Expected %v for %v, got %v", test.expected, test.expr, rv)
+		}
+	}
+}
+
+func TestCollatedComparisons(t *testing.T) {
+	ci := NewConstant("ci")
+	tests := []struct {
+		expr     Expression
+		expected bool
+	}{
+		{NewEq(NewCollate(NewConstant("Fred"), ci), NewConstant("FRED")), true},
+		{NewEq(NewConstant("FRED"), NewCollate(NewConstant("Fred"), ci)), true},
+		{NewEq(NewConstant("Fred"), NewConstant("FRED")), false},
+		{NewLT(NewCollate(NewConstant("a"), ci), NewConstant("B")), true},
+		{NewLT(NewConstant("a"), NewConstant("B")), false},
+		{NewBetween(NewCollate(NewConstant("B"), ci), NewConstant("a"), NewConstant("c")), true},
+		{NewEq(NewCollate(NewConstant("Crème"), NewConstant("ci_ai")), NewConstant("CREME")), true},
+	}
+
+	for _, test := range tests {
+		rv, err := test.expr.Evaluate(nil, nil)
+		if err != nil {
+			t.Errorf("Unexpected error %v for %v", err, test.expr)
+		} else if rv.Truth() != test.expected {
+			t.Errorf("Expected %v for %v, got %v", test.expected, test.expr, rv)
+		}
+	}
+
+	collate := NewCollate(NewConstant("Fred"), ci).(*Collate)
+	if key := collate.Key(value.NewValue("Fred")); key.Actual() != "fred" {
+		t.Errorf("Expected key fred, got %v", key)
+	}
+
+	invalid := NewCollate(NewConstant("Fred"), NewConstant("ci_ci"))
+	if _, err := invalid.Evaluate(nil, nil); err == nil {
+		t.Errorf("Expected an error for an invalid collation")
+	}
+}
+
+func TestILikeOf(t *testing.T) {
+	name := NewIdentifier("name")
+	ilike := NewILike(name, NewConstant("Fr%")).(*ILike)
+
+	like := ilike.LikeOf(NewLower(name))
+	if like == nil || like.String() != "(lower(`name`) like \"fr%\")" {
+		t.Errorf("Unexpected LIKE %v for an index on LOWER()", like)
+	}
+
+	like = ilike.LikeOf(NewCollate(name, NewConstant("ci")))
+	if like == nil {
+		t.Errorf("Expected a LIKE for an index on a case-insensitive collation")
+	}
+
+	if ilike.LikeOf(NewUpper(name)) != nil || ilike.LikeOf(name) != nil ||
+		ilike.LikeOf(NewLower(NewIdentifier("other"))) != nil ||
+		ilike.LikeOf(NewCollate(name, NewConstant("ci_ai"))) != nil {
+		t.Errorf("Expected no LIKE for keys that are not the LOWER() of the operand")
+	}
+}
@@ -23,6 +23,8 @@ type Between struct {
 }
 
 func NewBetween(item, low, high Expression) Function {
+	item, low = collated(item, low)
+	item, high = collated(item, high)
 	rv := &Between{
 		*NewTernaryFunctionBase("between", item, low, high),
 	}
@@ -24,6 +24,7 @@ type Eq struct {
 }
 
 func NewEq(first, second Expression) Function {
+	first, second = collated(first, second)
 	rv := &Eq{
 		*NewCommutativeBinaryFunctionBase("eq", first, second),
 	}
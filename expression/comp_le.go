@@ -22,6 +22,7 @@ type LE struct {
 }
 
 func NewLE(first, second Expression) Function {
+	first, second = collated(first, second)
 	rv := &LE{
 		*NewBinaryFunctionBase("le", first, second),
 	}
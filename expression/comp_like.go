@@ -11,6 +11,7 @@ package expression
 
 import (
 	"regexp"
+	"strings"
 
 	"github.com/couchbase/query/value"
 )
@@ -30,6 +31,7 @@ type Like struct {
 }
 
 func NewLike(first, second Expression) Function {
+	first, second = collated(first, second)
 	rv := &Like{
 		*NewBinaryFunctionBase("like", first, second),
 		nil,
@@ -180,3 +182,122 @@ This function implements the NOT LIKE operation.
 func NewNotLike(first, second Expression) Expression {
 	return NewNot(NewLike(first, second))
 }
+
+/*
+ILIKE and NOT ILIKE are the case-insensitive forms of LIKE. An
+expression ILIKE a pattern when the LOWER() of the expression is
+LIKE the LOWER() of the pattern.
+*/
+type ILike struct {
+	BinaryFunctionBase
+	re *regexp.Regexp
+}
+
+func NewILike(first, second Expression) Function {
+	first, second = collated(first, second)
+	rv := &ILike{
+		*NewBinaryFunctionBase("ilike", first, second),
+		nil,
+	}
+
+	if sv := second.Value(); sv != nil && sv.Type() == value.STRING {
+		rv.re, _, _ = likeCompile(strings.ToLower(sv.Actual().(string)))
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *ILike) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *ILike) Type() value.Type { return value.BOOLEAN }
+
+func (this *ILike) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+If this expression is in the WHERE clause of a partial index, lists
+the Expressions that are implicitly covered.
+
+For ILIKE, simply list this expression.
+*/
+func (this *ILike) FilterCovers(covers map[string]value.Value) map[string]value.Value {
+	covers[this.String()] = value.TRUE_VALUE
+	return covers
+}
+
+func (this *ILike) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if first.Type() != value.STRING || second.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	f := strings.ToLower(first.Actual().(string))
+	s := strings.ToLower(second.Actual().(string))
+
+	re := this.re
+	if re == nil {
+		var err error
+		re, _, err = likeCompile(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return value.NewValue(re.MatchString(f)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *ILike) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewILike(operands[0], operands[1])
+	}
+}
+
+/*
+If key is the LOWER() of the first operand, or the first operand
+under a collation whose keys are the LOWER() of their strings,
+returns the equivalent LIKE over key, else nil. This lets
+case-insensitive searches use indexes on such keys.
+*/
+func (this *ILike) LikeOf(key Expression) *Like {
+	first := this.First()
+	switch key := key.(type) {
+	case *Lower:
+		if !key.Operand().EquivalentTo(first) {
+			return nil
+		}
+	case *Collate:
+		if key.Collation() == nil || !key.Collation().Lowers() ||
+			!key.First().EquivalentTo(first) {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	var pattern Expression
+	if sv := this.Second().Value(); sv != nil && sv.Type() == value.STRING {
+		pattern = NewConstant(strings.ToLower(sv.Actual().(string)))
+	} else {
+		pattern = NewLower(this.Second())
+	}
+
+	return NewLike(key, pattern).(*Like)
+}
+
+/*
+This function implements the NOT ILIKE operation.
+*/
+func NewNotILike(first, second Expression) Expression {
+	return NewNot(NewILike(first, second))
+}
@@ -22,6 +22,7 @@ type LT struct {
 }
 
 func NewLT(first, second Expression) Function {
+	first, second = collated(first, second)
 	rv := &LT{
 		*NewBinaryFunctionBase("lt", first, second),
 	}
@@ -320,3 +320,24 @@ func IsCovered(expr Expression, keyspace string, exprs Expressions) bool {
 	isCovered := expr.CoveredBy(keyspace, exprs, coveredOptions{skip: false, trickle: false})
 	return isCovered == CoveredSkip || isCovered == CoveredEquiv || isCovered == CoveredTrue
 }
+
+/*
+Returns true if the expression, or any of its children, depends on
+clocks, random numbers or anything else that can change between two
+evaluations over the same data.
+*/
+func IsVolatile(expr Expression) bool {
+	if base, ok := expr.(interface {
+		volatile() bool
+	}); ok && base.volatile() {
+		return true
+	}
+
+	for _, child := range expr.Children() {
+		if IsVolatile(child) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,285 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"encoding/hex"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// HexEncode
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the encoding function HEX_ENCODE(expr). It returns
+the lower case hexadecimal encoding of the bytes of a string or
+binary value, or of the JSON encoding of any other value.
+*/
+type HexEncode struct {
+	UnaryFunctionBase
+}
+
+func NewHexEncode(operand Expression) Function {
+	rv := &HexEncode{
+		*NewUnaryFunctionBase("hex_encode", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *HexEncode) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *HexEncode) Type() value.Type { return value.STRING }
+
+func (this *HexEncode) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *HexEncode) Apply(context Context, arg value.Value) (value.Value, error) {
+	switch arg.Type() {
+	case value.MISSING, value.NULL:
+		return arg, nil
+	}
+
+	return value.NewValue(hex.EncodeToString(hashBytes(arg))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *HexEncode) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewHexEncode(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// HexDecode
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the decoding function HEX_DECODE(expr [, type ]). It
+returns the bytes encoded by the hexadecimal string expr as a string,
+or as a binary value if type is "binary". It returns NULL for invalid
+input, for bytes that are not valid UTF-8 when decoding to a string,
+and for other types.
+*/
+type HexDecode struct {
+	FunctionBase
+}
+
+func NewHexDecode(operands ...Expression) Function {
+	rv := &HexDecode{
+		*NewFunctionBase("hex_decode", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *HexDecode) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *HexDecode) Type() value.Type {
+	if len(this.operands) > 1 {
+		if t := this.operands[1].Value(); t != nil && t.Actual() == "binary" {
+			return value.BINARY
+		}
+	}
+	return value.STRING
+}
+
+func (this *HexDecode) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *HexDecode) Apply(context Context, args ...value.Value) (value.Value, error) {
+	binary := false
+	for _, arg := range args {
+		if arg.Type() == value.MISSING {
+			return value.MISSING_VALUE, nil
+		} else if arg.Type() != value.STRING {
+			return value.NULL_VALUE, nil
+		}
+	}
+
+	if len(args) > 1 {
+		switch args[1].Actual().(string) {
+		case "binary":
+			binary = true
+		case "string":
+		default:
+			return value.NULL_VALUE, nil
+		}
+	}
+
+	bytes, err := hex.DecodeString(args[0].Actual().(string))
+	if err != nil {
+		return value.NULL_VALUE, nil
+	}
+
+	if binary {
+		return value.NewBinaryValue(bytes), nil
+	} else if !utf8.Valid(bytes) {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(string(bytes)), nil
+}
+
+/*
+Minimum input arguments required is 1.
+*/
+func (this *HexDecode) MinArgs() int { return 1 }
+
+/*
+Maximum input arguments allowed is 2.
+*/
+func (this *HexDecode) MaxArgs() int { return 2 }
+
+/*
+Factory method pattern.
+*/
+func (this *HexDecode) Constructor() FunctionConstructor {
+	return NewHexDecode
+}
+
+///////////////////////////////////////////////////
+//
+// URLEncode
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the encoding function URL_ENCODE(expr). It escapes
+the string expr so that it can be safely placed inside a URL query.
+*/
+type URLEncode struct {
+	UnaryFunctionBase
+}
+
+func NewURLEncode(operand Expression) Function {
+	rv := &URLEncode{
+		*NewUnaryFunctionBase("url_encode", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *URLEncode) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *URLEncode) Type() value.Type { return value.STRING }
+
+func (this *URLEncode) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *URLEncode) Apply(context Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if arg.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(url.QueryEscape(arg.Actual().(string))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *URLEncode) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewURLEncode(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// URLDecode
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the decoding function URL_DECODE(expr). It reverses
+URL_ENCODE, and returns NULL for malformed escapes.
+*/
+type URLDecode struct {
+	UnaryFunctionBase
+}
+
+func NewURLDecode(operand Expression) Function {
+	rv := &URLDecode{
+		*NewUnaryFunctionBase("url_decode", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *URLDecode) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *URLDecode) Type() value.Type { return value.STRING }
+
+func (this *URLDecode) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *URLDecode) Apply(context Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if arg.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	s, err := url.QueryUnescape(arg.Actual().(string))
+	if err != nil {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(s), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *URLDecode) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewURLDecode(operands[0])
+	}
+}
@@ -0,0 +1,435 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"github.com/couchbase/query/value"
+)
+
+/*
+The geospatial functions accept GeoJSON geometries and features, as
+well as [lon, lat] positions, [west, south, east, north] bounding
+boxes and objects with lat and lon fields. They return NULL for
+values that are not geometries.
+*/
+
+/*
+The geospatial predicates that an index on GEOHASH() can be scanned
+for.
+*/
+type GeoWithinFunction interface {
+	BinaryFunction
+	GeohashPrefixes(key Expression) []string
+}
+
+///////////////////////////////////////////////////
+//
+// STDistance
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_DISTANCE(geom1, geom2). It
+returns the shortest distance between the geometries in meters, or 0
+if they intersect.
+*/
+type STDistance struct {
+	BinaryFunctionBase
+}
+
+func NewSTDistance(first, second Expression) Function {
+	rv := &STDistance{
+		*NewBinaryFunctionBase("st_distance", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *STDistance) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *STDistance) Type() value.Type { return value.NUMBER }
+
+func (this *STDistance) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *STDistance) Apply(context Context, first, second value.Value) (value.Value, error) {
+	g1, g2, rv := geoArgs(first, second)
+	if rv != nil {
+		return rv, nil
+	}
+
+	return value.NewValue(geoDistance(g1, g2)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *STDistance) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSTDistance(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// STWithin
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_WITHIN(geom1, geom2). It
+returns true if no point of geom1 lies outside geom2. An index on
+GEOHASH(geom1) can be scanned for geom1 within a constant geom2.
+*/
+type STWithin struct {
+	BinaryFunctionBase
+}
+
+func NewSTWithin(first, second Expression) Function {
+	rv := &STWithin{
+		*NewBinaryFunctionBase("st_within", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *STWithin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *STWithin) Type() value.Type { return value.BOOLEAN }
+
+func (this *STWithin) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+If this expression is in the WHERE clause of a partial index, lists
+the Expressions that are implicitly covered.
+
+For boolean functions, simply list this expression.
+*/
+func (this *STWithin) FilterCovers(covers map[string]value.Value) map[string]value.Value {
+	covers[this.String()] = value.TRUE_VALUE
+	return covers
+}
+
+func (this *STWithin) Apply(context Context, first, second value.Value) (value.Value, error) {
+	g1, g2, rv := geoArgs(first, second)
+	if rv != nil {
+		return rv, nil
+	}
+
+	return value.NewValue(geoWithin(g1, g2)), nil
+}
+
+/*
+Returns the geohash prefixes of the cells covering the second
+operand, if key is the GEOHASH() of the first operand.
+*/
+func (this *STWithin) GeohashPrefixes(key Expression) []string {
+	return geohashCover(key, this.First(), this.Second())
+}
+
+/*
+Factory method pattern.
+*/
+func (this *STWithin) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSTWithin(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// STContains
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_CONTAINS(geom1, geom2).
+It returns true if no point of geom2 lies outside geom1, that is, if
+geom2 is within geom1.
+*/
+type STContains struct {
+	BinaryFunctionBase
+}
+
+func NewSTContains(first, second Expression) Function {
+	rv := &STContains{
+		*NewBinaryFunctionBase("st_contains", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *STContains) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *STContains) Type() value.Type { return value.BOOLEAN }
+
+func (this *STContains) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+If this expression is in the WHERE clause of a partial index, lists
+the Expressions that are implicitly covered.
+
+For boolean functions, simply list this expression.
+*/
+func (this *STContains) FilterCovers(covers map[string]value.Value) map[string]value.Value {
+	covers[this.String()] = value.TRUE_VALUE
+	return covers
+}
+
+func (this *STContains) Apply(context Context, first, second value.Value) (value.Value, error) {
+	g1, g2, rv := geoArgs(first, second)
+	if rv != nil {
+		return rv, nil
+	}
+
+	return value.NewValue(geoWithin(g2, g1)), nil
+}
+
+/*
+Returns the geohash prefixes of the cells covering the first
+operand, if key is the GEOHASH() of the second operand.
+*/
+func (this *STContains) GeohashPrefixes(key Expression) []string {
+	return geohashCover(key, this.Second(), this.First())
+}
+
+/*
+Factory method pattern.
+*/
+func (this *STContains) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSTContains(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// STIntersects
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_INTERSECTS(geom1, geom2).
+It returns true if the geometries share any point.
+*/
+type STIntersects struct {
+	BinaryFunctionBase
+}
+
+func NewSTIntersects(first, second Expression) Function {
+	rv := &STIntersects{
+		*NewBinaryFunctionBase("st_intersects", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *STIntersects) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *STIntersects) Type() value.Type { return value.BOOLEAN }
+
+func (this *STIntersects) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+If this expression is in the WHERE clause of a partial index, lists
+the Expressions that are implicitly covered.
+
+For boolean functions, simply list this expression.
+*/
+func (this *STIntersects) FilterCovers(covers map[string]value.Value) map[string]value.Value {
+	covers[this.String()] = value.TRUE_VALUE
+	return covers
+}
+
+func (this *STIntersects) Apply(context Context, first, second value.Value) (value.Value, error) {
+	g1, g2, rv := geoArgs(first, second)
+	if rv != nil {
+		return rv, nil
+	}
+
+	return value.NewValue(geoIntersects(g1, g2)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *STIntersects) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSTIntersects(operands[0], operands[1])
+	}
+}
+
+func geoArgs(first, second value.Value) (*geometry, *geometry, value.Value) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return nil, nil, value.MISSING_VALUE
+	}
+
+	g1 := newGeometry(first)
+	g2 := newGeometry(second)
+	if g1 == nil || g2 == nil {
+		return nil, nil, value.NULL_VALUE
+	}
+
+	return g1, g2, nil
+}
+
+///////////////////////////////////////////////////
+//
+// STBuffer
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_BUFFER(geom, distance). It
+returns a GeoJSON polygon covering every point within distance meters
+of geom. The polygon is exact, up to the segments approximating its
+curves, for points and convex geometries, and otherwise also covers
+the concave parts of geom.
+*/
+type STBuffer struct {
+	BinaryFunctionBase
+}
+
+func NewSTBuffer(first, second Expression) Function {
+	rv := &STBuffer{
+		*NewBinaryFunctionBase("st_buffer", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *STBuffer) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *STBuffer) Type() value.Type { return value.OBJECT }
+
+func (this *STBuffer) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *STBuffer) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if second.Type() != value.NUMBER {
+		return value.NULL_VALUE, nil
+	}
+
+	g := newGeometry(first)
+	distance := value.AsNumberValue(second).Float64()
+	if g == nil || distance <= 0 {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(polygonJSON(geoBuffer(g, distance))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *STBuffer) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSTBuffer(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// STArea
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function ST_AREA(geom). It returns the
+area of the polygons of geom in square meters, and 0 for points and
+lines.
+*/
+type STArea struct {
+	UnaryFunctionBase
+}
+
+func NewSTArea(operand Expression) Function {
+	rv := &STArea{
+		*NewUnaryFunctionBase("st_area", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *STArea) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *STArea) Type() value.Type { return value.NUMBER }
+
+func (this *STArea) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *STArea) Apply(context Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	}
+
+	g := newGeometry(arg)
+	if g == nil {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(geoArea(g)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *STArea) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSTArea(operands[0])
+	}
+}
@@ -0,0 +1,768 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"math"
+	"sort"
+
+	"github.com/couchbase/query/value"
+)
+
+/*
+The mean radius of the earth, in meters.
+*/
+const _EARTH_RADIUS = 6371008.8
+
+/*
+A position, in degrees of longitude and latitude.
+*/
+type geoPoint struct {
+	lon, lat float64
+}
+
+/*
+A polygon: its outer ring followed by any holes. Rings are closed,
+their first and last positions being the same.
+*/
+type geoPolygon [][]geoPoint
+
+/*
+A geometry, reduced to its points, its lines and its polygons.
+*/
+type geometry struct {
+	points   []geoPoint
+	lines    [][]geoPoint
+	polygons []geoPolygon
+}
+
+/*
+Returns the geometry of a value, or nil if the value is not a
+geometry. Geometries are GeoJSON geometry objects and features, or
+else a [lon, lat] position, a [west, south, east, north] bounding
+box, or an object with lat and lon fields.
+*/
+func newGeometry(val value.Value) *geometry {
+	g := &geometry{}
+	if !g.add(val.Actual(), 0) || g.empty() {
+		return nil
+	}
+
+	return g
+}
+
+func (this *geometry) empty() bool {
+	return len(this.points) == 0 && len(this.lines) == 0 && len(this.polygons) == 0
+}
+
+func (this *geometry) add(v interface{}, depth int) bool {
+	if depth > 8 {
+		return false
+	}
+
+	v = geoActual(v)
+	switch v := v.(type) {
+	case []interface{}:
+		if p, ok := geoPosition(v); ok {
+			this.points = append(this.points, p)
+			return true
+		}
+		if box, ok := geoNumbers(v, 4); ok {
+			if box[0] > box[2] || box[1] > box[3] {
+				return false
+			}
+			this.polygons = append(this.polygons, bboxPolygon(box[0], box[1], box[2], box[3]))
+			return true
+		}
+		return false
+	case map[string]interface{}:
+	default:
+		return false
+	}
+
+	obj := v.(map[string]interface{})
+	typ, _ := geoActual(obj["type"]).(string)
+	if typ == "" {
+		lat, ok1 := geoNumber(obj["lat"])
+		lon, ok2 := geoNumber(obj["lon"])
+		if !ok2 {
+			lon, ok2 = geoNumber(obj["lng"])
+		}
+		if !ok1 || !ok2 || !validPosition(lon, lat) {
+			return false
+		}
+		this.points = append(this.points, geoPoint{lon, lat})
+		return true
+	}
+
+	switch typ {
+	case "Feature":
+		return this.add(obj["geometry"], depth+1)
+	case "FeatureCollection":
+		features, ok := geoActual(obj["features"]).([]interface{})
+		if !ok {
+			return false
+		}
+		for _, f := range features {
+			if !this.add(f, depth+1) {
+				return false
+			}
+		}
+		return true
+	case "GeometryCollection":
+		geometries, ok := geoActual(obj["geometries"]).([]interface{})
+		if !ok {
+			return false
+		}
+		for _, g := range geometries {
+			if !this.add(g, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+
+	coords, ok := geoActual(obj["coordinates"]).([]interface{})
+	if !ok {
+		return false
+	}
+
+	switch typ {
+	case "Point":
+		p, ok := geoPosition(coords)
+		if ok {
+			this.points = append(this.points, p)
+		}
+		return ok
+	case "MultiPoint":
+		points, ok := geoPositions(coords, 1)
+		if ok {
+			this.points = append(this.points, points...)
+		}
+		return ok
+	case "LineString":
+		line, ok := geoPositions(coords, 2)
+		if ok {
+			this.lines = append(this.lines, line)
+		}
+		return ok
+	case "MultiLineString":
+		for _, c := range coords {
+			a, _ := geoActual(c).([]interface{})
+			line, ok := geoPositions(a, 2)
+			if !ok {
+				return false
+			}
+			this.lines = append(this.lines, line)
+		}
+		return true
+	case "Polygon":
+		polygon, ok := geoRings(coords)
+		if ok {
+			this.polygons = append(this.polygons, polygon)
+		}
+		return ok
+	case "MultiPolygon":
+		for _, c := range coords {
+			a, _ := geoActual(c).([]interface{})
+			polygon, ok := geoRings(a)
+			if !ok {
+				return false
+			}
+			this.polygons = append(this.polygons, polygon)
+		}
+		return true
+	}
+
+	return false
+}
+
+/*
+Unwraps the values nested in constructed objects and arrays.
+*/
+func geoActual(v interface{}) interface{} {
+	if val, ok := v.(value.Value); ok {
+		return val.Actual()
+	}
+
+	return v
+}
+
+func geoNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case value.Value:
+		if v.Type() == value.NUMBER {
+			return value.AsNumberValue(v).Float64(), true
+		}
+	}
+
+	return 0, false
+}
+
+func geoNumbers(a []interface{}, n int) ([]float64, bool) {
+	if len(a) != n {
+		return nil, false
+	}
+
+	rv := make([]float64, n)
+	for i, v := range a {
+		f, ok := geoNumber(v)
+		if !ok {
+			return nil, false
+		}
+		rv[i] = f
+	}
+
+	return rv, true
+}
+
+func validPosition(lon, lat float64) bool {
+	return lon >= -180 && lon <= 180 && lat >= -90 && lat <= 90
+}
+
+/*
+A GeoJSON position, [lon, lat] with an optional altitude.
+*/
+func geoPosition(a []interface{}) (geoPoint, bool) {
+	if len(a) < 2 || len(a) > 3 {
+		return geoPoint{}, false
+	}
+
+	f, ok := geoNumbers(a[0:2], 2)
+	if !ok || !validPosition(f[0], f[1]) {
+		return geoPoint{}, false
+	}
+
+	if len(a) == 3 {
+		if _, ok := geoNumber(a[2]); !ok {
+			return geoPoint{}, false
+		}
+	}
+
+	return geoPoint{f[0], f[1]}, true
+}
+
+func geoPositions(a []interface{}, min int) ([]geoPoint, bool) {
+	if len(a) < min {
+		return nil, false
+	}
+
+	rv := make([]geoPoint, len(a))
+	for i, v := range a {
+		c, _ := geoActual(v).([]interface{})
+		p, ok := geoPosition(c)
+		if !ok {
+			return nil, false
+		}
+		rv[i] = p
+	}
+
+	return rv, true
+}
+
+func geoRings(a []interface{}) (geoPolygon, bool) {
+	if len(a) == 0 {
+		return nil, false
+	}
+
+	rv := make(geoPolygon, len(a))
+	for i, v := range a {
+		c, _ := geoActual(v).([]interface{})
+		ring, ok := geoPositions(c, 4)
+		if !ok || ring[0] != ring[len(ring)-1] {
+			return nil, false
+		}
+		rv[i] = ring
+	}
+
+	return rv, true
+}
+
+func bboxPolygon(west, south, east, north float64) geoPolygon {
+	return geoPolygon{{
+		{west, south}, {east, south}, {east, north}, {west, north}, {west, south},
+	}}
+}
+
+/*
+Calls f with every segment of the geometry's lines and rings, until
+it returns false.
+*/
+func (this *geometry) segments(f func(a, b geoPoint) bool) bool {
+	for _, line := range this.lines {
+		for i := 1; i < len(line); i++ {
+			if !f(line[i-1], line[i]) {
+				return false
+			}
+		}
+	}
+
+	for _, polygon := range this.polygons {
+		for _, ring := range polygon {
+			for i := 1; i < len(ring); i++ {
+				if !f(ring[i-1], ring[i]) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+/*
+Calls f with every position of the geometry, until it returns false.
+*/
+func (this *geometry) vertices(f func(p geoPoint) bool) bool {
+	for _, p := range this.points {
+		if !f(p) {
+			return false
+		}
+	}
+
+	for _, line := range this.lines {
+		for _, p := range line {
+			if !f(p) {
+				return false
+			}
+		}
+	}
+
+	for _, polygon := range this.polygons {
+		for _, ring := range polygon {
+			for _, p := range ring {
+				if !f(p) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+/*
+Returns the [west, south, east, north] bounding box of the geometry.
+*/
+func (this *geometry) bbox() [4]float64 {
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	this.vertices(func(p geoPoint) bool {
+		box[0] = math.Min(box[0], p.lon)
+		box[1] = math.Min(box[1], p.lat)
+		box[2] = math.Max(box[2], p.lon)
+		box[3] = math.Max(box[3], p.lat)
+		return true
+	})
+	return box
+}
+
+/*
+Returns true if p lies on the geometry: on one of its points, lines
+or polygon boundaries, or inside one of its polygons.
+*/
+func (this *geometry) covers(p geoPoint) bool {
+	for _, q := range this.points {
+		if p == q {
+			return true
+		}
+	}
+
+	on := !this.segments(func(a, b geoPoint) bool {
+		return !onSegment(p, a, b)
+	})
+	if on {
+		return true
+	}
+
+	for _, polygon := range this.polygons {
+		if polygon.contains(p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Returns true if p is inside the polygon or on its boundary.
+*/
+func (this geoPolygon) contains(p geoPoint) bool {
+	for i, ring := range this {
+		in := ringContains(ring, p)
+		if i == 0 && !in {
+			return false
+		} else if i > 0 && in && !ringBoundary(ring, p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ringBoundary(ring []geoPoint, p geoPoint) bool {
+	for i := 1; i < len(ring); i++ {
+		if onSegment(p, ring[i-1], ring[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Ray casting, counting the boundary as inside.
+*/
+func ringContains(ring []geoPoint, p geoPoint) bool {
+	if ringBoundary(ring, p) {
+		return true
+	}
+
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.lat > p.lat) != (b.lat > p.lat) &&
+			p.lon < (b.lon-a.lon)*(p.lat-a.lat)/(b.lat-a.lat)+a.lon {
+			in = !in
+		}
+	}
+
+	return in
+}
+
+func cross(o, a, b geoPoint) float64 {
+	return (a.lon-o.lon)*(b.lat-o.lat) - (a.lat-o.lat)*(b.lon-o.lon)
+}
+
+func onSegment(p, a, b geoPoint) bool {
+	if math.Abs(cross(a, b, p)) > 1e-12 {
+		return false
+	}
+
+	return p.lon >= math.Min(a.lon, b.lon) && p.lon <= math.Max(a.lon, b.lon) &&
+		p.lat >= math.Min(a.lat, b.lat) && p.lat <= math.Max(a.lat, b.lat)
+}
+
+/*
+Returns true if the segments cross at a single point interior to
+both.
+*/
+func segmentsCross(a, b, c, d geoPoint) bool {
+	d1 := cross(c, d, a)
+	d2 := cross(c, d, b)
+	d3 := cross(a, b, c)
+	d4 := cross(a, b, d)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func segmentsIntersect(a, b, c, d geoPoint) bool {
+	return segmentsCross(a, b, c, d) ||
+		onSegment(a, c, d) || onSegment(b, c, d) ||
+		onSegment(c, a, b) || onSegment(d, a, b)
+}
+
+/*
+Returns true if the geometries share any point. Predicates treat
+longitude and latitude as planar coordinates.
+*/
+func geoIntersects(g1, g2 *geometry) bool {
+	if !g1.vertices(func(p geoPoint) bool { return !g2.covers(p) }) {
+		return true
+	}
+
+	if !g2.vertices(func(p geoPoint) bool { return !g1.covers(p) }) {
+		return true
+	}
+
+	return !g1.segments(func(a, b geoPoint) bool {
+		return g2.segments(func(c, d geoPoint) bool {
+			return !segmentsCross(a, b, c, d)
+		})
+	})
+}
+
+/*
+Returns true if no point of g1 lies outside g2.
+*/
+func geoWithin(g1, g2 *geometry) bool {
+	if len(g2.polygons) == 0 && len(g1.polygons) > 0 {
+		return false
+	}
+
+	if !g1.vertices(g2.covers) {
+		return false
+	}
+
+	if len(g2.polygons) == 0 {
+		// Every segment must lie along g2
+		return g1.segments(func(a, b geoPoint) bool {
+			return g2.covers(geoPoint{(a.lon + b.lon) / 2, (a.lat + b.lat) / 2})
+		})
+	}
+
+	// No segment of g1 may leave g2 through its boundary
+	crosses := !g1.segments(func(a, b geoPoint) bool {
+		return g2.segments(func(c, d geoPoint) bool {
+			return !segmentsCross(a, b, c, d)
+		})
+	})
+	if crosses {
+		return false
+	}
+
+	// Nor may the holes of g2 lie inside g1
+	for _, polygon := range g2.polygons {
+		for _, hole := range polygon[1:] {
+			if g1.interior(ringCentroid(hole)) {
+				return false
+			}
+			for _, p := range hole {
+				if g1.interior(p) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+/*
+Returns true if p is inside one of the geometry's polygons, and not on
+its boundary.
+*/
+func (this *geometry) interior(p geoPoint) bool {
+	for _, polygon := range this.polygons {
+		if polygon.contains(p) {
+			boundary := false
+			for _, ring := range polygon {
+				boundary = boundary || ringBoundary(ring, p)
+			}
+			if !boundary {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+The average of the distinct positions of a ring.
+*/
+func ringCentroid(ring []geoPoint) geoPoint {
+	var c geoPoint
+	n := len(ring) - 1
+	for _, p := range ring[:n] {
+		c.lon += p.lon / float64(n)
+		c.lat += p.lat / float64(n)
+	}
+
+	return c
+}
+
+/*
+Returns the great-circle distance between two positions, in meters.
+*/
+func haversine(p, q geoPoint) float64 {
+	lat1 := p.lat * math.Pi / 180
+	lat2 := q.lat * math.Pi / 180
+	dlat := lat2 - lat1
+	dlon := (q.lon - p.lon) * math.Pi / 180
+
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * _EARTH_RADIUS * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+/*
+Returns the distance from p to a segment, in meters, projecting the
+segment onto the plane tangent at p.
+*/
+func segmentDistance(p, a, b geoPoint) float64 {
+	scale := math.Pi / 180 * _EARTH_RADIUS
+	coslat := math.Cos(p.lat * math.Pi / 180)
+
+	ax := (a.lon - p.lon) * coslat * scale
+	ay := (a.lat - p.lat) * scale
+	bx := (b.lon - p.lon) * coslat * scale
+	by := (b.lat - p.lat) * scale
+
+	dx := bx - ax
+	dy := by - ay
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/l))
+	}
+
+	if t == 0 {
+		return haversine(p, a)
+	} else if t == 1 {
+		return haversine(p, b)
+	}
+
+	x := ax + t*dx
+	y := ay + t*dy
+	return math.Sqrt(x*x + y*y)
+}
+
+/*
+Returns the shortest distance between the geometries, in meters, or 0
+if they intersect. Distances between positions are great-circle
+distances; distances to segments are approximated locally.
+*/
+func geoDistance(g1, g2 *geometry) float64 {
+	if geoIntersects(g1, g2) {
+		return 0
+	}
+
+	d := math.Inf(1)
+	measure := func(from, to *geometry) {
+		from.vertices(func(p geoPoint) bool {
+			for _, q := range to.points {
+				d = math.Min(d, haversine(p, q))
+			}
+			to.segments(func(a, b geoPoint) bool {
+				d = math.Min(d, segmentDistance(p, a, b))
+				return true
+			})
+			return true
+		})
+	}
+
+	measure(g1, g2)
+	measure(g2, g1)
+	return d
+}
+
+/*
+Returns the area of the geometry's polygons on the sphere, in square
+meters, less the area of their holes.
+*/
+func geoArea(g *geometry) float64 {
+	area := 0.0
+	for _, polygon := range g.polygons {
+		for i, ring := range polygon {
+			a := math.Abs(ringArea(ring))
+			if i == 0 {
+				area += a
+			} else {
+				area -= a
+			}
+		}
+	}
+
+	return area
+}
+
+func ringArea(ring []geoPoint) float64 {
+	area := 0.0
+	for i := 1; i < len(ring); i++ {
+		p, q := ring[i-1], ring[i]
+		area += (q.lon - p.lon) * math.Pi / 180 *
+			(2 + math.Sin(p.lat*math.Pi/180) + math.Sin(q.lat*math.Pi/180))
+	}
+
+	return area * _EARTH_RADIUS * _EARTH_RADIUS / 2
+}
+
+/*
+Returns the position reached by travelling distance meters from p
+along the bearing, in radians from north.
+*/
+func geoDestination(p geoPoint, distance, bearing float64) geoPoint {
+	lat1 := p.lat * math.Pi / 180
+	lon1 := p.lon * math.Pi / 180
+	d := distance / _EARTH_RADIUS
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(bearing))
+	lon2 := lon1 + math.Atan2(math.Sin(bearing)*math.Sin(d)*math.Cos(lat1),
+		math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+
+	lon := math.Mod(lon2*180/math.Pi+540, 360) - 180
+	return geoPoint{lon, lat2 * 180 / math.Pi}
+}
+
+const _BUFFER_SEGMENTS = 32
+
+/*
+Returns the polygon covering everything within distance meters of the
+geometry: the convex hull of the circles around its positions. This
+is exact, up to the segments of the circles, for points, segments and
+convex polygons, and covers more than the buffer of other geometries.
+*/
+func geoBuffer(g *geometry, distance float64) geoPolygon {
+	points := make([]geoPoint, 0, 64)
+	g.vertices(func(p geoPoint) bool {
+		for i := 0; i < _BUFFER_SEGMENTS; i++ {
+			bearing := 2 * math.Pi * float64(i) / _BUFFER_SEGMENTS
+			points = append(points, geoDestination(p, distance, bearing))
+		}
+		return true
+	})
+
+	return geoPolygon{convexHull(points)}
+}
+
+/*
+Andrew's monotone chain, returning a closed counter-clockwise ring.
+*/
+func convexHull(points []geoPoint) []geoPoint {
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].lon != points[j].lon {
+			return points[i].lon < points[j].lon
+		}
+		return points[i].lat < points[j].lat
+	})
+
+	hull := make([]geoPoint, 0, 2*len(points)+1)
+	for _, p := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		p := points[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	return hull
+}
+
+/*
+Returns the GeoJSON of a polygon.
+*/
+func polygonJSON(polygon geoPolygon) map[string]interface{} {
+	rings := make([]interface{}, len(polygon))
+	for i, ring := range polygon {
+		positions := make([]interface{}, len(ring))
+		for j, p := range ring {
+			positions[j] = []interface{}{p.lon, p.lat}
+		}
+		rings[i] = positions
+	}
+
+	return map[string]interface{}{
+		"type":        "Polygon",
+		"coordinates": rings,
+	}
+}
@@ -0,0 +1,348 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// Geohash
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function GEOHASH(geom [, precision ]).
+It returns the geohash of the center of the bounding box of geom, with
+precision characters, 12 by default. Geohashes sharing a prefix lie in
+the same cell, so an index on GEOHASH(geom) can be scanned for the
+cells covering the region of ST_WITHIN(geom, region).
+*/
+type Geohash struct {
+	FunctionBase
+}
+
+func NewGeohash(operands ...Expression) Function {
+	rv := &Geohash{
+		*NewFunctionBase("geohash", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Geohash) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Geohash) Type() value.Type { return value.STRING }
+
+func (this *Geohash) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *Geohash) Apply(context Context, args ...value.Value) (value.Value, error) {
+	for _, arg := range args {
+		if arg.Type() == value.MISSING {
+			return value.MISSING_VALUE, nil
+		}
+	}
+
+	precision := _GEOHASH_PRECISION
+	if len(args) > 1 {
+		var ok bool
+		precision, ok = geohashPrecision(args[1])
+		if !ok {
+			return value.NULL_VALUE, nil
+		}
+	}
+
+	g := newGeometry(args[0])
+	if g == nil {
+		return value.NULL_VALUE, nil
+	}
+
+	box := g.bbox()
+	return value.NewValue(geohashEncode((box[0]+box[2])/2, (box[1]+box[3])/2, precision)), nil
+}
+
+func (this *Geohash) MinArgs() int { return 1 }
+
+func (this *Geohash) MaxArgs() int { return 2 }
+
+/*
+Factory method pattern.
+*/
+func (this *Geohash) Constructor() FunctionConstructor {
+	return NewGeohash
+}
+
+/*
+Returns the precision of the geohashes of this expression, or false
+if it is not constant.
+*/
+func (this *Geohash) Precision() (int, bool) {
+	if len(this.operands) < 2 {
+		return _GEOHASH_PRECISION, true
+	}
+
+	pv := this.operands[1].Value()
+	if pv == nil {
+		return 0, false
+	}
+
+	return geohashPrecision(pv)
+}
+
+///////////////////////////////////////////////////
+//
+// GeohashDecode
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the geospatial function GEOHASH_DECODE(hash). It
+returns the cell of the geohash, as an object with the lat and lon of
+its center and its [west, south, east, north] bbox.
+*/
+type GeohashDecode struct {
+	UnaryFunctionBase
+}
+
+func NewGeohashDecode(operand Expression) Function {
+	rv := &GeohashDecode{
+		*NewUnaryFunctionBase("geohash_decode", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *GeohashDecode) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *GeohashDecode) Type() value.Type { return value.OBJECT }
+
+func (this *GeohashDecode) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *GeohashDecode) Apply(context Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if arg.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	box, ok := geohashDecode(strings.ToLower(arg.Actual().(string)))
+	if !ok {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(map[string]interface{}{
+		"lat":  (box[1] + box[3]) / 2,
+		"lon":  (box[0] + box[2]) / 2,
+		"bbox": []interface{}{box[0], box[1], box[2], box[3]},
+	}), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *GeohashDecode) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewGeohashDecode(operands[0])
+	}
+}
+
+const _GEOHASH_BASE32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+const _GEOHASH_PRECISION = 12
+
+/*
+The most cells whose prefixes are scanned for one region.
+*/
+const _GEOHASH_COVER_CELLS = 32
+
+func geohashPrecision(val value.Value) (int, bool) {
+	if val.Type() != value.NUMBER {
+		return 0, false
+	}
+
+	f := value.AsNumberValue(val).Float64()
+	if f != math.Trunc(f) || f < 1 || f > _GEOHASH_PRECISION {
+		return 0, false
+	}
+
+	return int(f), true
+}
+
+func geohashEncode(lon, lat float64, precision int) string {
+	west, east := -180.0, 180.0
+	south, north := -90.0, 90.0
+
+	hash := make([]byte, precision)
+	even := true
+	for i := 0; i < precision; i++ {
+		c := 0
+		for b := 0; b < 5; b++ {
+			c <<= 1
+			if even {
+				mid := (west + east) / 2
+				if lon >= mid {
+					c |= 1
+					west = mid
+				} else {
+					east = mid
+				}
+			} else {
+				mid := (south + north) / 2
+				if lat >= mid {
+					c |= 1
+					south = mid
+				} else {
+					north = mid
+				}
+			}
+			even = !even
+		}
+		hash[i] = _GEOHASH_BASE32[c]
+	}
+
+	return string(hash)
+}
+
+/*
+Returns the [west, south, east, north] bounding box of a geohash cell.
+*/
+func geohashDecode(hash string) ([4]float64, bool) {
+	box := [4]float64{-180, -90, 180, 90}
+	if hash == "" || len(hash) > _GEOHASH_PRECISION {
+		return box, false
+	}
+
+	even := true
+	for i := 0; i < len(hash); i++ {
+		c := strings.IndexByte(_GEOHASH_BASE32, hash[i])
+		if c < 0 {
+			return box, false
+		}
+		for b := 4; b >= 0; b-- {
+			bit := c>>uint(b)&1 == 1
+			if even {
+				mid := (box[0] + box[2]) / 2
+				if bit {
+					box[0] = mid
+				} else {
+					box[2] = mid
+				}
+			} else {
+				mid := (box[1] + box[3]) / 2
+				if bit {
+					box[1] = mid
+				} else {
+					box[3] = mid
+				}
+			}
+			even = !even
+		}
+	}
+
+	return box, true
+}
+
+/*
+If key is the GEOHASH() of inner and outer is a constant geometry,
+returns the prefixes of the geohash cells covering the bounding box
+of outer, in order. Any inner within outer has its geohash under one
+of the prefixes. Prefixes are as long as the precision of key allows
+without exceeding _GEOHASH_COVER_CELLS cells.
+*/
+func geohashCover(key, inner, outer Expression) []string {
+	gh, ok := key.(*Geohash)
+	if !ok || !gh.operands[0].EquivalentTo(inner) {
+		return nil
+	}
+
+	precision, ok := gh.Precision()
+	if !ok {
+		return nil
+	}
+
+	ov := outer.Value()
+	if ov == nil {
+		return nil
+	}
+
+	g := newGeometry(ov)
+	if g == nil {
+		return nil
+	}
+
+	box := g.bbox()
+	var x0, x1, y0, y1 int
+	level := 0
+	for l := 1; l <= precision; l++ {
+		cx0, cx1, cy0, cy1 := geohashCells(box, l)
+		if (cx1-cx0+1)*(cy1-cy0+1) > _GEOHASH_COVER_CELLS {
+			break
+		}
+		x0, x1, y0, y1, level = cx0, cx1, cy0, cy1, l
+	}
+
+	lonBits, latBits := geohashBits(level)
+	width := 360 / math.Pow(2, float64(lonBits))
+	height := 180 / math.Pow(2, float64(latBits))
+
+	prefixes := make([]string, 0, (x1-x0+1)*(y1-y0+1))
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			lon := -180 + (float64(x)+0.5)*width
+			lat := -90 + (float64(y)+0.5)*height
+			prefixes = append(prefixes, geohashEncode(lon, lat, level))
+		}
+	}
+
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+func geohashBits(level int) (int, int) {
+	return (5*level + 1) / 2, 5 * level / 2
+}
+
+/*
+Returns the column and row ranges of the cells at a level that the
+bounding box touches.
+*/
+func geohashCells(box [4]float64, level int) (int, int, int, int) {
+	lonBits, latBits := geohashBits(level)
+	columns := math.Pow(2, float64(lonBits))
+	rows := math.Pow(2, float64(latBits))
+
+	cell := func(v, min, extent, n float64) int {
+		return int(math.Min(math.Floor((v-min)/extent*n), n-1))
+	}
+
+	return cell(box[0], -180, 360, columns), cell(box[2], -180, 360, columns),
+		cell(box[1], -90, 180, rows), cell(box[3], -90, 180, rows)
+}
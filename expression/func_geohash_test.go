@@ -0,0 +1,56 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestGeohash_roundtrip(t *testing.T) {
+	hash := geohashEncode(-5.6, 42.6, 5)
+	if hash != "ezs42" {
+		t.Errorf("expected ezs42, received %s", hash)
+	}
+
+	box, ok := geohashDecode(hash)
+	if !ok || box[0] > -5.6 || box[2] < -5.6 || box[1] > 42.6 || box[3] < 42.6 {
+		t.Errorf("expected cell of ezs42 to contain the position, received %v", box)
+	}
+
+	if _, ok := geohashDecode("ezs4a"); ok {
+		t.Errorf("expected invalid geohash to fail")
+	}
+}
+
+func TestGeohash_cover(t *testing.T) {
+	location := NewIdentifier("location")
+	region := NewConstant(value.NewValue([]interface{}{-0.2, 51.45, -0.05, 51.55}))
+	within := NewSTWithin(location, region).(*STWithin)
+
+	prefixes := within.GeohashPrefixes(NewGeohash(location, NewConstant(6)))
+	if len(prefixes) == 0 || len(prefixes) > _GEOHASH_COVER_CELLS {
+		t.Fatalf("expected a cover of at most %d cells, received %v", _GEOHASH_COVER_CELLS, prefixes)
+	}
+
+	// Every position of the region hashes under one of the prefixes
+	for lon := -0.2; lon <= -0.05; lon += 0.01 {
+		for lat := 51.45; lat <= 51.55; lat += 0.01 {
+			hash := geohashEncode(lon, lat, 6)
+			covered := false
+			for _, prefix := range prefixes {
+				covered = covered || hash[0:len(prefix)] == prefix
+			}
+			if !covered {
+				t.Errorf("expected %s to be covered by %v", hash, prefixes)
+			}
+		}
+	}
+
+	if within.GeohashPrefixes(NewGeohash(NewIdentifier("other"))) != nil {
+		t.Errorf("expected no cover for an index on another field")
+	}
+
+	if NewSTWithin(location, NewIdentifier("box")).(*STWithin).GeohashPrefixes(NewGeohash(location)) != nil {
+		t.Errorf("expected no cover for a region that is not constant")
+	}
+}
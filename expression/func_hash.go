@@ -0,0 +1,426 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"strings"
+
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The hash functions hash the bytes of strings and binary values, and
+the JSON encoding of other values. Digests are returned as lower case
+hexadecimal strings.
+*/
+func hashBytes(arg value.Value) []byte {
+	switch arg.Type() {
+	case value.STRING:
+		return []byte(arg.Actual().(string))
+	case value.BINARY:
+		return arg.Actual().([]byte)
+	default:
+		bytes, _ := arg.MarshalJSON()
+		return bytes
+	}
+}
+
+func hashDigest(h hash.Hash, arg value.Value) value.Value {
+	switch arg.Type() {
+	case value.MISSING, value.NULL:
+		return arg
+	}
+
+	h.Write(hashBytes(arg))
+	return value.NewValue(hex.EncodeToString(h.Sum(nil)))
+}
+
+var _HASH_ALGORITHMS = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+///////////////////////////////////////////////////
+//
+// MD5
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the hash function MD5(expr). It returns the
+MD5 digest of expr.
+*/
+type MD5 struct {
+	UnaryFunctionBase
+}
+
+func NewMD5(operand Expression) Function {
+	rv := &MD5{
+		*NewUnaryFunctionBase("md5", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *MD5) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *MD5) Type() value.Type { return value.STRING }
+
+func (this *MD5) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *MD5) Apply(context Context, arg value.Value) (value.Value, error) {
+	return hashDigest(md5.New(), arg), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *MD5) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewMD5(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// SHA1
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the hash function SHA1(expr). It returns the
+SHA-1 digest of expr.
+*/
+type SHA1 struct {
+	UnaryFunctionBase
+}
+
+func NewSHA1(operand Expression) Function {
+	rv := &SHA1{
+		*NewUnaryFunctionBase("sha1", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *SHA1) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *SHA1) Type() value.Type { return value.STRING }
+
+func (this *SHA1) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *SHA1) Apply(context Context, arg value.Value) (value.Value, error) {
+	return hashDigest(sha1.New(), arg), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *SHA1) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSHA1(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// SHA256
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the hash function SHA256(expr). It returns the
+SHA-256 digest of expr.
+*/
+type SHA256 struct {
+	UnaryFunctionBase
+}
+
+func NewSHA256(operand Expression) Function {
+	rv := &SHA256{
+		*NewUnaryFunctionBase("sha256", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *SHA256) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *SHA256) Type() value.Type { return value.STRING }
+
+func (this *SHA256) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *SHA256) Apply(context Context, arg value.Value) (value.Value, error) {
+	return hashDigest(sha256.New(), arg), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *SHA256) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSHA256(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// SHA512
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the hash function SHA512(expr). It returns the
+SHA-512 digest of expr.
+*/
+type SHA512 struct {
+	UnaryFunctionBase
+}
+
+func NewSHA512(operand Expression) Function {
+	rv := &SHA512{
+		*NewUnaryFunctionBase("sha512", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *SHA512) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *SHA512) Type() value.Type { return value.STRING }
+
+func (this *SHA512) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *SHA512) Apply(context Context, arg value.Value) (value.Value, error) {
+	return hashDigest(sha512.New(), arg), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *SHA512) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSHA512(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// HMAC
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the hash function HMAC(alg, key, data). It returns
+the keyed-hash message authentication code of data, using the hash
+algorithm alg, one of "md5", "sha1", "sha256" or "sha512". Unknown
+algorithms return NULL.
+*/
+type HMAC struct {
+	TernaryFunctionBase
+}
+
+func NewHMAC(first, second, third Expression) Function {
+	rv := &HMAC{
+		*NewTernaryFunctionBase("hmac", first, second, third),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *HMAC) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *HMAC) Type() value.Type { return value.STRING }
+
+func (this *HMAC) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.TernaryEval(this, item, context)
+}
+
+func (this *HMAC) Apply(context Context, alg, key, data value.Value) (value.Value, error) {
+	if alg.Type() == value.MISSING || key.Type() == value.MISSING || data.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if alg.Type() != value.STRING || key.Type() == value.NULL || data.Type() == value.NULL {
+		return value.NULL_VALUE, nil
+	}
+
+	newHash, ok := _HASH_ALGORITHMS[strings.ToLower(alg.Actual().(string))]
+	if !ok {
+		return value.NULL_VALUE, nil
+	}
+
+	return hashDigest(hmac.New(newHash, hashBytes(key)), data), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *HMAC) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewHMAC(operands[0], operands[1], operands[2])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// CRC32
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the checksum function CRC32(expr). It returns the
+IEEE CRC-32 checksum of expr, as a number.
+*/
+type CRC32 struct {
+	UnaryFunctionBase
+}
+
+func NewCRC32(operand Expression) Function {
+	rv := &CRC32{
+		*NewUnaryFunctionBase("crc32", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *CRC32) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *CRC32) Type() value.Type { return value.NUMBER }
+
+func (this *CRC32) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *CRC32) Apply(context Context, arg value.Value) (value.Value, error) {
+	switch arg.Type() {
+	case value.MISSING, value.NULL:
+		return arg, nil
+	}
+
+	return value.NewValue(int64(crc32.ChecksumIEEE(hashBytes(arg)))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *CRC32) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewCRC32(operands[0])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// Hash64
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the hash function HASH64(expr). It returns a fast,
+non-cryptographic 64-bit hash of expr, as a signed number, for
+fingerprints and bucketing rather than security.
+*/
+type Hash64 struct {
+	UnaryFunctionBase
+}
+
+func NewHash64(operand Expression) Function {
+	rv := &Hash64{
+		*NewUnaryFunctionBase("hash64", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Hash64) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Hash64) Type() value.Type { return value.NUMBER }
+
+func (this *Hash64) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *Hash64) Apply(context Context, arg value.Value) (value.Value, error) {
+	switch arg.Type() {
+	case value.MISSING, value.NULL:
+		return arg, nil
+	}
+
+	return value.NewValue(int64(util.SeaHashSum64(hashBytes(arg)))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *Hash64) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewHash64(operands[0])
+	}
+}
@@ -0,0 +1,92 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func testFunctions(t *testing.T, tests []functionTest) {
+	for _, test := range tests {
+		rv, err := test.expr.Evaluate(nil, nil)
+		if err != nil {
+			t.Errorf("Unexpected error %v for %v", err, test.expr)
+		} else if rv.Type() != test.expected.Type() ||
+			rv.Type() > value.NULL && !rv.Equals(test.expected).Truth() {
+			t.Errorf("Expected %v for %v, got %v", test.expected, test.expr, rv)
+		}
+	}
+}
+
+type functionTest struct {
+	expr     Expression
+	expected value.Value
+}
+
+func TestHashFunctions(t *testing.T) {
+	abc := NewConstant("abc")
+	binary := NewConstant(value.NewBinaryValue([]byte("abc")))
+	testFunctions(t, []functionTest{
+		{NewMD5(abc), value.NewValue("900150983cd24fb0d6963f7d28e17f72")},
+		{NewMD5(binary), value.NewValue("900150983cd24fb0d6963f7d28e17f72")},
+		{NewSHA1(abc), value.NewValue("a9993e364706816aba3e25717850c26c9cd0d89d")},
+		{NewSHA256(binary), value.NewValue("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")},
+		{NewHMAC(NewConstant("sha256"), NewConstant("key"), NewConstant("The quick brown fox jumps over the lazy dog")),
+			value.NewValue("f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8")},
+		{NewHMAC(NewConstant("rot13"), NewConstant("key"), abc), value.NULL_VALUE},
+		{NewCRC32(abc), value.NewValue(891568578)},
+		{NewCRC32(binary), value.NewValue(891568578)},
+		{NewMD5(NewConstant(value.NULL_VALUE)), value.NULL_VALUE},
+		{NewSHA512(NewConstant(value.MISSING_VALUE)), value.MISSING_VALUE},
+	})
+
+	h1, _ := NewHash64(abc).Evaluate(nil, nil)
+	h2, _ := NewHash64(binary).Evaluate(nil, nil)
+	h3, _ := NewHash64(NewConstant("abd")).Evaluate(nil, nil)
+	if h1.Type() != value.NUMBER || !h1.Equals(h2).Truth() || h1.Equals(h3).Truth() {
+		t.Errorf("Unexpected HASH64 values %v, %v and %v", h1, h2, h3)
+	}
+}
+
+func TestEncodeFunctions(t *testing.T) {
+	testFunctions(t, []functionTest{
+		{NewHexEncode(NewConstant("café")), value.NewValue("636166c3a9")},
+		{NewHexEncode(NewConstant(value.NewBinaryValue([]byte{0xff, 0}))), value.NewValue("ff00")},
+		{NewHexDecode(NewConstant("636166c3a9")), value.NewValue("café")},
+		{NewHexDecode(NewConstant("636166c3a9"), NewConstant("string")), value.NewValue("café")},
+		{NewHexDecode(NewConstant("ff00")), value.NULL_VALUE},
+		{NewHexDecode(NewConstant("zz")), value.NULL_VALUE},
+		{NewHexDecode(NewConstant("ff00"), NewConstant("text")), value.NULL_VALUE},
+		{NewHexDecode(NewConstant(1)), value.NULL_VALUE},
+		{NewURLEncode(NewConstant("a b&c=d/é")), value.NewValue("a+b%26c%3Dd%2F%C3%A9")},
+		{NewURLDecode(NewConstant("a+b%26c%3Dd%2F%C3%A9")), value.NewValue("a b&c=d/é")},
+		{NewURLDecode(NewConstant("%zz")), value.NULL_VALUE},
+		{NewURLEncode(NewConstant(1)), value.NULL_VALUE},
+	})
+
+	for _, hex := range []string{"ff00", "616263"} {
+		decode := NewHexDecode(NewConstant(hex), NewConstant("binary"))
+		if decode.Type() != value.BINARY {
+			t.Errorf("Expected binary type for %v", decode)
+		}
+		rv, _ := decode.Evaluate(nil, nil)
+		if rv.Type() != value.BINARY {
+			t.Errorf("Expected binary value for %v, got %v", decode, rv)
+		}
+		if rv, _ = NewHexEncode(decode).Evaluate(nil, nil); rv.Actual() != hex {
+			t.Errorf("Expected %v to round trip, got %v", hex, rv)
+		}
+	}
+	if NewHexDecode(NewConstant("616263")).Type() != value.STRING {
+		t.Errorf("Expected string type for HEX_DECODE()")
+	}
+}
@@ -10,7 +10,6 @@
 package expression
 
 import (
-	"encoding/json"
 	"strings"
 
 	"github.com/couchbase/query/util"
@@ -59,9 +58,9 @@ This method returns a valid N1QL value from a JSON encoded
 string. If the input type is missing return missing, and if
 it isnt string then return null value. Conver the input arg
 to valid Go type and cast to a string. If it is an empty
-string return missing value. If not then call the Unmarshal
-method defined in the json package, by casting the strings
-to a bytes slice and return the json value.
+string return missing value. If not then parse the bytes of
+the string as a document, with integers that do not fit int64
+as decimals, and return the json value.
 */
 func (this *JSONDecode) Apply(context Context, arg value.Value) (value.Value, error) {
 	if arg.Type() == value.MISSING {
@@ -76,13 +75,12 @@ func (this *JSONDecode) Apply(context Context, arg value.Value) (value.Value, er
 		return value.NULL_VALUE, nil
 	}
 
-	var p interface{}
-	err := json.Unmarshal([]byte(s), &p)
-	if err != nil {
+	rv := value.NewValue([]byte(s))
+	if rv.Type() == value.BINARY {
 		return value.NULL_VALUE, nil
 	}
 
-	return value.NewValue(p), nil
+	return rv, nil
 }
 
 /*
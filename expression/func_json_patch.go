@@ -0,0 +1,611 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// JSONPointerGet
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the JSON function JSON_POINTER_GET(expr, pointer). It
+returns the value at the RFC 6901 JSON pointer within expr, such as
+"/a/0/b", or MISSING if there is none. The empty pointer denotes expr
+itself.
+*/
+type JSONPointerGet struct {
+	BinaryFunctionBase
+}
+
+func NewJSONPointerGet(first, second Expression) Function {
+	rv := &JSONPointerGet{
+		*NewBinaryFunctionBase("json_pointer_get", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *JSONPointerGet) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *JSONPointerGet) Type() value.Type { return value.JSON }
+
+func (this *JSONPointerGet) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *JSONPointerGet) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if second.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	tokens, ok := parsePointer(second.Actual().(string))
+	if !ok {
+		return value.NULL_VALUE, nil
+	}
+
+	rv, ok := pointerGet(first, tokens)
+	if !ok {
+		return value.MISSING_VALUE, nil
+	}
+
+	return rv, nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *JSONPointerGet) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewJSONPointerGet(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// JSONPatch
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the JSON function JSON_PATCH(expr, patch). It applies
+an RFC 6902 JSON patch, an array of add, remove, replace, move, copy
+and test operations, to expr and returns the result. expr itself is
+not modified. The patch is applied atomically: if any operation
+fails, including a test, the function raises an error.
+*/
+type JSONPatch struct {
+	BinaryFunctionBase
+}
+
+func NewJSONPatch(first, second Expression) Function {
+	rv := &JSONPatch{
+		*NewBinaryFunctionBase("json_patch", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *JSONPatch) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *JSONPatch) Type() value.Type { return value.JSON }
+
+func (this *JSONPatch) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *JSONPatch) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if second.Type() != value.ARRAY {
+		return value.NULL_VALUE, nil
+	}
+
+	doc := first
+	for i, op := range second.Actual().([]interface{}) {
+		var err error
+		doc, err = applyPatchOp(doc, value.NewValue(op))
+		if err != nil {
+			return nil, errors.NewEvaluationError(
+				fmt.Errorf("JSON patch operation %d failed: %v", i, err), "JSON_PATCH()")
+		}
+	}
+
+	return doc, nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *JSONPatch) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewJSONPatch(operands[0], operands[1])
+	}
+}
+
+func applyPatchOp(doc, op value.Value) (value.Value, error) {
+	if op.Type() != value.OBJECT {
+		return nil, fmt.Errorf("operation is not an object")
+	}
+
+	name, _ := op.Field("op")
+	if name.Type() != value.STRING {
+		return nil, fmt.Errorf("missing op")
+	}
+
+	path, ok := patchPointer(op, "path")
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid path")
+	}
+
+	val, hasVal := op.Field("value")
+
+	switch name.Actual().(string) {
+	case "add", "replace", "test":
+		if !hasVal {
+			return nil, fmt.Errorf("missing value")
+		}
+	case "move", "copy":
+		from, ok := patchPointer(op, "from")
+		if !ok {
+			return nil, fmt.Errorf("missing or invalid from")
+		}
+
+		val, ok = pointerGet(doc, from)
+		if !ok {
+			return nil, fmt.Errorf("from %v does not exist", pointerString(from))
+		}
+
+		if name.Actual().(string) == "copy" {
+			break
+		}
+
+		if len(from) < len(path) && pointerString(path[:len(from)]) == pointerString(from) {
+			return nil, fmt.Errorf("cannot move %v into itself", pointerString(from))
+		}
+
+		doc, ok = pointerUpdate(doc, from, nil, _POINTER_REMOVE)
+		if !ok {
+			return nil, fmt.Errorf("cannot remove %v", pointerString(from))
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("unknown op %v", name)
+	}
+
+	mode := _POINTER_ADD
+	switch name.Actual().(string) {
+	case "remove":
+		mode = _POINTER_REMOVE
+	case "replace":
+		mode = _POINTER_REPLACE
+	case "test":
+		cur, ok := pointerGet(doc, path)
+		if !ok || !cur.Equals(val).Truth() {
+			return nil, fmt.Errorf("test of %v failed", pointerString(path))
+		}
+		return doc, nil
+	}
+
+	rv, ok := pointerUpdate(doc, path, val, mode)
+	if !ok {
+		return nil, fmt.Errorf("cannot %v %v", name.Actual(), pointerString(path))
+	}
+
+	return rv, nil
+}
+
+func patchPointer(op value.Value, field string) ([]string, bool) {
+	p, _ := op.Field(field)
+	if p.Type() != value.STRING {
+		return nil, false
+	}
+
+	return parsePointer(p.Actual().(string))
+}
+
+///////////////////////////////////////////////////
+//
+// JSONMergePatch
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the JSON function JSON_MERGE_PATCH(expr, patch). It
+applies an RFC 7386 merge patch to expr: fields of an object patch
+are merged recursively into expr, and fields set to null are removed.
+Any other patch replaces expr.
+*/
+type JSONMergePatch struct {
+	BinaryFunctionBase
+}
+
+func NewJSONMergePatch(first, second Expression) Function {
+	rv := &JSONMergePatch{
+		*NewBinaryFunctionBase("json_merge_patch", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *JSONMergePatch) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *JSONMergePatch) Type() value.Type { return value.JSON }
+
+func (this *JSONMergePatch) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *JSONMergePatch) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	}
+
+	return mergePatch(first, second), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *JSONMergePatch) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewJSONMergePatch(operands[0], operands[1])
+	}
+}
+
+func mergePatch(target, patch value.Value) value.Value {
+	if patch.Type() != value.OBJECT {
+		return patch
+	}
+
+	rv := make(map[string]interface{})
+	if target.Type() == value.OBJECT {
+		for n, v := range target.Fields() {
+			rv[n] = v
+		}
+	}
+
+	for n, v := range patch.Fields() {
+		pv := value.NewValue(v)
+		if pv.Type() == value.NULL {
+			delete(rv, n)
+			continue
+		}
+
+		rv[n] = mergePatch(value.NewValue(rv[n]), pv)
+	}
+
+	return value.NewValue(rv)
+}
+
+///////////////////////////////////////////////////
+//
+// JSONDiff
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the JSON function JSON_DIFF(expr1, expr2). It returns
+an RFC 6902 JSON patch that turns expr1 into expr2, so that
+JSON_PATCH(expr1, JSON_DIFF(expr1, expr2)) equals expr2. Objects and
+arrays are compared recursively; other differences are replacements.
+*/
+type JSONDiff struct {
+	BinaryFunctionBase
+}
+
+func NewJSONDiff(first, second Expression) Function {
+	rv := &JSONDiff{
+		*NewBinaryFunctionBase("json_diff", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *JSONDiff) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *JSONDiff) Type() value.Type { return value.ARRAY }
+
+func (this *JSONDiff) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *JSONDiff) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	}
+
+	return value.NewValue(jsonDiff(first, second, "", make([]interface{}, 0, 8))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *JSONDiff) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewJSONDiff(operands[0], operands[1])
+	}
+}
+
+func jsonDiff(from, to value.Value, path string, ops []interface{}) []interface{} {
+	switch {
+	case from.Type() == value.OBJECT && to.Type() == value.OBJECT:
+		fromFields := from.Fields()
+		toFields := to.Fields()
+
+		for _, n := range sortedFieldNames(fromFields) {
+			if _, ok := toFields[n]; !ok {
+				ops = append(ops, patchOp("remove", path+"/"+escapePointer(n), nil))
+			}
+		}
+
+		for _, n := range sortedFieldNames(toFields) {
+			tv := value.NewValue(toFields[n])
+			if fv, ok := fromFields[n]; ok {
+				ops = jsonDiff(value.NewValue(fv), tv, path+"/"+escapePointer(n), ops)
+			} else {
+				ops = append(ops, patchOp("add", path+"/"+escapePointer(n), tv))
+			}
+		}
+	case from.Type() == value.ARRAY && to.Type() == value.ARRAY:
+		fromItems := from.Actual().([]interface{})
+		toItems := to.Actual().([]interface{})
+
+		for i := 0; i < len(fromItems) && i < len(toItems); i++ {
+			ops = jsonDiff(value.NewValue(fromItems[i]), value.NewValue(toItems[i]),
+				path+"/"+strconv.Itoa(i), ops)
+		}
+
+		for i := len(fromItems) - 1; i >= len(toItems); i-- {
+			ops = append(ops, patchOp("remove", path+"/"+strconv.Itoa(i), nil))
+		}
+
+		for i := len(fromItems); i < len(toItems); i++ {
+			ops = append(ops, patchOp("add", path+"/"+strconv.Itoa(i), value.NewValue(toItems[i])))
+		}
+	case !from.Equals(to).Truth():
+		ops = append(ops, patchOp("replace", path, to))
+	}
+
+	return ops
+}
+
+func patchOp(op, path string, val value.Value) interface{} {
+	rv := map[string]interface{}{
+		"op":   op,
+		"path": path,
+	}
+
+	if val != nil {
+		rv["value"] = val
+	}
+
+	return rv
+}
+
+func sortedFieldNames(fields map[string]interface{}) []string {
+	names := make([]string, 0, len(fields))
+	for n, _ := range fields {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+/*
+JSON pointers.
+*/
+
+const (
+	_POINTER_ADD = iota
+	_POINTER_REPLACE
+	_POINTER_REMOVE
+)
+
+/*
+Splits an RFC 6901 JSON pointer into its unescaped reference tokens.
+*/
+func parsePointer(pointer string) ([]string, bool) {
+	if pointer == "" {
+		return []string{}, true
+	} else if pointer[0] != '/' {
+		return nil, false
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		if strings.IndexByte(t, '~') >= 0 {
+			for j := 0; j < len(t); j++ {
+				if t[j] == '~' && (j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1')) {
+					return nil, false
+				}
+			}
+
+			tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+		}
+	}
+
+	return tokens, true
+}
+
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func pointerString(tokens []string) string {
+	rv := ""
+	for _, t := range tokens {
+		rv += "/" + escapePointer(t)
+	}
+	return rv
+}
+
+/*
+Parses an array index token, which must not have leading zeros.
+*/
+func pointerIndex(token string, length int) (int, bool) {
+	if token == "" || len(token) > 1 && token[0] == '0' {
+		return 0, false
+	}
+
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, false
+		}
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i >= length {
+		return 0, false
+	}
+
+	return i, true
+}
+
+func pointerGet(doc value.Value, tokens []string) (value.Value, bool) {
+	for _, t := range tokens {
+		switch doc.Type() {
+		case value.OBJECT:
+			v, ok := doc.Field(t)
+			if !ok {
+				return nil, false
+			}
+			doc = v
+		case value.ARRAY:
+			items := doc.Actual().([]interface{})
+			i, ok := pointerIndex(t, len(items))
+			if !ok {
+				return nil, false
+			}
+			doc = value.NewValue(items[i])
+		default:
+			return nil, false
+		}
+	}
+
+	return doc, true
+}
+
+/*
+Returns a copy of doc with the value at tokens added, replaced or
+removed, sharing unchanged subtrees with doc, which is not modified.
+*/
+func pointerUpdate(doc value.Value, tokens []string, val value.Value, mode int) (value.Value, bool) {
+	if len(tokens) == 0 {
+		return val, mode != _POINTER_REMOVE
+	}
+
+	t := tokens[0]
+	last := len(tokens) == 1
+
+	switch doc.Type() {
+	case value.OBJECT:
+		fields := doc.Fields()
+		child, exists := fields[t]
+		if !exists && (!last || mode != _POINTER_ADD) {
+			return nil, false
+		}
+
+		rv := make(map[string]interface{}, len(fields)+1)
+		for n, v := range fields {
+			rv[n] = v
+		}
+
+		if !last {
+			v, ok := pointerUpdate(value.NewValue(child), tokens[1:], val, mode)
+			if !ok {
+				return nil, false
+			}
+			rv[t] = v
+		} else if mode == _POINTER_REMOVE {
+			delete(rv, t)
+		} else {
+			rv[t] = val
+		}
+
+		return value.NewValue(rv), true
+	case value.ARRAY:
+		items := doc.Actual().([]interface{})
+		length := len(items)
+		if last && mode == _POINTER_ADD {
+			length++
+		}
+
+		i, ok := pointerIndex(t, length)
+		if t == "-" && last && mode == _POINTER_ADD {
+			i, ok = len(items), true
+		}
+		if !ok {
+			return nil, false
+		}
+
+		rv := make([]interface{}, 0, len(items)+1)
+		rv = append(rv, items[:i]...)
+
+		switch {
+		case !last:
+			v, ok := pointerUpdate(value.NewValue(items[i]), tokens[1:], val, mode)
+			if !ok {
+				return nil, false
+			}
+			rv = append(rv, v)
+			rv = append(rv, items[i+1:]...)
+		case mode == _POINTER_ADD:
+			rv = append(rv, val)
+			rv = append(rv, items[i:]...)
+		case mode == _POINTER_REPLACE:
+			rv = append(rv, val)
+			rv = append(rv, items[i+1:]...)
+		default:
+			rv = append(rv, items[i+1:]...)
+		}
+
+		return value.NewValue(rv), true
+	default:
+		return nil, false
+	}
+}
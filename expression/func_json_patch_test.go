@@ -0,0 +1,46 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestJSONPatch_atomic(t *testing.T) {
+	doc := value.NewValue(map[string]interface{}{"a": 1, "b": []interface{}{1, 2}})
+	patch := value.NewValue([]interface{}{
+		map[string]interface{}{"op": "remove", "path": "/b/0"},
+		map[string]interface{}{"op": "replace", "path": "/a", "value": 2},
+		map[string]interface{}{"op": "test", "path": "/a", "value": 1},
+	})
+
+	rv, err := NewJSONPatch(NewConstant(doc), NewConstant(patch)).Evaluate(nil, nil)
+	if err == nil {
+		t.Errorf("expected failed test to raise an error, received %v", rv)
+	}
+
+	er := value.NewValue(map[string]interface{}{"a": 1, "b": []interface{}{1, 2}})
+	if er.Collate(doc) != 0 {
+		t.Errorf("expected patched document to be unchanged, received %v", doc)
+	}
+}
+
+func TestJSONPatch_errors(t *testing.T) {
+	doc := value.NewValue(map[string]interface{}{"a": map[string]interface{}{"b": 1}})
+	for _, op := range []map[string]interface{}{
+		{"op": "add", "path": "/x/y", "value": 1},
+		{"op": "replace", "path": "/a/c", "value": 1},
+		{"op": "remove", "path": "/a/b/c"},
+		{"op": "move", "from": "/a", "path": "/a/d"},
+		{"op": "copy", "from": "/z", "path": "/d"},
+		{"op": "add", "path": "a", "value": 1},
+		{"op": "add", "path": "/a/b"},
+		{"op": "nop", "path": "/a"},
+	} {
+		patch := value.NewValue([]interface{}{op})
+		rv, err := NewJSONPatch(NewConstant(doc), NewConstant(patch)).Evaluate(nil, nil)
+		if err == nil {
+			t.Errorf("expected %v to raise an error, received %v", op, rv)
+		}
+	}
+}
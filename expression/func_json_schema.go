@@ -0,0 +1,194 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/validation"
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// JSONSchemaValidate
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the JSON function JSON_SCHEMA_VALIDATE(expr, schema).
+It returns true if expr conforms to the JSON schema, and false
+otherwise. An invalid schema is an error.
+*/
+type JSONSchemaValidate struct {
+	BinaryFunctionBase
+	schema *validation.Schema
+}
+
+func NewJSONSchemaValidate(first, second Expression) Function {
+	rv := &JSONSchemaValidate{
+		*NewBinaryFunctionBase("json_schema_validate", first, second),
+		nil,
+	}
+
+	rv.schema = precompileSchema(second.Value())
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *JSONSchemaValidate) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *JSONSchemaValidate) Type() value.Type { return value.BOOLEAN }
+
+func (this *JSONSchemaValidate) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+/*
+If this expression is in the WHERE clause of a partial index, lists
+the Expressions that are implicitly covered.
+
+For boolean functions, simply list this expression.
+*/
+func (this *JSONSchemaValidate) FilterCovers(covers map[string]value.Value) map[string]value.Value {
+	covers[this.String()] = value.TRUE_VALUE
+	return covers
+}
+
+func (this *JSONSchemaValidate) Apply(context Context, first, second value.Value) (value.Value, error) {
+	schema, rv, err := schemaArgs(this.schema, first, second, "JSON_SCHEMA_VALIDATE()")
+	if schema == nil {
+		return rv, err
+	}
+
+	return value.NewValue(schema.Valid(first)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *JSONSchemaValidate) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewJSONSchemaValidate(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// JSONSchemaErrors
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the JSON function JSON_SCHEMA_ERRORS(expr, schema).
+It returns an array of the ways in which expr violates the JSON
+schema, each an object with the JSON pointer path of the offending
+value and a message. The array is empty if expr conforms.
+*/
+type JSONSchemaErrors struct {
+	BinaryFunctionBase
+	schema *validation.Schema
+}
+
+func NewJSONSchemaErrors(first, second Expression) Function {
+	rv := &JSONSchemaErrors{
+		*NewBinaryFunctionBase("json_schema_errors", first, second),
+		nil,
+	}
+
+	rv.schema = precompileSchema(second.Value())
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *JSONSchemaErrors) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *JSONSchemaErrors) Type() value.Type { return value.ARRAY }
+
+func (this *JSONSchemaErrors) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *JSONSchemaErrors) Apply(context Context, first, second value.Value) (value.Value, error) {
+	schema, rv, err := schemaArgs(this.schema, first, second, "JSON_SCHEMA_ERRORS()")
+	if schema == nil {
+		return rv, err
+	}
+
+	errs := schema.Validate(first)
+	ra := make([]interface{}, len(errs))
+	for i, e := range errs {
+		ra[i] = map[string]interface{}{
+			"path":    e.Path,
+			"message": e.Message,
+		}
+	}
+
+	return value.NewValue(ra), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *JSONSchemaErrors) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewJSONSchemaErrors(operands[0], operands[1])
+	}
+}
+
+/*
+Compiles a constant schema once, when the function is built.
+*/
+func precompileSchema(source value.Value) *validation.Schema {
+	if source == nil {
+		return nil
+	}
+
+	schema, err := validation.NewSchema(source)
+	if err != nil {
+		return nil
+	}
+
+	return schema
+}
+
+/*
+Checks the arguments of JSON_SCHEMA_VALIDATE and JSON_SCHEMA_ERRORS,
+and returns the compiled schema, or else the MISSING or NULL result
+or the error for an invalid schema.
+*/
+func schemaArgs(schema *validation.Schema, first, second value.Value, fn string) (
+	*validation.Schema, value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return nil, value.MISSING_VALUE, nil
+	} else if second.Type() == value.NULL {
+		return nil, value.NULL_VALUE, nil
+	}
+
+	if schema != nil {
+		return schema, nil, nil
+	}
+
+	schema, err := validation.NewSchema(second)
+	if err != nil {
+		return nil, nil, errors.NewEvaluationError(err, fn)
+	}
+
+	return schema, nil, nil
+}
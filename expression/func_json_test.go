@@ -0,0 +1,45 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestJSONDecodeLongNumbers(t *testing.T) {
+	for in, out := range map[string]string{
+		"12345678901234567890123":               "12345678901234567890123",
+		` {"a": [-98765432109876543210, 1.5]} `: `{"a":[-98765432109876543210,1.5]}`,
+		`[1, 2, "x"]`:                           `[1,2,"x"]`,
+	} {
+		rv, err := NewJSONDecode(NewConstant(in)).Evaluate(nil, nil)
+		if err != nil {
+			t.Fatalf("received error %v", err)
+		}
+		bytes, _ := rv.MarshalJSON()
+		if string(bytes) != out {
+			t.Errorf("Expected %s for %s, got %s", out, in, bytes)
+		}
+	}
+
+	rv, _ := NewJSONDecode(NewConstant("12345678901234567890123")).Evaluate(nil, nil)
+	if !value.IsDecimal(rv) {
+		t.Errorf("Expected a decimal, got %v of type %T", rv, rv)
+	}
+
+	for _, in := range []string{"", "{", "12 x"} {
+		rv, _ := NewJSONDecode(NewConstant(in)).Evaluate(nil, nil)
+		if rv.Type() != value.NULL {
+			t.Errorf("Expected NULL for %s, got %v", in, rv)
+		}
+	}
+}
@@ -67,6 +67,10 @@ func (this *Abs) Apply(context Context, arg value.Value) (value.Value, error) {
 		return value.NULL_VALUE, nil
 	}
 
+	if d := value.DecimalAbs(arg); d != nil {
+		return d, nil
+	}
+
 	return value.NewValue(math.Abs(arg.Actual().(float64))), nil
 }
 
@@ -371,6 +375,10 @@ func (this *Ceil) Apply(context Context, arg value.Value) (value.Value, error) {
 		return value.NULL_VALUE, nil
 	}
 
+	if d := value.DecimalRound(arg, 0, value.ROUND_CEILING); d != nil {
+		return d, nil
+	}
+
 	return value.NewValue(math.Ceil(arg.Actual().(float64))), nil
 }
 
@@ -778,6 +786,10 @@ func (this *Floor) Apply(context Context, arg value.Value) (value.Value, error)
 		return value.NULL_VALUE, nil
 	}
 
+	if d := value.DecimalRound(arg, 0, value.ROUND_FLOOR); d != nil {
+		return d, nil
+	}
+
 	return value.NewValue(math.Floor(arg.Actual().(float64))), nil
 }
 
@@ -1312,6 +1324,9 @@ func (this *Round) Apply(context Context, args ...value.Value) (value.Value, err
 	v := arg.Actual().(float64)
 
 	if len(this.operands) == 1 {
+		if d := value.DecimalRound(arg, 0, value.ROUND_HALF_EVEN); d != nil {
+			return d, nil
+		}
 		return value.NewValue(roundFloat(v, 0)), nil
 	}
 
@@ -1329,6 +1344,9 @@ func (this *Round) Apply(context Context, args ...value.Value) (value.Value, err
 		p = int(pf)
 	}
 
+	if d := value.DecimalRound(arg, p, value.ROUND_HALF_EVEN); d != nil {
+		return d, nil
+	}
 	return value.NewValue(roundFloat(v, p)), nil
 }
 
@@ -1400,7 +1418,11 @@ func (this *Sign) Apply(context Context, arg value.Value) (value.Value, error) {
 		return value.NULL_VALUE, nil
 	}
 
+	// decimals too small for a float64 still have a sign
 	f := arg.Actual().(float64)
+	if value.IsDecimal(arg) {
+		f = float64(arg.Collate(value.ZERO_VALUE))
+	}
 	s := 0.0
 	if f < 0.0 {
 		s = -1.0
@@ -1655,6 +1677,9 @@ func (this *Trunc) Apply(context Context, args ...value.Value) (value.Value, err
 	v := arg.Actual().(float64)
 
 	if len(this.operands) == 1 {
+		if d := value.DecimalRound(arg, 0, value.ROUND_DOWN); d != nil {
+			return d, nil
+		}
 		return value.NewValue(truncateFloat(v, 0)), nil
 	}
 
@@ -1672,6 +1697,9 @@ func (this *Trunc) Apply(context Context, args ...value.Value) (value.Value, err
 		p = int(pf)
 	}
 
+	if d := value.DecimalRound(arg, p, value.ROUND_DOWN); d != nil {
+		return d, nil
+	}
 	return value.NewValue(truncateFloat(v, p)), nil
 }
 
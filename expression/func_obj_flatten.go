@@ -0,0 +1,290 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// ObjectPaths
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the object function OBJECT_PATHS(expr). It returns
+the JSON pointers of all the leaves of expr, in order, descending
+into nested objects and arrays. Empty objects and arrays are leaves.
+*/
+type ObjectPaths struct {
+	UnaryFunctionBase
+}
+
+func NewObjectPaths(operand Expression) Function {
+	rv := &ObjectPaths{
+		*NewUnaryFunctionBase("object_paths", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *ObjectPaths) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *ObjectPaths) Type() value.Type { return value.ARRAY }
+
+func (this *ObjectPaths) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *ObjectPaths) Apply(context Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if arg.Type() != value.OBJECT && arg.Type() != value.ARRAY {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(leafPaths(arg, "", make([]interface{}, 0, 16))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *ObjectPaths) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewObjectPaths(operands[0])
+	}
+}
+
+func leafPaths(val value.Value, path string, paths []interface{}) []interface{} {
+	switch val.Type() {
+	case value.OBJECT:
+		fields := val.Fields()
+		if len(fields) > 0 {
+			for _, n := range sortedFieldNames(fields) {
+				paths = leafPaths(value.NewValue(fields[n]), path+"/"+escapePointer(n), paths)
+			}
+			return paths
+		}
+	case value.ARRAY:
+		items := val.Actual().([]interface{})
+		if len(items) > 0 {
+			for i, item := range items {
+				paths = leafPaths(value.NewValue(item), path+"/"+strconv.Itoa(i), paths)
+			}
+			return paths
+		}
+	}
+
+	return append(paths, path)
+}
+
+///////////////////////////////////////////////////
+//
+// ObjectFlatten
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the object function OBJECT_FLATTEN(expr [, separator ]).
+It returns a single level object whose field names are the paths to
+the non-object values of expr, with the names along each path joined
+by separator, "." by default. Arrays and empty objects are kept as
+values.
+*/
+type ObjectFlatten struct {
+	FunctionBase
+}
+
+func NewObjectFlatten(operands ...Expression) Function {
+	rv := &ObjectFlatten{
+		*NewFunctionBase("object_flatten", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *ObjectFlatten) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *ObjectFlatten) Type() value.Type { return value.OBJECT }
+
+func (this *ObjectFlatten) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *ObjectFlatten) Apply(context Context, args ...value.Value) (value.Value, error) {
+	sep, rv := flattenArgs(args)
+	if rv != nil {
+		return rv, nil
+	}
+
+	fields := args[0].Fields()
+	flat := make(map[string]interface{}, len(fields))
+	flattenObject(fields, "", sep, flat)
+	return value.NewValue(flat), nil
+}
+
+func (this *ObjectFlatten) MinArgs() int { return 1 }
+
+func (this *ObjectFlatten) MaxArgs() int { return 2 }
+
+/*
+Factory method pattern.
+*/
+func (this *ObjectFlatten) Constructor() FunctionConstructor {
+	return NewObjectFlatten
+}
+
+func flattenObject(fields map[string]interface{}, prefix, sep string, flat map[string]interface{}) {
+	for n, v := range fields {
+		val := value.NewValue(v)
+		if val.Type() == value.OBJECT {
+			if nested := val.Fields(); len(nested) > 0 {
+				flattenObject(nested, prefix+n+sep, sep, flat)
+				continue
+			}
+		}
+
+		flat[prefix+n] = val
+	}
+}
+
+/*
+Checks the arguments of OBJECT_FLATTEN and OBJECT_UNFLATTEN, and
+returns the separator, or else the MISSING or NULL result.
+*/
+func flattenArgs(args []value.Value) (string, value.Value) {
+	for _, a := range args {
+		if a.Type() == value.MISSING {
+			return "", value.MISSING_VALUE
+		}
+	}
+
+	if args[0].Type() != value.OBJECT {
+		return "", value.NULL_VALUE
+	}
+
+	sep := "."
+	if len(args) > 1 {
+		if args[1].Type() != value.STRING || args[1].Actual().(string) == "" {
+			return "", value.NULL_VALUE
+		}
+		sep = args[1].Actual().(string)
+	}
+
+	return sep, nil
+}
+
+///////////////////////////////////////////////////
+//
+// ObjectUnflatten
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the object function OBJECT_UNFLATTEN(expr [, separator ]).
+It reverses OBJECT_FLATTEN, splitting the field names of expr on
+separator, "." by default, into nested objects. It returns NULL if two
+names conflict, such as "a" and "a.b" both holding values.
+*/
+type ObjectUnflatten struct {
+	FunctionBase
+}
+
+func NewObjectUnflatten(operands ...Expression) Function {
+	rv := &ObjectUnflatten{
+		*NewFunctionBase("object_unflatten", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *ObjectUnflatten) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *ObjectUnflatten) Type() value.Type { return value.OBJECT }
+
+func (this *ObjectUnflatten) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *ObjectUnflatten) Apply(context Context, args ...value.Value) (value.Value, error) {
+	sep, rv := flattenArgs(args)
+	if rv != nil {
+		return rv, nil
+	}
+
+	fields := args[0].Fields()
+	nested := make(map[string]interface{}, len(fields))
+
+	for _, name := range sortedFieldNames(fields) {
+		names := strings.Split(name, sep)
+		obj := nested
+		for i, n := range names[:len(names)-1] {
+
+			// a field holding a value is never descended into, so that
+			// the objects of the argument are left unchanged
+			if _, ok := fields[strings.Join(names[:i+1], sep)]; ok {
+				return value.NULL_VALUE, nil
+			}
+
+			child, ok := obj[n]
+			if !ok {
+				child = make(map[string]interface{})
+				obj[n] = child
+			}
+
+			m, ok := child.(map[string]interface{})
+			if !ok {
+				return value.NULL_VALUE, nil
+			}
+			obj = m
+		}
+
+		n := names[len(names)-1]
+		if _, ok := obj[n]; ok {
+			return value.NULL_VALUE, nil
+		}
+		obj[n] = fields[name]
+	}
+
+	return value.NewValue(nested), nil
+}
+
+func (this *ObjectUnflatten) MinArgs() int { return 1 }
+
+func (this *ObjectUnflatten) MaxArgs() int { return 2 }
+
+/*
+Factory method pattern.
+*/
+func (this *ObjectUnflatten) Constructor() FunctionConstructor {
+	return NewObjectUnflatten
+}
@@ -140,29 +140,40 @@ var _FUNCTIONS = map[string]Function{
 	"weekday_str":         &WeekdayStr{},
 
 	// String
-	"contains":  &Contains{},
-	"initcap":   &Title{},
-	"length":    &Length{},
-	"lower":     &Lower{},
-	"ltrim":     &LTrim{},
-	"position":  &Position0{},
-	"pos":       &Position0{},
-	"position0": &Position0{},
-	"pos0":      &Position0{},
-	"position1": &Position1{},
-	"pos1":      &Position1{},
-	"repeat":    &Repeat{},
-	"replace":   &Replace{},
-	"reverse":   &Reverse{},
-	"rtrim":     &RTrim{},
-	"split":     &Split{},
-	"substr":    &Substr0{},
-	"substr0":   &Substr0{},
-	"substr1":   &Substr1{},
-	"suffixes":  &Suffixes{},
-	"title":     &Title{},
-	"trim":      &Trim{},
-	"upper":     &Upper{},
+	"contains":      &Contains{},
+	"edit_distance": &Levenshtein{},
+	"format":        &Format{},
+	"initcap":       &Title{},
+	"length":        &Length{},
+	"levenshtein":   &Levenshtein{},
+	"lower":         &Lower{},
+	"lpad":          &LPad{},
+	"ltrim":         &LTrim{},
+	"mask":          &Mask{},
+	"metaphone":     &Metaphone{},
+	"normalize":     &Normalize{},
+	"number_format": &NumberFormat{},
+	"position":      &Position0{},
+	"pos":           &Position0{},
+	"position0":     &Position0{},
+	"pos0":          &Position0{},
+	"position1":     &Position1{},
+	"pos1":          &Position1{},
+	"repeat":        &Repeat{},
+	"replace":       &Replace{},
+	"reverse":       &Reverse{},
+	"rpad":          &RPad{},
+	"rtrim":         &RTrim{},
+	"soundex":       &Soundex{},
+	"split":         &Split{},
+	"substr":        &Substr0{},
+	"substr0":       &Substr0{},
+	"substr1":       &Substr1{},
+	"suffixes":      &Suffixes{},
+	"title":         &Title{},
+	"translate":     &Translate{},
+	"trim":          &Trim{},
+	"upper":         &Upper{},
 
 	// Regular expressions
 	"contains_regex":   &RegexpContains{},
@@ -271,6 +282,7 @@ var _FUNCTIONS = map[string]Function{
 	// Object
 	"object_add":          &ObjectAdd{},
 	"object_concat":       &ObjectConcat{},
+	"object_flatten":      &ObjectFlatten{},
 	"object_inner_pairs":  &ObjectInnerPairs{},
 	"object_innerpairs":   &ObjectInnerPairs{},
 	"object_inner_values": &ObjectInnerValues{},
@@ -282,21 +294,29 @@ var _FUNCTIONS = map[string]Function{
 	"object_outer_values": &ObjectValues{},
 	"object_outervalues":  &ObjectValues{},
 	"object_pairs":        &ObjectPairs{},
+	"object_paths":        &ObjectPaths{},
 	"object_put":          &ObjectPut{},
 	"object_remove":       &ObjectRemove{},
 	"object_rename":       &ObjectRename{},
 	"object_replace":      &ObjectReplace{},
+	"object_unflatten":    &ObjectUnflatten{},
 	"object_unwrap":       &ObjectUnwrap{},
 	"object_values":       &ObjectValues{},
 
 	// JSON
-	"decode_json":  &JSONDecode{},
-	"encode_json":  &JSONEncode{},
-	"encoded_size": &EncodedSize{},
-	"json_decode":  &JSONDecode{},
-	"json_encode":  &JSONEncode{},
-	"pairs":        &Pairs{},
-	"poly_length":  &PolyLength{},
+	"decode_json":          &JSONDecode{},
+	"encode_json":          &JSONEncode{},
+	"encoded_size":         &EncodedSize{},
+	"json_decode":          &JSONDecode{},
+	"json_diff":            &JSONDiff{},
+	"json_encode":          &JSONEncode{},
+	"json_merge_patch":     &JSONMergePatch{},
+	"json_patch":           &JSONPatch{},
+	"json_pointer_get":     &JSONPointerGet{},
+	"json_schema_errors":   &JSONSchemaErrors{},
+	"json_schema_validate": &JSONSchemaValidate{},
+	"pairs":                &Pairs{},
+	"poly_length":          &PolyLength{},
 
 	// Base64
 	"base64":        &Base64Encode{},
@@ -305,6 +325,36 @@ var _FUNCTIONS = map[string]Function{
 	"decode_base64": &Base64Decode{},
 	"encode_base64": &Base64Encode{},
 
+	// Hash
+	"crc32":  &CRC32{},
+	"hash64": &Hash64{},
+	"hmac":   &HMAC{},
+	"md5":    &MD5{},
+	"sha1":   &SHA1{},
+	"sha256": &SHA256{},
+	"sha512": &SHA512{},
+
+	// Encoding
+	"decode_hex": &HexDecode{},
+	"decode_url": &URLDecode{},
+	"encode_hex": &HexEncode{},
+	"encode_url": &URLEncode{},
+	"hex_decode": &HexDecode{},
+	"hex_encode": &HexEncode{},
+	"url_decode": &URLDecode{},
+	"url_encode": &URLEncode{},
+
+	// Geospatial
+	"geohash":        &Geohash{},
+	"geohash_decode": &GeohashDecode{},
+	"geohash_encode": &Geohash{},
+	"st_area":        &STArea{},
+	"st_buffer":      &STBuffer{},
+	"st_contains":    &STContains{},
+	"st_distance":    &STDistance{},
+	"st_intersects":  &STIntersects{},
+	"st_within":      &STWithin{},
+
 	// Comparison
 	"greatest":  &Greatest{},
 	"least":     &Least{},
@@ -393,6 +443,7 @@ var _FUNCTIONS = map[string]Function{
 	"to_atom":    &ToAtom{},
 	"to_bool":    &ToBoolean{},
 	"to_boolean": &ToBoolean{},
+	"to_decimal": &ToDecimal{},
 	"to_num":     &ToNumber{},
 	"to_number":  &ToNumber{},
 	"to_obj":     &ToObject{},
@@ -403,6 +454,7 @@ var _FUNCTIONS = map[string]Function{
 	"toatom":     &ToAtom{},
 	"tobool":     &ToBoolean{},
 	"toboolean":  &ToBoolean{},
+	"todecimal":  &ToDecimal{},
 	"tonum":      &ToNumber{},
 	"tonumber":   &ToNumber{},
 	"toobj":      &ToObject{},
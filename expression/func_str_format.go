@@ -0,0 +1,752 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// LPad
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function LPAD(expr, n [, pad ]). It
+returns expr padded on the left with repetitions of pad, a single
+space by default, to n characters. Longer strings are truncated to
+n characters.
+*/
+type LPad struct {
+	FunctionBase
+}
+
+func NewLPad(operands ...Expression) Function {
+	rv := &LPad{
+		*NewFunctionBase("lpad", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *LPad) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *LPad) Type() value.Type { return value.STRING }
+
+func (this *LPad) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *LPad) Apply(context Context, args ...value.Value) (value.Value, error) {
+	return strPadApply(args, true, "LPAD()")
+}
+
+func (this *LPad) MinArgs() int { return 2 }
+
+func (this *LPad) MaxArgs() int { return 3 }
+
+/*
+Factory method pattern.
+*/
+func (this *LPad) Constructor() FunctionConstructor {
+	return NewLPad
+}
+
+///////////////////////////////////////////////////
+//
+// RPad
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function RPAD(expr, n [, pad ]). It
+returns expr padded on the right with repetitions of pad, a single
+space by default, to n characters. Longer strings are truncated to
+n characters.
+*/
+type RPad struct {
+	FunctionBase
+}
+
+func NewRPad(operands ...Expression) Function {
+	rv := &RPad{
+		*NewFunctionBase("rpad", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *RPad) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *RPad) Type() value.Type { return value.STRING }
+
+func (this *RPad) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *RPad) Apply(context Context, args ...value.Value) (value.Value, error) {
+	return strPadApply(args, false, "RPAD()")
+}
+
+func (this *RPad) MinArgs() int { return 2 }
+
+func (this *RPad) MaxArgs() int { return 3 }
+
+/*
+Factory method pattern.
+*/
+func (this *RPad) Constructor() FunctionConstructor {
+	return NewRPad
+}
+
+func strPadApply(args []value.Value, left bool, name string) (value.Value, error) {
+	null := false
+
+	for i, a := range args {
+		if a.Type() == value.MISSING {
+			return value.MISSING_VALUE, nil
+		} else if i == 1 {
+			if a.Type() != value.NUMBER {
+				null = true
+			}
+		} else if a.Type() != value.STRING {
+			null = true
+		}
+	}
+
+	if null {
+		return value.NULL_VALUE, nil
+	}
+
+	nf := args[1].Actual().(float64)
+	if nf < 0.0 || nf != math.Trunc(nf) {
+		return value.NULL_VALUE, nil
+	}
+
+	n := int(nf)
+	if n > RANGE_LIMIT {
+		return nil, errors.NewRangeError(name)
+	}
+
+	str := []rune(args[0].Actual().(string))
+	pad := []rune(" ")
+	if len(args) > 2 {
+		pad = []rune(args[2].Actual().(string))
+	}
+
+	if len(str) >= n || len(pad) == 0 {
+		if len(str) > n {
+			str = str[:n]
+		}
+		return value.NewValue(string(str)), nil
+	}
+
+	fill := make([]rune, 0, n-len(str))
+	for len(fill) < n-len(str) {
+		fill = append(fill, pad[len(fill)%len(pad)])
+	}
+
+	if left {
+		return value.NewValue(string(fill) + string(str)), nil
+	}
+
+	return value.NewValue(string(str) + string(fill)), nil
+}
+
+///////////////////////////////////////////////////
+//
+// Format
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function FORMAT(template, args...). It
+returns template with each printf-style placeholder replaced by the
+next argument, e.g. FORMAT("%s owes %.2f", name, amount). Placeholders
+of the form %(name)s take the field name of the first argument, which
+must then be an object. Supported verbs are s, v and q for any value,
+d, o, b, x and X for integers, and f, e, E, g and G for numbers. %%
+produces a literal percent sign. Placeholders take the flags -, +, #,
+space and 0, and a width and precision of at most 100. The result is
+NULL if the template is malformed, or an argument is absent or of the
+wrong type, including numbers with a fraction for the integer verbs.
+*/
+type Format struct {
+	FunctionBase
+}
+
+func NewFormat(operands ...Expression) Function {
+	rv := &Format{
+		*NewFunctionBase("format", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Format) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Format) Type() value.Type { return value.STRING }
+
+func (this *Format) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *Format) Apply(context Context, args ...value.Value) (value.Value, error) {
+	for _, a := range args {
+		if a.Type() == value.MISSING {
+			return value.MISSING_VALUE, nil
+		}
+	}
+
+	if args[0].Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	template := args[0].Actual().(string)
+	next := 1
+	buf := bytes.NewBuffer(make([]byte, 0, len(template)))
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '%' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i < len(template) && template[i] == '%' {
+			buf.WriteByte('%')
+			continue
+		}
+
+		var arg value.Value
+		if i < len(template) && template[i] == '(' {
+			end := strings.IndexByte(template[i:], ')')
+			if end < 0 || len(args) < 2 || args[1].Type() != value.OBJECT {
+				return value.NULL_VALUE, nil
+			}
+
+			field, ok := args[1].Field(template[i+1 : i+end])
+			if !ok {
+				return value.NULL_VALUE, nil
+			}
+
+			arg = field
+			i += end + 1
+		} else {
+			if next >= len(args) {
+				return value.NULL_VALUE, nil
+			}
+
+			arg = args[next]
+			next++
+		}
+
+		start := i
+		for i < len(template) && strings.IndexByte("-+ #0123456789.", template[i]) >= 0 {
+			i++
+		}
+
+		if i >= len(template) {
+			return value.NULL_VALUE, nil
+		}
+
+		if !formatSpec(template[start:i]) {
+			return value.NULL_VALUE, nil
+		}
+
+		s, ok := formatVerb(template[start:i], template[i], arg)
+		if !ok {
+			return value.NULL_VALUE, nil
+		}
+
+		buf.WriteString(s)
+	}
+
+	return value.NewValue(buf.String()), nil
+}
+
+func (this *Format) MinArgs() int { return 1 }
+
+func (this *Format) MaxArgs() int { return math.MaxInt16 }
+
+/*
+Factory method pattern.
+*/
+func (this *Format) Constructor() FunctionConstructor {
+	return NewFormat
+}
+
+// bound widths and precisions, as NUMBER_FORMAT does its decimals
+const _FORMAT_MAX_WIDTH = 100
+
+/*
+Checks that a placeholder is flags, then an optional width, then an
+optional precision, so that fmt cannot produce its error strings.
+*/
+func formatSpec(spec string) bool {
+	i := 0
+	for i < len(spec) && strings.IndexByte("-+ #0", spec[i]) >= 0 {
+		i++
+	}
+
+	j := i
+	for j < len(spec) && spec[j] >= '0' && spec[j] <= '9' {
+		j++
+	}
+	if !formatWidth(spec[i:j]) {
+		return false
+	}
+
+	if j == len(spec) {
+		return true
+	} else if spec[j] != '.' {
+		return false
+	}
+
+	k := j + 1
+	for k < len(spec) && spec[k] >= '0' && spec[k] <= '9' {
+		k++
+	}
+	return k == len(spec) && formatWidth(spec[j+1:k])
+}
+
+func formatWidth(digits string) bool {
+	if digits == "" {
+		return true
+	}
+	w, err := strconv.Atoi(digits)
+	return err == nil && w <= _FORMAT_MAX_WIDTH
+}
+
+func formatVerb(spec string, verb byte, arg value.Value) (string, bool) {
+	format := "%" + spec + string(verb)
+
+	switch verb {
+	case 's', 'v', 'q':
+		if arg.Type() == value.STRING {
+			return fmt.Sprintf(format, arg.Actual().(string)), true
+		}
+		return fmt.Sprintf(format, arg.String()), true
+	case 'd', 'o', 'b', 'x', 'X':
+		if arg.Type() == value.STRING && (verb == 'x' || verb == 'X') {
+			return fmt.Sprintf(format, arg.Actual().(string)), true
+		} else if arg.Type() != value.NUMBER {
+			return "", false
+		}
+
+		if i, ok := arg.ActualForIndex().(int64); ok {
+			return fmt.Sprintf(format, i), true
+		}
+
+		// other numbers must be integers within int64
+		i := value.AsNumberValue(arg).Int64()
+		if !arg.Equals(value.NewValue(i)).Truth() {
+			return "", false
+		}
+		return fmt.Sprintf(format, i), true
+	case 'f', 'e', 'E', 'g', 'G':
+		if arg.Type() != value.NUMBER {
+			return "", false
+		}
+		return fmt.Sprintf(format, arg.Actual().(float64)), true
+	default:
+		return "", false
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// NumberFormat
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function NUMBER_FORMAT(num [, decimals
+[, locale ]]). It returns num as a string with the thousands and
+decimal separators of locale, "en" by default, rounded half away from
+zero to the given number of decimal places. If decimals is omitted,
+all the digits of num are kept.
+*/
+type NumberFormat struct {
+	FunctionBase
+}
+
+func NewNumberFormat(operands ...Expression) Function {
+	rv := &NumberFormat{
+		*NewFunctionBase("number_format", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *NumberFormat) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *NumberFormat) Type() value.Type { return value.STRING }
+
+func (this *NumberFormat) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *NumberFormat) Apply(context Context, args ...value.Value) (value.Value, error) {
+	null := false
+
+	for i, a := range args {
+		if a.Type() == value.MISSING {
+			return value.MISSING_VALUE, nil
+		} else if i < 2 {
+			if a.Type() != value.NUMBER {
+				null = true
+			}
+		} else if a.Type() != value.STRING {
+			null = true
+		}
+	}
+
+	if null {
+		return value.NULL_VALUE, nil
+	}
+
+	var digits string
+	switch a := args[0].ActualForIndex().(type) {
+	case int64:
+		digits = strconv.FormatInt(a, 10)
+	case float64:
+		if math.IsNaN(a) || math.IsInf(a, 0) {
+			return value.NULL_VALUE, nil
+		}
+		digits = strconv.FormatFloat(a, 'f', -1, 64)
+	default:
+		digits = args[0].String()
+	}
+
+	if len(args) > 1 {
+		df := args[1].Actual().(float64)
+		if df < 0.0 || df > 100.0 || df != math.Trunc(df) {
+			return value.NULL_VALUE, nil
+		}
+		digits = roundDigits(digits, int(df))
+	}
+
+	name := "en"
+	if len(args) > 2 {
+		name = args[2].Actual().(string)
+	}
+
+	locale, ok := numberLocale(name)
+	if !ok {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(locale.format(digits)), nil
+}
+
+func (this *NumberFormat) MinArgs() int { return 1 }
+
+func (this *NumberFormat) MaxArgs() int { return 3 }
+
+/*
+Factory method pattern.
+*/
+func (this *NumberFormat) Constructor() FunctionConstructor {
+	return NewNumberFormat
+}
+
+type numberSeparators struct {
+	group   string
+	decimal string
+	indian  bool
+}
+
+var _NUMBER_LOCALES = map[string]*numberSeparators{
+	"en":    {",", ".", false},
+	"ja":    {",", ".", false},
+	"ko":    {",", ".", false},
+	"zh":    {",", ".", false},
+	"de":    {".", ",", false},
+	"es":    {".", ",", false},
+	"id":    {".", ",", false},
+	"it":    {".", ",", false},
+	"nl":    {".", ",", false},
+	"pt":    {".", ",", false},
+	"tr":    {".", ",", false},
+	"cs":    {" ", ",", false},
+	"fi":    {" ", ",", false},
+	"fr":    {" ", ",", false},
+	"nb":    {" ", ",", false},
+	"pl":    {" ", ",", false},
+	"ru":    {" ", ",", false},
+	"sv":    {" ", ",", false},
+	"uk":    {" ", ",", false},
+	"de_ch": {"'", ".", false},
+	"fr_ch": {"'", ".", false},
+	"en_in": {",", ".", true},
+	"hi":    {",", ".", true},
+}
+
+/*
+Looks up a locale such as "de", "de-CH" or "en_IN", falling back from
+the region to the language.
+*/
+func numberLocale(name string) (*numberSeparators, bool) {
+	name = strings.Replace(strings.ToLower(name), "-", "_", -1)
+	if locale, ok := _NUMBER_LOCALES[name]; ok {
+		return locale, true
+	}
+
+	if n := strings.IndexByte(name, '_'); n > 0 {
+		locale, ok := _NUMBER_LOCALES[name[:n]]
+		return locale, ok
+	}
+
+	return nil, false
+}
+
+/*
+Formats a plain decimal string such as -1234567.89.
+*/
+func (this *numberSeparators) format(digits string) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign = "-"
+		digits = digits[1:]
+	}
+
+	frac := ""
+	if n := strings.IndexByte(digits, '.'); n >= 0 {
+		frac = this.decimal + digits[n+1:]
+		digits = digits[:n]
+	}
+
+	groups := make([]string, 0, len(digits)/3+1)
+	size := 3
+	for len(digits) > size {
+		groups = append(groups, digits[len(digits)-size:])
+		digits = digits[:len(digits)-size]
+		if this.indian {
+			size = 2
+		}
+	}
+	groups = append(groups, digits)
+
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+
+	return sign + strings.Join(groups, this.group) + frac
+}
+
+/*
+Rounds a plain decimal string half away from zero to the given number
+of decimal places, padding with zeros as needed.
+*/
+func roundDigits(digits string, places int) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign = "-"
+		digits = digits[1:]
+	}
+
+	whole, frac := digits, ""
+	if n := strings.IndexByte(digits, '.'); n >= 0 {
+		whole, frac = digits[:n], digits[n+1:]
+	}
+
+	up := len(frac) > places && frac[places] >= '5'
+	if len(frac) > places {
+		frac = frac[:places]
+	} else {
+		frac += strings.Repeat("0", places-len(frac))
+	}
+
+	b := []byte(whole + frac)
+	for i := len(b) - 1; up && i >= 0; i-- {
+		if b[i] == '9' {
+			b[i] = '0'
+		} else {
+			b[i]++
+			up = false
+		}
+	}
+	if up {
+		b = append([]byte{'1'}, b...)
+	}
+
+	whole, frac = string(b[:len(b)-places]), string(b[len(b)-places:])
+	if strings.Trim(whole+frac, "0") == "" {
+		sign = ""
+	}
+
+	if places == 0 {
+		return sign + whole
+	}
+
+	return sign + whole + "." + frac
+}
+
+///////////////////////////////////////////////////
+//
+// Mask
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function MASK(expr [, options ]). It
+redacts expr by replacing its characters with a mask character,
+keeping the first options.prefix characters (0 by default) and the
+last options.suffix characters (4 by default) readable. options.char
+is the mask character, "*" by default, and characters in
+options.preserve, such as separators, are never masked. Strings no
+longer than prefix plus suffix are masked entirely.
+*/
+type Mask struct {
+	FunctionBase
+}
+
+func NewMask(operands ...Expression) Function {
+	rv := &Mask{
+		*NewFunctionBase("mask", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Mask) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Mask) Type() value.Type { return value.STRING }
+
+func (this *Mask) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *Mask) Apply(context Context, args ...value.Value) (value.Value, error) {
+	if args[0].Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	}
+
+	options := _EMPTY_OPTIONS
+	if len(args) > 1 {
+		switch args[1].Type() {
+		case value.OBJECT:
+			options = args[1]
+		case value.MISSING:
+			return value.MISSING_VALUE, nil
+		default:
+			return value.NULL_VALUE, nil
+		}
+	}
+
+	if args[0].Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	prefix, ok1 := maskOption(options, "prefix", 0)
+	suffix, ok2 := maskOption(options, "suffix", 4)
+	if !ok1 || !ok2 {
+		return value.NULL_VALUE, nil
+	}
+
+	char, preserve := "*", ""
+	if c, ok := options.Field("char"); ok {
+		if c.Type() != value.STRING || c.Actual().(string) == "" {
+			return value.NULL_VALUE, nil
+		}
+		char = c.Actual().(string)
+	}
+	if p, ok := options.Field("preserve"); ok {
+		if p.Type() != value.STRING {
+			return value.NULL_VALUE, nil
+		}
+		preserve = p.Actual().(string)
+	}
+
+	str := []rune(args[0].Actual().(string))
+	if len(str) <= prefix+suffix {
+		prefix, suffix = 0, 0
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(str)*len(char)))
+	for i, r := range str {
+		if i < prefix || i >= len(str)-suffix || strings.ContainsRune(preserve, r) {
+			buf.WriteRune(r)
+		} else {
+			buf.WriteString(char)
+		}
+	}
+
+	return value.NewValue(buf.String()), nil
+}
+
+func (this *Mask) MinArgs() int { return 1 }
+
+func (this *Mask) MaxArgs() int { return 2 }
+
+/*
+Factory method pattern.
+*/
+func (this *Mask) Constructor() FunctionConstructor {
+	return NewMask
+}
+
+func maskOption(options value.Value, name string, def int) (int, bool) {
+	v, ok := options.Field(name)
+	if !ok {
+		return def, true
+	}
+
+	if v.Type() != value.NUMBER {
+		return 0, false
+	}
+
+	f := v.Actual().(float64)
+	if f < 0.0 || f != math.Trunc(f) || f > RANGE_LIMIT {
+		return 0, false
+	}
+
+	return int(f), true
+}
@@ -0,0 +1,108 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func constants(vals ...interface{}) Expressions {
+	rv := make(Expressions, len(vals))
+	for i, v := range vals {
+		rv[i] = NewConstant(v)
+	}
+	return rv
+}
+
+func TestFormat(t *testing.T) {
+	person := map[string]interface{}{"name": "Bob", "age": 42}
+	testFunctions(t, []functionTest{
+		{NewFormat(constants("%s owes %.2f (%d%%)", "Ann", 12.345, 7)...), value.NewValue("Ann owes 12.35 (7%)")},
+		{NewFormat(constants("%(name)s is %(age)d", person)...), value.NewValue("Bob is 42")},
+		{NewFormat(constants("[%-5s|%5s|%05d|%x|%+.1e]", "ab", "cd", 42, 255, 1500)...), value.NewValue("[ab   |   cd|00042|ff|+1.5e+03]")},
+		{NewFormat(constants("%v", []interface{}{1, "a"})...), value.NewValue(`[1,"a"]`)},
+		{NewFormat(constants("%d", 3.0)...), value.NewValue("3")},
+		{NewFormat(constants("%d", value.ToDecimal(value.NewValue("12")))...), value.NewValue("12")},
+		{NewFormat(constants("%100d", 1)...), value.NewValue(strings.Repeat(" ", 99) + "1")},
+
+		// malformed templates and mismatched arguments
+		{NewFormat(constants("%s %s", "one")...), value.NULL_VALUE},
+		{NewFormat(constants("%d", "x")...), value.NULL_VALUE},
+		{NewFormat(constants("%d", 3.5)...), value.NULL_VALUE},
+		{NewFormat(constants("%x", 1e300)...), value.NULL_VALUE},
+		{NewFormat(constants("%.99999999999f", 1)...), value.NULL_VALUE},
+		{NewFormat(constants("%101d", 1)...), value.NULL_VALUE},
+		{NewFormat(constants("%5.2.1f", 1)...), value.NULL_VALUE},
+		{NewFormat(constants("%5-d", 1)...), value.NULL_VALUE},
+		{NewFormat(constants("%z", 1)...), value.NULL_VALUE},
+		{NewFormat(constants("%", 1)...), value.NULL_VALUE},
+		{NewFormat(constants("%(name", person)...), value.NULL_VALUE},
+		{NewFormat(constants("%(other)s", person)...), value.NULL_VALUE},
+		{NewFormat(constants(1)...), value.NULL_VALUE},
+		{NewFormat(constants("%s", value.MISSING_VALUE)...), value.MISSING_VALUE},
+	})
+}
+
+func TestPadAndMask(t *testing.T) {
+	testFunctions(t, []functionTest{
+		{NewLPad(constants("7", 3, "0")...), value.NewValue("007")},
+		{NewRPad(constants("ab", 5, "xy")...), value.NewValue("abxyx")},
+		{NewLPad(constants("hello", 3)...), value.NewValue("hel")},
+		{NewRPad(constants("é", 3)...), value.NewValue("é  ")},
+		{NewLPad(constants("x", -1)...), value.NULL_VALUE},
+		{NewMask(constants("4111111111111111")...), value.NewValue("************1111")},
+		{NewMask(constants("4111-1111-1111-1234", map[string]interface{}{"preserve": "-", "char": "X"})...),
+			value.NewValue("XXXX-XXXX-XXXX-1234")},
+		{NewMask(constants("jane@example.com", map[string]interface{}{"prefix": 1, "suffix": 12})...),
+			value.NewValue("j***@example.com")},
+		{NewMask(constants(123)...), value.NULL_VALUE},
+	})
+}
+
+func TestNumberFormat(t *testing.T) {
+	testFunctions(t, []functionTest{
+		{NewNumberFormat(constants(1234567.891, 2)...), value.NewValue("1,234,567.89")},
+		{NewNumberFormat(constants(1234567.891, 2, "de")...), value.NewValue("1.234.567,89")},
+		{NewNumberFormat(constants(-1234.5, 0)...), value.NewValue("-1,235")},
+		{NewNumberFormat(constants(12345678, 0, "en-IN")...), value.NewValue("1,23,45,678")},
+		{NewNumberFormat(constants(1000)...), value.NewValue("1,000")},
+		{NewNumberFormat(constants(1, 2, "xx")...), value.NULL_VALUE},
+		{NewNumberFormat(constants(1, 101)...), value.NULL_VALUE},
+	})
+}
+
+func TestTextFunctions(t *testing.T) {
+	testFunctions(t, []functionTest{
+		{NewTranslate(NewConstant("a-b_c"), NewConstant("-_"), NewConstant(" ")), value.NewValue("a bc")},
+		{NewLevenshtein(NewConstant("kitten"), NewConstant("sitting")), value.NewValue(3)},
+		{NewLevenshtein(NewConstant("crème"), NewConstant("creme")), value.NewValue(1)},
+		{NewSoundex(NewConstant("Robert")), value.NewValue("R163")},
+		{NewSoundex(NewConstant("Tymczak")), value.NewValue("T522")},
+		{NewSoundex(NewConstant("Müller")), value.NewValue("M460")},
+		{NewMetaphone(NewConstant("Knight")), value.NewValue("NT")},
+		{NewMetaphone(NewConstant("Thompson")), value.NewValue("0MPSN")},
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	composed := "Café"
+	decomposed := "Café"
+	testFunctions(t, []functionTest{
+		{NewNormalize(NewConstant(decomposed)), value.NewValue(composed)},
+		{NewNormalize(NewConstant(composed), NewConstant("NFD")), value.NewValue(decomposed)},
+		{NewNormalize(NewConstant("ﬁ"), NewConstant("nfkc")), value.NewValue("fi")},
+		{NewNormalize(NewConstant("ﬁ"), NewConstant("NFC")), value.NewValue("ﬁ")},
+		{NewNormalize(NewConstant("x"), NewConstant("NFX")), value.NULL_VALUE},
+		{NewNormalize(NewConstant(1)), value.NULL_VALUE},
+	})
+}
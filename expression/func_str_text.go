@@ -0,0 +1,565 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package expression
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/couchbase/query/value"
+)
+
+///////////////////////////////////////////////////
+//
+// Translate
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function TRANSLATE(expr, from, to). It
+replaces each character of expr that occurs in from with the
+character at the same position in to. Characters of from with no
+counterpart in to are removed.
+*/
+type Translate struct {
+	TernaryFunctionBase
+}
+
+func NewTranslate(first, second, third Expression) Function {
+	rv := &Translate{
+		*NewTernaryFunctionBase("translate", first, second, third),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Translate) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Translate) Type() value.Type { return value.STRING }
+
+func (this *Translate) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.TernaryEval(this, item, context)
+}
+
+func (this *Translate) Apply(context Context, first, second, third value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING || third.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if first.Type() != value.STRING || second.Type() != value.STRING || third.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	from := []rune(second.Actual().(string))
+	to := []rune(third.Actual().(string))
+	mapping := make(map[rune]rune, len(from))
+	for i, r := range from {
+		if _, ok := mapping[r]; ok {
+			continue
+		}
+
+		if i < len(to) {
+			mapping[r] = to[i]
+		} else {
+			mapping[r] = -1
+		}
+	}
+
+	rv := strings.Map(func(r rune) rune {
+		if t, ok := mapping[r]; ok {
+			return t
+		}
+		return r
+	}, first.Actual().(string))
+
+	return value.NewValue(rv), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *Translate) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewTranslate(operands[0], operands[1], operands[2])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// Levenshtein
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function LEVENSHTEIN(expr1, expr2). It
+returns the edit distance between the two strings, i.e. the least
+number of character insertions, deletions and substitutions that
+turn one into the other.
+*/
+type Levenshtein struct {
+	BinaryFunctionBase
+}
+
+func NewLevenshtein(first, second Expression) Function {
+	rv := &Levenshtein{
+		*NewBinaryFunctionBase("levenshtein", first, second),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Levenshtein) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Levenshtein) Type() value.Type { return value.NUMBER }
+
+func (this *Levenshtein) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.BinaryEval(this, item, context)
+}
+
+func (this *Levenshtein) Apply(context Context, first, second value.Value) (value.Value, error) {
+	if first.Type() == value.MISSING || second.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if first.Type() != value.STRING || second.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	a := []rune(first.Actual().(string))
+	b := []rune(second.Actual().(string))
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return value.NewValue(prev[len(b)]), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *Levenshtein) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewLevenshtein(operands[0], operands[1])
+	}
+}
+
+///////////////////////////////////////////////////
+//
+// Soundex
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function SOUNDEX(expr). It returns the
+four character American Soundex code of expr, so that names that
+sound alike, such as "Robert" and "Rupert", have the same code.
+Accents are ignored, as are characters other than letters.
+*/
+type Soundex struct {
+	UnaryFunctionBase
+}
+
+func NewSoundex(operand Expression) Function {
+	rv := &Soundex{
+		*NewUnaryFunctionBase("soundex", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Soundex) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Soundex) Type() value.Type { return value.STRING }
+
+func (this *Soundex) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *Soundex) Apply(context Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if arg.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	word := phoneticLetters(arg.Actual().(string))
+	if len(word) == 0 {
+		return value.EMPTY_STRING_VALUE, nil
+	}
+
+	rv := []byte{word[0]}
+	last := _SOUNDEX_CODES[word[0]-'A']
+	for _, c := range word[1:] {
+		code := _SOUNDEX_CODES[c-'A']
+		switch code {
+		case '-':
+			continue
+		case '0':
+			last = code
+			continue
+		}
+
+		if code != last {
+			rv = append(rv, code)
+			if len(rv) == 4 {
+				break
+			}
+		}
+		last = code
+	}
+
+	for len(rv) < 4 {
+		rv = append(rv, '0')
+	}
+
+	return value.NewValue(string(rv)), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *Soundex) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewSoundex(operands[0])
+	}
+}
+
+/*
+Soundex digits of the letters A to Z. Vowels (0) separate letters
+with the same digit, while H and W (-) do not.
+*/
+const _SOUNDEX_CODES = "0123012-02245501262301-202"
+
+///////////////////////////////////////////////////
+//
+// Metaphone
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function METAPHONE(expr). It returns the
+Metaphone key of expr, a more accurate phonetic code for English
+words than Soundex; for instance, "Knight" and "Night" both map to
+"NT". Accents are ignored, as are characters other than letters.
+*/
+type Metaphone struct {
+	UnaryFunctionBase
+}
+
+func NewMetaphone(operand Expression) Function {
+	rv := &Metaphone{
+		*NewUnaryFunctionBase("metaphone", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Metaphone) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Metaphone) Type() value.Type { return value.STRING }
+
+func (this *Metaphone) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *Metaphone) Apply(context Context, arg value.Value) (value.Value, error) {
+	if arg.Type() == value.MISSING {
+		return value.MISSING_VALUE, nil
+	} else if arg.Type() != value.STRING {
+		return value.NULL_VALUE, nil
+	}
+
+	return value.NewValue(metaphone(phoneticLetters(arg.Actual().(string)))), nil
+}
+
+/*
+Factory method pattern.
+*/
+func (this *Metaphone) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewMetaphone(operands[0])
+	}
+}
+
+/*
+Returns the letters of s, without accents, in upper case.
+*/
+func phoneticLetters(s string) []byte {
+	rv := make([]byte, 0, len(s))
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			rv = append(rv, byte(r))
+		case r >= 'a' && r <= 'z':
+			rv = append(rv, byte(r-'a'+'A'))
+		}
+	}
+	return rv
+}
+
+func metaphone(word []byte) string {
+	if len(word) == 0 {
+		return ""
+	}
+
+	switch {
+	case len(word) > 1 && word[1] == 'N' && strings.IndexByte("GKP", word[0]) >= 0,
+		len(word) > 1 && word[0] == 'A' && word[1] == 'E',
+		len(word) > 1 && word[0] == 'W' && word[1] == 'R':
+		word = word[1:]
+	case word[0] == 'X':
+		word = append([]byte{'S'}, word[1:]...)
+	case len(word) > 1 && word[0] == 'W' && word[1] == 'H':
+		word = append([]byte{'W'}, word[2:]...)
+	}
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(word) {
+			return 0
+		}
+		return word[i]
+	}
+	vowel := func(c byte) bool {
+		return c != 0 && strings.IndexByte("AEIOU", c) >= 0
+	}
+	frontVowel := func(c byte) bool {
+		return c != 0 && strings.IndexByte("EIY", c) >= 0
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		if c == at(i-1) && c != 'C' {
+			continue
+		}
+
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				buf.WriteByte(c)
+			}
+		case 'B':
+			if !(at(i-1) == 'M' && i == len(word)-1) {
+				buf.WriteByte('B')
+			}
+		case 'C':
+			switch {
+			case at(i-1) == 'S' && frontVowel(at(i+1)):
+			case at(i+1) == 'I' && at(i+2) == 'A':
+				buf.WriteByte('X')
+			case frontVowel(at(i + 1)):
+				buf.WriteByte('S')
+			case at(i-1) == 'S' && at(i+1) == 'H':
+				buf.WriteByte('K')
+			case at(i+1) == 'H':
+				if i == 0 && !vowel(at(i+2)) {
+					buf.WriteByte('K')
+				} else {
+					buf.WriteByte('X')
+				}
+			default:
+				buf.WriteByte('K')
+			}
+		case 'D':
+			if at(i+1) == 'G' && frontVowel(at(i+2)) {
+				buf.WriteByte('J')
+				i += 2
+			} else {
+				buf.WriteByte('T')
+			}
+		case 'G':
+			switch {
+			case at(i+1) == 'H' && (i+2 >= len(word) || !vowel(at(i+2))):
+			case at(i+1) == 'N' && (i+2 == len(word) ||
+				(i+4 == len(word) && at(i+2) == 'E' && at(i+3) == 'D')):
+			case frontVowel(at(i+1)) && at(i-1) != 'G':
+				buf.WriteByte('J')
+			default:
+				buf.WriteByte('K')
+			}
+		case 'H':
+			if i < len(word)-1 && strings.IndexByte("CSPTG", at(i-1)) < 0 && vowel(at(i+1)) {
+				buf.WriteByte('H')
+			}
+		case 'K':
+			if at(i-1) != 'C' {
+				buf.WriteByte('K')
+			}
+		case 'P':
+			if at(i+1) == 'H' {
+				buf.WriteByte('F')
+			} else {
+				buf.WriteByte('P')
+			}
+		case 'Q':
+			buf.WriteByte('K')
+		case 'S':
+			switch {
+			case at(i+1) == 'H':
+				buf.WriteByte('X')
+			case at(i+1) == 'I' && (at(i+2) == 'O' || at(i+2) == 'A'):
+				buf.WriteByte('X')
+			default:
+				buf.WriteByte('S')
+			}
+		case 'T':
+			switch {
+			case at(i+1) == 'I' && (at(i+2) == 'O' || at(i+2) == 'A'):
+				buf.WriteByte('X')
+			case at(i+1) == 'H':
+				buf.WriteByte('0')
+			case at(i+1) == 'C' && at(i+2) == 'H':
+			default:
+				buf.WriteByte('T')
+			}
+		case 'V':
+			buf.WriteByte('F')
+		case 'W', 'Y':
+			if vowel(at(i + 1)) {
+				buf.WriteByte(c)
+			}
+		case 'X':
+			buf.WriteString("KS")
+		case 'Z':
+			buf.WriteByte('S')
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return buf.String()
+}
+
+///////////////////////////////////////////////////
+//
+// Normalize
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the String function NORMALIZE(expr [, form ]). It
+returns expr in the given Unicode normalization form, one of "NFC"
+(the default), "NFD", "NFKC" or "NFKD", so that canonically
+equivalent strings compare equal.
+*/
+type Normalize struct {
+	FunctionBase
+}
+
+func NewNormalize(operands ...Expression) Function {
+	rv := &Normalize{
+		*NewFunctionBase("normalize", operands...),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *Normalize) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *Normalize) Type() value.Type { return value.STRING }
+
+func (this *Normalize) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.Eval(this, item, context)
+}
+
+func (this *Normalize) Apply(context Context, args ...value.Value) (value.Value, error) {
+	null := false
+
+	for _, a := range args {
+		if a.Type() == value.MISSING {
+			return value.MISSING_VALUE, nil
+		} else if a.Type() != value.STRING {
+			null = true
+		}
+	}
+
+	if null {
+		return value.NULL_VALUE, nil
+	}
+
+	form := norm.NFC
+	if len(args) > 1 {
+		f, ok := _NORMAL_FORMS[strings.ToUpper(args[1].Actual().(string))]
+		if !ok {
+			return value.NULL_VALUE, nil
+		}
+		form = f
+	}
+
+	return value.NewValue(form.String(args[0].Actual().(string))), nil
+}
+
+func (this *Normalize) MinArgs() int { return 1 }
+
+func (this *Normalize) MaxArgs() int { return 2 }
+
+/*
+Factory method pattern.
+*/
+func (this *Normalize) Constructor() FunctionConstructor {
+	return NewNormalize
+}
+
+var _NORMAL_FORMS = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
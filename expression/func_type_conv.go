@@ -221,6 +221,70 @@ func (this *ToBoolean) Constructor() FunctionConstructor {
 	}
 }
 
+///////////////////////////////////////////////////
+//
+// ToDecimal
+//
+///////////////////////////////////////////////////
+
+/*
+This represents the type conversion function TO_DECIMAL(expr).
+It returns exact decimal numbers, for arithmetic and aggregates
+that must not lose precision. Missing and null map to themselves,
+false is 0, true is 1, numbers are the decimals of their shortest
+representation, strings that parse as numbers are those numbers
+and all other values are null (For e.g. "19.99" is exactly 19.99).
+*/
+type ToDecimal struct {
+	UnaryFunctionBase
+}
+
+func NewToDecimal(operand Expression) Function {
+	rv := &ToDecimal{
+		*NewUnaryFunctionBase("to_decimal", operand),
+	}
+
+	rv.expr = rv
+	return rv
+}
+
+/*
+Visitor pattern.
+*/
+func (this *ToDecimal) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitFunction(this)
+}
+
+func (this *ToDecimal) Type() value.Type { return value.NUMBER }
+
+func (this *ToDecimal) Evaluate(item value.Value, context Context) (value.Value, error) {
+	return this.UnaryEval(this, item, context)
+}
+
+func (this *ToDecimal) Apply(context Context, arg value.Value) (value.Value, error) {
+	switch arg.Type() {
+	case value.MISSING, value.NULL:
+		return arg, nil
+	case value.BOOLEAN:
+		if arg.Actual().(bool) {
+			return value.ToDecimal(value.ONE_VALUE), nil
+		} else {
+			return value.ToDecimal(value.ZERO_VALUE), nil
+		}
+	default:
+		return value.ToDecimal(arg), nil
+	}
+}
+
+/*
+Factory method pattern.
+*/
+func (this *ToDecimal) Constructor() FunctionConstructor {
+	return func(operands ...Expression) Function {
+		return NewToDecimal(operands[0])
+	}
+}
+
 ///////////////////////////////////////////////////
 //
 // ToNumber
@@ -402,6 +466,8 @@ func (this *ToString) Apply(context Context, arg value.Value) (value.Value, erro
 			s = strconv.FormatFloat(actual, 'f', -1, 64)
 		case int64:
 			s = strconv.FormatInt(actual, 10)
+		default:
+			s = arg.String()
 		}
 		return value.NewValue(s), nil
 	case value.BINARY:
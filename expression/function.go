@@ -309,6 +309,25 @@ func NewUnaryFunctionBase(name string, operand Expression) *UnaryFunctionBase {
 	}
 }
 
+/*
+The method NewParameterizedUnaryFunctionBase returns a pointer to a
+UnaryFunctionBase struct whose operand is followed by parameters, such
+as the fraction of a percentile aggregate. The parameters are operands
+for copying, mapping and formatting, but only the first operand is
+evaluated.
+*/
+func NewParameterizedUnaryFunctionBase(name string, operand Expression,
+	parameters ...Expression) *UnaryFunctionBase {
+	operands := make(Expressions, 0, 1+len(parameters))
+	operands = append(operands, operand)
+	return &UnaryFunctionBase{
+		FunctionBase{
+			name:     name,
+			operands: append(operands, parameters...),
+		},
+	}
+}
+
 /*
 This method Evaluates the unary function. It evaluates the
 operand using the input item and context, and Evaluates
@@ -580,6 +580,25 @@ func (this *Stringer) VisitSelf(expr *Self) (interface{}, error) {
 // Function
 func (this *Stringer) VisitFunction(expr Function) (interface{}, error) {
 	var buf bytes.Buffer
+
+	// operators that are not function calls
+	switch expr := expr.(type) {
+	case *ILike:
+		buf.WriteString("(")
+		buf.WriteString(this.Visit(expr.First()))
+		buf.WriteString(" ilike ")
+		buf.WriteString(this.Visit(expr.Second()))
+		buf.WriteString(")")
+		return buf.String(), nil
+	case *Collate:
+		buf.WriteString("(")
+		buf.WriteString(this.Visit(expr.First()))
+		buf.WriteString(" collate ")
+		buf.WriteString(this.Visit(expr.Second()))
+		buf.WriteString(")")
+		return buf.String(), nil
+	}
+
 	buf.WriteString(expr.Name())
 	buf.WriteString("(")
 
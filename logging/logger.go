@@ -186,6 +186,14 @@ func skipLogging(level Level) bool {
 	return level > curLevel
 }
 
+/*
+Whether entries of the given level are logged, so that callers can
+avoid building entries that would be discarded.
+*/
+func Enabled(level Level) bool {
+	return !skipLogging(level)
+}
+
 func SetLogger(newLogger Logger) {
 	loggerMutex.Lock()
 	defer loggerMutex.Unlock()
@@ -193,7 +201,7 @@ func SetLogger(newLogger Logger) {
 	if logger == nil {
 		curLevel = NONE
 	} else {
-		curLevel = newLogger.Level()
+		curLevel = effectiveLevel()
 	}
 }
 
@@ -444,7 +452,7 @@ func SetLevel(level Level) {
 	loggerMutex.Lock()
 	defer loggerMutex.Unlock()
 	logger.SetLevel(level)
-	curLevel = level
+	curLevel = effectiveLevel()
 }
 
 func LogLevel() Level {
@@ -453,6 +461,69 @@ func LogLevel() Level {
 	return logger.Level()
 }
 
+/*
+PackageLogger is implemented by loggers that can log the entries of
+individual packages at a level other than the logger level.
+Packages are identified by their path relative to the query
+repository, e.g. planner or datastore/couchbase.
+*/
+type PackageLogger interface {
+	SetPackageLevel(pkg string, level Level) // Set the logging level of a package
+	ClearPackageLevel(pkg string)            // Log a package at the logger level
+	PackageLevels() map[string]Level         // Get the packages with their own level
+}
+
+// the cached level has to let through the entries of the most verbose package
+func effectiveLevel() Level {
+	if logger == nil {
+		return NONE
+	}
+	level := logger.Level()
+	if packageLogger, ok := logger.(PackageLogger); ok {
+		for _, l := range packageLogger.PackageLevels() {
+			if l > level {
+				level = l
+			}
+		}
+	}
+	return level
+}
+
+// returns false if the current logger does not support package levels
+func SetPackageLevel(pkg string, level Level) bool {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+	packageLogger, ok := logger.(PackageLogger)
+	if !ok {
+		return false
+	}
+	packageLogger.SetPackageLevel(pkg, level)
+	curLevel = effectiveLevel()
+	return true
+}
+
+func ClearPackageLevel(pkg string) bool {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+	packageLogger, ok := logger.(PackageLogger)
+	if !ok {
+		return false
+	}
+	packageLogger.ClearPackageLevel(pkg)
+	curLevel = effectiveLevel()
+	return true
+}
+
+func PackageLevels() map[string]Level {
+	loggerMutex.RLock()
+	defer loggerMutex.RUnlock()
+	packageLogger, ok := logger.(PackageLogger)
+	if !ok {
+		return nil
+	}
+	return packageLogger.PackageLevels()
+}
+
 func Stackf(level Level, fmt string, args ...interface{}) {
 	if skipLogging(level) {
 		return
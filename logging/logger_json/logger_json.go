@@ -0,0 +1,388 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+Package logger_json writes log entries as JSON objects, one per line,
+for consumption by log aggregators.
+
+Each entry carries the time, level, message and the package logging it,
+plus all the pairs given by the caller, such as the request_id,
+client_context_id and user of the request being executed.
+Packages can be logged at a level other than the logger level.
+*/
+package logger_json
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/util"
+)
+
+const (
+	_TIME    = "time"
+	_LEVEL   = "level"
+	_RLEVEL  = "rlevel"
+	_MSG     = "msg"
+	_PACKAGE = "package"
+)
+
+const _REPO = "github.com/couchbase/query/"
+
+// frames belonging to the logging machinery, skipped when looking for the caller
+var _LOGGING_PACKAGES = []string{
+	_REPO + "logging.",
+	_REPO + "logging/logger_json.(*jsonLogger).",
+}
+
+const _MAX_FRAMES = 16
+
+type jsonLogger struct {
+	sync.RWMutex
+	out      io.Writer
+	level    logging.Level
+	packages map[string]logging.Level
+}
+
+func NewLogger(out io.Writer, level logging.Level) *jsonLogger {
+	return &jsonLogger{
+		out:      out,
+		level:    level,
+		packages: make(map[string]logging.Level),
+	}
+}
+
+/*
+Logs to a file, rotated as per util.NewRotatingFile.
+*/
+func NewFileLogger(name string, maxSize int64, maxAge time.Duration, maxBackups int,
+	level logging.Level) (*jsonLogger, error) {
+	file, err := util.NewRotatingFile(name, maxSize, maxAge, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(file, level), nil
+}
+
+func (jl *jsonLogger) Logp(level logging.Level, msg string, kv ...logging.Pair) {
+	pkg, ok := jl.enabled(level)
+	if !ok {
+		return
+	}
+	e := newEntry(msg, level, pkg, len(kv))
+	for _, p := range kv {
+		e.set(p.Name, p.Value)
+	}
+	jl.log(e)
+}
+
+func (jl *jsonLogger) Debugp(msg string, kv ...logging.Pair) {
+	jl.Logp(logging.DEBUG, msg, kv...)
+}
+
+func (jl *jsonLogger) Tracep(msg string, kv ...logging.Pair) {
+	jl.Logp(logging.TRACE, msg, kv...)
+}
+
+func (jl *jsonLogger) Requestp(rlevel logging.Level, msg string, kv ...logging.Pair) {
+	pkg, ok := jl.enabled(logging.REQUEST)
+	if !ok {
+		return
+	}
+	e := newEntry(msg, logging.REQUEST, pkg, len(kv)+1)
+	for _, p := range kv {
+		e.set(p.Name, p.Value)
+	}
+	e[_RLEVEL] = rlevel.String()
+	jl.log(e)
+}
+
+func (jl *jsonLogger) Infop(msg string, kv ...logging.Pair) {
+	jl.Logp(logging.INFO, msg, kv...)
+}
+
+func (jl *jsonLogger) Warnp(msg string, kv ...logging.Pair) {
+	jl.Logp(logging.WARN, msg, kv...)
+}
+
+func (jl *jsonLogger) Errorp(msg string, kv ...logging.Pair) {
+	jl.Logp(logging.ERROR, msg, kv...)
+}
+
+func (jl *jsonLogger) Severep(msg string, kv ...logging.Pair) {
+	jl.Logp(logging.SEVERE, msg, kv...)
+}
+
+func (jl *jsonLogger) Fatalp(msg string, kv ...logging.Pair) {
+	jl.Logp(logging.FATAL, msg, kv...)
+}
+
+func (jl *jsonLogger) Logm(level logging.Level, msg string, kv logging.Map) {
+	pkg, ok := jl.enabled(level)
+	if !ok {
+		return
+	}
+	e := newEntry(msg, level, pkg, len(kv))
+	for k, v := range kv {
+		e.set(k, v)
+	}
+	jl.log(e)
+}
+
+func (jl *jsonLogger) Debugm(msg string, kv logging.Map) {
+	jl.Logm(logging.DEBUG, msg, kv)
+}
+
+func (jl *jsonLogger) Tracem(msg string, kv logging.Map) {
+	jl.Logm(logging.TRACE, msg, kv)
+}
+
+func (jl *jsonLogger) Requestm(rlevel logging.Level, msg string, kv logging.Map) {
+	pkg, ok := jl.enabled(logging.REQUEST)
+	if !ok {
+		return
+	}
+	e := newEntry(msg, logging.REQUEST, pkg, len(kv)+1)
+	for k, v := range kv {
+		e.set(k, v)
+	}
+	e[_RLEVEL] = rlevel.String()
+	jl.log(e)
+}
+
+func (jl *jsonLogger) Infom(msg string, kv logging.Map) {
+	jl.Logm(logging.INFO, msg, kv)
+}
+
+func (jl *jsonLogger) Warnm(msg string, kv logging.Map) {
+	jl.Logm(logging.WARN, msg, kv)
+}
+
+func (jl *jsonLogger) Errorm(msg string, kv logging.Map) {
+	jl.Logm(logging.ERROR, msg, kv)
+}
+
+func (jl *jsonLogger) Severem(msg string, kv logging.Map) {
+	jl.Logm(logging.SEVERE, msg, kv)
+}
+
+func (jl *jsonLogger) Fatalm(msg string, kv logging.Map) {
+	jl.Logm(logging.FATAL, msg, kv)
+}
+
+func (jl *jsonLogger) Logf(level logging.Level, format string, args ...interface{}) {
+	pkg, ok := jl.enabled(level)
+	if !ok {
+		return
+	}
+	jl.log(newEntry(fmt.Sprintf(format, args...), level, pkg, 0))
+}
+
+func (jl *jsonLogger) Debugf(format string, args ...interface{}) {
+	jl.Logf(logging.DEBUG, format, args...)
+}
+
+func (jl *jsonLogger) Tracef(format string, args ...interface{}) {
+	jl.Logf(logging.TRACE, format, args...)
+}
+
+func (jl *jsonLogger) Requestf(rlevel logging.Level, format string, args ...interface{}) {
+	pkg, ok := jl.enabled(logging.REQUEST)
+	if !ok {
+		return
+	}
+	e := newEntry(fmt.Sprintf(format, args...), logging.REQUEST, pkg, 1)
+	e[_RLEVEL] = rlevel.String()
+	jl.log(e)
+}
+
+func (jl *jsonLogger) Infof(format string, args ...interface{}) {
+	jl.Logf(logging.INFO, format, args...)
+}
+
+func (jl *jsonLogger) Warnf(format string, args ...interface{}) {
+	jl.Logf(logging.WARN, format, args...)
+}
+
+func (jl *jsonLogger) Errorf(format string, args ...interface{}) {
+	jl.Logf(logging.ERROR, format, args...)
+}
+
+func (jl *jsonLogger) Severef(format string, args ...interface{}) {
+	jl.Logf(logging.SEVERE, format, args...)
+}
+
+func (jl *jsonLogger) Fatalf(format string, args ...interface{}) {
+	jl.Logf(logging.FATAL, format, args...)
+}
+
+func (jl *jsonLogger) Level() logging.Level {
+	jl.RLock()
+	defer jl.RUnlock()
+	return jl.level
+}
+
+func (jl *jsonLogger) SetLevel(level logging.Level) {
+	jl.Lock()
+	jl.level = level
+	jl.Unlock()
+}
+
+func (jl *jsonLogger) SetPackageLevel(pkg string, level logging.Level) {
+	jl.Lock()
+	jl.packages[pkg] = level
+	jl.Unlock()
+}
+
+func (jl *jsonLogger) ClearPackageLevel(pkg string) {
+	jl.Lock()
+	delete(jl.packages, pkg)
+	jl.Unlock()
+}
+
+func (jl *jsonLogger) PackageLevels() map[string]logging.Level {
+	jl.RLock()
+	defer jl.RUnlock()
+	rv := make(map[string]logging.Level, len(jl.packages))
+	for pkg, level := range jl.packages {
+		rv[pkg] = level
+	}
+	return rv
+}
+
+/*
+Determines the package logging the entry, and whether the entry
+should be logged at the level of that package.
+A package without a level of its own takes the level of the closest
+parent package that has one, or else the logger level.
+*/
+func (jl *jsonLogger) enabled(level logging.Level) (string, bool) {
+	if jl.out == nil {
+		return "", false
+	}
+
+	jl.RLock()
+	defer jl.RUnlock()
+
+	// walking the stack for the package is costly: skip it for entries
+	// that no package logs
+	if level > jl.level {
+		enabled := false
+		for _, l := range jl.packages {
+			if level <= l {
+				enabled = true
+				break
+			}
+		}
+		if !enabled {
+			return "", false
+		}
+	}
+	pkg := callerPackage()
+
+	for p := pkg; p != ""; {
+		l, ok := jl.packages[p]
+		if ok {
+			return pkg, level <= l
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return pkg, level <= jl.level
+}
+
+func (jl *jsonLogger) log(e entry) {
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		bytes, _ = json.Marshal(entry{
+			_TIME:  e[_TIME],
+			_LEVEL: e[_LEVEL],
+			_MSG:   fmt.Sprintf("Unable to marshal log entry: %v", err),
+		})
+	}
+	bytes = append(bytes, '\n')
+
+	jl.Lock()
+	jl.out.Write(bytes)
+	jl.Unlock()
+}
+
+// the package of the first frame outside of the logging machinery,
+// relative to the query repository
+func callerPackage() string {
+	var pcs [_MAX_FRAMES]uintptr
+
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !isLoggingFunction(frame.Function) {
+			return functionPackage(frame.Function)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isLoggingFunction(function string) bool {
+	for _, prefix := range _LOGGING_PACKAGES {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// eg github.com/couchbase/query/planner.(*builder).VisitSelect is in planner
+func functionPackage(function string) string {
+	function = strings.TrimPrefix(function, _REPO)
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
+
+type entry map[string]interface{}
+
+func newEntry(msg string, level logging.Level, pkg string, size int) entry {
+	e := make(entry, size+4)
+	e[_TIME] = time.Now().Format(time.RFC3339Nano)
+	e[_LEVEL] = level.String()
+	e[_MSG] = msg
+	if pkg != "" {
+		e[_PACKAGE] = pkg
+	}
+	return e
+}
+
+// caller's keys cannot override the standard keys
+func (e entry) set(key string, val interface{}) {
+	switch key {
+	case _TIME, _LEVEL, _RLEVEL, _MSG, _PACKAGE:
+		return
+	}
+	switch val := val.(type) {
+	case error:
+		e[key] = val.Error()
+	default:
+		e[key] = val
+	}
+}
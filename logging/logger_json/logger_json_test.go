@@ -0,0 +1,124 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package logger_json
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/logging"
+)
+
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var rv []map[string]interface{}
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]interface{}
+		err := json.Unmarshal([]byte(line), &e)
+		if err != nil {
+			t.Fatalf("Invalid entry %v: %v", line, err)
+		}
+		rv = append(rv, e)
+	}
+	buf.Reset()
+	return rv
+}
+
+func TestEntries(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLogger(buf, logging.INFO)
+	logging.SetLogger(logger)
+
+	logging.Infop("request failed", logging.Pair{Name: "request_id", Value: "1234"},
+		logging.Pair{Name: "client_context_id", Value: "abc"}, logging.Pair{Name: "msg", Value: "ignored"})
+	logging.Debugf("not logged")
+	logger.Requestf(logging.WARN, "request from %s", "test")
+
+	e := entries(t, buf)
+	if len(e) != 1 {
+		t.Fatalf("Expected 1 entry, got %v", e)
+	}
+	if e[0]["msg"] != "request failed" || e[0]["level"] != "INFO" ||
+		e[0]["request_id"] != "1234" || e[0]["client_context_id"] != "abc" {
+		t.Errorf("Unexpected entry %v", e[0])
+	}
+	if e[0]["package"] != "logging/logger_json" || e[0]["time"] == nil {
+		t.Errorf("Unexpected package or time in %v", e[0])
+	}
+
+	logging.SetLevel(logging.REQUEST)
+	logging.Requestm(logging.WARN, "request", logging.Map{"user": "admin"})
+	e = entries(t, buf)
+	if len(e) != 1 || e[0]["rlevel"] != "WARN" || e[0]["user"] != "admin" {
+		t.Errorf("Unexpected request entries %v", e)
+	}
+}
+
+func TestPackageLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewLogger(buf, logging.ERROR)
+	logging.SetLogger(logger)
+
+	if logging.Enabled(logging.WARN) || !logging.Enabled(logging.ERROR) {
+		t.Errorf("Expected only the levels of the logger to be enabled")
+	}
+	if !logging.SetPackageLevel("logging", logging.DEBUG) {
+		t.Fatalf("Expected package levels to be supported")
+	}
+	if logging.LogLevel() != logging.ERROR {
+		t.Errorf("Expected logger level to be unchanged, got %v", logging.LogLevel())
+	}
+
+	if !logging.Enabled(logging.DEBUG) {
+		t.Errorf("Expected the levels of any package to be enabled")
+	}
+
+	// the level of the parent package applies
+	logging.Debugf("debug")
+	if e := entries(t, buf); len(e) != 1 || e[0]["level"] != "DEBUG" {
+		t.Errorf("Unexpected entries %v", e)
+	}
+
+	logging.SetPackageLevel("logging/logger_json", logging.WARN)
+	logging.Infof("info")
+	logging.Warnf("warn")
+	if e := entries(t, buf); len(e) != 1 || e[0]["msg"] != "warn" {
+		t.Errorf("Unexpected entries %v", e)
+	}
+
+	logging.ClearPackageLevel("logging/logger_json")
+	logging.ClearPackageLevel("logging")
+	logging.Warnf("warn")
+	if e := entries(t, buf); len(e) != 0 {
+		t.Errorf("Unexpected entries %v", e)
+	}
+	if len(logging.PackageLevels()) != 0 {
+		t.Errorf("Unexpected package levels %v", logging.PackageLevels())
+	}
+}
+
+func TestFunctionPackage(t *testing.T) {
+	functions := map[string]string{
+		"github.com/couchbase/query/planner.(*builder).VisitSelect":        "planner",
+		"github.com/couchbase/query/datastore/couchbase.(*keyspace).Fetch": "datastore/couchbase",
+		"github.com/couchbase/query/execution.(*Fetch).RunOnce.func1":      "execution",
+		"main.main": "main",
+	}
+	for function, pkg := range functions {
+		if p := functionPackage(function); p != pkg {
+			t.Errorf("Expected package %v for %v, got %v", pkg, function, p)
+		}
+	}
+}
@@ -10,12 +10,25 @@
 package resolver
 
 import (
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/logging"
 	"github.com/couchbase/query/logging/logger_golog"
+	"github.com/couchbase/query/logging/logger_json"
+)
+
+const _JSON = "json"
+
+// defaults for JSON log files
+const (
+	_MAX_SIZE    = 100 * 1024 * 1024
+	_MAX_AGE     = 0
+	_MAX_BACKUPS = 10
 )
 
 func NewLogger(uri string) (logging.Logger, errors.Error) {
@@ -25,9 +38,75 @@ func NewLogger(uri string) (logging.Logger, errors.Error) {
 		logging.SetLogger(logger)
 		return logger, nil
 	}
+	if strings.HasPrefix(uri, _JSON) {
+		logger, err := newJsonLogger(uri)
+		if err != nil {
+			return nil, err
+		}
+		logging.SetLogger(logger)
+		return logger, nil
+	}
 	return nil, errors.NewAdminInvalidURL("Logger", uri)
 }
 
+/*
+JSON loggers log to stderr, or to a rotated file if one is specified, as in
+json:/var/log/query.json?maxsize=104857600&maxage=24h&backups=5
+*/
+func newJsonLogger(uri string) (logging.Logger, errors.Error) {
+	if uri == _JSON {
+		return logger_json.NewLogger(os.Stderr, logging.INFO), nil
+	}
+	if !strings.HasPrefix(uri, _JSON+":") {
+		return nil, errors.NewAdminInvalidURL("Logger", uri)
+	}
+
+	name := uri[len(_JSON)+1:]
+	options := url.Values{}
+	if i := strings.Index(name, "?"); i >= 0 {
+		var err error
+		options, err = url.ParseQuery(name[i+1:])
+		if err != nil {
+			return nil, errors.NewAdminInvalidURL("Logger", uri)
+		}
+		name = name[:i]
+	}
+	if name == "" {
+		return nil, errors.NewAdminInvalidURL("Logger", uri)
+	}
+
+	maxSize := int64(_MAX_SIZE)
+	maxAge := time.Duration(_MAX_AGE)
+	maxBackups := _MAX_BACKUPS
+	if v := options.Get("maxsize"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < 0 {
+			return nil, errors.NewAdminInvalidURL("Logger", uri)
+		}
+		maxSize = size
+	}
+	if v := options.Get("maxage"); v != "" {
+		age, err := time.ParseDuration(v)
+		if err != nil || age < 0 {
+			return nil, errors.NewAdminInvalidURL("Logger", uri)
+		}
+		maxAge = age
+	}
+	if v := options.Get("backups"); v != "" {
+		backups, err := strconv.Atoi(v)
+		if err != nil || backups < 0 {
+			return nil, errors.NewAdminInvalidURL("Logger", uri)
+		}
+		maxBackups = backups
+	}
+
+	logger, err := logger_json.NewFileLogger(name, maxSize, maxAge, maxBackups, logging.INFO)
+	if err != nil {
+		return nil, errors.NewAdminInvalidURL("Logger", uri)
+	}
+	return logger, nil
+}
+
 func init() {
 	logger := logger_golog.NewLogger(os.Stderr, logging.INFO, false)
 	logging.SetLogger(logger)
@@ -48,6 +48,7 @@ func ParseExpression(input string) (expression.Expression, error) {
 	input = strings.TrimSpace(input)
 	reader := strings.NewReader(input)
 	lex := newLexer(NewLexer(reader))
+	lex.text = input
 	lex.nex.ResetOffset()
 	lex.nex.ReportError(lex.ScannerError)
 	doParse(lex)
@@ -89,6 +90,11 @@ type lexer struct {
 	lastScannerError string
 	text             string
 	offset           int
+	lastToken        int
+	prevToken        int
+	peeked           bool
+	peekTok          int
+	peekVal          yySymType
 }
 
 func newLexer(nex *Lexer) *lexer {
@@ -100,7 +106,58 @@ func newLexer(nex *Lexer) *lexer {
 }
 
 func (this *lexer) Lex(lval *yySymType) int {
-	return this.nex.Lex(lval)
+	tok := this.next(lval)
+
+	// WITHIN followed by GROUP, as in PERCENTILE_CONT(0.5) WITHIN GROUP
+	// (ORDER BY expr), which the parser cannot tell from the WITHIN
+	// operator by the next token alone
+	if tok == WITHIN {
+		if this.peek() == GROUP {
+			tok = WITHIN_GROUP
+		}
+	}
+
+	// CROSS and FULL are keywords only in CROSS JOIN and FULL [OUTER]
+	// JOIN, and identifiers elsewhere, so that they remain usable as
+	// aliases and variables
+	switch tok {
+	case CROSS:
+		if this.peek() != JOIN {
+			tok = IDENT
+		}
+	case FULL:
+		if peekTok := this.peek(); peekTok != JOIN && peekTok != OUTER {
+			tok = IDENT
+		}
+	}
+
+	this.prevToken = this.lastToken
+	this.lastToken = tok
+	return tok
+}
+
+func (this *lexer) peek() int {
+	this.peekTok = this.next(&this.peekVal)
+	this.peeked = true
+	return this.peekTok
+}
+
+func (this *lexer) next(lval *yySymType) int {
+	if this.peeked {
+		this.peeked = false
+		*lval = this.peekVal
+		return this.peekTok
+	}
+
+	tok := this.nex.Lex(lval)
+
+	// optimizer hints follow SELECT, UPDATE or DELETE, other than the
+	// UPDATE and DELETE actions of MERGE, and are plain comments elsewhere
+	for tok == OPTIM_HINTS && this.lastToken != SELECT &&
+		((this.lastToken != UPDATE && this.lastToken != DELETE) || this.prevToken == THEN) {
+		tok = this.nex.Lex(lval)
+	}
+	return tok
 }
 
 func (this *lexer) Remainder(offset int) string {
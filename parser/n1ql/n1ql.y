@@ -51,6 +51,7 @@ group            *algebra.Group
 resultTerm       *algebra.ResultTerm
 resultTerms      algebra.ResultTerms
 projection       *algebra.Projection
+optimHints       *algebra.OptimHints
 order            *algebra.Order
 sortTerm         *algebra.SortTerm
 sortTerms        algebra.SortTerms
@@ -113,6 +114,7 @@ tokOffset	 int
 %token CORRELATED
 %token COVER
 %token CREATE
+%token CROSS
 %token DATABASE
 %token DATASET
 %token DATASTORE
@@ -143,6 +145,7 @@ tokOffset	 int
 %token FORCE
 %token FROM
 %token FTS
+%token FULL
 %token FUNCTION
 %token GRANT
 %token GROUP
@@ -213,6 +216,7 @@ tokOffset	 int
 %token RAW
 %token REALM
 %token REDUCE
+%token REFRESH
 %token RENAME
 %token RETURN
 %token RETURNING
@@ -263,7 +267,13 @@ tokOffset	 int
 %token WORK
 %token XOR
 
-%token INT NUM STR IDENT IDENT_ICASE NAMED_PARAM POSITIONAL_PARAM NEXT_PARAM
+/* WITHIN followed by GROUP, distinguished by the lexer */
+%token WITHIN_GROUP
+
+/* Optimizer hints comment following SELECT */
+%token OPTIM_HINTS
+
+%token INT NUM LONG_INT STR IDENT IDENT_ICASE NAMED_PARAM POSITIONAL_PARAM NEXT_PARAM
 %token LPAREN RPAREN
 %token LBRACE RBRACE LBRACKET RBRACKET RBRACKET_ICASE
 %token COMMA COLON
@@ -271,13 +281,13 @@ tokOffset	 int
 /* Precedence: lowest to highest */
 %left           ORDER
 %left           UNION INTERESECT EXCEPT
-%left           JOIN NEST UNNEST FLATTEN INNER LEFT RIGHT
+%left           JOIN NEST UNNEST FLATTEN INNER LEFT RIGHT FULL CROSS
 %left           OR
 %left           AND
 %right          NOT
 %nonassoc       EQ DEQ NE
 %nonassoc       LT GT LE GE
-%nonassoc       LIKE
+%nonassoc       LIKE ILIKE
 %nonassoc       BETWEEN
 %nonassoc       IN WITHIN
 %nonassoc       EXISTS
@@ -285,6 +295,7 @@ tokOffset	 int
 %left           CONCAT
 %left           PLUS MINUS
 %left           STAR DIV MOD
+%left           COLLATE
 
 /* Unary operators */
 %right          COVER
@@ -296,8 +307,10 @@ tokOffset	 int
 %left           LPAREN RPAREN
 
 /* Types */
-%type <s>                STR
-%type <s>                IDENT IDENT_ICASE
+%type <s>                STR OPTIM_HINTS LONG_INT
+%type <s>                IDENT IDENT_ICASE REFRESH
+%type <s>                ident
+%type <s>                collation
 %type <s>                NAMED_PARAM
 %type <f>                NUM
 %type <n>                INT
@@ -352,7 +365,8 @@ tokOffset	 int
 %type <expr>             opt_having having
 %type <resultTerm>       project
 %type <resultTerms>      projects
-%type <projection>       projection select_clause
+%type <projection>       projection
+%type <optimHints>       opt_optim_hints
 %type <order>            order_by opt_order_by
 %type <sortTerm>         sort_term
 %type <sortTerms>        sort_terms
@@ -367,6 +381,8 @@ tokOffset	 int
 %type <statement>        infer infer_keyspace
 %type <statement>        insert upsert delete update merge
 %type <statement>        index_stmt create_index drop_index alter_index build_index
+%type <statement>        view_stmt create_materialized_view drop_materialized_view refresh_materialized_view
+%type <statement>        create_view drop_view
 %type <statement>        role_stmt grant_role revoke_role
 
 %type <keyspaceRef>      keyspace_ref
@@ -603,6 +619,8 @@ merge
 
 ddl_stmt:
 index_stmt
+|
+view_stmt
 ;
 
 role_stmt:
@@ -621,6 +639,18 @@ alter_index
 build_index
 ;
 
+view_stmt:
+create_materialized_view
+|
+drop_materialized_view
+|
+refresh_materialized_view
+|
+create_view
+|
+drop_view
+;
+
 fullselect:
 select_terms opt_order_by
 {
@@ -730,26 +760,30 @@ select_from
 ;
 
 from_select:
-from opt_let opt_where opt_group select_clause
+from opt_let opt_where opt_group SELECT opt_optim_hints projection
 {
-    $$ = algebra.NewSubselect(nil, $1, $2, $3, $4, $5)
+    $$ = algebra.NewSubselect(nil, $1, $2, $3, $4, $7)
+    $$.SetOptimHints($6)
 }
 |
-opt_with from opt_let opt_where opt_group select_clause
+opt_with from opt_let opt_where opt_group SELECT opt_optim_hints projection
 {
-    $$ = algebra.NewSubselect($1, $2, $3, $4, $5, $6)
+    $$ = algebra.NewSubselect($1, $2, $3, $4, $5, $8)
+    $$.SetOptimHints($7)
 }
 ;
 
 select_from:
-select_clause opt_from opt_let opt_where opt_group
+SELECT opt_optim_hints projection opt_from opt_let opt_where opt_group
 {
-    $$ = algebra.NewSubselect(nil, $2, $3, $4, $5, $1)
+    $$ = algebra.NewSubselect(nil, $4, $5, $6, $7, $3)
+    $$.SetOptimHints($2)
 }
 |
-opt_with select_clause opt_from opt_let opt_where opt_group
+opt_with SELECT opt_optim_hints projection opt_from opt_let opt_where opt_group
 {
-    $$ = algebra.NewSubselect($1, $3, $4, $5, $6, $2)
+    $$ = algebra.NewSubselect($1, $5, $6, $7, $8, $4)
+    $$.SetOptimHints($3)
 }
 ;
 
@@ -760,11 +794,15 @@ opt_with select_clause opt_from opt_let opt_where opt_group
  *
  *************************************************/
 
-select_clause:
-SELECT
-projection
+opt_optim_hints:
+/* empty */
 {
-    $$ = $2
+    $$ = nil
+}
+|
+OPTIM_HINTS
+{
+    $$ = algebra.NewOptimHints($1)
 }
 ;
 
@@ -851,7 +889,7 @@ AS alias
 ;
 
 alias:
-IDENT
+ident
 ;
 
 
@@ -873,6 +911,9 @@ from
 from:
 FROM from_term
 {
+    if term, ok := $2.PrimaryTerm().(algebra.SimpleFromTerm); ok && term.JoinHint() != algebra.JOIN_HINT_NONE {
+        yylex.Error(fmt.Sprintf("Join hint (USE HASH or USE NL) cannot be specified on the first from term %s", term.Alias()))
+    }
     $$ = $2
 }
 ;
@@ -880,9 +921,6 @@ FROM from_term
 from_term:
 simple_from_term
 {
-    if $1.JoinHint() != algebra.JOIN_HINT_NONE {
-        yylex.Error(fmt.Sprintf("Join hint (USE HASH or USE NL) cannot be specified on the first from term %s", $1.Alias()))
-    }
     $$ = $1
 }
 |
@@ -945,10 +983,27 @@ from_term opt_join_type NEST simple_from_term ON expr
     $$ = algebra.NewAnsiNest($1, $2, $4, $6)
 }
 |
-simple_from_term RIGHT opt_outer JOIN simple_from_term ON expr
+from_term RIGHT opt_outer JOIN simple_from_term ON expr
+{
+    if left, ok := $1.(algebra.SimpleFromTerm); ok {
+        left.SetAnsiJoin()
+        $$ = algebra.NewAnsiRightJoin(left, $5, $7)
+    } else {
+        $5.SetAnsiJoin()
+        $$ = algebra.NewAnsiRightOuterJoin($1, $5, $7)
+    }
+}
+|
+from_term FULL opt_outer JOIN simple_from_term ON expr
 {
-    $1.SetAnsiJoin()
-    $$ = algebra.NewAnsiRightJoin($1, $5, $7)
+    $5.SetAnsiJoin()
+    $$ = algebra.NewAnsiFullJoin($1, $5, $7)
+}
+|
+from_term CROSS JOIN simple_from_term
+{
+    $4.SetAnsiJoin()
+    $$ = algebra.NewAnsiCrossJoin($1, $4)
 }
 ;
 
@@ -1636,9 +1691,11 @@ UPSERT INTO keyspace_ref LPAREN key_expr opt_value_expr RPAREN fullselect opt_re
  *************************************************/
 
 delete:
-DELETE FROM keyspace_ref opt_use_del_upd opt_where opt_limit opt_returning
+DELETE opt_optim_hints FROM keyspace_ref opt_use_del_upd opt_where opt_limit opt_returning
 {
-    $$ = algebra.NewDelete($3, $4.Keys(), $4.Indexes(), $5, $6, $7)
+    delete := algebra.NewDelete($4, $5.Keys(), $5.Indexes(), $6, $7, $8)
+    delete.SetOptimHints($2)
+    $$ = delete
 }
 ;
 
@@ -1650,19 +1707,25 @@ DELETE FROM keyspace_ref opt_use_del_upd opt_where opt_limit opt_returning
  *************************************************/
 
 update:
-UPDATE keyspace_ref opt_use_del_upd set unset opt_where opt_limit opt_returning
+UPDATE opt_optim_hints keyspace_ref opt_use_del_upd set unset opt_where opt_limit opt_returning
 {
-    $$ = algebra.NewUpdate($2, $3.Keys(), $3.Indexes(), $4, $5, $6, $7, $8)
+    update := algebra.NewUpdate($3, $4.Keys(), $4.Indexes(), $5, $6, $7, $8, $9)
+    update.SetOptimHints($2)
+    $$ = update
 }
 |
-UPDATE keyspace_ref opt_use_del_upd set opt_where opt_limit opt_returning
+UPDATE opt_optim_hints keyspace_ref opt_use_del_upd set opt_where opt_limit opt_returning
 {
-    $$ = algebra.NewUpdate($2, $3.Keys(), $3.Indexes(), $4, nil, $5, $6, $7)
+    update := algebra.NewUpdate($3, $4.Keys(), $4.Indexes(), $5, nil, $6, $7, $8)
+    update.SetOptimHints($2)
+    $$ = update
 }
 |
-UPDATE keyspace_ref opt_use_del_upd unset opt_where opt_limit opt_returning
+UPDATE opt_optim_hints keyspace_ref opt_use_del_upd unset opt_where opt_limit opt_returning
 {
-    $$ = algebra.NewUpdate($2, $3.Keys(), $3.Indexes(), nil, $4, $5, $6, $7)
+    update := algebra.NewUpdate($3, $4.Keys(), $4.Indexes(), nil, $5, $6, $7, $8)
+    update.SetOptimHints($2)
+    $$ = update
 }
 ;
 
@@ -1757,7 +1820,7 @@ variable COLON variable WITHIN expr
 ;
 
 variable:
-IDENT
+ident
 ;
 
 opt_when:
@@ -2261,6 +2324,71 @@ BUILD INDEX ON named_keyspace_ref LPAREN index_names RPAREN opt_index_using
 }
 ;
 
+/*************************************************
+ *
+ * CREATE MATERIALIZED VIEW
+ *
+ *************************************************/
+
+create_materialized_view:
+CREATE MATERIALIZED VIEW named_keyspace_ref AS fullselect
+{
+    $$ = algebra.NewCreateMaterializedView($4, $6, yylex.(*lexer).Remainder($<tokOffset>5))
+}
+;
+
+/*************************************************
+ *
+ * DROP MATERIALIZED VIEW
+ *
+ *************************************************/
+
+drop_materialized_view:
+DROP MATERIALIZED VIEW named_keyspace_ref
+{
+    $$ = algebra.NewDropMaterializedView($4)
+}
+;
+
+/*************************************************
+ *
+ * REFRESH MATERIALIZED VIEW
+ *
+ *************************************************/
+
+refresh_materialized_view:
+REFRESH MATERIALIZED VIEW named_keyspace_ref
+{
+    $$ = algebra.NewRefreshMaterializedView($4)
+}
+;
+
+/*************************************************
+ *
+ * CREATE VIEW
+ *
+ *************************************************/
+
+create_view:
+CREATE VIEW named_keyspace_ref AS fullselect
+{
+    $$ = algebra.NewCreateView($3, $5, yylex.(*lexer).Remainder($<tokOffset>4))
+}
+;
+
+/*************************************************
+ *
+ * DROP VIEW
+ *
+ *************************************************/
+
+drop_view:
+DROP VIEW named_keyspace_ref
+{
+    $$ = algebra.NewDropView($3)
+}
+;
+
 index_names:
 index_name
 {
@@ -2280,13 +2408,23 @@ index_names COMMA index_name
  *
  *************************************************/
 
-path:
+/*
+Keywords that are not reserved, and remain usable as identifiers
+and field names.
+*/
+ident:
 IDENT
+|
+REFRESH
+;
+
+path:
+ident
 {
     $$ = expression.NewIdentifier($1)
 }
 |
-path DOT IDENT
+path DOT ident
 {
     $$ = expression.NewField($1, expression.NewFieldName($3, false))
 }
@@ -2327,7 +2465,7 @@ expr:
 c_expr
 |
 /* Nested */
-expr DOT IDENT
+expr DOT ident
 {
     $$ = expression.NewField($1, expression.NewFieldName($3, false))
 }
@@ -2403,6 +2541,15 @@ expr CONCAT expr
     $$ = expression.NewConcat($1, $3)
 }
 |
+/* Collation */
+expr COLLATE collation
+{
+    if value.NewCollation($3) == nil {
+        yylex.Error(fmt.Sprintf("Invalid collation %s", $3))
+    }
+    $$ = expression.NewCollate($1, expression.NewConstant($3))
+}
+|
 /* Logical */
 expr AND expr
 {
@@ -2475,6 +2622,16 @@ expr NOT LIKE expr
     $$ = expression.NewNotLike($1, $4)
 }
 |
+expr ILIKE expr
+{
+    $$ = expression.NewILike($1, $3)
+}
+|
+expr NOT ILIKE expr
+{
+    $$ = expression.NewNotILike($1, $4)
+}
+|
 expr IN expr
 {
     $$ = expression.NewIn($1, $3)
@@ -2545,7 +2702,7 @@ literal
 construction_expr
 |
 /* Identifier */
-IDENT
+ident
 {
     $$ = expression.NewIdentifier($1)
 }
@@ -2598,6 +2755,17 @@ LPAREN expr RPAREN
 }
 ;
 
+collation:
+IDENT
+|
+STR
+|
+BINARY
+{
+    $$ = "binary"
+}
+;
+
 b_expr:
 c_expr
 |
@@ -2717,6 +2885,12 @@ INT
     $$ = expression.NewConstant(value.NewValue($1))
 }
 |
+LONG_INT
+{
+    d, _ := value.NewDecimalValue($1)
+    $$ = expression.NewConstant(d)
+}
+|
 STR
 {
     $$ = expression.NewConstant(value.NewValue($1))
@@ -2926,6 +3100,25 @@ function_name LPAREN opt_exprs RPAREN
     }
 }
 |
+function_name LPAREN opt_exprs RPAREN WITHIN_GROUP GROUP LPAREN ORDER BY expr opt_dir RPAREN
+{
+    $$ = nil;
+    agg, ok := algebra.GetOrderedSetAggregate($1);
+    if !ok {
+        yylex.Error(fmt.Sprintf("Invalid ordered-set aggregate function %s.", $1));
+    } else if len($3) != agg.MinArgs()-1 {
+        yylex.Error(fmt.Sprintf("Wrong number of arguments to function %s.", $1));
+    } else {
+        operands := make(expression.Expressions, 0, agg.MaxArgs())
+        operands = append(operands, $10)
+        operands = append(operands, $3...)
+        if $11 {
+            operands = append(operands, expression.TRUE_EXPR)
+        }
+        $$ = agg.Constructor()(operands...);
+    }
+}
+|
 function_name LPAREN DISTINCT expr RPAREN
 {
     agg, ok := algebra.GetAggregate($1, true);
@@ -0,0 +1,139 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package n1ql
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/value"
+)
+
+func TestWithinGroup(t *testing.T) {
+	for _, text := range []string{
+		"SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY x) FROM t",
+		"SELECT PERCENTILE_CONT(0.5) WITHIN /* c */ GROUP (ORDER BY x) FROM t",
+		"SELECT PERCENTILE_DISC(0.5) WITHIN\n/* c */\nGROUP (ORDER BY x DESC) FROM t",
+		"SELECT MODE() within group (ORDER BY x) FROM t",
+	} {
+		stmt, err := ParseStatement(text)
+		if err != nil {
+			t.Errorf("Unable to parse %s: %v", text, err)
+			continue
+		}
+		result := stmt.(*algebra.Select).Subresult().(*algebra.Subselect).Projection().Terms()[0]
+		if _, ok := result.Expression().(algebra.Aggregate); !ok {
+			t.Errorf("Expected an aggregate for %s, got %v", text, result.Expression())
+		}
+	}
+
+	for _, text := range []string{
+		"SELECT 1 FROM t WHERE x WITHIN y",
+		"SELECT 1 FROM t WHERE LOWER(x) WITHIN /* c */ y",
+		"SELECT 1 FROM t WHERE x NOT WITHIN y",
+	} {
+		_, err := ParseStatement(text)
+		if err != nil {
+			t.Errorf("Unable to parse %s: %v", text, err)
+		}
+	}
+
+	_, err := ParseStatement("SELECT LOWER(x) WITHIN GROUP (ORDER BY x) FROM t")
+	if err == nil {
+		t.Errorf("Expected WITHIN GROUP to be rejected for a scalar function")
+	}
+}
+
+func TestNonReservedKeywords(t *testing.T) {
+	for _, text := range []string{
+		"SELECT full FROM t",
+		"SELECT t.cross FROM t",
+		"SELECT Full.x, t.FULL FROM t",
+		"SELECT t.a FROM t FULL OUTER JOIN u ON t.a = u.a WHERE t.full = 1",
+		"SELECT t.a FROM t CROSS JOIN u WHERE u.cross IS NOT MISSING",
+		"SELECT a.x AS full FROM b a",
+		"SELECT a.x full FROM b a",
+		"SELECT 1 FROM b AS cross",
+		"SELECT 1 FROM b cross",
+		"SELECT 1 FROM b full FULL JOIN c cross ON full.x = cross.x",
+		"SELECT 1 FROM b LET cross = 1, full = 2 WHERE cross < full",
+		"SELECT ARRAY full FOR full IN b.a END FROM b",
+	} {
+		_, err := ParseStatement(text)
+		if err != nil {
+			t.Errorf("Unable to parse %s: %v", text, err)
+		}
+	}
+
+	expr, err := ParseExpression("full.cross")
+	if err != nil {
+		t.Fatalf("Unable to parse expression: %v", err)
+	}
+	if expr.String() != "(`full`.`cross`)" {
+		t.Errorf("Unexpected expression %s", expr.String())
+	}
+}
+
+func TestRefreshIdentifier(t *testing.T) {
+	for _, text := range []string{
+		"SELECT refresh FROM t",
+		"SELECT t.refresh, Refresh.x FROM t WHERE refresh > 0",
+		"REFRESH MATERIALIZED VIEW v",
+		"SELECT 1 FROM b LET refresh = 1",
+		"SELECT 1 FROM b AS refresh",
+		"SELECT b.x FROM b WHERE ANY refresh IN b.a SATISFIES refresh > 0 END",
+	} {
+		_, err := ParseStatement(text)
+		if err != nil {
+			t.Errorf("Unable to parse %s: %v", text, err)
+		}
+	}
+
+	expr, err := ParseExpression("refresh + 1")
+	if err != nil {
+		t.Fatalf("Unable to parse expression: %v", err)
+	}
+	if expr.String() != "(`refresh` + 1)" {
+		t.Errorf("Unexpected expression %s", expr.String())
+	}
+}
+
+func TestLongIntegerLiteral(t *testing.T) {
+	for text, out := range map[string]string{
+		"12345678901234567890123": "12345678901234567890123",
+		"9223372036854775808":     "9223372036854775808",
+		"9223372036854775807":     "9223372036854775807",
+	} {
+		expr, err := ParseExpression(text)
+		if err != nil {
+			t.Fatalf("Unable to parse %s: %v", text, err)
+		}
+		if expr.String() != out {
+			t.Errorf("Expected %s for %s, got %s", out, text, expr.String())
+		}
+	}
+
+	expr, err := ParseExpression("12345678901234567890123")
+	if err != nil {
+		t.Fatalf("Unable to parse expression: %v", err)
+	}
+	if !value.IsDecimal(expr.Value()) {
+		t.Errorf("Expected a decimal constant, got %v of type %T", expr.Value(), expr.Value())
+	}
+
+	expr, err = ParseExpression("-12345678901234567890123 + 1")
+	if err != nil {
+		t.Fatalf("Unable to parse expression: %v", err)
+	}
+	val, err := expr.Evaluate(nil, nil)
+	if err != nil || !value.IsDecimal(val) || val.String() != "-12345678901234567890122" {
+		t.Errorf("Expected decimal -12345678901234567890122, got %v %v", val, err)
+	}
+}
@@ -11,18 +11,22 @@ package plan
 
 import (
 	"encoding/json"
+
+	"github.com/couchbase/query/algebra"
 )
 
 type Explain struct {
 	readonly
-	op   Operator
-	text string
+	op         Operator
+	text       string
+	optimHints []*algebra.OptimHint
 }
 
-func NewExplain(op Operator, text string) *Explain {
+func NewExplain(op Operator, text string, optimHints []*algebra.OptimHint) *Explain {
 	return &Explain{
-		op:   op,
-		text: text,
+		op:         op,
+		text:       text,
+		optimHints: optimHints,
 	}
 }
 
@@ -38,6 +42,14 @@ func (this *Explain) Operator() Operator {
 	return this.op
 }
 
+/*
+Returns the optimizer hints of the statement, and whether each was
+followed.
+*/
+func (this *Explain) OptimHints() []*algebra.OptimHint {
+	return this.optimHints
+}
+
 func (this *Explain) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -46,6 +58,9 @@ func (this *Explain) MarshalBase(f func(map[string]interface{})) map[string]inte
 	r := make(map[string]interface{}, 2)
 	r["plan"] = this.op
 	r["text"] = this.text
+	if len(this.optimHints) > 0 {
+		r["optimizer_hints"] = this.optimHints
+	}
 	if f != nil {
 		f(r)
 	} else {
@@ -56,8 +71,9 @@ func (this *Explain) MarshalBase(f func(map[string]interface{})) map[string]inte
 
 func (this *Explain) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		Op   json.RawMessage `json:"plan"`
-		Text string          `json:"text"`
+		Op         json.RawMessage      `json:"plan"`
+		Text       string               `json:"text"`
+		OptimHints []*algebra.OptimHint `json:"optimizer_hints"`
 	}
 
 	var op_type struct {
@@ -75,6 +91,7 @@ func (this *Explain) UnmarshalJSON(body []byte) error {
 	}
 
 	this.text = _unmarshalled.Text
+	this.optimHints = _unmarshalled.OptimHints
 
 	err = json.Unmarshal(_unmarshalled.Op, &op_type)
 	if err != nil {
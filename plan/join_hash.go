@@ -20,17 +20,20 @@ import (
 type HashJoin struct {
 	readonly
 	outer        bool
+	rightOuter   bool
 	onclause     expression.Expression
 	child        Operator
 	buildExprs   expression.Expressions
 	probeExprs   expression.Expressions
 	buildAliases []string
+	concurrent   bool
 }
 
 func NewHashJoin(join *algebra.AnsiJoin, child Operator, buildExprs, probeExprs expression.Expressions,
 	buildAliases []string) *HashJoin {
 	return &HashJoin{
 		outer:        join.Outer(),
+		rightOuter:   join.RightOuter(),
 		onclause:     join.Onclause(),
 		child:        child,
 		buildExprs:   buildExprs,
@@ -51,6 +54,14 @@ func (this *HashJoin) Outer() bool {
 	return this.outer
 }
 
+/*
+Unmatched rows of the right-hand side are preserved, as in
+RIGHT and FULL OUTER JOIN.
+*/
+func (this *HashJoin) RightOuter() bool {
+	return this.rightOuter
+}
+
 func (this *HashJoin) Onclause() expression.Expression {
 	return this.onclause
 }
@@ -71,6 +82,18 @@ func (this *HashJoin) BuildAliases() []string {
 	return this.buildAliases
 }
 
+/*
+The left-hand side, which probes the hash table, does not depend on
+the right-hand side: it can run while the hash table is built.
+*/
+func (this *HashJoin) Concurrent() bool {
+	return this.concurrent
+}
+
+func (this *HashJoin) SetConcurrent() {
+	this.concurrent = true
+}
+
 func (this *HashJoin) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -83,6 +106,10 @@ func (this *HashJoin) MarshalBase(f func(map[string]interface{})) map[string]int
 		r["outer"] = this.outer
 	}
 
+	if this.rightOuter {
+		r["right_outer"] = this.rightOuter
+	}
+
 	buildList := make([]string, 0, len(this.buildExprs))
 	for _, build := range this.buildExprs {
 		buildList = append(buildList, expression.NewStringer().Visit(build))
@@ -97,6 +124,10 @@ func (this *HashJoin) MarshalBase(f func(map[string]interface{})) map[string]int
 
 	r["build_aliases"] = this.buildAliases
 
+	if this.concurrent {
+		r["concurrent"] = this.concurrent
+	}
+
 	r["~child"] = this.child
 
 	if f != nil {
@@ -110,9 +141,11 @@ func (this *HashJoin) UnmarshalJSON(body []byte) error {
 		_            string          `json:"#operator"`
 		Onclause     string          `json:"on_clause"`
 		Outer        bool            `json:"outer"`
+		RightOuter   bool            `json:"right_outer"`
 		BuildExprs   []string        `json:"build_exprs"`
 		ProbeExprs   []string        `json:"probe_exprs"`
 		BuildAliases []string        `json:"build_aliases"`
+		Concurrent   bool            `json:"concurrent"`
 		Child        json.RawMessage `json:"~child"`
 	}
 
@@ -129,6 +162,8 @@ func (this *HashJoin) UnmarshalJSON(body []byte) error {
 	}
 
 	this.outer = _unmarshalled.Outer
+	this.rightOuter = _unmarshalled.RightOuter
+	this.concurrent = _unmarshalled.Concurrent
 
 	this.buildExprs = make(expression.Expressions, len(_unmarshalled.BuildExprs))
 	for i, build := range _unmarshalled.BuildExprs {
@@ -19,18 +19,20 @@ import (
 
 type NLJoin struct {
 	readonly
-	outer    bool
-	alias    string
-	onclause expression.Expression
-	child    Operator
+	outer      bool
+	rightOuter bool
+	alias      string
+	onclause   expression.Expression
+	child      Operator
 }
 
 func NewNLJoin(join *algebra.AnsiJoin, child Operator) *NLJoin {
 	rv := &NLJoin{
-		outer:    join.Outer(),
-		alias:    join.Alias(),
-		onclause: join.Onclause(),
-		child:    child,
+		outer:      join.Outer(),
+		rightOuter: join.RightOuter(),
+		alias:      join.Alias(),
+		onclause:   join.Onclause(),
+		child:      child,
 	}
 
 	return rv
@@ -52,6 +54,14 @@ func (this *NLJoin) Alias() string {
 	return this.alias
 }
 
+/*
+Unmatched rows of the right-hand side are preserved, as in
+RIGHT and FULL OUTER JOIN.
+*/
+func (this *NLJoin) RightOuter() bool {
+	return this.rightOuter
+}
+
 func (this *NLJoin) Onclause() expression.Expression {
 	return this.onclause
 }
@@ -73,6 +83,10 @@ func (this *NLJoin) MarshalBase(f func(map[string]interface{})) map[string]inter
 		r["outer"] = this.outer
 	}
 
+	if this.rightOuter {
+		r["right_outer"] = this.rightOuter
+	}
+
 	r["~child"] = this.child
 
 	if f != nil {
@@ -83,11 +97,12 @@ func (this *NLJoin) MarshalBase(f func(map[string]interface{})) map[string]inter
 
 func (this *NLJoin) UnmarshalJSON(body []byte) error {
 	var _unmarshalled struct {
-		_        string          `json:"#operator"`
-		Onclause string          `json:"on_clause"`
-		Outer    bool            `json:"outer"`
-		Alias    string          `json:"alias"`
-		Child    json.RawMessage `json:"~child"`
+		_          string          `json:"#operator"`
+		Onclause   string          `json:"on_clause"`
+		Outer      bool            `json:"outer"`
+		RightOuter bool            `json:"right_outer"`
+		Alias      string          `json:"alias"`
+		Child      json.RawMessage `json:"~child"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -103,6 +118,7 @@ func (this *NLJoin) UnmarshalJSON(body []byte) error {
 	}
 
 	this.outer = _unmarshalled.Outer
+	this.rightOuter = _unmarshalled.RightOuter
 	this.alias = _unmarshalled.Alias
 
 	raw_child := _unmarshalled.Child
@@ -0,0 +1,194 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+)
+
+/*
+Hash semi-join, or anti-join. The hash table is built from the
+right-hand side, and probed with each left-hand side row, which alone
+is returned if a row satisfying the ON-clause is found, or, for an
+anti-join, if none is found.
+*/
+type HashSemiJoin struct {
+	readonly
+	anti       bool
+	alias      string
+	onclause   expression.Expression
+	child      Operator
+	buildExprs expression.Expressions
+	probeExprs expression.Expressions
+	concurrent bool
+}
+
+func NewHashSemiJoin(join *algebra.AnsiJoin, child Operator, buildExprs, probeExprs expression.Expressions) *HashSemiJoin {
+	return &HashSemiJoin{
+		anti:       join.Anti(),
+		alias:      join.Alias(),
+		onclause:   join.Onclause(),
+		child:      child,
+		buildExprs: buildExprs,
+		probeExprs: probeExprs,
+	}
+}
+
+func (this *HashSemiJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitHashSemiJoin(this)
+}
+
+func (this *HashSemiJoin) New() Operator {
+	return &HashSemiJoin{}
+}
+
+func (this *HashSemiJoin) Anti() bool {
+	return this.anti
+}
+
+func (this *HashSemiJoin) Alias() string {
+	return this.alias
+}
+
+func (this *HashSemiJoin) Onclause() expression.Expression {
+	return this.onclause
+}
+
+func (this *HashSemiJoin) Child() Operator {
+	return this.child
+}
+
+func (this *HashSemiJoin) BuildExprs() expression.Expressions {
+	return this.buildExprs
+}
+
+func (this *HashSemiJoin) ProbeExprs() expression.Expressions {
+	return this.probeExprs
+}
+
+/*
+The left-hand side, which probes the hash table, does not depend on
+the right-hand side: it can run while the hash table is built.
+*/
+func (this *HashSemiJoin) Concurrent() bool {
+	return this.concurrent
+}
+
+func (this *HashSemiJoin) SetConcurrent() {
+	this.concurrent = true
+}
+
+func (this *HashSemiJoin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *HashSemiJoin) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "HashSemiJoin"}
+	if this.anti {
+		r["#operator"] = "HashAntiJoin"
+	}
+
+	r["alias"] = this.alias
+	r["on_clause"] = expression.NewStringer().Visit(this.onclause)
+
+	buildList := make([]string, 0, len(this.buildExprs))
+	for _, build := range this.buildExprs {
+		buildList = append(buildList, expression.NewStringer().Visit(build))
+	}
+	r["build_exprs"] = buildList
+
+	probeList := make([]string, 0, len(this.probeExprs))
+	for _, probe := range this.probeExprs {
+		probeList = append(probeList, expression.NewStringer().Visit(probe))
+	}
+	r["probe_exprs"] = probeList
+
+	if this.concurrent {
+		r["concurrent"] = this.concurrent
+	}
+
+	r["~child"] = this.child
+
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *HashSemiJoin) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		Operator   string          `json:"#operator"`
+		Onclause   string          `json:"on_clause"`
+		Alias      string          `json:"alias"`
+		BuildExprs []string        `json:"build_exprs"`
+		ProbeExprs []string        `json:"probe_exprs"`
+		Concurrent bool            `json:"concurrent"`
+		Child      json.RawMessage `json:"~child"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	if _unmarshalled.Onclause != "" {
+		this.onclause, err = parser.Parse(_unmarshalled.Onclause)
+		if err != nil {
+			return err
+		}
+	}
+
+	this.anti = _unmarshalled.Operator == "HashAntiJoin"
+	this.alias = _unmarshalled.Alias
+	this.concurrent = _unmarshalled.Concurrent
+
+	this.buildExprs = make(expression.Expressions, len(_unmarshalled.BuildExprs))
+	for i, build := range _unmarshalled.BuildExprs {
+		buildExpr, err := parser.Parse(build)
+		if err != nil {
+			return err
+		}
+		this.buildExprs[i] = buildExpr
+	}
+
+	this.probeExprs = make(expression.Expressions, len(_unmarshalled.ProbeExprs))
+	for i, probe := range _unmarshalled.ProbeExprs {
+		probeExpr, err := parser.Parse(probe)
+		if err != nil {
+			return err
+		}
+		this.probeExprs[i] = probeExpr
+	}
+
+	raw_child := _unmarshalled.Child
+	var child_type struct {
+		Op_name string `json:"#operator"`
+	}
+
+	err = json.Unmarshal(raw_child, &child_type)
+	if err != nil {
+		return err
+	}
+
+	this.child, err = MakeOperator(child_type.Op_name, raw_child)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (this *HashSemiJoin) verify(prepared *Prepared) bool {
+	return this.child.verify(prepared)
+}
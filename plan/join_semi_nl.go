@@ -0,0 +1,130 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/expression/parser"
+)
+
+/*
+Nested-loop semi-join, or anti-join. For each left-hand side row, the
+child is run until a row satisfying the ON-clause is found, and the
+left-hand side row alone is returned if one is found, or, for an
+anti-join, if none is found.
+*/
+type NLSemiJoin struct {
+	readonly
+	anti     bool
+	alias    string
+	onclause expression.Expression
+	child    Operator
+}
+
+func NewNLSemiJoin(join *algebra.AnsiJoin, child Operator) *NLSemiJoin {
+	return &NLSemiJoin{
+		anti:     join.Anti(),
+		alias:    join.Alias(),
+		onclause: join.Onclause(),
+		child:    child,
+	}
+}
+
+func (this *NLSemiJoin) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitNLSemiJoin(this)
+}
+
+func (this *NLSemiJoin) New() Operator {
+	return &NLSemiJoin{}
+}
+
+func (this *NLSemiJoin) Anti() bool {
+	return this.anti
+}
+
+func (this *NLSemiJoin) Alias() string {
+	return this.alias
+}
+
+func (this *NLSemiJoin) Onclause() expression.Expression {
+	return this.onclause
+}
+
+func (this *NLSemiJoin) Child() Operator {
+	return this.child
+}
+
+func (this *NLSemiJoin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *NLSemiJoin) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "NestedLoopSemiJoin"}
+	if this.anti {
+		r["#operator"] = "NestedLoopAntiJoin"
+	}
+
+	r["alias"] = this.alias
+	r["on_clause"] = expression.NewStringer().Visit(this.onclause)
+	r["~child"] = this.child
+
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *NLSemiJoin) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		Operator string          `json:"#operator"`
+		Onclause string          `json:"on_clause"`
+		Alias    string          `json:"alias"`
+		Child    json.RawMessage `json:"~child"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	if _unmarshalled.Onclause != "" {
+		this.onclause, err = parser.Parse(_unmarshalled.Onclause)
+		if err != nil {
+			return err
+		}
+	}
+
+	this.anti = _unmarshalled.Operator == "NestedLoopAntiJoin"
+	this.alias = _unmarshalled.Alias
+
+	raw_child := _unmarshalled.Child
+	var child_type struct {
+		Op_name string `json:"#operator"`
+	}
+
+	err = json.Unmarshal(raw_child, &child_type)
+	if err != nil {
+		return err
+	}
+
+	this.child, err = MakeOperator(child_type.Op_name, raw_child)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (this *NLSemiJoin) verify(prepared *Prepared) bool {
+	return this.child.verify(prepared)
+}
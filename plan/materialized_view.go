@@ -0,0 +1,324 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/query/datastore"
+)
+
+// Create materialized view
+type CreateMaterializedView struct {
+	readwrite
+	keyspace   datastore.Keyspace
+	definition string
+}
+
+func NewCreateMaterializedView(keyspace datastore.Keyspace, definition string) *CreateMaterializedView {
+	return &CreateMaterializedView{
+		keyspace:   keyspace,
+		definition: definition,
+	}
+}
+
+func (this *CreateMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateMaterializedView(this)
+}
+
+func (this *CreateMaterializedView) New() Operator {
+	return &CreateMaterializedView{}
+}
+
+func (this *CreateMaterializedView) Keyspace() datastore.Keyspace {
+	return this.keyspace
+}
+
+func (this *CreateMaterializedView) Definition() string {
+	return this.definition
+}
+
+func (this *CreateMaterializedView) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *CreateMaterializedView) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "CreateMaterializedView"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	r["definition"] = this.definition
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *CreateMaterializedView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_          string `json:"#operator"`
+		Keys       string `json:"keyspace"`
+		Names      string `json:"namespace"`
+		Definition string `json:"definition"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.definition = _unmarshalled.Definition
+	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	return err
+}
+
+func (this *CreateMaterializedView) verify(prepared *Prepared) bool {
+	var res bool
+
+	this.keyspace, res = verifyKeyspace(this.keyspace, prepared)
+	return res
+}
+
+// Drop materialized view
+type DropMaterializedView struct {
+	readwrite
+	keyspace datastore.Keyspace
+}
+
+func NewDropMaterializedView(keyspace datastore.Keyspace) *DropMaterializedView {
+	return &DropMaterializedView{
+		keyspace: keyspace,
+	}
+}
+
+func (this *DropMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropMaterializedView(this)
+}
+
+func (this *DropMaterializedView) New() Operator {
+	return &DropMaterializedView{}
+}
+
+func (this *DropMaterializedView) Keyspace() datastore.Keyspace {
+	return this.keyspace
+}
+
+func (this *DropMaterializedView) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *DropMaterializedView) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "DropMaterializedView"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *DropMaterializedView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_     string `json:"#operator"`
+		Keys  string `json:"keyspace"`
+		Names string `json:"namespace"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	return err
+}
+
+func (this *DropMaterializedView) verify(prepared *Prepared) bool {
+	var res bool
+
+	this.keyspace, res = verifyKeyspace(this.keyspace, prepared)
+	return res
+}
+
+// Refresh materialized view
+type RefreshMaterializedView struct {
+	readwrite
+	keyspace datastore.Keyspace
+}
+
+func NewRefreshMaterializedView(keyspace datastore.Keyspace) *RefreshMaterializedView {
+	return &RefreshMaterializedView{
+		keyspace: keyspace,
+	}
+}
+
+func (this *RefreshMaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitRefreshMaterializedView(this)
+}
+
+func (this *RefreshMaterializedView) New() Operator {
+	return &RefreshMaterializedView{}
+}
+
+func (this *RefreshMaterializedView) Keyspace() datastore.Keyspace {
+	return this.keyspace
+}
+
+func (this *RefreshMaterializedView) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *RefreshMaterializedView) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "RefreshMaterializedView"}
+	r["keyspace"] = this.keyspace.Name()
+	r["namespace"] = this.keyspace.NamespaceId()
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *RefreshMaterializedView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_     string `json:"#operator"`
+		Keys  string `json:"keyspace"`
+		Names string `json:"namespace"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.keyspace, err = datastore.GetKeyspace(_unmarshalled.Names, _unmarshalled.Keys)
+	return err
+}
+
+func (this *RefreshMaterializedView) verify(prepared *Prepared) bool {
+	var res bool
+
+	this.keyspace, res = verifyKeyspace(this.keyspace, prepared)
+	return res
+}
+
+// A query answered from a materialized view
+// whether the view is fresh enough is only known at execution time, so
+// the plan for the query itself is kept as well
+type MaterializedView struct {
+	readonly
+	namespace  string
+	name       string
+	definition string
+	view       Operator // reads the view
+	query      Operator // runs the query
+}
+
+func NewMaterializedView(namespace, name, definition string, view, query Operator) *MaterializedView {
+	return &MaterializedView{
+		namespace:  namespace,
+		name:       name,
+		definition: definition,
+		view:       view,
+		query:      query,
+	}
+}
+
+func (this *MaterializedView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitMaterializedView(this)
+}
+
+func (this *MaterializedView) New() Operator {
+	return &MaterializedView{}
+}
+
+func (this *MaterializedView) Readonly() bool {
+	return this.view.Readonly() && this.query.Readonly()
+}
+
+func (this *MaterializedView) Namespace() string {
+	return this.namespace
+}
+
+func (this *MaterializedView) Name() string {
+	return this.name
+}
+
+// the definition of the view, when the plan was built
+func (this *MaterializedView) Definition() string {
+	return this.definition
+}
+
+func (this *MaterializedView) View() Operator {
+	return this.view
+}
+
+func (this *MaterializedView) Query() Operator {
+	return this.query
+}
+
+func (this *MaterializedView) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *MaterializedView) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "MaterializedView"}
+	r["namespace"] = this.namespace
+	r["keyspace"] = this.name
+	r["definition"] = this.definition
+	if f != nil {
+		f(r)
+	} else {
+		r["~view"] = this.view
+		r["~query"] = this.query
+	}
+	return r
+}
+
+func (this *MaterializedView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_          string          `json:"#operator"`
+		Names      string          `json:"namespace"`
+		Keys       string          `json:"keyspace"`
+		Definition string          `json:"definition"`
+		View       json.RawMessage `json:"~view"`
+		Query      json.RawMessage `json:"~query"`
+	}
+	var child_type struct {
+		Operator string `json:"#operator"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace = _unmarshalled.Names
+	this.name = _unmarshalled.Keys
+	this.definition = _unmarshalled.Definition
+
+	err = json.Unmarshal(_unmarshalled.View, &child_type)
+	if err != nil {
+		return err
+	}
+	this.view, err = MakeOperator(child_type.Operator, _unmarshalled.View)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(_unmarshalled.Query, &child_type)
+	if err != nil {
+		return err
+	}
+	this.query, err = MakeOperator(child_type.Operator, _unmarshalled.Query)
+	return err
+}
+
+func (this *MaterializedView) verify(prepared *Prepared) bool {
+	return this.view.verify(prepared) && this.query.verify(prepared)
+}
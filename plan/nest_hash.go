@@ -25,6 +25,7 @@ type HashNest struct {
 	buildExprs expression.Expressions
 	probeExprs expression.Expressions
 	buildAlias string
+	concurrent bool
 }
 
 func NewHashNest(nest *algebra.AnsiNest, child Operator, buildExprs, probeExprs expression.Expressions,
@@ -71,6 +72,18 @@ func (this *HashNest) BuildAlias() string {
 	return this.buildAlias
 }
 
+/*
+The left-hand side, which probes the hash table, does not depend on
+the right-hand side: it can run while the hash table is built.
+*/
+func (this *HashNest) Concurrent() bool {
+	return this.concurrent
+}
+
+func (this *HashNest) SetConcurrent() {
+	this.concurrent = true
+}
+
 func (this *HashNest) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -97,6 +110,10 @@ func (this *HashNest) MarshalBase(f func(map[string]interface{})) map[string]int
 
 	r["build_alias"] = this.buildAlias
 
+	if this.concurrent {
+		r["concurrent"] = this.concurrent
+	}
+
 	r["~child"] = this.child
 
 	if f != nil {
@@ -113,6 +130,7 @@ func (this *HashNest) UnmarshalJSON(body []byte) error {
 		BuildExprs []string        `json:"build_exprs"`
 		ProbeExprs []string        `json:"probe_exprs"`
 		BuildAlias string          `json:"build_alias"`
+		Concurrent bool            `json:"concurrent"`
 		Child      json.RawMessage `json:"~child"`
 	}
 
@@ -129,6 +147,7 @@ func (this *HashNest) UnmarshalJSON(body []byte) error {
 	}
 
 	this.outer = _unmarshalled.Outer
+	this.concurrent = _unmarshalled.Concurrent
 
 	this.buildExprs = make(expression.Expressions, len(_unmarshalled.BuildExprs))
 	for i, build := range _unmarshalled.BuildExprs {
@@ -74,6 +74,12 @@ var _OPERATORS = map[string]Operator{
 	"HashNest":       &HashNest{},
 	"Unnest":         &Unnest{},
 
+	// Semi-join, anti-join
+	"NestedLoopSemiJoin": &NLSemiJoin{},
+	"NestedLoopAntiJoin": &NLSemiJoin{},
+	"HashSemiJoin":       &HashSemiJoin{},
+	"HashAntiJoin":       &HashSemiJoin{},
+
 	// Let + Letting
 	"Let": &Let{},
 
@@ -142,6 +148,16 @@ var _OPERATORS = map[string]Operator{
 	"AlterIndex":         &AlterIndex{},
 	"BuildIndexes":       &BuildIndexes{},
 
+	// Materialized views
+	"CreateMaterializedView":  &CreateMaterializedView{},
+	"DropMaterializedView":    &DropMaterializedView{},
+	"RefreshMaterializedView": &RefreshMaterializedView{},
+	"MaterializedView":        &MaterializedView{},
+
+	// Views
+	"CreateView": &CreateView{},
+	"DropView":   &DropView{},
+
 	// Roles
 	"GrantRole":  &GrantRole{},
 	"RevokeRole": &RevokeRole{},
@@ -14,7 +14,10 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
+	"time"
 
+	atomic "github.com/couchbase/go-couchbase/platform"
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/value"
 )
@@ -28,9 +31,16 @@ type Prepared struct {
 	reqType         string
 	indexApiVersion int
 	featureControls uint64
+	keyspaces       []string
+	resultCache     bool          // RESULT_CACHE hint
+	resultCacheTTL  time.Duration // its time to live, if given
+	volatile        bool          // results can change without the data changing
 
 	indexers   []idxVersion // for reprepare checking
 	namespaces []nsVersion
+
+	keyspaceIndexers []ksIndexer // for index changes on the keyspaces used
+	keyspacesTracked bool
 }
 
 type idxVersion struct {
@@ -38,6 +48,13 @@ type idxVersion struct {
 	version uint64
 }
 
+// the version is updated in place, while other requests check it
+type ksIndexer struct {
+	version  atomic.AlignedUint64
+	keyspace string
+	indexer  datastore.Indexer
+}
+
 type nsVersion struct {
 	namespace datastore.Namespace
 	version   uint64
@@ -63,6 +80,15 @@ func (this *Prepared) MarshalBase(f func(map[string]interface{})) map[string]int
 	r["text"] = this.text
 	r["indexApiVersion"] = this.indexApiVersion
 	r["featureControls"] = this.featureControls
+	if len(this.keyspaces) > 0 {
+		r["keyspaces"] = this.keyspaces
+	}
+	if this.resultCache {
+		r["resultCacheTTL"] = this.resultCacheTTL.String()
+	}
+	if this.volatile {
+		r["volatile"] = this.volatile
+	}
 
 	if f != nil {
 		f(r)
@@ -80,6 +106,9 @@ func (this *Prepared) UnmarshalJSON(body []byte) error {
 		ReqType         string          `json:"reqType"`
 		ApiVersion      int             `json:"indexApiVersion"`
 		FeatureControls uint64          `json:"featureControls"`
+		Keyspaces       []string        `json:"keyspaces"`
+		ResultCacheTTL  string          `json:"resultCacheTTL"`
+		Volatile        bool            `json:"volatile"`
 	}
 
 	var op_type struct {
@@ -108,6 +137,12 @@ func (this *Prepared) UnmarshalJSON(body []byte) error {
 	this.reqType = _unmarshalled.ReqType
 	this.indexApiVersion = _unmarshalled.ApiVersion
 	this.featureControls = _unmarshalled.FeatureControls
+	this.keyspaces = _unmarshalled.Keyspaces
+	if _unmarshalled.ResultCacheTTL != "" {
+		this.resultCache = true
+		this.resultCacheTTL, _ = time.ParseDuration(_unmarshalled.ResultCacheTTL)
+	}
+	this.volatile = _unmarshalled.Volatile
 	this.Operator, err = MakeOperator(op_type.Operator, _unmarshalled.Operator)
 
 	return err
@@ -157,6 +192,35 @@ func (this *Prepared) SetFeatureControls(featureControls uint64) {
 	this.featureControls = featureControls
 }
 
+func (this *Prepared) Keyspaces() []string {
+	return this.keyspaces
+}
+
+func (this *Prepared) SetKeyspaces(keyspaces []string) {
+	this.keyspaces = keyspaces
+}
+
+// whether the statement asks for its results to be cached, and for how long
+// a time to live of 0 is the server default
+func (this *Prepared) ResultCache() (bool, time.Duration) {
+	return this.resultCache, this.resultCacheTTL
+}
+
+func (this *Prepared) SetResultCache(ttl time.Duration) {
+	this.resultCache = true
+	this.resultCacheTTL = ttl
+}
+
+// whether the statement uses functions such as NOW_STR(), RANDOM(), UUID()
+// or CURL(), whose results can change between two executions over the same data
+func (this *Prepared) Volatile() bool {
+	return this.volatile
+}
+
+func (this *Prepared) SetVolatile(volatile bool) {
+	this.volatile = volatile
+}
+
 func (this *Prepared) EncodedPlan() string {
 	return this.encoded_plan
 }
@@ -221,9 +285,84 @@ func (this *Prepared) MetadataCheck() bool {
 			return false
 		}
 	}
+
+	// and that no index has changed on the keyspaces used
+	for i := range this.keyspaceIndexers {
+		ks := &this.keyspaceIndexers[i]
+		ks.indexer.Refresh()
+		if ks.indexer.MetadataVersion() != atomic.LoadUint64(&ks.version) {
+			return false
+		}
+	}
 	return true
 }
 
 func (this *Prepared) Verify() bool {
-	return this.Operator.verify(this)
+	if !this.Operator.verify(this) {
+		return false
+	}
+	this.trackKeyspaces()
+	return true
+}
+
+// Locking is handled by the top level caller!
+// record the index metadata versions of all the keyspaces used, so that
+// indexes created, built or altered after planning can be detected
+// the indexers are only collected by the first verification, which is
+// done under a lock: after that, other requests may be checking them,
+// and only their versions are updated
+func (this *Prepared) trackKeyspaces() {
+	if this.keyspacesTracked {
+		for i := range this.keyspaceIndexers {
+			ks := &this.keyspaceIndexers[i]
+			ks.indexer.Refresh()
+			atomic.StoreUint64(&ks.version, ks.indexer.MetadataVersion())
+		}
+		return
+	}
+
+	var keyspaceIndexers []ksIndexer
+
+	for _, name := range this.keyspaces {
+		namespace := ""
+		keyspace := name
+		if colon := strings.IndexByte(name, ':'); colon >= 0 {
+			namespace = name[:colon]
+			keyspace = name[colon+1:]
+		}
+		ks, err := datastore.GetKeyspace(namespace, keyspace)
+		if err != nil {
+			continue
+		}
+		indexers, err := ks.Indexers()
+		if err != nil {
+			continue
+		}
+		for _, indexer := range indexers {
+			indexer.Refresh()
+			keyspaceIndexers = append(keyspaceIndexers, ksIndexer{
+				version:  atomic.AlignedUint64(indexer.MetadataVersion()),
+				keyspace: name,
+				indexer:  indexer,
+			})
+		}
+	}
+	this.keyspaceIndexers = keyspaceIndexers
+	this.keyspacesTracked = true
+}
+
+// the keyspaces used on which indexes have changed since the plan was verified
+func (this *Prepared) ChangedKeyspaces() []string {
+	var rv []string
+
+	for i := range this.keyspaceIndexers {
+		ks := &this.keyspaceIndexers[i]
+		if ks.indexer.MetadataVersion() == atomic.LoadUint64(&ks.version) {
+			continue
+		}
+		if len(rv) == 0 || rv[len(rv)-1] != ks.keyspace {
+			rv = append(rv, ks.keyspace)
+		}
+	}
+	return rv
 }
@@ -22,6 +22,7 @@ type InitialProject struct {
 	projection    *algebra.Projection
 	terms         ProjectTerms
 	starTermCount int
+	concurrent    bool
 }
 
 func NewInitialProject(projection *algebra.Projection) *InitialProject {
@@ -66,6 +67,18 @@ func (this *InitialProject) StarTermCount() int {
 	return this.starTermCount
 }
 
+/*
+The uncorrelated subqueries of the projection do not depend on the
+rows projected: they can be evaluated while the rows are produced.
+*/
+func (this *InitialProject) Concurrent() bool {
+	return this.concurrent
+}
+
+func (this *InitialProject) SetConcurrent() {
+	this.concurrent = true
+}
+
 func (this *InitialProject) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.MarshalBase(nil))
 }
@@ -101,6 +114,11 @@ func (this *InitialProject) MarshalBase(f func(map[string]interface{})) map[stri
 		s = append(s, t)
 	}
 	r["result_terms"] = s
+
+	if this.concurrent {
+		r["concurrent"] = this.concurrent
+	}
+
 	if f != nil {
 		f(r)
 	}
@@ -115,8 +133,9 @@ func (this *InitialProject) UnmarshalJSON(body []byte) error {
 			As   string `json:"as"`
 			Star bool   `json:"star"`
 		} `json:"result_terms"`
-		Distinct bool `json:"distinct"`
-		Raw      bool `json:"raw"`
+		Distinct   bool `json:"distinct"`
+		Raw        bool `json:"raw"`
+		Concurrent bool `json:"concurrent"`
 	}
 
 	err := json.Unmarshal(body, &_unmarshalled)
@@ -148,6 +167,7 @@ func (this *InitialProject) UnmarshalJSON(body []byte) error {
 
 	this.projection = projection
 	this.terms = project_terms
+	this.concurrent = _unmarshalled.Concurrent
 
 	return nil
 }
@@ -0,0 +1,146 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+)
+
+// Create view
+// views are not keyspaces: they are named, not verified
+type CreateView struct {
+	readwrite
+	namespace  string
+	name       string
+	definition string
+}
+
+func NewCreateView(namespace, name, definition string) *CreateView {
+	return &CreateView{
+		namespace:  namespace,
+		name:       name,
+		definition: definition,
+	}
+}
+
+func (this *CreateView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitCreateView(this)
+}
+
+func (this *CreateView) New() Operator {
+	return &CreateView{}
+}
+
+func (this *CreateView) Namespace() string {
+	return this.namespace
+}
+
+func (this *CreateView) Name() string {
+	return this.name
+}
+
+func (this *CreateView) Definition() string {
+	return this.definition
+}
+
+func (this *CreateView) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *CreateView) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "CreateView"}
+	r["namespace"] = this.namespace
+	r["keyspace"] = this.name
+	r["definition"] = this.definition
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *CreateView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_          string `json:"#operator"`
+		Names      string `json:"namespace"`
+		Keys       string `json:"keyspace"`
+		Definition string `json:"definition"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace = _unmarshalled.Names
+	this.name = _unmarshalled.Keys
+	this.definition = _unmarshalled.Definition
+	return nil
+}
+
+// Drop view
+type DropView struct {
+	readwrite
+	namespace string
+	name      string
+}
+
+func NewDropView(namespace, name string) *DropView {
+	return &DropView{
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+func (this *DropView) Accept(visitor Visitor) (interface{}, error) {
+	return visitor.VisitDropView(this)
+}
+
+func (this *DropView) New() Operator {
+	return &DropView{}
+}
+
+func (this *DropView) Namespace() string {
+	return this.namespace
+}
+
+func (this *DropView) Name() string {
+	return this.name
+}
+
+func (this *DropView) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.MarshalBase(nil))
+}
+
+func (this *DropView) MarshalBase(f func(map[string]interface{})) map[string]interface{} {
+	r := map[string]interface{}{"#operator": "DropView"}
+	r["namespace"] = this.namespace
+	r["keyspace"] = this.name
+	if f != nil {
+		f(r)
+	}
+	return r
+}
+
+func (this *DropView) UnmarshalJSON(body []byte) error {
+	var _unmarshalled struct {
+		_     string `json:"#operator"`
+		Names string `json:"namespace"`
+		Keys  string `json:"keyspace"`
+	}
+
+	err := json.Unmarshal(body, &_unmarshalled)
+	if err != nil {
+		return err
+	}
+
+	this.namespace = _unmarshalled.Names
+	this.name = _unmarshalled.Keys
+	return nil
+}
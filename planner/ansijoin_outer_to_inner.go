@@ -22,12 +22,17 @@ type ansijoinOuterToInner struct {
 	baseKeyspaces    map[string]*baseKeyspace
 	unnests          []*algebra.Unnest
 	pushableOnclause expression.Expression
+	where            expression.Expression
+	onclauses        expression.Expressions // ON clauses of enclosing inner joins
+	aliases          []string               // aliases visited so far
 }
 
-func newAnsijoinOuterToInner(baseKeyspaces map[string]*baseKeyspace, unnests []*algebra.Unnest) *ansijoinOuterToInner {
+func newAnsijoinOuterToInner(baseKeyspaces map[string]*baseKeyspace, unnests []*algebra.Unnest,
+	where expression.Expression) *ansijoinOuterToInner {
 	return &ansijoinOuterToInner{
 		baseKeyspaces: baseKeyspaces,
 		unnests:       unnests,
+		where:         where,
 	}
 }
 
@@ -91,6 +96,52 @@ func (this *ansijoinOuterToInner) visitAnsiJoin(left algebra.FromTerm, outer boo
 	return false, nil
 }
 
+// For RIGHT and FULL OUTER JOIN, the left-hand side is subservient. Since the left-hand
+// side may itself contain joins whose ON clauses are pushable, the check is done on the
+// WHERE clause and the ON clauses of enclosing inner joins, rather than on keyspace filters.
+// All keyspaces of the left-hand side have been visited by now.
+func (this *ansijoinOuterToInner) nullRejLeft() bool {
+	chkNullRej := newChkNullRej()
+
+	for _, a := range this.aliases {
+		chkNullRej.setAlias(a)
+
+		if this.where != nil && nullRejExpr(chkNullRej, this.where) {
+			return true
+		}
+
+		for _, onclause := range this.onclauses {
+			if nullRejExpr(chkNullRej, onclause) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (this *ansijoinOuterToInner) dropWhereFilters(aliases []string) {
+	for _, alias := range aliases {
+		baseKeyspace, ok := this.baseKeyspaces[alias]
+		if !ok {
+			continue
+		}
+
+		baseKeyspace.filters = onclauseFilters(baseKeyspace.filters)
+		baseKeyspace.joinfilters = onclauseFilters(baseKeyspace.joinfilters)
+	}
+}
+
+func onclauseFilters(filters Filters) Filters {
+	rv := make(Filters, 0, len(filters))
+	for _, fltr := range filters {
+		if fltr.isOnclause() {
+			rv = append(rv, fltr)
+		}
+	}
+	return rv
+}
+
 func (this *ansijoinOuterToInner) visitSetop(first algebra.Subresult, second algebra.Subresult) error {
 	// ansijoinOuterToInner is initialized at FROM clause processing, i.e., for each statement,
 	// and thus we don't expect it'll reach any of the set operations node
@@ -109,14 +160,17 @@ func (this *ansijoinOuterToInner) VisitSubselect(node *algebra.Subselect) (inter
 }
 
 func (this *ansijoinOuterToInner) VisitKeyspaceTerm(node *algebra.KeyspaceTerm) (interface{}, error) {
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitExpressionTerm(node *algebra.ExpressionTerm) (interface{}, error) {
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitSubqueryTerm(node *algebra.SubqueryTerm) (interface{}, error) {
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
@@ -131,14 +185,46 @@ func (this *ansijoinOuterToInner) VisitIndexJoin(node *algebra.IndexJoin) (inter
 }
 
 func (this *ansijoinOuterToInner) VisitAnsiJoin(node *algebra.AnsiJoin) (interface{}, error) {
+	inner := !node.Outer() && !node.RightOuter()
+	if inner {
+		this.onclauses = append(this.onclauses, node.Onclause())
+	}
+
+	start := len(this.aliases)
 	aoj2aij, err := this.visitAnsiJoin(node.Left(), node.Outer(), node.Alias())
 	if err != nil {
 		return nil, err
 	}
-	if aoj2aij {
-		this.addOnclause(node.Onclause())
+
+	if inner {
+		this.onclauses = this.onclauses[:len(this.onclauses)-1]
+	}
+
+	// an anti-join is not null-rejected
+	if aoj2aij && !node.Anti() {
 		node.SetOuter(false)
 	}
+
+	// RIGHT OUTER JOIN to INNER JOIN, FULL OUTER JOIN to LEFT OUTER JOIN
+	roj2ij := node.RightOuter() && this.nullRejLeft()
+	if roj2ij {
+		node.SetRightOuter(false)
+	}
+
+	// the WHERE clause filters left on the null-supplying side of a RIGHT or FULL
+	// OUTER JOIN do not reject nulls, and cannot be used to scan its keyspaces
+	if node.RightOuter() {
+		this.dropWhereFilters(this.aliases[start:])
+		if node.Outer() {
+			this.dropWhereFilters([]string{node.Alias()})
+		}
+	}
+
+	if (aoj2aij || roj2ij) && !node.Outer() && !node.RightOuter() {
+		this.addOnclause(node.Onclause())
+	}
+
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
@@ -153,19 +239,37 @@ func (this *ansijoinOuterToInner) VisitIndexNest(node *algebra.IndexNest) (inter
 }
 
 func (this *ansijoinOuterToInner) VisitAnsiNest(node *algebra.AnsiNest) (interface{}, error) {
+	inner := !node.Outer()
+	if inner {
+		this.onclauses = append(this.onclauses, node.Onclause())
+	}
+
 	aoj2aij, err := this.visitAnsiJoin(node.Left(), node.Outer(), node.Alias())
 	if err != nil {
 		return nil, err
 	}
+
+	if inner {
+		this.onclauses = this.onclauses[:len(this.onclauses)-1]
+	}
+
 	if aoj2aij {
 		this.addOnclause(node.Onclause())
 		node.SetOuter(false)
 	}
+
+	this.aliases = append(this.aliases, node.Alias())
 	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitUnnest(node *algebra.Unnest) (interface{}, error) {
-	return node.Left().Accept(this)
+	_, err := node.Left().Accept(this)
+	if err != nil {
+		return nil, err
+	}
+
+	this.aliases = append(this.aliases, node.Alias())
+	return nil, nil
 }
 
 func (this *ansijoinOuterToInner) VisitUnion(node *algebra.Union) (interface{}, error) {
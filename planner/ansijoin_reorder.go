@@ -0,0 +1,558 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"sort"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+)
+
+/*
+Join reordering. A run of inner ANSI JOINs on keyspaces is reordered
+greedily: the keyspace with the fewest estimated qualifying documents
+is read first, then the keyspace that is cheapest to join to those
+already joined is added, until all are joined. The ON-clauses of the
+run are split into conjuncts, and each conjunct is placed on the first
+join where all the keyspaces it references are available.
+
+Outer joins, semi-joins, nests, unnests, lookup joins, and keyspaces
+with USE KEYS
+are not moved, and end a run. Inner joins above them are reordered
+among themselves, after them.
+
+If no order is found in which every keyspace can be joined, with an
+index, on its primary key, or as a cross join, the joins are left in
+the order they are written.
+
+The reordered joins are built from copies of the joins and keyspace
+terms, so that the statement is left as it was written.
+*/
+
+// estimated selectivity of a filter on an index key, and on other filters
+const (
+	_REORDER_SARGABLE_SEL = 0.1
+	_REORDER_FILTER_SEL   = 0.5
+	_REORDER_UNKNOWN_SIZE = 1000000.0
+)
+
+// how a keyspace is joined, cheapest first
+const (
+	_REORDER_JOIN_PRIMARY = iota // on the primary key
+	_REORDER_JOIN_INDEX          // with an index on the join keys
+	_REORDER_JOIN_HASH           // with a hash join, as hinted
+	_REORDER_JOIN_CROSS          // with a cross join
+	_REORDER_JOIN_NONE           // cannot be joined
+)
+
+type reorderTerm struct {
+	term   algebra.SimpleFromTerm
+	alias  string
+	ident  *expression.Identifier
+	id     expression.Expression
+	keys   expression.Expressions // leading index keys
+	gsi    []bool
+	size   float64 // estimated qualifying documents
+	pos    int
+	hinted bool // has a join hint
+	hash   bool // prefers a hash join
+}
+
+type reorderConjunct struct {
+	expr expression.Expression
+	refs map[string]bool // aliases of the run referenced
+	on   bool            // from an ON-clause, rather than the WHERE clause
+}
+
+func (this *builder) reorderAnsiJoins(from algebra.FromTerm, where expression.Expression) (
+	algebra.FromTerm, error) {
+
+	join, ok := from.(*algebra.AnsiJoin)
+	if !ok {
+		return from, nil
+	}
+
+	// gather the run of inner joins on movable keyspaces
+	var joins []*algebra.AnsiJoin
+	var base algebra.FromTerm = join
+	for {
+		j, ok := base.(*algebra.AnsiJoin)
+		if !ok || j.Outer() || j.RightOuter() || j.Semi() || !movableJoinTerm(j.Right()) {
+			break
+		}
+		joins = append(joins, j)
+		base = j.Left()
+	}
+
+	if len(joins) == 0 {
+		left, err := this.reorderAnsiJoins(join.Left(), where)
+		if err != nil {
+			return nil, err
+		}
+		return rebaseAnsiJoins([]*algebra.AnsiJoin{join}, left), nil
+	}
+
+	for i, j := 0, len(joins)-1; i < j; i, j = i+1, j-1 {
+		joins[i], joins[j] = joins[j], joins[i]
+	}
+
+	base, err := this.reorderAnsiJoins(base, where)
+	if err != nil {
+		return nil, err
+	}
+
+	// the primary term is moved too, unless it has a join hint
+	terms := make([]algebra.SimpleFromTerm, 0, len(joins)+1)
+	var fixed algebra.FromTerm
+	if primary, ok := base.(algebra.SimpleFromTerm); ok && movableJoinTerm(primary) &&
+		this.termJoinHint(primary) == algebra.JOIN_HINT_NONE {
+		terms = append(terms, primary)
+	} else {
+		fixed = base
+	}
+
+	for _, j := range joins {
+		terms = append(terms, j.Right())
+	}
+
+	if len(terms) < 2 {
+		return rebaseAnsiJoins(joins, base), nil
+	}
+
+	rterms := make([]*reorderTerm, len(terms))
+	for i, term := range terms {
+		rterms[i], err = this.newReorderTerm(term, i)
+		if err != nil {
+			return nil, err
+		}
+		if rterms[i] == nil {
+			return rebaseAnsiJoins(joins, base), nil
+		}
+	}
+
+	var conjuncts []*reorderConjunct
+	for _, j := range joins {
+		conjuncts = appendReorderConjuncts(conjuncts, j.Onclause(), rterms, true)
+	}
+	conjuncts = appendReorderConjuncts(conjuncts, where, rterms, false)
+
+	for _, rterm := range rterms {
+		estimateReorderSize(rterm, conjuncts)
+	}
+
+	order := bestJoinOrder(rterms, conjuncts, fixed != nil)
+	if order == nil || isOriginalOrder(order) {
+		return rebaseAnsiJoins(joins, base), nil
+	}
+
+	// rebuild the joins in the new order
+	var left algebra.FromTerm
+	next := 0
+	if fixed != nil {
+		left = fixed
+	} else {
+		term := copyJoinTerm(order[0].term)
+		unsetAnsiJoin(term)
+		left = term
+		next = 1
+	}
+
+	bound := make(map[string]bool, len(order))
+	for _, rterm := range order[:next] {
+		bound[rterm.alias] = true
+	}
+
+	assigned := make([]bool, len(conjuncts))
+	for i, rterm := range order[next:] {
+		bound[rterm.alias] = true
+		onclause := joinOnclause(conjuncts, assigned, bound, i == 0)
+		term := copyJoinTerm(rterm.term)
+		setAnsiJoin(term)
+		left = algebra.NewAnsiJoin(left, false, term, onclause)
+	}
+
+	return left, nil
+}
+
+/*
+The joins, bottom up, on a new left-hand side. They are copied if the
+left-hand side has changed.
+*/
+func rebaseAnsiJoins(joins []*algebra.AnsiJoin, base algebra.FromTerm) algebra.FromTerm {
+	if base == joins[0].Left() {
+		return joins[len(joins)-1]
+	}
+
+	left := base
+	for _, join := range joins {
+		join = join.Copy()
+		join.SetLeft(left)
+		left = join
+	}
+	return left
+}
+
+func copyJoinTerm(term algebra.SimpleFromTerm) algebra.SimpleFromTerm {
+	switch term := term.(type) {
+	case *algebra.KeyspaceTerm:
+		return term.Copy()
+	case *algebra.ExpressionTerm:
+		return term.Copy()
+	}
+	return term
+}
+
+/*
+Keyspaces without USE KEYS can be joined in any order.
+*/
+func movableJoinTerm(term algebra.SimpleFromTerm) bool {
+	ksterm := algebra.GetKeyspaceTerm(term)
+	return ksterm != nil && ksterm.Keys() == nil
+}
+
+func setAnsiJoin(term algebra.SimpleFromTerm) {
+	term.SetAnsiJoin()
+	if ksterm := algebra.GetKeyspaceTerm(term); ksterm != nil {
+		ksterm.SetAnsiJoin()
+	}
+}
+
+func unsetAnsiJoin(term algebra.SimpleFromTerm) {
+	switch term := term.(type) {
+	case *algebra.KeyspaceTerm:
+		term.UnsetAnsiJoin()
+	case *algebra.ExpressionTerm:
+		term.UnsetAnsiJoin()
+	}
+}
+
+func (this *builder) newReorderTerm(term algebra.SimpleFromTerm, pos int) (*reorderTerm, error) {
+	ksterm := algebra.GetKeyspaceTerm(term)
+	keyspace, err := this.getTermKeyspace(ksterm)
+	if err != nil {
+		// reported when the keyspace is planned
+		return nil, nil
+	}
+
+	alias := ksterm.Alias()
+	ident := expression.NewIdentifier(alias)
+	rv := &reorderTerm{
+		term:   term,
+		alias:  alias,
+		ident:  ident,
+		id:     expression.NewField(expression.NewMeta(ident), expression.NewFieldName("id", false)),
+		size:   _REORDER_UNKNOWN_SIZE,
+		pos:    pos,
+		hinted: this.termJoinHint(term) != algebra.JOIN_HINT_NONE,
+		hash:   this.preferHash(term),
+	}
+
+	count, cerr := keyspace.Count(datastore.NULL_QUERY_CONTEXT)
+	if cerr == nil {
+		rv.size = float64(count)
+	}
+
+	var indexes []datastore.Index
+	if hints := this.termIndexes(ksterm); len(hints) > 0 {
+		indexes, err = allHints(keyspace, hints, nil, this.indexApiVersion)
+	} else {
+		indexes, err = allIndexes(keyspace, nil, nil, this.indexApiVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	formalizer := expression.NewSelfFormalizer(alias, nil)
+	for _, index := range indexes {
+		if index.IsPrimary() || index.Condition() != nil || len(index.RangeKey()) == 0 {
+			continue
+		}
+
+		formalizer.SetIndexScope()
+		key, err := formalizer.Map(index.RangeKey()[0].Copy())
+		formalizer.ClearIndexScope()
+		if err != nil {
+			return nil, err
+		}
+
+		dnf := NewDNF(key, true, true)
+		key, err = dnf.Map(key)
+		if err != nil {
+			return nil, err
+		}
+
+		rv.keys = append(rv.keys, key)
+		rv.gsi = append(rv.gsi, index.Type() == datastore.GSI)
+	}
+
+	return rv, nil
+}
+
+func appendReorderConjuncts(conjuncts []*reorderConjunct, pred expression.Expression,
+	rterms []*reorderTerm, on bool) []*reorderConjunct {
+
+	if pred == nil {
+		return conjuncts
+	}
+
+	if and, ok := pred.(*expression.And); ok {
+		for _, op := range and.Operands() {
+			conjuncts = appendReorderConjuncts(conjuncts, op, rterms, on)
+		}
+		return conjuncts
+	}
+
+	if on {
+		if val := pred.Value(); val != nil && val.Truth() {
+			return conjuncts
+		}
+	}
+
+	refs := make(map[string]bool, len(rterms))
+	for _, rterm := range rterms {
+		if pred.DependsOn(rterm.ident) {
+			refs[rterm.alias] = true
+		}
+	}
+
+	return append(conjuncts, &reorderConjunct{pred, refs, on})
+}
+
+/*
+Estimate the number of documents of the keyspace qualifying for the
+filters on it alone.
+*/
+func estimateReorderSize(rterm *reorderTerm, conjuncts []*reorderConjunct) {
+	for _, c := range conjuncts {
+		if len(c.refs) != 1 || !c.refs[rterm.alias] {
+			continue
+		}
+
+		if isPrimaryKeyFilter(c.expr, rterm) {
+			rterm.size = 1.0
+			break
+		} else if isIndexFilter(c.expr, rterm) {
+			rterm.size *= _REORDER_SARGABLE_SEL
+		} else {
+			rterm.size *= _REORDER_FILTER_SEL
+		}
+	}
+
+	if rterm.size < 1.0 {
+		rterm.size = 1.0
+	}
+}
+
+func isPrimaryKeyFilter(pred expression.Expression, rterm *reorderTerm) bool {
+	switch pred := pred.(type) {
+	case *expression.Eq:
+		return (pred.First().EquivalentTo(rterm.id) && !pred.Second().DependsOn(rterm.ident)) ||
+			(pred.Second().EquivalentTo(rterm.id) && !pred.First().DependsOn(rterm.ident))
+	case *expression.In:
+		return pred.First().EquivalentTo(rterm.id) && !pred.Second().DependsOn(rterm.ident)
+	}
+
+	return false
+}
+
+func isIndexFilter(pred expression.Expression, rterm *reorderTerm) bool {
+	for i, key := range rterm.keys {
+		if min, _, _ := SargableFor(pred, expression.Expressions{key}, false, rterm.gsi[i]); min > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+How the keyspace can be joined to the bound keyspaces, with the given
+ON-clause conjuncts and WHERE clause.
+*/
+func joinMethod(rterm *reorderTerm, conjuncts []*reorderConjunct, assigned []bool,
+	bound map[string]bool, first bool) int {
+
+	method := _REORDER_JOIN_NONE
+	onclause := false
+	connected := false
+
+	for i, c := range conjuncts {
+		if assigned[i] || !joinConjunct(c, rterm, bound, first) {
+			continue
+		}
+
+		if c.on {
+			onclause = true
+		}
+
+		if !c.refs[rterm.alias] {
+			continue
+		}
+
+		connected = connected || len(c.refs) > 1
+		if isPrimaryKeyFilter(c.expr, rterm) {
+			method = _REORDER_JOIN_PRIMARY
+		} else if method > _REORDER_JOIN_INDEX && isIndexFilter(c.expr, rterm) {
+			method = _REORDER_JOIN_INDEX
+		}
+	}
+
+	if method == _REORDER_JOIN_NONE {
+		if connected && rterm.hash {
+			method = _REORDER_JOIN_HASH
+		} else if !onclause {
+			method = _REORDER_JOIN_CROSS
+		}
+	}
+
+	return method
+}
+
+/*
+Whether the conjunct can be evaluated when joining the keyspace to
+the bound keyspaces. Conjuncts of the ON-clauses that reference none
+of the keyspaces of the run are placed on the first join.
+*/
+func joinConjunct(c *reorderConjunct, rterm *reorderTerm, bound map[string]bool, first bool) bool {
+	if len(c.refs) == 0 {
+		return c.on && first
+	}
+
+	for alias, _ := range c.refs {
+		if alias != rterm.alias && !bound[alias] {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+Find the order of the keyspaces, by trying each keyspace as the first,
+the smallest first, and adding the cheapest keyspace to join at each
+step. If the first keyspace is fixed, only the order of the others is
+found.
+*/
+func bestJoinOrder(rterms []*reorderTerm, conjuncts []*reorderConjunct, fixed bool) []*reorderTerm {
+	if fixed {
+		return greedyJoinOrder(nil, rterms, conjuncts)
+	}
+
+	firsts := make([]*reorderTerm, 0, len(rterms))
+	for _, rterm := range rterms {
+		if rterm.pos == 0 || !rterm.hinted {
+			firsts = append(firsts, rterm)
+		}
+	}
+
+	sort.SliceStable(firsts, func(i, j int) bool { return firsts[i].size < firsts[j].size })
+
+	for _, first := range firsts {
+		if order := greedyJoinOrder(first, rterms, conjuncts); order != nil {
+			return order
+		}
+	}
+
+	return nil
+}
+
+func greedyJoinOrder(first *reorderTerm, rterms []*reorderTerm, conjuncts []*reorderConjunct) []*reorderTerm {
+	order := make([]*reorderTerm, 0, len(rterms))
+	bound := make(map[string]bool, len(rterms))
+	assigned := make([]bool, len(conjuncts))
+
+	if first != nil {
+		order = append(order, first)
+		bound[first.alias] = true
+	}
+
+	for len(order) < len(rterms) {
+		var next *reorderTerm
+		nextMethod := _REORDER_JOIN_NONE
+
+		for _, rterm := range rterms {
+			if bound[rterm.alias] {
+				continue
+			}
+
+			method := joinMethod(rterm, conjuncts, assigned, bound, len(bound) <= 1)
+			if method < nextMethod || (method == nextMethod && next != nil && rterm.size < next.size) {
+				next, nextMethod = rterm, method
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		for i, c := range conjuncts {
+			if !assigned[i] && c.on && joinConjunct(c, next, bound, len(bound) <= 1) {
+				assigned[i] = true
+			}
+		}
+
+		order = append(order, next)
+		bound[next.alias] = true
+	}
+
+	return order
+}
+
+func isOriginalOrder(order []*reorderTerm) bool {
+	for i, rterm := range order {
+		if rterm.pos != i {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+The ON-clause of the join to the last bound keyspace: the conjuncts
+of the ON-clauses of the run not yet placed whose keyspaces are bound.
+*/
+func joinOnclause(conjuncts []*reorderConjunct, assigned []bool, bound map[string]bool,
+	first bool) expression.Expression {
+
+	var terms expression.Expressions
+	for i, c := range conjuncts {
+		if assigned[i] || !c.on {
+			continue
+		}
+
+		place := len(c.refs) == 0 && first
+		if len(c.refs) > 0 {
+			place = true
+			for alias, _ := range c.refs {
+				if !bound[alias] {
+					place = false
+					break
+				}
+			}
+		}
+
+		if place {
+			assigned[i] = true
+			terms = append(terms, c.expr)
+		}
+	}
+
+	switch len(terms) {
+	case 0:
+		return expression.TRUE_EXPR
+	case 1:
+		return terms[0]
+	default:
+		return expression.NewAnd(terms...)
+	}
+}
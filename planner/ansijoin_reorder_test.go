@@ -0,0 +1,96 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/util"
+)
+
+func TestJoinReorder(t *testing.T) {
+	cases := []struct {
+		text     string
+		controls uint64
+		first    string // alias of the keyspace read first, if the statement can be planned
+	}{
+		// b is a single document, and a can be joined to it on its primary key
+		{`SELECT * FROM b0 AS a JOIN b1 AS b ON META(a).id = b.ak WHERE META(b).id = "1"`, 0, "b"},
+
+		// the joins are left as written
+		{`SELECT /*+ ORDERED */ * FROM b0 AS a JOIN b1 AS b ON META(a).id = b.ak WHERE META(b).id = "1"`, 0, ""},
+		{`SELECT * FROM b0 AS a JOIN b1 AS b ON META(a).id = b.ak WHERE META(b).id = "1"`,
+			util.N1QL_JOIN_REORDER, ""},
+
+		// b cannot be moved before the outer join
+		{`SELECT * FROM b0 AS a LEFT JOIN b2 AS c ON META(c).id = a.ck JOIN b1 AS b ON META(a).id = b.ak ` +
+			`WHERE META(b).id = "1"`, 0, ""},
+
+		// already in the best order
+		{`SELECT * FROM b1 AS b JOIN b0 AS a ON META(a).id = b.ak WHERE META(b).id = "1"`, 0, "b"},
+	}
+
+	for _, c := range cases {
+		plan, err := planOf(t, c.text, c.controls)
+		if c.first == "" {
+			if err == nil || !strings.Contains(err.Error(), "No index available for ANSI join term b") {
+				t.Errorf("Expected %s to be planned as written, got %v %s", c.text, err, plan)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unable to plan %s: %v", c.text, err)
+			continue
+		}
+		if !strings.Contains(plan, `{"#operator":"IndexScan","as":"`+c.first+`"`) ||
+			!strings.Contains(plan, `{"#operator":"Join","as":"a"`) {
+			t.Errorf("Expected %s to be read first for %s, got %s", c.first, c.text, plan)
+		}
+	}
+}
+
+func TestJoinReorderKeepsStatement(t *testing.T) {
+	ds, err := mock.NewDatastore("mock:keyspaces=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+
+	text := `SELECT * FROM b0 AS a JOIN b1 AS b ON META(a).id = b.ak WHERE META(b).id = "1"`
+	stmt, er := n1ql.ParseStatement(text)
+	if er != nil {
+		t.Fatalf("Unable to parse %s: %v", text, er)
+	}
+
+	node := stmt.(*algebra.Select).Subresult().(*algebra.Subselect)
+	from := node.From()
+
+	for i := 0; i < 2; i++ {
+		_, er = Build(stmt, ds, nil, "p0", false, nil, nil, datastore.INDEX_API_MAX, 0)
+		if er != nil {
+			t.Fatalf("Unable to plan %s: %v", text, er)
+		}
+	}
+
+	join, ok := node.From().(*algebra.AnsiJoin)
+	if node.From() != from || !ok || !strings.HasPrefix(from.String(), "`p0`:`b0` as `a` join `p0`:`b1` as `b`") {
+		t.Fatalf("Expected the FROM clause to be unchanged, got %v", node.From())
+	}
+	left := algebra.GetKeyspaceTerm(join.Left().(algebra.SimpleFromTerm))
+	right := algebra.GetKeyspaceTerm(join.Right())
+	if join.PrimaryTerm().Alias() != "a" || left.IsAnsiJoin() || !right.IsAnsiJoin() {
+		t.Errorf("Expected the keyspace terms to be unchanged")
+	}
+}
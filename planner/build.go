@@ -24,7 +24,21 @@ func Build(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	positionalArgs value.Values, indexApiVersion int, featureControls uint64) (plan.Operator, error) {
 	builder := newBuilder(datastore, systemstore, namespace, subquery, namedArgs, positionalArgs,
 		indexApiVersion, featureControls)
-	o, err := stmt.Accept(builder)
+	return builder.build(stmt)
+}
+
+func (this *builder) build(stmt algebra.Statement) (plan.Operator, error) {
+	// Views are expanded, in subqueries too, when the statement is
+	// planned; subqueries planned at execution time belong to the
+	// shared statement of the plan, and are left alone
+	if !this.subquery {
+		err := this.expandViews(stmt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	o, err := stmt.Accept(this)
 
 	if err != nil {
 		return nil, err
@@ -33,7 +47,7 @@ func Build(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	op := o.(plan.Operator)
 	_, is_prepared := o.(*plan.Prepared)
 
-	if !subquery && !is_prepared {
+	if !this.subquery && !is_prepared {
 		privs, er := stmt.Privileges()
 		if er != nil {
 			return nil, er
@@ -67,33 +81,44 @@ const (
 
 type builder struct {
 	indexPushDowns
-	datastore         datastore.Datastore
-	systemstore       datastore.Datastore
-	namespace         string
-	indexApiVersion   int
-	featureControls   uint64
-	subquery          bool
-	correlated        bool
-	maxParallelism    int
-	delayProjection   bool                  // Used to allow ORDER BY non-projected expressions
-	from              algebra.FromTerm      // Used for index selection
-	where             expression.Expression // Used for index selection
-	setOpDistinct     bool                  // Used for SETOP Distinct to apply DISTINCT on projection
-	children          []plan.Operator
-	subChildren       []plan.Operator
-	cover             expression.HasExpressions
-	node              expression.HasExpressions
-	coveringScans     []plan.CoveringOperator
-	coveredUnnests    map[*algebra.Unnest]bool
-	countScan         plan.CoveringOperator
-	skipDynamic       bool
-	requirePrimaryKey bool
-	orderScan         plan.SecondaryScan
-	namedArgs         map[string]value.Value
-	positionalArgs    value.Values
-	baseKeyspaces     map[string]*baseKeyspace
-	pushableOnclause  expression.Expression // combined ON-clause from all inner joins
-	builderFlags      uint32
+	datastore          datastore.Datastore
+	systemstore        datastore.Datastore
+	namespace          string
+	indexApiVersion    int
+	featureControls    uint64
+	subquery           bool
+	correlated         bool
+	maxParallelism     int
+	delayProjection    bool                  // Used to allow ORDER BY non-projected expressions
+	from               algebra.FromTerm      // Used for index selection
+	where              expression.Expression // Used for index selection
+	setOpDistinct      bool                  // Used for SETOP Distinct to apply DISTINCT on projection
+	children           []plan.Operator
+	subChildren        []plan.Operator
+	cover              expression.HasExpressions
+	node               expression.HasExpressions
+	coveringScans      []plan.CoveringOperator
+	coveredUnnests     map[*algebra.Unnest]bool
+	countScan          plan.CoveringOperator
+	skipDynamic        bool
+	requirePrimaryKey  bool
+	orderScan          plan.SecondaryScan
+	namedArgs          map[string]value.Value
+	positionalArgs     value.Values
+	baseKeyspaces      map[string]*baseKeyspace
+	pushableOnclause   expression.Expression // combined ON-clause from all inner joins
+	builderFlags       uint32
+	optimHints         []*algebra.OptimHint            // all optimizer hints, for EXPLAIN
+	keyspaceHints      map[string][]*algebra.OptimHint // INDEX, USE_HASH and USE_NL hints by alias
+	indexHints         map[string]algebra.IndexRefs    // indexes of followed INDEX hints by alias
+	joinHints          map[string]algebra.JoinHint     // join methods of USE_HASH and USE_NL hints by alias
+	orderedHint        bool                            // ORDERED hint of the query block
+	maxParallelismHint int                             // MAX_PARALLELISM hint of the query block
+	queryBlocks        int                             // query blocks whose hints have been processed
+	resultCacheHint    *algebra.OptimHint              // RESULT_CACHE hint of the statement
+	materializedView   bool                            // planning a query answered from a materialized view
+	expandingViews     map[string]bool                 // views being expanded, as namespace:name
+	viewKeyspaces      []string                        // views expanded, as namespace:name
 }
 
 type indexPushDowns struct {
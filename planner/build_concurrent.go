@@ -0,0 +1,56 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+Mark the operators whose subplans are independent of the rest of the
+plan, so that they can be run concurrently with it: the right-hand
+side of hash joins and nests, which builds the hash table, and the
+uncorrelated subqueries of projections. The executor runs them
+concurrently within max_parallelism; MAX_PARALLELISM(1) keeps the
+query block serial.
+*/
+func (this *builder) markConcurrent(op plan.Operator) {
+	if this.maxParallelismHint == 1 {
+		return
+	}
+
+	switch op := op.(type) {
+	case *plan.HashJoin:
+		op.SetConcurrent()
+	case *plan.HashNest:
+		op.SetConcurrent()
+	case *plan.HashSemiJoin:
+		op.SetConcurrent()
+	case *plan.InitialProject:
+		if hasUncorrelatedSubqueries(op.Projection().Expressions()) {
+			op.SetConcurrent()
+		}
+	}
+}
+
+func hasUncorrelatedSubqueries(exprs expression.Expressions) bool {
+	subqueries, err := expression.ListSubqueries(exprs, false)
+	if err != nil {
+		return false
+	}
+
+	for _, subquery := range subqueries {
+		if !subquery.IsCorrelated() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+)
+
+func projectOf(t *testing.T, text string) *plan.InitialProject {
+	stmt, err := n1ql.ParseStatement(text)
+	if err != nil {
+		t.Fatalf("Unable to parse %s: %v", text, err)
+	}
+	return plan.NewInitialProject(stmt.(*algebra.Select).Subresult().(*algebra.Subselect).Projection())
+}
+
+func TestMarkConcurrent(t *testing.T) {
+	uncorrelated := "SELECT k.a, (SELECT RAW COUNT(*) FROM other) AS n FROM k"
+	cases := map[string]bool{
+		uncorrelated:             true,
+		"SELECT k.a, k.b FROM k": false,
+		"SELECT (SELECT RAW o.b FROM other AS o USE KEYS k.id) AS b FROM k": false,
+	}
+	for text, expected := range cases {
+		project := projectOf(t, text)
+		(&builder{}).markConcurrent(project)
+		if project.Concurrent() != expected {
+			t.Errorf("Expected concurrent %v for %s", expected, text)
+		}
+	}
+
+	project := projectOf(t, uncorrelated)
+	(&builder{maxParallelismHint: 1}).markConcurrent(project)
+	if project.Concurrent() {
+		t.Errorf("Expected MAX_PARALLELISM(1) to keep the projection serial")
+	}
+
+	project = projectOf(t, uncorrelated)
+	(&builder{}).markConcurrent(project)
+	bytes, err := json.Marshal(project)
+	if err != nil {
+		t.Fatalf("Unable to marshal projection: %v", err)
+	}
+	unmarshalled := &plan.InitialProject{}
+	err = json.Unmarshal(bytes, unmarshalled)
+	if err != nil || !unmarshalled.Concurrent() {
+		t.Errorf("Expected the mark to survive marshalling: %s", bytes)
+	}
+}
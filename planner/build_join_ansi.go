@@ -20,6 +20,14 @@ import (
 )
 
 func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, err error) {
+	if node.RightOuter() {
+		return this.buildAnsiRightOuterJoin(node)
+	}
+
+	if node.Semi() {
+		return this.buildAnsiSemiJoin(node)
+	}
+
 	right := node.Right()
 
 	if ksterm := algebra.GetKeyspaceTerm(right); ksterm != nil {
@@ -36,7 +44,7 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 		if util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN) {
 			// currently only consider hash join when USE HASH join hint is specified
 			var hjoin *plan.HashJoin
-			if right.PreferHash() {
+			if this.preferHash(right) {
 				hjoin, err = this.buildHashJoin(node)
 				if hjoin != nil || err != nil {
 					return hjoin, err
@@ -47,7 +55,15 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 		right.SetUnderNL()
 		scans, primaryJoinKeys, newOnclause, err := this.buildAnsiJoinScan(right, node.Onclause())
 		if err != nil {
-			return nil, err
+			// CROSS JOIN with no index available for the WHERE clause:
+			// scan the whole right-hand side for each left-hand side row
+			if e, ok := err.(errors.Error); ok && e.Code() == errors.NO_ANSI_JOIN && isCrossJoin(node) {
+				right.SetUnderHash()
+				scans, primaryJoinKeys, newOnclause, err = this.buildAnsiJoinScan(right, node.Onclause())
+			}
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		if newOnclause != nil {
@@ -73,7 +89,7 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 
 		// make a copy of the original KeyspaceTerm with the extra
 		// primaryJoinKeys and construct a JOIN operator
-		newKeyspaceTerm := algebra.NewKeyspaceTerm(right.Namespace(), right.Keyspace(), right.As(), nil, right.Indexes())
+		newKeyspaceTerm := algebra.NewKeyspaceTerm(right.Namespace(), right.Keyspace(), right.As(), nil, this.termIndexes(right))
 		newKeyspaceTerm.SetProperty(right.Property())
 		newKeyspaceTerm.SetJoinKeys(primaryJoinKeys)
 		return plan.NewJoinFromAnsi(keyspace, newKeyspaceTerm, node.Outer()), nil
@@ -86,7 +102,7 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 		if util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN) {
 			// for expression term and subquery term, consider hash join
 			// even without USE HASH hint, as long as USE NL is not specified
-			if !right.PreferNL() {
+			if !this.preferNL(right) {
 				hjoin, err := this.buildHashJoin(node)
 				if hjoin != nil || err != nil {
 					return hjoin, err
@@ -109,6 +125,133 @@ func (this *builder) buildAnsiJoin(node *algebra.AnsiJoin) (op plan.Operator, er
 	}
 }
 
+/*
+RIGHT and FULL OUTER JOIN. The right-hand side is read only once, independently
+of the left-hand side, so that its rows not matched by any left-hand side row
+can be returned after all the left-hand side rows are joined.
+*/
+func (this *builder) buildAnsiRightOuterJoin(node *algebra.AnsiJoin) (op plan.Operator, err error) {
+	right := node.Right()
+
+	if ksterm := algebra.GetKeyspaceTerm(right); ksterm != nil {
+		right = ksterm
+	}
+
+	switch right := right.(type) {
+	case *algebra.KeyspaceTerm:
+		if right.Keys() != nil && right.Keys().Static() == nil {
+			return nil, errors.NewRightOuterJoinCorrelatedError(right.Alias())
+		}
+	case *algebra.ExpressionTerm:
+		if right.IsCorrelated() {
+			return nil, errors.NewRightOuterJoinCorrelatedError(right.Alias())
+		}
+	case *algebra.SubqueryTerm:
+		if right.Subquery().IsCorrelated() {
+			return nil, errors.NewRightOuterJoinCorrelatedError(right.Alias())
+		}
+	default:
+		return nil, errors.NewPlanInternalError(fmt.Sprintf("buildAnsiRightOuterJoin: Unexpected right-hand side node type"))
+	}
+
+	// the ON-clause must not restrict the right-hand side, so, unlike for
+	// other outer joins, its filters are not added to the keyspace filters
+	err = this.processOnclause(right.Alias(), node.Onclause(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN) && !this.preferNL(right) {
+		child, buildExprs, probeExprs, aliases, err := this.buildHashJoinScan(right, node.Outer(),
+			node.Onclause(), "join")
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			return plan.NewHashJoin(node, child, buildExprs, probeExprs, aliases), nil
+		}
+	}
+
+	var scans []plan.Operator
+	var newOnclause expression.Expression
+
+	if ksterm, ok := right.(*algebra.KeyspaceTerm); ok {
+		ksterm.SetUnderNL()
+		ksterm.SetUnderHash()
+		scans, _, newOnclause, err = this.buildAnsiJoinScan(ksterm, node.Onclause())
+		if err == nil && len(scans) == 0 {
+			err = errors.NewNoAnsiJoinError(ksterm.Alias(), "join")
+		}
+	} else {
+		scans, newOnclause, err = this.buildAnsiJoinSimpleFromTerm(right, node.Onclause())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if newOnclause != nil {
+		node.SetOnclause(newOnclause)
+	}
+
+	return plan.NewNLJoin(node, plan.NewSequence(scans...)), nil
+}
+
+/*
+Semi-join and anti-join, from a subquery of the WHERE clause. A
+nested-loop semi-join is used if an index is available for the
+ON-clause, and a hash semi-join building the right-hand side
+otherwise, or with a USE HASH hint, when hash join is enabled.
+*/
+func (this *builder) buildAnsiSemiJoin(node *algebra.AnsiJoin) (op plan.Operator, err error) {
+	right := algebra.GetKeyspaceTerm(node.Right())
+	if right == nil {
+		return nil, errors.NewPlanInternalError(fmt.Sprintf("buildAnsiSemiJoin: Unexpected right-hand side node type"))
+	}
+
+	err = this.processOnclause(right.Alias(), node.Onclause(), node.Outer())
+	if err != nil {
+		return nil, err
+	}
+
+	hash := util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN)
+	if !hash || !this.preferHash(right) {
+		right.SetUnderNL()
+		scans, _, newOnclause, err := this.buildAnsiJoinScan(right, node.Onclause())
+		if err != nil {
+			if e, ok := err.(errors.Error); !ok || e.Code() != errors.NO_ANSI_JOIN {
+				return nil, err
+			}
+		} else if len(scans) > 0 {
+			if newOnclause != nil {
+				node.SetOnclause(newOnclause)
+			}
+			return plan.NewNLSemiJoin(node, plan.NewSequence(scans...)), nil
+		}
+		right.UnsetUnderNL()
+	}
+
+	if !hash {
+		return nil, errors.NewNoAnsiJoinError(node.Alias(), "join")
+	}
+
+	child, buildExprs, probeExprs, _, err := this.buildHashJoinScan(node.Right(), node.Outer(),
+		node.Onclause(), "join")
+	if err != nil {
+		return nil, err
+	}
+
+	if child == nil {
+		return nil, errors.NewNoAnsiJoinError(node.Alias(), "join")
+	}
+
+	return plan.NewHashSemiJoin(node, child, buildExprs, probeExprs), nil
+}
+
+func isCrossJoin(node *algebra.AnsiJoin) bool {
+	cpred := node.Onclause().Value()
+	return cpred != nil && cpred.Truth()
+}
+
 func (this *builder) buildAnsiNest(node *algebra.AnsiNest) (op plan.Operator, err error) {
 	right := node.Right()
 
@@ -126,7 +269,7 @@ func (this *builder) buildAnsiNest(node *algebra.AnsiNest) (op plan.Operator, er
 		if util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN) {
 			// currently only consider hash nest when USE HASH join hint is specified
 			var hnest *plan.HashNest
-			if right.PreferHash() {
+			if this.preferHash(right) {
 				hnest, err = this.buildHashNest(node)
 				if hnest != nil || err != nil {
 					return hnest, err
@@ -163,7 +306,7 @@ func (this *builder) buildAnsiNest(node *algebra.AnsiNest) (op plan.Operator, er
 
 		// make a copy of the original KeyspaceTerm with the extra
 		// primaryJoinKeys and construct a NEST operator
-		newKeyspaceTerm := algebra.NewKeyspaceTerm(right.Namespace(), right.Keyspace(), right.As(), nil, right.Indexes())
+		newKeyspaceTerm := algebra.NewKeyspaceTerm(right.Namespace(), right.Keyspace(), right.As(), nil, this.termIndexes(right))
 		newKeyspaceTerm.SetProperty(right.Property())
 		newKeyspaceTerm.SetJoinKeys(primaryJoinKeys)
 		return plan.NewNestFromAnsi(keyspace, newKeyspaceTerm, node.Outer()), nil
@@ -171,7 +314,7 @@ func (this *builder) buildAnsiNest(node *algebra.AnsiNest) (op plan.Operator, er
 		if util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN) {
 			// for expression term and subquery term, consider hash join
 			// even without USE HASH hint, as long as USE NL is not specified
-			if !right.PreferNL() {
+			if !this.preferNL(right) {
 				hnest, err := this.buildHashNest(node)
 				if hnest != nil || err != nil {
 					return hnest, err
@@ -376,7 +519,7 @@ func (this *builder) buildAnsiJoinScan(node *algebra.KeyspaceTerm, onclause expr
 }
 
 func (this *builder) buildHashJoin(node *algebra.AnsiJoin) (hjoin *plan.HashJoin, err error) {
-	child, buildExprs, probeExprs, aliases, err := this.buildHashJoinScan(node.Right(), node.Outer(), "join")
+	child, buildExprs, probeExprs, aliases, err := this.buildHashJoinScan(node.Right(), node.Outer(), nil, "join")
 	if err != nil || child == nil {
 		// cannot do hash join
 		return nil, err
@@ -385,7 +528,7 @@ func (this *builder) buildHashJoin(node *algebra.AnsiJoin) (hjoin *plan.HashJoin
 }
 
 func (this *builder) buildHashNest(node *algebra.AnsiNest) (hnest *plan.HashNest, err error) {
-	child, buildExprs, probeExprs, aliases, err := this.buildHashJoinScan(node.Right(), node.Outer(), "nest")
+	child, buildExprs, probeExprs, aliases, err := this.buildHashJoinScan(node.Right(), node.Outer(), nil, "nest")
 	if err != nil || child == nil {
 		// cannot do hash nest
 		return nil, err
@@ -396,8 +539,14 @@ func (this *builder) buildHashNest(node *algebra.AnsiNest) (hnest *plan.HashNest
 	return plan.NewHashNest(node, child, buildExprs, probeExprs, aliases[0]), nil
 }
 
-func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer bool, op string) (
-	child plan.Operator, buildExprs expression.Expressions, probeExprs expression.Expressions, buildAliases []string, err error) {
+/*
+For RIGHT and FULL OUTER JOIN, rightOnclause is the ON-clause, from which the join
+predicates are taken since its filters are not added to the keyspace filters, and
+the right-hand side is always the build side.
+*/
+func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer bool, rightOnclause expression.Expression,
+	op string) (child plan.Operator, buildExprs expression.Expressions, probeExprs expression.Expressions,
+	buildAliases []string, err error) {
 
 	var ksterm *algebra.KeyspaceTerm
 	var defaultBuildRight bool
@@ -433,16 +582,16 @@ func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer bool,
 	}
 
 	buildRight := false
-	joinHint := right.JoinHint()
+	joinHint := this.termJoinHint(right)
 	if joinHint == algebra.USE_HASH_BUILD {
 		buildRight = true
 	} else if joinHint == algebra.USE_HASH_PROBE {
 		// in case of outer join, cannot build on dominant side
 		// also in case of nest, can only build on right-hand-side
-		if outer || op == "nest" {
+		if outer || rightOnclause != nil || op == "nest" {
 			return nil, nil, nil, nil, nil
 		}
-	} else if defaultBuildRight {
+	} else if defaultBuildRight || rightOnclause != nil {
 		// for expression term and subquery term, if no USE HASH hint is
 		// specified, then consider hash join/nest with the right-hand side
 		// as build side
@@ -461,12 +610,23 @@ func (this *builder) buildHashJoinScan(right algebra.SimpleFromTerm, outer bool,
 	rightExprs := make(expression.Expressions, 0, 4)
 
 	// look for equality join predicates
-	for _, fltr := range baseKeyspace.filters {
-		if !fltr.isJoin() {
-			continue
+	var joinPreds expression.Expressions
+	if rightOnclause != nil {
+		joinPreds = expression.Expressions{rightOnclause}
+		if and, ok := rightOnclause.(*expression.And); ok {
+			and, _ = flattenAnd(and)
+			joinPreds = and.Operands()
 		}
+	} else {
+		for _, fltr := range baseKeyspace.filters {
+			if fltr.isJoin() {
+				joinPreds = append(joinPreds, fltr.fltrExpr)
+			}
+		}
+	}
 
-		if eqFltr, ok := fltr.fltrExpr.(*expression.Eq); ok {
+	for _, pred := range joinPreds {
+		if eqFltr, ok := pred.(*expression.Eq); ok {
 			if !eqFltr.First().Indexable() || !eqFltr.Second().Indexable() {
 				continue
 			}
@@ -0,0 +1,152 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/util"
+)
+
+func subselectOf(t *testing.T, text string) *algebra.Subselect {
+	stmt, err := n1ql.ParseStatement(text)
+	if err != nil {
+		t.Fatalf("Unable to parse %s: %v", text, err)
+	}
+	return stmt.(*algebra.Select).Subresult().(*algebra.Subselect)
+}
+
+/*
+Plans a statement against a mock datastore with keyspaces b0, b1 and b2,
+which only have primary indexes.
+*/
+func planOf(t *testing.T, text string, featureControls uint64) (string, error) {
+	ds, err := mock.NewDatastore("mock:keyspaces=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+
+	stmt, er := n1ql.ParseStatement(text)
+	if er != nil {
+		t.Fatalf("Unable to parse %s: %v", text, er)
+	}
+
+	op, er := Build(stmt, ds, nil, "p0", false, nil, nil, datastore.INDEX_API_MAX, featureControls)
+	if er != nil {
+		return "", er
+	}
+
+	bytes, er := json.Marshal(op)
+	if er != nil {
+		t.Fatalf("Unable to marshal plan of %s: %v", text, er)
+	}
+	return string(bytes), nil
+}
+
+func TestRightOuterJoinPlans(t *testing.T) {
+	cases := []struct {
+		text     string
+		controls uint64
+		expected []string
+	}{
+		{"SELECT * FROM b0 AS a FULL JOIN b1 AS b ON a.x = b.y", 0,
+			[]string{`"#operator":"HashJoin","build_aliases":["b"]`, `"outer":true`, `"right_outer":true`}},
+		{"SELECT * FROM b0 AS a FULL JOIN b1 AS b ON a.x = b.y", util.N1QL_HASH_JOIN,
+			[]string{`"#operator":"NestedLoopJoin"`, `"outer":true`, `"right_outer":true`}},
+		{"SELECT * FROM b0 AS a JOIN b2 AS c USE HASH(BUILD) ON a.x = c.x RIGHT JOIN b1 AS b ON a.x = b.y", 0,
+			[]string{`"build_aliases":["b"]`, `"right_outer":true`}},
+		{"SELECT * FROM b0 AS a CROSS JOIN b1 AS b", 0,
+			[]string{`"#operator":"NestedLoopJoin","alias":"b","on_clause":"true"`}},
+	}
+
+	for _, c := range cases {
+		plan, err := planOf(t, c.text, c.controls)
+		if err != nil {
+			t.Errorf("Unable to plan %s: %v", c.text, err)
+			continue
+		}
+		for _, expected := range c.expected {
+			if !strings.Contains(plan, expected) {
+				t.Errorf("Expected %s in the plan of %s, got %s", expected, c.text, plan)
+			}
+		}
+	}
+
+	// the right-hand side is read once, independently of the left-hand side
+	_, err := planOf(t, `SELECT * FROM [{"x":[1]}] AS a FULL JOIN a.x AS b ON b = 1`, 0)
+	if err == nil || !strings.Contains(err.Error(), "cannot depend on other terms") {
+		t.Errorf("Expected a correlated right-hand side to be rejected, got %v", err)
+	}
+}
+
+func TestRightOuterJoinWhereFilters(t *testing.T) {
+	cases := []struct {
+		text       string
+		rightOuter bool
+		outer      bool
+		filters    map[string]int // WHERE clause filters left on each keyspace
+	}{
+		// neither side rejects nulls: no WHERE clause filter is pushed down
+		{"SELECT * FROM b0 AS a FULL JOIN b1 AS b ON a.x = b.y WHERE a.z IS MISSING AND b.w IS NOT VALUED",
+			true, true, map[string]int{"a": 0, "b": 0}},
+		// the preserved right-hand side keeps its filters
+		{"SELECT * FROM b0 AS a JOIN b2 AS c ON a.x = c.x RIGHT JOIN b1 AS b ON a.x = b.y " +
+			"WHERE a.z IS MISSING AND b.w = 1",
+			true, false, map[string]int{"a": 0, "b": 1, "c": 0}},
+		// rejects nulls on the left-hand side: LEFT OUTER JOIN
+		{"SELECT * FROM b0 AS a FULL JOIN b1 AS b ON a.x = b.y WHERE a.z = 1 AND b.w IS MISSING",
+			false, true, map[string]int{"a": 1, "b": 1}},
+		// rejects nulls on the right-hand side: RIGHT OUTER JOIN
+		{"SELECT * FROM b0 AS a FULL JOIN b1 AS b ON a.x = b.y WHERE a.z IS MISSING AND b.w = 1",
+			true, false, map[string]int{"a": 0, "b": 1}},
+	}
+
+	for _, c := range cases {
+		node := subselectOf(t, c.text)
+
+		baseKeyspaces := make(map[string]*baseKeyspace, _MAP_KEYSPACE_CAP)
+		_, err := node.From().Accept(newKeyspaceFinder(baseKeyspaces, node.From().PrimaryTerm().Alias()))
+		if err != nil {
+			t.Fatalf("Unable to find keyspaces of %s: %v", c.text, err)
+		}
+		_, err = ClassifyExpr(node.Where(), baseKeyspaces, false)
+		if err != nil {
+			t.Fatalf("Unable to classify WHERE clause of %s: %v", c.text, err)
+		}
+		_, err = node.From().Accept(newAnsijoinOuterToInner(baseKeyspaces, nil, node.Where()))
+		if err != nil {
+			t.Fatalf("Unable to transform %s: %v", c.text, err)
+		}
+
+		join := node.From().(*algebra.AnsiJoin)
+		if join.RightOuter() != c.rightOuter || join.Outer() != c.outer {
+			t.Errorf("Expected right outer %v and outer %v for %s, got %v and %v",
+				c.rightOuter, c.outer, c.text, join.RightOuter(), join.Outer())
+		}
+
+		for alias, expected := range c.filters {
+			n := 0
+			for _, fltr := range baseKeyspaces[alias].filters {
+				if !fltr.isOnclause() {
+					n++
+				}
+			}
+			if n != expected {
+				t.Errorf("Expected %d WHERE clause filters on %s for %s, got %d", expected, alias, c.text, n)
+			}
+		}
+	}
+}
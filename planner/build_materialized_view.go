@@ -0,0 +1,80 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/views"
+)
+
+func (this *builder) VisitCreateMaterializedView(stmt *algebra.CreateMaterializedView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateMaterializedView(keyspace, stmt.Text()), nil
+}
+
+func (this *builder) VisitDropMaterializedView(stmt *algebra.DropMaterializedView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewDropMaterializedView(keyspace), nil
+}
+
+func (this *builder) VisitRefreshMaterializedView(stmt *algebra.RefreshMaterializedView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	keyspace, err := this.getNameKeyspace(ksref.Namespace(), ksref.Keyspace())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewRefreshMaterializedView(keyspace), nil
+}
+
+/*
+Plan a SELECT identical to the definition of a materialized view both
+ways: reading the view, and running the query, for when the view is not
+fresh. Returns nil if no view matches.
+*/
+func (this *builder) buildMaterializedView(stmt *algebra.Select) (plan.Operator, error) {
+	if this.subquery || this.materializedView {
+		return nil, nil
+	}
+
+	view, rewritten := views.Rewrite(stmt, this.namespace)
+	if view == nil {
+		return nil, nil
+	}
+
+	this.materializedView = true
+	defer func() {
+		this.materializedView = false
+	}()
+
+	query, err := stmt.Accept(this)
+	if err != nil {
+		return nil, err
+	}
+
+	read, err := rewritten.Accept(this)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewMaterializedView(view.Namespace, view.Name, view.Definition,
+		read.(plan.Operator), query.(plan.Operator)), nil
+}
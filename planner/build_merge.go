@@ -150,6 +150,7 @@ func (this *builder) VisitMerge(stmt *algebra.Merge) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		this.markConcurrent(join)
 
 		switch join := join.(type) {
 		case *plan.NLJoin:
@@ -17,10 +17,20 @@ import (
 )
 
 func (this *builder) beginMutate(keyspace datastore.Keyspace, ksref *algebra.KeyspaceRef,
-	keys expression.Expression, indexes algebra.IndexRefs, limit expression.Expression, mustFetch bool) error {
+	keys expression.Expression, indexes algebra.IndexRefs, limit expression.Expression, mustFetch bool,
+	optimHints *algebra.OptimHints) error {
 	ksref.SetDefaultNamespace(this.namespace)
 	term := algebra.NewKeyspaceTerm(ksref.Namespace(), ksref.Keyspace(), ksref.As(), keys, indexes)
 
+	// Apply optimizer hints
+	var terms map[string]algebra.SimpleFromTerm
+	if optimHints != nil {
+		terms = map[string]algebra.SimpleFromTerm{term.Alias(): term}
+	}
+	this.processOptimHints(optimHints, terms)
+	this.maxParallelism = this.maxParallelismHint
+	defer this.finishOptimHints()
+
 	this.children = make([]plan.Operator, 0, 8)
 	this.subChildren = make([]plan.Operator, 0, 8)
 
@@ -0,0 +1,311 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
+)
+
+/*
+Apply the optimizer hints of a query block, given the terms of its
+FROM clause by alias. INDEX, USE_HASH and USE_NL are applied to the
+keyspace terms as USE INDEX, USE HASH and USE NL would be, and
+NO_INDEX excludes indexes from index selection; whether they are
+followed is only known once the keyspace is planned. MAX_PARALLELISM
+and NO_COVER apply to the whole query block, and RESULT_CACHE to the
+whole statement, when given in its first query block.
+
+The statement is not changed: the planner records its choices, and
+the state of each hint, in copies of the hints kept by the builder.
+*/
+func (this *builder) processOptimHints(hints *algebra.OptimHints, terms map[string]algebra.SimpleFromTerm) {
+	this.keyspaceHints = nil
+	this.indexHints = nil
+	this.joinHints = nil
+	this.orderedHint = false
+	this.maxParallelismHint = 0
+	this.queryBlocks++
+
+	for _, hint := range hints.Hints() {
+		hint = hint.Copy()
+		this.optimHints = append(this.optimHints, hint)
+		if hint.State() == algebra.HINT_STATE_IGNORED {
+			continue
+		}
+
+		alias := hint.Keyspace()
+		switch hint.Name() {
+		case algebra.HINT_INDEX, algebra.HINT_NO_INDEX:
+			ksterm := algebra.GetKeyspaceTerm(terms[alias])
+			if ksterm == nil {
+				hint.Ignore("keyspace " + alias + " not found")
+			} else if ksterm.Keys() != nil {
+				hint.Ignore("USE KEYS given for " + alias)
+			} else if hint.Name() == algebra.HINT_NO_INDEX {
+				this.addKeyspaceHint(alias, hint)
+				hint.Follow()
+			} else if len(ksterm.Indexes()) > 0 {
+				hint.Ignore("USE INDEX given for " + alias)
+			} else if indexes := this.hintIndexes(ksterm, hint); len(indexes) > 0 {
+				if this.indexHints == nil {
+					this.indexHints = make(map[string]algebra.IndexRefs, _MAP_KEYSPACE_CAP)
+				}
+				this.indexHints[alias] = indexes
+				this.addKeyspaceHint(alias, hint)
+			}
+		case algebra.HINT_USE_HASH, algebra.HINT_USE_NL:
+			term := terms[alias]
+			if term == nil {
+				hint.Ignore("keyspace " + alias + " not found")
+			} else if !term.IsAnsiJoinOp() {
+				hint.Ignore(alias + " is not the right-hand side of an ANSI join")
+			} else if term.JoinHint() != algebra.JOIN_HINT_NONE {
+				hint.Ignore("join hint given for " + alias)
+			} else if hint.Name() == algebra.HINT_USE_NL {
+				this.addJoinHint(alias, algebra.USE_NL, hint)
+			} else if !util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN) {
+				hint.Ignore("hash join is not enabled")
+			} else {
+				if hint.Option() == "PROBE" {
+					this.addJoinHint(alias, algebra.USE_HASH_PROBE, hint)
+				} else {
+					this.addJoinHint(alias, algebra.USE_HASH_BUILD, hint)
+				}
+			}
+		case algebra.HINT_ORDERED:
+			if len(terms) < 2 {
+				hint.Ignore("no joins")
+			} else {
+				this.orderedHint = true
+				hint.Follow()
+			}
+		case algebra.HINT_MAX_PARALLELISM:
+			this.maxParallelismHint = hint.Value()
+			hint.Follow()
+		case algebra.HINT_NO_COVER:
+			this.cover = nil
+			hint.Follow()
+		case algebra.HINT_RESULT_CACHE:
+			if _, ok := this.node.(*algebra.Subselect); !ok {
+				hint.Ignore("only query results can be cached")
+			} else if this.subquery || this.queryBlocks > 1 {
+				hint.Ignore("not the first query block of the statement")
+			} else {
+				this.resultCacheHint = hint
+				hint.Follow()
+			}
+		}
+	}
+}
+
+/*
+The indexes of an INDEX hint which exist on the keyspace.
+*/
+func (this *builder) hintIndexes(ksterm *algebra.KeyspaceTerm, hint *algebra.OptimHint) algebra.IndexRefs {
+	keyspace, err := this.getTermKeyspace(ksterm)
+	if err != nil {
+		hint.Ignore(err.Error())
+		return nil
+	}
+
+	indexer, err := keyspace.Indexer(datastore.DEFAULT)
+	if err != nil {
+		hint.Ignore(err.Error())
+		return nil
+	}
+
+	var indexes algebra.IndexRefs
+	for _, name := range hint.Indexes() {
+		if _, err := indexer.IndexByName(name); err == nil {
+			indexes = append(indexes, algebra.NewIndexRef(name, datastore.DEFAULT))
+		}
+	}
+
+	if len(indexes) == 0 {
+		hint.Ignore("no index in the hint exists on " + ksterm.Alias())
+	}
+
+	return indexes
+}
+
+func (this *builder) addKeyspaceHint(alias string, hint *algebra.OptimHint) {
+	if this.keyspaceHints == nil {
+		this.keyspaceHints = make(map[string][]*algebra.OptimHint, _MAP_KEYSPACE_CAP)
+	}
+	this.keyspaceHints[alias] = append(this.keyspaceHints[alias], hint)
+}
+
+func (this *builder) addJoinHint(alias string, joinHint algebra.JoinHint, hint *algebra.OptimHint) {
+	if this.joinHints == nil {
+		this.joinHints = make(map[string]algebra.JoinHint, _MAP_KEYSPACE_CAP)
+	}
+	this.joinHints[alias] = joinHint
+	this.addKeyspaceHint(alias, hint)
+}
+
+/*
+The indexes of the keyspace term: those of its USE INDEX clause, or of
+its INDEX hint.
+*/
+func (this *builder) termIndexes(node *algebra.KeyspaceTerm) algebra.IndexRefs {
+	if indexes := node.Indexes(); len(indexes) > 0 {
+		return indexes
+	}
+	return this.indexHints[node.Alias()]
+}
+
+/*
+The join method of the term: that of its USE HASH or USE NL clause, or
+of its USE_HASH or USE_NL hint.
+*/
+func (this *builder) termJoinHint(term algebra.SimpleFromTerm) algebra.JoinHint {
+	if joinHint := term.JoinHint(); joinHint != algebra.JOIN_HINT_NONE {
+		return joinHint
+	}
+	return this.joinHints[term.Alias()]
+}
+
+func (this *builder) preferHash(term algebra.SimpleFromTerm) bool {
+	joinHint := this.termJoinHint(term)
+	return joinHint == algebra.USE_HASH_BUILD || joinHint == algebra.USE_HASH_PROBE
+}
+
+func (this *builder) preferNL(term algebra.SimpleFromTerm) bool {
+	return this.termJoinHint(term) == algebra.USE_NL
+}
+
+/*
+The FROM terms of a query block, by alias, for its optimizer hints.
+*/
+func collectHintTerms(from algebra.FromTerm, terms map[string]algebra.SimpleFromTerm) {
+	switch from := from.(type) {
+	case algebra.SimpleFromTerm:
+		terms[from.Alias()] = from
+	case *algebra.AnsiJoin:
+		collectHintTerms(from.Left(), terms)
+		terms[from.Alias()] = from.Right()
+	case *algebra.AnsiNest:
+		collectHintTerms(from.Left(), terms)
+		terms[from.Alias()] = from.Right()
+	case algebra.JoinTerm:
+		collectHintTerms(from.Left(), terms)
+	}
+}
+
+/*
+Exclude the indexes of NO_INDEX hints on the keyspace.
+*/
+func (this *builder) excludeIndexes(node *algebra.KeyspaceTerm, indexes []datastore.Index) []datastore.Index {
+	hints := this.keyspaceHints[node.Alias()]
+	if len(hints) == 0 {
+		return indexes
+	}
+
+	rv := indexes[:0]
+	for _, index := range indexes {
+		if excludedIndex(hints, index.Name()) == nil {
+			rv = append(rv, index)
+		}
+	}
+
+	return rv
+}
+
+/*
+The NO_INDEX hint excluding the index, if any.
+*/
+func excludedIndex(hints []*algebra.OptimHint, name string) *algebra.OptimHint {
+	for _, hint := range hints {
+		if hint.Name() != algebra.HINT_NO_INDEX {
+			continue
+		}
+
+		for _, index := range hint.Indexes() {
+			if index == name {
+				return hint
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+A primary scan is the scan of last resort, and is used even if its
+index is excluded by NO_INDEX.
+*/
+func (this *builder) markPrimaryIndex(alias string, primary datastore.Index) {
+	if hint := excludedIndex(this.keyspaceHints[alias], primary.Name()); hint != nil {
+		hint.Ignore("primary index " + primary.Name() + " is needed for " + alias)
+	}
+}
+
+/*
+Record whether the indexes of an INDEX hint were used for the keyspace.
+*/
+func (this *builder) markIndexHints(alias string, used bool) {
+	for _, hint := range this.keyspaceHints[alias] {
+		if hint.Name() != algebra.HINT_INDEX {
+			continue
+		}
+
+		if used {
+			hint.Follow()
+		} else {
+			hint.Ignore("no index in the hint is usable for " + alias)
+		}
+	}
+}
+
+/*
+Record whether the join of the keyspace follows its USE_HASH or USE_NL
+hint.
+*/
+func (this *builder) markJoinHints(alias string, op plan.Operator) {
+	hash := false
+	switch op.(type) {
+	case *plan.HashJoin, *plan.HashNest, *plan.HashSemiJoin:
+		hash = true
+	}
+
+	for _, hint := range this.keyspaceHints[alias] {
+		switch hint.Name() {
+		case algebra.HINT_USE_HASH:
+			if hash {
+				hint.Follow()
+			} else {
+				hint.Ignore("hash join is not possible for " + alias)
+			}
+		case algebra.HINT_USE_NL:
+			if !hash {
+				hint.Follow()
+			} else {
+				hint.Ignore("nested-loop join is not possible for " + alias)
+			}
+		}
+	}
+}
+
+/*
+Hints of the query block which were not used by the plan, such as the
+hints of a keyspace whose scan does not depend on the hint.
+*/
+func (this *builder) finishOptimHints() {
+	for alias, hints := range this.keyspaceHints {
+		for _, hint := range hints {
+			if hint.State() == algebra.HINT_STATE_UNKNOWN {
+				hint.Ignore("not applicable to the plan for " + alias)
+			}
+		}
+	}
+}
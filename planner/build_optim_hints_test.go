@@ -0,0 +1,70 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+)
+
+func TestOptimHintsKeepStatement(t *testing.T) {
+	ds, err := mock.NewDatastore("mock:keyspaces=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+
+	text := "EXPLAIN SELECT /*+ INDEX(a #primary) USE_NL(b) ORDERED INDEX(c #primary) */ * " +
+		"FROM b0 AS a JOIN b1 AS b ON META(b).id = a.bk WHERE a.x = 1"
+	stmt, er := n1ql.ParseStatement(text)
+	if er != nil {
+		t.Fatalf("Unable to parse %s: %v", text, er)
+	}
+
+	node := stmt.(*algebra.Explain).Statement().(*algebra.Select).Subresult().(*algebra.Subselect)
+	join := node.From().(*algebra.AnsiJoin)
+
+	for i := 0; i < 2; i++ {
+		op, er := Build(stmt, ds, nil, "p0", false, nil, nil, datastore.INDEX_API_MAX, 0)
+		if er != nil {
+			t.Fatalf("Unable to plan %s: %v", text, er)
+		}
+
+		// the planner records the state of its own copies of the hints
+		states := []algebra.HintState{algebra.HINT_STATE_FOLLOWED, algebra.HINT_STATE_FOLLOWED,
+			algebra.HINT_STATE_FOLLOWED, algebra.HINT_STATE_IGNORED}
+		explain := op.(*plan.Sequence).Children()[0].(*plan.Authorize).Child().(*plan.Explain)
+		hints := explain.OptimHints()
+		if len(hints) != len(states) {
+			t.Fatalf("Expected %v hints, got %v", len(states), len(hints))
+		}
+		for i, hint := range hints {
+			if hint.State() != states[i] {
+				t.Errorf("Expected hint %v to be in state %v, got %v (%v)", hint, states[i], hint.State(), hint.Reason())
+			}
+		}
+	}
+
+	for _, hint := range node.OptimHints().Hints() {
+		if hint.State() != algebra.HINT_STATE_UNKNOWN {
+			t.Errorf("Expected hint %v of the statement to be unchanged, got %v", hint, hint.State())
+		}
+	}
+
+	left := algebra.GetKeyspaceTerm(join.Left().(algebra.SimpleFromTerm))
+	right := algebra.GetKeyspaceTerm(join.Right())
+	if left.Indexes() != nil || right.JoinHint() != algebra.JOIN_HINT_NONE {
+		t.Errorf("Expected the keyspace terms to be unchanged")
+	}
+}
@@ -41,19 +41,16 @@ func (this *builder) VisitPrepare(stmt *algebra.Prepare) (interface{}, error) {
 		}
 
 		if prep != nil {
-			json_bytes, err := prep.MarshalJSON()
-			if err != nil {
-				return nil, err
-			}
-			val := value.NewValue(json_bytes)
-			err = val.SetField("encoded_plan", value.NewValue(prep.EncodedPlan()))
-			if err != nil {
-				return nil, err
-			}
-			return plan.NewPrepare(val, prep, false), nil
+			return newPrepare(prep, false)
 		}
 	}
 
+	// a plan pinned by a baseline is used even when forcing
+	prep = planCache.GetPinnedPlan(name, text, this.indexApiVersion, this.featureControls)
+	if prep != nil {
+		return newPrepare(prep, true)
+	}
+
 	prep, err = BuildPrepared(stmt.Statement(), this.datastore, this.systemstore, this.namespace, false,
 		this.namedArgs, this.positionalArgs, this.indexApiVersion, this.featureControls)
 	if err != nil {
@@ -81,3 +78,16 @@ func (this *builder) VisitPrepare(stmt *algebra.Prepare) (interface{}, error) {
 
 	return plan.NewPrepare(val, prep, true), nil
 }
+
+func newPrepare(prep *plan.Prepared, force bool) (interface{}, error) {
+	json_bytes, err := prep.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	val := value.NewValue(json_bytes)
+	err = val.SetField("encoded_plan", value.NewValue(prep.EncodedPlan()))
+	if err != nil {
+		return nil, err
+	}
+	return plan.NewPrepare(val, prep, force), nil
+}
@@ -10,8 +10,11 @@
 package planner
 
 import (
+	"sort"
+
 	"github.com/couchbase/query/algebra"
 	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/value"
 )
@@ -19,12 +22,48 @@ import (
 func BuildPrepared(stmt algebra.Statement, datastore, systemstore datastore.Datastore,
 	namespace string, subquery bool, namedArgs map[string]value.Value, positionalArgs value.Values,
 	indexApiVersion int, featureControls uint64) (*plan.Prepared, error) {
-	operator, err := Build(stmt, datastore, systemstore, namespace, subquery, namedArgs, positionalArgs,
+	builder := newBuilder(datastore, systemstore, namespace, subquery, namedArgs, positionalArgs,
 		indexApiVersion, featureControls)
+	operator, err := builder.build(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	keyspaces, err := algebra.ReferencedKeyspaces(stmt, namespace)
 	if err != nil {
 		return nil, err
 	}
 
+	// the views used are listed as keyspaces, so that changing them
+	// invalidates the results cached
+	for _, view := range builder.viewKeyspaces {
+		i := sort.SearchStrings(keyspaces, view)
+		if i == len(keyspaces) || keyspaces[i] != view {
+			keyspaces = append(keyspaces, "")
+			copy(keyspaces[i+1:], keyspaces[i:])
+			keyspaces[i] = view
+		}
+	}
+
 	signature := stmt.Signature()
-	return plan.NewPrepared(operator, signature), nil
+	prepared := plan.NewPrepared(operator, signature)
+	prepared.SetKeyspaces(keyspaces)
+	if builder.resultCacheHint != nil && stmt.Type() == "SELECT" {
+		prepared.SetResultCache(builder.resultCacheHint.Duration())
+	}
+	prepared.SetVolatile(isVolatile(stmt.Expressions()))
+	return prepared, nil
+}
+
+/*
+Whether any of the expressions, including those of subqueries, can
+change between two evaluations over the same data.
+*/
+func isVolatile(exprs expression.Expressions) bool {
+	for _, expr := range exprs {
+		if expression.IsVolatile(expr) {
+			return true
+		}
+	}
+	return false
 }
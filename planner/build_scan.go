@@ -28,7 +28,7 @@ func (this *builder) selectScan(keyspace datastore.Keyspace, node *algebra.Keysp
 		this.resetPushDowns()
 		switch keys.(type) {
 		case *expression.ArrayConstruct, *algebra.NamedParameter, *algebra.PositionalParameter:
-			this.maxParallelism = 0
+			this.maxParallelism = this.maxParallelismHint
 		default:
 			this.maxParallelism = 1
 		}
@@ -57,13 +57,14 @@ func (this *builder) buildScan(keyspace datastore.Keyspace, node *algebra.Keyspa
 	hash := node.IsUnderHash()
 
 	var hints []datastore.Index
-	if len(node.Indexes()) > 0 {
+	if indexes := this.termIndexes(node); len(indexes) > 0 {
 		hints = _HINT_POOL.Get()
 		defer _HINT_POOL.Put(hints)
-		hints, err = allHints(keyspace, node.Indexes(), hints, this.indexApiVersion)
+		hints, err = allHints(keyspace, indexes, hints, this.indexApiVersion)
 		if err != nil {
 			return
 		}
+		hints = this.excludeIndexes(node, hints)
 	}
 
 	baseKeyspace, ok := this.baseKeyspaces[node.Alias()]
@@ -159,9 +160,11 @@ func (this *builder) buildPredicateScan(keyspace datastore.Keyspace, node *algeb
 		secondary, primary, err = this.buildSubsetScan(
 			keyspace, node, baseKeyspace, id, hints, primaryKey, formalizer, true)
 		if secondary != nil || primary != nil || err != nil {
+			this.markIndexHints(node.Alias(), err == nil)
 			return
 		}
 	}
+	this.markIndexHints(node.Alias(), false)
 
 	others := _INDEX_POOL.Get()
 	defer _INDEX_POOL.Put(others)
@@ -169,6 +172,7 @@ func (this *builder) buildPredicateScan(keyspace datastore.Keyspace, node *algeb
 	if err != nil {
 		return
 	}
+	others = this.excludeIndexes(node, others)
 
 	secondary, primary, err = this.buildSubsetScan(keyspace, node, baseKeyspace, id, others, primaryKey, formalizer, false)
 
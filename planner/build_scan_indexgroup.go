@@ -35,6 +35,9 @@ var _INDEX_AGG_PROPERTIES = map[string]*indexGroupAggProperties{
 	"max":                  &indexGroupAggProperties{3, true, datastore.AGG_MAX, false, true},
 	"median":               &indexGroupAggProperties{3, false, datastore.AGG_MEDIAN, false, false},
 	"min":                  &indexGroupAggProperties{3, true, datastore.AGG_MIN, false, true},
+	"mode":                 &indexGroupAggProperties{3, false, datastore.AGG_MODE, false, false},
+	"percentile_cont":      &indexGroupAggProperties{3, false, datastore.AGG_PCTCONT, false, false},
+	"percentile_disc":      &indexGroupAggProperties{3, false, datastore.AGG_PCTDISC, false, false},
 	"sum":                  &indexGroupAggProperties{3, true, datastore.AGG_SUM, false, true},
 	"stddev":               &indexGroupAggProperties{3, false, datastore.AGG_STDDEV, false, false},
 	"stddev_pop":           &indexGroupAggProperties{3, false, datastore.AGG_STDDEVPOP, false, false},
@@ -25,6 +25,7 @@ func (this *builder) buildPrimaryScan(keyspace datastore.Keyspace, node *algebra
 	if primary == nil || err != nil {
 		return nil, err
 	}
+	this.markPrimaryIndex(node.Alias(), primary)
 
 	this.resetProjection()
 	if this.group != nil {
@@ -67,6 +68,7 @@ func (this *builder) buildCoveringPrimaryScan(keyspace datastore.Keyspace, node
 	if err != nil {
 		return nil, err
 	}
+	this.markPrimaryIndex(node.Alias(), primary)
 
 	keys := expression.Expressions{id}
 
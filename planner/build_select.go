@@ -22,6 +22,11 @@ import (
 // SELECT
 
 func (this *builder) VisitSelect(stmt *algebra.Select) (interface{}, error) {
+	view, err := this.buildMaterializedView(stmt)
+	if view != nil || err != nil {
+		return view, err
+	}
+
 	// Restore previous values when exiting. VisitSelect()
 	// can be called multiple times by set operators
 	prevCover := this.cover
@@ -16,6 +16,7 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
 
@@ -29,14 +30,23 @@ func (this *builder) visitFrom(node *algebra.Subselect, group *algebra.Group) er
 		this.maxParallelism = 1
 		this.resetPushDowns()
 	} else if node.From() != nil {
+		from := node.From()
+		if !this.orderedHint &&
+			util.IsFeatureEnabled(this.featureControls, util.N1QL_JOIN_REORDER) {
+			from, err = this.reorderAnsiJoins(from, node.Where())
+			if err != nil {
+				return err
+			}
+		}
+
 		prevFrom := this.from
-		this.from = node.From()
+		this.from = from
 		defer func() { this.from = prevFrom }()
 
 		// gather keyspace references
 		this.baseKeyspaces = make(map[string]*baseKeyspace, _MAP_KEYSPACE_CAP)
 		keyspaceFinder := newKeyspaceFinder(this.baseKeyspaces, this.from.PrimaryTerm().Alias())
-		_, err := node.From().Accept(keyspaceFinder)
+		_, err := from.Accept(keyspaceFinder)
 		if err != nil {
 			return err
 		}
@@ -74,10 +84,10 @@ func (this *builder) visitFrom(node *algebra.Subselect, group *algebra.Group) er
 		if !this.falseWhereClause() {
 			unnests := _UNNEST_POOL.Get()
 			defer _UNNEST_POOL.Put(unnests)
-			unnests = collectInnerUnnests(node.From(), unnests)
+			unnests = collectInnerUnnests(from, unnests)
 
-			aoj2aij := newAnsijoinOuterToInner(this.baseKeyspaces, unnests)
-			_, err = node.From().Accept(aoj2aij)
+			aoj2aij := newAnsijoinOuterToInner(this.baseKeyspaces, unnests, this.where)
+			_, err = from.Accept(aoj2aij)
 			if err != nil {
 				return err
 			}
@@ -98,7 +108,7 @@ func (this *builder) visitFrom(node *algebra.Subselect, group *algebra.Group) er
 		}
 
 		// Use FROM clause in index selection
-		_, err = node.From().Accept(this)
+		_, err = from.Accept(this)
 		if err != nil {
 			return err
 		}
@@ -322,11 +332,17 @@ func (this *builder) VisitAnsiJoin(node *algebra.AnsiJoin) (interface{}, error)
 	if err != nil {
 		return nil, err
 	}
+	this.markJoinHints(node.Alias(), join)
+	this.markConcurrent(join)
 
-	switch join := join.(type) {
-	case *plan.NLJoin:
+	// the unmatched right-hand side rows of a RIGHT or FULL OUTER nested-loop join
+	// are only known once all the left-hand side rows are seen, so the join
+	// cannot be performed in parallel
+	if nljoin, ok := join.(*plan.NLJoin); ok && !nljoin.RightOuter() {
+		this.subChildren = append(this.subChildren, join)
+	} else if _, ok := join.(*plan.NLSemiJoin); ok {
 		this.subChildren = append(this.subChildren, join)
-	case *plan.Join, *plan.HashJoin:
+	} else {
 		if len(this.subChildren) > 0 {
 			parallel := plan.NewParallel(plan.NewSequence(this.subChildren...), this.maxParallelism)
 			this.children = append(this.children, parallel)
@@ -444,6 +460,8 @@ func (this *builder) VisitAnsiNest(node *algebra.AnsiNest) (interface{}, error)
 	if err != nil {
 		return nil, err
 	}
+	this.markJoinHints(node.Alias(), nest)
+	this.markConcurrent(nest)
 
 	switch nest := nest.(type) {
 	case *plan.NLNest:
@@ -17,6 +17,7 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/expression"
 	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
 
@@ -31,10 +32,16 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 	prevPushableOnclause := this.pushableOnclause
 	prevBuilderFlags := this.builderFlags
 	prevMaxParallelism := this.maxParallelism
+	prevKeyspaceHints := this.keyspaceHints
+	prevIndexHints := this.indexHints
+	prevJoinHints := this.joinHints
+	prevOrderedHint := this.orderedHint
+	prevMaxParallelismHint := this.maxParallelismHint
 
 	indexPushDowns := this.storeIndexPushDowns()
 
 	defer func() {
+		this.finishOptimHints()
 		this.cover = prevCover
 		this.where = prevWhere
 		this.correlated = prevCorrelated
@@ -45,6 +52,11 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 		this.pushableOnclause = prevPushableOnclause
 		this.builderFlags = prevBuilderFlags
 		this.maxParallelism = prevMaxParallelism
+		this.keyspaceHints = prevKeyspaceHints
+		this.indexHints = prevIndexHints
+		this.joinHints = prevJoinHints
+		this.orderedHint = prevOrderedHint
+		this.maxParallelismHint = prevMaxParallelismHint
 		this.restoreIndexPushDowns(indexPushDowns, false)
 	}()
 
@@ -66,6 +78,32 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 
 	this.node = node
 
+	// Apply optimizer hints
+	var terms map[string]algebra.SimpleFromTerm
+	if node.OptimHints() != nil && node.From() != nil {
+		terms = make(map[string]algebra.SimpleFromTerm, _MAP_KEYSPACE_CAP)
+		collectHintTerms(node.From(), terms)
+	}
+	this.processOptimHints(node.OptimHints(), terms)
+	this.maxParallelism = this.maxParallelismHint
+
+	// Rewrite correlated subqueries as semi-joins
+	if util.IsFeatureEnabled(this.featureControls, util.N1QL_DECORRELATE) {
+		decorrelated, err := this.decorrelateSubqueries(node)
+		if err != nil {
+			return nil, err
+		}
+
+		if decorrelated != node {
+			if this.cover == node {
+				this.cover = decorrelated
+			}
+			node = decorrelated
+			this.node = node
+			this.projection = node.Projection()
+		}
+	}
+
 	// Inline LET expressions for index selection
 	if node.Let() != nil && node.Where() != nil {
 		var err error
@@ -222,7 +260,9 @@ func (this *builder) VisitSubselect(node *algebra.Subselect) (interface{}, error
 		}
 
 		projection := node.Projection()
-		this.subChildren = append(this.subChildren, plan.NewInitialProject(projection))
+		project := plan.NewInitialProject(projection)
+		this.markConcurrent(project)
+		this.subChildren = append(this.subChildren, project)
 
 		// Initial DISTINCT (parallel)
 		if projection.Distinct() || this.setOpDistinct {
@@ -706,7 +746,7 @@ func (this *builder) setIndexGroupAggs(group *algebra.Group, aggs algebra.Aggreg
 
 		for _, agg := range aggs {
 			aggIndexProperties := aggToIndexAgg(agg)
-			if !aggIndexProperties.supported {
+			if aggIndexProperties == nil || !aggIndexProperties.supported {
 				this.resetPushDowns()
 				return
 			}
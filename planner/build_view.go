@@ -0,0 +1,471 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/views"
+)
+
+func (this *builder) VisitCreateView(stmt *algebra.CreateView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	namespace := ksref.Namespace()
+	if namespace == "" {
+		namespace = this.namespace
+	}
+
+	_, err := this.getNameKeyspace(namespace, ksref.Keyspace())
+	if err == nil {
+		return nil, errors.NewViewDefinitionError(ksref.Keyspace(), "a keyspace of the same name exists")
+	}
+
+	// the definition must be valid now: plan it
+	builder := newBuilder(this.datastore, this.systemstore, namespace, true, nil, nil,
+		this.indexApiVersion, this.featureControls)
+	_, err = builder.build(stmt.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.NewCreateView(namespace, ksref.Keyspace(), stmt.Text()), nil
+}
+
+func (this *builder) VisitDropView(stmt *algebra.DropView) (interface{}, error) {
+	ksref := stmt.Keyspace()
+	namespace := ksref.Namespace()
+	if namespace == "" {
+		namespace = this.namespace
+	}
+
+	view := views.GetView(namespace, ksref.Keyspace())
+	if view == nil {
+		return nil, errors.NewNoSuchViewError(ksref.Keyspace())
+	}
+
+	// dropping a view requires the privileges of its definition
+	query, er := view.Query()
+	if er != nil {
+		return nil, er
+	}
+	err := this.expandSelectViews(query, namespace)
+	if err != nil {
+		return nil, err
+	}
+	stmt.SetQuery(query)
+
+	return plan.NewDropView(namespace, ksref.Keyspace()), nil
+}
+
+/*
+Replace the views used by a statement, in its FROM clauses and those of
+its subqueries, by subqueries of their definitions, before the statement
+is planned. The statement is then planned, and its privileges checked,
+as if it had been written with the subqueries.
+*/
+func (this *builder) expandViews(stmt algebra.Statement) error {
+	switch stmt := stmt.(type) {
+	case *algebra.Select:
+		return this.expandSelectViews(stmt, this.namespace)
+	case *algebra.Explain:
+		return this.expandViews(stmt.Statement())
+	case *algebra.CreateView:
+		namespace := stmt.Keyspace().Namespace()
+		if namespace == "" {
+			namespace = this.namespace
+		}
+		return this.expandSelectViews(stmt.Query(), namespace)
+	case *algebra.Insert:
+		if stmt.Select() != nil {
+			err := this.expandSelectViews(stmt.Select(), this.namespace)
+			if err != nil {
+				return err
+			}
+		}
+	case *algebra.Upsert:
+		if stmt.Select() != nil {
+			err := this.expandSelectViews(stmt.Select(), this.namespace)
+			if err != nil {
+				return err
+			}
+		}
+	case *algebra.Merge:
+		if stmt.Source().SubqueryTerm() != nil {
+			err := this.expandSelectViews(stmt.Source().SubqueryTerm().Subquery(), this.namespace)
+			if err != nil {
+				return err
+			}
+		}
+	case *algebra.Update, *algebra.Delete:
+	default:
+		return nil
+	}
+
+	return this.expandSubqueryViews(stmt.Expressions(), this.namespace)
+}
+
+func (this *builder) expandSelectViews(stmt *algebra.Select, namespace string) error {
+	err := this.expandSubresultViews(stmt.Subresult(), namespace)
+	if err != nil {
+		return err
+	}
+
+	return this.expandSubqueryViews(stmt.Expressions(), namespace)
+}
+
+func (this *builder) expandSubqueryViews(exprs expression.Expressions, namespace string) error {
+	subqueries, err := expression.ListSubqueries(exprs, false)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range subqueries {
+		if sub, ok := s.(*algebra.Subquery); ok {
+			err = this.expandSelectViews(sub.Select(), namespace)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (this *builder) expandSubresultViews(node algebra.Subresult, namespace string) error {
+	switch node := node.(type) {
+	case *algebra.Subselect:
+		if node.From() == nil {
+			return nil
+		}
+
+		from, err := this.expandTermViews(node, node.From(), namespace, false)
+		if err != nil {
+			return err
+		}
+		if from != node.From() {
+			node.SetFrom(from)
+		}
+
+		if node.With() != nil {
+			return this.expandSubqueryViews(node.With().Expressions(), namespace)
+		}
+		return nil
+	case *algebra.SelectTerm:
+		return this.expandSelectViews(node.Select(), namespace)
+	case interface {
+		First() algebra.Subresult
+		Second() algebra.Subresult
+	}:
+		err := this.expandSubresultViews(node.First(), namespace)
+		if err != nil {
+			return err
+		}
+		return this.expandSubresultViews(node.Second(), namespace)
+	default:
+		return nil
+	}
+}
+
+/*
+Views can be used as the first term of a FROM clause, and on the right
+of ANSI JOIN and NEST. Filters are not pushed down to views whose rows
+may be replaced by NULLs, as by outer joins, nor to nested views, whose
+rows the WHERE clause only sees as arrays.
+*/
+func (this *builder) expandTermViews(node *algebra.Subselect, term algebra.FromTerm,
+	namespace string, nullable bool) (algebra.FromTerm, error) {
+
+	switch term := term.(type) {
+	case *algebra.AnsiJoin:
+		left, err := this.expandTermViews(node, term.Left(), namespace, nullable || term.RightOuter())
+		if err != nil {
+			return nil, err
+		}
+		if left != term.Left() {
+			term.SetLeft(left)
+		}
+
+		right, err := this.expandSimpleTermViews(node, term.Right(), namespace, !nullable && !term.Outer())
+		if err != nil {
+			return nil, err
+		}
+		if right != term.Right() {
+			term.SetRight(right)
+		}
+		return term, nil
+	case *algebra.AnsiNest:
+		left, err := this.expandTermViews(node, term.Left(), namespace, nullable)
+		if err != nil {
+			return nil, err
+		}
+		if left != term.Left() {
+			term.SetLeft(left)
+		}
+
+		right, err := this.expandSimpleTermViews(node, term.Right(), namespace, false)
+		if err != nil {
+			return nil, err
+		}
+		if right != term.Right() {
+			term.SetRight(right)
+		}
+		return term, nil
+	case *algebra.Join:
+		return term, this.expandLookupViews(node, term, term.Right(), namespace, nullable)
+	case *algebra.Nest:
+		return term, this.expandLookupViews(node, term, term.Right(), namespace, nullable)
+	case *algebra.IndexJoin:
+		return term, this.expandLookupViews(node, term, term.Right(), namespace, nullable)
+	case *algebra.IndexNest:
+		return term, this.expandLookupViews(node, term, term.Right(), namespace, nullable)
+	case *algebra.Unnest:
+		left, err := this.expandTermViews(node, term.Left(), namespace, nullable)
+		if err != nil {
+			return nil, err
+		}
+		if left != term.Left() {
+			term.SetLeft(left)
+		}
+		return term, nil
+	case algebra.SimpleFromTerm:
+		return this.expandSimpleTermViews(node, term, namespace, !nullable)
+	default:
+		return term, nil
+	}
+}
+
+/*
+The right of lookup and index joins must be a keyspace.
+*/
+func (this *builder) expandLookupViews(node *algebra.Subselect, term interface {
+	Left() algebra.FromTerm
+	SetLeft(algebra.FromTerm)
+}, right *algebra.KeyspaceTerm, namespace string, nullable bool) error {
+
+	left, err := this.expandTermViews(node, term.Left(), namespace, nullable)
+	if err != nil {
+		return err
+	}
+	if left != term.Left() {
+		term.SetLeft(left)
+	}
+
+	right.SetDefaultNamespace(namespace)
+	if views.GetView(right.Namespace(), right.Keyspace()) != nil {
+		return errors.NewViewUseError(right.Keyspace(), "lookup and index joins need a keyspace")
+	}
+	return nil
+}
+
+func (this *builder) expandSimpleTermViews(node *algebra.Subselect, term algebra.SimpleFromTerm,
+	namespace string, pushdown bool) (algebra.SimpleFromTerm, error) {
+
+	if subquery, ok := term.(*algebra.SubqueryTerm); ok {
+		return term, this.expandSelectViews(subquery.Subquery(), namespace)
+	}
+
+	keyspace := algebra.GetKeyspaceTerm(term)
+	if keyspace == nil {
+		return term, nil
+	}
+	keyspace.SetDefaultNamespace(namespace)
+	view := views.GetView(keyspace.Namespace(), keyspace.Keyspace())
+	if view == nil {
+		return term, nil
+	}
+
+	if keyspace.Keys() != nil {
+		return nil, errors.NewViewUseError(view.Name, "USE KEYS needs a keyspace")
+	}
+	if keyspace.Indexes() != nil {
+		return nil, errors.NewViewUseError(view.Name, "USE INDEX needs a keyspace")
+	}
+
+	key := view.Namespace + ":" + view.Name
+	if this.expandingViews[key] {
+		return nil, errors.NewViewDefinitionError(view.Name, "a view cannot use itself")
+	}
+	query, er := view.Query()
+	if er != nil {
+		return nil, er
+	}
+
+	// the views used by the view
+	if this.expandingViews == nil {
+		this.expandingViews = make(map[string]bool)
+	}
+	this.expandingViews[key] = true
+	err := this.expandSelectViews(query, view.Namespace)
+	delete(this.expandingViews, key)
+	if err != nil {
+		return nil, err
+	}
+	this.viewKeyspaces = append(this.viewKeyspaces, key)
+
+	if pushdown {
+		err = pushViewFilters(node.Where(), query, keyspace.Alias())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rv := algebra.NewSubqueryTerm(query, keyspace.Alias(), term.JoinHint())
+	if term.IsAnsiJoin() {
+		rv.SetAnsiJoin()
+	}
+	if term.IsAnsiNest() {
+		rv.SetAnsiNest()
+	}
+	return rv, nil
+}
+
+/*
+Add to the WHERE clause of a view's definition the conjuncts of the
+WHERE clause using it that only filter the view's rows, with the view's
+fields replaced by the expressions that compute them. The conjuncts are
+kept where they are.
+
+Filtering the rows before they are computed only gives the same results
+when the view does not limit its rows, and does not aggregate them, or
+when the fields filtered are those it groups its rows by.
+*/
+func pushViewFilters(where expression.Expression, query *algebra.Select, alias string) error {
+	if where == nil || query.Limit() != nil || query.Offset() != nil {
+		return nil
+	}
+
+	node, ok := query.Subresult().(*algebra.Subselect)
+	if !ok || node.Projection().Raw() {
+		return nil
+	}
+
+	var aggs map[string]algebra.Aggregate
+	if node.Group() == nil {
+		aggs = make(map[string]algebra.Aggregate)
+	}
+
+	fields := make(map[string]expression.Expression, len(node.Projection().Terms()))
+	for _, term := range node.Projection().Terms() {
+		if term.Star() {
+			return nil
+		}
+
+		expr := term.Expression()
+		if aggs != nil {
+			collectAggregates(aggs, expr)
+			if len(aggs) > 0 {
+				return nil
+			}
+		} else if !groupKey(expr, node.Group().By()) {
+			continue
+		}
+		fields[term.Alias()] = expr
+	}
+
+	conjuncts := expression.Expressions{where}
+	if and, ok := where.(*expression.And); ok {
+		conjuncts = and.Operands()
+	}
+
+	ident := expression.NewIdentifier(alias)
+	mapper := newViewFields(alias, fields)
+	pushed := make(expression.Expressions, 0, len(conjuncts))
+	for _, conjunct := range conjuncts {
+		if !conjunct.DependsOn(ident) || !onlyIdentifier(conjunct, alias) ||
+			hasSubquery(conjunct) || expression.IsVolatile(conjunct) {
+			continue
+		}
+
+		filter, err := mapper.Map(conjunct.Copy())
+		if err != nil {
+			return err
+		}
+		if !filter.DependsOn(ident) {
+			pushed = append(pushed, filter)
+		}
+	}
+
+	if len(pushed) == 0 {
+		return nil
+	}
+	if node.Where() != nil {
+		pushed = append(expression.Expressions{node.Where()}, pushed...)
+	}
+	if len(pushed) == 1 {
+		node.SetWhere(pushed[0])
+	} else {
+		node.SetWhere(expression.NewAnd(pushed...))
+	}
+	return nil
+}
+
+func groupKey(expr expression.Expression, keys expression.Expressions) bool {
+	for _, key := range keys {
+		if expr.EquivalentTo(key) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Bindings, as in ANY ... SATISFIES, are not told apart from other
+identifiers: expressions using them are not pushed down.
+*/
+func onlyIdentifier(expr expression.Expression, alias string) bool {
+	if ident, ok := expr.(*expression.Identifier); ok {
+		return ident.Identifier() == alias
+	}
+
+	for _, child := range expr.Children() {
+		if !onlyIdentifier(child, alias) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Replace the fields of a view, as alias.field, by copies of the
+expressions computing them.
+*/
+type viewFields struct {
+	expression.MapperBase
+
+	alias  string
+	fields map[string]expression.Expression
+}
+
+func newViewFields(alias string, fields map[string]expression.Expression) *viewFields {
+	rv := &viewFields{
+		alias:  alias,
+		fields: fields,
+	}
+
+	rv.SetMapper(rv)
+	rv.SetMapFunc(
+		func(expr expression.Expression) (expression.Expression, error) {
+			if field, ok := expr.(*expression.Field); ok && !field.CaseInsensitive() {
+				ident, ok1 := field.First().(*expression.Identifier)
+				name, ok2 := field.Second().(*expression.FieldName)
+				if ok1 && ok2 && ident.Identifier() == rv.alias {
+					if computed, ok := rv.fields[name.Alias()]; ok {
+						return computed.Copy(), nil
+					}
+				}
+			}
+
+			return expr, expr.MapChildren(rv)
+		})
+
+	return rv
+}
@@ -65,6 +65,31 @@ func (this *chkNullRej) hasReferences(expr expression.Expression) bool {
 	return true
 }
 
+/*
+Whether the operands replace a NULL or MISSING keyspace by a value, as
+IFMISSING() and CASE do, so that the comparison of them does not
+reject nulls.
+*/
+func (this *chkNullRej) replacesUnknowns(exprs expression.Expressions) bool {
+	for _, expr := range exprs {
+		switch expr.(type) {
+		case *expression.IfMissing, *expression.IfMissingOrNull, *expression.IfNull, *expression.NVL,
+			*expression.NVL2, *expression.Decode, *expression.SearchedCase, *expression.SimpleCase,
+			*expression.IsMissing, *expression.IsNotMissing, *expression.IsNull, *expression.IsNotNull,
+			*expression.IsValued, *expression.IsNotValued:
+			if this.hasReferences(expr) {
+				return true
+			}
+		default:
+			if this.replacesUnknowns(expr.Children()) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Logic
 
 func (this *chkNullRej) VisitAnd(expr *expression.And) (interface{}, error) {
@@ -213,34 +238,34 @@ func (this *chkNullRej) VisitExists(pred *expression.Exists) (interface{}, error
 
 /* IN, WITHIN expressions are null rejecting */
 func (this *chkNullRej) VisitIn(pred *expression.In) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 func (this *chkNullRej) VisitWithin(pred *expression.Within) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 // Comparison
 
 /* all relational comparison operations are null rejecting */
 func (this *chkNullRej) VisitBetween(pred *expression.Between) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 func (this *chkNullRej) VisitEq(pred *expression.Eq) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 func (this *chkNullRej) VisitLE(pred *expression.LE) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 func (this *chkNullRej) VisitLike(pred *expression.Like) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 func (this *chkNullRej) VisitLT(pred *expression.LT) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 func (this *chkNullRej) VisitIsMissing(pred *expression.IsMissing) (interface{}, error) {
@@ -249,12 +274,12 @@ func (this *chkNullRej) VisitIsMissing(pred *expression.IsMissing) (interface{},
 
 /* IS NOT MISSING is null rejecting */
 func (this *chkNullRej) VisitIsNotMissing(pred *expression.IsNotMissing) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 /* IS NOT NULL is null rejecting */
 func (this *chkNullRej) VisitIsNotNull(pred *expression.IsNotNull) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 func (this *chkNullRej) VisitIsNotValued(pred *expression.IsNotValued) (interface{}, error) {
@@ -267,7 +292,7 @@ func (this *chkNullRej) VisitIsNull(pred *expression.IsNull) (interface{}, error
 
 /* IS VALUED is null rejecting */
 func (this *chkNullRej) VisitIsValued(pred *expression.IsValued) (interface{}, error) {
-	return true, nil
+	return !this.replacesUnknowns(pred.Children()), nil
 }
 
 // Concat
@@ -107,7 +107,7 @@ func (this *keyspaceFinder) VisitIndexJoin(node *algebra.IndexJoin) (interface{}
 
 func (this *keyspaceFinder) VisitAnsiJoin(node *algebra.AnsiJoin) (interface{}, error) {
 	// if this is inner join, gather ON-clause
-	if !node.Outer() {
+	if !node.Outer() && !node.RightOuter() {
 		this.addOnclause(node.Onclause())
 	}
 	return nil, this.visitJoin(node.Left(), node.Right())
@@ -27,6 +27,9 @@ type PlanCache interface {
 
 	// check if plan already exists for name / text / options combo
 	GetPlan(name string, text string, indexApiVersion int, featureControls uint64) (*plan.Prepared, errors.Error)
+
+	// return the plan pinned by a baseline for name / text / options combo, if still valid
+	GetPinnedPlan(name string, text string, indexApiVersion int, featureControls uint64) *plan.Prepared
 }
 
 var planCache PlanCache
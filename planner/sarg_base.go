@@ -173,6 +173,10 @@ func (this *sarg) VisitFunction(pred expression.Function) (interface{}, error) {
 	switch pred := pred.(type) {
 	case *expression.RegexpLike:
 		return this.visitLike(pred)
+	case *expression.ILike:
+		return this.visitILike(pred)
+	case expression.GeoWithinFunction:
+		return this.visitGeoWithin(pred)
 	}
 
 	return this.visitDefault(pred)
@@ -0,0 +1,52 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/plan"
+)
+
+/*
+ST_WITHIN(geom, region) over an index on GEOHASH(geom) scans the
+geohash prefixes of the cells covering region. The cells cover more
+than region, so the spans are not exact.
+*/
+func (this *sarg) visitGeoWithin(pred expression.GeoWithinFunction) (interface{}, error) {
+	prefixes := pred.GeohashPrefixes(this.key)
+	if prefixes == nil {
+		return this.visitDefault(pred)
+	}
+
+	// Merge the ranges of adjacent cells
+	lows := make([]string, 0, len(prefixes))
+	highs := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		bytes := []byte(prefix)
+		bytes[len(bytes)-1]++
+
+		if n := len(highs); n > 0 && highs[n-1] == prefix {
+			highs[n-1] = string(bytes)
+		} else {
+			lows = append(lows, prefix)
+			highs = append(highs, string(bytes))
+		}
+	}
+
+	spans := make(plan.Spans2, len(lows))
+	for i, low := range lows {
+		range2 := plan.NewRange2(expression.NewConstant(low),
+			expression.NewConstant(highs[i]), datastore.LOW)
+		spans[i] = plan.NewSpan2(nil, plan.Ranges2{range2}, false)
+	}
+
+	return NewTermSpans(spans...), nil
+}
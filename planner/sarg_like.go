@@ -67,6 +67,18 @@ func (this *sarg) visitLike(pred expression.LikeFunction) (interface{}, error) {
 	return NewTermSpans(span), nil
 }
 
+/*
+ILIKE can use the spans of the equivalent LIKE over index keys such
+as LOWER(expr).
+*/
+func (this *sarg) visitILike(pred *expression.ILike) (interface{}, error) {
+	if like := pred.LikeOf(this.key); like != nil {
+		return this.visitLike(like)
+	}
+
+	return this.visitDefault(pred)
+}
+
 func likeSpans(pred expression.LikeFunction) SargSpans {
 	range2 := plan.NewRange2(expression.EMPTY_STRING_EXPR, expression.EMPTY_ARRAY_EXPR, datastore.LOW)
 
@@ -0,0 +1,56 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/expression"
+)
+
+func TestILikeSargable(t *testing.T) {
+	name := expression.NewIdentifier("name")
+	lower := expression.Expressions{expression.NewLower(name)}
+	ci := expression.Expressions{expression.NewCollate(name, expression.NewConstant("ci"))}
+	plain := expression.Expressions{name}
+
+	ilike := expression.NewILike(name, expression.NewConstant("Fr%"))
+	tests := []struct {
+		keys   expression.Expressions
+		prefix bool
+	}{
+		{lower, true},
+		{ci, true},
+		{plain, false},
+		{expression.Expressions{expression.NewUpper(name)}, false},
+	}
+
+	// only keys that are the LOWER() of name get the spans of the
+	// lower case prefix; the others can at most exclude unknowns
+	for _, test := range tests {
+		_, max, _ := SargableFor(ilike, test.keys, false, true)
+		if test.prefix && max != 1 {
+			t.Errorf("Expected keys %v to be sargable", test.keys)
+			continue
+		}
+
+		spans, exact, err := SargFor(ilike, test.keys, max, false, "b")
+		if err != nil {
+			t.Errorf("Unexpected error %v for keys %v", err, test.keys)
+			continue
+		}
+
+		prefix := spans != nil && strings.Contains(spans.String(), `"low":"\"fr\""`)
+		if prefix != test.prefix || prefix && !exact {
+			t.Errorf("Unexpected spans %v for keys %v", spans, test.keys)
+		}
+	}
+}
@@ -254,6 +254,10 @@ func (this *sargable) VisitFunction(pred expression.Function) (interface{}, erro
 	switch pred := pred.(type) {
 	case *expression.RegexpLike:
 		return this.visitLike(pred)
+	case *expression.ILike:
+		return this.visitILike(pred)
+	case expression.GeoWithinFunction:
+		return this.visitGeoWithin(pred)
 	}
 
 	return this.visitDefault(pred)
@@ -0,0 +1,491 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+/*
+Subquery decorrelation. Correlated subqueries of the WHERE clause of
+the forms
+
+	EXISTS (SELECT ... FROM ks AS a WHERE cond)
+	NOT EXISTS (SELECT ... FROM ks AS a WHERE cond)
+	expr IN (SELECT RAW e FROM ks AS a WHERE cond)
+
+are evaluated for each row. They are rewritten as semi-joins, or an
+anti-join for NOT EXISTS, of the FROM clause with ks ON cond, and
+ON cond AND expr = e for IN, so that ks is read once into a hash
+table, or through an index on the correlated keys.
+
+Only subqueries on a single keyspace, without WITH, LET, GROUP BY,
+aggregates, OFFSET or LIMIT (other than the LIMIT of an EXISTS), are
+rewritten, and only when they do not reference the LET variables of
+the outer query. Without USE KEYS, cond must have an equality between
+expressions of ks and of the FROM clause, on which to hash, and hash
+join must be enabled; with USE KEYS, the keys are looked up for each
+row, as for the subquery.
+
+Correlated scalar aggregate subqueries of the WHERE clause and of the
+projection, of the form
+
+	(SELECT RAW agg(e) FROM ks AS a WHERE a.k1 = x1 AND ... AND filters)[0]
+
+are rewritten as a hash join of the FROM clause with the grouped
+subquery
+
+	LEFT JOIN (SELECT a.k1 AS key0, ..., agg(e) AS agg FROM ks AS a
+		WHERE filters GROUP BY a.k1, ...) AS a ON x1 = a.key0 AND ...
+
+and the subquery replaced by IFMISSING(a.agg, d), where d is the value
+of agg over no documents: 0 for COUNT, and NULL otherwise. The filters
+must only reference ks. They are not rewritten if the outer query
+groups its rows, or projects *, which would include the grouped rows.
+
+The rewritten query is a copy, with copies of the clauses it changes,
+so that the statement is left as it was written.
+*/
+func (this *builder) decorrelateSubqueries(node *algebra.Subselect) (*algebra.Subselect, error) {
+	if node.From() == nil {
+		return node, nil
+	}
+
+	hash := util.IsFeatureEnabled(this.featureControls, util.N1QL_HASH_JOIN)
+
+	aliases := make(map[string]*baseKeyspace, _MAP_KEYSPACE_CAP)
+	keyspaceFinder := newKeyspaceFinder(aliases, node.From().PrimaryTerm().Alias())
+	_, err := node.From().Accept(keyspaceFinder)
+	if err != nil {
+		return nil, err
+	}
+
+	var conjuncts expression.Expressions
+	if node.Where() != nil {
+		conjuncts = andConjuncts(node.Where())
+	}
+
+	from := node.From()
+	where := make(expression.Expressions, 0, len(conjuncts))
+	for _, conjunct := range conjuncts {
+		right, onclause, anti := decorrelateSubquery(conjunct, aliases, node.Let(), hash)
+		if right == nil {
+			where = append(where, conjunct)
+			continue
+		}
+
+		right = copyJoinTerm(right)
+		setAnsiJoin(right)
+
+		from = algebra.NewAnsiSemiJoin(from, right, onclause.Copy(), anti)
+		aliases[right.Alias()] = nil
+	}
+
+	projection := node.Projection()
+	if hash && groupableProjection(node) {
+		grouped := newGroupedSubqueries(aliases, node.Let())
+		mapped := make(expression.Expressions, len(where))
+		for i, conjunct := range where {
+			mapped[i], err = grouped.Map(conjunct.Copy())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		copied := projection.Copy()
+		err = copied.MapExpressions(grouped)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(grouped.terms) > 0 {
+			where = mapped
+			projection = copied
+		}
+
+		for i, term := range grouped.terms {
+			from = algebra.NewAnsiJoin(from, true, term, grouped.onclauses[i])
+		}
+	}
+
+	if from == node.From() {
+		return node, nil
+	}
+
+	rv := node.Copy()
+	rv.SetFrom(from)
+	rv.SetProjection(projection)
+	switch len(where) {
+	case 0:
+		rv.SetWhere(nil)
+	case 1:
+		rv.SetWhere(where[0])
+	default:
+		rv.SetWhere(expression.NewAnd(where...))
+	}
+
+	return rv, nil
+}
+
+/*
+The right-hand side and ON-clause of the semi-join for the conjunct,
+and whether it is an anti-join, or nil if the conjunct is not
+rewritten.
+*/
+func decorrelateSubquery(conjunct expression.Expression, aliases map[string]*baseKeyspace,
+	let expression.Bindings, hash bool) (algebra.SimpleFromTerm, expression.Expression, bool) {
+
+	var subq *algebra.Subquery
+	var expr expression.Expression
+	anti := false
+
+	switch pred := conjunct.(type) {
+	case *expression.Exists:
+		subq, _ = pred.Operand().(*algebra.Subquery)
+	case *algebra.Exists:
+		subq, _ = pred.Operand().(*algebra.Subquery)
+	case *expression.Not:
+		switch exists := pred.Operand().(type) {
+		case *expression.Exists:
+			subq, _ = exists.Operand().(*algebra.Subquery)
+		case *algebra.Exists:
+			subq, _ = exists.Operand().(*algebra.Subquery)
+		}
+		anti = true
+	case *expression.In:
+		subq, _ = pred.Second().(*algebra.Subquery)
+		expr = pred.First()
+	}
+
+	if subq == nil || !subq.IsCorrelated() {
+		return nil, nil, false
+	}
+
+	sel := subq.Select()
+	if sel.Offset() != nil || (sel.Limit() != nil && !positiveLimit(sel.Limit(), expr == nil)) {
+		return nil, nil, false
+	}
+
+	sub, ok := sel.Subresult().(*algebra.Subselect)
+	if !ok || sub.With() != nil || sub.Let() != nil || sub.Group() != nil {
+		return nil, nil, false
+	}
+
+	right, ok := sub.From().(algebra.SimpleFromTerm)
+	if !ok {
+		return nil, nil, false
+	}
+
+	// with USE KEYS, the keys are looked up for each row
+	ksterm := algebra.GetKeyspaceTerm(right)
+	if ksterm == nil {
+		return nil, nil, false
+	} else if keys := ksterm.Keys(); keys != nil &&
+		(right.PreferHash() || dependsOnLet(keys, let) || hasSubquery(keys)) {
+		return nil, nil, false
+	}
+
+	alias := right.Alias()
+	if _, ok := aliases[alias]; ok {
+		return nil, nil, false
+	}
+
+	projection := sub.Projection()
+	aggs := make(map[string]algebra.Aggregate, 4)
+	for _, term := range projection.Terms() {
+		collectAggregates(aggs, term.Expression())
+	}
+	collectAggregates(aggs, sub.Where())
+	if len(aggs) > 0 {
+		return nil, nil, false
+	}
+
+	var onclauses expression.Expressions
+	if expr != nil {
+		if !projection.Raw() || len(projection.Terms()) != 1 {
+			return nil, nil, false
+		}
+		onclauses = append(onclauses, expression.NewEq(expr, projection.Terms()[0].Expression()))
+	}
+
+	if sub.Where() != nil {
+		onclauses = append(onclauses, andConjuncts(sub.Where())...)
+	}
+
+	if ksterm.Keys() != nil {
+		hash = false
+	} else if !hash {
+		return nil, nil, false
+	}
+
+	ident := expression.NewIdentifier(alias)
+	hashable := false
+	for _, onclause := range onclauses {
+		if dependsOnLet(onclause, let) || hasSubquery(onclause) {
+			return nil, nil, false
+		}
+
+		if !hash {
+			continue
+		}
+
+		if eq, ok := onclause.(*expression.Eq); ok && eq.First().Indexable() && eq.Second().Indexable() {
+			first := eq.First().DependsOn(ident)
+			second := eq.Second().DependsOn(ident)
+			if first != second &&
+				((first && !dependsOnAliases(eq.First(), aliases)) ||
+					(second && !dependsOnAliases(eq.Second(), aliases))) {
+				hashable = true
+			}
+		}
+	}
+
+	if hash && !hashable {
+		return nil, nil, false
+	}
+
+	if len(onclauses) == 0 {
+		return right, expression.TRUE_EXPR, anti
+	} else if len(onclauses) == 1 {
+		return right, onclauses[0], anti
+	}
+
+	return right, expression.NewAnd(onclauses...), anti
+}
+
+/*
+A constant LIMIT of at least 1, which does not change the result of
+EXISTS.
+*/
+func positiveLimit(limit expression.Expression, exists bool) bool {
+	if !exists {
+		return false
+	}
+
+	val := limit.Value()
+	return val != nil && val.Type() == value.NUMBER && value.AsNumberValue(val).Float64() >= 1.0
+}
+
+func dependsOnAliases(expr expression.Expression, aliases map[string]*baseKeyspace) bool {
+	for alias, _ := range aliases {
+		if expr.DependsOn(expression.NewIdentifier(alias)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasSubquery(expr expression.Expression) bool {
+	if _, ok := expr.(*algebra.Subquery); ok {
+		return true
+	}
+
+	for _, child := range expr.Children() {
+		if hasSubquery(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func andConjuncts(pred expression.Expression) expression.Expressions {
+	if and, ok := pred.(*expression.And); ok {
+		and, _ = flattenAnd(and)
+		return and.Operands()
+	}
+
+	return expression.Expressions{pred}
+}
+
+/*
+The projection of a query that does not group its rows, nor project
+all the keyspaces of its FROM clause.
+*/
+func groupableProjection(node *algebra.Subselect) bool {
+	if node.Group() != nil {
+		return false
+	}
+
+	aggs := make(map[string]algebra.Aggregate, 4)
+	for _, term := range node.Projection().Terms() {
+		if _, ok := term.Expression().(*expression.Self); ok && term.Star() {
+			return false
+		}
+		collectAggregates(aggs, term.Expression())
+	}
+
+	return len(aggs) == 0
+}
+
+/*
+Replaces scalar aggregate subqueries by the results of grouped
+subqueries, which are collected with the ON-clauses that join them to
+the FROM clause.
+*/
+type groupedSubqueries struct {
+	expression.MapperBase
+
+	aliases   map[string]*baseKeyspace
+	let       expression.Bindings
+	terms     []*algebra.SubqueryTerm
+	onclauses expression.Expressions
+}
+
+func newGroupedSubqueries(aliases map[string]*baseKeyspace, let expression.Bindings) *groupedSubqueries {
+	rv := &groupedSubqueries{
+		aliases: aliases,
+		let:     let,
+	}
+
+	rv.SetMapper(rv)
+	rv.SetMapFunc(
+		func(expr expression.Expression) (expression.Expression, error) {
+			switch expr := expr.(type) {
+			case *expression.Element:
+				if grouped := rv.groupSubquery(expr); grouped != nil {
+					return grouped, nil
+				}
+			case *algebra.Subquery:
+				return expr, nil
+			}
+
+			return expr, expr.MapChildren(rv)
+		})
+
+	return rv
+}
+
+func (this *groupedSubqueries) groupSubquery(elem *expression.Element) expression.Expression {
+	subq, ok := elem.First().(*algebra.Subquery)
+	if !ok || !subq.IsCorrelated() {
+		return nil
+	}
+
+	index := elem.Second().Value()
+	if index == nil || index.Type() != value.NUMBER || value.AsNumberValue(index).Float64() != 0.0 {
+		return nil
+	}
+
+	sel := subq.Select()
+	if sel.Order() != nil || sel.Offset() != nil || sel.Limit() != nil {
+		return nil
+	}
+
+	sub, ok := sel.Subresult().(*algebra.Subselect)
+	if !ok || sub.With() != nil || sub.Let() != nil || sub.Group() != nil ||
+		!sub.Projection().Raw() || len(sub.Projection().Terms()) != 1 {
+		return nil
+	}
+
+	agg, ok := sub.Projection().Terms()[0].Expression().(algebra.Aggregate)
+	if !ok || dependsOnAliases(agg, this.aliases) {
+		return nil
+	}
+
+	right, ok := sub.From().(algebra.SimpleFromTerm)
+	if !ok {
+		return nil
+	}
+
+	ksterm := algebra.GetKeyspaceTerm(right)
+	if ksterm == nil || ksterm.Keys() != nil {
+		return nil
+	}
+
+	alias := right.Alias()
+	if _, ok := this.aliases[alias]; ok {
+		return nil
+	}
+
+	// split the WHERE clause into the correlated keys and the filters
+	ident := expression.NewIdentifier(alias)
+	var keys, outers, filters expression.Expressions
+	if sub.Where() != nil {
+		for _, conjunct := range andConjuncts(sub.Where()) {
+			if !dependsOnAliases(conjunct, this.aliases) {
+				filters = append(filters, conjunct.Copy())
+				continue
+			}
+
+			eq, ok := conjunct.(*expression.Eq)
+			if !ok || dependsOnLet(conjunct, this.let) || hasSubquery(conjunct) {
+				return nil
+			}
+
+			key, outer := eq.First(), eq.Second()
+			if outer.DependsOn(ident) {
+				key, outer = outer, key
+			}
+			if !key.DependsOn(ident) || outer.DependsOn(ident) || dependsOnAliases(key, this.aliases) {
+				return nil
+			}
+
+			keys = append(keys, key.Copy())
+			outers = append(outers, outer.Copy())
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	by := make(algebra.GroupTerms, len(keys))
+	terms := make(algebra.ResultTerms, 0, len(keys)+1)
+	for i, key := range keys {
+		by[i] = algebra.NewGroupTerm(key, "")
+		terms = append(terms, algebra.NewResultTerm(key.Copy(), false, fmt.Sprintf("key%d", i)))
+	}
+	terms = append(terms, algebra.NewResultTerm(agg.Copy(), false, "agg"))
+
+	var where expression.Expression
+	if len(filters) == 1 {
+		where = filters[0]
+	} else if len(filters) > 1 {
+		where = expression.NewAnd(filters...)
+	}
+
+	term := copyJoinTerm(right)
+	unsetAnsiJoin(term)
+	grouped := algebra.NewSelect(algebra.NewSubselect(nil, term, nil, where,
+		algebra.NewGroup(by, nil, nil), algebra.NewProjection(false, terms)), nil, nil, nil)
+
+	// the filters cannot reference the enclosing queries either
+	err := grouped.FormalizeSubquery(expression.NewFormalizer("", nil))
+	if err != nil || grouped.IsCorrelated() {
+		return nil
+	}
+
+	ident.SetKeyspaceAlias(true)
+	onclauses := make(expression.Expressions, len(outers))
+	for i, outer := range outers {
+		onclauses[i] = expression.NewEq(outer,
+			expression.NewField(ident, expression.NewFieldName(fmt.Sprintf("key%d", i), false)))
+	}
+
+	rterm := algebra.NewSubqueryTerm(grouped, alias, algebra.JOIN_HINT_NONE)
+	rterm.SetAnsiJoin()
+	this.terms = append(this.terms, rterm)
+	if len(onclauses) == 1 {
+		this.onclauses = append(this.onclauses, onclauses[0])
+	} else {
+		this.onclauses = append(this.onclauses, expression.NewAnd(onclauses...))
+	}
+	this.aliases[alias] = nil
+
+	return expression.NewIfMissing(expression.NewField(ident, expression.NewFieldName("agg", false)),
+		expression.NewConstant(agg.Default()))
+}
@@ -0,0 +1,158 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package planner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/util"
+)
+
+func TestDecorrelateSubqueries(t *testing.T) {
+	cases := []struct {
+		text     string
+		controls uint64
+		expected string // in the plan, or empty if the subquery is not rewritten
+	}{
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c WHERE c.x = a.x)", 0,
+			`"#operator":"HashSemiJoin"`},
+		{"SELECT 1 FROM b0 AS a WHERE NOT EXISTS (SELECT 1 FROM b1 AS c WHERE c.x = a.x)", 0,
+			`"#operator":"HashAntiJoin"`},
+		{"SELECT 1 FROM b0 AS a WHERE a.y IN (SELECT RAW c.y FROM b1 AS c WHERE c.x = a.x)", 0,
+			`"#operator":"HashSemiJoin"`},
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c USE KEYS a.k)", 0,
+			`"#operator":"NestedLoopSemiJoin"`},
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c WHERE c.x = a.x LIMIT 1)", 0,
+			`"#operator":"HashSemiJoin"`},
+
+		// not rewritten
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c WHERE c.x = a.x)", util.N1QL_HASH_JOIN, ""},
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c WHERE c.x > a.x)", 0, ""},
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c WHERE c.x = a.x LIMIT 0)", 0, ""},
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT COUNT(*) FROM b1 AS c WHERE c.x = a.x)", 0, ""},
+		{"SELECT 1 FROM b0 AS a WHERE a.y NOT IN (SELECT RAW c.y FROM b1 AS c WHERE c.x = a.x)", 0, ""},
+	}
+
+	for _, c := range cases {
+		plan, err := planOf(t, c.text, c.controls)
+		if err != nil {
+			t.Errorf("Unable to plan %s: %v", c.text, err)
+			continue
+		}
+
+		rewritten := strings.Contains(plan, "SemiJoin") || strings.Contains(plan, "AntiJoin")
+		if c.expected == "" && rewritten {
+			t.Errorf("Expected %s not to be rewritten, got %s", c.text, plan)
+		} else if c.expected != "" && !strings.Contains(plan, c.expected) {
+			t.Errorf("Expected %s in the plan of %s, got %s", c.expected, c.text, plan)
+		}
+	}
+}
+
+func TestDecorrelateScalarAggregates(t *testing.T) {
+	grouped := `"result_terms":[{"as":"key0","expr":"(` + "`c`.`x`" + `)"},{"as":"agg",`
+	cases := []struct {
+		text      string
+		controls  uint64
+		rewritten bool
+	}{
+		{"SELECT a.x, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x = a.x)[0] AS n FROM b0 AS a", 0, true},
+		{"SELECT 1 FROM b0 AS a WHERE (SELECT RAW MAX(c.y) FROM b1 AS c WHERE c.x = a.x AND c.z > 0)[0] > a.y",
+			0, true},
+
+		// not rewritten
+		{"SELECT a.x, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x = a.x)[0] AS n FROM b0 AS a",
+			util.N1QL_HASH_JOIN, false},
+		{"SELECT a.x, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x = a.x)[1] AS n FROM b0 AS a", 0, false},
+		{"SELECT a.x, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x > a.x)[0] AS n FROM b0 AS a", 0, false},
+		{"SELECT a.x, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x = a.x AND c.y > a.y)[0] AS n FROM b0 AS a",
+			0, false},
+		{"SELECT a.x, (SELECT RAW c.y FROM b1 AS c WHERE c.x = a.x)[0] AS n FROM b0 AS a", 0, false},
+		{"SELECT *, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x = a.x)[0] AS n FROM b0 AS a", 0, false},
+		{"SELECT a.x, MAX((SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x = a.x)[0]) AS n FROM b0 AS a GROUP BY a.x",
+			0, false},
+	}
+
+	for _, c := range cases {
+		plan, err := planOf(t, c.text, c.controls)
+		if err != nil {
+			t.Errorf("Unable to plan %s: %v", c.text, err)
+			continue
+		}
+
+		if strings.Contains(plan, grouped) != c.rewritten {
+			t.Errorf("Expected rewritten %v for %s, got %s", c.rewritten, c.text, plan)
+		} else if c.rewritten && !strings.Contains(plan, `"outer":true`) {
+			t.Errorf("Expected an outer join for %s, got %s", c.text, plan)
+		}
+	}
+}
+
+func TestDecorrelateKeepsStatement(t *testing.T) {
+	ds, err := mock.NewDatastore("mock:keyspaces=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"SELECT 1 FROM b0 AS a WHERE EXISTS (SELECT 1 FROM b1 AS c WHERE c.x = a.x) AND a.y > 0",
+			`"#operator":"HashSemiJoin"`},
+		{"SELECT a.x, (SELECT RAW COUNT(*) FROM b1 AS c WHERE c.x = a.x)[0] + 1 AS n FROM b0 AS a",
+			`"result_terms":[{"as":"key0","expr":"(` + "`c`.`x`" + `)"},{"as":"agg",`},
+	}
+
+	for _, c := range cases {
+		stmt, er := n1ql.ParseStatement(c.text)
+		if er != nil {
+			t.Fatalf("Unable to parse %s: %v", c.text, er)
+		}
+
+		node := stmt.(*algebra.Select).Subresult().(*algebra.Subselect)
+		from, where, projection := node.From(), node.Where(), node.Projection()
+
+		for i := 0; i < 2; i++ {
+			op, er := Build(stmt, ds, nil, "p0", false, nil, nil, datastore.INDEX_API_MAX, 0)
+			if er != nil {
+				t.Fatalf("Unable to plan %s: %v", c.text, er)
+			}
+			bytes, er := json.Marshal(op)
+			if er != nil {
+				t.Fatalf("Unable to marshal plan of %s: %v", c.text, er)
+			}
+			if !strings.Contains(string(bytes), c.expected) {
+				t.Errorf("Expected %s in plan %d of %s, got %s", c.expected, i, c.text, bytes)
+			}
+		}
+
+		if node.From() != from || node.Where() != where || node.Projection() != projection {
+			t.Errorf("Expected the clauses of %s to be unchanged, got %s", c.text, node.String())
+		}
+
+		subqueries, er := expression.ListSubqueries(node.Expressions(), false)
+		if er != nil || len(subqueries) != 1 {
+			t.Fatalf("Expected one subquery in %s, got %v %v", c.text, subqueries, er)
+		}
+		sub := subqueries[0].(*algebra.Subquery).Select().Subresult().(*algebra.Subselect)
+		subFrom, _ := sub.From().(algebra.SimpleFromTerm)
+		if term := algebra.GetKeyspaceTerm(subFrom); term == nil || term.IsAnsiJoin() {
+			t.Errorf("Expected the keyspace term of the subquery of %s to be unchanged", c.text)
+		}
+	}
+}
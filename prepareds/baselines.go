@@ -0,0 +1,346 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package prepareds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/plan"
+)
+
+// Plan baselines
+//
+// A baseline is the plan of a prepared statement captured by an
+// administrator. Once accepted, the baseline is pinned: whenever the
+// statement has to be planned again (PREPARE, PREPARE FORCE, a failed
+// verification, auto prepare after a restart) the pinned plan is used
+// instead, as long as the indexes and keyspaces it references still exist.
+// When they don't, a new plan is built and the fallback is recorded.
+
+type Baseline struct {
+	Name            string    `json:"name"`
+	Text            string    `json:"statement"`
+	IndexApiVersion int       `json:"indexApiVersion"`
+	FeatureControls uint64    `json:"featureControls"`
+	EncodedPlan     string    `json:"encoded_plan"`
+	Accepted        bool      `json:"accepted"`
+	Captured        time.Time `json:"captured"`
+
+	// usage is not persisted
+	Uses           int32     `json:"-"`
+	Fallbacks      int32     `json:"-"`
+	FallbackReason string    `json:"-"`
+	LastFallback   time.Time `json:"-"`
+}
+
+// persistence for baselines
+type BaselineStore interface {
+
+	// return all the baselines stored
+	Load() ([]*Baseline, errors.Error)
+
+	// replace the stored baselines
+	Save(baselines []*Baseline) errors.Error
+}
+
+type baselineCache struct {
+	sync.RWMutex
+	store     BaselineStore
+	baselines map[string]*Baseline
+}
+
+var baselines = &baselineCache{
+	baselines: make(map[string]*Baseline),
+}
+
+// init the baselines, from a store if one is given
+func PreparedsBaselinesInit(store BaselineStore) errors.Error {
+	baselines.Lock()
+	defer baselines.Unlock()
+
+	baselines.store = store
+	baselines.baselines = make(map[string]*Baseline)
+	if store == nil {
+		return nil
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		return err
+	}
+	for _, b := range loaded {
+		baselines.baselines[b.Name] = b
+	}
+	return nil
+}
+
+// capture the plan currently cached for a prepared statement
+// a new baseline needs to be accepted before it is used
+func CaptureBaseline(name string) (*Baseline, errors.Error) {
+	var prepared *plan.Prepared
+
+	PreparedDo(name, func(entry *CacheEntry) {
+		prepared = entry.Prepared
+	})
+	if prepared == nil {
+		return nil, errors.NewNoSuchPreparedError(name)
+	}
+
+	encoded_plan, err := encodePrepared(prepared)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Baseline{
+		Name:            name,
+		Text:            prepared.Text(),
+		IndexApiVersion: prepared.IndexApiVersion(),
+		FeatureControls: prepared.FeatureControls(),
+		EncodedPlan:     encoded_plan,
+		Captured:        time.Now(),
+	}
+
+	baselines.Lock()
+	defer baselines.Unlock()
+	baselines.baselines[name] = b
+	rv := *b
+	return &rv, baselines.save()
+}
+
+// pin a baseline, and use it from now on
+func AcceptBaseline(name string) errors.Error {
+	baselines.Lock()
+	b, ok := baselines.baselines[name]
+	if !ok {
+		baselines.Unlock()
+		return errors.NewNoSuchBaselineError(name)
+	}
+	b.Accepted = true
+	b.FallbackReason = ""
+	text, indexApiVersion, featureControls := b.Text, b.IndexApiVersion, b.FeatureControls
+	err := baselines.save()
+	baselines.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// replace the cached plan, if it still exists
+	prepared := pinnedPlan(name, text, indexApiVersion, featureControls)
+	if prepared != nil {
+		prepareds.add(prepared, true, false, func(ce *CacheEntry) bool {
+			return ce.Prepared.Text() == prepared.Text()
+		})
+	}
+	return nil
+}
+
+// remove a baseline: the statement will be planned normally
+func DropBaseline(name string) errors.Error {
+	baselines.Lock()
+	defer baselines.Unlock()
+
+	_, ok := baselines.baselines[name]
+	if !ok {
+		return errors.NewNoSuchBaselineError(name)
+	}
+	delete(baselines.baselines, name)
+	return baselines.save()
+}
+
+func GetBaseline(name string) *Baseline {
+	baselines.RLock()
+	defer baselines.RUnlock()
+
+	b, ok := baselines.baselines[name]
+	if !ok {
+		return nil
+	}
+	rv := *b
+	return &rv
+}
+
+// all the baselines, by name
+func Baselines() []*Baseline {
+	baselines.RLock()
+	rv := make([]*Baseline, 0, len(baselines.baselines))
+	for _, b := range baselines.baselines {
+		c := *b
+		rv = append(rv, &c)
+	}
+	baselines.RUnlock()
+
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Name < rv[j].Name })
+	return rv
+}
+
+// how a cached plan relates to its baseline, if there is one
+func BaselineStatus(prepared *plan.Prepared) map[string]interface{} {
+	baselines.RLock()
+	defer baselines.RUnlock()
+
+	b, ok := baselines.baselines[prepared.Name()]
+	if !ok {
+		return nil
+	}
+
+	rv := map[string]interface{}{
+		"uses":      b.Uses,
+		"fallbacks": b.Fallbacks,
+	}
+	switch {
+	case !b.Accepted:
+		rv["state"] = "captured"
+	case !prepared.MismatchingEncodedPlan(b.EncodedPlan):
+		rv["state"] = "pinned"
+	case b.FallbackReason != "":
+		rv["state"] = "fallback"
+		rv["reason"] = b.FallbackReason
+		rv["lastFallback"] = b.LastFallback.String()
+	default:
+
+		// the plan came from elsewhere, eg another node
+		rv["state"] = "mismatch"
+	}
+	return rv
+}
+
+// preparedCache implements planner.PlanCache
+func (this *preparedCache) GetPinnedPlan(name string, text string, indexApiVersion int, featureControls uint64) *plan.Prepared {
+	return pinnedPlan(name, text, indexApiVersion, featureControls)
+}
+
+// the pinned plan for a statement, if it can still be used
+func pinnedPlan(name string, text string, indexApiVersion int, featureControls uint64) *plan.Prepared {
+	baselines.RLock()
+	b, ok := baselines.baselines[name]
+	if ok {
+		ok = b.Accepted
+	}
+	var encoded_plan string
+	var reason string
+	if ok {
+		encoded_plan = b.EncodedPlan
+		if b.Text != text {
+			reason = "statement does not match the baseline"
+		} else if b.IndexApiVersion != indexApiVersion || b.FeatureControls != featureControls {
+			reason = "index API version or feature controls do not match the baseline"
+		}
+	}
+	baselines.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	var prepared *plan.Prepared
+	if reason == "" {
+		prepared, reason = decodePinnedPlan(encoded_plan)
+	}
+
+	baselines.Lock()
+	defer baselines.Unlock()
+
+	// somebody dropped or replaced it in the interim
+	b, ok = baselines.baselines[name]
+	if !ok || b.EncodedPlan != encoded_plan {
+		return nil
+	}
+	if reason != "" {
+		b.Fallbacks++
+		b.FallbackReason = reason
+		b.LastFallback = time.Now()
+		logging.Infof("Plan baseline for %v not used: %v", name, reason)
+		return nil
+	}
+	b.Uses++
+	b.FallbackReason = ""
+	return prepared
+}
+
+func decodePinnedPlan(encoded_plan string) (*plan.Prepared, string) {
+	prepared_bytes, err := decodePlan(encoded_plan)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	// unlike unmarshalPrepared, we never reprepare a baseline
+	prepared := plan.NewPrepared(nil, nil)
+	err1 := prepared.UnmarshalJSON(prepared_bytes)
+	if err1 != nil {
+		return nil, fmt.Sprintf("unable to decode the baseline: %v", err1)
+	}
+	prepared.SetEncodedPlan(encoded_plan)
+
+	// check the plan and populate metadata counters
+	if !prepared.Verify() {
+		return nil, "the plan references indexes or keyspaces that no longer exist"
+	}
+	return prepared, ""
+}
+
+// Locking is handled by the top level caller!
+func (this *baselineCache) save() errors.Error {
+	if this.store == nil {
+		return nil
+	}
+
+	saved := make([]*Baseline, 0, len(this.baselines))
+	for _, b := range this.baselines {
+		saved = append(saved, b)
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Name < saved[j].Name })
+	return this.store.Save(saved)
+}
+
+// local file baseline store
+type fileBaselineStore struct {
+	path string
+}
+
+func NewFileBaselineStore(path string) BaselineStore {
+	return &fileBaselineStore{path: path}
+}
+
+func (this *fileBaselineStore) Load() ([]*Baseline, errors.Error) {
+	bytes, err := ioutil.ReadFile(this.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.NewBaselineStoreError(err)
+	}
+
+	var rv []*Baseline
+	err = json.Unmarshal(bytes, &rv)
+	if err != nil {
+		return nil, errors.NewBaselineStoreError(err)
+	}
+	return rv, nil
+}
+
+func (this *fileBaselineStore) Save(baselines []*Baseline) errors.Error {
+	bytes, err := json.MarshalIndent(baselines, "", "    ")
+	if err != nil {
+		return errors.NewBaselineStoreError(err)
+	}
+
+	err = replaceFile(this.path, bytes)
+	if err != nil {
+		return errors.NewBaselineStoreError(err)
+	}
+	return nil
+}
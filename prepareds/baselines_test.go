@@ -0,0 +1,147 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package prepareds
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/planner"
+)
+
+func TestBaselines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "baselines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileBaselineStore(filepath.Join(dir, "baselines.json"))
+
+	// a missing file is no baselines
+	if err := PreparedsBaselinesInit(store); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if len(Baselines()) != 0 {
+		t.Errorf("expected no baselines, got %v", len(Baselines()))
+	}
+
+	// accept and reload
+	if err := store.Save([]*Baseline{{Name: "p1", Text: "PREPARE p1 FROM SELECT 1", EncodedPlan: "plan"}}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := PreparedsBaselinesInit(store); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if err := AcceptBaseline("p1"); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if err := PreparedsBaselinesInit(store); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	b := GetBaseline("p1")
+	if b == nil || !b.Accepted || b.Text != "PREPARE p1 FROM SELECT 1" {
+		t.Fatalf("expected accepted baseline p1, got %v", b)
+	}
+
+	// a plan that doesn't decode falls back, and is reported
+	if pinnedPlan("p1", b.Text, b.IndexApiVersion, b.FeatureControls) != nil {
+		t.Errorf("expected no pinned plan for an invalid baseline")
+	}
+	prepared := plan.NewPrepared(nil, nil)
+	prepared.SetName("p1")
+	status := BaselineStatus(prepared)
+	if status["state"] != "fallback" || status["fallbacks"] != int32(1) {
+		t.Errorf("expected a fallback, got %v", status)
+	}
+
+	// drop
+	if err := DropBaseline("p1"); err != nil {
+		t.Fatalf("drop: %v", err)
+	}
+	if err := DropBaseline("p1"); err == nil || err.Code() != errors.NO_SUCH_BASELINE {
+		t.Errorf("expected no such baseline, got %v", err)
+	}
+	if err := PreparedsBaselinesInit(store); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if len(Baselines()) != 0 {
+		t.Errorf("expected no baselines after drop, got %v", len(Baselines()))
+	}
+}
+
+func TestPinnedBaseline(t *testing.T) {
+	ds := testPrepareds(t)
+	if err := PreparedsBaselinesInit(nil); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	defer PreparedsBaselinesInit(nil)
+
+	text := "PREPARE p2 FROM SELECT * FROM b0"
+	testPrepare(t, "p2", "SELECT * FROM b0")
+	b, err := CaptureBaseline("p2")
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if err := AcceptBaseline("p2"); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+
+	// each use of the pinned plan is counted
+	uses := GetBaseline("p2").Uses
+	expectPinned := func(what string, prepared *plan.Prepared) {
+		uses++
+		if prepared == nil || prepared.MismatchingEncodedPlan(b.EncodedPlan) {
+			t.Errorf("%v: expected the pinned plan, got %v", what, prepared)
+		} else if status := BaselineStatus(prepared); status["state"] != "pinned" || status["uses"] != uses {
+			t.Errorf("%v: expected the pinned plan to be used, got %v", what, status)
+		}
+	}
+
+	// a plan that is no longer good is replaced by the pinned plan
+	stale := plan.NewPrepared(nil, nil)
+	stale.SetName("p2")
+	stale.SetText(text)
+	stale.SetIndexApiVersion(b.IndexApiVersion)
+	stale.SetFeatureControls(b.FeatureControls)
+	prepared, err := reprepare(stale, nil)
+	if err != nil {
+		t.Fatalf("reprepare: %v", err)
+	}
+	expectPinned("reprepare", prepared)
+
+	// as is an auto prepared statement no longer cached
+	if err := DeletePrepared("p2"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	expectPinned("auto prepare", GetAutoPreparePlan("p2", text, b.IndexApiVersion, b.FeatureControls))
+
+	// and PREPARE FORCE uses it rather than planning the statement again
+	stmt, er := n1ql.ParseStatement("PREPARE FORCE p2 FROM SELECT * FROM b0")
+	if er != nil {
+		t.Fatalf("parse: %v", er)
+	}
+	op, er := planner.Build(stmt, ds, nil, "p0", false, nil, nil, b.IndexApiVersion, b.FeatureControls)
+	if er != nil {
+		t.Fatalf("prepare force: %v", er)
+	}
+	bytes, _ := json.Marshal(op)
+	uses++
+	if !strings.Contains(string(bytes), b.EncodedPlan) || GetBaseline("p2").Uses != uses {
+		t.Errorf("prepare force: expected the pinned plan, got %s", bytes)
+	}
+}
@@ -62,9 +62,37 @@ type CacheEntry struct {
 
 	sync.Mutex // for concurrent checking
 	populated  bool
+
+	// plans built because of index changes, most recent last
+	planChanges []*planChange
+}
+
+// a new plan built because the indexes of a keyspace used have changed
+type planChange struct {
+	Time      time.Time
+	Keyspaces []string
+	Changed   bool // the new plan is different
+	Error     errors.Error
+}
+
+// how many plan changes are kept for each statement
+const _PLAN_CHANGES = 8
+
+// what to do when indexes change on the keyspaces used by a prepared statement
+const (
+	REPREPARE_OFF     = iota // keep the plan as long as it is valid
+	REPREPARE_ALWAYS         // replace the plan
+	REPREPARE_CHANGED        // only replace the plan if it is different
+)
+
+var _REPREPARE_MODES = map[string]uint32{
+	"off":     REPREPARE_OFF,
+	"always":  REPREPARE_ALWAYS,
+	"changed": REPREPARE_CHANGED,
 }
 
 var prepareds = &preparedCache{}
+var autoReprepare uint32 = REPREPARE_CHANGED
 var store datastore.Datastore
 var systemstore datastore.Datastore
 var namespace string
@@ -199,6 +227,24 @@ func PreparedsSetLimit(limit int) {
 	prepareds.cache.SetLimit(limit)
 }
 
+func PreparedsAutoReprepare() string {
+	mode := atomic.LoadUint32(&autoReprepare)
+	for name, m := range _REPREPARE_MODES {
+		if m == mode {
+			return name
+		}
+	}
+	return ""
+}
+
+func PreparedsSetAutoReprepare(mode string) bool {
+	m, ok := _REPREPARE_MODES[mode]
+	if ok {
+		atomic.StoreUint32(&autoReprepare, m)
+	}
+	return ok
+}
+
 func (this *preparedCache) get(name value.Value, track bool) *CacheEntry {
 	var cv interface{}
 
@@ -297,8 +343,15 @@ func GetAutoPreparePlan(name string, text string, indexApiVersion int, featureCo
 	if err != nil {
 		if err.Code() != errors.NO_SUCH_PREPARED {
 			logging.Infof("Auto Prepare plan fetching failed with %v", err)
+			return nil
 		}
-		return nil
+
+		// not cached, but possibly pinned
+		prep = pinnedPlan(name, text, indexApiVersion, featureControls)
+		if prep != nil {
+			prepareds.add(prep, true, true, nil)
+		}
+		return prep
 	}
 
 	// this should never happen
@@ -429,6 +482,7 @@ func (prepareds *preparedCache) getPrepared(prepared_stmt value.Value, options u
 				}, distributed.NO_CREDS, "")
 		} else if prepared != nil && verify {
 			var good bool
+			var changed []string
 
 			// things have already been set up
 			// take the short way home
@@ -438,10 +492,13 @@ func (prepareds *preparedCache) getPrepared(prepared_stmt value.Value, options u
 				// since the structure of the plan tree won't change, nor the
 				// keyspaces and indexers, the worse that is going to happen is
 				// two requests amending the same counter
+				// the index versions of the keyspaces used are amended
+				// atomically, as other requests check them concurrently
 				good = prepared.MetadataCheck()
 
 				// counters have changed. fetch new values
 				if !good && !metaCheck {
+					changed = prepared.ChangedKeyspaces()
 					good = prepared.Verify()
 				}
 			} else {
@@ -475,6 +532,10 @@ func (prepareds *preparedCache) getPrepared(prepared_stmt value.Value, options u
 				if err == nil {
 					err = AddPrepared(prepared)
 				}
+			} else if good && len(changed) > 0 {
+
+				// the plan is still valid, but there may be a better one
+				prepared = indexesChanged(ce, prepared, changed, phaseTime)
 			}
 		}
 		if err != nil {
@@ -489,6 +550,95 @@ func (prepareds *preparedCache) getPrepared(prepared_stmt value.Value, options u
 	}
 }
 
+// plan a statement again, as indexes have been created, built or altered
+// on the keyspaces it uses
+// as for plans which are no longer valid, concurrent requests might
+// replan at the same time
+func indexesChanged(ce *CacheEntry, prepared *plan.Prepared, keyspaces []string, phaseTime *time.Duration) *plan.Prepared {
+	mode := atomic.LoadUint32(&autoReprepare)
+	if mode == REPREPARE_OFF {
+		return prepared
+	}
+
+	change := &planChange{
+		Time:      time.Now(),
+		Keyspaces: keyspaces,
+	}
+	rv := prepared
+	newPrepared, err := reprepare(prepared, phaseTime)
+	if err != nil {
+
+		// keep the plan we have, it is still good
+		change.Error = err
+	} else {
+		change.Changed = !samePlan(prepared, newPrepared)
+		if change.Changed || mode == REPREPARE_ALWAYS {
+			err = AddPrepared(newPrepared)
+			if err == nil {
+				rv = newPrepared
+			} else {
+				change.Error = err
+			}
+		}
+	}
+
+	ce.Lock()
+	ce.planChanges = append(ce.planChanges, change)
+	if len(ce.planChanges) > _PLAN_CHANGES {
+		ce.planChanges = ce.planChanges[len(ce.planChanges)-_PLAN_CHANGES:]
+	}
+	ce.Unlock()
+
+	if change.Error != nil {
+		logging.Infof("Prepared statement <ud>%v</ud> not replanned after index changes on %v: %v",
+			prepared.Name(), keyspaces, change.Error)
+	} else if change.Changed {
+		logging.Infof("Prepared statement <ud>%v</ud> has a new plan after index changes on %v", prepared.Name(), keyspaces)
+	}
+	return rv
+}
+
+// whether two plans for a statement are the same
+// the operators are compared, as auto prepared statements have no encoded plan
+func samePlan(prepared, newPrepared *plan.Prepared) bool {
+	oldBytes, err := json.Marshal(prepared.Operator)
+	if err != nil {
+		return false
+	}
+	newBytes, err := json.Marshal(newPrepared.Operator)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(oldBytes, newBytes)
+}
+
+// the plans built because of index changes, most recent last
+func (this *CacheEntry) PlanChanges() []interface{} {
+	this.Lock()
+	defer this.Unlock()
+
+	if len(this.planChanges) == 0 {
+		return nil
+	}
+	rv := make([]interface{}, len(this.planChanges))
+	for i, change := range this.planChanges {
+		keyspaces := make([]interface{}, len(change.Keyspaces))
+		for k, keyspace := range change.Keyspaces {
+			keyspaces[k] = keyspace
+		}
+		c := map[string]interface{}{
+			"time":      change.Time.String(),
+			"keyspaces": keyspaces,
+			"changed":   change.Changed,
+		}
+		if change.Error != nil {
+			c["error"] = change.Error.Error()
+		}
+		rv[i] = c
+	}
+	return rv
+}
+
 func RecordPreparedMetrics(prepared *plan.Prepared, requestTime, serviceTime time.Duration) {
 	if prepared == nil {
 		return
@@ -518,26 +668,19 @@ func RecordPreparedMetrics(prepared *plan.Prepared, requestTime, serviceTime tim
 func DecodePrepared(prepared_name string, prepared_stmt string, track bool, distribute bool, phaseTime *time.Duration) (*plan.Prepared, errors.Error) {
 	added := true
 
-	decoded, err := base64.StdEncoding.DecodeString(prepared_stmt)
+	prepared_bytes, err := decodePlan(prepared_stmt)
 	if err != nil {
-		return nil, errors.NewPreparedDecodingError(err)
-	}
-	var buf bytes.Buffer
-	buf.Write(decoded)
-	reader, err := gzip.NewReader(&buf)
-	if err != nil {
-		return nil, errors.NewPreparedDecodingError(err)
-	}
-	prepared_bytes, err := ioutil.ReadAll(reader)
-	if err != nil {
-		return nil, errors.NewPreparedDecodingError(err)
+		return nil, err
 	}
 	prepared, err := unmarshalPrepared(prepared_bytes, phaseTime)
 	if err != nil {
 		return nil, errors.NewPreparedDecodingError(err)
 	}
 
-	prepared.SetEncodedPlan(prepared_stmt)
+	// a plan reprepared because it could not be unmarshalled has its own encoding
+	if prepared.EncodedPlan() == "" {
+		prepared.SetEncodedPlan(prepared_stmt)
+	}
 
 	// MB-19509 we now have to check that the encoded plan matches
 	// the prepared statement named in the rest API
@@ -584,7 +727,7 @@ func DecodePrepared(prepared_name string, prepared_stmt string, track bool, dist
 
 	if added {
 		if distribute {
-			distributePrepared(prepared.Name(), prepared_stmt)
+			distributePrepared(prepared.Name(), prepared.EncodedPlan())
 		}
 		return prepared, nil
 	} else {
@@ -592,6 +735,39 @@ func DecodePrepared(prepared_name string, prepared_stmt string, track bool, dist
 	}
 }
 
+func decodePlan(encoded_plan string) ([]byte, errors.Error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded_plan)
+	if err != nil {
+		return nil, errors.NewPreparedDecodingError(err)
+	}
+	var buf bytes.Buffer
+	buf.Write(decoded)
+	reader, err := gzip.NewReader(&buf)
+	if err != nil {
+		return nil, errors.NewPreparedDecodingError(err)
+	}
+	prepared_bytes, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.NewPreparedDecodingError(err)
+	}
+	return prepared_bytes, nil
+}
+
+func encodePrepared(prepared *plan.Prepared) (string, errors.Error) {
+	encoded_plan := prepared.EncodedPlan()
+	if encoded_plan != "" {
+		return encoded_plan, nil
+	}
+
+	// auto prepared statements are not encoded
+	// encode a copy, as the cached plan is shared
+	json_bytes, err := prepared.MarshalJSON()
+	if err != nil {
+		return "", errors.NewPlanError(err, "")
+	}
+	return plan.NewPrepared(nil, nil).BuildEncodedPlan(json_bytes), nil
+}
+
 func unmarshalPrepared(bytes []byte, phaseTime *time.Duration) (*plan.Prepared, errors.Error) {
 	prepared := plan.NewPrepared(nil, nil)
 	err := prepared.UnmarshalJSON(bytes)
@@ -627,6 +803,15 @@ func distributePrepared(name, plan string) {
 }
 
 func reprepare(prepared *plan.Prepared, phaseTime *time.Duration) (*plan.Prepared, errors.Error) {
+
+	// a pinned plan wins, if it is still good
+	if prepared.Name() != "" {
+		pl := pinnedPlan(prepared.Name(), prepared.Text(), prepared.IndexApiVersion(), prepared.FeatureControls())
+		if pl != nil {
+			return pl, nil
+		}
+	}
+
 	parse := time.Now()
 	stmt, err := n1ql.ParseStatement(prepared.Text())
 	if phaseTime != nil {
@@ -0,0 +1,232 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package prepareds
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+// the mock datastore, with index metadata versions that can be changed,
+// as if indexes had been created
+var indexVersion uint64
+
+type versionedStore struct {
+	datastore.Datastore
+}
+
+func (this *versionedStore) NamespaceById(id string) (datastore.Namespace, errors.Error) {
+	return this.NamespaceByName(id)
+}
+
+func (this *versionedStore) NamespaceByName(name string) (datastore.Namespace, errors.Error) {
+	ns, err := this.Datastore.NamespaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &versionedNamespace{ns}, nil
+}
+
+type versionedNamespace struct {
+	datastore.Namespace
+}
+
+func (this *versionedNamespace) KeyspaceById(id string) (datastore.Keyspace, errors.Error) {
+	return this.KeyspaceByName(id)
+}
+
+func (this *versionedNamespace) KeyspaceByName(name string) (datastore.Keyspace, errors.Error) {
+	ks, err := this.Namespace.KeyspaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &versionedKeyspace{ks}, nil
+}
+
+type versionedKeyspace struct {
+	datastore.Keyspace
+}
+
+func (this *versionedKeyspace) Indexer(name datastore.IndexType) (datastore.Indexer, errors.Error) {
+	indexer, err := this.Keyspace.Indexer(name)
+	if err != nil {
+		return nil, err
+	}
+	return &versionedIndexer{indexer}, nil
+}
+
+func (this *versionedKeyspace) Indexers() ([]datastore.Indexer, errors.Error) {
+	indexer, err := this.Indexer(datastore.DEFAULT)
+	if err != nil {
+		return nil, err
+	}
+	return []datastore.Indexer{indexer}, nil
+}
+
+type versionedIndexer struct {
+	datastore.Indexer
+}
+
+func (this *versionedIndexer) MetadataVersion() uint64 {
+	return indexVersion
+}
+
+func testVersionedPrepareds(t *testing.T) {
+	ds, err := mock.NewDatastore("mock:keyspaces=2,items=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+	vs := &versionedStore{ds}
+	datastore.SetDatastore(vs)
+	PreparedsInit(1024)
+	PreparedsReprepareInit(vs, vs, "p0")
+}
+
+func TestChangedKeyspaces(t *testing.T) {
+	testVersionedPrepareds(t)
+
+	prepared := testPrepare(t, "p1", "SELECT * FROM b0")
+	if !prepared.Verify() || !prepared.MetadataCheck() {
+		t.Fatalf("expected the plan to be good")
+	}
+	if changed := prepared.ChangedKeyspaces(); len(changed) != 0 {
+		t.Errorf("expected no changed keyspaces, got %v", changed)
+	}
+
+	indexVersion++
+	if prepared.MetadataCheck() {
+		t.Errorf("expected the metadata check to fail after index changes")
+	}
+	if changed := prepared.ChangedKeyspaces(); !reflect.DeepEqual(changed, []string{"p0:b0"}) {
+		t.Errorf("expected p0:b0 to have changed, got %v", changed)
+	}
+
+	// verifying tracks the new versions
+	if !prepared.Verify() || !prepared.MetadataCheck() || len(prepared.ChangedKeyspaces()) != 0 {
+		t.Errorf("expected no changed keyspaces after verification")
+	}
+}
+
+// run with -race: requests verify the shared plan without a lock
+func TestConcurrentVerify(t *testing.T) {
+	defer PreparedsSetAutoReprepare("changed")
+	testVersionedPrepareds(t)
+	PreparedsSetAutoReprepare("off")
+
+	prepared := testPrepare(t, "p1", "SELECT * FROM b0")
+	if _, err := GetPrepared(value.NewValue("p1"), OPT_VERIFY, nil); err != nil {
+		t.Fatalf("%v", err)
+	}
+	indexVersion++
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rv, err := GetPrepared(value.NewValue("p1"), OPT_VERIFY, nil)
+			if err != nil || rv != prepared {
+				t.Errorf("expected the cached plan, got %v %v", rv, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !prepared.MetadataCheck() || len(prepared.ChangedKeyspaces()) != 0 {
+		t.Errorf("expected the new index versions to be tracked")
+	}
+}
+
+func TestReprepareModes(t *testing.T) {
+	defer PreparedsSetAutoReprepare("changed")
+
+	// the cached plan, and the plan after index changes
+	cases := []struct {
+		mode     string
+		auto     bool
+		other    string // the statement the cached plan is for, if not the same
+		replaced bool
+		changes  []interface{}
+	}{
+		{"off", false, "", false, nil},
+		{"always", false, "", true, []interface{}{false}},
+		{"always", true, "", true, []interface{}{false}},
+		{"changed", false, "", false, []interface{}{false}},
+		{"changed", true, "", false, []interface{}{false}},
+		{"changed", false, "SELECT * FROM b0 LIMIT 1", true, []interface{}{true}},
+		{"changed", true, "SELECT * FROM b0 LIMIT 1", true, []interface{}{true}},
+	}
+
+	for _, c := range cases {
+		testVersionedPrepareds(t)
+		if !PreparedsSetAutoReprepare(c.mode) {
+			t.Fatalf("unknown mode %v", c.mode)
+		}
+
+		text := "SELECT * FROM b0"
+		if c.other != "" {
+			text = c.other
+		}
+		prepared := testPrepare(t, "p1", text)
+		prepared.SetText("PREPARE p1 FROM SELECT * FROM b0")
+
+		// auto prepared statements have no encoded plan
+		if c.auto {
+			prepared.SetEncodedPlan("")
+		}
+
+		// populate, then change the indexes
+		if _, err := GetPrepared(value.NewValue("p1"), OPT_VERIFY, nil); err != nil {
+			t.Fatalf("%v: %v", c.mode, err)
+		}
+		indexVersion++
+
+		rv, err := GetPrepared(value.NewValue("p1"), OPT_VERIFY, nil)
+		if err != nil {
+			t.Fatalf("%v: %v", c.mode, err)
+		}
+		if replaced := rv != prepared; replaced != c.replaced {
+			t.Errorf("%v, auto %v, other %q: expected replaced %v, got %v", c.mode, c.auto, c.other, c.replaced, replaced)
+		}
+
+		var changes []interface{}
+		PreparedDo("p1", func(ce *CacheEntry) {
+			for _, change := range ce.PlanChanges() {
+				change := change.(map[string]interface{})
+				if change["error"] != nil {
+					t.Errorf("%v: unexpected error %v", c.mode, change["error"])
+				}
+				changes = append(changes, change["changed"])
+			}
+		})
+		if !reflect.DeepEqual(changes, c.changes) {
+			t.Errorf("%v, auto %v, other %q: expected changes %v, got %v", c.mode, c.auto, c.other, c.changes, changes)
+		}
+		if c.replaced && rv.MismatchingEncodedPlan(cachedPlan(t, "p1").EncodedPlan()) {
+			t.Errorf("%v: expected the new plan to be cached", c.mode)
+		}
+	}
+}
+
+func cachedPlan(t *testing.T, name string) *plan.Prepared {
+	var rv *plan.Prepared
+	PreparedDo(name, func(ce *CacheEntry) { rv = ce.Prepared })
+	if rv == nil {
+		t.Fatalf("expected %v to be cached", name)
+	}
+	return rv
+}
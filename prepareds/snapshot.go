@@ -0,0 +1,182 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package prepareds
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/plan"
+)
+
+// Prepared cache snapshots
+//
+// The cache can be written to a local file periodically and on shutdown,
+// and reloaded at startup, so that a restarted engine knows the statements
+// its clients have prepared.
+// Reloaded plans are verified against the current metadata, and reprepared
+// if no longer good, exactly as encoded plans received from other nodes.
+
+type snapshotEntry struct {
+	Name           string    `json:"name"`
+	EncodedPlan    string    `json:"encoded_plan"`
+	Uses           int32     `json:"uses"`
+	LastUse        time.Time `json:"lastUse"`
+	ServiceTime    uint64    `json:"serviceTime"`
+	RequestTime    uint64    `json:"requestTime"`
+	MinServiceTime uint64    `json:"minServiceTime"`
+	MinRequestTime uint64    `json:"minRequestTime"`
+	MaxServiceTime uint64    `json:"maxServiceTime"`
+	MaxRequestTime uint64    `json:"maxRequestTime"`
+}
+
+var snapshot struct {
+	sync.Mutex // one writer at a time
+	path       string
+}
+
+// reload the cache from a snapshot, and write snapshots every interval
+// needs PreparedsReprepareInit to have been called, for reprepare
+func PreparedsSnapshotInit(path string, interval time.Duration) errors.Error {
+	snapshot.Lock()
+	snapshot.path = path
+	snapshot.Unlock()
+	if path == "" {
+		return nil
+	}
+
+	err := loadSnapshot(path)
+	if err != nil {
+		return err
+	}
+
+	if interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				err := PreparedsSnapshot()
+				if err != nil {
+					logging.Errorf("Unable to write prepared statements snapshot: %v", err)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// write a snapshot of the cache now
+func PreparedsSnapshot() errors.Error {
+	snapshot.Lock()
+	defer snapshot.Unlock()
+
+	if snapshot.path == "" {
+		return nil
+	}
+
+	var cached []*CacheEntry
+	PreparedsForeach(func(name string, ce *CacheEntry) bool {
+		cached = append(cached, ce)
+		return true
+	}, nil)
+
+	// encoding happens outside of the cache lock
+	entries := make([]*snapshotEntry, 0, len(cached))
+	for _, ce := range cached {
+		prepared := ce.Prepared
+		encoded_plan, err := encodePrepared(prepared)
+		if err != nil {
+			logging.Infof("Prepared statement <ud>%v</ud> not saved: %v", prepared.Name(), err)
+			continue
+		}
+		entries = append(entries, &snapshotEntry{
+			Name:           prepared.Name(),
+			EncodedPlan:    encoded_plan,
+			Uses:           ce.Uses,
+			LastUse:        ce.LastUse,
+			ServiceTime:    uint64(ce.ServiceTime),
+			RequestTime:    uint64(ce.RequestTime),
+			MinServiceTime: uint64(ce.MinServiceTime),
+			MinRequestTime: uint64(ce.MinRequestTime),
+			MaxServiceTime: uint64(ce.MaxServiceTime),
+			MaxRequestTime: uint64(ce.MaxRequestTime),
+		})
+	}
+
+	bytes, err := json.Marshal(entries)
+	if err != nil {
+		return errors.NewPreparedSnapshotError(err)
+	}
+	err = replaceFile(snapshot.path, bytes)
+	if err != nil {
+		return errors.NewPreparedSnapshotError(err)
+	}
+	return nil
+}
+
+func loadSnapshot(path string) errors.Error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.NewPreparedSnapshotError(err)
+	}
+
+	var entries []*snapshotEntry
+	err = json.Unmarshal(bytes, &entries)
+	if err != nil {
+		return errors.NewPreparedSnapshotError(err)
+	}
+
+	count := 0
+	for _, entry := range entries {
+
+		// verify, and reprepare if needed
+		prepared, err := DecodePrepared(entry.Name, entry.EncodedPlan, false, false, nil)
+		if err != nil {
+			logging.Infof("Prepared statement <ud>%v</ud> not restored: %v", entry.Name, err)
+			continue
+		}
+		restoreStats(prepared, entry)
+		count++
+	}
+	logging.Infof("Restored %v of %v prepared statements from %v", count, len(entries), path)
+	return nil
+}
+
+func restoreStats(prepared *plan.Prepared, entry *snapshotEntry) {
+	PreparedDo(prepared.Name(), func(ce *CacheEntry) {
+		ce.Uses = entry.Uses
+		ce.LastUse = entry.LastUse
+		ce.ServiceTime = atomic.AlignedUint64(entry.ServiceTime)
+		ce.RequestTime = atomic.AlignedUint64(entry.RequestTime)
+		ce.MinServiceTime = atomic.AlignedUint64(entry.MinServiceTime)
+		ce.MinRequestTime = atomic.AlignedUint64(entry.MinRequestTime)
+		ce.MaxServiceTime = atomic.AlignedUint64(entry.MaxServiceTime)
+		ce.MaxRequestTime = atomic.AlignedUint64(entry.MaxRequestTime)
+	})
+}
+
+// write a new file and rename, so that a crash never leaves a partial file
+func replaceFile(path string, bytes []byte) error {
+	tmp := path + ".tmp"
+	err := ioutil.WriteFile(tmp, bytes, 0600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package prepareds
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/value"
+)
+
+// a cache planning against a mock datastore
+func testPrepareds(t *testing.T) datastore.Datastore {
+	ds, err := mock.NewDatastore("mock:keyspaces=2,items=3")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+	datastore.SetDatastore(ds)
+	PreparedsInit(1024)
+	PreparedsReprepareInit(ds, ds, "p0")
+	return ds
+}
+
+// plan a statement as PREPARE would, and cache it
+func testPrepare(t *testing.T, name, text string) *plan.Prepared {
+	prepared := plan.NewPrepared(nil, nil)
+	prepared.SetName(name)
+	prepared.SetText("PREPARE " + name + " FROM " + text)
+	prepared, err := reprepare(prepared, nil)
+	if err != nil {
+		t.Fatalf("Unable to prepare %s: %v", text, err)
+	}
+	if err := AddPrepared(prepared); err != nil {
+		t.Fatalf("Unable to add %s: %v", name, err)
+	}
+	return prepared
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prepareds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer PreparedsSnapshotInit("", 0)
+
+	path := filepath.Join(dir, "prepareds.json")
+	testPrepareds(t)
+
+	// a missing file is no statements
+	if err := PreparedsSnapshotInit(path, 0); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+
+	testPrepare(t, "p1", "SELECT * FROM b0")
+
+	// auto prepared statements have no encoded plan
+	auto := testPrepare(t, "p2", "SELECT * FROM b1")
+	auto.SetEncodedPlan("")
+	RecordPreparedMetrics(auto, 3*time.Millisecond, 2*time.Millisecond)
+	PreparedDo("p2", func(ce *CacheEntry) { ce.Uses = 5 })
+
+	if err := PreparedsSnapshot(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	// a restarted engine gets the statements, and their statistics, back
+	PreparedsInit(1024)
+	if err := PreparedsSnapshotInit(path, 0); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if CountPrepareds() != 2 {
+		t.Fatalf("expected 2 statements, got %v", NamePrepareds())
+	}
+	PreparedDo("p2", func(ce *CacheEntry) {
+		if ce.Uses != 5 || uint64(ce.ServiceTime) != uint64(2*time.Millisecond) ||
+			uint64(ce.MaxRequestTime) != uint64(3*time.Millisecond) ||
+			uint64(ce.MinServiceTime) != uint64(2*time.Millisecond) {
+			t.Errorf("expected the statistics of p2 to be restored, got %v uses, %v service time",
+				ce.Uses, ce.ServiceTime)
+		}
+		if !ce.populated {
+			t.Errorf("expected the plan of p2 to be verified")
+		}
+	})
+
+	// a stale plan is reprepared, and an undecodable one skipped
+	var entries []*snapshotEntry
+	bytes, _ := ioutil.ReadFile(path)
+	if err := json.Unmarshal(bytes, &entries); err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 saved statements, got %v %v", len(entries), err)
+	}
+	for _, entry := range entries {
+		if entry.Name == "p1" {
+			decoded, _ := decodePlan(entry.EncodedPlan)
+			stale := strings.Replace(string(decoded), `"keyspace":"b0"`, `"keyspace":"gone"`, -1)
+			entry.EncodedPlan = plan.NewPrepared(nil, nil).BuildEncodedPlan([]byte(stale))
+		} else {
+			entry.EncodedPlan = "not a plan"
+		}
+	}
+	bytes, _ = json.Marshal(entries)
+	if err := ioutil.WriteFile(path, bytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	PreparedsInit(1024)
+	if err := PreparedsSnapshotInit(path, 0); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	prepared, er := GetPrepared(value.NewValue("p1"), 0, nil)
+	if er != nil || CountPrepareds() != 1 {
+		t.Fatalf("expected only p1 to be restored, got %v %v", NamePrepareds(), er)
+	}
+	decoded, _ := decodePlan(prepared.EncodedPlan())
+	if !prepared.Verify() || !strings.Contains(string(decoded), `"keyspace":"b0"`) {
+		t.Errorf("expected p1 to be reprepared")
+	}
+
+	// a corrupt snapshot fails initialization
+	if err := ioutil.WriteFile(path, []byte("[{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := PreparedsSnapshotInit(path, 0); err == nil {
+		t.Errorf("expected an error for a corrupt snapshot")
+	}
+}
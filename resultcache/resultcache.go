@@ -0,0 +1,277 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package resultcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	json "github.com/couchbase/go_json"
+	"github.com/couchbase/query/accounting"
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/value"
+)
+
+// Query result cache
+//
+// Requests opt in with the result_cache request parameter, or with the
+// RESULT_CACHE hint in the statement. The full result set of a successful
+// query is kept for a time to live, keyed by statement, arguments and
+// credentials, and later identical requests are answered from the cache
+// without being queued or executed.
+//
+// Statements using functions whose results can change without the data
+// changing, such as NOW_STR(), RANDOM(), UUID() or CURL(), are not cached.
+//
+// The cache is local to each query node, and so is its invalidation.
+// Entries are invalidated when the datastore reports mutations on a
+// keyspace they reference, or when DML executed by this engine touches it.
+// Mutations made elsewhere, such as through other query nodes, and which
+// the datastore does not report, are only caught by the time to live.
+
+// the largest result set kept, in bytes
+const MAX_ENTRY_SIZE = 1 << 20
+
+type Entry struct {
+	Signature  value.Value
+	Results    [][]byte // compact JSON of each result
+	Type       string
+	Keyspaces  []string
+	Privileges *auth.Privileges
+	Hinted     bool // the statement itself asks for caching
+
+	// for EXECUTE, the prepared statement executed, which can be replaced
+	Name string
+	Text string
+
+	seq     uint64
+	expires time.Time
+}
+
+type resultCache struct {
+	cache *util.GenCache
+	ttl   time.Duration
+
+	// invalidation sequence, and when each keyspace was last invalidated
+	sync.RWMutex
+	seq         atomic.AlignedUint64
+	invalidated map[string]uint64
+}
+
+var results = &resultCache{
+	invalidated: make(map[string]uint64),
+}
+
+// init the result cache
+// a limit of 0 disables it
+func ResultCacheInit(limit int, ttl time.Duration) {
+	if results.cache == nil {
+		datastore.AddMutationListener(mutated)
+	}
+	results.cache = util.NewGenCache(limit)
+	results.ttl = ttl
+}
+
+func Enabled() bool {
+	return results.cache != nil && results.cache.Limit() != 0
+}
+
+func Limit() int {
+	if results.cache == nil {
+		return 0
+	}
+	return results.cache.Limit()
+}
+
+func SetLimit(limit int) {
+	if results.cache != nil {
+		results.cache.SetLimit(limit)
+	}
+}
+
+func Count() int {
+	if results.cache == nil {
+		return 0
+	}
+	return results.cache.Size()
+}
+
+// the cache key for a request
+// credentials include passwords, so that only requests that can be
+// authenticated as the same users share results
+func Key(statement, namespace string, namedArgs map[string]value.Value,
+	positionalArgs value.Values, creds auth.Credentials) string {
+	h := sha256.New()
+	h.Write([]byte(statement))
+	h.Write([]byte{0})
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+
+	if len(namedArgs) > 0 {
+		bytes, _ := json.Marshal(namedArgs)
+		h.Write(bytes)
+	}
+	h.Write([]byte{0})
+	if len(positionalArgs) > 0 {
+		bytes, _ := json.Marshal(positionalArgs)
+		h.Write(bytes)
+	}
+	h.Write([]byte{0})
+
+	users := make([]string, 0, len(creds))
+	for user, _ := range creds {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		h.Write([]byte(user))
+		h.Write([]byte{0})
+		h.Write([]byte(creds[user]))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// can the results of a plan be cached, and should they
+// the request option, if set, overrides the hint
+func Cacheable(prepared *plan.Prepared, option value.Tristate) bool {
+	if !Enabled() || prepared == nil || !prepared.Readonly() || prepared.Volatile() {
+		return false
+	}
+	for _, keyspace := range prepared.Keyspaces() {
+		if strings.HasPrefix(keyspace, "#system:") {
+			return false
+		}
+	}
+	if option != value.NONE {
+		return option == value.TRUE
+	}
+	hinted, _ := prepared.ResultCache()
+	return hinted
+}
+
+// the time to live for an entry: the request's, the hint's, or the default
+func TTL(prepared *plan.Prepared, ttl time.Duration) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	if prepared != nil {
+		_, ttl = prepared.ResultCache()
+		if ttl > 0 {
+			return ttl
+		}
+	}
+	return results.ttl
+}
+
+// the privileges needed to see the results of a plan
+func Privileges(prepared *plan.Prepared) *auth.Privileges {
+	if seq, ok := prepared.Operator.(*plan.Sequence); ok {
+		for _, child := range seq.Children() {
+			if authorize, ok := child.(*plan.Authorize); ok {
+				return authorize.Privileges()
+			}
+		}
+	}
+	return nil
+}
+
+// the current invalidation sequence
+// take it before executing a request whose results will be added
+func Sequence() uint64 {
+	return atomic.LoadUint64(&results.seq)
+}
+
+// a valid entry for a key, if there is one
+func Get(key string) *Entry {
+	if !Enabled() {
+		return nil
+	}
+
+	cv := results.cache.Get(key, nil)
+	if cv == nil {
+		return nil
+	}
+	entry := cv.(*Entry)
+	if time.Now().After(entry.expires) || results.stale(entry.Keyspaces, entry.seq) {
+		results.cache.Delete(key, nil)
+		return nil
+	}
+	return entry
+}
+
+// add the results of a request, executed from sequence seq on
+// results too large, or for keyspaces invalidated since, are not added
+func Add(key string, entry *Entry, seq uint64, ttl time.Duration) bool {
+	if !Enabled() || ttl <= 0 || entry.Size() > MAX_ENTRY_SIZE ||
+		results.stale(entry.Keyspaces, seq) {
+		return false
+	}
+
+	entry.seq = seq
+	entry.expires = time.Now().Add(ttl)
+	results.cache.Add(entry, key, nil)
+	return true
+}
+
+// drop the entries for the keyspaces given
+// entries are only checked, and removed, when they are next used
+func Invalidate(keyspaces ...string) {
+	if len(keyspaces) == 0 {
+		return
+	}
+
+	results.Lock()
+	defer results.Unlock()
+	seq := atomic.AddUint64(&results.seq, 1)
+	for _, keyspace := range keyspaces {
+		results.invalidated[keyspace] = seq
+	}
+}
+
+func mutated(keyspace string, keys []string) {
+	Invalidate(keyspace)
+}
+
+func (this *resultCache) stale(keyspaces []string, seq uint64) bool {
+	this.RLock()
+	defer this.RUnlock()
+	for _, keyspace := range keyspaces {
+		if this.invalidated[keyspace] > seq {
+			return true
+		}
+	}
+	return false
+}
+
+func RecordHit() {
+	accounting.UpdateCounter(accounting.RESULT_CACHE_HITS)
+}
+
+func RecordMiss() {
+	accounting.UpdateCounter(accounting.RESULT_CACHE_MISSES)
+}
+
+func (this *Entry) Size() int {
+	rv := 0
+	for _, r := range this.Results {
+		rv += len(r)
+	}
+	return rv
+}
@@ -0,0 +1,121 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package resultcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/datastore/mock"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/plan"
+	"github.com/couchbase/query/planner"
+	"github.com/couchbase/query/value"
+)
+
+func TestResultCache(t *testing.T) {
+	ResultCacheInit(4, time.Minute)
+
+	args := value.Values{value.NewValue(1)}
+	key := Key("SELECT * FROM t WHERE a = $1", "default", nil, args, auth.Credentials{"u": "p"})
+	if key == Key("SELECT * FROM t WHERE a = $1", "default", nil, args, auth.Credentials{"u": "q"}) {
+		t.Errorf("expected different keys for different credentials")
+	}
+	if key == Key("SELECT * FROM t WHERE a = $1", "default", nil, value.Values{value.NewValue(2)}, auth.Credentials{"u": "p"}) {
+		t.Errorf("expected different keys for different arguments")
+	}
+
+	// added and found
+	seq := Sequence()
+	entry := &Entry{Results: [][]byte{[]byte(`{"a":1}`)}, Keyspaces: []string{"default:t"}}
+	if !Add(key, entry, seq, time.Minute) || Get(key) != entry {
+		t.Fatalf("expected a cached entry")
+	}
+
+	// mutations drop it
+	datastore.NotifyMutation("default", "t", []string{"k"})
+	if Get(key) != nil {
+		t.Errorf("expected the entry to be invalidated")
+	}
+
+	// results seen before a mutation are not added
+	if Add(key, &Entry{Keyspaces: []string{"default:t"}}, seq, time.Minute) {
+		t.Errorf("expected stale results not to be added")
+	}
+
+	// other keyspaces are not affected
+	seq = Sequence()
+	other := &Entry{Keyspaces: []string{"default:u"}}
+	Add(key, other, seq, time.Minute)
+	Invalidate("default:t")
+	if Get(key) != other {
+		t.Errorf("expected the entry for another keyspace to be kept")
+	}
+
+	// expiry
+	Add(key, other, Sequence(), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if Get(key) != nil {
+		t.Errorf("expected the entry to expire")
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	ResultCacheInit(4, time.Minute)
+
+	ds, err := mock.NewDatastore("mock:keyspaces=2")
+	if err != nil {
+		t.Fatalf("Unable to create datastore: %v", err)
+	}
+	datastore.SetDatastore(ds)
+
+	cases := []struct {
+		text      string
+		cacheable bool
+	}{
+		{"SELECT * FROM b0 WHERE b0.a = 1", true},
+		{"SELECT NOW_STR() AS now FROM b0", false},
+		{"SELECT * FROM b0 WHERE b0.t < CLOCK_MILLIS()", false},
+		{"SELECT RANDOM() AS r, b0.* FROM b0", false},
+		{"SELECT UUID() AS id", false},
+		{"SELECT CURL(\"http://localhost:8093/admin/ping\") AS ping", false},
+		{"SELECT (SELECT RAW NOW_MILLIS() FROM b1)[0] AS now FROM b0", false},
+		{"SELECT * FROM (SELECT RANDOM() AS r FROM b1) AS d", false},
+	}
+
+	for _, c := range cases {
+		stmt, er := n1ql.ParseStatement(c.text)
+		if er != nil {
+			t.Fatalf("Unable to parse %s: %v", c.text, er)
+		}
+		prepared, er := planner.BuildPrepared(stmt, ds, nil, "p0", false, nil, nil, datastore.INDEX_API_MAX, 0)
+		if er != nil {
+			t.Fatalf("Unable to plan %s: %v", c.text, er)
+		}
+		if Cacheable(prepared, value.TRUE) != c.cacheable {
+			t.Errorf("Expected %s to be cacheable %v", c.text, c.cacheable)
+		}
+
+		// plans received from other nodes know too
+		bytes, er := prepared.MarshalJSON()
+		if er != nil {
+			t.Fatalf("Unable to marshal the plan of %s: %v", c.text, er)
+		}
+		decoded := plan.NewPrepared(nil, nil)
+		if er = decoded.UnmarshalJSON(bytes); er != nil {
+			t.Fatalf("Unable to unmarshal the plan of %s: %v", c.text, er)
+		}
+		if decoded.Volatile() != prepared.Volatile() {
+			t.Errorf("Expected the plan of %s to keep whether it is volatile", c.text)
+		}
+	}
+}
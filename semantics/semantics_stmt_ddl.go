@@ -33,6 +33,26 @@ func (this *SemChecker) VisitBuildIndexes(stmt *algebra.BuildIndexes) (interface
 	return nil, nil
 }
 
+func (this *SemChecker) VisitCreateMaterializedView(stmt *algebra.CreateMaterializedView) (interface{}, error) {
+	return stmt.Query().Accept(this)
+}
+
+func (this *SemChecker) VisitDropMaterializedView(stmt *algebra.DropMaterializedView) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *SemChecker) VisitRefreshMaterializedView(stmt *algebra.RefreshMaterializedView) (interface{}, error) {
+	return nil, nil
+}
+
+func (this *SemChecker) VisitCreateView(stmt *algebra.CreateView) (interface{}, error) {
+	return stmt.Query().Accept(this)
+}
+
+func (this *SemChecker) VisitDropView(stmt *algebra.DropView) (interface{}, error) {
+	return nil, nil
+}
+
 func (this *SemChecker) VisitGrantRole(stmt *algebra.GrantRole) (interface{}, error) {
 	return nil, nil
 }
@@ -30,12 +30,17 @@ import (
 	datastore_package "github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/datastore/resolver"
 	"github.com/couchbase/query/datastore/system"
+	"github.com/couchbase/query/execution"
 	"github.com/couchbase/query/logging"
 	log_resolver "github.com/couchbase/query/logging/resolver"
 	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/server"
 	"github.com/couchbase/query/server/http"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/util"
+	"github.com/couchbase/query/validation"
+	"github.com/couchbase/query/views"
 )
 
 var DATASTORE = flag.String("datastore", "", "Datastore address (http://URL or dir:PATH or mock:)")
@@ -61,7 +66,8 @@ var CERT_FILE = flag.String("certfile", "", "HTTPS certificate file")
 var KEY_FILE = flag.String("keyfile", "", "HTTPS private key file")
 var IPv6 = flag.Bool("ipv6", false, "Query is IPv6 compliant")
 
-var LOGGER = flag.String("logger", "", "Logger implementation")
+var AUDIT_FILE = flag.String("audit-file", "", "Local file to write audit records to, when not using the audit service")
+var LOGGER = flag.String("logger", "", "Logger implementation: golog, or json[:file[?maxsize=bytes&maxage=duration&backups=n]]")
 var LOG_LEVEL = flag.String("loglevel", "info", "Log level: debug, trace, info, warn, error, severe, none")
 var DEBUG = flag.Bool("debug", false, "Debug mode")
 var KEEP_ALIVE_LENGTH = flag.Int("keep-alive-length", server.KEEP_ALIVE_DEFAULT, "maximum size of buffered result")
@@ -82,6 +88,21 @@ var COMPLETED_LIMIT = flag.Int("completed-limit", 4000, "maximum number of compl
 
 var PREPARED_LIMIT = flag.Int("prepared-limit", 16384, "maximum number of prepared statements")
 var AUTO_PREPARE = flag.Bool("auto-prepare", false, "Silently prepare ad hoc statements if possible")
+var AUTO_REPREPARE = flag.String("auto-reprepare", "changed", "Prepare statements again when indexes change on their keyspaces: off, always, or changed to only use plans that differ")
+var PLAN_BASELINES = flag.String("plan-baselines", "", "file to persist plan baselines to; baselines are kept in memory only if not set")
+var VALIDATION_RULES = flag.String("validation-rules", "", "file to persist keyspace validation rules to; rules are kept in memory only if not set")
+var PREPARED_SNAPSHOT = flag.String("prepared-snapshot", "", "file to save prepared statements to, and restore them from at startup")
+var PREPARED_SNAPSHOT_INTERVAL = flag.Duration("prepared-snapshot-interval", 5*time.Minute, "how often to save prepared statements; use zero or negative value to only save on shutdown")
+
+var MATERIALIZED_VIEWS = flag.String("materialized-views", "", "file to persist materialized view definitions to; views are kept in memory only if not set")
+var VIEWS = flag.String("views", "", "file to persist view definitions to; views are kept in memory only if not set")
+
+var RESULT_CACHE_LIMIT = flag.Int("result-cache-limit", 1024, "maximum number of query results cached; use zero to disable the result cache")
+var RESULT_CACHE_TTL = flag.Duration("result-cache-ttl", time.Minute, "how long query results are cached, unless the request or statement says otherwise")
+
+// Tracing
+var TRACE_FILE = flag.String("trace-file", "", "file to append request traces to, in OTLP/JSON format")
+var TRACE_ENDPOINT = flag.String("trace-endpoint", "", "OTLP/HTTP collector endpoint to send request traces to, e.g. http://localhost:4318/v1/traces")
 
 // GOGC
 var _GOGC_PERCENT = 200
@@ -204,6 +225,42 @@ func main() {
 		*PREPARED_LIMIT = 16384
 	}
 	prepareds.PreparedsInit(*PREPARED_LIMIT)
+	if !prepareds.PreparedsSetAutoReprepare(*AUTO_REPREPARE) {
+		logging.Errorp("Ignoring invalid auto reprepare mode",
+			logging.Pair{"value", *AUTO_REPREPARE})
+	}
+
+	// Load the plan baselines
+	var baselineStore prepareds.BaselineStore
+	if *PLAN_BASELINES != "" {
+		baselineStore = prepareds.NewFileBaselineStore(*PLAN_BASELINES)
+	}
+	if err := prepareds.PreparedsBaselinesInit(baselineStore); err != nil {
+		logging.Errorp("Could not load plan baselines", logging.Pair{"error", err})
+		os.Exit(1)
+	}
+
+	// Load the validation rules
+	var ruleStore validation.RuleStore
+	if *VALIDATION_RULES != "" {
+		ruleStore = validation.NewFileRuleStore(*VALIDATION_RULES)
+	}
+	if err := validation.RulesInit(ruleStore); err != nil {
+		logging.Errorp("Could not load validation rules", logging.Pair{"error", err})
+		os.Exit(1)
+	}
+
+	// Initialize the query result cache
+	if *RESULT_CACHE_LIMIT < 0 || *RESULT_CACHE_TTL <= 0 {
+		logging.Errorp("Disabling the result cache: invalid size or time to live",
+			logging.Pair{"limit", *RESULT_CACHE_LIMIT}, logging.Pair{"ttl", *RESULT_CACHE_TTL})
+		*RESULT_CACHE_LIMIT = 0
+	}
+	resultcache.ResultCacheInit(*RESULT_CACHE_LIMIT, *RESULT_CACHE_TTL)
+
+	if err := tracing.SetExporter(*TRACE_FILE, *TRACE_ENDPOINT); err != nil {
+		logging.Errorp("Could not start request tracing", logging.Pair{"error", err})
+	}
 
 	numProcs := runtime.GOMAXPROCS(0)
 	channel := make(server.RequestChannel, *REQUEST_CAP*numProcs)
@@ -227,6 +284,32 @@ func main() {
 	datastore_package.SetSystemstore(server.Systemstore())
 	prepareds.PreparedsReprepareInit(datastore, sys, *NAMESPACE)
 
+	// Load the views, before the materialized views that may use them
+	var logicalViewStore views.LogicalViewStore
+	if *VIEWS != "" {
+		logicalViewStore = views.NewFileLogicalViewStore(*VIEWS)
+	}
+	if err := views.ViewsInit(logicalViewStore); err != nil {
+		logging.Errorp("Could not load views", logging.Pair{"error", err})
+		os.Exit(1)
+	}
+
+	// Load the materialized views
+	var viewStore views.ViewStore
+	if *MATERIALIZED_VIEWS != "" {
+		viewStore = views.NewFileViewStore(*MATERIALIZED_VIEWS)
+	}
+	if err := views.MaterializedViewsInit(viewStore,
+		execution.NewMaterializedViewEvaluator(datastore, server.Systemstore())); err != nil {
+		logging.Errorp("Could not load materialized views", logging.Pair{"error", err})
+		os.Exit(1)
+	}
+
+	// Restore prepared statements saved by a previous run
+	if err := prepareds.PreparedsSnapshotInit(*PREPARED_SNAPSHOT, *PREPARED_SNAPSHOT_INTERVAL); err != nil {
+		logging.Errorp("Could not restore prepared statements", logging.Pair{"error", err})
+	}
+
 	server.SetCpuProfile(*CPU_PROFILE)
 	server.SetKeepAlive(*KEEP_ALIVE_LENGTH)
 	server.SetMemProfile(*MEM_PROFILE)
@@ -244,6 +327,13 @@ func main() {
 	}
 
 	audit.StartAuditService(*DATASTORE, *SERVICERS+*PLUS_SERVICERS)
+	if *AUDIT_FILE != "" {
+		err := audit.SetAuditFile(*AUDIT_FILE)
+		if err != nil {
+			logging.Errorp("Unable to audit to file", logging.Pair{"error", err})
+			os.Exit(1)
+		}
+	}
 
 	go server.Serve()
 	go server.PlusServe()
@@ -279,6 +369,7 @@ func main() {
 
 	// Now that we are up and running, try to prime the prepareds cache
 	prepareds.PreparedsRemotePrime()
+	validation.RulesRemotePrime()
 
 	// Since TLS listener has already been started by NewServiceEndpoint
 	// So not starting here
@@ -312,6 +403,9 @@ func signalCatcher(server *server.Server, endpoint *http.HttpEndpoint) {
 			f.Close()
 		}
 	}
+	if err := prepareds.PreparedsSnapshot(); err != nil {
+		logging.Errorp("Could not save prepared statements", logging.Pair{"error", err})
+	}
 	if s == os.Interrupt {
 		// Interrupt (ctrl-C) => Immediate (ungraceful) exit
 		logging.Infop("Shutting down immediately")
@@ -10,6 +10,7 @@
 package server
 
 import (
+	"github.com/couchbase/query/audit"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/logging"
 )
@@ -19,6 +20,7 @@ const (
 	DEBUG           = "debug"
 	KEEPALIVELENGTH = "keep-alive-length"
 	LOGLEVEL        = "loglevel"
+	PKGLOGLEVELS    = "package-loglevels"
 	MAXPARALLELISM  = "max-parallelism"
 	MEMPROFILE      = "memprofile"
 	REQUESTSIZECAP  = "request-size-cap"
@@ -37,6 +39,11 @@ const (
 	CONTROLS        = "controls"
 	N1QLFEATCTRL    = "n1ql-feat-ctrl"
 	AUTOPREPARE     = "auto-prepare"
+	AUTOREPREPARE   = "auto-reprepare"
+	AUDITFILE       = "audit-file"
+	AUDITDISABLED   = "audit-disabled-events"
+	AUDITWHITELIST  = "audit-user-whitelist"
+	WORKLOADCLASSES = "workload-classes"
 )
 
 type Checker func(interface{}) (bool, errors.Error)
@@ -46,6 +53,7 @@ var CHECKERS = map[string]Checker{
 	DEBUG:           checkBool,
 	KEEPALIVELENGTH: checkNumber,
 	LOGLEVEL:        checkLogLevel,
+	PKGLOGLEVELS:    checkPackageLogLevels,
 	MAXPARALLELISM:  checkNumber,
 	MEMPROFILE:      checkString,
 	REQUESTSIZECAP:  checkNumber,
@@ -64,6 +72,11 @@ var CHECKERS = map[string]Checker{
 	CONTROLS:        checkControlsAdmin,
 	N1QLFEATCTRL:    checkNumber,
 	AUTOPREPARE:     checkBool,
+	AUTOREPREPARE:   checkAutoReprepare,
+	AUDITFILE:       checkAuditFile,
+	AUDITDISABLED:   checkAuditEvents,
+	AUDITWHITELIST:  checkAuditUsers,
+	WORKLOADCLASSES: checkWorkloadClasses,
 }
 
 func checkBool(val interface{}) (bool, errors.Error) {
@@ -138,3 +151,90 @@ func checkLogLevel(val interface{}) (bool, errors.Error) {
 	_, ok := logging.ParseLevel(level)
 	return ok, nil
 }
+
+func checkAutoReprepare(val interface{}) (bool, errors.Error) {
+	mode, is_string := val.(string)
+	if !is_string {
+		return false, nil
+	}
+	return mode == "off" || mode == "always" || mode == "changed", nil
+}
+
+// package levels map package names to levels, or to null to revert to the logger level
+func checkPackageLogLevels(val interface{}) (bool, errors.Error) {
+	object, ok := val.(map[string]interface{})
+	if !ok || logging.PackageLevels() == nil {
+		return false, nil
+	}
+	for pkg, level := range object {
+		if pkg == "" {
+			return false, nil
+		}
+		if level == nil {
+			continue
+		}
+		ok, _ = checkLogLevel(level)
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func checkAuditFile(val interface{}) (bool, errors.Error) {
+	err := audit.CheckLocalAudit()
+	if err != nil {
+		return false, err
+	}
+	return checkString(val)
+}
+
+// events are event ids or statement types
+func checkAuditEvents(val interface{}) (bool, errors.Error) {
+	err := audit.CheckLocalAudit()
+	if err != nil {
+		return false, err
+	}
+	events, ok := val.([]interface{})
+	if !ok {
+		return false, nil
+	}
+	for _, event := range events {
+		if !audit.CheckAuditEvent(event) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func checkAuditUsers(val interface{}) (bool, errors.Error) {
+	err := audit.CheckLocalAudit()
+	if err != nil {
+		return false, err
+	}
+	users, ok := val.([]interface{})
+	if !ok {
+		return false, nil
+	}
+	for _, user := range users {
+		ok, _ = checkString(user)
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func checkWorkloadClasses(val interface{}) (bool, errors.Error) {
+	classes, ok := val.(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	for name, class := range classes {
+		_, err := newWorkloadClass(name, class)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/prepareds"
 	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/validation"
 	"github.com/couchbase/query/value"
 	"github.com/gorilla/mux"
 )
@@ -30,6 +31,8 @@ const (
 	accountingPrefix = adminPrefix + "/stats"
 	vitalsPrefix     = adminPrefix + "/vitals"
 	preparedsPrefix  = adminPrefix + "/prepareds"
+	baselinesPrefix  = adminPrefix + "/baselines"
+	validationPrefix = adminPrefix + "/validations"
 	requestsPrefix   = adminPrefix + "/active_requests"
 	completedsPrefix = adminPrefix + "/completed_requests"
 	indexesPrefix    = adminPrefix + "/indexes"
@@ -61,6 +64,18 @@ func (this *HttpEndpoint) registerAccountingHandlers() {
 	preparedsHandler := func(w http.ResponseWriter, req *http.Request) {
 		this.wrapAPI(w, req, doPrepareds)
 	}
+	baselineHandler := func(w http.ResponseWriter, req *http.Request) {
+		this.wrapAPI(w, req, doBaseline)
+	}
+	baselinesHandler := func(w http.ResponseWriter, req *http.Request) {
+		this.wrapAPI(w, req, doBaselines)
+	}
+	baselineAcceptHandler := func(w http.ResponseWriter, req *http.Request) {
+		this.wrapAPI(w, req, doBaselineAccept)
+	}
+	validationHandler := func(w http.ResponseWriter, req *http.Request) {
+		this.wrapAPI(w, req, doValidation)
+	}
 	requestsHandler := func(w http.ResponseWriter, req *http.Request) {
 		this.wrapAPI(w, req, doActiveRequests)
 	}
@@ -76,6 +91,9 @@ func (this *HttpEndpoint) registerAccountingHandlers() {
 	preparedIndexHandler := func(w http.ResponseWriter, req *http.Request) {
 		this.wrapAPI(w, req, doPreparedIndex)
 	}
+	validationIndexHandler := func(w http.ResponseWriter, req *http.Request) {
+		this.wrapAPI(w, req, doValidationIndex)
+	}
 	requestIndexHandler := func(w http.ResponseWriter, req *http.Request) {
 		this.wrapAPI(w, req, doRequestIndex)
 	}
@@ -89,13 +107,19 @@ func (this *HttpEndpoint) registerAccountingHandlers() {
 		accountingPrefix:                      {handler: statsHandler, methods: []string{"GET"}},
 		accountingPrefix + "/{stat}":          {handler: statHandler, methods: []string{"GET", "DELETE"}},
 		vitalsPrefix:                          {handler: vitalsHandler, methods: []string{"GET"}},
+		vitalsPrefix + "/{name}":              {handler: vitalsHandler, methods: []string{"GET"}},
 		preparedsPrefix:                       {handler: preparedsHandler, methods: []string{"GET"}},
 		preparedsPrefix + "/{name}":           {handler: preparedHandler, methods: []string{"GET", "POST", "DELETE", "PUT"}},
+		baselinesPrefix:                       {handler: baselinesHandler, methods: []string{"GET"}},
+		baselinesPrefix + "/{name}":           {handler: baselineHandler, methods: []string{"GET", "PUT", "DELETE"}},
+		baselinesPrefix + "/{name}/accept":    {handler: baselineAcceptHandler, methods: []string{"POST"}},
+		validationPrefix + "/{name}":          {handler: validationHandler, methods: []string{"GET", "PUT", "DELETE"}},
 		requestsPrefix:                        {handler: requestsHandler, methods: []string{"GET"}},
 		requestsPrefix + "/{request}":         {handler: requestHandler, methods: []string{"GET", "POST", "DELETE"}},
 		completedsPrefix:                      {handler: completedsHandler, methods: []string{"GET"}},
 		completedsPrefix + "/{request}":       {handler: completedHandler, methods: []string{"GET", "POST", "DELETE"}},
 		indexesPrefix + "/prepareds":          {handler: preparedIndexHandler, methods: []string{"GET"}},
+		indexesPrefix + "/validations":        {handler: validationIndexHandler, methods: []string{"GET"}},
 		indexesPrefix + "/active_requests":    {handler: requestIndexHandler, methods: []string{"GET"}},
 		indexesPrefix + "/completed_requests": {handler: completedIndexHandler, methods: []string{"GET"}},
 	}
@@ -218,6 +242,20 @@ func getCredentialsFromRequest(req *http.Request) (auth.Credentials, errors.Erro
 }
 
 func verifyCredentialsFromRequest(api string, req *http.Request, af *audit.ApiAuditFields) errors.Error {
+	privs := auth.NewPrivileges()
+	privs.Add("system:"+api, auth.PRIV_SYSTEM_READ)
+	return verifyPrivilegesFromRequest(privs, req, af)
+}
+
+// Changing validation rules needs the same privilege as changing them
+// through system:validations
+func verifyValidationWriteFromRequest(req *http.Request, af *audit.ApiAuditFields) errors.Error {
+	privs := auth.NewPrivileges()
+	privs.Add("#system:validations", auth.PRIV_QUERY_INSERT)
+	return verifyPrivilegesFromRequest(privs, req, af)
+}
+
+func verifyPrivilegesFromRequest(privs *auth.Privileges, req *http.Request, af *audit.ApiAuditFields) errors.Error {
 	creds, err := getCredentialsFromRequest(req)
 	if err != nil {
 		return err
@@ -229,8 +267,6 @@ func verifyCredentialsFromRequest(api string, req *http.Request, af *audit.ApiAu
 	}
 	af.Users = users
 
-	privs := auth.NewPrivileges()
-	privs.Add("system:"+api, auth.PRIV_SYSTEM_READ)
 	_, err = datastore.GetDatastore().Authorize(privs, creds, req)
 	return err
 }
@@ -305,6 +341,12 @@ func doPrepared(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request
 			if req.Method == "POST" {
 				itemMap["plan"] = entry.Prepared.Operator
 			}
+			if baseline := prepareds.BaselineStatus(entry.Prepared); baseline != nil {
+				itemMap["baseline"] = baseline
+			}
+			if planChanges := entry.PlanChanges(); planChanges != nil {
+				itemMap["planChanges"] = planChanges
+			}
 
 			// only give times for entries that have completed at least one execution
 			if entry.Uses > 0 && entry.RequestTime > 0 {
@@ -366,6 +408,161 @@ func doPrepareds(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Reques
 	}
 }
 
+func baselineItem(b *prepareds.Baseline) map[string]interface{} {
+	itemMap := map[string]interface{}{
+		"name":            b.Name,
+		"statement":       b.Text,
+		"indexApiVersion": b.IndexApiVersion,
+		"featureControls": b.FeatureControls,
+		"encoded_plan":    b.EncodedPlan,
+		"accepted":        b.Accepted,
+		"captured":        b.Captured.String(),
+		"uses":            b.Uses,
+		"fallbacks":       b.Fallbacks,
+	}
+	if b.FallbackReason != "" {
+		itemMap["reason"] = b.FallbackReason
+	}
+	if b.Fallbacks > 0 {
+		itemMap["lastFallback"] = b.LastFallback.String()
+	}
+	return itemMap
+}
+
+func doBaseline(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
+	vars := mux.Vars(req)
+	name := vars["name"]
+
+	af.EventTypeId = audit.API_ADMIN_PREPAREDS
+	af.Name = name
+
+	err := verifyCredentialsFromRequest("prepareds", req, af)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Method {
+	case "GET":
+		b := prepareds.GetBaseline(name)
+		if b == nil {
+			return nil, errors.NewNoSuchBaselineError(name)
+		}
+		return baselineItem(b), nil
+	case "PUT":
+
+		// capture the plan currently cached for the statement
+		b, err := prepareds.CaptureBaseline(name)
+		if err != nil {
+			return nil, err
+		}
+		return baselineItem(b), nil
+	case "DELETE":
+		err = prepareds.DropBaseline(name)
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	default:
+		return nil, errors.NewServiceErrorHttpMethod(req.Method)
+	}
+}
+
+func doBaselineAccept(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
+	vars := mux.Vars(req)
+	name := vars["name"]
+
+	af.EventTypeId = audit.API_ADMIN_PREPAREDS
+	af.Name = name
+
+	switch req.Method {
+	case "POST":
+		err := verifyCredentialsFromRequest("prepareds", req, af)
+		if err != nil {
+			return nil, err
+		}
+		err = prepareds.AcceptBaseline(name)
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	default:
+		return nil, errors.NewServiceErrorHttpMethod(req.Method)
+	}
+}
+
+func doBaselines(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
+	af.EventTypeId = audit.API_ADMIN_PREPAREDS
+	switch req.Method {
+	case "GET":
+		err := verifyCredentialsFromRequest("prepareds", req, af)
+		if err != nil {
+			return nil, err
+		}
+
+		baselines := prepareds.Baselines()
+		data := make([]map[string]interface{}, len(baselines))
+		for i, b := range baselines {
+			data[i] = baselineItem(b)
+		}
+		return data, nil
+	default:
+		return nil, errors.NewServiceErrorHttpMethod(req.Method)
+	}
+}
+
+// PUT and DELETE apply rule changes made on other query nodes, and GET
+// lets a starting node copy the rules of the others
+func doValidation(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
+	vars := mux.Vars(req)
+	name := vars["name"]
+
+	af.EventTypeId = audit.API_ADMIN_VALIDATIONS
+	af.Name = name
+
+	switch req.Method {
+	case "GET":
+		err := verifyCredentialsFromRequest("validations", req, af)
+		if err != nil {
+			return nil, err
+		}
+		rule := validation.RuleRecord(name)
+		if rule == nil {
+			return nil, errors.NewServiceErrorHttpReq(name)
+		}
+		return rule, nil
+	case "PUT":
+		body, err1 := ioutil.ReadAll(req.Body)
+		defer req.Body.Close()
+
+		// http.BasicAuth eats the body, so verify credentials after getting the body.
+		err := verifyValidationWriteFromRequest(req, af)
+		if err != nil {
+			return nil, err
+		}
+
+		if err1 != nil {
+			return nil, errors.NewAdminBodyError(err1)
+		}
+		err = validation.SetRemoteRule(name, value.NewValue(body))
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	case "DELETE":
+		err := verifyValidationWriteFromRequest(req, af)
+		if err != nil {
+			return nil, err
+		}
+		_, err = validation.DeleteRemoteRule(name)
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	default:
+		return nil, errors.NewServiceErrorHttpMethod(req.Method)
+	}
+}
+
 func doActiveRequest(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
 	vars := mux.Vars(req)
 	requestId := vars["request"]
@@ -701,6 +898,16 @@ func doPreparedIndex(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Re
 	return prepareds.NamePrepareds(), nil
 }
 
+func doValidationIndex(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
+	af.EventTypeId = audit.API_ADMIN_INDEXES_VALIDATIONS
+	keys := make([]string, 0, validation.CountRules())
+	validation.ScanRules(func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys, nil
+}
+
 func doRequestIndex(endpoint *HttpEndpoint, w http.ResponseWriter, req *http.Request, af *audit.ApiAuditFields) (interface{}, errors.Error) {
 	af.EventTypeId = audit.API_ADMIN_INDEXES_ACTIVE_REQUESTS
 	numEntries, err := endpoint.actives.Count()
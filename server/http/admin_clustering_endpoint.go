@@ -414,14 +414,22 @@ func fillSettings(settings map[string]interface{}, srvr *server.Server) map[stri
 	settings[server.TIMEOUTSETTING] = srvr.Timeout()
 	settings[server.KEEPALIVELENGTH] = srvr.KeepAlive()
 	settings[server.LOGLEVEL] = srvr.LogLevel()
+	settings[server.PKGLOGLEVELS] = srvr.PackageLogLevels()
+	settings[server.WORKLOADCLASSES] = server.WorkloadClasses()
 	threshold, _ := server.RequestsGetQualifier("threshold")
 	settings[server.CMPTHRESHOLD] = threshold
 	settings[server.CMPLIMIT] = server.RequestsLimit()
 	settings[server.CMPOBJECT] = server.RequestsGetQualifiers()
 	settings[server.PRPLIMIT] = prepareds.PreparedsLimit()
+	settings[server.AUTOREPREPARE] = prepareds.PreparedsAutoReprepare()
 	settings[server.PRETTY] = srvr.Pretty()
 	settings[server.MAXINDEXAPI] = srvr.MaxIndexAPI()
 	settings[server.N1QLFEATCTRL] = util.GetN1qlFeatureControl()
+	if audit.CheckLocalAudit() == nil {
+		settings[server.AUDITFILE] = audit.AuditFile()
+		settings[server.AUDITDISABLED] = audit.DisabledEvents()
+		settings[server.AUDITWHITELIST] = audit.UserWhitelist()
+	}
 	settings = server.GetProfileAdmin(settings, srvr)
 	settings = server.GetControlsAdmin(settings, srvr)
 	return settings
@@ -149,6 +149,19 @@ func (this *HttpEndpoint) ServeHTTP(resp http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	// cached results bypass admission control and the servicers
+	if this.serveCachedResults(request) {
+		return
+	}
+
+	release, err := this.server.Admit(request)
+	if err != nil {
+		request.Fail(err)
+		request.Failed(this.server)
+		return
+	}
+	defer release()
+
 	if request.ScanConsistency() == datastore.UNBOUNDED {
 		select {
 		case this.server.Channel() <- request:
@@ -250,12 +263,41 @@ func (this *HttpEndpoint) doStats(request *httpRequest, srvr *server.Server) {
 		prepared, (request.State() != server.COMPLETED),
 		string(request.ScanConsistency()))
 
+	accounting.RecordDimensionMetrics(request_time, request.errorCount, request.Type(),
+		request.Keyspaces(), request.EventUsers(), request.UserAgent())
+
 	request.CompleteRequest(request_time, service_time, request.resultCount,
 		request.resultSize, request.errorCount, request.req, srvr)
 
+	finishTrace(request)
+
 	audit.Submit(request)
 }
 
+// decorate the request span and export the trace
+func finishTrace(request *httpRequest) {
+	trace := request.Trace()
+	if trace == nil {
+		return
+	}
+
+	root := trace.Root()
+	root.SetAttribute("db.system", "couchbase")
+	root.SetAttribute("db.statement", request.Statement())
+	root.SetAttribute("db.operation", request.Type())
+	root.SetAttribute("requestID", request.Id().String())
+	if clientId := request.ClientID().String(); clientId != "" {
+		root.SetAttribute("clientContextID", clientId)
+	}
+	root.SetAttribute("state", string(request.State()))
+	root.SetAttribute("resultCount", request.resultCount)
+	root.SetAttribute("errorCount", request.errorCount)
+	if errs := request.Errors(); len(errs) > 0 {
+		root.SetError(errs[0].Error())
+	}
+	trace.Finish()
+}
+
 func ServicePrefix() string {
 	return servicePrefix
 }
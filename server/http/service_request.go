@@ -32,6 +32,7 @@ import (
 	"github.com/couchbase/query/prepareds"
 	"github.com/couchbase/query/server"
 	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -57,6 +58,13 @@ type httpRequest struct {
 
 	stmtCnt int
 	consCnt int
+
+	// result cache
+	resultCacheKey string
+	resultCacheSeq uint64
+	caching        bool
+	cachedResults  [][]byte
+	cachedSize     int
 }
 
 func (r *httpRequest) OriginalHttpRequest() *http.Request {
@@ -92,6 +100,7 @@ func newHttpRequest(rv *httpRequest, resp http.ResponseWriter, req *http.Request
 	rv.req = req
 	server.NewBaseRequest(&rv.BaseRequest)
 	rv.SetRequestTime(reqTime)
+	rv.SetTrace(tracing.NewTrace(req.Header.Get(tracing.TRACEPARENT), "n1ql.request"))
 
 	// for GET method, only readonly access
 	if req.Method == "GET" {
@@ -381,6 +390,41 @@ func handleAutoPrepare(rv *httpRequest, httpArgs httpRequestArgs, parm string, v
 	return err
 }
 
+func handleWorkloadClass(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	class, err := httpArgs.getStringVal(parm, val)
+	if err == nil {
+		rv.SetWorkloadClass(class)
+	}
+	return err
+}
+
+// result_cache answers the request from, and adds its results to, the result
+// cache of the query node receiving it; invalidation is also local to the node,
+// so that mutations made through other query nodes may only be seen once the
+// entry's time to live has passed
+func handleResultCache(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	resultCache, err := httpArgs.getTristateVal(parm, val)
+	if err == nil {
+		rv.SetResultCache(resultCache)
+	}
+	return err
+}
+
+// result_cache_ttl is how long the results of the request are kept in the
+// result cache of the node, overriding the RESULT_CACHE hint and the default
+func handleResultCacheTTL(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
+	var ttl time.Duration
+
+	t, err := httpArgs.getStringVal(parm, val)
+	if err == nil && t != "" {
+		ttl, err = newDuration(t)
+		if err == nil {
+			rv.SetResultCacheTTL(ttl)
+		}
+	}
+	return err
+}
+
 func handleConsistency(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error {
 	rv.consCnt++
 	return nil
@@ -518,6 +562,9 @@ const ( // Request argument names
 	N1QL_FEAT_CTRL    = "n1ql_feat_ctrl"
 	MAX_INDEX_API     = "max_index_api"
 	AUTO_PREPARE      = "auto_prepare"
+	WORKLOAD_CLASS    = "workload_class"
+	RESULT_CACHE      = "result_cache"
+	RESULT_CACHE_TTL  = "result_cache_ttl"
 )
 
 var _PARAMETERS = map[string]func(rv *httpRequest, httpArgs httpRequestArgs, parm string, val interface{}) errors.Error{
@@ -549,6 +596,9 @@ var _PARAMETERS = map[string]func(rv *httpRequest, httpArgs httpRequestArgs, par
 	N1QL_FEAT_CTRL:    handleN1QLFeatCtrl,
 	MAX_INDEX_API:     handleMaxIndexAPI,
 	AUTO_PREPARE:      handleAutoPrepare,
+	WORKLOAD_CLASS:    handleWorkloadClass,
+	RESULT_CACHE:      handleResultCache,
+	RESULT_CACHE_TTL:  handleResultCacheTTL,
 }
 
 func isValidParameter(a string) bool {
@@ -878,21 +928,12 @@ func (this *urlArgs) getPositionalArgs() (value.Values, errors.Error) {
 		return positionalArgs, err
 	}
 
-	var args []interface{}
-
-	e := json.Unmarshal([]byte(args_field), &args)
-	if e != nil {
+	args, ok := newArgs(json.RawMessage(args_field))
+	if !ok {
 		return positionalArgs, errors.NewServiceErrorBadValue(go_errors.New("unable to parse args parameter as array"), ARGS)
 	}
 
-	positionalArgs = make([]value.Value, len(args))
-
-	// Put each element of args into positionalArgs
-	for i, arg := range args {
-		positionalArgs[i] = value.NewValue(arg)
-	}
-
-	return positionalArgs, nil
+	return args, nil
 }
 
 // Note: This function has no receiver, which makes it easier to test.
@@ -1099,21 +1140,31 @@ func newJsonArgs(req *http.Request) (*jsonArgs, errors.Error) {
 	if e != nil {
 		return nil, e
 	}
-	err := decoder.Decode(&p.args)
+
+	// arguments are kept raw, so that they are parsed as documents,
+	// with integers that do not fit int64 as decimals
+	var raw map[string]json.RawMessage
+	err := decoder.Decode(&raw)
 	if err != nil {
 		return nil, errors.NewServiceErrorBadValue(go_errors.New("unable to parse JSON"), "JSON request body")
 	}
-	for arg, val := range p.args {
+	p.args = make(map[string]interface{}, len(raw))
+	for arg, val := range raw {
 		newArg := strings.TrimSpace(strings.ToLower(arg))
 		if !isValidParameter(newArg) {
 			return nil, errors.NewServiceErrorUnrecognizedParameter(newArg)
 		}
 		if newArg[0] == '$' {
-			delete(p.args, arg)
-			p.named = addNamedArg(p.named, arg, value.NewValue(val))
-		} else if arg != newArg {
-			delete(p.args, arg)
+			p.named = addNamedArg(p.named, arg, value.NewValue([]byte(val)))
+		} else if newArg == ARGS {
 			p.args[newArg] = val
+		} else {
+			var v interface{}
+			err = json.Unmarshal(val, &v)
+			if err != nil {
+				return nil, errors.NewServiceErrorBadValue(go_errors.New("unable to parse JSON"), "JSON request body")
+			}
+			p.args[newArg] = v
 		}
 	}
 	p.req = req
@@ -1149,18 +1200,13 @@ func (this *jsonArgs) getPositionalArgs() (value.Values, errors.Error) {
 		return positionalArgs, nil
 	}
 
-	args, type_ok := args_field.([]interface{})
+	raw, _ := args_field.(json.RawMessage)
+	args, type_ok := newArgs(raw)
 	if !type_ok {
 		return positionalArgs, errors.NewServiceErrorTypeMismatch(ARGS, "array")
 	}
 
-	positionalArgs = make([]value.Value, len(args))
-	// Put each element of args into positionalArgs
-	for i, arg := range args {
-		positionalArgs[i] = value.NewValue(arg)
-	}
-
-	return positionalArgs, nil
+	return args, nil
 }
 
 func (this *jsonArgs) getCredentials() ([]map[string]string, errors.Error) {
@@ -1552,6 +1598,24 @@ func newScanConsistency(s string) server.ScanConsistency {
 	}
 }
 
+// newArgs parses a JSON array of positional arguments, with integers that
+// do not fit int64 as decimals
+func newArgs(raw json.RawMessage) (value.Values, bool) {
+	args := value.NewValue([]byte(raw))
+	if args.Type() != value.ARRAY {
+		return nil, false
+	}
+
+	elems := args.Actual().([]interface{})
+	positionalArgs := make(value.Values, len(elems))
+
+	// Put each element of args into positionalArgs
+	for i, elem := range elems {
+		positionalArgs[i] = value.NewValue(elem)
+	}
+	return positionalArgs, true
+}
+
 // addNamedArgs is used by getNamedArgs implementations to add a named argument
 func addNamedArg(args map[string]value.Value, name string, arg value.Value) map[string]value.Value {
 	if args == nil {
@@ -258,6 +258,38 @@ func TestRequestWithTimeout(t *testing.T) {
 	}
 }
 
+func TestLongNumberArgs(t *testing.T) {
+	long := "12345678901234567890123"
+	verifyArgs := func(kind string) {
+		args := test_server.request().PositionalArgs()
+		if len(args) != 2 || !value.IsDecimal(args[0]) || args[0].String() != long ||
+			args[1].String() != "1.5" {
+			t.Errorf("Expected %s positional args [%s, 1.5], got %v", kind, long, args)
+		}
+		named := test_server.request().NamedArgs()["n"]
+		if named == nil || !value.IsDecimal(named) || named.String() != long {
+			t.Errorf("Expected %s named arg %s, got %v", kind, long, named)
+		}
+	}
+
+	_, err := doJsonEncodedPost(map[string]interface{}{
+		"statement": "select $1, $n",
+		"args":      []interface{}{json.Number(long), 1.5},
+		"$n":        json.Number(long),
+	})
+	if err != nil {
+		t.Errorf("Unexpected error in HTTP request: %v", err)
+	}
+	verifyArgs("JSON")
+
+	doUrlRequest(t, map[string]string{
+		"statement": "select $1, $n",
+		"args":      "[" + long + ", 1.5]",
+		"$n":        long,
+	})
+	verifyArgs("URL")
+}
+
 func TestPrepareStatements(t *testing.T) {
 	preparedSequence(t, "doSelect", "SELECT b FROM p0:b0 LIMIT 5")
 	preparedSequence(t, "doInsert", "INSERT INTO p0:b0 VALUES ($1, $2)")
@@ -22,6 +22,7 @@ import (
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/execution"
 	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/server"
 	"github.com/couchbase/query/value"
 )
@@ -70,6 +71,8 @@ func mapErrorToHttpResponse(err errors.Error, def int) int {
 		return http.StatusBadRequest
 	case 1120:
 		return http.StatusNotAcceptable
+	case 1180: // rejected by admission control
+		return http.StatusServiceUnavailable
 	case 3000: // parse error range
 		return http.StatusBadRequest
 	case 4000, errors.NO_SUCH_PREPARED: // plan error range
@@ -129,6 +132,12 @@ func (this *httpRequest) Execute(srvr *server.Server, signature value.Value) {
 	this.setHttpCode(http.StatusOK)
 	this.writePrefix(srvr, signature, this.prefix, this.indent)
 
+	// not in the result cache, but will be
+	this.caching = this.resultCacheKey != "" && this.ResultCachePlan() != nil
+	if this.caching {
+		resultcache.RecordMiss()
+	}
+
 	// release writer
 	this.Done()
 
@@ -158,6 +167,9 @@ func (this *httpRequest) Execute(srvr *server.Server, signature value.Value) {
 	state := this.State()
 	this.writeSuffix(srvr, state, this.prefix, this.indent)
 	this.writer.noMoreData()
+	if this.caching && state == server.COMPLETED && this.errorCount == 0 && this.warningCount == 0 {
+		this.addCachedResults(signature)
+	}
 	if !stopped {
 		this.Stop(server.COMPLETED)
 	}
@@ -250,6 +262,9 @@ func (this *httpRequest) Result(item value.AnnotatedValue) bool {
 		} else {
 			this.resultSize += (this.writer.mark() - beforeResult)
 			this.resultCount++
+			if this.caching {
+				this.cacheResult(beforeResult)
+			}
 			this.writer.sizeFlush()
 		}
 	} else {
@@ -480,7 +495,7 @@ func (this *httpRequest) writeControls(controls bool, prefix, indent string) boo
 			e, err = json.Marshal(namedArgs)
 		}
 		if err != nil || !this.writeString(fmt.Sprintf("%s\"namedArgs\": %s", newPrefix, e)) {
-			logging.Infop("Error writing namedArgs", logging.Pair{"error", err})
+			this.Logp(logging.INFO, "Error writing namedArgs", logging.Pair{"error", err})
 		}
 		needComma = true
 	}
@@ -494,7 +509,7 @@ func (this *httpRequest) writeControls(controls bool, prefix, indent string) boo
 			e, err = json.Marshal(positionalArgs)
 		}
 		if err != nil || !this.writeString(fmt.Sprintf("%s\"positionalArgs\": %s", newPrefix, e)) {
-			logging.Infop("Error writing positional args", logging.Pair{"error", err})
+			this.Logp(logging.INFO, "Error writing positional args", logging.Pair{"error", err})
 		}
 	}
 	if prefix != "" && !(this.writeString("\n") && this.writeString(prefix)) {
@@ -533,7 +548,7 @@ func (this *httpRequest) writeProfile(profile server.Profile, prefix, indent str
 				e, err = json.Marshal(phaseTimes)
 			}
 			if err != nil || !this.writeString(fmt.Sprintf("%s\"phaseTimes\": %s", newPrefix, e)) {
-				logging.Infop("Error writing phase times", logging.Pair{"error", err})
+				this.Logp(logging.INFO, "Error writing phase times", logging.Pair{"error", err})
 			}
 			needComma = true
 		}
@@ -548,7 +563,7 @@ func (this *httpRequest) writeProfile(profile server.Profile, prefix, indent str
 				e, err = json.Marshal(phaseCounts)
 			}
 			if err != nil || !this.writeString(fmt.Sprintf("%s\"phaseCounts\": %s", newPrefix, e)) {
-				logging.Infop("Error writing phase counts", logging.Pair{"error", err})
+				this.Logp(logging.INFO, "Error writing phase counts", logging.Pair{"error", err})
 			}
 			needComma = true
 		}
@@ -563,7 +578,7 @@ func (this *httpRequest) writeProfile(profile server.Profile, prefix, indent str
 				e, err = json.Marshal(phaseOperators)
 			}
 			if err != nil || !this.writeString(fmt.Sprintf("%s\"phaseOperators\": %s", newPrefix, e)) {
-				logging.Infop("Error writing phase operators", logging.Pair{"error", err})
+				this.Logp(logging.INFO, "Error writing phase operators", logging.Pair{"error", err})
 			}
 		}
 	}
@@ -576,7 +591,7 @@ func (this *httpRequest) writeProfile(profile server.Profile, prefix, indent str
 				e, err = json.Marshal(timings)
 			}
 			if err != nil || !this.writeString(fmt.Sprintf(",%s\"executionTimings\": %s", newPrefix, e)) {
-				logging.Infop("Error writing timings", logging.Pair{"error", err})
+				this.Logp(logging.INFO, "Error writing timings", logging.Pair{"error", err})
 			}
 		}
 	}
@@ -0,0 +1,169 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/resultcache"
+	"github.com/couchbase/query/server"
+	"github.com/couchbase/query/value"
+)
+
+// Answer a request from the result cache, if its results are there and
+// it asks for them, either with the result_cache parameter or with the
+// RESULT_CACHE hint in the statement.
+// Otherwise note the cache key, so that the results can be added later.
+func (this *HttpEndpoint) serveCachedResults(request *httpRequest) bool {
+	if !resultcache.Enabled() || request.ResultCache() == value.FALSE ||
+		request.ScanConsistency() != datastore.UNBOUNDED {
+		return false
+	}
+
+	text := request.Statement()
+	if prepared := request.Prepared(); prepared != nil {
+		text = prepared.Text()
+	}
+	if text == "" {
+		return false
+	}
+
+	request.resultCacheKey = resultcache.Key(text, request.Namespace(), request.NamedArgs(),
+		request.PositionalArgs(), request.Credentials())
+	request.resultCacheSeq = resultcache.Sequence()
+
+	entry := resultcache.Get(request.resultCacheKey)
+	if entry == nil || (request.ResultCache() != value.TRUE && !entry.Hinted) {
+		return false
+	}
+
+	// EXECUTE of a prepared statement that has been replaced since
+	if entry.Name != "" {
+		replaced := true
+		prepareds.PreparedDo(entry.Name, func(ce *prepareds.CacheEntry) {
+			replaced = ce.Prepared.Text() != entry.Text
+		})
+		if replaced {
+			return false
+		}
+	}
+
+	// the users may have lost access since: let the request fail normally
+	ds := datastore.GetDatastore()
+	if ds != nil && entry.Privileges != nil {
+		_, err := ds.Authorize(entry.Privileges, request.Credentials(), request.req)
+		if err != nil {
+			return false
+		}
+	}
+
+	resultcache.RecordHit()
+	request.writeCachedResults(this.server, entry)
+	return true
+}
+
+func (this *httpRequest) writeCachedResults(srvr *server.Server, entry *resultcache.Entry) {
+	this.Servicing()
+	this.SetType(entry.Type)
+	this.SetKeyspaces(entry.Keyspaces)
+
+	this.prefix, this.indent = this.prettyStrings(srvr.Pretty(), false)
+	this.setHttpCode(http.StatusOK)
+	success := this.writePrefix(srvr, entry.Signature, this.prefix, this.indent)
+
+	for _, result := range entry.Results {
+		if !success {
+			break
+		}
+		beforeWrites := this.writer.mark()
+		if this.resultCount == 0 {
+			success = this.writer.write("\n")
+		} else {
+			success = this.writer.write(",\n")
+		}
+		if success {
+			success = this.writer.write(this.prefix)
+		}
+		beforeResult := this.writer.mark()
+		if success {
+			if this.indent == "" {
+				_, err := this.writer.buffer.Write(result)
+				success = err == nil
+			} else {
+				success = json.Indent(this.writer.buffer, result, this.prefix, this.indent) == nil
+			}
+		}
+		if !success {
+			this.writer.truncate(beforeWrites)
+			break
+		}
+		this.resultSize += (this.writer.mark() - beforeResult)
+		this.resultCount++
+		this.writer.sizeFlush()
+	}
+
+	state := server.COMPLETED
+	if !success {
+		state = server.CLOSED
+	}
+	this.SetState(state)
+	this.markTimeOfCompletion(time.Now())
+	this.writeSuffix(srvr, state, this.prefix, this.indent)
+	this.writer.noMoreData()
+}
+
+// keep a copy of the result just written, in compact form
+func (this *httpRequest) cacheResult(beforeResult int) {
+	result := this.writer.buffer.Bytes()[beforeResult:]
+
+	var compact bytes.Buffer
+	if this.indent == "" {
+		compact.Write(result)
+	} else if json.Compact(&compact, result) != nil {
+		this.stopCaching()
+		return
+	}
+
+	if this.cachedSize+compact.Len() > resultcache.MAX_ENTRY_SIZE {
+		this.stopCaching()
+		return
+	}
+	this.cachedResults = append(this.cachedResults, compact.Bytes())
+	this.cachedSize += compact.Len()
+}
+
+func (this *httpRequest) stopCaching() {
+	this.caching = false
+	this.cachedResults = nil
+	this.cachedSize = 0
+}
+
+func (this *httpRequest) addCachedResults(signature value.Value) {
+	prepared := this.ResultCachePlan()
+	hinted, _ := prepared.ResultCache()
+	entry := &resultcache.Entry{
+		Signature:  signature,
+		Results:    this.cachedResults,
+		Type:       this.Type(),
+		Keyspaces:  prepared.Keyspaces(),
+		Privileges: resultcache.Privileges(prepared),
+		Hinted:     hinted,
+	}
+	if executed := this.Prepared(); executed != nil && this.Statement() != "" && executed.Text() != this.Statement() {
+		entry.Name = executed.Name()
+		entry.Text = executed.Text()
+	}
+	resultcache.Add(this.resultCacheKey, entry, this.resultCacheSeq, this.ResultCacheTTL())
+}
@@ -21,8 +21,10 @@ import (
 	"github.com/couchbase/query/distributed"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/execution"
+	"github.com/couchbase/query/logging"
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/timestamp"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -55,6 +57,10 @@ type Request interface {
 	SetType(string)
 	IsPrepare() bool
 	SetIsPrepare(bool)
+	Keyspaces() []string
+	SetKeyspaces([]string)
+	Trace() *tracing.Trace
+	SetTrace(*tracing.Trace)
 	NamedArgs() map[string]value.Value
 	SetNamedArgs(args map[string]value.Value)
 	PositionalArgs() value.Values
@@ -93,6 +99,14 @@ type Request interface {
 	SetFeatureControls(controls uint64)
 	AutoPrepare() value.Tristate
 	SetAutoPrepare(a value.Tristate)
+	WorkloadClass() string
+	SetWorkloadClass(class string)
+	ResultCache() value.Tristate
+	SetResultCache(c value.Tristate)
+	ResultCacheTTL() time.Duration
+	SetResultCacheTTL(ttl time.Duration)
+	ResultCachePlan() *plan.Prepared
+	SetResultCachePlan(prepared *plan.Prepared)
 	SetExecTime(time time.Time)
 	RequestTime() time.Time
 	ServiceTime() time.Time
@@ -115,6 +129,7 @@ type Request interface {
 	SetTimings(o execution.Operator)
 	GetTimings() execution.Operator
 	OriginalHttpRequest() *http.Request
+	Logp(level logging.Level, msg string, kv ...logging.Pair)
 	IsAdHoc() bool
 }
 
@@ -201,6 +216,8 @@ type BaseRequest struct {
 	prepared       *plan.Prepared
 	reqType        string
 	isPrepare      bool
+	keyspaces      []string
+	trace          *tracing.Trace
 	namedArgs      map[string]value.Value
 	positionalArgs value.Values
 	namespace      string
@@ -235,6 +252,10 @@ type BaseRequest struct {
 	indexApiVersion int    // Index API version
 	featureControls uint64 // feature bit controls
 	autoPrepare     value.Tristate
+	workloadClass   string
+	resultCache     value.Tristate
+	resultCacheTTL  time.Duration
+	resultCachePlan *plan.Prepared // set if the results are to be cached
 }
 
 type requestIDImpl struct {
@@ -283,6 +304,7 @@ func NewBaseRequest(rv *BaseRequest) {
 	rv.profile = ProfUnset
 	rv.controls = value.NONE
 	rv.autoPrepare = value.NONE
+	rv.resultCache = value.NONE
 	rv.indexApiVersion = util.GetMaxIndexAPI()
 	rv.featureControls = util.GetN1qlFeatureControl()
 	uuid, _ := util.UUID()
@@ -334,6 +356,14 @@ func (this *BaseRequest) IsPrepare() bool {
 	return this.isPrepare
 }
 
+func (this *BaseRequest) Keyspaces() []string {
+	return this.keyspaces
+}
+
+func (this *BaseRequest) Trace() *tracing.Trace {
+	return this.trace
+}
+
 func (this *BaseRequest) NamedArgs() map[string]value.Value {
 	return this.namedArgs
 }
@@ -481,6 +511,16 @@ func (this *BaseRequest) SetIsPrepare(ip bool) {
 	this.isPrepare = ip
 }
 
+func (this *BaseRequest) SetKeyspaces(keyspaces []string) {
+	this.Lock()
+	defer this.Unlock()
+	this.keyspaces = keyspaces
+}
+
+func (this *BaseRequest) SetTrace(trace *tracing.Trace) {
+	this.trace = trace
+}
+
 func (this *BaseRequest) SetState(state State) {
 	this.Lock()
 	defer this.Unlock()
@@ -518,6 +558,19 @@ func (this *BaseRequest) Credentials() auth.Credentials {
 	return this.credentials
 }
 
+// log entries made on behalf of the request carry its identification
+func (this *BaseRequest) Logp(level logging.Level, msg string, kv ...logging.Pair) {
+	if !logging.Enabled(level) {
+		return
+	}
+	var clientId string
+	if this.client_id != nil {
+		clientId = this.client_id.String()
+	}
+	pairs := execution.RequestLogPairs(this.id.String(), clientId, execution.CredentialUsers(this.credentials))
+	logging.Logp(level, msg, append(kv, pairs...)...)
+}
+
 func (this *BaseRequest) SetCredentials(credentials auth.Credentials) {
 	this.credentials = credentials
 }
@@ -700,6 +753,38 @@ func (this *BaseRequest) AutoPrepare() value.Tristate {
 	return this.autoPrepare
 }
 
+func (this *BaseRequest) SetWorkloadClass(class string) {
+	this.workloadClass = class
+}
+
+func (this *BaseRequest) WorkloadClass() string {
+	return this.workloadClass
+}
+
+func (this *BaseRequest) SetResultCache(c value.Tristate) {
+	this.resultCache = c
+}
+
+func (this *BaseRequest) ResultCache() value.Tristate {
+	return this.resultCache
+}
+
+func (this *BaseRequest) SetResultCacheTTL(ttl time.Duration) {
+	this.resultCacheTTL = ttl
+}
+
+func (this *BaseRequest) ResultCacheTTL() time.Duration {
+	return this.resultCacheTTL
+}
+
+func (this *BaseRequest) SetResultCachePlan(prepared *plan.Prepared) {
+	this.resultCachePlan = prepared
+}
+
+func (this *BaseRequest) ResultCachePlan() *plan.Prepared {
+	return this.resultCachePlan
+}
+
 func (this *BaseRequest) Results() chan bool {
 	return this.stopResult
 }
@@ -32,8 +32,10 @@ import (
 	"github.com/couchbase/query/plan"
 	"github.com/couchbase/query/planner"
 	"github.com/couchbase/query/prepareds"
+	"github.com/couchbase/query/resultcache"
 	"github.com/couchbase/query/semantics"
 	queryMetakv "github.com/couchbase/query/server/settings/couchbase"
+	"github.com/couchbase/query/tracing"
 	"github.com/couchbase/query/util"
 	"github.com/couchbase/query/value"
 )
@@ -355,6 +357,34 @@ func (this *Server) SetLogLevel(level string) {
 	logging.SetLevel(lvl)
 }
 
+// the packages logged at a level other than the logger level
+func (this *Server) PackageLogLevels() map[string]interface{} {
+	levels := logging.PackageLevels()
+	rv := make(map[string]interface{}, len(levels))
+	for pkg, level := range levels {
+		rv[pkg] = level.String()
+	}
+	return rv
+}
+
+// an empty level reverts the package to the logger level
+func (this *Server) SetPackageLogLevel(pkg string, level string) {
+	if level == "" {
+		logging.ClearPackageLevel(pkg)
+		return
+	}
+	lvl, ok := logging.ParseLevel(level)
+	if !ok {
+		logging.Errorp("SetPackageLogLevel: unrecognized level", logging.Pair{"package", pkg},
+			logging.Pair{"level", level})
+		return
+	}
+	if !logging.SetPackageLevel(pkg, lvl) {
+		logging.Errorp("SetPackageLogLevel: logger does not support package levels",
+			logging.Pair{"package", pkg})
+	}
+}
+
 const (
 	MAX_REQUEST_SIZE = 64 * (1 << 20)
 )
@@ -514,7 +544,7 @@ func (this *Server) serviceRequest(request Request) {
 			buf := make([]byte, 1<<16)
 			n := runtime.Stack(buf, false)
 			s := string(buf[0:n])
-			logging.Severep("", logging.Pair{"panic", err},
+			request.Logp(logging.SEVERE, "", logging.Pair{"panic", err},
 				logging.Pair{"stack", s})
 			os.Stderr.WriteString(s)
 			os.Stderr.Sync()
@@ -544,6 +574,18 @@ func (this *Server) serviceRequest(request Request) {
 		return
 	}
 
+	// DML drops the cached results for the keyspaces it touches, both
+	// before and after mutating, so that queries running meanwhile
+	// don't cache what they see
+	if !prepared.Readonly() {
+		resultcache.Invalidate(prepared.Keyspaces()...)
+		defer resultcache.Invalidate(prepared.Keyspaces()...)
+	} else if !request.IsPrepare() && request.ScanConsistency() == datastore.UNBOUNDED &&
+		resultcache.Cacheable(prepared, request.ResultCache()) {
+		request.SetResultCachePlan(prepared)
+		request.SetResultCacheTTL(resultcache.TTL(prepared, request.ResultCacheTTL()))
+	}
+
 	maxParallelism := request.MaxParallelism()
 	if maxParallelism <= 0 {
 		maxParallelism = this.MaxParallelism()
@@ -556,6 +598,12 @@ func (this *Server) serviceRequest(request Request) {
 		prepared, request.IndexApiVersion(), request.FeatureControls())
 
 	context.SetWhitelist(this.whitelist)
+	context.SetClientContextId(request.ClientID().String())
+
+	// operator and datastore spans go under the run span
+	runSpan := request.Trace().StartSpan(execution.RUN.String(), tracing.SPAN_KIND_INTERNAL, nil)
+	defer runSpan.End()
+	context.SetTrace(request.Trace(), runSpan)
 
 	build := time.Now()
 	operator, er := execution.Build(prepared, context)
@@ -622,11 +670,15 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 
 	if prepared == nil {
 		parse := time.Now()
+		span := request.Trace().StartSpan(execution.PARSE.String(), tracing.SPAN_KIND_INTERNAL, nil)
 		stmt, err := n1ql.ParseStatement(request.Statement())
 		request.Output().AddPhaseTime(execution.PARSE, time.Since(parse))
 		if err != nil {
+			span.SetError(err.Error())
+			span.End()
 			return nil, errors.NewParseSyntaxError(err, "")
 		}
+		span.End()
 
 		semChecker := semantics.NewSemChecker()
 		_, err = stmt.Accept(semChecker)
@@ -640,6 +692,7 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 		}
 
 		prep := time.Now()
+		span = request.Trace().StartSpan(execution.PLAN.String(), tracing.SPAN_KIND_INTERNAL, nil)
 		namedArgs := request.NamedArgs()
 		positionalArgs := request.PositionalArgs()
 
@@ -653,8 +706,11 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 			namedArgs, positionalArgs, request.IndexApiVersion(), request.FeatureControls())
 		request.Output().AddPhaseTime(execution.PLAN, time.Since(prep))
 		if err != nil {
+			span.SetError(err.Error())
+			span.End()
 			return nil, errors.NewPlanError(err, "")
 		}
+		span.End()
 
 		// EXECUTE doesn't get a plan. Get the plan from the cache.
 		switch stmt.Type() {
@@ -750,23 +806,26 @@ func (this *Server) getPrepared(request Request, namespace string) (*plan.Prepar
 		request.SetType(prepared.Type())
 	}
 
+	// keyspaces referenced, for accounting purposes
+	request.SetKeyspaces(prepared.Keyspaces())
+
 	if logging.LogLevel() >= logging.DEBUG {
 		// log EXPLAIN for the request
-		logExplain(prepared)
+		logExplain(request, prepared)
 	}
 
 	return prepared, nil
 }
 
-func logExplain(prepared *plan.Prepared) {
+func logExplain(request Request, prepared *plan.Prepared) {
 	var pl plan.Operator = prepared
 	explain, err := json.MarshalIndent(pl, "", "    ")
 	if err != nil {
-		logging.Tracep("Error logging explain", logging.Pair{"error", err})
+		request.Logp(logging.TRACE, "Error logging explain", logging.Pair{"error", err})
 		return
 	}
 
-	logging.Tracep("Explain ", logging.Pair{"explain", fmt.Sprintf("<ud>%v</ud>", string(explain))})
+	request.Logp(logging.TRACE, "Explain ", logging.Pair{"explain", fmt.Sprintf("<ud>%v</ud>", string(explain))})
 }
 
 // API for tracking server options
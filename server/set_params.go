@@ -13,6 +13,7 @@ import (
 	"time"
 
 	gsi "github.com/couchbase/indexing/secondary/queryport/n1ql"
+	"github.com/couchbase/query/audit"
 	"github.com/couchbase/query/datastore"
 	"github.com/couchbase/query/errors"
 	"github.com/couchbase/query/logging"
@@ -44,6 +45,35 @@ var _SETTERS = map[string]Setter{
 		s.SetLogLevel(value)
 		return nil
 	},
+	PKGLOGLEVELS: func(s *Server, o interface{}) errors.Error {
+		value, _ := o.(map[string]interface{})
+		for pkg, level := range value {
+			lvl, _ := level.(string)
+			s.SetPackageLogLevel(pkg, lvl)
+		}
+		return nil
+	},
+	AUDITFILE: func(s *Server, o interface{}) errors.Error {
+		value, _ := o.(string)
+		return audit.SetAuditFile(value)
+	},
+	AUDITDISABLED: func(s *Server, o interface{}) errors.Error {
+		value, _ := o.([]interface{})
+		return audit.SetDisabledEvents(value)
+	},
+	AUDITWHITELIST: func(s *Server, o interface{}) errors.Error {
+		value, _ := o.([]interface{})
+		users := make([]string, 0, len(value))
+		for _, user := range value {
+			name, _ := user.(string)
+			users = append(users, name)
+		}
+		return audit.SetUserWhitelist(users)
+	},
+	WORKLOADCLASSES: func(s *Server, o interface{}) errors.Error {
+		value, _ := o.(map[string]interface{})
+		return SetWorkloadClasses(value, cap(s.channel))
+	},
 	MAXPARALLELISM: func(s *Server, o interface{}) errors.Error {
 		value := getNumber(o)
 		s.SetMaxParallelism(int(value))
@@ -125,6 +155,11 @@ var _SETTERS = map[string]Setter{
 		s.SetAutoPrepare(value)
 		return nil
 	},
+	AUTOREPREPARE: func(s *Server, o interface{}) errors.Error {
+		value, _ := o.(string)
+		prepareds.PreparedsSetAutoReprepare(value)
+		return nil
+	},
 }
 
 func getNumber(o interface{}) float64 {
@@ -0,0 +1,451 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+/*
+Admission control.
+
+Requests are assigned to named workload classes, each with its own limit
+on the requests executing at the same time, limit on the requests waiting
+for admission, default timeout and priority.
+When servicers become available, the waiting requests of the classes with
+the highest priority are admitted first, so that, for instance, a flood of
+reporting requests cannot starve interactive traffic.
+
+Requests are assigned to the class named by their workload_class parameter,
+else to the first class, by priority, listing one of their users, or a prefix
+of their client_context_id, else to the default class.
+With no classes configured, requests are admitted as soon as they are
+received, as they always were.
+*/
+
+const DEFAULT_WORKLOAD_CLASS = "default"
+
+// workload class settings
+const (
+	WLC_USERS     = "users"
+	WLC_PREFIXES  = "client-context-prefixes"
+	WLC_MAXACTIVE = "max-active"
+	WLC_MAXQUEUE  = "max-queue"
+	WLC_TIMEOUT   = "timeout"
+	WLC_PRIORITY  = "priority"
+)
+
+type workloadClass struct {
+	name      string
+	users     map[string]bool
+	prefixes  []string
+	maxActive int           // 0 for no limit
+	maxQueue  int           // 0 for no waiting
+	timeout   time.Duration // default request timeout, 0 for none
+	priority  int
+	removed   bool
+
+	active   int
+	waiters  []*workloadWaiter
+	admitted int64
+	rejected int64
+	timedOut int64
+}
+
+type workloadWaiter struct {
+	class    *workloadClass
+	pool     *workloadPool
+	admitted chan bool
+}
+
+// the servicers of a request channel
+type workloadPool struct {
+	active    int
+	servicers int
+}
+
+type workloadManager struct {
+	sync.Mutex
+	classes    []*workloadClass // by priority, highest first
+	pools      [2]workloadPool
+	configured bool
+}
+
+var workloads = &workloadManager{}
+
+func (this *workloadClass) full() bool {
+	return this.maxActive > 0 && this.active >= this.maxActive
+}
+
+func (this *workloadClass) matches(request Request) bool {
+	for user := range request.Credentials() {
+		if this.users[user] {
+			return true
+		}
+	}
+	if len(this.prefixes) > 0 {
+		id := request.ClientID().String()
+		for _, prefix := range this.prefixes {
+			if strings.HasPrefix(id, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the default class is assigned when no other is, and has no limits unless configured
+func newDefaultWorkloadClass(maxQueue int) *workloadClass {
+	return &workloadClass{
+		name:     DEFAULT_WORKLOAD_CLASS,
+		users:    map[string]bool{},
+		maxQueue: maxQueue,
+		priority: 0,
+	}
+}
+
+func (this *workloadManager) class(name string) *workloadClass {
+	for _, class := range this.classes {
+		if class.name == name && !class.removed {
+			return class
+		}
+	}
+	return nil
+}
+
+func (this *workloadManager) classify(request Request) (*workloadClass, errors.Error) {
+	name := request.WorkloadClass()
+	if name != "" {
+		class := this.class(name)
+		if class == nil {
+			return nil, errors.NewServiceErrorUnrecognizedValue("workload_class", name)
+		}
+		return class, nil
+	}
+	for _, class := range this.classes {
+		if !class.removed && class.name != DEFAULT_WORKLOAD_CLASS && class.matches(request) {
+			return class, nil
+		}
+	}
+	return this.class(DEFAULT_WORKLOAD_CLASS), nil
+}
+
+/*
+Admits a request for execution by the servicers of a request channel,
+waiting if its class or the servicers are busy.
+Once admitted, the returned function must be called when the request
+has finished executing.
+*/
+func (this *Server) Admit(request Request) (func(), errors.Error) {
+	var pool *workloadPool
+	var servicers int
+
+	if request.ScanConsistency() == datastore.UNBOUNDED {
+		pool = &workloads.pools[0]
+		servicers = this.Servicers()
+	} else {
+		pool = &workloads.pools[1]
+		servicers = this.PlusServicers()
+	}
+
+	workloads.Lock()
+	if !workloads.configured {
+		workloads.Unlock()
+		return func() {}, nil
+	}
+
+	class, err := workloads.classify(request)
+	if err != nil {
+		workloads.Unlock()
+		return nil, err
+	}
+	if class.timeout > 0 && request.Timeout() <= 0 {
+		request.SetTimeout(class.timeout)
+	}
+
+	pool.servicers = servicers
+	release := func() {
+		workloads.release(class, pool)
+	}
+
+	// nobody waiting ahead of us
+	if len(class.waiters) == 0 && !class.full() && pool.active < servicers {
+		class.active++
+		class.admitted++
+		pool.active++
+		workloads.Unlock()
+		return release, nil
+	}
+
+	if len(class.waiters) >= class.maxQueue {
+		class.rejected++
+		workloads.Unlock()
+		return nil, errors.NewServiceErrorWorkloadRejected(class.name, "queue full")
+	}
+	waiter := &workloadWaiter{
+		class:    class,
+		pool:     pool,
+		admitted: make(chan bool, 1),
+	}
+	class.waiters = append(class.waiters, waiter)
+	workloads.Unlock()
+
+	// don't wait longer than the request would be allowed to run
+	timeout := request.Timeout()
+	if this.timeout > 0 && (this.timeout < timeout || timeout <= 0) {
+		timeout = this.timeout
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-waiter.admitted:
+		return release, nil
+	case <-expired:
+	}
+
+	workloads.Lock()
+	defer workloads.Unlock()
+	for i, w := range class.waiters {
+		if w == waiter {
+			class.waiters = append(class.waiters[:i], class.waiters[i+1:]...)
+			class.timedOut++
+			return nil, errors.NewServiceErrorWorkloadRejected(class.name, "timed out waiting for admission")
+		}
+	}
+
+	// admitted as the timer fired
+	return release, nil
+}
+
+func (this *workloadManager) release(class *workloadClass, pool *workloadPool) {
+	this.Lock()
+	class.active--
+	pool.active--
+	this.dispatch(pool)
+	this.Unlock()
+}
+
+// admits waiters for the pool, highest priority first
+func (this *workloadManager) dispatch(pool *workloadPool) {
+	for _, class := range this.classes {
+		for i := 0; i < len(class.waiters) && pool.active < pool.servicers && !class.full(); {
+			waiter := class.waiters[i]
+			if waiter.pool != pool {
+				i++
+				continue
+			}
+			class.waiters = append(class.waiters[:i], class.waiters[i+1:]...)
+			class.active++
+			class.admitted++
+			pool.active++
+			waiter.admitted <- true
+		}
+	}
+
+	// forget removed classes that are done with
+	classes := this.classes[:0]
+	for _, class := range this.classes {
+		if !class.removed || class.active > 0 || len(class.waiters) > 0 {
+			classes = append(classes, class)
+		}
+	}
+	this.classes = classes
+}
+
+/*
+Replaces the workload classes.
+Requests already assigned to a class that is removed complete under it.
+An empty set of classes disables admission control.
+*/
+func SetWorkloadClasses(settings map[string]interface{}, defaultQueue int) errors.Error {
+	newClasses := make(map[string]*workloadClass, len(settings)+1)
+	for name, v := range settings {
+		class, err := newWorkloadClass(name, v)
+		if err != nil {
+			return err
+		}
+		newClasses[name] = class
+	}
+	if len(newClasses) > 0 && newClasses[DEFAULT_WORKLOAD_CLASS] == nil {
+		newClasses[DEFAULT_WORKLOAD_CLASS] = newDefaultWorkloadClass(defaultQueue)
+	}
+
+	workloads.Lock()
+	defer workloads.Unlock()
+
+	// existing classes keep their requests and counters
+	classes := make([]*workloadClass, 0, len(newClasses)+len(workloads.classes))
+	for _, class := range workloads.classes {
+		newClass, ok := newClasses[class.name]
+		if ok && !class.removed {
+			class.users = newClass.users
+			class.prefixes = newClass.prefixes
+			class.maxActive = newClass.maxActive
+			class.maxQueue = newClass.maxQueue
+			class.timeout = newClass.timeout
+			class.priority = newClass.priority
+			delete(newClasses, class.name)
+		} else {
+			class.removed = true
+		}
+		classes = append(classes, class)
+	}
+	for _, class := range newClasses {
+		classes = append(classes, class)
+	}
+	sort.SliceStable(classes, func(i, j int) bool {
+		if classes[i].priority != classes[j].priority {
+			return classes[i].priority > classes[j].priority
+		}
+		return classes[i].name < classes[j].name
+	})
+	workloads.classes = classes
+	workloads.configured = len(settings) > 0
+
+	// waiters of removed classes, or classes with higher limits, may go now
+	for i := range workloads.pools {
+		workloads.dispatch(&workloads.pools[i])
+	}
+	return nil
+}
+
+func newWorkloadClass(name string, val interface{}) (*workloadClass, errors.Error) {
+	settings, ok := val.(map[string]interface{})
+	if !ok || name == "" {
+		return nil, errors.NewAdminSettingTypeError(name, val)
+	}
+	class := &workloadClass{
+		name:  name,
+		users: map[string]bool{},
+	}
+	for setting, v := range settings {
+		ok = true
+		switch setting {
+		case WLC_USERS:
+			var users []string
+			users, ok = stringArray(v)
+			for _, user := range users {
+				class.users[user] = true
+			}
+		case WLC_PREFIXES:
+			class.prefixes, ok = stringArray(v)
+		case WLC_MAXACTIVE:
+			class.maxActive, ok = nonNegative(v)
+		case WLC_MAXQUEUE:
+			class.maxQueue, ok = nonNegative(v)
+		case WLC_TIMEOUT:
+			var timeout int
+			timeout, ok = nonNegative(v)
+			class.timeout = time.Duration(timeout)
+		case WLC_PRIORITY:
+			ok, _ = checkNumber(v)
+			class.priority = int(getNumber(v))
+		default:
+			return nil, errors.NewAdminUnknownSettingError(name + "." + setting)
+		}
+		if !ok {
+			return nil, errors.NewAdminSettingTypeError(name+"."+setting, v)
+		}
+	}
+	return class, nil
+}
+
+func stringArray(val interface{}) ([]string, bool) {
+	array, ok := val.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	rv := make([]string, len(array))
+	for i, v := range array {
+		rv[i], ok = v.(string)
+		if !ok {
+			return nil, false
+		}
+	}
+	return rv, true
+}
+
+func nonNegative(val interface{}) (int, bool) {
+	ok, _ := checkNumber(val)
+	if !ok {
+		return 0, false
+	}
+	rv := getNumber(val)
+	return int(rv), rv >= 0
+}
+
+func WorkloadClasses() map[string]interface{} {
+	workloads.Lock()
+	defer workloads.Unlock()
+
+	rv := make(map[string]interface{}, len(workloads.classes))
+	if !workloads.configured {
+		return rv
+	}
+	for _, class := range workloads.classes {
+		if class.removed {
+			continue
+		}
+		users := make([]interface{}, 0, len(class.users))
+		for user := range class.users {
+			users = append(users, user)
+		}
+		prefixes := make([]interface{}, len(class.prefixes))
+		for i, prefix := range class.prefixes {
+			prefixes[i] = prefix
+		}
+		rv[class.name] = map[string]interface{}{
+			WLC_USERS:     users,
+			WLC_PREFIXES:  prefixes,
+			WLC_MAXACTIVE: class.maxActive,
+			WLC_MAXQUEUE:  class.maxQueue,
+			WLC_TIMEOUT:   int64(class.timeout),
+			WLC_PRIORITY:  class.priority,
+		}
+	}
+	return rv
+}
+
+/*
+Per class activity, for the vitals.
+*/
+func WorkloadVitals() map[string]interface{} {
+	workloads.Lock()
+	defer workloads.Unlock()
+
+	if !workloads.configured {
+		return nil
+	}
+	rv := make(map[string]interface{}, len(workloads.classes))
+	for _, class := range workloads.classes {
+		if class.removed {
+			continue
+		}
+		rv[class.name] = map[string]interface{}{
+			"active":    class.active,
+			"queued":    len(class.waiters),
+			"admitted":  class.admitted,
+			"rejected":  class.rejected,
+			"timed_out": class.timedOut,
+		}
+	}
+	return rv
+}
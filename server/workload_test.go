@@ -0,0 +1,165 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	atomic "github.com/couchbase/go-couchbase/platform"
+	"github.com/couchbase/query/auth"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+)
+
+// only what admission control needs of a request
+type workloadRequest struct {
+	Request
+	user    string
+	client  string
+	class   string
+	timeout time.Duration
+}
+
+func (this *workloadRequest) ScanConsistency() datastore.ScanConsistency {
+	return datastore.UNBOUNDED
+}
+
+func (this *workloadRequest) Credentials() auth.Credentials {
+	return auth.Credentials{this.user: ""}
+}
+
+func (this *workloadRequest) ClientID() ClientContextID {
+	return newClientContextIDImpl(this.client)
+}
+
+func (this *workloadRequest) WorkloadClass() string {
+	return this.class
+}
+
+func (this *workloadRequest) Timeout() time.Duration {
+	return this.timeout
+}
+
+func (this *workloadRequest) SetTimeout(timeout time.Duration) {
+	this.timeout = timeout
+}
+
+func TestWorkloadClasses(t *testing.T) {
+	srvr := &Server{}
+	atomic.StoreInt64(&srvr.servicers, 2)
+	defer SetWorkloadClasses(nil, 0)
+
+	// no classes, no admission control
+	release, err := srvr.Admit(&workloadRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	release()
+
+	err = SetWorkloadClasses(map[string]interface{}{
+		"interactive": map[string]interface{}{
+			"users":     []interface{}{"alice"},
+			"max-queue": float64(10),
+			"priority":  float64(10),
+		},
+		"reporting": map[string]interface{}{
+			"client-context-prefixes": []interface{}{"report-"},
+			"max-active":              float64(1),
+			"max-queue":               float64(1),
+			"timeout":                 float64(time.Second),
+		},
+	}, 10)
+	if err != nil {
+		t.Fatalf("Unable to set workload classes: %v", err)
+	}
+
+	_, err = srvr.Admit(&workloadRequest{class: "unknown"})
+	if err == nil {
+		t.Fatalf("Expected unknown workload class to be rejected")
+	}
+
+	report := &workloadRequest{client: "report-1"}
+	releaseReport, err := srvr.Admit(report)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if report.timeout != time.Second {
+		t.Errorf("Expected class timeout, got %v", report.timeout)
+	}
+
+	// reporting is at its limit: one request can wait, a second can't
+	waiting := make(chan errors.Error)
+	go func() {
+		release, err := srvr.Admit(&workloadRequest{client: "report-2", timeout: time.Minute})
+		if err == nil {
+			release()
+		}
+		waiting <- err
+	}()
+	for vitals := WorkloadVitals(); vitals["reporting"].(map[string]interface{})["queued"] != 1; vitals = WorkloadVitals() {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = srvr.Admit(&workloadRequest{client: "report-3"})
+	if err == nil {
+		t.Fatalf("Expected reporting request to be rejected")
+	}
+
+	// the waiting request goes once the first is done
+	releaseReport()
+	if err = <-waiting; err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	reporting := WorkloadVitals()["reporting"].(map[string]interface{})
+	if reporting["admitted"] != int64(2) || reporting["rejected"] != int64(1) || reporting["active"] != 0 {
+		t.Errorf("Unexpected reporting vitals %v", reporting)
+	}
+
+	// with the servicers busy, interactive requests go ahead of reporting ones
+	atomic.StoreInt64(&srvr.servicers, 1)
+	releaseInteractive, err := srvr.Admit(&workloadRequest{user: "alice"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	admitted := make(chan string, 2)
+	admit := func(request *workloadRequest, name string) {
+		release, err := srvr.Admit(request)
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+			return
+		}
+		admitted <- name
+		<-time.After(10 * time.Millisecond)
+		release()
+	}
+	go admit(&workloadRequest{client: "report-4", timeout: time.Minute}, "reporting")
+	for WorkloadVitals()["reporting"].(map[string]interface{})["queued"] != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go admit(&workloadRequest{user: "alice", timeout: time.Minute}, "interactive")
+	for WorkloadVitals()["interactive"].(map[string]interface{})["queued"] != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	releaseInteractive()
+	if first, second := <-admitted, <-admitted; first != "interactive" || second != "reporting" {
+		t.Errorf("Expected interactive request to be admitted first, got %v, %v", first, second)
+	}
+	for WorkloadVitals()["reporting"].(map[string]interface{})["active"] != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	vitals := WorkloadVitals()
+	if vitals["default"] == nil {
+		t.Errorf("Expected default workload class in %v", vitals)
+	}
+	if vitals["interactive"].(map[string]interface{})["active"] != 0 {
+		t.Errorf("Unexpected interactive vitals %v", vitals["interactive"])
+	}
+}
@@ -0,0 +1,289 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/util"
+)
+
+const _SERVICE_NAME = "cbq-engine"
+const _SCOPE_NAME = "github.com/couchbase/query"
+
+// number of traces that can be waiting to be sent to a collector
+const _EXPORT_QUEUE = 1024
+const _EXPORT_TIMEOUT = 5 * time.Second
+
+/*
+An Exporter sends traces, encoded as OTLP/JSON
+ExportTraceServiceRequest messages, to their destination.
+*/
+type Exporter interface {
+	Export(data []byte)
+	Close()
+}
+
+var exporterLock sync.RWMutex
+var exporter Exporter
+
+func Enabled() bool {
+	exporterLock.RLock()
+	rv := exporter != nil
+	exporterLock.RUnlock()
+	return rv
+}
+
+/*
+Configures where traces are exported to: a file, where each trace is
+appended as a line, or the traces endpoint of an OTLP/HTTP collector,
+eg http://localhost:4318/v1/traces.
+Tracing is disabled if neither is specified.
+*/
+func SetExporter(file string, endpoint string) error {
+	var newExporter Exporter
+	var err error
+
+	if file != "" && endpoint != "" {
+		return fmt.Errorf("tracing can be exported either to a file or to an endpoint")
+	}
+	if file != "" {
+		newExporter, err = newFileExporter(file)
+		if err != nil {
+			return err
+		}
+	} else if endpoint != "" {
+		newExporter = newEndpointExporter(endpoint)
+	}
+
+	exporterLock.Lock()
+	oldExporter := exporter
+	exporter = newExporter
+	exporterLock.Unlock()
+
+	if oldExporter != nil {
+		oldExporter.Close()
+	}
+	return nil
+}
+
+func export(trace *Trace, spans []*Span) {
+	exporterLock.RLock()
+	defer exporterLock.RUnlock()
+	if exporter == nil || len(spans) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(marshalTrace(trace, spans))
+	if err != nil {
+		logging.Errorf("Unable to marshal trace: %v", err)
+		return
+	}
+	exporter.Export(data)
+}
+
+// OTLP/JSON encoding
+
+func marshalTrace(trace *Trace, spans []*Span) map[string]interface{} {
+	otlpSpans := make([]interface{}, len(spans))
+
+	trace.Lock()
+	for i, span := range spans {
+		otlpSpans[i] = marshalSpan(trace, span)
+	}
+	trace.Unlock()
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": marshalAttributes(map[string]interface{}{
+						"service.name":    _SERVICE_NAME,
+						"service.version": util.VERSION,
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{
+							"name": _SCOPE_NAME,
+						},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func marshalSpan(trace *Trace, span *Span) map[string]interface{} {
+	rv := map[string]interface{}{
+		"traceId":           trace.traceId.String(),
+		"spanId":            span.spanId.String(),
+		"name":              span.name,
+		"kind":              int(span.kind),
+		"startTimeUnixNano": strconv.FormatInt(span.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.end.UnixNano(), 10),
+	}
+	if span.parentId.IsValid() {
+		rv["parentSpanId"] = span.parentId.String()
+	}
+	if len(span.attributes) > 0 {
+		rv["attributes"] = marshalAttributes(span.attributes)
+	}
+
+	// status codes as per the OTLP specification: 1 ok, 2 error
+	if span.errMsg != "" {
+		rv["status"] = map[string]interface{}{
+			"code":    2,
+			"message": span.errMsg,
+		}
+	} else {
+		rv["status"] = map[string]interface{}{
+			"code": 1,
+		}
+	}
+	return rv
+}
+
+func marshalAttributes(attributes map[string]interface{}) []interface{} {
+	rv := make([]interface{}, 0, len(attributes))
+	for k, v := range attributes {
+		rv = append(rv, map[string]interface{}{
+			"key":   k,
+			"value": marshalAnyValue(v),
+		})
+	}
+	return rv
+}
+
+// 64 bit integers are encoded as strings in OTLP/JSON
+func marshalAnyValue(val interface{}) map[string]interface{} {
+	switch val := val.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": val}
+	case bool:
+		return map[string]interface{}{"boolValue": val}
+	case int:
+		return map[string]interface{}{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case uint64:
+		return map[string]interface{}{"intValue": strconv.FormatUint(val, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": val}
+	case time.Duration:
+		return map[string]interface{}{"stringValue": val.String()}
+	default:
+		return map[string]interface{}{"stringValue": fmt.Sprintf("%v", val)}
+	}
+}
+
+// file exporter
+
+type fileExporter struct {
+	sync.Mutex
+	file *os.File
+}
+
+func newFileExporter(name string) (*fileExporter, error) {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileExporter{file: file}, nil
+}
+
+func (this *fileExporter) Export(data []byte) {
+	this.Lock()
+	defer this.Unlock()
+	if this.file == nil {
+		return
+	}
+	_, err := this.file.Write(append(data, '\n'))
+	if err != nil {
+		logging.Errorf("Unable to export trace: %v", err)
+	}
+}
+
+func (this *fileExporter) Close() {
+	this.Lock()
+	defer this.Unlock()
+	if this.file != nil {
+		this.file.Close()
+		this.file = nil
+	}
+}
+
+// OTLP/HTTP collector exporter
+// traces are sent in the background so as not to delay requests, and
+// are dropped if the collector can't keep up
+
+type endpointExporter struct {
+	endpoint string
+	client   *http.Client
+	queue    chan []byte
+	closed   chan bool
+	once     sync.Once
+}
+
+func newEndpointExporter(endpoint string) *endpointExporter {
+	rv := &endpointExporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: _EXPORT_TIMEOUT},
+		queue:    make(chan []byte, _EXPORT_QUEUE),
+		closed:   make(chan bool),
+	}
+	go rv.run()
+	return rv
+}
+
+func (this *endpointExporter) Export(data []byte) {
+	select {
+	case <-this.closed:
+	case this.queue <- data:
+	default:
+		logging.Debugf("Trace export queue full, dropping trace")
+	}
+}
+
+func (this *endpointExporter) Close() {
+	this.once.Do(func() { close(this.closed) })
+}
+
+func (this *endpointExporter) run() {
+	for {
+		select {
+		case <-this.closed:
+			return
+		case data := <-this.queue:
+			this.send(data)
+		}
+	}
+}
+
+func (this *endpointExporter) send(data []byte) {
+	resp, err := this.client.Post(this.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		logging.Errorf("Unable to export trace to %v: %v", this.endpoint, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logging.Errorf("Unable to export trace to %v: %v", this.endpoint, resp.Status)
+	}
+}
@@ -0,0 +1,319 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+Package tracing records the execution of requests as a tree of spans,
+in the manner of OpenTelemetry, and exports them in OTLP/JSON format.
+
+A Trace is created for each request when an exporter is configured.
+The trace context of the caller, if any, is taken from the W3C
+traceparent header, so that the request spans become part of the
+caller's trace.
+
+All methods are safe to call on nil traces and spans, so that callers
+need not check whether tracing is enabled.
+*/
+package tracing
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SpanKind int
+
+// as per the OTLP specification
+const (
+	SPAN_KIND_UNSPECIFIED = SpanKind(iota)
+	SPAN_KIND_INTERNAL
+	SPAN_KIND_SERVER
+	SPAN_KIND_CLIENT
+)
+
+const TRACEPARENT = "traceparent"
+
+const _TRACEPARENT_VERSION = "00"
+const _FLAG_SAMPLED = 0x01
+
+type TraceId [16]byte
+type SpanId [8]byte
+
+func (this TraceId) String() string {
+	return hex.EncodeToString(this[:])
+}
+
+func (this TraceId) IsValid() bool {
+	return this != TraceId{}
+}
+
+func (this SpanId) String() string {
+	return hex.EncodeToString(this[:])
+}
+
+func (this SpanId) IsValid() bool {
+	return this != SpanId{}
+}
+
+var idLock sync.Mutex
+var idSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func newTraceId() TraceId {
+	var rv TraceId
+
+	idLock.Lock()
+	for !rv.IsValid() {
+		idSource.Read(rv[:])
+	}
+	idLock.Unlock()
+	return rv
+}
+
+func newSpanId() SpanId {
+	var rv SpanId
+
+	idLock.Lock()
+	for !rv.IsValid() {
+		idSource.Read(rv[:])
+	}
+	idLock.Unlock()
+	return rv
+}
+
+/*
+Parse a W3C traceparent header, in the form
+version-traceid-parentid-flags
+*/
+func ParseTraceParent(header string) (traceId TraceId, parentId SpanId, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return
+	}
+
+	// future versions may add fields, version 00 may not
+	if parts[0] == _TRACEPARENT_VERSION && len(parts) != 4 {
+		return
+	}
+	if len(parts[1]) != 2*len(traceId) || len(parts[2]) != 2*len(parentId) || len(parts[3]) != 2 {
+		return
+	}
+
+	_, err := hex.Decode(traceId[:], []byte(parts[1]))
+	if err != nil || !traceId.IsValid() {
+		return
+	}
+	_, err = hex.Decode(parentId[:], []byte(parts[2]))
+	if err != nil || !parentId.IsValid() {
+		return
+	}
+	var flags [1]byte
+	_, err = hex.Decode(flags[:], []byte(parts[3]))
+	if err != nil {
+		return
+	}
+	return traceId, parentId, flags[0]&_FLAG_SAMPLED != 0, true
+}
+
+/*
+Spans recorded per trace, other than the root span. Further spans are
+only counted, so that a long running request does not accumulate an
+unbounded number of spans.
+*/
+const _MAX_SPANS = 1000
+
+/*
+A Trace collects the spans of a request.
+*/
+type Trace struct {
+	sync.Mutex
+	traceId  TraceId
+	root     *Span
+	spans    []*Span
+	dropped  int
+	finished bool
+}
+
+/*
+A Span is a timed operation within a trace.
+*/
+type Span struct {
+	trace      *Trace
+	spanId     SpanId
+	parentId   SpanId
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	errMsg     string
+}
+
+/*
+Starts a new trace for a request, with a root span of the given name.
+Returns nil if tracing is not enabled, or if the caller has requested
+that the trace should not be sampled.
+*/
+func NewTrace(traceParent string, name string) *Trace {
+	if !Enabled() {
+		return nil
+	}
+
+	var parentId SpanId
+
+	rv := &Trace{}
+	if traceParent != "" {
+		traceId, spanId, sampled, ok := ParseTraceParent(traceParent)
+		if ok {
+			if !sampled {
+				return nil
+			}
+			rv.traceId = traceId
+			parentId = spanId
+		}
+	}
+	if !rv.traceId.IsValid() {
+		rv.traceId = newTraceId()
+	}
+	rv.root = rv.newSpan(name, SPAN_KIND_SERVER, parentId)
+	return rv
+}
+
+func (this *Trace) Id() string {
+	if this == nil {
+		return ""
+	}
+	return this.traceId.String()
+}
+
+func (this *Trace) Root() *Span {
+	if this == nil {
+		return nil
+	}
+	return this.root
+}
+
+/*
+The traceparent header to be used to propagate the trace to
+downstream services
+*/
+func (this *Trace) TraceParent() string {
+	if this == nil {
+		return ""
+	}
+	return _TRACEPARENT_VERSION + "-" + this.traceId.String() + "-" +
+		this.root.spanId.String() + "-01"
+}
+
+func (this *Trace) newSpan(name string, kind SpanKind, parentId SpanId) *Span {
+	return &Span{
+		trace:    this,
+		spanId:   newSpanId(),
+		parentId: parentId,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+	}
+}
+
+/*
+Starts a span as a child of parent, or of the root span if parent is nil.
+*/
+func (this *Trace) StartSpan(name string, kind SpanKind, parent *Span) *Span {
+	if this == nil {
+		return nil
+	}
+	if parent == nil {
+		parent = this.root
+	}
+	return this.newSpan(name, kind, parent.spanId)
+}
+
+/*
+Ends the root span and exports the trace.
+Spans that end after this point are discarded.
+*/
+func (this *Trace) Finish() {
+	if this == nil {
+		return
+	}
+	this.root.End()
+
+	this.Lock()
+	if this.finished {
+		this.Unlock()
+		return
+	}
+	this.finished = true
+	spans := this.spans
+	this.spans = nil
+	if this.dropped > 0 {
+		if this.root.attributes == nil {
+			this.root.attributes = make(map[string]interface{}, 1)
+		}
+		this.root.attributes["dropped_spans"] = this.dropped
+	}
+	this.Unlock()
+
+	export(this, spans)
+}
+
+/*
+Starts a child span of this span.
+*/
+func (this *Span) StartSpan(name string, kind SpanKind) *Span {
+	if this == nil {
+		return nil
+	}
+	return this.trace.newSpan(name, kind, this.spanId)
+}
+
+func (this *Span) SetAttribute(key string, val interface{}) {
+	if this == nil {
+		return
+	}
+	this.trace.Lock()
+	if this.attributes == nil {
+		this.attributes = make(map[string]interface{}, 4)
+	}
+	this.attributes[key] = val
+	this.trace.Unlock()
+}
+
+func (this *Span) SetError(msg string) {
+	if this == nil {
+		return
+	}
+	this.trace.Lock()
+	this.errMsg = msg
+	this.trace.Unlock()
+}
+
+/*
+Ends the span and records it in its trace, unless the trace already
+has _MAX_SPANS spans. Ending a span more than once has no effect.
+*/
+func (this *Span) End() {
+	if this == nil {
+		return
+	}
+	this.trace.Lock()
+	if this.end.IsZero() {
+		this.end = time.Now()
+		if !this.trace.finished {
+			if len(this.trace.spans) < _MAX_SPANS || this == this.trace.root {
+				this.trace.spans = append(this.trace.spans, this)
+			} else {
+				this.trace.dropped++
+			}
+		}
+	}
+	this.trace.Unlock()
+}
@@ -0,0 +1,203 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	traceId, parentId, sampled, ok := ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if !ok || !sampled {
+		t.Fatalf("Expected valid sampled traceparent")
+	}
+	if traceId.String() != "0af7651916cd43dd8448eb211c80319c" || parentId.String() != "b7ad6b7169203331" {
+		t.Errorf("Unexpected trace context %v %v", traceId, parentId)
+	}
+
+	_, _, sampled, ok = ParseTraceParent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	if !ok || sampled {
+		t.Errorf("Expected valid unsampled traceparent")
+	}
+
+	invalid := []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-extra",
+		"00-xyz7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	}
+	for _, header := range invalid {
+		_, _, _, ok = ParseTraceParent(header)
+		if ok {
+			t.Errorf("Expected invalid traceparent %v", header)
+		}
+	}
+}
+
+func TestFileExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if err != nil {
+		t.Fatalf("Unable to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if NewTrace("", "request") != nil {
+		t.Fatalf("Expected no trace with tracing disabled")
+	}
+
+	name := filepath.Join(dir, "traces.json")
+	err = SetExporter(name, "")
+	if err != nil {
+		t.Fatalf("Unable to set exporter: %v", err)
+	}
+	defer SetExporter("", "")
+
+	if NewTrace("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00", "request") != nil {
+		t.Fatalf("Expected no trace for unsampled request")
+	}
+
+	trace := NewTrace("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "request")
+	if trace.Id() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Fatalf("Expected trace to continue the caller's trace, got %v", trace.Id())
+	}
+	parse := trace.StartSpan("parse", SPAN_KIND_INTERNAL, nil)
+	parse.End()
+	fetch := parse.StartSpan("datastore.fetch", SPAN_KIND_CLIENT)
+	fetch.SetAttribute("keys", 10)
+	fetch.SetError("not found")
+	fetch.End()
+	unfinished := trace.StartSpan("unfinished", SPAN_KIND_INTERNAL, nil)
+	trace.Finish()
+	unfinished.End()
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Unable to read traces: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one trace, got %v", len(lines))
+	}
+
+	var exported struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceId      string `json:"traceId"`
+					SpanId       string `json:"spanId"`
+					ParentSpanId string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Kind         int    `json:"kind"`
+					Status       struct {
+						Code int `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	err = json.Unmarshal([]byte(lines[0]), &exported)
+	if err != nil {
+		t.Fatalf("Unable to unmarshal trace: %v", err)
+	}
+
+	spans := exported.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %v", len(spans))
+	}
+	ids := make(map[string]string, len(spans))
+	for _, span := range spans {
+		if span.TraceId != trace.Id() {
+			t.Errorf("Unexpected trace id %v", span.TraceId)
+		}
+		ids[span.Name] = span.SpanId
+	}
+	for _, span := range spans {
+		switch span.Name {
+		case "request":
+			if span.ParentSpanId != "b7ad6b7169203331" || span.Kind != int(SPAN_KIND_SERVER) {
+				t.Errorf("Unexpected request span %v", span)
+			}
+		case "parse":
+			if span.ParentSpanId != ids["request"] {
+				t.Errorf("Unexpected parse span parent %v", span.ParentSpanId)
+			}
+		case "datastore.fetch":
+			if span.ParentSpanId != ids["parse"] || span.Status.Code != 2 {
+				t.Errorf("Unexpected fetch span %v", span)
+			}
+		default:
+			t.Errorf("Unexpected span %v", span.Name)
+		}
+	}
+}
+
+func TestSpanLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if err != nil {
+		t.Fatalf("Unable to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "traces.json")
+	err = SetExporter(name, "")
+	if err != nil {
+		t.Fatalf("Unable to set exporter: %v", err)
+	}
+	defer SetExporter("", "")
+
+	trace := NewTrace("", "request")
+	for i := 0; i < _MAX_SPANS+10; i++ {
+		trace.StartSpan("datastore.fetch", SPAN_KIND_CLIENT, nil).End()
+	}
+	trace.Finish()
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Unable to read traces: %v", err)
+	}
+
+	var exported struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					Name       string `json:"name"`
+					Attributes []struct {
+						Key   string `json:"key"`
+						Value struct {
+							IntValue string `json:"intValue"`
+						} `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	err = json.Unmarshal(data, &exported)
+	if err != nil {
+		t.Fatalf("Unable to unmarshal trace: %v", err)
+	}
+
+	spans := exported.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != _MAX_SPANS+1 {
+		t.Fatalf("Expected %v spans, got %v", _MAX_SPANS+1, len(spans))
+	}
+	root := spans[len(spans)-1]
+	if root.Name != "request" || len(root.Attributes) != 1 ||
+		root.Attributes[0].Key != "dropped_spans" || root.Attributes[0].Value.IntValue != "10" {
+		t.Errorf("Expected the root span to count 10 dropped spans, got %v", root)
+	}
+}
@@ -27,6 +27,8 @@ func GetMaxIndexAPI() int {
 const (
 	N1QL_GROUPAGG_PUSHDOWN uint64 = 1 << iota
 	N1QL_HASH_JOIN
+	N1QL_JOIN_REORDER
+	N1QL_DECORRELATE
 	N1QL_ALL_BITS // Add anything above this. This needs to be last one
 )
 
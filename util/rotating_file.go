@@ -0,0 +1,149 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sorts in the order the files were rotated
+const _ROTATED_FORMAT = "20060102-150405.000000"
+
+/*
+A file, such as a log, that is rotated once it grows past maxSize bytes or gets
+older than maxAge. Rotated files are renamed by appending the rotation
+time to the file name, and only the last maxBackups of them are kept.
+A zero limit disables the corresponding check.
+*/
+type RotatingFile struct {
+	sync.Mutex
+	name       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	file       *os.File
+	size       int64
+	opened     time.Time
+}
+
+func NewRotatingFile(name string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	rv := &RotatingFile{
+		name:       name,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+	err := rv.open()
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+func (this *RotatingFile) Write(p []byte) (int, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.file == nil || this.mustRotate(int64(len(p))) {
+		err := this.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := this.file.Write(p)
+	this.size += int64(n)
+	return n, err
+}
+
+func (this *RotatingFile) Close() error {
+	this.Lock()
+	defer this.Unlock()
+	if this.file == nil {
+		return nil
+	}
+	err := this.file.Close()
+	this.file = nil
+	return err
+}
+
+// an empty file is never rotated, however large the entry
+func (this *RotatingFile) mustRotate(size int64) bool {
+	if this.size == 0 {
+		return false
+	}
+	if this.maxSize > 0 && this.size+size > this.maxSize {
+		return true
+	}
+	return this.maxAge > 0 && time.Since(this.opened) > this.maxAge
+}
+
+// appends to an existing file, which is aged from the time it was opened;
+// new files are only accessible to the owner, as they may hold audit records
+func (this *RotatingFile) open() error {
+	file, err := os.OpenFile(this.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	this.file = file
+	this.size = info.Size()
+	this.opened = time.Now()
+	return nil
+}
+
+func (this *RotatingFile) rotate() error {
+	if this.file != nil {
+		this.file.Close()
+		this.file = nil
+		err := os.Rename(this.name, this.name+"."+time.Now().Format(_ROTATED_FORMAT))
+		if err != nil {
+			return err
+		}
+		this.prune()
+	}
+	return this.open()
+}
+
+func (this *RotatingFile) Name() string {
+	return this.name
+}
+
+/*
+The rotated files, oldest first.
+*/
+func (this *RotatingFile) Backups() []string {
+	backups, err := filepath.Glob(this.name + ".[0-9]*")
+	if err != nil {
+		return nil
+	}
+	sort.Strings(backups)
+	return backups
+}
+
+func (this *RotatingFile) prune() {
+	if this.maxBackups <= 0 {
+		return
+	}
+	backups := this.Backups()
+	if len(backups) <= this.maxBackups {
+		return
+	}
+	for _, backup := range backups[:len(backups)-this.maxBackups] {
+		os.Remove(backup)
+	}
+}
@@ -0,0 +1,53 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotating_file")
+	if err != nil {
+		t.Fatalf("Unable to create directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "query.json")
+	file, err := NewRotatingFile(name, 10, 0, 2)
+	if err != nil {
+		t.Fatalf("Unable to create log file: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		_, err = file.Write([]byte("0123456789\n"))
+		if err != nil {
+			t.Fatalf("Unable to write log file: %v", err)
+		}
+	}
+	file.Close()
+
+	backups := file.Backups()
+	if len(backups) != 2 {
+		t.Errorf("Expected 2 backups, got %v", backups)
+	}
+	for _, backup := range append(backups, name) {
+		info, err := os.Stat(backup)
+		if err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600 for %s, got %v", backup, info)
+		}
+	}
+	data, _ := ioutil.ReadFile(name)
+	if string(data) != "0123456789\n" {
+		t.Errorf("Unexpected log file contents %v", string(data))
+	}
+}
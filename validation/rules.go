@@ -0,0 +1,206 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package validation
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/value"
+)
+
+/*
+The validation rules, one per keyspace, which INSERT, UPSERT and
+UPDATE check before writing documents. Rules are keyed by the full
+name of the keyspace, namespace:keyspace, and are maintained through
+the system:validations keyspace. Changes are persisted to the rule
+store, if there is one, and sent to the other query nodes.
+*/
+type rules struct {
+	sync.RWMutex
+	store   RuleStore
+	schemas map[string]*Schema
+}
+
+var _RULES = &rules{schemas: make(map[string]*Schema)}
+
+/*
+Returns the full name of a keyspace, defaulting the namespace.
+*/
+func RuleKey(key string) string {
+	if strings.IndexByte(key, ':') < 0 {
+		return "default:" + key
+	}
+	return key
+}
+
+/*
+Returns the schema that documents written to the keyspace must
+conform to, or nil if there is no rule.
+*/
+func Rule(namespace, keyspace string) *Schema {
+	_RULES.RLock()
+	defer _RULES.RUnlock()
+
+	if len(_RULES.schemas) == 0 {
+		return nil
+	}
+
+	return _RULES.schemas[namespace+":"+keyspace]
+}
+
+/*
+Returns the rule document for a keyspace, or nil if there is none.
+*/
+func RuleRecord(key string) value.Value {
+	key = RuleKey(key)
+
+	_RULES.RLock()
+	schema := _RULES.schemas[key]
+	_RULES.RUnlock()
+
+	if schema == nil {
+		return nil
+	}
+
+	return value.NewValue(map[string]interface{}{
+		"keyspace": key,
+		"schema":   schema.Source(),
+	})
+}
+
+/*
+Calls f with the keyspace of each rule, in order, until it returns
+false.
+*/
+func ScanRules(f func(key string) bool) {
+	_RULES.RLock()
+	keys := make([]string, 0, len(_RULES.schemas))
+	for key, _ := range _RULES.schemas {
+		keys = append(keys, key)
+	}
+	_RULES.RUnlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !f(key) {
+			return
+		}
+	}
+}
+
+func CountRules() int {
+	_RULES.RLock()
+	defer _RULES.RUnlock()
+	return len(_RULES.schemas)
+}
+
+const (
+	RULE_INSERT = iota
+	RULE_UPDATE
+	RULE_UPSERT
+)
+
+/*
+Adds or replaces the rule for a keyspace. The rule document must be
+an object whose schema field holds the JSON schema.
+*/
+func SetRule(key string, rule value.Value, mode int) errors.Error {
+	return changeRule(RuleKey(key), rule, mode, true)
+}
+
+/*
+Applies a rule set on another query node.
+*/
+func SetRemoteRule(key string, rule value.Value) errors.Error {
+	return changeRule(RuleKey(key), rule, RULE_UPSERT, false)
+}
+
+func changeRule(key string, rule value.Value, mode int, distribute bool) errors.Error {
+	var source value.Value
+	if rule.Type() == value.OBJECT {
+		source, _ = rule.Field("schema")
+	}
+	if source == nil || source.Type() == value.MISSING {
+		return errors.NewSystemValidationRuleError(nil, key, "the rule must have a schema field")
+	}
+
+	schema, err := NewSchema(source)
+	if err != nil {
+		return errors.NewSystemValidationRuleError(err, key, "invalid schema")
+	}
+
+	_RULES.Lock()
+	old, exists := _RULES.schemas[key]
+	switch {
+	case mode == RULE_INSERT && exists:
+		_RULES.Unlock()
+		return errors.NewSystemValidationRuleError(nil, key, "a rule already exists")
+	case mode == RULE_UPDATE && !exists:
+		_RULES.Unlock()
+		return errors.NewSystemValidationRuleError(nil, key, "there is no rule")
+	}
+
+	_RULES.schemas[key] = schema
+	err1 := _RULES.save()
+	if err1 != nil {
+		if exists {
+			_RULES.schemas[key] = old
+		} else {
+			delete(_RULES.schemas, key)
+		}
+	}
+	_RULES.Unlock()
+
+	if err1 == nil && distribute {
+		distributeRule(key, rule)
+	}
+	return err1
+}
+
+/*
+Removes the rule for a keyspace, returning false if there was none.
+*/
+func DeleteRule(key string) (bool, errors.Error) {
+	return deleteRule(RuleKey(key), true)
+}
+
+/*
+Applies a rule removal made on another query node.
+*/
+func DeleteRemoteRule(key string) (bool, errors.Error) {
+	return deleteRule(RuleKey(key), false)
+}
+
+func deleteRule(key string, distribute bool) (bool, errors.Error) {
+	_RULES.Lock()
+	old, exists := _RULES.schemas[key]
+	if !exists {
+		_RULES.Unlock()
+		return false, nil
+	}
+
+	delete(_RULES.schemas, key)
+	err := _RULES.save()
+	if err != nil {
+		_RULES.schemas[key] = old
+	}
+	_RULES.Unlock()
+
+	if err != nil {
+		return false, err
+	}
+	if distribute {
+		distributeRule(key, nil)
+	}
+	return true, nil
+}
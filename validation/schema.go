@@ -0,0 +1,936 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+/*
+Package validation checks values against JSON Schema documents, and
+holds the validation rules that keyspaces enforce on the documents
+written to them.
+
+The supported JSON Schema (draft 7) keywords are type, enum, const,
+properties, patternProperties, additionalProperties, required,
+propertyNames, minProperties, maxProperties, dependencies, items,
+additionalItems, contains, minItems, maxItems, uniqueItems, minLength,
+maxLength, pattern, format, minimum, maximum, exclusiveMinimum,
+exclusiveMaximum, multipleOf, allOf, anyOf, oneOf, not, if, then,
+else, and $ref to local definitions. Other keywords are ignored.
+*/
+package validation
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/couchbase/query/value"
+)
+
+/*
+A compiled JSON schema.
+*/
+type Schema struct {
+	source value.Value
+	root   *node
+}
+
+/*
+A violation of a schema, at the JSON pointer path of the offending
+part of the value.
+*/
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (this *SchemaError) Error() string {
+	if this.Path == "" {
+		return this.Message
+	}
+
+	return this.Path + ": " + this.Message
+}
+
+/*
+Compiles a JSON schema, which must be an object or a boolean.
+*/
+func NewSchema(source value.Value) (*Schema, error) {
+	c := &compiler{
+		source: source,
+		refs:   make(map[string]*node),
+	}
+
+	root, err := c.compile(source, "#")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{
+		source: source,
+		root:   root,
+	}, nil
+}
+
+/*
+The schema document.
+*/
+func (this *Schema) Source() value.Value {
+	return this.source
+}
+
+/*
+Returns the ways in which val violates the schema, if any.
+*/
+func (this *Schema) Validate(val value.Value) []*SchemaError {
+	var errs []*SchemaError
+	this.root.validate(val, "", &errs)
+	return errs
+}
+
+/*
+Returns true if val conforms to the schema.
+*/
+func (this *Schema) Valid(val value.Value) bool {
+	var errs []*SchemaError
+	return this.root.check(val, "", &errs, true, 0)
+}
+
+type pattern struct {
+	re     *regexp.Regexp
+	schema *node
+}
+
+type node struct {
+	always *bool
+
+	types    []string
+	enum     []value.Value
+	constant value.Value
+
+	properties    map[string]*node
+	patterns      []*pattern
+	additional    *node
+	required      []string
+	propertyNames *node
+	minProperties int
+	maxProperties int
+	dependencies  map[string]*node
+	depRequired   map[string][]string
+
+	items           *node
+	tupleItems      []*node
+	additionalItems *node
+	contains        *node
+	minItems        int
+	maxItems        int
+	uniqueItems     bool
+
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+	format    string
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       float64
+
+	allOf []*node
+	anyOf []*node
+	oneOf []*node
+	not   *node
+	ifS   *node
+	thenS *node
+	elseS *node
+	ref   *node
+}
+
+type compiler struct {
+	source value.Value
+	refs   map[string]*node
+}
+
+func (this *compiler) compile(schema value.Value, path string) (*node, error) {
+	rv := &node{
+		minProperties: -1,
+		maxProperties: -1,
+		minItems:      -1,
+		maxItems:      -1,
+		minLength:     -1,
+		maxLength:     -1,
+	}
+
+	switch schema.Type() {
+	case value.BOOLEAN:
+		always := schema.Truth()
+		rv.always = &always
+		return rv, nil
+	case value.OBJECT:
+	default:
+		return nil, fmt.Errorf("Schema %s must be an object or a boolean.", path)
+	}
+
+	fields := schema.Fields()
+	for _, name := range sortedNames(fields) {
+		v := value.NewValue(fields[name])
+		at := path + "/" + name
+		var err error
+
+		switch name {
+		case "type":
+			rv.types, err = stringList(v, at, true)
+			for _, t := range rv.types {
+				switch t {
+				case "null", "boolean", "object", "array", "number", "integer", "string":
+				default:
+					err = fmt.Errorf("Invalid type %s in schema %s.", t, at)
+				}
+			}
+		case "enum":
+			if v.Type() != value.ARRAY {
+				err = fmt.Errorf("Schema %s must be an array.", at)
+				break
+			}
+			for _, e := range v.Actual().([]interface{}) {
+				rv.enum = append(rv.enum, value.NewValue(e))
+			}
+		case "const":
+			rv.constant = v
+		case "properties", "patternProperties", "dependencies", "definitions", "$defs":
+			if v.Type() != value.OBJECT {
+				err = fmt.Errorf("Schema %s must be an object.", at)
+				break
+			}
+
+			props := v.Fields()
+			for _, pname := range sortedNames(props) {
+				p := value.NewValue(props[pname])
+				var pn *node
+				switch name {
+				case "properties":
+					pn, err = this.compile(p, at+"/"+pname)
+					if rv.properties == nil {
+						rv.properties = make(map[string]*node, len(props))
+					}
+					rv.properties[pname] = pn
+				case "patternProperties":
+					var re *regexp.Regexp
+					re, err = regexp.Compile(pname)
+					if err == nil {
+						pn, err = this.compile(p, at+"/"+pname)
+						rv.patterns = append(rv.patterns, &pattern{re, pn})
+					}
+				case "dependencies":
+					if p.Type() == value.ARRAY {
+						var req []string
+						req, err = stringList(p, at+"/"+pname, false)
+						if rv.depRequired == nil {
+							rv.depRequired = make(map[string][]string)
+						}
+						rv.depRequired[pname] = req
+					} else {
+						pn, err = this.compile(p, at+"/"+pname)
+						if rv.dependencies == nil {
+							rv.dependencies = make(map[string]*node)
+						}
+						rv.dependencies[pname] = pn
+					}
+				default:
+					// compiled when referenced
+				}
+
+				if err != nil {
+					break
+				}
+			}
+		case "additionalProperties":
+			rv.additional, err = this.compile(v, at)
+		case "required":
+			rv.required, err = stringList(v, at, false)
+		case "propertyNames":
+			rv.propertyNames, err = this.compile(v, at)
+		case "minProperties":
+			rv.minProperties, err = count(v, at)
+		case "maxProperties":
+			rv.maxProperties, err = count(v, at)
+		case "items":
+			if v.Type() == value.ARRAY {
+				rv.tupleItems, err = this.compileList(v, at)
+			} else {
+				rv.items, err = this.compile(v, at)
+			}
+		case "additionalItems":
+			rv.additionalItems, err = this.compile(v, at)
+		case "contains":
+			rv.contains, err = this.compile(v, at)
+		case "minItems":
+			rv.minItems, err = count(v, at)
+		case "maxItems":
+			rv.maxItems, err = count(v, at)
+		case "uniqueItems":
+			rv.uniqueItems = v.Truth()
+		case "minLength":
+			rv.minLength, err = count(v, at)
+		case "maxLength":
+			rv.maxLength, err = count(v, at)
+		case "pattern":
+			if v.Type() != value.STRING {
+				err = fmt.Errorf("Schema %s must be a string.", at)
+				break
+			}
+			rv.pattern, err = regexp.Compile(v.Actual().(string))
+		case "format":
+			if v.Type() != value.STRING {
+				err = fmt.Errorf("Schema %s must be a string.", at)
+				break
+			}
+			rv.format = v.Actual().(string)
+		case "minimum":
+			rv.minimum, err = number(v, at)
+		case "maximum":
+			rv.maximum, err = number(v, at)
+		case "exclusiveMinimum":
+			rv.exclusiveMinimum, err = number(v, at)
+		case "exclusiveMaximum":
+			rv.exclusiveMaximum, err = number(v, at)
+		case "multipleOf":
+			var m *float64
+			m, err = number(v, at)
+			if err == nil && *m <= 0.0 {
+				err = fmt.Errorf("Schema %s must be positive.", at)
+			} else if err == nil {
+				rv.multipleOf = *m
+			}
+		case "allOf":
+			rv.allOf, err = this.compileList(v, at)
+		case "anyOf":
+			rv.anyOf, err = this.compileList(v, at)
+		case "oneOf":
+			rv.oneOf, err = this.compileList(v, at)
+		case "not":
+			rv.not, err = this.compile(v, at)
+		case "if":
+			rv.ifS, err = this.compile(v, at)
+		case "then":
+			rv.thenS, err = this.compile(v, at)
+		case "else":
+			rv.elseS, err = this.compile(v, at)
+		case "$ref":
+			if v.Type() != value.STRING {
+				err = fmt.Errorf("Schema %s must be a string.", at)
+				break
+			}
+			rv.ref, err = this.resolve(v.Actual().(string))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+func (this *compiler) compileList(schemas value.Value, path string) ([]*node, error) {
+	if schemas.Type() != value.ARRAY {
+		return nil, fmt.Errorf("Schema %s must be an array.", path)
+	}
+
+	items := schemas.Actual().([]interface{})
+	rv := make([]*node, len(items))
+	for i, item := range items {
+		n, err := this.compile(value.NewValue(item), path+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		rv[i] = n
+	}
+
+	return rv, nil
+}
+
+/*
+Resolves a local reference, such as #/definitions/address. Nodes are
+cached before they are compiled, so that recursive schemas terminate.
+A reference that leads back to itself without descending into a field
+or an item, such as {"$ref": "#"}, could never be checked and is
+rejected.
+*/
+func (this *compiler) resolve(ref string) (*node, error) {
+	if n, ok := this.refs[ref]; ok {
+		return n, nil
+	}
+
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("Unsupported schema reference %s.", ref)
+	}
+
+	target := this.source
+	if ref != "#" {
+		for _, t := range strings.Split(ref[2:], "/") {
+			t = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+			if u, err := url.PathUnescape(t); err == nil {
+				t = u
+			}
+
+			var ok bool
+			switch target.Type() {
+			case value.OBJECT:
+				target, ok = target.Field(t)
+			case value.ARRAY:
+				var i int
+				i, ok = arrayIndex(t)
+				if ok {
+					target, ok = target.Index(i)
+				}
+			}
+
+			if !ok {
+				return nil, fmt.Errorf("Unresolved schema reference %s.", ref)
+			}
+		}
+	}
+
+	rv := &node{}
+	this.refs[ref] = rv
+
+	n, err := this.compile(target, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	*rv = *n
+	if rv.loopsTo(rv, make(map[*node]bool)) {
+		return nil, fmt.Errorf("Schema reference %s refers to itself without descending into the value.", ref)
+	}
+
+	return rv, nil
+}
+
+/*
+Returns true if target is reachable from this node through schemas
+that apply to the same value.
+*/
+func (this *node) loopsTo(target *node, seen map[*node]bool) bool {
+	if seen[this] {
+		return false
+	}
+	seen[this] = true
+
+	next := make([]*node, 0, 8)
+	next = append(next, this.ref, this.not, this.ifS, this.thenS, this.elseS)
+	next = append(next, this.allOf...)
+	next = append(next, this.anyOf...)
+	next = append(next, this.oneOf...)
+	for _, n := range this.dependencies {
+		next = append(next, n)
+	}
+
+	for _, n := range next {
+		if n != nil && (n == target || n.loopsTo(target, seen)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Validates val, appending any errors, and returns true if it is valid.
+*/
+func (this *node) validate(val value.Value, path string, errs *[]*SchemaError) bool {
+	return this.check(val, path, errs, false, 0)
+}
+
+/*
+Bounds the nesting of schemas applied to a value. References that
+loop without descending into the value are rejected when the schema
+is compiled, so this only guards against pathological documents.
+*/
+const _MAX_DEPTH = 1000
+
+/*
+If quick is set, stops at the first error without recording it.
+*/
+func (this *node) check(val value.Value, path string, errs *[]*SchemaError, quick bool, depth int) bool {
+	valid := true
+	fail := func(format string, args ...interface{}) bool {
+		valid = false
+		if !quick {
+			*errs = append(*errs, &SchemaError{path, fmt.Sprintf(format, args...)})
+		}
+		return !quick
+	}
+
+	if this.always != nil {
+		if !*this.always {
+			fail("No value is allowed here.")
+		}
+		return valid
+	}
+
+	if depth > _MAX_DEPTH {
+		fail("Value is nested too deeply for the schema.")
+		return false
+	}
+
+	if this.ref != nil && !this.ref.check(val, path, errs, quick, depth+1) {
+		valid = false
+		if quick {
+			return false
+		}
+	}
+
+	if len(this.types) > 0 {
+		ok := false
+		for _, t := range this.types {
+			if hasType(val, t) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			fail("Expected type %s, found %s.", strings.Join(this.types, " or "), typeName(val))
+			return false
+		}
+	}
+
+	if len(this.enum) > 0 {
+		ok := false
+		for _, e := range this.enum {
+			if e.Equals(val).Truth() {
+				ok = true
+				break
+			}
+		}
+
+		if !ok && !fail("Value is not one of the enumerated values.") {
+			return false
+		}
+	}
+
+	if this.constant != nil && !this.constant.Equals(val).Truth() &&
+		!fail("Value must be %s.", this.constant) {
+		return false
+	}
+
+	ok := true
+	switch val.Type() {
+	case value.OBJECT:
+		ok = this.checkObject(val, path, errs, quick, depth, fail)
+	case value.ARRAY:
+		ok = this.checkArray(val, path, errs, quick, depth, fail)
+	case value.STRING:
+		ok = this.checkString(val.Actual().(string), fail)
+	case value.NUMBER:
+		ok = this.checkNumber(val.Actual().(float64), fail)
+	}
+
+	if !ok {
+		valid = false
+		if quick {
+			return false
+		}
+	}
+
+	for _, s := range this.allOf {
+		if !s.check(val, path, errs, quick, depth+1) {
+			valid = false
+			if quick {
+				return false
+			}
+		}
+	}
+
+	if len(this.anyOf) > 0 {
+		ok := false
+		for _, s := range this.anyOf {
+			if s.check(val, path, nil, true, depth+1) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok && !fail("Value does not match any of the anyOf schemas.") {
+			return false
+		}
+	}
+
+	if len(this.oneOf) > 0 {
+		matches := 0
+		for _, s := range this.oneOf {
+			if s.check(val, path, nil, true, depth+1) {
+				matches++
+			}
+		}
+
+		if matches != 1 && !fail("Value matches %d of the oneOf schemas instead of exactly one.", matches) {
+			return false
+		}
+	}
+
+	if this.not != nil && this.not.check(val, path, nil, true, depth+1) &&
+		!fail("Value must not match the not schema.") {
+		return false
+	}
+
+	if this.ifS != nil {
+		branch := this.elseS
+		if this.ifS.check(val, path, nil, true, depth+1) {
+			branch = this.thenS
+		}
+
+		if branch != nil && !branch.check(val, path, errs, quick, depth+1) {
+			valid = false
+		}
+	}
+
+	return valid
+}
+
+type failer func(format string, args ...interface{}) bool
+
+func (this *node) checkObject(val value.Value, path string, errs *[]*SchemaError, quick bool, depth int, fail failer) bool {
+	fields := val.Fields()
+	valid := true
+	sub := func(s *node, v value.Value, at string) bool {
+		if !s.check(v, at, errs, quick, depth+1) {
+			valid = false
+			return !quick
+		}
+		return true
+	}
+
+	if this.minProperties >= 0 && len(fields) < this.minProperties &&
+		!fail("Object must have at least %d fields.", this.minProperties) {
+		return false
+	}
+
+	if this.maxProperties >= 0 && len(fields) > this.maxProperties &&
+		!fail("Object must have at most %d fields.", this.maxProperties) {
+		return false
+	}
+
+	for _, r := range this.required {
+		if _, ok := fields[r]; !ok && !fail("Missing required field %s.", r) {
+			return false
+		}
+	}
+
+	for _, name := range sortedNames(fields) {
+		v := value.NewValue(fields[name])
+		at := path + "/" + escape(name)
+
+		if this.propertyNames != nil && !sub(this.propertyNames, value.NewValue(name), at) {
+			return false
+		}
+
+		matched := false
+		if s, ok := this.properties[name]; ok {
+			matched = true
+			if !sub(s, v, at) {
+				return false
+			}
+		}
+
+		for _, p := range this.patterns {
+			if p.re.MatchString(name) {
+				matched = true
+				if !sub(p.schema, v, at) {
+					return false
+				}
+			}
+		}
+
+		if !matched && this.additional != nil {
+			if this.additional.always != nil && !*this.additional.always {
+				if !fail("Field %s is not allowed.", name) {
+					return false
+				}
+			} else if !sub(this.additional, v, at) {
+				return false
+			}
+		}
+
+		for _, r := range this.depRequired[name] {
+			if _, ok := fields[r]; !ok && !fail("Field %s requires field %s.", name, r) {
+				return false
+			}
+		}
+
+		if s, ok := this.dependencies[name]; ok && !sub(s, val, path) {
+			return false
+		}
+	}
+
+	return valid
+}
+
+func (this *node) checkArray(val value.Value, path string, errs *[]*SchemaError, quick bool, depth int, fail failer) bool {
+	items := val.Actual().([]interface{})
+	valid := true
+
+	if this.minItems >= 0 && len(items) < this.minItems &&
+		!fail("Array must have at least %d items.", this.minItems) {
+		return false
+	}
+
+	if this.maxItems >= 0 && len(items) > this.maxItems &&
+		!fail("Array must have at most %d items.", this.maxItems) {
+		return false
+	}
+
+	for i, item := range items {
+		v := value.NewValue(item)
+
+		s := this.items
+		if this.tupleItems != nil {
+			s = this.additionalItems
+			if i < len(this.tupleItems) {
+				s = this.tupleItems[i]
+			} else if s != nil && s.always != nil && !*s.always {
+				if !fail("Array must have at most %d items.", len(this.tupleItems)) {
+					return false
+				}
+				s = nil
+			}
+		}
+
+		if s != nil && !s.check(v, path+"/"+strconv.Itoa(i), errs, quick, depth+1) {
+			valid = false
+			if quick {
+				return false
+			}
+		}
+
+		if this.uniqueItems {
+			for j := 0; j < i; j++ {
+				if value.NewValue(items[j]).Equals(v).Truth() {
+					if !fail("Array items %d and %d are equal.", j, i) {
+						return false
+					}
+					break
+				}
+			}
+		}
+	}
+
+	if this.contains != nil {
+		ok := false
+		for _, item := range items {
+			if this.contains.check(value.NewValue(item), path, nil, true, depth+1) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok && !fail("Array does not contain a matching item.") {
+			return false
+		}
+	}
+
+	return valid
+}
+
+func (this *node) checkString(s string, fail failer) bool {
+	if this.minLength >= 0 || this.maxLength >= 0 {
+		n := utf8.RuneCountInString(s)
+		if this.minLength >= 0 && n < this.minLength &&
+			!fail("String must have at least %d characters.", this.minLength) {
+			return false
+		}
+
+		if this.maxLength >= 0 && n > this.maxLength &&
+			!fail("String must have at most %d characters.", this.maxLength) {
+			return false
+		}
+	}
+
+	if this.pattern != nil && !this.pattern.MatchString(s) &&
+		!fail("String does not match pattern %s.", this.pattern) {
+		return false
+	}
+
+	if this.format != "" && !validFormat(this.format, s) &&
+		!fail("String is not a valid %s.", this.format) {
+		return false
+	}
+
+	return true
+}
+
+func (this *node) checkNumber(f float64, fail failer) bool {
+	if this.minimum != nil && f < *this.minimum &&
+		!fail("Number must be at least %v.", *this.minimum) {
+		return false
+	}
+
+	if this.maximum != nil && f > *this.maximum &&
+		!fail("Number must be at most %v.", *this.maximum) {
+		return false
+	}
+
+	if this.exclusiveMinimum != nil && f <= *this.exclusiveMinimum &&
+		!fail("Number must be greater than %v.", *this.exclusiveMinimum) {
+		return false
+	}
+
+	if this.exclusiveMaximum != nil && f >= *this.exclusiveMaximum &&
+		!fail("Number must be less than %v.", *this.exclusiveMaximum) {
+		return false
+	}
+
+	if this.multipleOf > 0.0 {
+		q := f / this.multipleOf
+		if math.Abs(q-math.Floor(q+0.5)) > 1e-9 && !fail("Number must be a multiple of %v.", this.multipleOf) {
+			return false
+		}
+	}
+
+	return true
+}
+
+var _UUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+/*
+Checks the common formats. Unknown formats are not checked.
+*/
+func validFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		if err != nil {
+			_, err = time.Parse("15:04:05.999999999Z07:00", s)
+		}
+		return err == nil
+	case "email":
+		at := strings.LastIndex(s, "@")
+		return at > 0 && at < len(s)-1 && !strings.ContainsAny(s, " \t\r\n")
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && strings.Contains(s, ".")
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	case "uuid":
+		return _UUID.MatchString(s)
+	default:
+		return true
+	}
+}
+
+func hasType(val value.Value, t string) bool {
+	switch t {
+	case "null":
+		return val.Type() == value.NULL
+	case "boolean":
+		return val.Type() == value.BOOLEAN
+	case "object":
+		return val.Type() == value.OBJECT
+	case "array":
+		return val.Type() == value.ARRAY
+	case "string":
+		return val.Type() == value.STRING
+	case "number":
+		return val.Type() == value.NUMBER
+	case "integer":
+		if val.Type() != value.NUMBER {
+			return false
+		}
+		f := val.Actual().(float64)
+		return f == math.Trunc(f)
+	default:
+		return false
+	}
+}
+
+func typeName(val value.Value) string {
+	if val.Type() == value.NUMBER {
+		return "number"
+	}
+
+	return strings.ToLower(val.Type().String())
+}
+
+func stringList(v value.Value, path string, single bool) ([]string, error) {
+	if single && v.Type() == value.STRING {
+		return []string{v.Actual().(string)}, nil
+	}
+
+	if v.Type() != value.ARRAY {
+		return nil, fmt.Errorf("Schema %s must be an array of strings.", path)
+	}
+
+	items := v.Actual().([]interface{})
+	rv := make([]string, len(items))
+	for i, item := range items {
+		s, ok := value.NewValue(item).Actual().(string)
+		if !ok {
+			return nil, fmt.Errorf("Schema %s must be an array of strings.", path)
+		}
+		rv[i] = s
+	}
+
+	return rv, nil
+}
+
+func count(v value.Value, path string) (int, error) {
+	if v.Type() == value.NUMBER {
+		f := v.Actual().(float64)
+		if f >= 0.0 && f == math.Trunc(f) && f <= math.MaxInt32 {
+			return int(f), nil
+		}
+	}
+
+	return 0, fmt.Errorf("Schema %s must be a non-negative integer.", path)
+}
+
+func number(v value.Value, path string) (*float64, error) {
+	if v.Type() != value.NUMBER {
+		return nil, fmt.Errorf("Schema %s must be a number.", path)
+	}
+
+	f := v.Actual().(float64)
+	return &f, nil
+}
+
+func arrayIndex(t string) (int, bool) {
+	i, err := strconv.Atoi(t)
+	return i, err == nil && i >= 0
+}
+
+func escape(name string) string {
+	return strings.Replace(strings.Replace(name, "~", "~0", -1), "/", "~1", -1)
+}
+
+func sortedNames(fields map[string]interface{}) []string {
+	names := make([]string, 0, len(fields))
+	for n, _ := range fields {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+	return names
+}
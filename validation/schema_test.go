@@ -0,0 +1,109 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestSchemaRef(t *testing.T) {
+	schema, err := NewSchema(value.NewValue([]byte(`{
+		"definitions": {"id": {"type": "string", "pattern": "^[a-z]+$"}},
+		"type": "object",
+		"properties": {"ids": {"type": "array", "items": {"$ref": "#/definitions/id"}}},
+		"additionalProperties": false
+	}`)))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !schema.Valid(value.NewValue(map[string]interface{}{"ids": []interface{}{"a", "bc"}})) {
+		t.Errorf("Expected valid document")
+	}
+
+	errs := schema.Validate(value.NewValue(map[string]interface{}{
+		"ids":   []interface{}{"a", "B"},
+		"other": 1,
+	}))
+	if len(errs) != 2 || errs[0].Path != "/ids/1" || errs[1].Path != "" {
+		t.Errorf("Unexpected errors %v", errs)
+	}
+}
+
+func TestSchemaInvalid(t *testing.T) {
+	for _, s := range []string{`1`, `{"type": "widget"}`, `{"$ref": "#/missing"}`, `{"minLength": -1}`} {
+		if _, err := NewSchema(value.NewValue([]byte(s))); err == nil {
+			t.Errorf("Expected error for schema %s", s)
+		}
+	}
+}
+
+func TestSchemaRefLoop(t *testing.T) {
+	for _, s := range []string{
+		`{"$ref": "#"}`,
+		`{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"allOf": [{"$ref": "#/definitions/a"}]}},
+		  "$ref": "#/definitions/a"}`,
+	} {
+		if _, err := NewSchema(value.NewValue([]byte(s))); err == nil {
+			t.Errorf("Expected error for schema %s", s)
+		}
+	}
+
+	schema, err := NewSchema(value.NewValue([]byte(`{
+		"type": "object",
+		"properties": {"child": {"$ref": "#"}},
+		"additionalProperties": false
+	}`)))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	var doc interface{} = map[string]interface{}{}
+	for i := 0; i < 10; i++ {
+		doc = map[string]interface{}{"child": doc}
+	}
+	if !schema.Valid(value.NewValue(doc)) {
+		t.Errorf("Expected valid document")
+	}
+
+	for i := 0; i < 2*_MAX_DEPTH; i++ {
+		doc = map[string]interface{}{"child": doc}
+	}
+	if schema.Valid(value.NewValue(doc)) {
+		t.Errorf("Expected document nested too deeply to be rejected")
+	}
+}
+
+func TestRules(t *testing.T) {
+	rule := value.NewValue(map[string]interface{}{
+		"schema": map[string]interface{}{"required": []interface{}{"a"}},
+	})
+
+	if err := SetRule("orders", rule, RULE_UPDATE); err == nil {
+		t.Errorf("Expected error updating a missing rule")
+	}
+	if err := SetRule("orders", rule, RULE_INSERT); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := SetRule("default:orders", rule, RULE_INSERT); err == nil {
+		t.Errorf("Expected error inserting an existing rule")
+	}
+
+	schema := Rule("default", "orders")
+	if schema == nil || schema.Valid(value.NewValue(map[string]interface{}{})) {
+		t.Errorf("Expected rule to reject document")
+	}
+
+	if ok, _ := DeleteRule("orders"); !ok || CountRules() != 0 || Rule("default", "orders") != nil {
+		t.Errorf("Expected rule to be deleted")
+	}
+}
@@ -0,0 +1,190 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package validation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/couchbase/query/distributed"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/value"
+)
+
+/*
+A persisted validation rule, in the form of the system:validations
+documents.
+*/
+type StoredRule struct {
+	Keyspace string      `json:"keyspace"`
+	Schema   interface{} `json:"schema"`
+}
+
+/*
+Persistence for the validation rules.
+*/
+type RuleStore interface {
+
+	// return all the rules stored
+	Load() ([]*StoredRule, errors.Error)
+
+	// replace the stored rules
+	Save(rules []*StoredRule) errors.Error
+}
+
+/*
+Initializes the rules from a store, if one is given. Changes are
+saved to the store from then on.
+*/
+func RulesInit(store RuleStore) errors.Error {
+	schemas := make(map[string]*Schema)
+	if store != nil {
+		loaded, err := store.Load()
+		if err != nil {
+			return err
+		}
+
+		for _, r := range loaded {
+			schema, err1 := NewSchema(value.NewValue(r.Schema))
+			if err1 != nil {
+				return errors.NewSystemValidationRuleError(err1, r.Keyspace, "invalid schema")
+			}
+			schemas[RuleKey(r.Keyspace)] = schema
+		}
+	}
+
+	_RULES.Lock()
+	_RULES.store = store
+	_RULES.schemas = schemas
+	_RULES.Unlock()
+	return nil
+}
+
+/*
+Copies the rules of the first other query node that has any, if none
+were loaded from the store.
+*/
+func RulesRemotePrime() {
+	if CountRules() > 0 {
+		return
+	}
+
+	thisHost := distributed.RemoteAccess().WhoAmI()
+	if thisHost == "" {
+		return
+	}
+
+	for _, host := range distributed.RemoteAccess().GetNodeNames() {
+		if host == thisHost {
+			continue
+		}
+
+		count := 0
+		distributed.RemoteAccess().GetRemoteKeys([]string{host}, "validations",
+			func(id string) bool {
+				_, key := distributed.RemoteAccess().SplitKey(id)
+				distributed.RemoteAccess().GetRemoteDoc(host, key, "validations", "GET",
+					func(doc map[string]interface{}) {
+						if SetRemoteRule(key, value.NewValue(doc)) == nil {
+							count++
+						}
+					},
+					func(warn errors.Error) {
+					}, distributed.NO_CREDS, "")
+				return true
+			}, nil)
+
+		if count > 0 {
+			return
+		}
+	}
+}
+
+// send a rule change to the other query nodes; a nil rule is a removal
+func distributeRule(key string, rule value.Value) {
+	command := "DELETE"
+	data := ""
+	if rule != nil {
+		command = "PUT"
+		bytes, _ := rule.MarshalJSON()
+		data = string(bytes)
+	}
+
+	go distributed.RemoteAccess().DoRemoteOps([]string{}, "validations", command, key, data,
+		func(warn errors.Error) {
+			if warn != nil {
+				logging.Infof("failed to distribute validation rule for %v: %v", key, warn)
+			}
+		}, distributed.NO_CREDS, "")
+}
+
+// Locking is handled by the top level caller!
+func (this *rules) save() errors.Error {
+	if this.store == nil {
+		return nil
+	}
+
+	saved := make([]*StoredRule, 0, len(this.schemas))
+	for key, schema := range this.schemas {
+		saved = append(saved, &StoredRule{Keyspace: key, Schema: schema.Source().Actual()})
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Keyspace < saved[j].Keyspace })
+	return this.store.Save(saved)
+}
+
+/*
+A local file rule store.
+*/
+type fileRuleStore struct {
+	path string
+}
+
+func NewFileRuleStore(path string) RuleStore {
+	return &fileRuleStore{path: path}
+}
+
+func (this *fileRuleStore) Load() ([]*StoredRule, errors.Error) {
+	bytes, err := ioutil.ReadFile(this.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.NewSystemValidationStoreError(err)
+	}
+
+	var rv []*StoredRule
+	err = json.Unmarshal(bytes, &rv)
+	if err != nil {
+		return nil, errors.NewSystemValidationStoreError(err)
+	}
+	return rv, nil
+}
+
+func (this *fileRuleStore) Save(rules []*StoredRule) errors.Error {
+	bytes, err := json.MarshalIndent(rules, "", "    ")
+	if err != nil {
+		return errors.NewSystemValidationStoreError(err)
+	}
+
+	// write a new file and rename it, so that a failure cannot leave
+	// a truncated one behind
+	tmp := this.path + ".tmp"
+	err = ioutil.WriteFile(tmp, bytes, 0600)
+	if err == nil {
+		err = os.Rename(tmp, this.path)
+	}
+	if err != nil {
+		return errors.NewSystemValidationStoreError(err)
+	}
+	return nil
+}
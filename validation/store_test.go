@@ -0,0 +1,90 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package validation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+func TestRuleStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer RulesInit(nil)
+
+	path := filepath.Join(dir, "validations.json")
+	store := NewFileRuleStore(path)
+
+	// a missing file is no rules
+	if err := RulesInit(store); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	if CountRules() != 0 {
+		t.Errorf("expected no rules, got %v", CountRules())
+	}
+
+	rule := value.NewValue(map[string]interface{}{
+		"schema": map[string]interface{}{"type": "object", "required": []interface{}{"id"}},
+	})
+	if err := SetRule("orders", rule, RULE_INSERT); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := SetRemoteRule("default:items", rule); err != nil {
+		t.Fatalf("set remote: %v", err)
+	}
+
+	// reload from the file
+	if err := RulesInit(NewFileRuleStore(path)); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	schema := Rule("default", "orders")
+	if CountRules() != 2 || schema == nil || schema.Valid(value.NewValue(map[string]interface{}{})) {
+		t.Errorf("expected the rules to be reloaded")
+	}
+
+	// removals are persisted too
+	if ok, err := DeleteRule("orders"); !ok || err != nil {
+		t.Fatalf("delete: %v %v", ok, err)
+	}
+	if err := RulesInit(NewFileRuleStore(path)); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if CountRules() != 1 || Rule("default", "orders") != nil || Rule("default", "items") == nil {
+		t.Errorf("expected only the items rule, got %v rules", CountRules())
+	}
+
+	// a rule that cannot be saved is not kept
+	os.Remove(path)
+	if err := os.Mkdir(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetRule("orders", rule, RULE_INSERT); err == nil || Rule("default", "orders") != nil {
+		t.Errorf("expected the rule to be refused")
+	}
+	if ok, err := DeleteRule("items"); ok || err == nil || Rule("default", "items") == nil {
+		t.Errorf("expected the rule to be kept")
+	}
+
+	// a corrupt file fails initialization
+	os.Remove(path)
+	if err := ioutil.WriteFile(path, []byte("[{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RulesInit(NewFileRuleStore(path)); err == nil {
+		t.Errorf("expected an error for a corrupt file")
+	}
+}
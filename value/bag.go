@@ -25,6 +25,7 @@ type Bag struct {
 	nulls    *BagEntry
 	booleans map[bool]*BagEntry
 	floats   map[float64]*BagEntry
+	decimals map[string]*BagEntry
 	ints     map[int64]*BagEntry
 	strings  map[string]*BagEntry
 	arrays   map[string]*BagEntry
@@ -94,7 +95,7 @@ func (this *Bag) Put(key, item Value) {
 
 		entry.Count++
 	case NUMBER:
-		num := key.unwrap()
+		num := exactNumber(key.unwrap())
 		switch num := num.(type) {
 		case floatValue:
 			f := float64(num)
@@ -125,6 +126,18 @@ func (this *Bag) Put(key, item Value) {
 				this.ints[akey] = entry
 			}
 
+			entry.Count++
+		case *decimalValue:
+			if this.decimals == nil {
+				this.decimals = make(map[string]*BagEntry, _MAP_CAP)
+			}
+			akey := num.String()
+			entry := this.decimals[akey]
+			if entry == nil {
+				entry = &BagEntry{Value: item}
+				this.decimals[akey] = entry
+			}
+
 			entry.Count++
 		default:
 			panic(fmt.Sprintf("Unsupported value type %T.", key))
@@ -183,7 +196,7 @@ func (this *Bag) Entry(key Value) *BagEntry {
 	case BOOLEAN:
 		return this.booleans[key.Actual().(bool)]
 	case NUMBER:
-		num := key.unwrap()
+		num := exactNumber(key.unwrap())
 		switch num := num.(type) {
 		case floatValue:
 			f := float64(num)
@@ -194,6 +207,8 @@ func (this *Bag) Entry(key Value) *BagEntry {
 			}
 		case intValue:
 			return this.ints[int64(num)]
+		case *decimalValue:
+			return this.decimals[num.String()]
 		default:
 			panic(fmt.Sprintf("Unsupported value type %T.", key))
 		}
@@ -212,8 +227,8 @@ func (this *Bag) Entry(key Value) *BagEntry {
 }
 
 func (this *Bag) DistinctLen() int {
-	rv := len(this.booleans) + len(this.floats) + len(this.ints) + len(this.strings) +
-		len(this.arrays) + len(this.objects) + len(this.binaries)
+	rv := len(this.booleans) + len(this.floats) + len(this.decimals) + len(this.ints) +
+		len(this.strings) + len(this.arrays) + len(this.objects) + len(this.binaries)
 
 	if this.nills != nil {
 		rv++
@@ -253,6 +268,10 @@ func (this *Bag) Entries() []*BagEntry {
 		rv = append(rv, av)
 	}
 
+	for _, av := range this.decimals {
+		rv = append(rv, av)
+	}
+
 	for _, av := range this.ints {
 		rv = append(rv, av)
 	}
@@ -291,6 +310,11 @@ func (this *Bag) Clear() {
 		delete(this.floats, k)
 	}
 
+	for k, _ := range this.decimals {
+		this.decimals[k] = nil
+		delete(this.decimals, k)
+	}
+
 	for k, _ := range this.ints {
 		this.ints[k] = nil
 		delete(this.ints, k)
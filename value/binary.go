@@ -19,6 +19,14 @@ import (
 
 type binaryValue []byte
 
+/*
+Returns a BINARY value over bytes, without attempting to parse them
+as JSON.
+*/
+func NewBinaryValue(bytes []byte) Value {
+	return binaryValue(bytes)
+}
+
 func (this binaryValue) String() string {
 	return fmt.Sprintf("\"<binary (%d b)>\"", len(this))
 }
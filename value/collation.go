@@ -0,0 +1,234 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package value
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+A Collation orders strings other than by their bytes.
+
+Collations are named by an optional language, followed by one or both
+of ci (case-insensitive) and ai (accent-insensitive), separated by
+underscores, e.g. ci, ci_ai or tr_ci. The binary collation is the
+default ordering of Collate.
+
+Strings are collated by their keys: the lower case of the string for
+case-insensitive collations, and the string without diacritical marks
+for accent-insensitive ones. The case mapping follows the language
+where it differs from the default, as it does for Turkish and Azeri.
+*/
+type Collation struct {
+	name              string
+	caseInsensitive   bool
+	accentInsensitive bool
+	special           unicode.SpecialCase
+}
+
+var BINARY_COLLATION = &Collation{name: "binary"}
+
+/*
+Returns the named collation, or nil if the name is not valid.
+*/
+func NewCollation(name string) *Collation {
+	name = strings.ToLower(name)
+	if name == "binary" {
+		return BINARY_COLLATION
+	}
+
+	rv := &Collation{name: name}
+	parts := strings.Split(name, "_")
+	if len(parts) > 1 && parts[0] != "ci" && parts[0] != "ai" {
+		if !validLanguage(parts[0]) {
+			return nil
+		}
+		rv.special = _LANGUAGE_CASES[parts[0]]
+		parts = parts[1:]
+	}
+
+	for _, part := range parts {
+		switch {
+		case part == "ci" && !rv.caseInsensitive:
+			rv.caseInsensitive = true
+		case part == "ai" && !rv.accentInsensitive:
+			rv.accentInsensitive = true
+		default:
+			return nil
+		}
+	}
+
+	return rv
+}
+
+func validLanguage(lang string) bool {
+	if len(lang) < 2 || len(lang) > 3 {
+		return false
+	}
+	for _, r := range lang {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+var _LANGUAGE_CASES = map[string]unicode.SpecialCase{
+	"tr": unicode.TurkishCase,
+	"az": unicode.AzeriCase,
+}
+
+func (this *Collation) Name() string {
+	return this.name
+}
+
+func (this *Collation) CaseInsensitive() bool {
+	return this.caseInsensitive
+}
+
+func (this *Collation) AccentInsensitive() bool {
+	return this.accentInsensitive
+}
+
+/*
+Returns true if the keys of this collation are the LOWER() of their
+strings.
+*/
+func (this *Collation) Lowers() bool {
+	return this.caseInsensitive && !this.accentInsensitive && this.special == nil
+}
+
+/*
+Returns the collation key of a value. Strings are replaced by their
+keys, including those within arrays and objects. Other values are
+their own keys.
+*/
+func (this *Collation) Key(val Value) Value {
+	if this == BINARY_COLLATION {
+		return val
+	}
+
+	switch val.Type() {
+	case STRING:
+		return NewValue(this.key(val.Actual().(string)))
+	case ARRAY, OBJECT:
+		return NewValue(this.keys(val.Actual()))
+	default:
+		return val
+	}
+}
+
+func (this *Collation) keys(val interface{}) interface{} {
+	switch val := val.(type) {
+	case string:
+		return this.key(val)
+	case Value:
+		return this.keys(val.Actual())
+	case []interface{}:
+		rv := make([]interface{}, len(val))
+		for i, v := range val {
+			rv[i] = this.keys(v)
+		}
+		return rv
+	case map[string]interface{}:
+		rv := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			rv[k] = this.keys(v)
+		}
+		return rv
+	default:
+		return val
+	}
+}
+
+func (this *Collation) key(s string) string {
+	if this.caseInsensitive {
+		if this.special != nil {
+			s = strings.ToLowerSpecial(this.special, s)
+		} else {
+			s = strings.ToLower(s)
+		}
+	}
+
+	if this.accentInsensitive {
+		s = strings.Map(unaccent, s)
+	}
+
+	return s
+}
+
+/*
+Drops combining marks, and maps precomposed Latin letters to their
+base letters.
+*/
+func unaccent(r rune) rune {
+	if r < 0x80 {
+		return r
+	}
+	if unicode.Is(unicode.Mn, r) {
+		return -1
+	}
+	if base, ok := _BASE_LETTERS[r]; ok {
+		return base
+	}
+	return r
+}
+
+var _BASE_LETTERS = make(map[rune]rune, 256)
+
+func init() {
+	accented := map[rune]string{
+		'A': "ÀÁÂÃÄÅĀĂĄǍǞǠǺȀȂȦ",
+		'a': "àáâãäåāăąǎǟǡǻȁȃȧ",
+		'C': "ÇĆĈĊČ",
+		'c': "çćĉċč",
+		'D': "ĎĐ",
+		'd': "ďđ",
+		'E': "ÈÉÊËĒĔĖĘĚȄȆȨ",
+		'e': "èéêëēĕėęěȅȇȩ",
+		'G': "ĜĞĠĢǦǴ",
+		'g': "ĝğġģǧǵ",
+		'H': "ĤĦȞ",
+		'h': "ĥħȟ",
+		'I': "ÌÍÎÏĨĪĬĮİǏȈȊ",
+		'i': "ìíîïĩīĭįıǐȉȋ",
+		'J': "Ĵ",
+		'j': "ĵǰ",
+		'K': "ĶǨ",
+		'k': "ķǩ",
+		'L': "ĹĻĽĿŁ",
+		'l': "ĺļľŀł",
+		'N': "ÑŃŅŇǸ",
+		'n': "ñńņňǹ",
+		'O': "ÒÓÔÕÖØŌŎŐƠǑǪǬǾȌȎȪȬȮȰ",
+		'o': "òóôõöøōŏőơǒǫǭǿȍȏȫȭȯȱ",
+		'R': "ŔŖŘȐȒ",
+		'r': "ŕŗřȑȓ",
+		'S': "ŚŜŞŠȘ",
+		's': "śŝşšș",
+		'T': "ŢŤŦȚ",
+		't': "ţťŧț",
+		'U': "ÙÚÛÜŨŪŬŮŰŲƯǓǕǗǙǛȔȖ",
+		'u': "ùúûüũūŭůűųưǔǖǘǚǜȕȗ",
+		'W': "Ŵ",
+		'w': "ŵ",
+		'Y': "ÝŶŸȲ",
+		'y': "ýÿŷȳ",
+		'Z': "ŹŻŽ",
+		'z': "źżž",
+	}
+
+	for base, letters := range accented {
+		for _, r := range letters {
+			_BASE_LETTERS[r] = base
+		}
+	}
+}
@@ -0,0 +1,60 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package value
+
+import (
+	"testing"
+)
+
+func TestCollationNames(t *testing.T) {
+	valid := []string{"binary", "BINARY", "ci", "ai", "ci_ai", "ai_ci", "tr_ci", "fra_ai"}
+	for _, name := range valid {
+		if NewCollation(name) == nil {
+			t.Errorf("Expected collation %v to be valid", name)
+		}
+	}
+
+	invalid := []string{"", "cs", "ci_ci", "ci_tr", "t_ci", "tr1_ci", "tr", "tr_"}
+	for _, name := range invalid {
+		if NewCollation(name) != nil {
+			t.Errorf("Expected collation %v to be invalid", name)
+		}
+	}
+
+	if NewCollation("ci").Lowers() != true || NewCollation("ci_ai").Lowers() ||
+		NewCollation("tr_ci").Lowers() {
+		t.Errorf("Unexpected collations with LOWER() keys")
+	}
+}
+
+func TestCollationKeys(t *testing.T) {
+	keys := []struct {
+		collation string
+		val       interface{}
+		key       interface{}
+	}{
+		{"binary", "Crème", "Crème"},
+		{"ci", "Crème", "crème"},
+		{"ai", "Crème", "Creme"},
+		{"ci_ai", "CRÈME", "creme"},
+		{"ci", "I", "i"},
+		{"tr_ci", "I", "ı"},
+		{"ci", 1, 1},
+		{"ci", []interface{}{"A", map[string]interface{}{"B": "C"}},
+			[]interface{}{"a", map[string]interface{}{"B": "c"}}},
+	}
+
+	for _, k := range keys {
+		key := NewCollation(k.collation).Key(NewValue(k.val))
+		if !key.Equals(NewValue(k.key)).Truth() {
+			t.Errorf("Expected key %v for %v under %v, got %v", k.key, k.val, k.collation, key)
+		}
+	}
+}
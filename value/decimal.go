@@ -0,0 +1,746 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package value
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/couchbase/query/util"
+)
+
+/*
+An exact decimal number of arbitrary precision, for amounts that int64
+and float64 cannot hold exactly. Decimals are produced from JSON
+integers that do not fit int64, and by TO_DECIMAL().
+
+A decimal is unscaled * 10^-scale. Decimals are kept normalized, with
+no trailing zeros after the decimal point, so that equal decimals have
+the same representation and marshal to the same JSON.
+
+Addition, subtraction and multiplication of decimals are exact, as are
+those of decimals with integers and floats, which are converted to the
+decimals of their shortest representation. Comparisons convert floats
+the same way. Division is exact to DECIMAL_DIV_DIGITS significant
+digits, and DecimalRound() rounds decimals exactly. The other numeric
+functions compute on the nearest float64.
+*/
+type decimalValue struct {
+	unscaled *big.Int
+	scale    int
+}
+
+/*
+Significant digits of decimal division, as in IEEE 754 decimal128.
+*/
+const DECIMAL_DIV_DIGITS = 34
+
+// bound the exponents of parsed decimals, so that 1e999999999 cannot
+// exhaust memory
+const _DECIMAL_MAX_EXPONENT = 10000
+
+var _BIG_TEN = big.NewInt(10)
+
+func newDecimal(unscaled *big.Int, scale int) *decimalValue {
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(-scale))
+		scale = 0
+	}
+
+	if unscaled.Sign() == 0 {
+		scale = 0
+	} else if scale > 0 {
+		var q, r big.Int
+		for scale > 0 {
+			q.QuoRem(unscaled, _BIG_TEN, &r)
+			if r.Sign() != 0 {
+				break
+			}
+			unscaled.Set(&q)
+			scale--
+		}
+	}
+
+	return &decimalValue{unscaled, scale}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(_BIG_TEN, big.NewInt(int64(n)), nil)
+}
+
+/*
+Parses a decimal from its JSON number or exponent notation, e.g.
+19.99 or -1.5e3.
+*/
+func NewDecimalValue(s string) (Value, bool) {
+	d, ok := parseDecimal(s)
+	if !ok {
+		return nil, false
+	}
+	return d, true
+}
+
+func parseDecimal(s string) (*decimalValue, bool) {
+	s = strings.TrimSpace(s)
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e > _DECIMAL_MAX_EXPONENT || e < -_DECIMAL_MAX_EXPONENT {
+			return nil, false
+		}
+		exp = e
+		s = s[:i]
+	}
+
+	neg := false
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	digits := s
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits = s[:i] + s[i+1:]
+		scale = len(s) - i - 1
+	}
+	if digits == "" {
+		return nil, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return nil, false
+		}
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		unscaled.Neg(unscaled)
+	}
+	return newDecimal(unscaled, scale-exp), true
+}
+
+/*
+Returns the decimal of a number, and false for NaN and infinities,
+which have no decimal.
+*/
+func asDecimal(n Value) (*decimalValue, bool) {
+	switch n := n.unwrap().(type) {
+	case *decimalValue:
+		return n, true
+	case intValue:
+		return &decimalValue{big.NewInt(int64(n)), 0}, true
+	case floatValue:
+		f := float64(n)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false
+		}
+		return parseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+	default:
+		return nil, false
+	}
+}
+
+/*
+TO_DECIMAL(). Numbers and strings holding numbers are converted,
+other values are NULL.
+*/
+func ToDecimal(val Value) Value {
+	switch val.Type() {
+	case MISSING:
+		return MISSING_VALUE
+	case NUMBER:
+		if d, ok := asDecimal(val); ok {
+			return d
+		}
+	case STRING:
+		if d, ok := parseDecimal(val.Actual().(string)); ok {
+			return d
+		}
+	}
+
+	return NULL_VALUE
+}
+
+/*
+The int or float equal to a decimal, if any, else the decimal. Sets
+and bags key numbers by these, so that equal numbers are held once.
+*/
+func exactNumber(num Value) Value {
+	d, ok := num.(*decimalValue)
+	if !ok {
+		return num
+	}
+
+	if d.scale == 0 && d.unscaled.BitLen() < 64 {
+		return intValue(d.unscaled.Int64())
+	}
+	if f := d.Float64(); d.Collate(floatValue(f)) == 0 {
+		return floatValue(f)
+	}
+	return d
+}
+
+func IsDecimal(val Value) bool {
+	_, ok := val.unwrap().(*decimalValue)
+	return ok
+}
+
+/*
+Divides two numbers, exactly to DECIMAL_DIV_DIGITS significant digits
+if either is a decimal. Division by zero is NULL.
+*/
+func DecimalDiv(n, d Value) Value {
+	dn, ok1 := asDecimal(n)
+	dd, ok2 := asDecimal(d)
+	if !ok1 || !ok2 {
+		f := AsNumberValue(d).Float64()
+		if f == 0 {
+			return NULL_VALUE
+		}
+		return NewValue(AsNumberValue(n).Float64() / f)
+	}
+	if dd.unscaled.Sign() == 0 {
+		return NULL_VALUE
+	}
+
+	// scale the numerator for a quotient of DECIMAL_DIV_DIGITS
+	// significant digits, which may take one digit less
+	extra := DECIMAL_DIV_DIGITS + numDigits(dd.unscaled) - numDigits(dn.unscaled)
+	if extra < 0 {
+		extra = 0
+	}
+
+	num := new(big.Int).Mul(dn.unscaled, pow10(extra))
+	q, r := new(big.Int).QuoRem(num, dd.unscaled, new(big.Int))
+	if numDigits(q) > DECIMAL_DIV_DIGITS && extra > 0 {
+		extra--
+		num.Quo(num, _BIG_TEN)
+		q.QuoRem(num, dd.unscaled, r)
+	}
+
+	// round half away from zero
+	if r.Sign() != 0 {
+		r.Abs(r).Lsh(r, 1)
+		if r.Cmp(new(big.Int).Abs(dd.unscaled)) >= 0 {
+			q.Add(q, big.NewInt(int64(num.Sign()*dd.unscaled.Sign())))
+		}
+	}
+
+	return newDecimal(q, dn.scale-dd.scale+extra)
+}
+
+/*
+The remainder of the truncated division of two numbers, exact if
+either is a decimal. A zero divisor is NULL.
+*/
+func DecimalMod(n, d Value) Value {
+	dn, ok1 := asDecimal(n)
+	dd, ok2 := asDecimal(d)
+	if !ok1 || !ok2 {
+		f := AsNumberValue(d).Float64()
+		if f == 0 {
+			return NULL_VALUE
+		}
+		return NewValue(math.Mod(AsNumberValue(n).Float64(), f))
+	}
+	if dd.unscaled.Sign() == 0 {
+		return NULL_VALUE
+	}
+
+	a, b, scale := align(dn, dd)
+	return newDecimal(new(big.Int).Rem(a, b), scale)
+}
+
+/*
+Rounding modes of DecimalRound().
+*/
+const (
+	ROUND_HALF_EVEN = iota // ROUND()
+	ROUND_DOWN             // TRUNC(), towards zero
+	ROUND_FLOOR            // FLOOR()
+	ROUND_CEILING          // CEIL()
+)
+
+/*
+Rounds a decimal to the given number of digits after the decimal
+point, which may be negative. Other values return nil, for the caller
+to round their float64.
+*/
+func DecimalRound(val Value, digits, mode int) Value {
+	d, ok := val.unwrap().(*decimalValue)
+	if !ok {
+		return nil
+	}
+	if d.scale <= digits {
+		return d
+	}
+	if digits < -_DECIMAL_MAX_EXPONENT {
+		digits = -_DECIMAL_MAX_EXPONENT
+	}
+
+	div := pow10(d.scale - digits)
+	q, r := new(big.Int).QuoRem(d.unscaled, div, new(big.Int))
+	if r.Sign() != 0 {
+		switch mode {
+		case ROUND_HALF_EVEN:
+			c := new(big.Int).Lsh(new(big.Int).Abs(r), 1).Cmp(div)
+			if c > 0 || c == 0 && q.Bit(0) == 1 {
+				q.Add(q, big.NewInt(int64(r.Sign())))
+			}
+		case ROUND_FLOOR:
+			if r.Sign() < 0 {
+				q.Sub(q, big.NewInt(1))
+			}
+		case ROUND_CEILING:
+			if r.Sign() > 0 {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+
+	return newDecimal(q, digits)
+}
+
+/*
+The absolute value of a decimal, or nil for other values.
+*/
+func DecimalAbs(val Value) Value {
+	d, ok := val.unwrap().(*decimalValue)
+	if !ok {
+		return nil
+	}
+	if d.unscaled.Sign() < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+func numDigits(n *big.Int) int {
+	if n.Sign() == 0 {
+		return 1
+	}
+	return len(new(big.Int).Abs(n).String())
+}
+
+/*
+Returns the unscaled values of two decimals at a common scale.
+*/
+func align(d1, d2 *decimalValue) (*big.Int, *big.Int, int) {
+	switch {
+	case d1.scale == d2.scale:
+		return d1.unscaled, d2.unscaled, d1.scale
+	case d1.scale < d2.scale:
+		return new(big.Int).Mul(d1.unscaled, pow10(d2.scale-d1.scale)), d2.unscaled, d2.scale
+	default:
+		return d1.unscaled, new(big.Int).Mul(d2.unscaled, pow10(d1.scale-d2.scale)), d1.scale
+	}
+}
+
+/*
+Decimals are marshalled without exponents.
+*/
+func (this *decimalValue) String() string {
+	s := this.unscaled.String()
+	if this.scale == 0 {
+		return s
+	}
+
+	neg := s[0] == '-'
+	if neg {
+		s = s[1:]
+	}
+	if len(s) <= this.scale {
+		s = strings.Repeat("0", this.scale-len(s)+1) + s
+	}
+
+	var buf bytes.Buffer
+	if neg {
+		buf.WriteByte('-')
+	}
+	buf.WriteString(s[:len(s)-this.scale])
+	buf.WriteByte('.')
+	buf.WriteString(s[len(s)-this.scale:])
+	return buf.String()
+}
+
+func (this *decimalValue) MarshalJSON() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+func (this *decimalValue) WriteJSON(w io.Writer, prefix, indent string, fast bool) error {
+	_, err := w.Write([]byte(this.String()))
+	return err
+}
+
+func (this *decimalValue) Type() Type {
+	return NUMBER
+}
+
+/*
+The nearest float64, as for the other numbers.
+*/
+func (this *decimalValue) Actual() interface{} {
+	return this.Float64()
+}
+
+/*
+The decimal itself, which marshals losslessly.
+*/
+func (this *decimalValue) ActualForIndex() interface{} {
+	return this
+}
+
+func (this *decimalValue) Equals(other Value) Value {
+	other = other.unwrap()
+	switch other := other.(type) {
+	case missingValue:
+		return other
+	case *nullValue:
+		return other
+	case *decimalValue, intValue, floatValue:
+		if this.Collate(other) == 0 {
+			return TRUE_VALUE
+		}
+	}
+
+	return FALSE_VALUE
+}
+
+func (this *decimalValue) EquivalentTo(other Value) bool {
+	other = other.unwrap()
+	switch other.(type) {
+	case *decimalValue, intValue, floatValue:
+		return this.Collate(other) == 0
+	default:
+		return false
+	}
+}
+
+func (this *decimalValue) Collate(other Value) int {
+	other = other.unwrap()
+	switch other := other.(type) {
+	case *decimalValue:
+		a, b, _ := align(this, other)
+		return a.Cmp(b)
+	case intValue:
+		o, _ := asDecimal(other)
+		return this.Collate(o)
+	case floatValue:
+		f := float64(other)
+
+		// NaN and -Inf sort first, +Inf last
+		switch {
+		case math.IsNaN(f), math.IsInf(f, -1):
+			return 1
+		case math.IsInf(f, 1):
+			return -1
+		}
+
+		// floats collate as the decimals of their shortest
+		// representation, as in arithmetic, so that
+		// TO_DECIMAL(0.1) = 0.1
+		o, _ := asDecimal(other)
+		return this.Collate(o)
+	default:
+		return int(NUMBER - other.Type())
+	}
+}
+
+func (this *decimalValue) Compare(other Value) Value {
+	other = other.unwrap()
+	switch other := other.(type) {
+	case missingValue:
+		return other
+	case *nullValue:
+		return other
+	default:
+		return intValue(this.Collate(other))
+	}
+}
+
+func (this *decimalValue) Truth() bool {
+	return this.unscaled.Sign() != 0
+}
+
+/*
+Decimals are immutable.
+*/
+func (this *decimalValue) Copy() Value {
+	return this
+}
+
+func (this *decimalValue) CopyForUpdate() Value {
+	return this
+}
+
+func (this *decimalValue) Field(field string) (Value, bool) {
+	return missingField(field), false
+}
+
+func (this *decimalValue) SetField(field string, val interface{}) error {
+	return Unsettable(field)
+}
+
+func (this *decimalValue) UnsetField(field string) error {
+	return Unsettable(field)
+}
+
+func (this *decimalValue) Index(index int) (Value, bool) {
+	return missingIndex(index), false
+}
+
+func (this *decimalValue) SetIndex(index int, val interface{}) error {
+	return Unsettable(index)
+}
+
+func (this *decimalValue) Slice(start, end int) (Value, bool) {
+	return NULL_VALUE, false
+}
+
+func (this *decimalValue) SliceTail(start int) (Value, bool) {
+	return NULL_VALUE, false
+}
+
+func (this *decimalValue) Descendants(buffer []interface{}) []interface{} {
+	return buffer
+}
+
+func (this *decimalValue) Fields() map[string]interface{} {
+	return nil
+}
+
+func (this *decimalValue) FieldNames(buffer []string) []string {
+	return nil
+}
+
+func (this *decimalValue) DescendantPairs(buffer []util.IPair) []util.IPair {
+	return buffer
+}
+
+/*
+The least float greater than the decimal.
+*/
+func (this *decimalValue) Successor() Value {
+	f := this.Float64()
+	for !math.IsInf(f, 1) && this.Collate(floatValue(f)) >= 0 {
+		f = math.Nextafter(f, math.Inf(1))
+	}
+
+	if math.IsInf(f, 1) {
+		return EMPTY_STRING_VALUE
+	}
+	return floatValue(f)
+}
+
+func (this *decimalValue) Track() {
+}
+
+func (this *decimalValue) Recycle() {
+}
+
+func (this *decimalValue) Tokens(set *Set, options Value) *Set {
+	set.Add(this)
+	return set
+}
+
+func (this *decimalValue) ContainsToken(token, options Value) bool {
+	return this.EquivalentTo(token)
+}
+
+func (this *decimalValue) ContainsMatchingToken(matcher MatchFunc, options Value) bool {
+	return matcher(this.Float64())
+}
+
+func (this *decimalValue) unwrap() Value {
+	return this
+}
+
+/*
+NumberValue methods.
+*/
+
+func (this *decimalValue) Add(n NumberValue) NumberValue {
+	d, ok := asDecimal(n)
+	if !ok {
+		return floatValue(this.Float64() + n.Float64())
+	}
+
+	a, b, scale := align(this, d)
+	return newDecimal(new(big.Int).Add(a, b), scale)
+}
+
+func (this *decimalValue) IDiv(n NumberValue) Value {
+	d, ok := asDecimal(n)
+	if !ok {
+		return NULL_VALUE
+	}
+
+	divisor := d.truncate()
+	if divisor.Sign() == 0 {
+		return NULL_VALUE
+	}
+	return newDecimal(new(big.Int).Quo(this.truncate(), divisor), 0)
+}
+
+func (this *decimalValue) IMod(n NumberValue) Value {
+	d, ok := asDecimal(n)
+	if !ok {
+		return NULL_VALUE
+	}
+
+	divisor := d.truncate()
+	if divisor.Sign() == 0 {
+		return NULL_VALUE
+	}
+	return newDecimal(new(big.Int).Rem(this.truncate(), divisor), 0)
+}
+
+func (this *decimalValue) Mult(n NumberValue) NumberValue {
+	d, ok := asDecimal(n)
+	if !ok {
+		return floatValue(this.Float64() * n.Float64())
+	}
+
+	return newDecimal(new(big.Int).Mul(this.unscaled, d.unscaled), this.scale+d.scale)
+}
+
+func (this *decimalValue) Neg() NumberValue {
+	return &decimalValue{new(big.Int).Neg(this.unscaled), this.scale}
+}
+
+func (this *decimalValue) Sub(n NumberValue) NumberValue {
+	return this.Add(n.Neg())
+}
+
+/*
+The integer part, saturated to the range of int64.
+*/
+func (this *decimalValue) Int64() int64 {
+	i := this.truncate()
+	switch {
+	case i.BitLen() < 64:
+		return i.Int64()
+	case i.Sign() < 0:
+		return math.MinInt64
+	default:
+		return math.MaxInt64
+	}
+}
+
+func (this *decimalValue) Float64() float64 {
+	f, _ := strconv.ParseFloat(this.String(), 64)
+	return f
+}
+
+func (this *decimalValue) truncate() *big.Int {
+	if this.scale == 0 {
+		return this.unscaled
+	}
+	return new(big.Int).Quo(this.unscaled, pow10(this.scale))
+}
+
+/*
+JSON integers that do not fit int64 are parsed as decimals. Such
+integers parse to float64 values of at least 2^63 in magnitude, and
+only the containers holding one of these are parsed again, so that
+the raw bytes are never scanned for them.
+*/
+const _MIN_LONG_FLOAT = float64(1 << 63)
+
+func hasLongNumber(val interface{}) bool {
+	switch val := val.(type) {
+	case float64:
+		return val >= _MIN_LONG_FLOAT || val <= -_MIN_LONG_FLOAT
+	case []interface{}:
+		for _, v := range val {
+			if hasLongNumber(v) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, v := range val {
+			if hasLongNumber(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// integers of at most 18 digits always fit int64
+func longInteger(token []byte) bool {
+	token = bytes.TrimSpace(token)
+	if bytes.IndexAny(token, ".eE") >= 0 {
+		return false
+	}
+	digits := len(token)
+	if token[0] == '-' {
+		digits--
+	}
+	if digits < 19 {
+		return false
+	}
+	_, err := strconv.ParseInt(string(token), 10, 64)
+	return err != nil
+}
+
+/*
+Parses JSON, with integers that do not fit int64 as decimals.
+*/
+func unmarshalDecimals(raw []byte) (interface{}, error) {
+	var p interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	err := dec.Decode(&p)
+	if err != nil {
+		return nil, err
+	}
+	return convertNumbers(p), nil
+}
+
+func convertNumbers(val interface{}) interface{} {
+	switch val := val.(type) {
+	case json.Number:
+		return numberValue(string(val))
+	case []interface{}:
+		for i, v := range val {
+			val[i] = convertNumbers(v)
+		}
+	case map[string]interface{}:
+		for k, v := range val {
+			val[k] = convertNumbers(v)
+		}
+	}
+	return val
+}
+
+/*
+The Value of a JSON number.
+*/
+func numberValue(s string) Value {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return intValue(i)
+	}
+
+	if strings.IndexAny(s, ".eE") < 0 {
+		if d, ok := parseDecimal(s); ok {
+			return d
+		}
+	}
+
+	f, _ := strconv.ParseFloat(s, 64)
+	return NewValue(f)
+}
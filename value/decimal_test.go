@@ -0,0 +1,173 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package value
+
+import (
+	"testing"
+)
+
+func decimal(t *testing.T, s string) NumberValue {
+	d, ok := NewDecimalValue(s)
+	if !ok {
+		t.Fatalf("Expected decimal for %s", s)
+	}
+	return d.(NumberValue)
+}
+
+func TestDecimalParse(t *testing.T) {
+	val := NewParsedValue([]byte("12345678901234567890123"), false)
+	if !IsDecimal(val) || val.String() != "12345678901234567890123" {
+		t.Errorf("Expected decimal 12345678901234567890123, got %v of type %T", val, val)
+	}
+
+	val = NewParsedValue([]byte(`{"big": 98765432109876543210, "small": 5, "f": 1.5}`), false)
+	bytes, _ := val.MarshalJSON()
+	if string(bytes) != `{"big":98765432109876543210,"f":1.5,"small":5}` {
+		t.Errorf("Expected lossless JSON, got %s", bytes)
+	}
+	big, _ := val.Field("big")
+	if !IsDecimal(big) {
+		t.Errorf("Expected decimal field, got %v of type %T", big, big)
+	}
+
+	val = NewParsedValue([]byte("9223372036854775807"), false)
+	if IsDecimal(val) {
+		t.Errorf("Expected int64, got %v of type %T", val, val)
+	}
+
+	for in, out := range map[string]string{
+		`{"s": "12345678901234567890123"}`:            `{"s":"12345678901234567890123"}`,
+		`[9223372036854775807, -9223372036854775808]`: `[9223372036854775807,-9223372036854775808]`,
+		`{"s": "a\\", "b": 9223372036854775808}`:      `{"b":9223372036854775808,"s":"a\\"}`,
+		`[-12345678901234567890123]`:                  `[-12345678901234567890123]`,
+	} {
+		bytes, _ := NewParsedValue([]byte(in), false).MarshalJSON()
+		if string(bytes) != out {
+			t.Errorf("Expected %s for %s, got %s", out, in, bytes)
+		}
+	}
+
+	for in, out := range map[string]string{
+		"19.990":   "19.99",
+		"-0.0500":  "-0.05",
+		"1.5e3":    "1500",
+		"12e-4":    "0.0012",
+		"0.000":    "0",
+		"+007.250": "7.25",
+	} {
+		if s := decimal(t, in).String(); s != out {
+			t.Errorf("Expected %s for %s, got %s", out, in, s)
+		}
+	}
+
+	for _, in := range []string{"", ".", "1.2.3", "abc", "1e99999999"} {
+		if _, ok := NewDecimalValue(in); ok {
+			t.Errorf("Expected no decimal for %q", in)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	var sum NumberValue = ZERO_NUMBER
+	for i := 0; i < 1000; i++ {
+		sum = sum.Add(decimal(t, "0.01"))
+	}
+	if sum.String() != "10" {
+		t.Errorf("Expected 10, got %v", sum)
+	}
+
+	if s := decimal(t, "0.1").Add(NewValue(0.2).(NumberValue)).String(); s != "0.3" {
+		t.Errorf("Expected 0.3, got %s", s)
+	}
+	if s := NewValue(1).(NumberValue).Sub(decimal(t, "0.9")).String(); s != "0.1" {
+		t.Errorf("Expected 0.1, got %s", s)
+	}
+	if s := decimal(t, "19.99").Mult(NewValue(3).(NumberValue)).String(); s != "59.97" {
+		t.Errorf("Expected 59.97, got %s", s)
+	}
+	if s := DecimalDiv(decimal(t, "2"), NewValue(3)).String(); s != "0.6666666666666666666666666666666667" {
+		t.Errorf("Expected 34 digits of 2/3, got %s", s)
+	}
+	if s := DecimalDiv(decimal(t, "1"), NewValue(8)).String(); s != "0.125" {
+		t.Errorf("Expected 0.125, got %s", s)
+	}
+	if s := DecimalMod(decimal(t, "-10.5"), NewValue(3)).String(); s != "-1.5" {
+		t.Errorf("Expected -1.5, got %s", s)
+	}
+	if v := DecimalDiv(decimal(t, "1"), ZERO_VALUE); v != NULL_VALUE {
+		t.Errorf("Expected NULL for division by zero, got %v", v)
+	}
+}
+
+func TestDecimalCollate(t *testing.T) {
+	if decimal(t, "5").Collate(NewValue(5)) != 0 || NewValue(5).Collate(decimal(t, "5")) != 0 {
+		t.Errorf("Expected decimal 5 to collate equal to 5")
+	}
+	if decimal(t, "0.1").Collate(NewValue(0.1)) != 0 || !NewValue(0.1).Equals(decimal(t, "0.1")).Truth() {
+		t.Errorf("Expected decimal 0.1 to equal float 0.1")
+	}
+	if decimal(t, "0.1000000000000000001").Collate(NewValue(0.1)) <= 0 {
+		t.Errorf("Expected 0.1000000000000000001 > 0.1")
+	}
+	if NewValue(0.5).Collate(decimal(t, "0.5")) != 0 || !decimal(t, "0.5").Equals(NewValue(0.5)).Truth() {
+		t.Errorf("Expected decimal 0.5 to equal float 0.5")
+	}
+	if decimal(t, "12345678901234567890123").Collate(NewValue(1.0e22)) <= 0 {
+		t.Errorf("Expected 12345678901234567890123 > 1e22")
+	}
+	if decimal(t, "-1").Collate(NewValue("a")) >= 0 {
+		t.Errorf("Expected numbers before strings")
+	}
+
+	set := NewSet(4, true, false)
+	set.Add(decimal(t, "5"))
+	set.Add(NewValue(5))
+	set.Add(decimal(t, "0.1"))
+	set.Add(NewValue(0.1))
+	set.Add(decimal(t, "0.1234567890123456789"))
+	set.Add(decimal(t, "0.12345678901234567890"))
+	if set.Len() != 3 {
+		t.Errorf("Expected 3 distinct numbers, got %v", set.Values())
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		digits int
+		mode   int
+		out    string
+	}{
+		{"1.255", 2, ROUND_HALF_EVEN, "1.26"},
+		{"1.265", 2, ROUND_HALF_EVEN, "1.26"},
+		{"-1.255", 2, ROUND_HALF_EVEN, "-1.26"},
+		{"1.2551", 2, ROUND_HALF_EVEN, "1.26"},
+		{"1250", -2, ROUND_HALF_EVEN, "1200"},
+		{"1.5", 5, ROUND_HALF_EVEN, "1.5"},
+		{"-1.999", 2, ROUND_DOWN, "-1.99"},
+		{"-1.001", 0, ROUND_FLOOR, "-2"},
+		{"1.001", 0, ROUND_FLOOR, "1"},
+		{"1.001", 0, ROUND_CEILING, "2"},
+		{"-1.999", 0, ROUND_CEILING, "-1"},
+	}
+
+	for _, test := range tests {
+		if s := DecimalRound(decimal(t, test.in), test.digits, test.mode).String(); s != test.out {
+			t.Errorf("Expected %s for %s to %d digits in mode %d, got %s", test.out, test.in, test.digits, test.mode, s)
+		}
+	}
+
+	if DecimalRound(NewValue(1.5), 0, ROUND_HALF_EVEN) != nil || DecimalAbs(NewValue(-1)) != nil {
+		t.Errorf("Expected no decimal results for other numbers")
+	}
+	if s := DecimalAbs(decimal(t, "-0.01")).String(); s != "0.01" {
+		t.Errorf("Expected 0.01, got %s", s)
+	}
+}
@@ -86,6 +86,8 @@ func (this floatValue) Equals(other Value) Value {
 		if float64(this) == float64(other) {
 			return TRUE_VALUE
 		}
+	case *decimalValue:
+		return other.Equals(this)
 	}
 
 	return FALSE_VALUE
@@ -98,6 +100,8 @@ func (this floatValue) EquivalentTo(other Value) bool {
 		return this == other
 	case intValue:
 		return float64(this) == float64(other)
+	case *decimalValue:
+		return other.EquivalentTo(this)
 	default:
 		return false
 	}
@@ -114,6 +118,8 @@ func (this floatValue) Collate(other Value) int {
 		t := float64(this)
 		o := float64(other)
 		return collateFloat(t, o)
+	case *decimalValue:
+		return -other.Collate(this)
 	default:
 		return int(NUMBER - other.Type())
 	}
@@ -332,6 +338,10 @@ NumberValue methods.
 */
 
 func (this floatValue) Add(n NumberValue) NumberValue {
+	if d, ok := n.(*decimalValue); ok {
+		return d.Add(this)
+	}
+
 	return floatValue(float64(this) + n.Actual().(float64))
 }
 
@@ -372,6 +382,10 @@ func (this floatValue) IMod(n NumberValue) Value {
 }
 
 func (this floatValue) Mult(n NumberValue) NumberValue {
+	if d, ok := n.(*decimalValue); ok {
+		return d.Mult(this)
+	}
+
 	return floatValue(float64(this) * n.Actual().(float64))
 }
 
@@ -380,6 +394,10 @@ func (this floatValue) Neg() NumberValue {
 }
 
 func (this floatValue) Sub(n NumberValue) NumberValue {
+	if d, ok := n.(*decimalValue); ok {
+		return d.Neg().Add(this)
+	}
+
 	return floatValue(float64(this) - n.Actual().(float64))
 }
 
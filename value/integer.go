@@ -80,6 +80,8 @@ func (this intValue) Equals(other Value) Value {
 		if float64(this) == float64(other) {
 			return TRUE_VALUE
 		}
+	case *decimalValue:
+		return other.Equals(this)
 	}
 
 	return FALSE_VALUE
@@ -92,6 +94,8 @@ func (this intValue) EquivalentTo(other Value) bool {
 		return this == other
 	case floatValue:
 		return float64(this) == float64(other)
+	case *decimalValue:
+		return other.EquivalentTo(this)
 	default:
 		return false
 	}
@@ -109,7 +113,7 @@ func (this intValue) Collate(other Value) int {
 		default:
 			return 0
 		}
-	case floatValue:
+	case floatValue, *decimalValue:
 		return -other.Collate(this)
 	default:
 		return int(NUMBER - other.Type())
@@ -274,6 +278,8 @@ func (this intValue) Add(n NumberValue) NumberValue {
 		if !overFlow {
 			return rv
 		}
+	case *decimalValue:
+		return n.Add(this)
 	}
 
 	return floatValue(float64(this) + n.Actual().(float64))
@@ -326,6 +332,8 @@ func (this intValue) Mult(n NumberValue) NumberValue {
 		if this == 0 || rv/this == n {
 			return rv
 		}
+	case *decimalValue:
+		return n.Mult(this)
 	}
 
 	return floatValue(float64(this) * n.Actual().(float64))
@@ -345,6 +353,8 @@ func (this intValue) Sub(n NumberValue) NumberValue {
 		if n > math.MinInt64 {
 			return this.Add(-n)
 		}
+	case *decimalValue:
+		return n.Neg().Add(this)
 	}
 
 	return floatValue(float64(this) - n.Actual().(float64))
@@ -53,7 +53,9 @@ func NewParsedValueWithOptions(bytes []byte, isValidated, useState bool) Value {
 		var p interface{}
 		var err error
 
-		if isValidated {
+		if parsedType == NUMBER && longInteger(bytes) {
+			p, err = unmarshalDecimals(bytes)
+		} else if isValidated {
 			err = json.UnmarshalNoValidate(bytes, &p)
 		} else {
 			err = json.Unmarshal(bytes, &p)
@@ -460,8 +462,12 @@ func (this *parsedValue) unwrap() Value {
 			this.parsed = binaryValue(this.raw)
 		} else {
 			var p interface{}
+			var err error
 
-			err := json.UnmarshalNoValidate(this.raw, &p)
+			err = json.UnmarshalNoValidate(this.raw, &p)
+			if err == nil && hasLongNumber(p) {
+				p, err = unmarshalDecimals(this.raw)
+			}
 			if err != nil {
 				this.parsedType = BINARY
 				this.parsed = binaryValue(this.raw)
@@ -25,6 +25,7 @@ type Set struct {
 	nulls     Value
 	booleans  map[bool]Value
 	floats    map[float64]Value
+	decimals  map[string]Value
 	ints      map[int64]Value
 	strings   map[string]Value
 	arrays    map[string]Value
@@ -91,7 +92,7 @@ func (this *Set) Put(key, item Value) {
 	case BOOLEAN:
 		this.booleans[key.Actual().(bool)] = mapItem
 	case NUMBER:
-		num := key.unwrap()
+		num := exactNumber(key.unwrap())
 		switch num := num.(type) {
 		case floatValue:
 			f := float64(num)
@@ -102,6 +103,11 @@ func (this *Set) Put(key, item Value) {
 			}
 		case intValue:
 			this.ints[int64(num)] = mapItem
+		case *decimalValue:
+			if this.decimals == nil {
+				this.decimals = make(map[string]Value, _MAP_CAP)
+			}
+			this.decimals[num.String()] = mapItem
 		default:
 			panic(fmt.Sprintf("Unsupported value type %T.", key))
 		}
@@ -138,7 +144,7 @@ func (this *Set) Remove(key Value) {
 	case BOOLEAN:
 		delete(this.booleans, key.Actual().(bool))
 	case NUMBER:
-		num := key.unwrap()
+		num := exactNumber(key.unwrap())
 		switch num := num.(type) {
 		case floatValue:
 			f := float64(num)
@@ -149,6 +155,8 @@ func (this *Set) Remove(key Value) {
 			}
 		case intValue:
 			delete(this.ints, int64(num))
+		case *decimalValue:
+			delete(this.decimals, num.String())
 		default:
 			panic(fmt.Sprintf("Unsupported value type %T.", key))
 		}
@@ -185,7 +193,7 @@ func (this *Set) Has(key Value) bool {
 	case BOOLEAN:
 		_, ok = this.booleans[key.Actual().(bool)]
 	case NUMBER:
-		num := key.unwrap()
+		num := exactNumber(key.unwrap())
 		switch num := num.(type) {
 		case floatValue:
 			f := float64(num)
@@ -196,6 +204,8 @@ func (this *Set) Has(key Value) bool {
 			}
 		case intValue:
 			_, ok = this.ints[int64(num)]
+		case *decimalValue:
+			_, ok = this.decimals[num.String()]
 		default:
 			panic(fmt.Sprintf("Unsupported value type %T.", key))
 		}
@@ -216,8 +226,8 @@ func (this *Set) Has(key Value) bool {
 }
 
 func (this *Set) Len() int {
-	rv := len(this.booleans) + len(this.floats) + len(this.ints) + len(this.strings) +
-		len(this.arrays) + len(this.objects) + len(this.binaries)
+	rv := len(this.booleans) + len(this.floats) + len(this.decimals) + len(this.ints) +
+		len(this.strings) + len(this.arrays) + len(this.objects) + len(this.binaries)
 
 	if this.nills {
 		rv++
@@ -263,6 +273,10 @@ func (this *Set) Values() []Value {
 		rv = append(rv, av)
 	}
 
+	for _, av := range this.decimals {
+		rv = append(rv, av)
+	}
+
 	for _, av := range this.ints {
 		rv = append(rv, av)
 	}
@@ -309,6 +323,10 @@ func (this *Set) Actuals() []interface{} {
 		rv = append(rv, av.Actual())
 	}
 
+	for _, av := range this.decimals {
+		rv = append(rv, av.Actual())
+	}
+
 	for _, av := range this.ints {
 		rv = append(rv, av.Actual())
 	}
@@ -363,6 +381,10 @@ func (this *Set) Items() []interface{} {
 		rv = append(rv, av)
 	}
 
+	for _, av := range this.decimals {
+		rv = append(rv, av)
+	}
+
 	for _, av := range this.ints {
 		rv = append(rv, av)
 	}
@@ -398,6 +420,11 @@ func (this *Set) Clear() {
 		delete(this.floats, k)
 	}
 
+	for k, _ := range this.decimals {
+		this.decimals[k] = nil
+		delete(this.decimals, k)
+	}
+
 	for k, _ := range this.ints {
 		this.ints[k] = nil
 		delete(this.ints, k)
@@ -477,6 +504,13 @@ func (this *Set) Copy() *Set {
 		rv.floats[k] = v
 	}
 
+	if len(this.decimals) > 0 {
+		rv.decimals = make(map[string]Value, 2*len(this.decimals))
+		for k, v := range this.decimals {
+			rv.decimals[k] = v
+		}
+	}
+
 	for k, v := range this.ints {
 		rv.ints[k] = v
 	}
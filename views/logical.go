@@ -0,0 +1,255 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package views
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+)
+
+// Views
+//
+// A view is a named SELECT statement, which can be used in FROM clauses
+// in place of a keyspace. Nothing is stored but the definition: the planner
+// expands each reference to a view into a subquery of its definition, and
+// pushes down to it the filters on the view that do not change its results.
+//
+// Views belong to a namespace, and their definition is run in it. They may
+// use other views, but not themselves.
+
+type View struct {
+	Name       string    `json:"name"`
+	Namespace  string    `json:"namespace"`
+	Definition string    `json:"definition"`
+	Created    time.Time `json:"created"`
+}
+
+// persistence for view definitions
+type LogicalViewStore interface {
+
+	// return all the views stored
+	Load() ([]*View, errors.Error)
+
+	// replace the stored views
+	Save(views []*View) errors.Error
+}
+
+type logicalViewCache struct {
+	sync.RWMutex
+	store LogicalViewStore
+	views map[string]*View
+}
+
+var logicalViews = &logicalViewCache{
+	views: make(map[string]*View),
+}
+
+// init the views, from a store if one is given
+func ViewsInit(store LogicalViewStore) errors.Error {
+	logicalViews.Lock()
+	defer logicalViews.Unlock()
+
+	logicalViews.store = store
+	logicalViews.views = make(map[string]*View)
+	if store == nil {
+		return nil
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		return err
+	}
+	for _, v := range loaded {
+		_, err = v.Query()
+		if err != nil {
+			logging.Errorf("View %v not loaded: %v", v.key(), err)
+			continue
+		}
+		logicalViews.views[v.key()] = v
+	}
+	return nil
+}
+
+// add a view
+func CreateView(namespace, name, definition string) (*View, errors.Error) {
+	v := &View{
+		Name:       name,
+		Namespace:  namespace,
+		Definition: definition,
+		Created:    time.Now(),
+	}
+	query, err := v.Query()
+	if err != nil {
+		return nil, err
+	}
+	if query.Params() > 0 {
+		return nil, errors.NewViewDefinitionError(name, "parameters are not allowed")
+	}
+
+	err = logicalViews.add(v, query)
+	if err != nil {
+		return nil, err
+	}
+	changed(v)
+	return v, nil
+}
+
+func (this *logicalViewCache) add(v *View, query *algebra.Select) errors.Error {
+	this.Lock()
+	defer this.Unlock()
+
+	_, ok := this.views[v.key()]
+	if ok {
+		return errors.NewViewExistsError(v.Name)
+	}
+	if this.uses(query, v.Namespace, v.key(), map[string]bool{}) {
+		return errors.NewViewDefinitionError(v.Name, "a view cannot use itself")
+	}
+
+	this.views[v.key()] = v
+	err := this.save()
+	if err != nil {
+		delete(this.views, v.key())
+	}
+	return err
+}
+
+// remove a view
+// the views using it can no longer be used
+func DropView(namespace, name string) errors.Error {
+	logicalViews.Lock()
+	key := namespace + ":" + name
+	v, ok := logicalViews.views[key]
+	if !ok {
+		logicalViews.Unlock()
+		return errors.NewNoSuchViewError(name)
+	}
+	delete(logicalViews.views, key)
+	err := logicalViews.save()
+	if err != nil {
+		logicalViews.views[key] = v
+	}
+	logicalViews.Unlock()
+
+	if err == nil {
+		changed(v)
+	}
+	return err
+}
+
+func GetView(namespace, name string) *View {
+	logicalViews.RLock()
+	defer logicalViews.RUnlock()
+	return logicalViews.views[namespace+":"+name]
+}
+
+// all the views, by namespace and name
+func Views() []*View {
+	logicalViews.RLock()
+	rv := make([]*View, 0, len(logicalViews.views))
+	for _, v := range logicalViews.views {
+		rv = append(rv, v)
+	}
+	logicalViews.RUnlock()
+
+	sort.Slice(rv, func(i, j int) bool { return rv[i].key() < rv[j].key() })
+	return rv
+}
+
+// the view's definition, as a new statement, which the caller can modify
+func (this *View) Query() (*algebra.Select, errors.Error) {
+	query, err := parse(this.Definition)
+	if err != nil {
+		return nil, errors.NewViewDefinitionError(this.Name, err.Error())
+	}
+	return query, nil
+}
+
+// statements using a view see it as a keyspace: report a change of
+// definition as a change of all its documents, so that cached results,
+// and materialized views, using the view are recomputed
+func changed(v *View) {
+	datastore.NotifyMutation(v.Namespace, v.Name, nil)
+}
+
+func (this *View) key() string {
+	return this.Namespace + ":" + this.Name
+}
+
+// does the query use the view with the given key, directly or through
+// other views
+// Locking is handled by the top level caller!
+func (this *logicalViewCache) uses(query *algebra.Select, namespace, key string, seen map[string]bool) bool {
+	keyspaces, err := algebra.ReferencedKeyspaces(query, namespace)
+	if err != nil {
+		return false
+	}
+	for _, keyspace := range keyspaces {
+		if keyspace == key {
+			return true
+		}
+		v, ok := this.views[keyspace]
+		if !ok || seen[keyspace] {
+			continue
+		}
+		seen[keyspace] = true
+		query, err := v.Query()
+		if err == nil && this.uses(query, v.Namespace, key, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// Locking is handled by the top level caller!
+func (this *logicalViewCache) save() errors.Error {
+	if this.store == nil {
+		return nil
+	}
+
+	saved := make([]*View, 0, len(this.views))
+	for _, v := range this.views {
+		saved = append(saved, v)
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].key() < saved[j].key() })
+	return this.store.Save(saved)
+}
+
+// local file view store
+type fileLogicalViewStore struct {
+	path string
+}
+
+func NewFileLogicalViewStore(path string) LogicalViewStore {
+	return &fileLogicalViewStore{path: path}
+}
+
+func (this *fileLogicalViewStore) Load() ([]*View, errors.Error) {
+	var rv []*View
+	err := readFile(this.path, &rv)
+	if err != nil {
+		return nil, errors.NewViewStoreError(err)
+	}
+	return rv, nil
+}
+
+func (this *fileLogicalViewStore) Save(views []*View) errors.Error {
+	err := writeFile(this.path, views)
+	if err != nil {
+		return errors.NewViewStoreError(err)
+	}
+	return nil
+}
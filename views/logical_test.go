@@ -0,0 +1,62 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package views
+
+import (
+	"testing"
+)
+
+func TestLogicalViews(t *testing.T) {
+	ViewsInit(nil)
+	defer ViewsInit(nil)
+
+	_, err := CreateView("default", "daily", "SELECT o.day, o.amount FROM orders AS o")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = CreateView("default", "big", "SELECT d.day FROM daily AS d WHERE d.amount > 100")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if GetView("default", "daily") == nil || GetView("other", "daily") != nil {
+		t.Errorf("expected views to be found by namespace and name")
+	}
+	if len(Views()) != 2 || Views()[0].Name != "big" {
+		t.Errorf("unexpected views %v", Views())
+	}
+
+	for _, definition := range []string{
+		"SELECT o.day FROM orders AS o WHERE o.day = $1",
+		"SELECT d.day FROM daily2 AS d",
+		"DELETE FROM orders",
+	} {
+		_, err = CreateView("default", "daily2", definition)
+		if err == nil {
+			t.Errorf("expected %s to be rejected", definition)
+		}
+	}
+	_, err = CreateView("default", "daily", "SELECT 1")
+	if err == nil {
+		t.Errorf("expected an existing view to be rejected")
+	}
+
+	// a view using itself through another view
+	err = DropView("default", "daily")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = CreateView("default", "daily", "SELECT b.day FROM big AS b")
+	if err == nil {
+		t.Errorf("expected a view using itself to be rejected")
+	}
+	if DropView("default", "daily") == nil {
+		t.Errorf("expected a dropped view not to be found")
+	}
+}
@@ -0,0 +1,462 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package views
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/expression"
+	"github.com/couchbase/query/value"
+)
+
+// documents written or deleted at a time, and source documents, or
+// groups, handled by each query of an incremental refresh
+const _BATCH_SIZE = 256
+
+// how an incremental view finds the groups of source documents
+type groupDefinition struct {
+	from  string   // the source, with USE KEYS to follow
+	alias string   // of the source
+	where string   // the source filter, if any
+	names []string // the names of the group keys in the results
+	exprs []string // and the group keys
+}
+
+// views that group a single keyspace, and project each group key, can be
+// refreshed a group at a time
+func newGroupDefinition(query *algebra.Select, keyspaces []string) *groupDefinition {
+	sub, ok := query.Subresult().(*algebra.Subselect)
+	if !ok || len(keyspaces) != 1 || query.Order() != nil || query.Offset() != nil || query.Limit() != nil {
+		return nil
+	}
+	from, _ := sub.From().(algebra.SimpleFromTerm)
+	term := algebra.GetKeyspaceTerm(from)
+	if term == nil || term.Keys() != nil || len(sub.With()) > 0 || len(sub.Let()) > 0 {
+		return nil
+	}
+	group := sub.Group()
+	projection := sub.Projection()
+	if group == nil || len(group.By()) == 0 || projection.Distinct() || projection.Raw() {
+		return nil
+	}
+
+	// subqueries could read other documents
+	subqueries, err := expression.ListSubqueries(query.Expressions(), false)
+	if err != nil || len(subqueries) > 0 {
+		return nil
+	}
+
+	namespace := keyspaces[0][:strings.IndexByte(keyspaces[0], ':')]
+	rv := &groupDefinition{
+		from:  "`" + namespace + "`:`" + term.Keyspace() + "` AS `" + term.Alias() + "`",
+		alias: term.Alias(),
+	}
+	if sub.Where() != nil {
+		rv.where = sub.Where().String()
+	}
+
+	for _, by := range group.By() {
+		name := ""
+		for _, term := range projection.Terms() {
+			if !term.Star() && term.Alias() != "" && term.Expression().EquivalentTo(by) {
+				name = term.Alias()
+				break
+			}
+		}
+		if name == "" {
+			return nil
+		}
+		rv.names = append(rv.names, name)
+		rv.exprs = append(rv.exprs, by.String())
+	}
+	return rv
+}
+
+// the key of the document holding the results of a group
+// a group key can be missing, which is not the same as null
+func (this *groupDefinition) rowKey(row value.Value) string {
+	h := sha256.New()
+	for _, name := range this.names {
+		v, ok := row.Field(name)
+		if ok {
+			bytes, _ := v.MarshalJSON()
+			h.Write(bytes)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// the group keys of source documents
+// all the documents, when no keys are given
+func (this *groupDefinition) membershipQuery(keys []string) string {
+	s := "SELECT RAW [META(`" + this.alias + "`).id, {"
+	for i, name := range this.names {
+		if i > 0 {
+			s += ", "
+		}
+		s += value.NewValue(name).String() + ": " + this.exprs[i]
+	}
+	s += "}] FROM " + this.from
+	if keys != nil {
+		list := make([]interface{}, len(keys))
+		for i, key := range keys {
+			list[i] = key
+		}
+		s += " USE KEYS " + value.NewValue(list).String()
+	}
+	if this.where != "" {
+		s += " WHERE " + this.where
+	}
+	return s
+}
+
+// the row key of source documents, and the group keys of each row
+func (this *groupDefinition) membership(evaluator Evaluator, keys []string) (map[string]string, map[string]value.Value, errors.Error) {
+	results, err := evaluate(evaluator, this.membershipQuery(keys))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	members := make(map[string]string, len(results))
+	groups := make(map[string]value.Value)
+	for _, r := range results {
+		id, _ := r.Index(0)
+		group, _ := r.Index(1)
+		key, ok := id.Actual().(string)
+		if !ok {
+			continue
+		}
+		rowKey := this.rowKey(group)
+		members[key] = rowKey
+		groups[rowKey] = group
+	}
+	return members, groups, nil
+}
+
+// the view's definition, restricted to the groups given
+func (this *groupDefinition) groupsQuery(definition *algebra.Select, groups []value.Value) *algebra.Select {
+	sub := definition.Subresult().(*algebra.Subselect)
+	by := sub.Group().By()
+
+	matches := make(expression.Expressions, 0, len(groups))
+	for _, group := range groups {
+		conds := make(expression.Expressions, len(by))
+		for i, expr := range by {
+			v, ok := group.Field(this.names[i])
+			switch {
+			case !ok:
+				conds[i] = expression.NewIsMissing(expr)
+			case v.Type() == value.NULL:
+				conds[i] = expression.NewIsNull(expr)
+			default:
+				conds[i] = expression.NewEq(expr, expression.NewConstant(v))
+			}
+		}
+		if len(conds) == 1 {
+			matches = append(matches, conds[0])
+		} else {
+			matches = append(matches, expression.NewAnd(conds...))
+		}
+	}
+
+	var cond expression.Expression = expression.NewOr(matches...)
+	if len(matches) == 1 {
+		cond = matches[0]
+	}
+	if sub.Where() != nil {
+		cond = expression.NewAnd(sub.Where(), cond)
+	}
+	sub.SetWhere(cond)
+	return definition
+}
+
+// recompute the whole view, returning the number of documents written
+// and deleted
+func (this *MaterializedView) Refresh(evaluator Evaluator) (uint64, errors.Error) {
+	this.refresh.Lock()
+	defer this.refresh.Unlock()
+	return this.refreshAll(evaluator)
+}
+
+// recompute the groups of the source documents mutated since the last
+// refresh, or the whole view, if they are not known
+func (this *MaterializedView) RefreshIncremental(evaluator Evaluator) (uint64, errors.Error) {
+	this.refresh.Lock()
+	defer this.refresh.Unlock()
+
+	views.Lock()
+	if this.group == nil || this.members == nil || this.stale {
+		views.Unlock()
+		return this.refreshAll(evaluator)
+	}
+	keys := make([]string, 0, len(this.pending))
+	for key, _ := range this.pending {
+		keys = append(keys, key)
+	}
+	this.pending = nil
+	this.refreshing = true
+	views.Unlock()
+
+	var mutations uint64
+	var err errors.Error
+	for len(keys) > 0 && err == nil {
+		n := len(keys)
+		if n > _BATCH_SIZE {
+			n = _BATCH_SIZE
+		}
+		var count uint64
+		count, err = this.refreshGroups(evaluator, keys[:n])
+		mutations += count
+		keys = keys[n:]
+	}
+
+	views.Lock()
+	defer views.Unlock()
+	this.refreshing = false
+	if err != nil {
+		this.stale = true
+		this.LastError = err.Error()
+		return mutations, err
+	}
+	this.IncrementalRefreshes++
+	this.LastRefresh = time.Now()
+	this.LastError = ""
+	return mutations, nil
+}
+
+// Locking is handled by the top level caller!
+func (this *MaterializedView) refreshAll(evaluator Evaluator) (uint64, errors.Error) {
+	views.Lock()
+	this.refreshing = true
+	this.stale = false
+	this.pending = nil
+	views.Unlock()
+
+	mutations, members, groups, err := this.recompute(evaluator)
+	notified := err == nil && this.sourcesNotify()
+
+	views.Lock()
+	defer views.Unlock()
+	this.refreshing = false
+	if err != nil {
+		this.stale = true
+		this.LastError = err.Error()
+		return mutations, err
+	}
+	this.members = members
+	this.groups = groups
+	this.refreshed = true
+	this.notified = notified
+	this.Refreshes++
+	this.LastRefresh = time.Now()
+	this.LastError = ""
+	return mutations, nil
+}
+
+func (this *MaterializedView) recompute(evaluator Evaluator) (uint64, map[string]string, map[string]value.Value, errors.Error) {
+	keyspace, err := datastore.GetKeyspace(this.Namespace, this.Name)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	current, err := evaluate(evaluator, "SELECT META(`v`).id FROM "+this.keyspace()+" AS `v`")
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	query, err := this.query()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	results, err := evaluator.Evaluate(query)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	var members map[string]string
+	var groups map[string]value.Value
+	if this.group != nil {
+		members, groups, err = this.group.membership(evaluator, nil)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+	}
+
+	rows, _ := results.Actual().([]interface{})
+	pairs := make([]value.Pair, 0, len(rows))
+	keys := make(map[string]bool, len(rows))
+	for i, r := range rows {
+		row := value.NewValue(r)
+		if row.Type() == value.MISSING {
+			continue
+		}
+
+		// ordered results are read back in key order
+		key := fmt.Sprintf("%010d", i)
+		if this.group != nil {
+			key = this.group.rowKey(row)
+		}
+		pairs = append(pairs, value.Pair{Name: key, Value: row})
+		keys[key] = true
+	}
+
+	var deletes []string
+	for _, c := range current {
+		id, _ := c.Field("id")
+		key, ok := id.Actual().(string)
+		if ok && !keys[key] {
+			deletes = append(deletes, key)
+		}
+	}
+
+	mutations, err := write(keyspace, pairs, deletes, evaluator)
+	return mutations, members, groups, err
+}
+
+// recompute the groups some source documents belong, or belonged, to
+// Locking is handled by the top level caller!
+func (this *MaterializedView) refreshGroups(evaluator Evaluator, keys []string) (uint64, errors.Error) {
+	keyspace, err := datastore.GetKeyspace(this.Namespace, this.Name)
+	if err != nil {
+		return 0, err
+	}
+	members, groups, err := this.group.membership(evaluator, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	// the members and groups are only changed by refreshes
+	affected := make(map[string]value.Value, len(keys))
+	for _, key := range keys {
+		rowKey, ok := this.members[key]
+		if ok && this.groups[rowKey] != nil {
+			affected[rowKey] = this.groups[rowKey]
+		}
+	}
+	for rowKey, group := range groups {
+		affected[rowKey] = group
+	}
+
+	var mutations uint64
+	if len(affected) > 0 {
+		list := make([]value.Value, 0, len(affected))
+		for _, group := range affected {
+			list = append(list, group)
+		}
+		query, err := this.query()
+		if err != nil {
+			return 0, err
+		}
+		results, err := evaluator.Evaluate(this.group.groupsQuery(query, list))
+		if err != nil {
+			return 0, err
+		}
+
+		rows, _ := results.Actual().([]interface{})
+		pairs := make([]value.Pair, 0, len(rows))
+		for _, r := range rows {
+			row := value.NewValue(r)
+			key := this.group.rowKey(row)
+			pairs = append(pairs, value.Pair{Name: key, Value: row})
+			delete(affected, key)
+		}
+
+		// groups with no results left
+		deletes := make([]string, 0, len(affected))
+		for rowKey, _ := range affected {
+			deletes = append(deletes, rowKey)
+		}
+		mutations, err = write(keyspace, pairs, deletes, evaluator)
+		if err != nil {
+			return mutations, err
+		}
+	}
+
+	views.Lock()
+	defer views.Unlock()
+	for _, key := range keys {
+		delete(this.members, key)
+	}
+	for key, rowKey := range members {
+		this.members[key] = rowKey
+	}
+	for rowKey, group := range groups {
+		this.groups[rowKey] = group
+	}
+	return mutations, nil
+}
+
+func (this *MaterializedView) sourcesNotify() bool {
+	for _, name := range this.keyspaces {
+		colon := strings.IndexByte(name, ':')
+		keyspace, err := datastore.GetKeyspace(name[:colon], name[colon+1:])
+		if err != nil || !datastore.NotifiesMutations(keyspace) {
+			return false
+		}
+	}
+	return true
+}
+
+func evaluate(evaluator Evaluator, text string) ([]value.Value, errors.Error) {
+	query, err := parse(text)
+	if err != nil {
+		return nil, err
+	}
+	results, err := evaluator.Evaluate(query)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := results.Actual().([]interface{})
+	rv := make([]value.Value, len(actual))
+	for i, a := range actual {
+		rv[i] = value.NewValue(a)
+	}
+	return rv, nil
+}
+
+// upsert and delete view documents, in batches
+func write(keyspace datastore.Keyspace, pairs []value.Pair, deletes []string, context datastore.QueryContext) (uint64, errors.Error) {
+	var mutations uint64
+
+	for len(pairs) > 0 {
+		n := len(pairs)
+		if n > _BATCH_SIZE {
+			n = _BATCH_SIZE
+		}
+		upserted, err := keyspace.Upsert(pairs[:n])
+		mutations += uint64(len(upserted))
+		if err != nil {
+			return mutations, err
+		}
+		pairs = pairs[n:]
+	}
+
+	for len(deletes) > 0 {
+		n := len(deletes)
+		if n > _BATCH_SIZE {
+			n = _BATCH_SIZE
+		}
+		deleted, err := keyspace.Delete(deletes[:n], context)
+		mutations += uint64(len(deleted))
+		if err != nil {
+			return mutations, err
+		}
+		deletes = deletes[n:]
+	}
+	return mutations, nil
+}
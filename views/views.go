@@ -0,0 +1,520 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package views
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/couchbase/query/algebra"
+	"github.com/couchbase/query/datastore"
+	"github.com/couchbase/query/errors"
+	"github.com/couchbase/query/logging"
+	"github.com/couchbase/query/parser/n1ql"
+	"github.com/couchbase/query/value"
+)
+
+// Materialized views
+//
+// A materialized view is a SELECT statement whose results are stored in a
+// regular keyspace, which has the name of the view and must exist, empty,
+// when the view is created. REFRESH MATERIALIZED VIEW recomputes the
+// results from scratch.
+//
+// Views that group a single keyspace, and project all their group keys,
+// are also refreshed incrementally: when the datastore reports mutations on
+// the source keyspace, only the groups the mutated documents belonged to,
+// or now belong to, are recomputed. Such views are stored with a document
+// per group, and are fully refreshed, in the background, when the documents
+// mutated are not known, or after a restart.
+//
+// While a view is fresh, that is refreshed and with no mutations on its
+// sources since, SELECT statements identical to its definition are answered
+// from the view. Views over keyspaces whose datastore does not report all
+// mutations are never considered fresh.
+
+type MaterializedView struct {
+	Name       string    `json:"name"`
+	Namespace  string    `json:"namespace"`
+	Definition string    `json:"definition"`
+	Created    time.Time `json:"created"`
+
+	// refresh state is not persisted
+	LastRefresh          time.Time `json:"-"`
+	Refreshes            int64     `json:"-"`
+	IncrementalRefreshes int64     `json:"-"`
+	LastError            string    `json:"-"`
+
+	text      string   // the definition, as the planner prints it
+	keyspaces []string // the sources, as namespace:keyspace
+	ordered   bool     // results are stored in order
+	group     *groupDefinition
+
+	refresh    sync.Mutex // serializes refreshes
+	refreshing bool
+	refreshed  bool
+	stale      bool            // mutations need a full refresh
+	notified   bool            // the sources report all their mutations
+	pending    map[string]bool // source documents mutated since the last refresh
+	members    map[string]string
+	groups     map[string]value.Value
+}
+
+// persistence for view definitions
+type ViewStore interface {
+
+	// return all the views stored
+	Load() ([]*MaterializedView, errors.Error)
+
+	// replace the stored views
+	Save(views []*MaterializedView) errors.Error
+}
+
+// runs the queries of a refresh
+// the view's namespace is the default namespace of the queries
+type Evaluator interface {
+	datastore.QueryContext
+
+	Evaluate(query *algebra.Select) (value.Value, errors.Error)
+}
+
+type NewEvaluator func(namespace string) Evaluator
+
+type viewCache struct {
+	sync.RWMutex
+	store     ViewStore
+	evaluator NewEvaluator
+	views     map[string]*MaterializedView
+	maintain  chan bool
+}
+
+var views = &viewCache{
+	views: make(map[string]*MaterializedView),
+}
+
+// init the materialized views, from a store if one is given
+// incremental refreshes run in the background, using the evaluator given
+func MaterializedViewsInit(store ViewStore, evaluator NewEvaluator) errors.Error {
+	views.Lock()
+	defer views.Unlock()
+
+	views.store = store
+	views.evaluator = evaluator
+	views.views = make(map[string]*MaterializedView)
+	if views.maintain == nil {
+		views.maintain = make(chan bool, 1)
+		datastore.AddMutationListener(mutated)
+		go maintain()
+	}
+	if store == nil {
+		return nil
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		return err
+	}
+	for _, v := range loaded {
+		err = v.analyze()
+		if err != nil {
+			logging.Errorf("Materialized view %v not loaded: %v", v.key(), err)
+			continue
+		}
+		views.views[v.key()] = v
+	}
+
+	// mutations may have been missed: bring incremental views up to date
+	signal()
+	return nil
+}
+
+// add a view, and compute its results
+// the keyspace of the view must be empty: its documents are replaced by the
+// results of the view
+func CreateMaterializedView(namespace, name, definition string, evaluator Evaluator) (*MaterializedView, uint64, errors.Error) {
+	v := &MaterializedView{
+		Name:       name,
+		Namespace:  namespace,
+		Definition: definition,
+		Created:    time.Now(),
+	}
+	err := v.analyze()
+	if err != nil {
+		return nil, 0, err
+	}
+	if GetMaterializedView(namespace, name) != nil {
+		return nil, 0, errors.NewMaterializedViewExistsError(name)
+	}
+	current, err := evaluate(evaluator, "SELECT META(`v`).id FROM "+v.keyspace()+" AS `v` LIMIT 1")
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(current) > 0 {
+		return nil, 0, errors.NewMaterializedViewDefinitionError(name, "the keyspace of the view is not empty")
+	}
+
+	views.Lock()
+	_, ok := views.views[v.key()]
+	if !ok {
+		views.views[v.key()] = v
+		err = views.save()
+		if err != nil {
+			delete(views.views, v.key())
+		}
+	}
+	views.Unlock()
+	if ok {
+		return nil, 0, errors.NewMaterializedViewExistsError(name)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	mutations, err := v.Refresh(evaluator)
+	if err != nil {
+		DropMaterializedView(namespace, name)
+		return nil, mutations, err
+	}
+	return v, mutations, nil
+}
+
+// remove a view
+// its results are left in its keyspace
+func DropMaterializedView(namespace, name string) errors.Error {
+	views.Lock()
+	defer views.Unlock()
+
+	key := namespace + ":" + name
+	v, ok := views.views[key]
+	if !ok {
+		return errors.NewNoSuchMaterializedViewError(name)
+	}
+	delete(views.views, key)
+	err := views.save()
+	if err != nil {
+		views.views[key] = v
+	}
+	return err
+}
+
+func GetMaterializedView(namespace, name string) *MaterializedView {
+	views.RLock()
+	defer views.RUnlock()
+	return views.views[namespace+":"+name]
+}
+
+// all the views, by namespace and name
+func MaterializedViews() []*MaterializedView {
+	views.RLock()
+	rv := make([]*MaterializedView, 0, len(views.views))
+	for _, v := range views.views {
+		rv = append(rv, v)
+	}
+	views.RUnlock()
+
+	sort.Slice(rv, func(i, j int) bool { return rv[i].key() < rv[j].key() })
+	return rv
+}
+
+// the view a SELECT statement can be answered from, if there is one, and
+// the statement reading the view
+// the view may not be fresh: check it with Fresh before reading it
+func Rewrite(query *algebra.Select, namespace string) (*MaterializedView, *algebra.Select) {
+	text := query.String()
+
+	views.RLock()
+	var matches []*MaterializedView
+	for _, v := range views.views {
+		if v.text == text {
+			matches = append(matches, v)
+		}
+	}
+	views.RUnlock()
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	// the same text reads the same keyspaces, unless they are not qualified
+	keyspaces, er := algebra.ReferencedKeyspaces(query, namespace)
+	if er != nil {
+		return nil, nil
+	}
+	var view *MaterializedView
+	for _, v := range matches {
+		if sameKeyspaces(keyspaces, v.keyspaces) {
+			view = v
+			break
+		}
+	}
+	if view == nil {
+		return nil, nil
+	}
+
+	stmt := "SELECT RAW `v` FROM " + view.keyspace() + " AS `v`"
+	if view.ordered {
+		stmt += " ORDER BY META(`v`).id"
+	}
+	rewritten, err := parse(stmt)
+	if err != nil {
+		logging.Errorf("Materialized view %v cannot be read: %v", view.key(), err)
+		return nil, nil
+	}
+	return view, rewritten
+}
+
+// can the view with the given definition be read
+func Fresh(namespace, name, definition string) bool {
+	views.RLock()
+	defer views.RUnlock()
+
+	v, ok := views.views[namespace+":"+name]
+	return ok && v.Definition == definition && v.fresh()
+}
+
+func (this *MaterializedView) Incremental() bool {
+	return this.group != nil
+}
+
+// the source keyspaces, as namespace:keyspace
+func (this *MaterializedView) Keyspaces() []string {
+	return this.keyspaces
+}
+
+// Locking is handled by the top level caller!
+func (this *MaterializedView) fresh() bool {
+	return this.refreshed && this.notified && !this.refreshing && !this.stale && len(this.pending) == 0
+}
+
+func (this *MaterializedView) key() string {
+	return this.Namespace + ":" + this.Name
+}
+
+func (this *MaterializedView) keyspace() string {
+	return "`" + this.Namespace + "`:`" + this.Name + "`"
+}
+
+// the view's definition, as a new statement
+func (this *MaterializedView) query() (*algebra.Select, errors.Error) {
+	return parse(this.Definition)
+}
+
+// check the definition, and determine how the view can be refreshed
+func (this *MaterializedView) analyze() errors.Error {
+	query, err := this.query()
+	if err != nil {
+		return errors.NewMaterializedViewDefinitionError(this.Name, err.Error())
+	}
+	if query.Params() > 0 {
+		return errors.NewMaterializedViewDefinitionError(this.Name, "parameters are not allowed")
+	}
+
+	keyspaces, er := algebra.ReferencedKeyspaces(query, this.Namespace)
+	if er != nil {
+		return errors.NewMaterializedViewDefinitionError(this.Name, er.Error())
+	}
+	for _, keyspace := range keyspaces {
+		if keyspace == this.key() {
+			return errors.NewMaterializedViewDefinitionError(this.Name, "a view cannot read its own keyspace")
+		}
+	}
+
+	this.text = query.String()
+	this.keyspaces = keyspaces
+	this.ordered = query.Order() != nil
+	this.group = newGroupDefinition(query, keyspaces)
+	this.stale = true
+	return nil
+}
+
+func parse(text string) (*algebra.Select, errors.Error) {
+	stmt, err := n1ql.ParseStatement(text)
+	if err != nil {
+		return nil, errors.NewParseSyntaxError(err, "")
+	}
+	query, ok := stmt.(*algebra.Select)
+	if !ok {
+		return nil, errors.NewParseSyntaxError(nil, "not a SELECT statement")
+	}
+	return query, nil
+}
+
+// Locking is handled by the top level caller!
+func (this *viewCache) save() errors.Error {
+	if this.store == nil {
+		return nil
+	}
+
+	saved := make([]*MaterializedView, 0, len(this.views))
+	for _, v := range this.views {
+		saved = append(saved, v)
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].key() < saved[j].key() })
+	return this.store.Save(saved)
+}
+
+// mutations reported by the datastore
+// views track the documents mutated, or note that they need a full refresh
+func mutated(keyspace string, keys []string) {
+	work := false
+
+	views.Lock()
+	for _, v := range views.views {
+		if !v.reads(keyspace) {
+			continue
+		}
+		if v.group == nil || keys == nil || v.members == nil || v.stale {
+			v.stale = true
+		} else {
+			if v.pending == nil {
+				v.pending = make(map[string]bool, len(keys))
+			}
+			for _, key := range keys {
+				v.pending[key] = true
+			}
+		}
+		work = work || v.group != nil
+	}
+	views.Unlock()
+
+	if work {
+		signal()
+	}
+}
+
+func sameKeyspaces(keyspaces1, keyspaces2 []string) bool {
+	if len(keyspaces1) != len(keyspaces2) {
+		return false
+	}
+	for i, keyspace := range keyspaces1 {
+		if keyspace != keyspaces2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *MaterializedView) reads(keyspace string) bool {
+	for _, ks := range this.keyspaces {
+		if ks == keyspace {
+			return true
+		}
+	}
+	return false
+}
+
+func signal() {
+	select {
+	case views.maintain <- true:
+	default:
+	}
+}
+
+// keep incremental views up to date
+func maintain() {
+	for _ = range views.maintain {
+		views.RLock()
+		newEvaluator := views.evaluator
+		views.RUnlock()
+		if newEvaluator == nil {
+			continue
+		}
+
+		for _, v := range MaterializedViews() {
+			if !v.Incremental() {
+				continue
+			}
+
+			// a mistake here should not stop the maintenance of other views
+			func() {
+				defer func() {
+					r := recover()
+					if r != nil {
+						logging.Errorf("Materialized view %v maintenance failed: %v", v.key(), r)
+					}
+				}()
+
+				var err errors.Error
+				views.RLock()
+				full := v.stale || v.members == nil
+				work := full || len(v.pending) > 0
+				views.RUnlock()
+				if !work {
+					return
+				}
+				if full {
+					_, err = v.Refresh(newEvaluator(v.Namespace))
+				} else {
+					_, err = v.RefreshIncremental(newEvaluator(v.Namespace))
+				}
+				if err != nil {
+					logging.Errorf("Materialized view %v not refreshed: %v", v.key(), err)
+				}
+			}()
+		}
+	}
+}
+
+// local file view store
+type fileViewStore struct {
+	path string
+}
+
+func NewFileViewStore(path string) ViewStore {
+	return &fileViewStore{path: path}
+}
+
+func (this *fileViewStore) Load() ([]*MaterializedView, errors.Error) {
+	var rv []*MaterializedView
+	err := readFile(this.path, &rv)
+	if err != nil {
+		return nil, errors.NewMaterializedViewStoreError(err)
+	}
+	return rv, nil
+}
+
+func (this *fileViewStore) Save(views []*MaterializedView) errors.Error {
+	err := writeFile(this.path, views)
+	if err != nil {
+		return errors.NewMaterializedViewStoreError(err)
+	}
+	return nil
+}
+
+// read definitions stored as JSON
+// a file that does not exist holds no definitions
+func readFile(path string, definitions interface{}) error {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(bytes, definitions)
+}
+
+func writeFile(path string, definitions interface{}) error {
+	bytes, err := json.MarshalIndent(definitions, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	// never leave the store half written
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, bytes, 0600)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	return err
+}
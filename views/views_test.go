@@ -0,0 +1,140 @@
+//  Copyright (c) 2018 Couchbase, Inc.
+//  Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file
+//  except in compliance with the License. You may obtain a copy of the License at
+//    http://www.apache.org/licenses/LICENSE-2.0
+//  Unless required by applicable law or agreed to in writing, software distributed under the
+//  License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+//  either express or implied. See the License for the specific language governing permissions
+//  and limitations under the License.
+
+package views
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/couchbase/query/value"
+)
+
+const _ROLLUP = "SELECT o.day, o.region, SUM(o.amount) AS total FROM orders AS o WHERE o.type = \"order\" GROUP BY o.day, o.region"
+
+func newView(t *testing.T, name, definition string) *MaterializedView {
+	v := &MaterializedView{Name: name, Namespace: "default", Definition: definition}
+	err := v.analyze()
+	if err != nil {
+		t.Fatalf("unexpected error analyzing %s: %v", definition, err)
+	}
+	return v
+}
+
+func TestAnalyze(t *testing.T) {
+	v := newView(t, "daily", _ROLLUP)
+	if !v.Incremental() {
+		t.Errorf("expected a view grouping a single keyspace to be incremental")
+	}
+	if len(v.Keyspaces()) != 1 || v.Keyspaces()[0] != "default:orders" {
+		t.Errorf("unexpected sources %v", v.Keyspaces())
+	}
+
+	for _, definition := range []string{
+		"SELECT o.day, SUM(o.amount) AS total FROM orders AS o GROUP BY o.day, o.region",
+		"SELECT o.day, SUM(o.amount) AS total FROM orders AS o GROUP BY o.day ORDER BY o.day",
+		"SELECT o.day, c.name FROM orders AS o JOIN customers AS c ON KEYS o.customer GROUP BY o.day, c.name",
+		"SELECT o.day FROM orders AS o",
+	} {
+		if newView(t, "other", definition).Incremental() {
+			t.Errorf("expected %s not to be incremental", definition)
+		}
+	}
+	if !newView(t, "other", "SELECT o.day FROM orders AS o ORDER BY o.day").ordered {
+		t.Errorf("expected the results of an ordered view to be stored in order")
+	}
+
+	for _, definition := range []string{
+		"SELECT o.day FROM orders AS o WHERE o.day = $1",
+		"SELECT d.day FROM daily AS d",
+		"DELETE FROM orders",
+	} {
+		v := &MaterializedView{Name: "daily", Namespace: "default", Definition: definition}
+		if v.analyze() == nil {
+			t.Errorf("expected %s to be rejected", definition)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	v := newView(t, "daily", _ROLLUP)
+
+	row := value.NewValue(map[string]interface{}{"day": "2018-01-01", "region": "emea", "total": 10})
+	same := value.NewValue(map[string]interface{}{"day": "2018-01-01", "region": "emea", "total": 20})
+	null := value.NewValue(map[string]interface{}{"day": "2018-01-01", "region": nil, "total": 10})
+	missing := value.NewValue(map[string]interface{}{"day": "2018-01-01", "total": 10})
+	if v.group.rowKey(row) != v.group.rowKey(same) {
+		t.Errorf("expected rows of the same group to have the same key")
+	}
+	if v.group.rowKey(null) == v.group.rowKey(missing) {
+		t.Errorf("expected null and missing group keys to be distinguished")
+	}
+
+	query := v.group.membershipQuery([]string{"k1"})
+	if !strings.Contains(query, "USE KEYS [\"k1\"]") || !strings.Contains(query, "WHERE") {
+		t.Errorf("unexpected membership query %s", query)
+	}
+
+	definition, err := v.query()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	text := v.group.groupsQuery(definition, []value.Value{row, missing}).String()
+	if !strings.Contains(text, "\"emea\"") || !strings.Contains(text, "is missing") {
+		t.Errorf("unexpected groups query %s", text)
+	}
+}
+
+func TestMutations(t *testing.T) {
+	MaterializedViewsInit(nil, nil)
+
+	incremental := newView(t, "daily", _ROLLUP)
+	full := newView(t, "days", "SELECT o.day FROM orders AS o")
+	for _, v := range []*MaterializedView{incremental, full} {
+		v.refreshed = true
+		v.notified = true
+		v.stale = false
+		v.members = map[string]string{}
+		views.views[v.key()] = v
+	}
+	defer MaterializedViewsInit(nil, nil)
+
+	if !Fresh("default", "daily", _ROLLUP) || Fresh("default", "daily", "SELECT 1") {
+		t.Errorf("expected only the current definition to be fresh")
+	}
+
+	query, err := parse(_ROLLUP)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	view, rewritten := Rewrite(query, "default")
+	if view != incremental || !strings.Contains(rewritten.String(), "`default`:`daily`") {
+		t.Errorf("expected the query to be answered from the view")
+	}
+
+	// other keyspaces do not matter
+	mutated("default:customers", []string{"c1"})
+	if !Fresh("default", "daily", _ROLLUP) || !Fresh("default", "days", full.Definition) {
+		t.Errorf("expected views to stay fresh")
+	}
+
+	mutated("default:orders", []string{"k1"})
+	if Fresh("default", "daily", _ROLLUP) || !incremental.pending["k1"] || incremental.stale {
+		t.Errorf("expected the mutated document to be pending")
+	}
+	if Fresh("default", "days", full.Definition) || !full.stale {
+		t.Errorf("expected a view that is not incremental to be stale")
+	}
+
+	// unknown documents need a full refresh
+	mutated("default:orders", nil)
+	if !incremental.stale {
+		t.Errorf("expected the view to be stale")
+	}
+}